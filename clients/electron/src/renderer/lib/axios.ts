@@ -35,12 +35,12 @@ export const fetcher = async (args: string | [string, AxiosRequestConfig]) => {
 export const endpoints = {
   // Tennex Backend API endpoints
   auth: {
-    me: '/auth/me',
-    signIn: '/auth/login',
-    signUp: '/auth/register',
+    me: '/v1/auth/me',
+    signIn: '/v1/auth/login',
+    signUp: '/v1/auth/register',
   },
-  qr: '/qr',
-  settings: '/settings',
+  qr: '/v1/qr',
+  settings: '/v1/settings',
   // Template endpoints (kept for compatibility)
   chat: '/api/chat',
   kanban: '/api/kanban',
@@ -123,7 +123,7 @@ export function WhatsAppSyncView() {
       let totalConversations = 0;
 
       while (hasMore) {
-        const response = await axios.get(`/sync/conversations/${integrationId}`, {
+        const response = await axios.get(`/v1/sync/conversations/${integrationId}`, {
           params: { since_seq: conversationSeq, limit: 100 },
         });
 
@@ -162,7 +162,7 @@ export function WhatsAppSyncView() {
       let totalMessages = 0;
 
       while (hasMore) {
-        const response = await axios.get(`/sync/messages/${integrationId}`, {
+        const response = await axios.get(`/v1/sync/messages/${integrationId}`, {
           params: { since_seq: messageSeq, limit: 1500 },
         });
 
@@ -201,7 +201,7 @@ export function WhatsAppSyncView() {
       let totalContacts = 0;
 
       while (hasMore) {
-        const response = await axios.get(`/sync/contacts/${integrationId}`, {
+        const response = await axios.get(`/v1/sync/contacts/${integrationId}`, {
           params: { since_seq: contactSeq, limit: 500 },
         });
 
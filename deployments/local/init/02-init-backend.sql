@@ -494,6 +494,606 @@ ADD COLUMN seq BIGSERIAL;
 CREATE INDEX idx_conversation_participants_seq ON conversation_participants (seq);
 END IF;
 END $$;
+-- ==================== 007: Resolve Message Replies ====================
+-- Clear placeholder nil UUIDs and backfill reply links for stored parents
+UPDATE messages
+SET reply_to_message_id = NULL
+WHERE reply_to_message_id = '00000000-0000-0000-0000-000000000000'::uuid;
+UPDATE messages child
+SET reply_to_message_id = parent.id
+FROM messages parent
+WHERE child.reply_to_message_id IS NULL
+    AND child.reply_to_external_id IS NOT NULL
+    AND child.reply_to_external_id != ''
+    AND parent.conversation_id = child.conversation_id
+    AND parent.external_message_id = child.reply_to_external_id;
+-- ==================== 008: Pending Message Replies ====================
+-- Replies whose parent message hasn't been synced yet
+CREATE TABLE IF NOT EXISTS pending_message_replies (
+    message_id UUID PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    parent_external_message_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_pending_message_replies_parent ON pending_message_replies (conversation_id, parent_external_message_id);
+INSERT INTO pending_message_replies (message_id, conversation_id, parent_external_message_id)
+SELECT id,
+    conversation_id,
+    reply_to_external_id
+FROM messages
+WHERE reply_to_message_id IS NULL
+    AND reply_to_external_id IS NOT NULL
+    AND reply_to_external_id != '' ON CONFLICT (message_id) DO NOTHING;
+COMMENT ON TABLE pending_message_replies IS 'Replies waiting for their parent message to be synced';
+COMMENT ON COLUMN pending_message_replies.parent_external_message_id IS 'External platform ID of the message being replied to';
+-- Track the latest poll vote of each voter
+-- WhatsApp sends every vote as the voter's full selection, so a new vote
+-- replaces the previous one. Votes are keyed by the poll's external ID so
+-- they can be stored before the poll message itself has been synced.
+CREATE TABLE poll_votes (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    poll_external_message_id TEXT NOT NULL,
+    voter_external_id TEXT NOT NULL,
+    selected_option_hashes TEXT [] NOT NULL DEFAULT '{}',
+    voted_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (
+        conversation_id,
+        poll_external_message_id,
+        voter_external_id
+    )
+);
+COMMENT ON TABLE poll_votes IS 'Latest poll vote per voter, tallied per option on read';
+COMMENT ON COLUMN poll_votes.selected_option_hashes IS 'Hex SHA-256 of each selected option name; empty when the vote was retracted';
+-- Track how far event retention has compacted each account's event log
+-- Clients syncing from a seq below the low-water mark have missed deleted
+-- events and must rebuild their state from a snapshot.
+CREATE TABLE event_retention (
+    account_id TEXT PRIMARY KEY,
+    low_water_seq BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE event_retention IS 'Per-account low-water mark of events deleted by retention';
+COMMENT ON COLUMN event_retention.low_water_seq IS 'Highest seq deleted for the account; syncing from below it requires a snapshot';
+-- Add a role to users so admin-only endpoints can be authorized from the JWT
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD CONSTRAINT users_role_check
+    CHECK (role IN ('user', 'admin'));
+COMMENT ON COLUMN users.role IS 'Authorization role (user or admin), carried in the JWT role claim';
+-- Keep a history of integration status transitions so repeated disconnects
+-- and re-pairings can be investigated after the current status has changed
+CREATE TABLE integration_status_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_integration_id INTEGER NOT NULL REFERENCES user_integrations(id) ON DELETE CASCADE,
+    old_status TEXT,
+    new_status TEXT NOT NULL,
+    reason TEXT,
+    metadata JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_integration_status_events_integration ON integration_status_events(user_integration_id, id DESC);
+COMMENT ON TABLE integration_status_events IS 'Append-only log of integration status transitions, trimmed to the most recent entries per integration';
+COMMENT ON COLUMN integration_status_events.old_status IS 'Status before the transition; NULL when the integration was first created';
+COMMENT ON COLUMN integration_status_events.reason IS 'Why the status changed, e.g. logged_out or paired';
+-- Map WhatsApp LIDs to phone-number JIDs
+-- Newer WhatsApp accounts can address a contact by LID (…@lid) instead of
+-- their phone-number JID (…@s.whatsapp.net), which splits one contact into
+-- two conversations. Once a mapping is known, everything stored under the
+-- LID is merged into the phone-number conversation and contact, and new
+-- events from the LID are stored under the phone-number JID.
+CREATE TABLE contact_identities (
+    user_integration_id INTEGER NOT NULL REFERENCES user_integrations(id) ON DELETE CASCADE,
+    lid_jid TEXT NOT NULL,
+    pn_jid TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_integration_id, lid_jid)
+);
+CREATE INDEX idx_contact_identities_pn ON contact_identities (user_integration_id, pn_jid);
+COMMENT ON TABLE contact_identities IS 'Known LID to phone-number JID mappings per integration';
+COMMENT ON COLUMN contact_identities.pn_jid IS 'Canonical phone-number JID that events from lid_jid are stored under';
+-- Merge everything stored under p_lid into p_pn for one integration.
+-- Returns true when a LID conversation was merged into (or renamed to) the
+-- phone-number conversation. Rows that change get a new seq so syncing
+-- clients pick them up.
+CREATE FUNCTION merge_contact_identity(
+    p_user_integration_id INTEGER,
+    p_lid_jid TEXT,
+    p_pn_jid TEXT
+) RETURNS BOOLEAN LANGUAGE plpgsql AS $$
+DECLARE
+    lid_conversation_id UUID;
+    pn_conversation_id UUID;
+BEGIN
+    SELECT id INTO lid_conversation_id
+    FROM conversations
+    WHERE user_integration_id = p_user_integration_id
+        AND external_conversation_id = p_lid_jid
+    FOR UPDATE;
+
+    SELECT id INTO pn_conversation_id
+    FROM conversations
+    WHERE user_integration_id = p_user_integration_id
+        AND external_conversation_id = p_pn_jid
+    FOR UPDATE;
+
+    IF lid_conversation_id IS NOT NULL AND pn_conversation_id IS NULL THEN
+        UPDATE conversations
+        SET external_conversation_id = p_pn_jid,
+            seq = nextval(pg_get_serial_sequence('conversations', 'seq')),
+            updated_at = NOW()
+        WHERE id = lid_conversation_id;
+    ELSIF lid_conversation_id IS NOT NULL THEN
+        -- Messages stored in both conversations keep their phone-number copy
+        UPDATE messages child
+        SET reply_to_message_id = kept.id,
+            seq = nextval(pg_get_serial_sequence('messages', 'seq')),
+            updated_at = NOW()
+        FROM messages duplicate
+            JOIN messages kept ON kept.conversation_id = pn_conversation_id
+            AND kept.external_message_id = duplicate.external_message_id
+        WHERE duplicate.conversation_id = lid_conversation_id
+            AND child.reply_to_message_id = duplicate.id;
+
+        DELETE FROM messages duplicate USING messages kept
+        WHERE duplicate.conversation_id = lid_conversation_id
+            AND kept.conversation_id = pn_conversation_id
+            AND kept.external_message_id = duplicate.external_message_id;
+
+        UPDATE messages
+        SET conversation_id = pn_conversation_id,
+            seq = nextval(pg_get_serial_sequence('messages', 'seq')),
+            updated_at = NOW()
+        WHERE conversation_id = lid_conversation_id;
+
+        -- Replies whose parent was on the other side of the split
+        WITH resolved AS (
+            DELETE FROM pending_message_replies pending USING messages parent
+            WHERE pending.conversation_id IN (lid_conversation_id, pn_conversation_id)
+                AND parent.conversation_id = pn_conversation_id
+                AND parent.external_message_id = pending.parent_external_message_id
+            RETURNING pending.message_id, parent.id AS parent_id
+        )
+        UPDATE messages
+        SET reply_to_message_id = resolved.parent_id,
+            seq = nextval(pg_get_serial_sequence('messages', 'seq')),
+            updated_at = NOW()
+        FROM resolved
+        WHERE messages.id = resolved.message_id;
+
+        UPDATE pending_message_replies
+        SET conversation_id = pn_conversation_id
+        WHERE conversation_id = lid_conversation_id;
+
+        -- Keep the later of two votes by the same voter on the same poll
+        DELETE FROM poll_votes pn_vote USING poll_votes lid_vote
+        WHERE pn_vote.conversation_id = pn_conversation_id
+            AND lid_vote.conversation_id = lid_conversation_id
+            AND lid_vote.poll_external_message_id = pn_vote.poll_external_message_id
+            AND lid_vote.voter_external_id = pn_vote.voter_external_id
+            AND lid_vote.voted_at > pn_vote.voted_at;
+        DELETE FROM poll_votes lid_vote USING poll_votes pn_vote
+        WHERE lid_vote.conversation_id = lid_conversation_id
+            AND pn_vote.conversation_id = pn_conversation_id
+            AND pn_vote.poll_external_message_id = lid_vote.poll_external_message_id
+            AND pn_vote.voter_external_id = lid_vote.voter_external_id;
+        UPDATE poll_votes
+        SET conversation_id = pn_conversation_id,
+            updated_at = NOW()
+        WHERE conversation_id = lid_conversation_id;
+
+        DELETE FROM conversation_participants lid_participant USING conversation_participants pn_participant
+        WHERE lid_participant.conversation_id = lid_conversation_id
+            AND pn_participant.conversation_id = pn_conversation_id
+            AND pn_participant.external_user_id = lid_participant.external_user_id;
+        UPDATE conversation_participants
+        SET conversation_id = pn_conversation_id,
+            seq = nextval(pg_get_serial_sequence('conversation_participants', 'seq')),
+            updated_at = NOW()
+        WHERE conversation_id = lid_conversation_id;
+
+        UPDATE conversations pn
+        SET last_message_at = GREATEST(pn.last_message_at, lid.last_message_at),
+            last_activity_at = GREATEST(pn.last_activity_at, lid.last_activity_at),
+            seq = nextval(pg_get_serial_sequence('conversations', 'seq')),
+            updated_at = NOW()
+        FROM conversations lid
+        WHERE pn.id = pn_conversation_id
+            AND lid.id = lid_conversation_id;
+
+        DELETE FROM conversations
+        WHERE id = lid_conversation_id;
+    END IF;
+
+    -- Group messages, votes and memberships by the LID in any conversation
+    UPDATE messages
+    SET sender_external_id = p_pn_jid,
+        seq = nextval(pg_get_serial_sequence('messages', 'seq')),
+        updated_at = NOW()
+    FROM conversations c
+    WHERE messages.conversation_id = c.id
+        AND c.user_integration_id = p_user_integration_id
+        AND messages.sender_external_id = p_lid_jid;
+
+    DELETE FROM poll_votes lid_vote USING poll_votes pn_vote, conversations c
+    WHERE lid_vote.conversation_id = c.id
+        AND c.user_integration_id = p_user_integration_id
+        AND lid_vote.voter_external_id = p_lid_jid
+        AND pn_vote.conversation_id = lid_vote.conversation_id
+        AND pn_vote.poll_external_message_id = lid_vote.poll_external_message_id
+        AND pn_vote.voter_external_id = p_pn_jid;
+    UPDATE poll_votes
+    SET voter_external_id = p_pn_jid,
+        updated_at = NOW()
+    FROM conversations c
+    WHERE poll_votes.conversation_id = c.id
+        AND c.user_integration_id = p_user_integration_id
+        AND poll_votes.voter_external_id = p_lid_jid;
+
+    DELETE FROM conversation_participants lid_participant USING conversation_participants pn_participant, conversations c
+    WHERE lid_participant.conversation_id = c.id
+        AND c.user_integration_id = p_user_integration_id
+        AND lid_participant.external_user_id = p_lid_jid
+        AND pn_participant.conversation_id = lid_participant.conversation_id
+        AND pn_participant.external_user_id = p_pn_jid;
+    UPDATE conversation_participants
+    SET external_user_id = p_pn_jid,
+        seq = nextval(pg_get_serial_sequence('conversation_participants', 'seq')),
+        updated_at = NOW()
+    FROM conversations c
+    WHERE conversation_participants.conversation_id = c.id
+        AND c.user_integration_id = p_user_integration_id
+        AND conversation_participants.external_user_id = p_lid_jid;
+
+    -- The phone-number contact wins; a LID-only contact takes its JID
+    IF EXISTS (
+        SELECT 1
+        FROM contacts
+        WHERE user_integration_id = p_user_integration_id
+            AND external_contact_id = p_pn_jid
+    ) THEN
+        DELETE FROM contacts
+        WHERE user_integration_id = p_user_integration_id
+            AND external_contact_id = p_lid_jid;
+    ELSE
+        UPDATE contacts
+        SET external_contact_id = p_pn_jid,
+            seq = nextval(pg_get_serial_sequence('contacts', 'seq')),
+            updated_at = NOW()
+        WHERE user_integration_id = p_user_integration_id
+            AND external_contact_id = p_lid_jid;
+    END IF;
+
+    RETURN lid_conversation_id IS NOT NULL;
+END;
+$$;
+COMMENT ON FUNCTION merge_contact_identity IS 'Merge conversations, messages and contacts stored under a LID into its phone-number JID';
+-- Seed mappings from the LIDs already stored on phone-number contacts and
+-- merge the duplicates they have created so far
+INSERT INTO contact_identities (user_integration_id, lid_jid, pn_jid)
+SELECT DISTINCT ON (user_integration_id, platform_metadata->>'lid_jid')
+    user_integration_id,
+    platform_metadata->>'lid_jid',
+    external_contact_id
+FROM contacts
+WHERE integration_type = 'whatsapp'
+    AND external_contact_id LIKE '%@s.whatsapp.net'
+    AND platform_metadata->>'lid_jid' LIKE '%@lid'
+ORDER BY user_integration_id,
+    platform_metadata->>'lid_jid',
+    updated_at DESC;
+SELECT merge_contact_identity(user_integration_id, lid_jid, pn_jid)
+FROM contact_identities;
+-- Index reply links per conversation so reply chains can be walked without
+-- scanning a conversation's messages
+CREATE INDEX idx_messages_conversation_reply ON messages (conversation_id, reply_to_message_id)
+WHERE reply_to_message_id IS NOT NULL;
+-- Let a user connect several integrations of the same type, e.g. two WhatsApp
+-- numbers. An external account still belongs to a single user
+-- (unique_external_id).
+-- WhatsApp integrations used to be stored under the paired device's JID
+-- ("<number>:<device>@s.whatsapp.net"). Key them by the account JID so that
+-- re-pairing a number updates its integration instead of adding another.
+-- Several integrations can map to the same account JID: the most recently
+-- updated one keeps the number and the others are disconnected, with
+-- metadata.superseded_by pointing at the one kept. They stay under their
+-- device JID, or under "<account JID>#superseded:<id>" if they already had
+-- the account JID.
+WITH ranked AS (
+    SELECT id,
+        regexp_replace(external_id, ':[0-9]+@', '@') AS account_jid,
+        ROW_NUMBER() OVER numbers AS rank,
+        FIRST_VALUE(id) OVER numbers AS kept_id
+    FROM user_integrations
+    WHERE integration_type = 'whatsapp'
+    WINDOW numbers AS (
+        PARTITION BY regexp_replace(external_id, ':[0-9]+@', '@')
+        ORDER BY updated_at DESC, id DESC
+    )
+)
+UPDATE user_integrations ui
+SET status = 'disconnected',
+    external_id = CASE
+        WHEN ui.external_id = r.account_jid THEN ui.external_id || '#superseded:' || ui.id
+        ELSE ui.external_id
+    END,
+    metadata = COALESCE(ui.metadata, '{}') || jsonb_build_object('superseded_by', r.kept_id),
+    updated_at = NOW()
+FROM ranked r
+WHERE ui.id = r.id
+    AND r.rank > 1;
+UPDATE user_integrations
+SET external_id = regexp_replace(external_id, ':[0-9]+@', '@')
+WHERE integration_type = 'whatsapp'
+    AND external_id ~ ':[0-9]+@'
+    AND NOT COALESCE(metadata, '{}') ? 'superseded_by';
+ALTER TABLE user_integrations DROP CONSTRAINT unique_user_integration;
+ALTER TABLE user_integrations
+ADD CONSTRAINT unique_user_integration UNIQUE (user_id, integration_type, external_id);
+-- Per-user webhook subscriptions that receive backend events over HTTP, and
+-- a record of every delivery for debugging
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT [] NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_event_seq BIGINT NOT NULL DEFAULT 0,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    disabled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_webhooks_user_id ON webhooks(user_id);
+CREATE INDEX idx_webhooks_enabled ON webhooks(id)
+WHERE enabled;
+COMMENT ON COLUMN webhooks.secret IS 'HMAC-SHA256 key used to sign delivery bodies';
+COMMENT ON COLUMN webhooks.event_types IS 'Event types to deliver; empty means all types';
+COMMENT ON COLUMN webhooks.last_event_seq IS 'Highest event seq queued for delivery';
+COMMENT ON COLUMN webhooks.consecutive_failures IS 'Failed delivery attempts since the last success; the webhook is disabled once this reaches the configured limit';
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_seq BIGINT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status_code INTEGER,
+    last_error TEXT,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_webhook_event UNIQUE (webhook_id, event_seq)
+);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at)
+WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id DESC);
+COMMENT ON COLUMN webhook_deliveries.payload IS 'Request body, captured when the delivery is queued so retention cannot remove it';
+COMMENT ON COLUMN webhook_deliveries.next_attempt_at IS 'When the delivery is next due; pushed forward while an attempt is in flight';
+-- Remember the idempotency key of each pairing so a retried
+-- CreateUserIntegration returns the integration it created instead of
+-- recording the pairing again
+CREATE TABLE integration_pairings (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key TEXT NOT NULL,
+    user_integration_id INTEGER NOT NULL REFERENCES user_integrations(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, idempotency_key)
+);
+CREATE INDEX idx_integration_pairings_integration ON integration_pairings(user_integration_id);
+COMMENT ON COLUMN integration_pairings.idempotency_key IS 'Chosen by the caller per pairing, e.g. the WhatsApp device JID';
+-- Full-text search over message content. The 'simple' configuration neither
+-- stems nor drops stop words, so it behaves the same for every language a
+-- chat may be written in.
+ALTER TABLE messages
+ADD COLUMN content_tsv tsvector GENERATED ALWAYS AS (to_tsvector('simple', coalesce(content, ''))) STORED;
+CREATE INDEX idx_messages_content_tsv ON messages USING GIN (content_tsv);
+-- When a conversation's pin, archive or mute state was last changed, by the
+-- clock of whoever changed it. A state update older than this is a late echo
+-- of an earlier change and is ignored.
+ALTER TABLE conversations
+ADD COLUMN state_changed_at TIMESTAMPTZ;
+-- Attachments point at their file by content hash, so one stored file in
+-- media_blobs serves every message it was sent or forwarded in. The hash is
+-- known from the message before the file is downloaded, so it isn't a
+-- foreign key.
+ALTER TABLE message_media
+ADD COLUMN content_hash TEXT;
+CREATE INDEX idx_message_media_content_hash ON message_media(content_hash)
+WHERE content_hash IS NOT NULL;
+-- A message's attachment is stored once however often the message is synced
+DELETE FROM message_media a USING message_media b
+WHERE a.message_id = b.message_id
+    AND a.media_type = b.media_type
+    AND (a.created_at, a.id) > (b.created_at, b.id);
+CREATE UNIQUE INDEX idx_message_media_message_type ON message_media(message_id, media_type);
+COMMENT ON COLUMN message_media.content_hash IS 'Hex SHA-256 of the file; matches media_blobs.content_hash once stored';
+COMMENT ON COLUMN media_blobs.storage_url IS 'Path of the file relative to the media directory';
+-- When the newest message the user has read in a conversation was sent.
+-- Incoming messages after it are unread. NULL while unknown, as for a
+-- conversation synced with unread messages: its synced unread count stands
+-- until the user reads it.
+ALTER TABLE conversations
+ADD COLUMN last_read_at TIMESTAMPTZ;
+-- Events removed by retention when archiving is enabled. Rows keep their
+-- original seq; sync never reads them, so the account's low-water mark still
+-- applies.
+CREATE TABLE events_archive (
+    seq           BIGINT PRIMARY KEY,
+    id            UUID NOT NULL,
+    ts            TIMESTAMPTZ NOT NULL,
+    type          TEXT NOT NULL,
+    account_id    TEXT NOT NULL,
+    device_id     TEXT,
+    convo_id      TEXT NOT NULL,
+    wa_message_id TEXT,
+    sender_jid    TEXT,
+    payload       JSONB NOT NULL,
+    attachment_ref JSONB,
+    archived_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_events_archive_account_seq ON events_archive (account_id, seq);
+COMMENT ON TABLE events_archive IS 'Events removed from the events table by retention, kept for audit and recovery';
+-- Events are keyed by account (the user ID) while synced data is keyed by
+-- user integration. Record the integration an event came through so the two
+-- can be cross-referenced; events from before this migration are backfilled
+-- only where the account has exactly one integration.
+ALTER TABLE events
+ADD COLUMN user_integration_id INTEGER REFERENCES user_integrations(id) ON DELETE SET NULL;
+
+ALTER TABLE events_archive
+ADD COLUMN user_integration_id INTEGER;
+
+CREATE INDEX idx_events_user_integration_seq ON events (user_integration_id, seq)
+WHERE user_integration_id IS NOT NULL;
+COMMENT ON COLUMN events.user_integration_id IS 'User integration the event came through; NULL when it is not tied to one';
+-- Disappearing messages. A conversation's timer applies to messages stored
+-- while it is on; each message records when it expires so the expiry worker
+-- can purge it without consulting the conversation.
+ALTER TABLE conversations
+ADD COLUMN disappearing_seconds INTEGER NOT NULL DEFAULT 0 CHECK (disappearing_seconds >= 0);
+
+ALTER TABLE messages
+ADD COLUMN expires_at TIMESTAMPTZ;
+
+CREATE INDEX idx_messages_expires_at ON messages (expires_at)
+WHERE expires_at IS NOT NULL;
+COMMENT ON COLUMN conversations.disappearing_seconds IS 'Disappearing messages timer in seconds; 0 when off';
+COMMENT ON COLUMN messages.expires_at IS 'When the message disappears and is purged; NULL for messages that are kept';
+-- Delivery and read receipts from the bridge advance sent outbox entries, so
+-- clients can tell a message reached the recipient.
+ALTER TABLE outbox DROP CONSTRAINT outbox_status_check;
+ALTER TABLE outbox ADD CONSTRAINT outbox_status_check
+    CHECK (status IN ('queued', 'sending', 'sent', 'delivered', 'read', 'failed', 'retry'));
+-- Mutes with an expiry are lifted by the mute expiry worker once mute_until
+-- passes; this index lets it find them without scanning every conversation.
+CREATE INDEX idx_conversations_mute_until ON conversations (mute_until)
+WHERE is_muted AND mute_until IS NOT NULL;
+-- Single-use tokens for resetting a forgotten password. Only a SHA-256 hash
+-- of each token is stored, so a leaked table can't be used to reset
+-- passwords. A token is spent once used_at is set.
+CREATE TABLE password_reset_tokens (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash  TEXT NOT NULL UNIQUE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_password_reset_tokens_user ON password_reset_tokens (user_id)
+WHERE used_at IS NULL;
+COMMENT ON TABLE password_reset_tokens IS 'Single-use password reset tokens, stored hashed';
+COMMENT ON COLUMN password_reset_tokens.token_hash IS 'Hex SHA-256 of the token sent to the user';
+COMMENT ON COLUMN password_reset_tokens.used_at IS 'When the token was used or revoked; NULL while it can still be used';
+-- Events are keyed by account_id, which is the ID of the user owning the
+-- account. Events written before that convention used other account IDs
+-- (such as the WhatsApp JID); map each of those to the user owning it so the
+-- API can check an account belongs to the caller. Account IDs that are user
+-- IDs need no mapping.
+CREATE TABLE event_accounts (
+    account_id  TEXT PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_event_accounts_user ON event_accounts (user_id);
+
+-- Legacy accounts keyed by an integration's external ID
+INSERT INTO event_accounts (account_id, user_id)
+SELECT DISTINCT e.account_id, ui.user_id
+FROM events e
+JOIN user_integrations ui ON ui.external_id = e.account_id
+WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id::text = e.account_id)
+ON CONFLICT (account_id) DO NOTHING;
+
+-- Any other legacy account whose events came through an integration
+INSERT INTO event_accounts (account_id, user_id)
+SELECT DISTINCT e.account_id, ui.user_id
+FROM events e
+JOIN user_integrations ui ON ui.id = e.user_integration_id
+WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id::text = e.account_id)
+ON CONFLICT (account_id) DO NOTHING;
+
+COMMENT ON TABLE event_accounts IS 'Owner of each legacy event account_id that is not a user ID';
+-- Outbox entries carry the message they send, so the worker builds the bridge
+-- request from the entry alone instead of reading its event back.
+-- server_msg_id still links an entry to its msg_out_pending event.
+ALTER TABLE outbox ADD COLUMN payload JSONB;
+
+-- Entries queued before carry their event's payload
+UPDATE outbox o
+SET payload = e.payload
+FROM events e
+WHERE e.seq = o.server_msg_id;
+
+COMMENT ON COLUMN outbox.payload IS 'The outbound message (a MessageOutPayload); null only for entries whose event was gone when the column was added';
+
+-- When a user's password was last reset. Access tokens issued before it are
+-- refused, so a reset signs out every existing session.
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN users.password_changed_at IS 'When the password was last reset; tokens issued before it are revoked. NULL if never reset';
+
+-- Webhook deliveries reference their event by seq instead of holding a copy of
+-- its payload; the worker reads the payload when it sends. Finished deliveries
+-- are deleted once they are past the webhook retention.
+ALTER TABLE webhook_deliveries DROP COLUMN payload;
+
+CREATE INDEX idx_webhook_deliveries_finished ON webhook_deliveries(updated_at)
+WHERE status <> 'pending';
+
+COMMENT ON COLUMN webhook_deliveries.event_seq IS 'The delivered event; a delivery whose event is gone when it is due is dropped';
+
+-- Expired disappearing messages have their archived events redacted by
+-- WhatsApp message ID, like their live events.
+CREATE INDEX idx_events_archive_wa_message_id ON events_archive (wa_message_id) WHERE wa_message_id IS NOT NULL;
+
+-- Record the migrations above so the backend sees the schema as current
+CREATE TABLE schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_migrations (version, name) VALUES
+    (1, '001_initial_schema'),
+    (2, '002_add_users'),
+    (3, '003_user_integrations'),
+    (4, '004_add_conversations_and_messages'),
+    (5, '005_fix_message_reply_constraint'),
+    (6, '006_add_sync_sequences'),
+    (7, '007_resolve_message_replies'),
+    (8, '008_pending_message_replies'),
+    (9, '009_poll_votes'),
+    (10, '010_event_retention'),
+    (11, '011_user_roles'),
+    (12, '012_integration_status_events'),
+    (13, '013_contact_identities'),
+    (14, '014_message_thread_index'),
+    (15, '015_multiple_integrations_per_type'),
+    (16, '016_webhooks'),
+    (17, '017_integration_pairings'),
+    (18, '018_message_search'),
+    (19, '019_conversation_state_changes'),
+    (20, '020_media_blob_dedup'),
+    (21, '021_conversation_read_marker'),
+    (22, '022_event_archive'),
+    (23, '023_event_integrations'),
+    (24, '024_disappearing_messages'),
+    (25, '025_outbox_delivery_status'),
+    (26, '026_mute_expiry'),
+    (27, '027_password_reset_tokens'),
+    (28, '028_event_accounts'),
+    (29, '029_outbox_payload'),
+    (30, '030_password_changed_at'),
+    (31, '031_webhook_delivery_event_refs'),
+    (32, '032_expired_message_redaction');
 -- Re-grant permissions after creating all tables
 GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA backend TO tennex;
 GRANT ALL PRIVILEGES ON ALL SEQUENCES IN SCHEMA backend TO tennex;
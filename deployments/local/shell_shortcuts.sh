@@ -94,6 +94,7 @@ function txmigrateall() {
         cd ${TENNEX_HOME}
         echo "📊 Running all database migrations..."
         for file in pkg/db/schema/*.sql; do
+            case "$file" in *.down.sql) continue ;; esac
             echo "Applying $file..."
             docker exec -i tennex-postgres psql -U tennex -d tennex < "$file"
         done
@@ -526,14 +527,17 @@ function txreplayshow() {
 function txreplay() {
     local SESSION_NAME=$1
     local RANGE=${2:-""}
+    local EXTRA_ARGS=("${@:3}")
     
     if [ -z "$SESSION_NAME" ]; then
-        echo "❌ Usage: txreplay <session-name> [range]"
+        echo "❌ Usage: txreplay <session-name> [range] [replay flags]"
         echo ""
         echo "Examples:"
         echo "  txreplay whatsapp-abc123-1234567890           # Replay entire session"
         echo "  txreplay whatsapp-abc123-1234567890 5         # Replay only recording #5"
         echo "  txreplay whatsapp-abc123-1234567890 1-10      # Replay recordings #1-10"
+        echo "  txreplay whatsapp-abc123-1234567890 \"\" --timing=recorded --speed=10"
+        echo "                                                # Keep original gaps, 10x faster"
         echo ""
         echo "💡 Run 'txrecord' to list available sessions"
         return 1
@@ -551,11 +555,12 @@ function txreplay() {
         echo "   Backend: $BACKEND_ADDR"
         echo ""
         
-        go run services/bridge/cmd/replay/main.go \
+        go run ./services/bridge/cmd/replay \
             --dir="$RECORDINGS_DIR" \
             --session="$SESSION_NAME" \
             --range="$RANGE" \
-            --backend="$BACKEND_ADDR"
+            --backend="$BACKEND_ADDR" \
+            "${EXTRA_ARGS[@]}"
     )
 }
 
@@ -565,14 +570,14 @@ function txreplaydocker() {
     local RANGE=${2:-""}
     
     if [ -z "$SESSION_NAME" ]; then
-        echo "❌ Usage: txreplaydocker <session-name> [range]"
+        echo "❌ Usage: txreplaydocker <session-name> [range] [replay flags]"
         echo "💡 This replays against the backend container (backend:6001)"
         return 1
     fi
     
     (
         export BACKEND_GRPC_ADDR="localhost:6001"
-        txreplay "$SESSION_NAME" "$RANGE"
+        txreplay "$SESSION_NAME" "$RANGE" "${@:3}"
     )
 }
 
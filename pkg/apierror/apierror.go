@@ -0,0 +1,47 @@
+// Package apierror describes errors the HTTP APIs report to their callers.
+package apierror
+
+// E is an error response: the status to respond with, what to tell the
+// caller, and the underlying cause
+type E struct {
+	Status int
+
+	// Machine-readable error code, e.g. "invalid_request"; optional
+	Code string
+
+	// Human-readable error message
+	Message string
+
+	// Extra fields for the response's details object
+	Details map[string]interface{}
+
+	// Underlying cause, logged and reported as details.details
+	Err error
+}
+
+// New creates an error response with a status and message
+func New(status int, message string) *E {
+	return &E{Status: status, Message: message}
+}
+
+// Wrap creates an error response caused by err, which may be nil
+func Wrap(status int, message string, err error) *E {
+	return &E{Status: status, Message: message, Err: err}
+}
+
+// WithCode sets the machine-readable error code
+func (e *E) WithCode(code string) *E {
+	e.Code = code
+	return e
+}
+
+func (e *E) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *E) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,49 @@
+// Package bootstrap holds the startup code the services share: loading their
+// configuration, building their logger and connecting to their dependencies,
+// so a fix to any of it applies to every service.
+package bootstrap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/env"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+)
+
+// ConfigFile is the optional YAML file LoadConfig reads
+const ConfigFile = "config.yaml"
+
+// LoadConfig fills config, a pointer to a struct with koanf tags, from
+// ConfigFile if it exists and then from the environment. Fields neither sets
+// keep the defaults the caller filled in. Each of envPrefixes is read in turn,
+// later ones overriding earlier ones, and maps PREFIX_NATS_URL to nats.url.
+func LoadConfig(config any, envPrefixes ...string) error {
+	k := koanf.New(".")
+
+	// A missing file is fine; the defaults and environment cover everything
+	_ = k.Load(file.Provider(ConfigFile), yaml.Parser())
+
+	for _, prefix := range envPrefixes {
+		if err := k.Load(env.Provider(prefix, ".", func(s string) string {
+			return envKey(prefix, s)
+		}), nil); err != nil {
+			return fmt.Errorf("error loading env config: %w", err)
+		}
+	}
+
+	if err := k.Unmarshal("", config); err != nil {
+		return fmt.Errorf("error unmarshaling config: %w", err)
+	}
+	return nil
+}
+
+// envKey converts an environment variable like TENNEX_HTTP_PORT to its config
+// key, http.port
+func envKey(prefix, name string) string {
+	key := strings.TrimPrefix(name, prefix)
+	key = strings.ToLower(key)
+	return strings.ReplaceAll(key, "_", ".")
+}
@@ -0,0 +1,38 @@
+package bootstrap
+
+import "testing"
+
+type testConfig struct {
+	HTTP struct {
+		Port int    `koanf:"port"`
+		Host string `koanf:"host"`
+	} `koanf:"http"`
+	NATS struct {
+		URL string `koanf:"url"`
+	} `koanf:"nats"`
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Chdir(t.TempDir()) // No config file
+	t.Setenv("TENNEX_SVC_HTTP_PORT", "7000")
+	t.Setenv("TENNEX_SVC_NATS_URL", "nats://svc:4222")
+	t.Setenv("TENNEX_NATS_URL", "nats://shared:4222")
+
+	config := &testConfig{}
+	config.HTTP.Port = 6000
+	config.HTTP.Host = "0.0.0.0"
+	if err := LoadConfig(config, "TENNEX_SVC_", "TENNEX_"); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if config.HTTP.Port != 7000 {
+		t.Errorf("expected the environment to set http.port, got %d", config.HTTP.Port)
+	}
+	if config.HTTP.Host != "0.0.0.0" {
+		t.Errorf("expected http.host to keep its default, got %q", config.HTTP.Host)
+	}
+	// Later prefixes override earlier ones
+	if config.NATS.URL != "nats://shared:4222" {
+		t.Errorf("expected TENNEX_NATS_URL to win, got %q", config.NATS.URL)
+	}
+}
@@ -0,0 +1,29 @@
+package bootstrap
+
+import "go.uber.org/zap"
+
+// NewLogger builds a service's logger: JSON in production, human-readable in
+// development. Unknown levels log at info.
+func NewLogger(level string, jsonFormat bool) (*zap.Logger, error) {
+	var config zap.Config
+	if jsonFormat {
+		config = zap.NewProductionConfig()
+	} else {
+		config = zap.NewDevelopmentConfig()
+	}
+
+	switch level {
+	case "debug":
+		config.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
+	case "info":
+		config.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
+	case "warn":
+		config.Level = zap.NewAtomicLevelAt(zap.WarnLevel)
+	case "error":
+		config.Level = zap.NewAtomicLevelAt(zap.ErrorLevel)
+	default:
+		config.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
+	}
+
+	return config.Build()
+}
@@ -0,0 +1,31 @@
+package bootstrap
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewLoggerLevels(t *testing.T) {
+	for _, tc := range []struct {
+		level string
+		want  zapcore.Level
+	}{
+		{"debug", zap.DebugLevel},
+		{"warn", zap.WarnLevel},
+		{"error", zap.ErrorLevel},
+		{"loud", zap.InfoLevel}, // Unknown levels log at info
+	} {
+		for _, jsonFormat := range []bool{false, true} {
+			logger, err := NewLogger(tc.level, jsonFormat)
+			if err != nil {
+				t.Fatalf("NewLogger(%q, %v): %v", tc.level, jsonFormat, err)
+			}
+			core := logger.Core()
+			if !core.Enabled(tc.want) || (tc.want > zap.DebugLevel && core.Enabled(tc.want-1)) {
+				t.Errorf("NewLogger(%q, %v): expected logging from %s up", tc.level, jsonFormat, tc.want)
+			}
+		}
+	}
+}
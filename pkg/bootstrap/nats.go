@@ -0,0 +1,48 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+
+	"github.com/tennex/pkg/reconnect"
+)
+
+// ConnectNATS connects to NATS, retrying for as long as wait allows. A dropped
+// connection is retried forever, backing off between attempts so a recovering
+// server isn't hammered. Close it with natsx.Drain.
+func ConnectNATS(ctx context.Context, url string, backoff reconnect.Backoff, wait DependencyWait, logger *zap.Logger) (*nats.Conn, error) {
+	var nc *nats.Conn
+	err := WaitForDependency(ctx, "nats", wait, func(context.Context) error {
+		var err error
+		nc, err = nats.Connect(url,
+			nats.MaxReconnects(-1),
+			nats.CustomReconnectDelay(backoff.Delay),
+			nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
+				logger.Warn("NATS disconnected", zap.Error(err))
+			}),
+			nats.ReconnectHandler(func(nc *nats.Conn) {
+				logger.Info("NATS reconnected", zap.String("url", nc.ConnectedUrl()))
+			}),
+			nats.ClosedHandler(func(nc *nats.Conn) {
+				logger.Info("NATS connection closed", zap.Error(nc.LastError()))
+			}),
+			nats.ErrorHandler(func(nc *nats.Conn, sub *nats.Subscription, err error) {
+				fields := []zap.Field{zap.Error(err)}
+				if sub != nil {
+					fields = append(fields, zap.String("subject", sub.Subject))
+				}
+				logger.Error("NATS asynchronous error", fields...)
+			}),
+		)
+		return err
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	logger.Info("NATS connection established", zap.String("url", url))
+	return nc, nil
+}
@@ -0,0 +1,66 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DependencyWait bounds how long startup retries a dependency like Postgres
+// or NATS, so a service can start before it (e.g. under docker-compose)
+type DependencyWait struct {
+	Timeout        time.Duration // Total time to keep retrying; <= 0 tries once
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// NewDependencyWait returns a wait retrying for up to timeout, with the
+// default backoff between attempts
+func NewDependencyWait(timeout time.Duration) DependencyWait {
+	return DependencyWait{
+		Timeout:        timeout,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+	}
+}
+
+// WaitForDependency calls connect until it succeeds, doubling the pause
+// between attempts up to wait.MaxBackoff. Once wait.Timeout has passed it
+// returns the errors of every attempt.
+func WaitForDependency(ctx context.Context, name string, wait DependencyWait, connect func(context.Context) error, logger *zap.Logger) error {
+	deadline := time.Now().Add(wait.Timeout)
+	backoff := wait.InitialBackoff
+	var errs []error
+
+	for attempt := 1; ; attempt++ {
+		err := connect(ctx)
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("Dependency ready", zap.String("dependency", name), zap.Int("attempts", attempt))
+			}
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("attempt %d: %w", attempt, err))
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%s not ready after %d attempts in %s: %w", name, attempt, wait.Timeout, errors.Join(errs...))
+		}
+		delay := min(backoff, remaining)
+		logger.Warn("Dependency not ready, retrying",
+			zap.String("dependency", name),
+			zap.Int("attempt", attempt),
+			zap.Duration("retry_in", delay),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s not ready after %d attempts: %w", name, attempt, errors.Join(append(errs, ctx.Err())...))
+		case <-time.After(delay):
+		}
+		backoff = min(backoff*2, wait.MaxBackoff)
+	}
+}
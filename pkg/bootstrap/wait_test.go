@@ -0,0 +1,50 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestWaitForDependencyRetries(t *testing.T) {
+	wait := DependencyWait{Timeout: time.Second, InitialBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}
+
+	attempts := 0
+	dial := func(context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	if err := WaitForDependency(context.Background(), "postgres", wait, dial, zap.NewNop()); err != nil {
+		t.Fatalf("WaitForDependency: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestWaitForDependencyGivesUp(t *testing.T) {
+	wait := DependencyWait{Timeout: 20 * time.Millisecond, InitialBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}
+
+	attempts := 0
+	dial := func(context.Context) error {
+		attempts++
+		return errors.New("connection refused")
+	}
+	err := WaitForDependency(context.Background(), "nats", wait, dial, zap.NewNop())
+	if err == nil {
+		t.Fatal("expected an error once the wait expires")
+	}
+	if attempts < 2 {
+		t.Errorf("expected several attempts, got %d", attempts)
+	}
+	if !strings.HasPrefix(err.Error(), "nats not ready after") || !strings.Contains(err.Error(), "attempt 1: connection refused") {
+		t.Errorf("expected an error listing every attempt, got %q", err)
+	}
+}
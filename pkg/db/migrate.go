@@ -0,0 +1,269 @@
+// Package db embeds the backend's schema migrations and applies them.
+//
+// Migrations are the numbered files in schema/: NNN_name.sql applies a change
+// and NNN_name.down.sql, if present, reverts it. Applied versions are recorded
+// in schema_migrations.
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+//go:embed schema/*.sql
+var schemaFS embed.FS
+
+// migrationLockID is the advisory lock held while a migration is applied or
+// reverted, so backends starting together don't apply the same one twice
+const migrationLockID = 7_104_771_402
+
+// ErrNoMigrations is returned by MigrateDown when no migration is applied
+var ErrNoMigrations = errors.New("no migrations applied")
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string // Empty if the migration can't be reverted
+}
+
+// MigrationState is a migration and when it was applied, if it was
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// DB runs migrations (implemented by *pgxpool.Pool and *pgx.Conn)
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// Migrations returns the embedded migrations by version
+func Migrations() ([]Migration, error) {
+	files, err := fs.Glob(schemaFS, "schema/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, file := range files {
+		base := path.Base(file)
+		down := strings.HasSuffix(base, ".down.sql")
+		name := strings.TrimSuffix(strings.TrimSuffix(base, ".sql"), ".down")
+
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s doesn't start with a version number", base)
+		}
+		data, err := schemaFS.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", base, err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", migration.Name, name, version)
+		}
+		if down {
+			migration.down = string(data)
+		} else {
+			migration.up = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" {
+			return nil, fmt.Errorf("migration %s has no up file", migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrationStatus returns every embedded migration and whether it is applied
+func MigrationStatus(ctx context.Context, db DB) ([]MigrationState, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, len(migrations))
+	for i, migration := range migrations {
+		states[i] = MigrationState{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			states[i].AppliedAt = &appliedAt
+		}
+	}
+	return states, nil
+}
+
+// PendingMigrations returns the embedded migrations that aren't applied
+func PendingMigrations(ctx context.Context, db DB) ([]Migration, error) {
+	states, err := MigrationStatus(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, state := range states {
+		if state.AppliedAt == nil {
+			pending = append(pending, state.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// MigrateUp applies every pending migration in order, each in its own
+// transaction, and returns the ones it applied
+func MigrateUp(ctx context.Context, db DB) ([]Migration, error) {
+	pending, err := PendingMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range pending {
+		ran, err := migrate(ctx, db, migration, true)
+		if err != nil {
+			return applied, err
+		}
+		if ran {
+			applied = append(applied, migration)
+		}
+	}
+	return applied, nil
+}
+
+// MigrateDown reverts the latest applied migration and returns it. It returns
+// ErrNoMigrations if none is applied.
+func MigrateDown(ctx context.Context, db DB) (Migration, error) {
+	states, err := MigrationStatus(ctx, db)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	for i := len(states) - 1; i >= 0; i-- {
+		if states[i].AppliedAt == nil {
+			continue
+		}
+		migration := states[i].Migration
+		if migration.down == "" {
+			return Migration{}, fmt.Errorf("migration %s can't be reverted", migration.Name)
+		}
+		if _, err := migrate(ctx, db, migration, false); err != nil {
+			return Migration{}, err
+		}
+		return migration, nil
+	}
+	return Migration{}, ErrNoMigrations
+}
+
+// migrate applies or reverts one migration under the migration lock. It
+// reports false if another process got there first.
+func migrate(ctx context.Context, db DB, migration Migration, up bool) (bool, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin migration %s: %w", migration.Name, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Backfills can run far longer than the pool's statement_timeout allows
+	// for serving queries
+	if _, err := tx.Exec(ctx, `SET LOCAL statement_timeout = 0`); err != nil {
+		return false, fmt.Errorf("failed to lift the statement timeout: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, int64(migrationLockID)); err != nil {
+		return false, fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+	var applied bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, migration.Version).Scan(&applied)
+	if err != nil {
+		return false, fmt.Errorf("failed to check migration %s: %w", migration.Name, err)
+	}
+	if applied == up {
+		return false, nil
+	}
+
+	if up {
+		if _, err := tx.Exec(ctx, migration.up); err != nil {
+			return false, fmt.Errorf("failed to apply migration %s: %w", migration.Name, err)
+		}
+		_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name)
+	} else {
+		if _, err := tx.Exec(ctx, migration.down); err != nil {
+			return false, fmt.Errorf("failed to revert migration %s: %w", migration.Name, err)
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record migration %s: %w", migration.Name, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit migration %s: %w", migration.Name, err)
+	}
+	return true, nil
+}
+
+func ensureMigrationsTable(ctx context.Context, db DB) error {
+	_, err := db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func appliedMigrations(ctx context.Context, db DB) (map[int64]time.Time, error) {
+	rows, err := db.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return applied, nil
+}
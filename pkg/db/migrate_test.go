@@ -0,0 +1,238 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestMigrationsAreNumberedAndReversible(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, migration := range migrations {
+		if migration.Version != int64(i+1) {
+			t.Errorf("expected migration %d, got %s", i+1, migration.Name)
+		}
+		if migration.down == "" {
+			t.Errorf("migration %s has no down file", migration.Name)
+		}
+	}
+}
+
+// localInitScript is the schema the local stack's Postgres starts with. It
+// mirrors the migrations and records them, as the backend refuses to serve a
+// schema that is behind unless it migrates it itself.
+const localInitScript = "../../deployments/local/init/02-init-backend.sql"
+
+func TestLocalInitScriptRecordsEveryMigration(t *testing.T) {
+	script, err := os.ReadFile(localInitScript)
+	if err != nil {
+		t.Fatalf("failed to read the local init script: %v", err)
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations: %v", err)
+	}
+	for _, migration := range migrations {
+		row := fmt.Sprintf("(%d, '%s')", migration.Version, migration.Name)
+		if !strings.Contains(string(script), row) {
+			t.Errorf("the local init script doesn't record %s; mirror the migration there", migration.Name)
+		}
+	}
+}
+
+// setupSchema returns a pool on a fresh, empty Postgres schema. Set
+// TENNEX_TEST_DATABASE_URL to run tests that need a database.
+func setupSchema(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	databaseURL := os.Getenv("TENNEX_TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TENNEX_TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	schema := fmt.Sprintf("migrate_%d", time.Now().UnixNano())
+
+	admin, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+		admin.Close(context.Background())
+	})
+
+	poolConfig, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		t.Fatalf("invalid database URL: %v", err)
+	}
+	poolConfig.ConnConfig.RuntimeParams["search_path"] = schema
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	pool := setupSchema(t)
+	ctx := context.Background()
+
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations: %v", err)
+	}
+
+	tableExists := func(table string) bool {
+		t.Helper()
+		var exists bool
+		if err := pool.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, table).Scan(&exists); err != nil {
+			t.Fatalf("failed to look up %s: %v", table, err)
+		}
+		return exists
+	}
+
+	applied, err := MigrateUp(ctx, pool)
+	if err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Fatalf("expected %d migrations applied, got %d", len(migrations), len(applied))
+	}
+	for _, table := range []string{"events", "users", "user_integrations", "conversations", "messages", "webhooks", "events_archive"} {
+		if !tableExists(table) {
+			t.Errorf("expected table %s after migrating up", table)
+		}
+	}
+
+	// Running up again finds nothing to do
+	if applied, err := MigrateUp(ctx, pool); err != nil || len(applied) != 0 {
+		t.Fatalf("expected a second MigrateUp to apply nothing, got %d: %v", len(applied), err)
+	}
+	if pending, err := PendingMigrations(ctx, pool); err != nil || len(pending) != 0 {
+		t.Fatalf("expected no pending migrations, got %d: %v", len(pending), err)
+	}
+
+	// Down reverts one migration at a time, newest first
+	reverted, err := MigrateDown(ctx, pool)
+	if err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if latest := migrations[len(migrations)-1]; reverted.Version != latest.Version {
+		t.Fatalf("expected %s reverted first, got %s", latest.Name, reverted.Name)
+	}
+	if pending, err := PendingMigrations(ctx, pool); err != nil || len(pending) != 1 || pending[0].Version != reverted.Version {
+		t.Fatalf("expected only %s pending, got %+v: %v", reverted.Name, pending, err)
+	}
+
+	for range migrations[1:] {
+		if _, err := MigrateDown(ctx, pool); err != nil {
+			t.Fatalf("MigrateDown: %v", err)
+		}
+	}
+	if _, err := MigrateDown(ctx, pool); !errors.Is(err, ErrNoMigrations) {
+		t.Fatalf("expected ErrNoMigrations once everything is reverted, got %v", err)
+	}
+	for _, table := range []string{"events", "users", "user_integrations", "conversations"} {
+		if tableExists(table) {
+			t.Errorf("expected table %s dropped after migrating down", table)
+		}
+	}
+}
+
+func TestMigrationKeysWhatsAppIntegrationsByAccountJID(t *testing.T) {
+	pool := setupSchema(t)
+	ctx := context.Background()
+
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations: %v", err)
+	}
+	if err := ensureMigrationsTable(ctx, pool); err != nil {
+		t.Fatalf("failed to create schema_migrations: %v", err)
+	}
+	var next Migration
+	for _, migration := range migrations {
+		if migration.Version >= 15 {
+			next = migration
+			break
+		}
+		if _, err := migrate(ctx, pool, migration, true); err != nil {
+			t.Fatalf("failed to apply %s: %v", migration.Name, err)
+		}
+	}
+	if next.Version != 15 {
+		t.Fatalf("expected migration 15 next, got %s", next.Name)
+	}
+
+	// Three users paired the same number from different devices, the oldest
+	// under the account JID; another number has a single device
+	integration := func(username, externalID string, updatedAt time.Time) int32 {
+		t.Helper()
+		var id int32
+		err := pool.QueryRow(ctx, `
+			WITH u AS (
+				INSERT INTO users (username, email, password_hash)
+				VALUES ($1, $1 || '@example.com', 'x')
+				RETURNING id
+			)
+			INSERT INTO user_integrations (user_id, integration_type, external_id, status, updated_at)
+			SELECT id, 'whatsapp', $2, 'connected', $3 FROM u
+			RETURNING id`, username, externalID, updatedAt).Scan(&id)
+		if err != nil {
+			t.Fatalf("failed to insert integration %s: %v", externalID, err)
+		}
+		return id
+	}
+	now := time.Now()
+	oldest := integration("oldest", "972501111111@s.whatsapp.net", now.Add(-3*time.Hour))
+	older := integration("older", "972501111111:3@s.whatsapp.net", now.Add(-2*time.Hour))
+	newest := integration("newest", "972501111111:7@s.whatsapp.net", now.Add(-time.Hour))
+	single := integration("single", "972502222222:1@s.whatsapp.net", now)
+
+	if _, err := migrate(ctx, pool, next, true); err != nil {
+		t.Fatalf("failed to apply %s: %v", next.Name, err)
+	}
+
+	type state struct {
+		externalID   string
+		status       string
+		supersededBy int32 // 0 if kept
+	}
+	want := map[int32]state{
+		newest: {"972501111111@s.whatsapp.net", "connected", 0},
+		single: {"972502222222@s.whatsapp.net", "connected", 0},
+		older:  {"972501111111:3@s.whatsapp.net", "disconnected", newest},
+		oldest: {fmt.Sprintf("972501111111@s.whatsapp.net#superseded:%d", oldest), "disconnected", newest},
+	}
+	for id, want := range want {
+		var got state
+		err := pool.QueryRow(ctx, `
+			SELECT external_id, status, COALESCE((metadata->>'superseded_by')::int, 0)
+			FROM user_integrations WHERE id = $1`, id).Scan(&got.externalID, &got.status, &got.supersededBy)
+		if err != nil {
+			t.Fatalf("failed to read integration %d: %v", id, err)
+		}
+		if got != want {
+			t.Errorf("integration %d: expected %+v, got %+v", id, want, got)
+		}
+	}
+}
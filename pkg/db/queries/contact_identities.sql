@@ -0,0 +1,28 @@
+-- Contact identity (LID to phone-number JID) queries
+-- name: UpsertContactIdentity :execrows
+-- Record a mapping. Returns 0 when it was already known, so callers can skip
+-- merging.
+INSERT INTO contact_identities (user_integration_id, lid_jid, pn_jid)
+VALUES (
+        @user_integration_id::integer,
+        @lid_jid::text,
+        @pn_jid::text
+    ) ON CONFLICT (user_integration_id, lid_jid) DO
+UPDATE
+SET pn_jid = EXCLUDED.pn_jid,
+    updated_at = NOW()
+WHERE contact_identities.pn_jid <> EXCLUDED.pn_jid;
+-- name: GetContactIdentityByLID :one
+-- Resolve a LID to the phone-number JID it belongs to
+SELECT pn_jid
+FROM contact_identities
+WHERE user_integration_id = @user_integration_id::integer
+    AND lid_jid = @lid_jid::text;
+-- name: MergeContactIdentity :one
+-- Move conversations, messages and contacts stored under a LID to its
+-- phone-number JID. Returns true when a LID conversation was merged.
+SELECT merge_contact_identity(
+        @user_integration_id::integer,
+        @lid_jid::text,
+        @pn_jid::text
+    )::bool AS merged;
@@ -1,6 +1,7 @@
 -- Contacts table queries
 -- Integration-specific contact management
 -- name: UpsertContact :one
+-- Create or update a contact. An empty avatar_url means it has no avatar.
 INSERT INTO contacts (
         user_integration_id,
         external_contact_id,
@@ -28,7 +29,7 @@ VALUES (
         @is_blocked::bool,
         @is_favorite::bool,
         @last_seen::timestamptz,
-        @avatar_url::text,
+        NULLIF(@avatar_url::text, ''),
         @platform_metadata::jsonb
     ) ON CONFLICT (user_integration_id, external_contact_id) DO
 UPDATE
@@ -42,6 +43,11 @@ SET display_name = EXCLUDED.display_name,
     last_seen = EXCLUDED.last_seen,
     avatar_url = EXCLUDED.avatar_url,
     platform_metadata = EXCLUDED.platform_metadata,
+    -- A new avatar is synced to clients
+    seq = CASE
+        WHEN contacts.avatar_url IS DISTINCT FROM EXCLUDED.avatar_url THEN nextval(pg_get_serial_sequence('contacts', 'seq'))
+        ELSE contacts.seq
+    END,
     updated_at = NOW()
 RETURNING id,
     user_integration_id,
@@ -118,6 +124,56 @@ SELECT id,
 FROM contacts
 WHERE user_integration_id = $1::int
 ORDER BY display_name ASC NULLS LAST;
+-- name: ListContactsByName :many
+-- List an integration's contacts alphabetically by name for contact pickers,
+-- optionally only those whose name, first name or last name starts with a
+-- prefix. Contacts without a name are listed by phone number, then by ID.
+SELECT id,
+    external_contact_id,
+    (
+        COALESCE(
+            NULLIF(btrim(display_name), ''),
+            NULLIF(btrim(concat_ws(' ', first_name, last_name)), ''),
+            NULLIF(phone_number, ''),
+            external_contact_id
+        )
+    )::text AS name,
+    display_name,
+    first_name,
+    last_name,
+    phone_number,
+    username,
+    avatar_url,
+    is_favorite,
+    is_blocked
+FROM contacts
+WHERE user_integration_id = @user_integration_id::int
+    AND (
+        @name_prefix::text = ''
+        OR starts_with(
+            lower(
+                COALESCE(
+                    NULLIF(btrim(display_name), ''),
+                    NULLIF(btrim(concat_ws(' ', first_name, last_name)), ''),
+                    NULLIF(phone_number, ''),
+                    external_contact_id
+                )
+            ),
+            lower(@name_prefix::text)
+        )
+        OR starts_with(lower(first_name), lower(@name_prefix::text))
+        OR starts_with(lower(last_name), lower(@name_prefix::text))
+    )
+ORDER BY lower(
+        COALESCE(
+            NULLIF(btrim(display_name), ''),
+            NULLIF(btrim(concat_ws(' ', first_name, last_name)), ''),
+            NULLIF(phone_number, ''),
+            external_contact_id
+        )
+    ),
+    id
+LIMIT @limit_count::int OFFSET @offset_count::int;
 -- name: ListFavoriteContacts :many
 SELECT id,
     user_integration_id,
@@ -46,6 +46,58 @@ RETURNING id,
     platform_metadata,
     created_at,
     updated_at;
+-- name: UpsertConversationParticipants :exec
+-- Upsert many participants of one conversation in a single statement.
+-- The arrays are parallel and must not repeat an external_user_id.
+INSERT INTO conversation_participants (
+        conversation_id,
+        external_user_id,
+        integration_type,
+        display_name,
+        role,
+        is_active,
+        joined_at,
+        left_at,
+        added_by_external_id,
+        platform_metadata
+    )
+SELECT @conversation_id::uuid,
+    p.external_user_id,
+    @integration_type::text,
+    p.display_name,
+    p.role,
+    p.is_active,
+    p.joined_at,
+    p.left_at,
+    p.added_by_external_id,
+    p.platform_metadata
+FROM unnest(
+        @external_user_ids::text [],
+        @display_names::text [],
+        @roles::text [],
+        @is_active::bool [],
+        @joined_at::timestamptz [],
+        @left_at::timestamptz [],
+        @added_by_external_ids::text [],
+        @platform_metadata::jsonb []
+    ) AS p(
+        external_user_id,
+        display_name,
+        role,
+        is_active,
+        joined_at,
+        left_at,
+        added_by_external_id,
+        platform_metadata
+    ) ON CONFLICT (conversation_id, external_user_id) DO
+UPDATE
+SET display_name = EXCLUDED.display_name,
+    role = EXCLUDED.role,
+    is_active = EXCLUDED.is_active,
+    left_at = EXCLUDED.left_at,
+    added_by_external_id = EXCLUDED.added_by_external_id,
+    platform_metadata = EXCLUDED.platform_metadata,
+    updated_at = NOW();
 -- name: GetConversationParticipant :one
 SELECT id,
     conversation_id,
@@ -185,6 +237,39 @@ SET role = $3::text,
     updated_at = NOW()
 WHERE conversation_id = $1::uuid
     AND external_user_id = $2::text;
+-- name: SetParticipantRole :one
+-- Set a participant's role, adding them to the conversation if they aren't
+-- known yet. Returns the role they had, empty for a new participant; no row
+-- is returned when the role didn't change.
+WITH previous AS (
+    SELECT role
+    FROM conversation_participants
+    WHERE conversation_id = @conversation_id::uuid
+        AND external_user_id = @external_user_id::text
+)
+INSERT INTO conversation_participants (
+        conversation_id,
+        external_user_id,
+        integration_type,
+        role
+    )
+VALUES (
+        @conversation_id::uuid,
+        @external_user_id::text,
+        @integration_type::text,
+        @role::text
+    ) ON CONFLICT (conversation_id, external_user_id) DO
+UPDATE
+SET role = EXCLUDED.role,
+    updated_at = NOW()
+WHERE conversation_participants.role <> EXCLUDED.role
+RETURNING COALESCE(
+        (
+            SELECT role
+            FROM previous
+        ),
+        ''
+    )::text AS previous_role;
 -- name: UpdateParticipantDisplayName :exec
 UPDATE conversation_participants
 SET display_name = $3::text,
@@ -1,6 +1,11 @@
 -- Conversations table queries
 -- Platform-agnostic conversation/chat/channel management
 -- name: UpsertConversation :one
+-- Create or update a conversation. Updates older than the stored last_activity_at
+-- are ignored (no row is returned), so an out-of-order history sync batch can't
+-- overwrite fresher live state. The platform's message counters only seed a new
+-- conversation; from then on the backend keeps them as messages are stored.
+-- An empty avatar_url means it has no avatar.
 INSERT INTO conversations (
         user_integration_id,
         external_conversation_id,
@@ -20,6 +25,8 @@ INSERT INTO conversations (
         total_message_count,
         last_message_at,
         last_activity_at,
+        last_read_at,
+        disappearing_seconds,
         platform_metadata
     )
 VALUES (
@@ -29,7 +36,7 @@ VALUES (
         @conversation_type::text,
         @name::text,
         @description::text,
-        @avatar_url::text,
+        NULLIF(@avatar_url::text, ''),
         @is_archived::bool,
         @is_pinned::bool,
         @is_muted::bool,
@@ -41,6 +48,8 @@ VALUES (
         @total_message_count::int,
         @last_message_at::timestamptz,
         @last_activity_at::timestamptz,
+        sqlc.narg(last_read_at)::timestamptz,
+        @disappearing_seconds::int,
         @platform_metadata::jsonb
     ) ON CONFLICT (user_integration_id, external_conversation_id) DO
 UPDATE
@@ -54,13 +63,13 @@ SET conversation_type = EXCLUDED.conversation_type,
     mute_until = EXCLUDED.mute_until,
     is_read_only = EXCLUDED.is_read_only,
     is_locked = EXCLUDED.is_locked,
-    unread_count = EXCLUDED.unread_count,
-    unread_mention_count = EXCLUDED.unread_mention_count,
-    total_message_count = EXCLUDED.total_message_count,
     last_message_at = EXCLUDED.last_message_at,
     last_activity_at = EXCLUDED.last_activity_at,
+    disappearing_seconds = EXCLUDED.disappearing_seconds,
     platform_metadata = EXCLUDED.platform_metadata,
     updated_at = NOW()
+WHERE conversations.last_activity_at IS NULL
+    OR EXCLUDED.last_activity_at >= conversations.last_activity_at
 RETURNING id,
     user_integration_id,
     external_conversation_id,
@@ -108,6 +117,20 @@ SELECT id,
     updated_at
 FROM conversations
 WHERE id = $1::uuid;
+-- name: GetUserConversationState :one
+SELECT c.id,
+    c.user_integration_id,
+    c.external_conversation_id,
+    c.is_archived,
+    c.is_pinned,
+    c.is_muted,
+    c.mute_until,
+    ui.integration_type,
+    ui.status AS integration_status
+FROM conversations c
+    JOIN user_integrations ui ON ui.id = c.user_integration_id
+WHERE c.id = @conversation_id::uuid
+    AND ui.user_id = @user_id::uuid;
 -- name: ListUserIntegrationConversations :many
 SELECT id,
     user_integration_id,
@@ -234,6 +257,101 @@ SET unread_count = unread_count + 1,
     updated_at = NOW()
 WHERE user_integration_id = $1::int
     AND external_conversation_id = $2::text;
+-- name: CountNewMessage :exec
+-- Adds a newly stored message to its conversation's counters. An incoming
+-- message is unread when it is newer than the read marker; without a marker,
+-- only live messages are, as the platform's unread count covers the history.
+-- Status updates are never unread.
+UPDATE conversations
+SET total_message_count = total_message_count + 1,
+    unread_count = unread_count + CASE
+        WHEN @incoming::bool
+        AND external_conversation_id <> 'status@broadcast'
+        AND COALESCE(
+            @message_timestamp::timestamptz > last_read_at,
+            @live::bool
+        ) THEN 1
+        ELSE 0
+    END,
+    updated_at = NOW()
+WHERE id = @conversation_id::uuid;
+-- name: MarkConversationRead :one
+-- Move a user's conversation's read marker up to the newest of the given
+-- messages, count as unread only the incoming messages after it and clear its
+-- mentions. No row is returned when none of the messages are stored.
+WITH marker AS (
+    SELECT c.id,
+        MAX(m.timestamp) AS read_at
+    FROM conversations c
+        JOIN user_integrations ui ON ui.id = c.user_integration_id
+        JOIN messages m ON m.conversation_id = c.id
+    WHERE ui.user_id = @user_id::uuid
+        AND ui.integration_type = @integration_type::text
+        AND c.external_conversation_id = @external_conversation_id::text
+        AND m.external_message_id = ANY(@external_message_ids::text [])
+    GROUP BY c.id
+)
+UPDATE conversations c
+SET last_read_at = GREATEST(c.last_read_at, marker.read_at),
+    unread_count = (
+        SELECT COUNT(*)
+        FROM messages m
+        WHERE m.conversation_id = c.id
+            AND m.is_from_me = false
+            AND m.timestamp > GREATEST(c.last_read_at, marker.read_at)
+    ),
+    unread_mention_count = 0,
+    updated_at = NOW()
+FROM marker
+WHERE c.id = marker.id
+RETURNING c.id,
+    c.unread_count,
+    c.last_read_at;
+-- name: RecountConversation :one
+-- Recompute a conversation's counters from its stored messages. Without a read
+-- marker the platform's unread count is kept, capped at the incoming messages.
+-- Status updates are never unread.
+WITH previous AS (
+    SELECT id,
+        total_message_count,
+        unread_count
+    FROM conversations
+    WHERE id = @conversation_id::uuid
+    FOR UPDATE
+)
+UPDATE conversations c
+SET total_message_count = (
+        SELECT COUNT(*)
+        FROM messages m
+        WHERE m.conversation_id = c.id
+    ),
+    unread_count = CASE
+        WHEN c.external_conversation_id = 'status@broadcast' THEN 0
+        WHEN c.last_read_at IS NULL THEN LEAST(
+            c.unread_count,
+            (
+                SELECT COUNT(*)
+                FROM messages m
+                WHERE m.conversation_id = c.id
+                    AND m.is_from_me = false
+            )
+        )
+        ELSE (
+            SELECT COUNT(*)
+            FROM messages m
+            WHERE m.conversation_id = c.id
+                AND m.is_from_me = false
+                AND m.timestamp > c.last_read_at
+        )
+    END,
+    updated_at = NOW()
+FROM previous p
+WHERE c.id = p.id
+RETURNING p.total_message_count AS previous_total_message_count,
+    p.unread_count AS previous_unread_count,
+    c.total_message_count,
+    c.unread_count,
+    c.last_read_at;
 -- name: UpdateConversationMessageCount :exec
 UPDATE conversations
 SET total_message_count = $3::int,
@@ -284,15 +402,46 @@ SET is_muted = false,
     updated_at = NOW()
 WHERE user_integration_id = $1::int
     AND external_conversation_id = $2::text;
--- name: UpdateConversationState :exec
-UPDATE conversations
-SET is_archived = @is_archived::bool,
-    is_pinned = @is_pinned::bool,
-    is_muted = @is_muted::bool,
-    mute_until = @mute_until::timestamptz,
+-- name: UpdateConversationState :one
+-- Set a conversation's pin, archive and mute state; NULL fields are left unchanged,
+-- and mute_until is set with is_muted. An update whose changed_at is older than the
+-- conversation's latest change returns no row, as does an unknown conversation.
+WITH previous AS (
+    SELECT id,
+        is_archived,
+        is_pinned,
+        is_muted,
+        mute_until
+    FROM conversations
+    WHERE user_integration_id = @user_integration_id::int
+        AND external_conversation_id = @external_conversation_id::text
+        AND (
+            sqlc.narg(changed_at)::timestamptz IS NULL
+            OR state_changed_at IS NULL
+            OR state_changed_at <= sqlc.narg(changed_at)::timestamptz
+        )
+    FOR UPDATE
+)
+UPDATE conversations c
+SET is_archived = COALESCE(sqlc.narg(is_archived)::bool, c.is_archived),
+    is_pinned = COALESCE(sqlc.narg(is_pinned)::bool, c.is_pinned),
+    is_muted = COALESCE(sqlc.narg(is_muted)::bool, c.is_muted),
+    mute_until = CASE
+        WHEN sqlc.narg(is_muted)::bool IS NULL THEN c.mute_until
+        ELSE sqlc.narg(mute_until)::timestamptz
+    END,
+    state_changed_at = GREATEST(c.state_changed_at, sqlc.narg(changed_at)::timestamptz),
     updated_at = NOW()
-WHERE user_integration_id = @user_integration_id::int
-    AND external_conversation_id = @external_conversation_id::text;
+FROM previous p
+WHERE c.id = p.id
+RETURNING p.is_archived AS previous_is_archived,
+    p.is_pinned AS previous_is_pinned,
+    p.is_muted AS previous_is_muted,
+    p.mute_until AS previous_mute_until,
+    c.is_archived,
+    c.is_pinned,
+    c.is_muted,
+    c.mute_until;
 -- name: GetConversationByExternalID :one
 SELECT id,
     user_integration_id,
@@ -319,8 +468,43 @@ SELECT id,
 FROM conversations
 WHERE user_integration_id = @user_integration_id::int
     AND external_conversation_id = @external_conversation_id::text;
+-- name: UpdateConversationAvatar :execrows
+-- Set or, with an empty avatar_url, clear the avatar of a conversation and the
+-- platform's ID of the picture it was made from. Unlike UpsertConversation it
+-- applies whatever the conversation's last activity, and the conversation gets
+-- a new seq so syncing clients pick the avatar up.
+UPDATE conversations
+SET avatar_url = NULLIF(@avatar_url::text, ''),
+    platform_metadata = CASE
+        WHEN @picture_id::text = '' THEN platform_metadata - @picture_id_key::text
+        ELSE platform_metadata || jsonb_build_object(@picture_id_key::text, @picture_id::text)
+    END,
+    seq = nextval(pg_get_serial_sequence('conversations', 'seq')),
+    updated_at = NOW()
+WHERE user_integration_id = @user_integration_id::int
+    AND external_conversation_id = @external_conversation_id::text
+    AND avatar_url IS DISTINCT FROM NULLIF(@avatar_url::text, '');
+-- name: CreatePlaceholderConversation :one
+-- Create a minimal conversation for messages that arrive before their conversation is synced.
+-- An existing conversation is left untouched (no row is returned).
+INSERT INTO conversations (
+        user_integration_id,
+        external_conversation_id,
+        integration_type,
+        conversation_type,
+        platform_metadata
+    )
+VALUES (
+        @user_integration_id::int,
+        @external_conversation_id::text,
+        @integration_type::text,
+        @conversation_type::text,
+        '{"placeholder": "true"}'::jsonb
+    ) ON CONFLICT (user_integration_id, external_conversation_id) DO NOTHING
+RETURNING id;
 -- name: ListUserIntegrationConversationsSinceSeq :many
--- Fetch conversations for a user integration since a sequence number (for sync)
+-- Fetch conversations of the given types for a user integration since a
+-- sequence number (for sync)
 SELECT seq,
     id,
     user_integration_id,
@@ -347,6 +531,7 @@ SELECT seq,
 FROM conversations
 WHERE user_integration_id = @user_integration_id::int
     AND seq > @since_seq::bigint
+    AND conversation_type = ANY(@conversation_types::text [])
 ORDER BY seq ASC
 LIMIT @limit_count::int;
 -- name: GetUserIntegrationLatestConversationSeq :one
@@ -16,6 +16,13 @@ WHERE account_id = $1 AND seq > $2
 ORDER BY seq ASC
 LIMIT $3;
 
+-- name: GetEventsSinceByTypes :many
+SELECT seq, id, ts, type, account_id, device_id, convo_id, wa_message_id, sender_jid, payload, attachment_ref
+FROM events 
+WHERE account_id = @account_id AND seq > @seq AND type = ANY(@types::text[])
+ORDER BY seq ASC
+LIMIT @row_limit;
+
 -- name: GetEventsByConvo :many
 SELECT seq, id, ts, type, account_id, device_id, convo_id, wa_message_id, sender_jid, payload, attachment_ref
 FROM events 
@@ -47,3 +54,30 @@ WHERE account_id = $1;
 SELECT COUNT(*) as total_events
 FROM events 
 WHERE type = $1 AND account_id = $2;
+
+-- name: DeleteExpiredEvents :one
+-- Deletes one batch of events of a type older than the cutoff and raises the
+-- affected accounts' low-water marks. Events referenced by the outbox are kept.
+WITH deleted AS (
+    DELETE FROM events
+    WHERE seq IN (
+        SELECT e.seq FROM events e
+        WHERE e.type = @type AND e.ts < @cutoff
+          AND NOT EXISTS (SELECT 1 FROM outbox o WHERE o.server_msg_id = e.seq)
+        ORDER BY e.seq
+        LIMIT @batch_size
+    )
+    RETURNING account_id, seq
+), marks AS (
+    INSERT INTO event_retention (account_id, low_water_seq)
+    SELECT account_id, MAX(seq) FROM deleted GROUP BY account_id
+    ON CONFLICT (account_id) DO UPDATE
+    SET low_water_seq = GREATEST(event_retention.low_water_seq, excluded.low_water_seq),
+        updated_at = NOW()
+)
+SELECT COUNT(*) AS deleted_count FROM deleted;
+
+-- name: GetEventLowWaterMark :one
+SELECT COALESCE(MAX(low_water_seq), 0)::bigint AS low_water_seq
+FROM event_retention
+WHERE account_id = $1;
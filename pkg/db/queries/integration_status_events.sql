@@ -0,0 +1,28 @@
+-- Integration status history queries
+-- name: InsertIntegrationStatusEvent :exec
+INSERT INTO integration_status_events (
+        user_integration_id,
+        old_status,
+        new_status,
+        reason,
+        metadata
+    )
+VALUES (
+        @user_integration_id::integer,
+        NULLIF(@old_status::text, ''),
+        @new_status::text,
+        NULLIF(@reason::text, ''),
+        @metadata::jsonb
+    );
+-- name: TrimIntegrationStatusEvents :execrows
+-- Keep only the most recent @keep events for an integration
+DELETE FROM integration_status_events
+WHERE user_integration_id = @user_integration_id::integer
+    AND id <= (
+        SELECT id
+        FROM integration_status_events
+        WHERE user_integration_id = @user_integration_id::integer
+        ORDER BY id DESC
+        OFFSET @keep::integer
+        LIMIT 1
+    );
@@ -28,3 +28,62 @@ LIMIT $2;
 -- name: GetTotalMediaStorage :one
 SELECT COALESCE(SUM(size_bytes), 0) as total_bytes
 FROM media_blobs;
+
+-- name: ListStoredMediaBlobHashes :many
+-- Which of the hashes already have a stored file
+SELECT content_hash
+FROM media_blobs
+WHERE content_hash = ANY(@content_hashes::text []);
+
+-- name: StoreMediaBlob :one
+-- Record a stored file and complete the attachments that were waiting for it.
+-- If the file was already recorded its existing path is returned.
+WITH blob AS (
+    INSERT INTO media_blobs (content_hash, mime_type, size_bytes, storage_url)
+    VALUES (
+            @content_hash::text,
+            @mime_type::text,
+            @size_bytes::bigint,
+            @storage_url::text
+        ) ON CONFLICT (content_hash) DO
+    UPDATE
+    SET content_hash = EXCLUDED.content_hash
+    RETURNING content_hash,
+        storage_url,
+        (xmax = 0)::boolean AS inserted
+),
+linked AS (
+    UPDATE message_media mm
+    SET download_status = 'completed',
+        local_file_path = blob.storage_url,
+        downloaded_at = NOW(),
+        updated_at = NOW()
+    FROM blob
+    WHERE mm.content_hash = blob.content_hash
+        AND mm.download_status <> 'completed'
+    RETURNING mm.id
+)
+SELECT blob.storage_url,
+    blob.inserted,
+    (
+        SELECT COUNT(*)
+        FROM linked
+    )::bigint AS linked_count
+FROM blob;
+
+-- name: UserHasAvatar :one
+-- Whether one of the user's contacts or conversations has the avatar
+SELECT EXISTS (
+        SELECT 1
+        FROM contacts c
+            JOIN user_integrations ui ON ui.id = c.user_integration_id
+        WHERE ui.user_id = @user_id::uuid
+            AND c.avatar_url = @avatar_url::text
+    )
+    OR EXISTS (
+        SELECT 1
+        FROM conversations cv
+            JOIN user_integrations ui ON ui.id = cv.user_integration_id
+        WHERE ui.user_id = @user_id::uuid
+            AND cv.avatar_url = @avatar_url::text
+    ) AS has_avatar;
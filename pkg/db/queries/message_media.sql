@@ -1,6 +1,8 @@
 -- Message media table queries
 -- Rich media attachment management
 -- name: CreateMessageMedia :one
+-- An attachment whose file is already stored is completed right away. Syncing
+-- the message again refreshes the attachment's details and keeps its download.
 INSERT INTO message_media (
         message_id,
         media_type,
@@ -15,24 +17,58 @@ INSERT INTO message_media (
         local_file_path,
         download_status,
         downloaded_at,
-        platform_metadata
-    )
-VALUES (
-        @message_id::uuid,
-        @media_type::text,
-        @file_name::text,
-        @file_size::bigint,
-        @mime_type::text,
-        @duration_seconds::int,
-        @width::int,
-        @height::int,
-        @original_url::text,
-        @thumbnail_url::text,
-        @local_file_path::text,
-        @download_status::text,
-        @downloaded_at::timestamptz,
-        @platform_metadata::jsonb
+        platform_metadata,
+        content_hash
     )
+SELECT @message_id::uuid,
+    @media_type::text,
+    @file_name::text,
+    @file_size::bigint,
+    @mime_type::text,
+    @duration_seconds::int,
+    @width::int,
+    @height::int,
+    @original_url::text,
+    @thumbnail_url::text,
+    COALESCE(mb.storage_url, @local_file_path::text),
+    CASE
+        WHEN mb.content_hash IS NULL THEN @download_status::text
+        ELSE 'completed'
+    END,
+    CASE
+        WHEN mb.content_hash IS NULL THEN @downloaded_at::timestamptz
+        ELSE NOW()
+    END,
+    @platform_metadata::jsonb,
+    NULLIF(@content_hash::text, '')
+FROM (
+        SELECT 1
+    ) AS input
+    LEFT JOIN media_blobs mb ON mb.content_hash = NULLIF(@content_hash::text, '') ON CONFLICT (message_id, media_type) DO
+UPDATE
+SET file_name = EXCLUDED.file_name,
+    file_size = EXCLUDED.file_size,
+    mime_type = EXCLUDED.mime_type,
+    duration_seconds = EXCLUDED.duration_seconds,
+    width = EXCLUDED.width,
+    height = EXCLUDED.height,
+    original_url = EXCLUDED.original_url,
+    thumbnail_url = EXCLUDED.thumbnail_url,
+    content_hash = COALESCE(EXCLUDED.content_hash, message_media.content_hash),
+    local_file_path = CASE
+        WHEN message_media.download_status = 'completed' THEN message_media.local_file_path
+        ELSE EXCLUDED.local_file_path
+    END,
+    download_status = CASE
+        WHEN message_media.download_status = 'completed' THEN message_media.download_status
+        ELSE EXCLUDED.download_status
+    END,
+    downloaded_at = CASE
+        WHEN message_media.download_status = 'completed' THEN message_media.downloaded_at
+        ELSE EXCLUDED.downloaded_at
+    END,
+    platform_metadata = EXCLUDED.platform_metadata,
+    updated_at = NOW()
 RETURNING id,
     message_id,
     media_type,
@@ -49,7 +85,8 @@ RETURNING id,
     downloaded_at,
     platform_metadata,
     created_at,
-    updated_at;
+    updated_at,
+    content_hash;
 -- name: GetMessageMediaByID :one
 SELECT id,
     message_id,
@@ -90,6 +127,23 @@ SELECT id,
     updated_at
 FROM message_media
 WHERE message_id = $1::uuid;
+-- name: GetUserMessageMedia :one
+-- Get the first media attachment of a message the user owns
+SELECT mm.id,
+    mm.media_type,
+    mm.file_name,
+    mm.mime_type,
+    mm.local_file_path,
+    mm.download_status
+FROM message_media mm
+    JOIN messages m ON m.id = mm.message_id
+    JOIN conversations c ON c.id = m.conversation_id
+    JOIN user_integrations ui ON ui.id = c.user_integration_id
+WHERE mm.message_id = @message_id::uuid
+    AND ui.user_id = @user_id::uuid
+    AND m.is_deleted = false
+ORDER BY mm.created_at ASC
+LIMIT 1;
 -- name: ListMessageMedia :many
 SELECT id,
     message_id,
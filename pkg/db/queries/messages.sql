@@ -1,6 +1,8 @@
 -- Messages table queries
 -- Platform-agnostic message storage and retrieval
 -- name: UpsertMessage :one
+-- Inserted is false when the message was already stored, e.g. a real-time
+-- message that history sync delivers again
 INSERT INTO messages (
         conversation_id,
         external_message_id,
@@ -36,7 +38,10 @@ VALUES (
         @is_forwarded::bool,
         @is_deleted::bool,
         @deleted_at::timestamptz,
-        @reply_to_message_id::uuid,
+        NULLIF(
+            @reply_to_message_id::uuid,
+            '00000000-0000-0000-0000-000000000000'::uuid
+        ),
         @reply_to_external_id::text,
         @delivery_status::text,
         @platform_metadata::jsonb
@@ -75,7 +80,8 @@ RETURNING id,
     delivery_status,
     platform_metadata,
     created_at,
-    updated_at;
+    updated_at,
+    (xmax = 0)::boolean AS inserted;
 -- name: GetMessageByExternalID :one
 SELECT id,
     conversation_id,
@@ -99,8 +105,8 @@ SELECT id,
     created_at,
     updated_at
 FROM messages
-WHERE conversation_id = $1::uuid
-    AND external_message_id = $2::text;
+WHERE conversation_id = @conversation_id::uuid
+    AND external_message_id = @external_message_id::text;
 -- name: GetMessageByID :one
 SELECT id,
     conversation_id,
@@ -291,6 +297,80 @@ WHERE conversation_id = $1::uuid
     AND is_deleted = false
 ORDER BY timestamp DESC
 LIMIT $3::int;
+-- name: SearchUserMessages :many
+-- Full-text search across all of the user's conversations. Only the newest
+-- @max_candidates matches are ranked, so a very common term costs the same
+-- as a rare one. Snippets wrap matched words in chr(2) and chr(3) so the
+-- caller can escape the content before adding its own markup.
+WITH matches AS (
+    SELECT m.id,
+        m.conversation_id,
+        m.external_message_id,
+        m.sender_external_id,
+        m.sender_display_name,
+        m.message_type,
+        m.content,
+        m.timestamp,
+        m.is_from_me,
+        m.content_tsv
+    FROM messages m
+        JOIN conversations c ON c.id = m.conversation_id
+        JOIN user_integrations ui ON ui.id = c.user_integration_id
+    WHERE ui.user_id = @user_id::uuid
+        AND m.is_deleted = false
+        AND m.content_tsv @@ websearch_to_tsquery('simple', @query::text)
+        AND (
+            sqlc.narg(conversation_id)::uuid IS NULL
+            OR m.conversation_id = sqlc.narg(conversation_id)::uuid
+        )
+        AND (
+            sqlc.narg(from_time)::timestamptz IS NULL
+            OR m.timestamp >= sqlc.narg(from_time)::timestamptz
+        )
+        AND (
+            sqlc.narg(to_time)::timestamptz IS NULL
+            OR m.timestamp <= sqlc.narg(to_time)::timestamptz
+        )
+    ORDER BY m.timestamp DESC
+    LIMIT @max_candidates::int
+), ranked AS (
+    SELECT id,
+        conversation_id,
+        external_message_id,
+        sender_external_id,
+        sender_display_name,
+        message_type,
+        content,
+        timestamp,
+        is_from_me,
+        ts_rank_cd(content_tsv, websearch_to_tsquery('simple', @query::text)) AS rank
+    FROM matches
+    ORDER BY rank DESC,
+        timestamp DESC
+    LIMIT @result_limit::int
+)
+SELECT r.id,
+    r.conversation_id,
+    c.user_integration_id,
+    c.external_conversation_id,
+    c.name AS conversation_name,
+    r.external_message_id,
+    r.sender_external_id,
+    r.sender_display_name,
+    r.message_type,
+    r.timestamp,
+    r.is_from_me,
+    r.rank::real AS rank,
+    ts_headline(
+        'simple',
+        coalesce(r.content, ''),
+        websearch_to_tsquery('simple', @query::text),
+        'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxWords=35, MinWords=15, MaxFragments=2'
+    )::text AS snippet
+FROM ranked r
+    JOIN conversations c ON c.id = r.conversation_id
+ORDER BY r.rank DESC,
+    r.timestamp DESC;
 -- name: ListMessagesByType :many
 SELECT id,
     conversation_id,
@@ -345,11 +425,39 @@ FROM messages
 WHERE reply_to_message_id = $1::uuid
     AND is_deleted = false
 ORDER BY timestamp ASC;
+-- name: ResolvePendingReplies :execrows
+-- Link replies that were stored before the message they reply to arrived
+WITH resolved AS (
+    DELETE FROM pending_message_replies
+    WHERE conversation_id = @conversation_id::uuid
+        AND parent_external_message_id = @parent_external_message_id::text
+    RETURNING message_id
+)
+UPDATE messages
+SET reply_to_message_id = @parent_message_id::uuid,
+    seq = nextval(pg_get_serial_sequence('messages', 'seq')),
+    updated_at = NOW()
+WHERE id IN (
+        SELECT message_id
+        FROM resolved
+    );
 -- name: UpdateMessageDeliveryStatus :exec
 UPDATE messages
 SET delivery_status = $2::text,
     updated_at = NOW()
 WHERE external_message_id = $1::text;
+-- name: AdvanceMessageDeliveryStatus :execrows
+-- Move a message sent through an integration to delivered or read. Like the
+-- outbox, the status never moves back.
+UPDATE messages m
+SET delivery_status = @delivery_status::text,
+    updated_at = NOW()
+FROM conversations c
+WHERE m.conversation_id = c.id
+    AND c.user_integration_id = @user_integration_id::int
+    AND m.external_message_id = @external_message_id::text
+    AND m.delivery_status <> 'read'
+    AND (m.delivery_status <> 'delivered' OR @delivery_status::text = 'read');
 -- name: MarkMessageAsRead :exec
 UPDATE messages
 SET delivery_status = 'read',
@@ -464,4 +572,65 @@ FROM messages m
     JOIN conversations c ON m.conversation_id = c.id
 WHERE c.user_integration_id = @user_integration_id::int
     AND m.seq > @since_seq::bigint
-    AND m.is_deleted = false;
\ No newline at end of file
+    AND m.is_deleted = false;
+-- name: GetMessageThread :many
+-- Fetch every reply below a message, directly or through other replies,
+-- oldest first. Replies are looked up within the root's conversation, which
+-- idx_messages_conversation_reply covers.
+WITH RECURSIVE thread AS (
+    SELECT m.id,
+        m.conversation_id,
+        1 AS depth
+    FROM messages m
+        JOIN messages root ON root.id = @root_message_id::uuid
+    WHERE m.conversation_id = root.conversation_id
+        AND m.reply_to_message_id = root.id
+    UNION ALL
+    SELECT m.id,
+        m.conversation_id,
+        thread.depth + 1
+    FROM messages m
+        JOIN thread ON m.conversation_id = thread.conversation_id
+        AND m.reply_to_message_id = thread.id
+    WHERE thread.depth < 100
+)
+SELECT m.seq,
+    m.id,
+    m.conversation_id,
+    m.external_message_id,
+    m.integration_type,
+    m.sender_external_id,
+    m.sender_display_name,
+    m.message_type,
+    m.content,
+    m.timestamp,
+    m.is_from_me,
+    m.is_forwarded,
+    m.is_deleted,
+    m.reply_to_message_id,
+    m.reply_to_external_id,
+    m.delivery_status,
+    m.platform_metadata,
+    m.created_at,
+    m.updated_at,
+    thread.depth::int AS depth
+FROM thread
+    JOIN messages m ON m.id = thread.id
+WHERE m.is_deleted = false
+ORDER BY m.timestamp ASC,
+    m.id ASC;
+-- name: ScheduleMessageExpiry :exec
+-- Set when a newly stored message disappears: after its own timer, or its
+-- conversation's when it carries none. Messages without either are kept.
+UPDATE messages m
+SET expires_at = m.timestamp + make_interval(secs => ttl.seconds)
+FROM (
+        SELECT COALESCE(
+                NULLIF(@expiration_seconds::int, 0),
+                c.disappearing_seconds
+            ) AS seconds
+        FROM conversations c
+        WHERE c.id = @conversation_id::uuid
+    ) ttl
+WHERE m.id = @message_id::uuid
+    AND ttl.seconds > 0;
@@ -3,31 +3,44 @@
 
 -- name: CreateOutboxEntry :one
 INSERT INTO outbox (
-    client_msg_uuid, account_id, convo_id, server_msg_id, status
+    client_msg_uuid, account_id, convo_id, server_msg_id, status, payload
 ) VALUES (
-    $1, $2, $3, $4, $5
+    $1, $2, $3, $4, $5, $6
 ) ON CONFLICT (client_msg_uuid) DO NOTHING
 RETURNING client_msg_uuid, created_at;
 
 -- name: GetPendingOutboxEntries :many
-SELECT client_msg_uuid, account_id, convo_id, server_msg_id, status, last_error, created_at, updated_at
+SELECT client_msg_uuid, account_id, convo_id, server_msg_id, status, last_error, created_at, updated_at, payload
 FROM outbox 
 WHERE status IN ('queued', 'retry')
 ORDER BY created_at ASC
 LIMIT $1;
 
 -- name: UpdateOutboxStatus :exec
+-- A delivered or read entry was sent, whatever a late send result says
 UPDATE outbox 
 SET status = $2, last_error = $3, updated_at = NOW()
-WHERE client_msg_uuid = $1;
+WHERE client_msg_uuid = $1 AND status NOT IN ('delivered', 'read');
+
+-- name: AdvanceOutboxDelivery :one
+-- Move an entry to delivered or read. Receipts can arrive out of order, and
+-- before the send result, so the status never moves back; no row means the
+-- entry is unknown or already has this status or a later one.
+UPDATE outbox
+SET status = @status::text, last_error = NULL, updated_at = NOW()
+WHERE client_msg_uuid = @client_msg_uuid
+    AND account_id = @account_id
+    AND status <> 'read'
+    AND (status <> 'delivered' OR @status::text = 'read')
+RETURNING convo_id;
 
 -- name: GetOutboxEntry :one
-SELECT client_msg_uuid, account_id, convo_id, server_msg_id, status, last_error, created_at, updated_at
+SELECT client_msg_uuid, account_id, convo_id, server_msg_id, status, last_error, created_at, updated_at, payload
 FROM outbox 
 WHERE client_msg_uuid = $1;
 
 -- name: GetOutboxByServerMsgID :one
-SELECT client_msg_uuid, account_id, convo_id, server_msg_id, status, last_error, created_at, updated_at
+SELECT client_msg_uuid, account_id, convo_id, server_msg_id, status, last_error, created_at, updated_at, payload
 FROM outbox 
 WHERE server_msg_id = $1;
 
@@ -36,7 +49,7 @@ DELETE FROM outbox
 WHERE client_msg_uuid = $1;
 
 -- name: GetFailedOutboxEntries :many
-SELECT client_msg_uuid, account_id, convo_id, server_msg_id, status, last_error, created_at, updated_at
+SELECT client_msg_uuid, account_id, convo_id, server_msg_id, status, last_error, created_at, updated_at, payload
 FROM outbox 
 WHERE status = 'failed' AND created_at > NOW() - INTERVAL '24 hours'
 ORDER BY created_at DESC;
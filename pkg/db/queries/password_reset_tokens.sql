@@ -0,0 +1,40 @@
+-- Password reset token queries
+
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+VALUES (@user_id, @token_hash, @expires_at);
+
+-- name: RevokePasswordResetTokens :exec
+-- Spends a user's outstanding tokens, so only the latest one sent works
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE user_id = @user_id AND used_at IS NULL;
+
+-- name: ResetPasswordWithToken :one
+-- Spends a valid token of the user with the email and sets their password,
+-- in one statement so a token can't be used twice. The user's other
+-- outstanding tokens are revoked with it, as are the access tokens issued
+-- before the reset. No rows when the token is unknown, expired, used, or
+-- another user's.
+WITH consumed AS (
+    UPDATE password_reset_tokens t
+    SET used_at = NOW()
+    FROM users u
+    WHERE t.token_hash = @token_hash
+        AND t.user_id = u.id
+        AND u.email = @email
+        AND u.is_active
+        AND t.used_at IS NULL
+        AND t.expires_at > NOW()
+    RETURNING t.user_id
+), revoked AS (
+    UPDATE password_reset_tokens
+    SET used_at = NOW()
+    WHERE user_id IN (SELECT user_id FROM consumed)
+        AND token_hash <> @token_hash
+        AND used_at IS NULL
+)
+UPDATE users
+SET password_hash = @password_hash, password_changed_at = NOW(), updated_at = NOW()
+WHERE id = (SELECT user_id FROM consumed)
+RETURNING id;
@@ -0,0 +1,30 @@
+-- Pending message replies queries
+-- Links between replies and parent messages that haven't been synced yet
+-- name: CreatePendingMessageReply :exec
+INSERT INTO pending_message_replies (
+        message_id,
+        conversation_id,
+        parent_external_message_id
+    )
+VALUES (
+        @message_id::uuid,
+        @conversation_id::uuid,
+        @parent_external_message_id::text
+    ) ON CONFLICT (message_id) DO
+UPDATE
+SET parent_external_message_id = EXCLUDED.parent_external_message_id;
+-- name: DeletePendingMessageReply :exec
+DELETE FROM pending_message_replies
+WHERE message_id = @message_id::uuid;
+-- name: CountPendingMessageReplies :one
+SELECT COUNT(*)
+FROM pending_message_replies
+WHERE conversation_id = @conversation_id::uuid;
+-- name: LockMessageReplyLink :exec
+-- Serialize reply resolution for one parent message until the transaction ends
+SELECT pg_advisory_xact_lock(
+        hashtextextended(
+            @conversation_id::text || ':' || @external_message_id::text,
+            0
+        )
+    );
@@ -0,0 +1,47 @@
+-- Poll votes queries
+-- Latest vote per voter for polls, tallied per option when read
+-- name: UpsertPollVote :exec
+INSERT INTO poll_votes (
+        conversation_id,
+        poll_external_message_id,
+        voter_external_id,
+        selected_option_hashes,
+        voted_at
+    )
+VALUES (
+        @conversation_id::uuid,
+        @poll_external_message_id::text,
+        @voter_external_id::text,
+        @selected_option_hashes::text [],
+        @voted_at::timestamptz
+    ) ON CONFLICT (
+        conversation_id,
+        poll_external_message_id,
+        voter_external_id
+    ) DO
+UPDATE
+SET selected_option_hashes = EXCLUDED.selected_option_hashes,
+    voted_at = EXCLUDED.voted_at,
+    updated_at = NOW()
+WHERE poll_votes.voted_at <= EXCLUDED.voted_at;
+-- name: ListPollVotes :many
+SELECT voter_external_id,
+    selected_option_hashes,
+    voted_at
+FROM poll_votes
+WHERE conversation_id = @conversation_id::uuid
+    AND poll_external_message_id = @poll_external_message_id::text
+ORDER BY voted_at ASC;
+-- name: GetUserMessage :one
+-- Fetch a message only if it belongs to one of the user's integrations
+SELECT m.id,
+    m.conversation_id,
+    m.external_message_id,
+    m.message_type,
+    m.content,
+    m.platform_metadata
+FROM messages m
+    JOIN conversations c ON c.id = m.conversation_id
+    JOIN user_integrations ui ON ui.id = c.user_integration_id
+WHERE m.id = @message_id::uuid
+    AND ui.user_id = @user_id::uuid;
@@ -20,10 +20,9 @@ VALUES (
         @avatar_url::text,
         @metadata::jsonb,
         @last_seen::timestamptz
-    ) ON CONFLICT (user_id, integration_type) DO
+    ) ON CONFLICT (user_id, integration_type, external_id) DO
 UPDATE
-SET external_id = EXCLUDED.external_id,
-    status = EXCLUDED.status,
+SET status = EXCLUDED.status,
     display_name = EXCLUDED.display_name,
     avatar_url = EXCLUDED.avatar_url,
     metadata = EXCLUDED.metadata,
@@ -54,7 +53,24 @@ SELECT id,
     updated_at
 FROM user_integrations
 WHERE user_id = @user_id::uuid
-    AND integration_type = @integration_type::text;
+    AND integration_type = @integration_type::text
+    AND external_id = @external_id::text;
+-- name: GetUserIntegrations :many
+SELECT id,
+    user_id,
+    integration_type,
+    external_id,
+    status,
+    display_name,
+    avatar_url,
+    metadata,
+    last_seen,
+    created_at,
+    updated_at
+FROM user_integrations
+WHERE user_id = @user_id::uuid
+    AND integration_type = @integration_type::text
+ORDER BY created_at;
 -- name: GetUserIntegrationByExternalID :one
 SELECT id,
     user_id,
@@ -75,8 +91,7 @@ UPDATE user_integrations
 SET status = @status::text,
     last_seen = @last_seen::timestamptz,
     updated_at = NOW()
-WHERE user_id = @user_id::uuid
-    AND integration_type = @integration_type::text;
+WHERE id = @id;
 -- name: ListUserIntegrations :many
 SELECT id,
     user_id,
@@ -126,4 +141,28 @@ ORDER BY last_seen DESC;
 -- name: DeleteUserIntegration :exec
 DELETE FROM user_integrations
 WHERE user_id = @user_id::uuid
-    AND integration_type = @integration_type::text;
\ No newline at end of file
+    AND integration_type = @integration_type::text
+    AND external_id = @external_id::text;
+-- name: LockIntegrationPairing :exec
+-- Serialize pairings with the same idempotency key until the transaction ends
+SELECT pg_advisory_xact_lock(
+        hashtextextended(
+            @user_id::text || ':' || @idempotency_key::text,
+            0
+        )
+    );
+-- name: GetIntegrationPairing :one
+SELECT ui.id,
+    ui.integration_type,
+    ui.external_id
+FROM integration_pairings p
+    JOIN user_integrations ui ON ui.id = p.user_integration_id
+WHERE p.user_id = @user_id::uuid
+    AND p.idempotency_key = @idempotency_key::text;
+-- name: InsertIntegrationPairing :exec
+INSERT INTO integration_pairings (user_id, idempotency_key, user_integration_id)
+VALUES (
+        @user_id::uuid,
+        @idempotency_key::text,
+        @user_integration_id::int
+    ) ON CONFLICT (user_id, idempotency_key) DO NOTHING;
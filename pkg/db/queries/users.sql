@@ -6,25 +6,32 @@ INSERT INTO users (
     username, email, password_hash, full_name
 ) VALUES (
     $1, $2, $3, $4
-) RETURNING id, username, email, full_name, is_active, created_at, updated_at;
+) RETURNING id, username, email, full_name, role, is_active, created_at, updated_at;
 
 -- name: GetUserByUsername :one
-SELECT id, username, email, password_hash, full_name, is_active, created_at, updated_at
+SELECT id, username, email, password_hash, full_name, role, is_active, created_at, updated_at
 FROM users 
 WHERE username = $1 AND is_active = true;
 
 -- name: GetUserByEmail :one
-SELECT id, username, email, password_hash, full_name, is_active, created_at, updated_at
+SELECT id, username, email, password_hash, full_name, role, is_active, created_at, updated_at
 FROM users 
 WHERE email = $1 AND is_active = true;
 
 -- name: GetUserByID :one
-SELECT id, username, email, password_hash, full_name, is_active, created_at, updated_at
+SELECT id, username, email, password_hash, full_name, role, is_active, created_at, updated_at
 FROM users 
 WHERE id = $1 AND is_active = true;
 
+-- name: GetUserPasswordChangedAt :one
+-- Tokens issued before the returned time are revoked; NULL if the password
+-- was never reset. No rows for a deactivated user.
+SELECT password_changed_at
+FROM users
+WHERE id = $1 AND is_active = true;
+
 -- name: GetUserByUsernameOrEmail :one
-SELECT id, username, email, password_hash, full_name, is_active, created_at, updated_at
+SELECT id, username, email, password_hash, full_name, role, is_active, created_at, updated_at
 FROM users 
 WHERE (username = $1 OR email = $1) AND is_active = true;
 
@@ -35,7 +42,7 @@ SET
     full_name = COALESCE($3, full_name),
     updated_at = NOW()
 WHERE id = $1 AND is_active = true
-RETURNING id, username, email, full_name, is_active, created_at, updated_at;
+RETURNING id, username, email, full_name, role, is_active, created_at, updated_at;
 
 -- name: UpdateUserPassword :exec
 UPDATE users 
@@ -48,7 +55,7 @@ SET is_active = false, updated_at = NOW()
 WHERE id = $1;
 
 -- name: ListUsers :many
-SELECT id, username, email, full_name, is_active, created_at, updated_at
+SELECT id, username, email, full_name, role, is_active, created_at, updated_at
 FROM users 
 WHERE is_active = true
 ORDER BY created_at DESC
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS accounts;
+DROP TABLE media_blobs;
+DROP TABLE outbox;
+DROP TABLE events;
+DROP FUNCTION update_updated_at_column();
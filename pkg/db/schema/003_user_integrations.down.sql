@@ -0,0 +1,17 @@
+DROP TABLE user_integrations;
+DROP FUNCTION update_user_integrations_updated_at();
+-- Restore the WhatsApp-specific accounts table; its rows are not recovered
+CREATE TABLE accounts (
+    id          TEXT PRIMARY KEY,
+    wa_jid      TEXT UNIQUE,
+    display_name TEXT,
+    avatar_url   TEXT,
+    status       TEXT NOT NULL DEFAULT 'disconnected',
+    last_seen    TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+ALTER TABLE accounts ADD CONSTRAINT accounts_status_check
+    CHECK (status IN ('connected', 'disconnected', 'connecting', 'error'));
+CREATE TRIGGER update_accounts_updated_at BEFORE UPDATE ON accounts
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
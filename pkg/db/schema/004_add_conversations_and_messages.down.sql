@@ -0,0 +1,6 @@
+DROP TABLE integration_settings;
+DROP TABLE contacts;
+DROP TABLE message_media;
+DROP TABLE messages;
+DROP TABLE conversation_participants;
+DROP TABLE conversations;
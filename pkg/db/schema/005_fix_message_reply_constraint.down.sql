@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_messages_reply_to;
+-- Replies synced before their parent may not satisfy the constraint, so it
+-- only applies to new rows
+ALTER TABLE messages ADD CONSTRAINT messages_reply_to_message_id_fkey
+    FOREIGN KEY (reply_to_message_id) REFERENCES messages(id) NOT VALID;
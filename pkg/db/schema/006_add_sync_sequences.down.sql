@@ -0,0 +1,5 @@
+ALTER TABLE conversation_participants DROP COLUMN seq;
+ALTER TABLE message_media DROP COLUMN seq;
+ALTER TABLE contacts DROP COLUMN seq;
+ALTER TABLE messages DROP COLUMN seq;
+ALTER TABLE conversations DROP COLUMN seq;
@@ -0,0 +1 @@
+-- Resolved reply links are kept; the migration only filled in data
@@ -0,0 +1,16 @@
+-- Resolve reply_to_message_id from reply_to_external_id
+-- Replies may be synced before the message they reply to, so the link is
+-- filled in when either side arrives. Unresolved replies keep a NULL reference.
+-- Clear placeholder nil UUIDs written before resolution was implemented
+UPDATE messages
+SET reply_to_message_id = NULL
+WHERE reply_to_message_id = '00000000-0000-0000-0000-000000000000'::uuid;
+-- Backfill links for replies whose parent is already stored
+UPDATE messages child
+SET reply_to_message_id = parent.id
+FROM messages parent
+WHERE child.reply_to_message_id IS NULL
+    AND child.reply_to_external_id IS NOT NULL
+    AND child.reply_to_external_id != ''
+    AND parent.conversation_id = child.conversation_id
+    AND parent.external_message_id = child.reply_to_external_id;
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- Track replies whose parent message hasn't been synced yet
+-- History sync can deliver a reply before the message it replies to. The
+-- pending link is recorded here and resolved once the parent is upserted.
+CREATE TABLE pending_message_replies (
+    message_id UUID PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    parent_external_message_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_pending_message_replies_parent ON pending_message_replies (conversation_id, parent_external_message_id);
+-- Seed with replies that are still unresolved
+INSERT INTO pending_message_replies (message_id, conversation_id, parent_external_message_id)
+SELECT id,
+    conversation_id,
+    reply_to_external_id
+FROM messages
+WHERE reply_to_message_id IS NULL
+    AND reply_to_external_id IS NOT NULL
+    AND reply_to_external_id != '' ON CONFLICT (message_id) DO NOTHING;
+COMMENT ON TABLE pending_message_replies IS 'Replies waiting for their parent message to be synced';
+COMMENT ON COLUMN pending_message_replies.parent_external_message_id IS 'External platform ID of the message being replied to';
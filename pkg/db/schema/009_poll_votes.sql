@@ -0,0 +1,20 @@
+-- Track the latest poll vote of each voter
+-- WhatsApp sends every vote as the voter's full selection, so a new vote
+-- replaces the previous one. Votes are keyed by the poll's external ID so
+-- they can be stored before the poll message itself has been synced.
+CREATE TABLE poll_votes (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    poll_external_message_id TEXT NOT NULL,
+    voter_external_id TEXT NOT NULL,
+    selected_option_hashes TEXT [] NOT NULL DEFAULT '{}',
+    voted_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (
+        conversation_id,
+        poll_external_message_id,
+        voter_external_id
+    )
+);
+COMMENT ON TABLE poll_votes IS 'Latest poll vote per voter, tallied per option on read';
+COMMENT ON COLUMN poll_votes.selected_option_hashes IS 'Hex SHA-256 of each selected option name; empty when the vote was retracted';
@@ -0,0 +1,10 @@
+-- Track how far event retention has compacted each account's event log
+-- Clients syncing from a seq below the low-water mark have missed deleted
+-- events and must rebuild their state from a snapshot.
+CREATE TABLE event_retention (
+    account_id TEXT PRIMARY KEY,
+    low_water_seq BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+COMMENT ON TABLE event_retention IS 'Per-account low-water mark of events deleted by retention';
+COMMENT ON COLUMN event_retention.low_water_seq IS 'Highest seq deleted for the account; syncing from below it requires a snapshot';
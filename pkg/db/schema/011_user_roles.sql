@@ -0,0 +1,7 @@
+-- Add a role to users so admin-only endpoints can be authorized from the JWT
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+
+ALTER TABLE users ADD CONSTRAINT users_role_check
+    CHECK (role IN ('user', 'admin'));
+
+COMMENT ON COLUMN users.role IS 'Authorization role (user or admin), carried in the JWT role claim';
@@ -0,0 +1 @@
+DROP TABLE integration_status_events;
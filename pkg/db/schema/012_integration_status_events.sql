@@ -0,0 +1,15 @@
+-- Keep a history of integration status transitions so repeated disconnects
+-- and re-pairings can be investigated after the current status has changed
+CREATE TABLE integration_status_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_integration_id INTEGER NOT NULL REFERENCES user_integrations(id) ON DELETE CASCADE,
+    old_status TEXT,
+    new_status TEXT NOT NULL,
+    reason TEXT,
+    metadata JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_integration_status_events_integration ON integration_status_events(user_integration_id, id DESC);
+COMMENT ON TABLE integration_status_events IS 'Append-only log of integration status transitions, trimmed to the most recent entries per integration';
+COMMENT ON COLUMN integration_status_events.old_status IS 'Status before the transition; NULL when the integration was first created';
+COMMENT ON COLUMN integration_status_events.reason IS 'Why the status changed, e.g. logged_out or paired';
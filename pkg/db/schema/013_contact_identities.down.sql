@@ -0,0 +1,2 @@
+DROP FUNCTION merge_contact_identity;
+DROP TABLE contact_identities;
@@ -0,0 +1,209 @@
+-- Map WhatsApp LIDs to phone-number JIDs
+-- Newer WhatsApp accounts can address a contact by LID (…@lid) instead of
+-- their phone-number JID (…@s.whatsapp.net), which splits one contact into
+-- two conversations. Once a mapping is known, everything stored under the
+-- LID is merged into the phone-number conversation and contact, and new
+-- events from the LID are stored under the phone-number JID.
+CREATE TABLE contact_identities (
+    user_integration_id INTEGER NOT NULL REFERENCES user_integrations(id) ON DELETE CASCADE,
+    lid_jid TEXT NOT NULL,
+    pn_jid TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_integration_id, lid_jid)
+);
+CREATE INDEX idx_contact_identities_pn ON contact_identities (user_integration_id, pn_jid);
+COMMENT ON TABLE contact_identities IS 'Known LID to phone-number JID mappings per integration';
+COMMENT ON COLUMN contact_identities.pn_jid IS 'Canonical phone-number JID that events from lid_jid are stored under';
+-- Merge everything stored under p_lid into p_pn for one integration.
+-- Returns true when a LID conversation was merged into (or renamed to) the
+-- phone-number conversation. Rows that change get a new seq so syncing
+-- clients pick them up.
+CREATE FUNCTION merge_contact_identity(
+    p_user_integration_id INTEGER,
+    p_lid_jid TEXT,
+    p_pn_jid TEXT
+) RETURNS BOOLEAN LANGUAGE plpgsql AS $$
+DECLARE
+    lid_conversation_id UUID;
+    pn_conversation_id UUID;
+BEGIN
+    SELECT id INTO lid_conversation_id
+    FROM conversations
+    WHERE user_integration_id = p_user_integration_id
+        AND external_conversation_id = p_lid_jid
+    FOR UPDATE;
+
+    SELECT id INTO pn_conversation_id
+    FROM conversations
+    WHERE user_integration_id = p_user_integration_id
+        AND external_conversation_id = p_pn_jid
+    FOR UPDATE;
+
+    IF lid_conversation_id IS NOT NULL AND pn_conversation_id IS NULL THEN
+        UPDATE conversations
+        SET external_conversation_id = p_pn_jid,
+            seq = nextval(pg_get_serial_sequence('conversations', 'seq')),
+            updated_at = NOW()
+        WHERE id = lid_conversation_id;
+    ELSIF lid_conversation_id IS NOT NULL THEN
+        -- Messages stored in both conversations keep their phone-number copy
+        UPDATE messages child
+        SET reply_to_message_id = kept.id,
+            seq = nextval(pg_get_serial_sequence('messages', 'seq')),
+            updated_at = NOW()
+        FROM messages duplicate
+            JOIN messages kept ON kept.conversation_id = pn_conversation_id
+            AND kept.external_message_id = duplicate.external_message_id
+        WHERE duplicate.conversation_id = lid_conversation_id
+            AND child.reply_to_message_id = duplicate.id;
+
+        DELETE FROM messages duplicate USING messages kept
+        WHERE duplicate.conversation_id = lid_conversation_id
+            AND kept.conversation_id = pn_conversation_id
+            AND kept.external_message_id = duplicate.external_message_id;
+
+        UPDATE messages
+        SET conversation_id = pn_conversation_id,
+            seq = nextval(pg_get_serial_sequence('messages', 'seq')),
+            updated_at = NOW()
+        WHERE conversation_id = lid_conversation_id;
+
+        -- Replies whose parent was on the other side of the split
+        WITH resolved AS (
+            DELETE FROM pending_message_replies pending USING messages parent
+            WHERE pending.conversation_id IN (lid_conversation_id, pn_conversation_id)
+                AND parent.conversation_id = pn_conversation_id
+                AND parent.external_message_id = pending.parent_external_message_id
+            RETURNING pending.message_id, parent.id AS parent_id
+        )
+        UPDATE messages
+        SET reply_to_message_id = resolved.parent_id,
+            seq = nextval(pg_get_serial_sequence('messages', 'seq')),
+            updated_at = NOW()
+        FROM resolved
+        WHERE messages.id = resolved.message_id;
+
+        UPDATE pending_message_replies
+        SET conversation_id = pn_conversation_id
+        WHERE conversation_id = lid_conversation_id;
+
+        -- Keep the later of two votes by the same voter on the same poll
+        DELETE FROM poll_votes pn_vote USING poll_votes lid_vote
+        WHERE pn_vote.conversation_id = pn_conversation_id
+            AND lid_vote.conversation_id = lid_conversation_id
+            AND lid_vote.poll_external_message_id = pn_vote.poll_external_message_id
+            AND lid_vote.voter_external_id = pn_vote.voter_external_id
+            AND lid_vote.voted_at > pn_vote.voted_at;
+        DELETE FROM poll_votes lid_vote USING poll_votes pn_vote
+        WHERE lid_vote.conversation_id = lid_conversation_id
+            AND pn_vote.conversation_id = pn_conversation_id
+            AND pn_vote.poll_external_message_id = lid_vote.poll_external_message_id
+            AND pn_vote.voter_external_id = lid_vote.voter_external_id;
+        UPDATE poll_votes
+        SET conversation_id = pn_conversation_id,
+            updated_at = NOW()
+        WHERE conversation_id = lid_conversation_id;
+
+        DELETE FROM conversation_participants lid_participant USING conversation_participants pn_participant
+        WHERE lid_participant.conversation_id = lid_conversation_id
+            AND pn_participant.conversation_id = pn_conversation_id
+            AND pn_participant.external_user_id = lid_participant.external_user_id;
+        UPDATE conversation_participants
+        SET conversation_id = pn_conversation_id,
+            seq = nextval(pg_get_serial_sequence('conversation_participants', 'seq')),
+            updated_at = NOW()
+        WHERE conversation_id = lid_conversation_id;
+
+        UPDATE conversations pn
+        SET last_message_at = GREATEST(pn.last_message_at, lid.last_message_at),
+            last_activity_at = GREATEST(pn.last_activity_at, lid.last_activity_at),
+            seq = nextval(pg_get_serial_sequence('conversations', 'seq')),
+            updated_at = NOW()
+        FROM conversations lid
+        WHERE pn.id = pn_conversation_id
+            AND lid.id = lid_conversation_id;
+
+        DELETE FROM conversations
+        WHERE id = lid_conversation_id;
+    END IF;
+
+    -- Group messages, votes and memberships by the LID in any conversation
+    UPDATE messages
+    SET sender_external_id = p_pn_jid,
+        seq = nextval(pg_get_serial_sequence('messages', 'seq')),
+        updated_at = NOW()
+    FROM conversations c
+    WHERE messages.conversation_id = c.id
+        AND c.user_integration_id = p_user_integration_id
+        AND messages.sender_external_id = p_lid_jid;
+
+    DELETE FROM poll_votes lid_vote USING poll_votes pn_vote, conversations c
+    WHERE lid_vote.conversation_id = c.id
+        AND c.user_integration_id = p_user_integration_id
+        AND lid_vote.voter_external_id = p_lid_jid
+        AND pn_vote.conversation_id = lid_vote.conversation_id
+        AND pn_vote.poll_external_message_id = lid_vote.poll_external_message_id
+        AND pn_vote.voter_external_id = p_pn_jid;
+    UPDATE poll_votes
+    SET voter_external_id = p_pn_jid,
+        updated_at = NOW()
+    FROM conversations c
+    WHERE poll_votes.conversation_id = c.id
+        AND c.user_integration_id = p_user_integration_id
+        AND poll_votes.voter_external_id = p_lid_jid;
+
+    DELETE FROM conversation_participants lid_participant USING conversation_participants pn_participant, conversations c
+    WHERE lid_participant.conversation_id = c.id
+        AND c.user_integration_id = p_user_integration_id
+        AND lid_participant.external_user_id = p_lid_jid
+        AND pn_participant.conversation_id = lid_participant.conversation_id
+        AND pn_participant.external_user_id = p_pn_jid;
+    UPDATE conversation_participants
+    SET external_user_id = p_pn_jid,
+        seq = nextval(pg_get_serial_sequence('conversation_participants', 'seq')),
+        updated_at = NOW()
+    FROM conversations c
+    WHERE conversation_participants.conversation_id = c.id
+        AND c.user_integration_id = p_user_integration_id
+        AND conversation_participants.external_user_id = p_lid_jid;
+
+    -- The phone-number contact wins; a LID-only contact takes its JID
+    IF EXISTS (
+        SELECT 1
+        FROM contacts
+        WHERE user_integration_id = p_user_integration_id
+            AND external_contact_id = p_pn_jid
+    ) THEN
+        DELETE FROM contacts
+        WHERE user_integration_id = p_user_integration_id
+            AND external_contact_id = p_lid_jid;
+    ELSE
+        UPDATE contacts
+        SET external_contact_id = p_pn_jid,
+            seq = nextval(pg_get_serial_sequence('contacts', 'seq')),
+            updated_at = NOW()
+        WHERE user_integration_id = p_user_integration_id
+            AND external_contact_id = p_lid_jid;
+    END IF;
+
+    RETURN lid_conversation_id IS NOT NULL;
+END;
+$$;
+COMMENT ON FUNCTION merge_contact_identity IS 'Merge conversations, messages and contacts stored under a LID into its phone-number JID';
+-- Seed mappings from the LIDs already stored on phone-number contacts and
+-- merge the duplicates they have created so far
+INSERT INTO contact_identities (user_integration_id, lid_jid, pn_jid)
+SELECT DISTINCT ON (user_integration_id, platform_metadata->>'lid_jid')
+    user_integration_id,
+    platform_metadata->>'lid_jid',
+    external_contact_id
+FROM contacts
+WHERE integration_type = 'whatsapp'
+    AND external_contact_id LIKE '%@s.whatsapp.net'
+    AND platform_metadata->>'lid_jid' LIKE '%@lid'
+ORDER BY user_integration_id,
+    platform_metadata->>'lid_jid',
+    updated_at DESC;
+SELECT merge_contact_identity(user_integration_id, lid_jid, pn_jid)
+FROM contact_identities;
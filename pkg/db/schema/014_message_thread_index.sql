@@ -0,0 +1,4 @@
+-- Index reply links per conversation so reply chains can be walked without
+-- scanning a conversation's messages
+CREATE INDEX idx_messages_conversation_reply ON messages (conversation_id, reply_to_message_id)
+WHERE reply_to_message_id IS NOT NULL;
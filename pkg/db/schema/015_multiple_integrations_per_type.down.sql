@@ -0,0 +1,5 @@
+-- Fails while a user has several integrations of one type. External IDs
+-- stay keyed by the account JID.
+ALTER TABLE user_integrations DROP CONSTRAINT unique_user_integration;
+ALTER TABLE user_integrations
+ADD CONSTRAINT unique_user_integration UNIQUE (user_id, integration_type);
@@ -0,0 +1,42 @@
+-- Let a user connect several integrations of the same type, e.g. two WhatsApp
+-- numbers. An external account still belongs to a single user
+-- (unique_external_id).
+-- WhatsApp integrations used to be stored under the paired device's JID
+-- ("<number>:<device>@s.whatsapp.net"). Key them by the account JID so that
+-- re-pairing a number updates its integration instead of adding another.
+-- Several integrations can map to the same account JID: the most recently
+-- updated one keeps the number and the others are disconnected, with
+-- metadata.superseded_by pointing at the one kept. They stay under their
+-- device JID, or under "<account JID>#superseded:<id>" if they already had
+-- the account JID.
+WITH ranked AS (
+    SELECT id,
+        regexp_replace(external_id, ':[0-9]+@', '@') AS account_jid,
+        ROW_NUMBER() OVER numbers AS rank,
+        FIRST_VALUE(id) OVER numbers AS kept_id
+    FROM user_integrations
+    WHERE integration_type = 'whatsapp'
+    WINDOW numbers AS (
+        PARTITION BY regexp_replace(external_id, ':[0-9]+@', '@')
+        ORDER BY updated_at DESC, id DESC
+    )
+)
+UPDATE user_integrations ui
+SET status = 'disconnected',
+    external_id = CASE
+        WHEN ui.external_id = r.account_jid THEN ui.external_id || '#superseded:' || ui.id
+        ELSE ui.external_id
+    END,
+    metadata = COALESCE(ui.metadata, '{}') || jsonb_build_object('superseded_by', r.kept_id),
+    updated_at = NOW()
+FROM ranked r
+WHERE ui.id = r.id
+    AND r.rank > 1;
+UPDATE user_integrations
+SET external_id = regexp_replace(external_id, ':[0-9]+@', '@')
+WHERE integration_type = 'whatsapp'
+    AND external_id ~ ':[0-9]+@'
+    AND NOT COALESCE(metadata, '{}') ? 'superseded_by';
+ALTER TABLE user_integrations DROP CONSTRAINT unique_user_integration;
+ALTER TABLE user_integrations
+ADD CONSTRAINT unique_user_integration UNIQUE (user_id, integration_type, external_id);
@@ -0,0 +1,43 @@
+-- Per-user webhook subscriptions that receive backend events over HTTP, and
+-- a record of every delivery for debugging
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT [] NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_event_seq BIGINT NOT NULL DEFAULT 0,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    disabled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_webhooks_user_id ON webhooks(user_id);
+CREATE INDEX idx_webhooks_enabled ON webhooks(id)
+WHERE enabled;
+COMMENT ON COLUMN webhooks.secret IS 'HMAC-SHA256 key used to sign delivery bodies';
+COMMENT ON COLUMN webhooks.event_types IS 'Event types to deliver; empty means all types';
+COMMENT ON COLUMN webhooks.last_event_seq IS 'Highest event seq queued for delivery';
+COMMENT ON COLUMN webhooks.consecutive_failures IS 'Failed delivery attempts since the last success; the webhook is disabled once this reaches the configured limit';
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_seq BIGINT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status_code INTEGER,
+    last_error TEXT,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_webhook_event UNIQUE (webhook_id, event_seq)
+);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at)
+WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id DESC);
+COMMENT ON COLUMN webhook_deliveries.payload IS 'Request body, captured when the delivery is queued so retention cannot remove it';
+COMMENT ON COLUMN webhook_deliveries.next_attempt_at IS 'When the delivery is next due; pushed forward while an attempt is in flight';
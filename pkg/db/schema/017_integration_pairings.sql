@@ -0,0 +1,12 @@
+-- Remember the idempotency key of each pairing so a retried
+-- CreateUserIntegration returns the integration it created instead of
+-- recording the pairing again
+CREATE TABLE integration_pairings (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key TEXT NOT NULL,
+    user_integration_id INTEGER NOT NULL REFERENCES user_integrations(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, idempotency_key)
+);
+CREATE INDEX idx_integration_pairings_integration ON integration_pairings(user_integration_id);
+COMMENT ON COLUMN integration_pairings.idempotency_key IS 'Chosen by the caller per pairing, e.g. the WhatsApp device JID';
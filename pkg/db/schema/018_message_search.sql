@@ -0,0 +1,6 @@
+-- Full-text search over message content. The 'simple' configuration neither
+-- stems nor drops stop words, so it behaves the same for every language a
+-- chat may be written in.
+ALTER TABLE messages
+ADD COLUMN content_tsv tsvector GENERATED ALWAYS AS (to_tsvector('simple', coalesce(content, ''))) STORED;
+CREATE INDEX idx_messages_content_tsv ON messages USING GIN (content_tsv);
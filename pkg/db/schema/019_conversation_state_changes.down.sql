@@ -0,0 +1 @@
+ALTER TABLE conversations DROP COLUMN state_changed_at;
@@ -0,0 +1,5 @@
+-- When a conversation's pin, archive or mute state was last changed, by the
+-- clock of whoever changed it. A state update older than this is a late echo
+-- of an earlier change and is ignored.
+ALTER TABLE conversations
+ADD COLUMN state_changed_at TIMESTAMPTZ;
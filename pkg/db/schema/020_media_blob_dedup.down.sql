@@ -0,0 +1,3 @@
+-- Duplicate attachments deleted by the migration are not restored
+DROP INDEX idx_message_media_message_type;
+ALTER TABLE message_media DROP COLUMN content_hash;
@@ -0,0 +1,16 @@
+-- Attachments point at their file by content hash, so one stored file in
+-- media_blobs serves every message it was sent or forwarded in. The hash is
+-- known from the message before the file is downloaded, so it isn't a
+-- foreign key.
+ALTER TABLE message_media
+ADD COLUMN content_hash TEXT;
+CREATE INDEX idx_message_media_content_hash ON message_media(content_hash)
+WHERE content_hash IS NOT NULL;
+-- A message's attachment is stored once however often the message is synced
+DELETE FROM message_media a USING message_media b
+WHERE a.message_id = b.message_id
+    AND a.media_type = b.media_type
+    AND (a.created_at, a.id) > (b.created_at, b.id);
+CREATE UNIQUE INDEX idx_message_media_message_type ON message_media(message_id, media_type);
+COMMENT ON COLUMN message_media.content_hash IS 'Hex SHA-256 of the file; matches media_blobs.content_hash once stored';
+COMMENT ON COLUMN media_blobs.storage_url IS 'Path of the file relative to the media directory';
@@ -0,0 +1 @@
+ALTER TABLE conversations DROP COLUMN last_read_at;
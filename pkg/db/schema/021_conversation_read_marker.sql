@@ -0,0 +1,6 @@
+-- When the newest message the user has read in a conversation was sent.
+-- Incoming messages after it are unread. NULL while unknown, as for a
+-- conversation synced with unread messages: its synced unread count stands
+-- until the user reads it.
+ALTER TABLE conversations
+ADD COLUMN last_read_at TIMESTAMPTZ;
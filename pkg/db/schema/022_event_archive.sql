@@ -0,0 +1,20 @@
+-- Events removed by retention when archiving is enabled. Rows keep their
+-- original seq; sync never reads them, so the account's low-water mark still
+-- applies.
+CREATE TABLE events_archive (
+    seq           BIGINT PRIMARY KEY,
+    id            UUID NOT NULL,
+    ts            TIMESTAMPTZ NOT NULL,
+    type          TEXT NOT NULL,
+    account_id    TEXT NOT NULL,
+    device_id     TEXT,
+    convo_id      TEXT NOT NULL,
+    wa_message_id TEXT,
+    sender_jid    TEXT,
+    payload       JSONB NOT NULL,
+    attachment_ref JSONB,
+    archived_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_events_archive_account_seq ON events_archive (account_id, seq);
+COMMENT ON TABLE events_archive IS 'Events removed from the events table by retention, kept for audit and recovery';
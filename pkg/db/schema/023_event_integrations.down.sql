@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_events_user_integration_seq;
+ALTER TABLE events_archive DROP COLUMN user_integration_id;
+ALTER TABLE events DROP COLUMN user_integration_id;
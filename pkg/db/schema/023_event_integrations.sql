@@ -0,0 +1,23 @@
+-- Events are keyed by account (the user ID) while synced data is keyed by
+-- user integration. Record the integration an event came through so the two
+-- can be cross-referenced; events from before this migration are backfilled
+-- only where the account has exactly one integration.
+ALTER TABLE events
+ADD COLUMN user_integration_id INTEGER REFERENCES user_integrations(id) ON DELETE SET NULL;
+
+ALTER TABLE events_archive
+ADD COLUMN user_integration_id INTEGER;
+
+UPDATE events e
+SET user_integration_id = ui.id
+FROM (
+    SELECT user_id, MIN(id) AS id
+    FROM user_integrations
+    GROUP BY user_id
+    HAVING COUNT(*) = 1
+) ui
+WHERE e.account_id = ui.user_id::text;
+
+CREATE INDEX idx_events_user_integration_seq ON events (user_integration_id, seq)
+WHERE user_integration_id IS NOT NULL;
+COMMENT ON COLUMN events.user_integration_id IS 'User integration the event came through; NULL when it is not tied to one';
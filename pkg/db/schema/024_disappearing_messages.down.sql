@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_messages_expires_at;
+ALTER TABLE messages DROP COLUMN expires_at;
+ALTER TABLE conversations DROP COLUMN disappearing_seconds;
@@ -0,0 +1,13 @@
+-- Disappearing messages. A conversation's timer applies to messages stored
+-- while it is on; each message records when it expires so the expiry worker
+-- can purge it without consulting the conversation.
+ALTER TABLE conversations
+ADD COLUMN disappearing_seconds INTEGER NOT NULL DEFAULT 0 CHECK (disappearing_seconds >= 0);
+
+ALTER TABLE messages
+ADD COLUMN expires_at TIMESTAMPTZ;
+
+CREATE INDEX idx_messages_expires_at ON messages (expires_at)
+WHERE expires_at IS NOT NULL;
+COMMENT ON COLUMN conversations.disappearing_seconds IS 'Disappearing messages timer in seconds; 0 when off';
+COMMENT ON COLUMN messages.expires_at IS 'When the message disappears and is purged; NULL for messages that are kept';
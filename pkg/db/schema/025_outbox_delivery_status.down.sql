@@ -0,0 +1,4 @@
+UPDATE outbox SET status = 'sent' WHERE status IN ('delivered', 'read');
+ALTER TABLE outbox DROP CONSTRAINT outbox_status_check;
+ALTER TABLE outbox ADD CONSTRAINT outbox_status_check
+    CHECK (status IN ('queued', 'sending', 'sent', 'failed', 'retry'));
@@ -0,0 +1,5 @@
+-- Delivery and read receipts from the bridge advance sent outbox entries, so
+-- clients can tell a message reached the recipient.
+ALTER TABLE outbox DROP CONSTRAINT outbox_status_check;
+ALTER TABLE outbox ADD CONSTRAINT outbox_status_check
+    CHECK (status IN ('queued', 'sending', 'sent', 'delivered', 'read', 'failed', 'retry'));
@@ -0,0 +1,4 @@
+-- Mutes with an expiry are lifted by the mute expiry worker once mute_until
+-- passes; this index lets it find them without scanning every conversation.
+CREATE INDEX idx_conversations_mute_until ON conversations (mute_until)
+WHERE is_muted AND mute_until IS NOT NULL;
@@ -0,0 +1,17 @@
+-- Single-use tokens for resetting a forgotten password. Only a SHA-256 hash
+-- of each token is stored, so a leaked table can't be used to reset
+-- passwords. A token is spent once used_at is set.
+CREATE TABLE password_reset_tokens (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash  TEXT NOT NULL UNIQUE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_password_reset_tokens_user ON password_reset_tokens (user_id)
+WHERE used_at IS NULL;
+COMMENT ON TABLE password_reset_tokens IS 'Single-use password reset tokens, stored hashed';
+COMMENT ON COLUMN password_reset_tokens.token_hash IS 'Hex SHA-256 of the token sent to the user';
+COMMENT ON COLUMN password_reset_tokens.used_at IS 'When the token was used or revoked; NULL while it can still be used';
@@ -0,0 +1,30 @@
+-- Events are keyed by account_id, which is the ID of the user owning the
+-- account. Events written before that convention used other account IDs
+-- (such as the WhatsApp JID); map each of those to the user owning it so the
+-- API can check an account belongs to the caller. Account IDs that are user
+-- IDs need no mapping.
+CREATE TABLE event_accounts (
+    account_id  TEXT PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_event_accounts_user ON event_accounts (user_id);
+
+-- Legacy accounts keyed by an integration's external ID
+INSERT INTO event_accounts (account_id, user_id)
+SELECT DISTINCT e.account_id, ui.user_id
+FROM events e
+JOIN user_integrations ui ON ui.external_id = e.account_id
+WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id::text = e.account_id)
+ON CONFLICT (account_id) DO NOTHING;
+
+-- Any other legacy account whose events came through an integration
+INSERT INTO event_accounts (account_id, user_id)
+SELECT DISTINCT e.account_id, ui.user_id
+FROM events e
+JOIN user_integrations ui ON ui.id = e.user_integration_id
+WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id::text = e.account_id)
+ON CONFLICT (account_id) DO NOTHING;
+
+COMMENT ON TABLE event_accounts IS 'Owner of each legacy event account_id that is not a user ID';
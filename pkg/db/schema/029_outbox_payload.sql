@@ -0,0 +1,12 @@
+-- Outbox entries carry the message they send, so the worker builds the bridge
+-- request from the entry alone instead of reading its event back.
+-- server_msg_id still links an entry to its msg_out_pending event.
+ALTER TABLE outbox ADD COLUMN payload JSONB;
+
+-- Entries queued before carry their event's payload
+UPDATE outbox o
+SET payload = e.payload
+FROM events e
+WHERE e.seq = o.server_msg_id;
+
+COMMENT ON COLUMN outbox.payload IS 'The outbound message (a MessageOutPayload); null only for entries whose event was gone when the column was added';
@@ -0,0 +1,5 @@
+-- When a user's password was last reset. Access tokens issued before it are
+-- refused, so a reset signs out every existing session.
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN users.password_changed_at IS 'When the password was last reset; tokens issued before it are revoked. NULL if never reset';
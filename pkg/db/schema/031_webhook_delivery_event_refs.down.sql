@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_webhook_deliveries_finished;
+
+ALTER TABLE webhook_deliveries ADD COLUMN payload JSONB;
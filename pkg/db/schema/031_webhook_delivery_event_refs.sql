@@ -0,0 +1,9 @@
+-- Webhook deliveries reference their event by seq instead of holding a copy of
+-- its payload; the worker reads the payload when it sends. Finished deliveries
+-- are deleted once they are past the webhook retention.
+ALTER TABLE webhook_deliveries DROP COLUMN payload;
+
+CREATE INDEX idx_webhook_deliveries_finished ON webhook_deliveries(updated_at)
+WHERE status <> 'pending';
+
+COMMENT ON COLUMN webhook_deliveries.event_seq IS 'The delivered event; a delivery whose event is gone when it is due is dropped';
@@ -0,0 +1,3 @@
+-- Expired disappearing messages have their archived events redacted by
+-- WhatsApp message ID, like their live events.
+CREATE INDEX idx_events_archive_wa_message_id ON events_archive (wa_message_id) WHERE wa_message_id IS NOT NULL;
@@ -0,0 +1,55 @@
+package events
+
+import "strings"
+
+// The outbox work queue is a JetStream stream through which the backend can
+// hand outbound messages to bridges instead of calling one bridge over gRPC.
+// The backend publishes each SendMessageRequest to OutboxSendSubject for its
+// integration type; the bridge that holds the account's session sends it and
+// publishes a SendMessageResult to OutboxResultSubject. Results the backend
+// can't apply are moved to OutboxDeadLetterSubject.
+
+// OutboxStreamName returns the name of the outbox work queue stream for a
+// subject prefix, e.g. "TENNEX_PROD_OUTBOX" for "tennex.prod"
+func OutboxStreamName(prefix string) string {
+	if prefix = strings.Trim(prefix, "."); prefix != "" {
+		return strings.ToUpper(strings.ReplaceAll(prefix, ".", "_")) + "_OUTBOX"
+	}
+	return "OUTBOX"
+}
+
+// OutboxSendSubject returns the subject bridges of an integration type
+// consume outbound messages from, e.g. "tennex.prod.outbox.send.whatsapp"
+func OutboxSendSubject(prefix, integrationType string) string {
+	return prefixed(prefix, "outbox.send."+integrationType)
+}
+
+// OutboxResultSubject returns the subject bridges report send results on
+func OutboxResultSubject(prefix string) string {
+	return prefixed(prefix, "outbox.result")
+}
+
+// OutboxDeadLetterSubject returns the subject the backend moves send results
+// to once it has given up applying them
+func OutboxDeadLetterSubject(prefix string) string {
+	return prefixed(prefix, "outbox.dead")
+}
+
+// OutboxStreamSubjects returns every subject captured by the outbox stream
+func OutboxStreamSubjects(prefix string) []string {
+	return []string{OutboxSendSubject(prefix, "*"), OutboxResultSubject(prefix), OutboxDeadLetterSubject(prefix)}
+}
+
+// OutboxSentBucket returns the name of the key-value bucket in which bridges
+// record the messages they sent, keyed by client_msg_uuid, so a redelivered
+// message isn't sent twice
+func OutboxSentBucket(prefix string) string {
+	return OutboxStreamName(prefix) + "_SENT"
+}
+
+func prefixed(prefix, subject string) string {
+	if prefix = strings.Trim(prefix, "."); prefix != "" {
+		subject = prefix + "." + subject
+	}
+	return subject
+}
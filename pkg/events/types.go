@@ -19,6 +19,15 @@ const (
 
 	// History synchronization
 	TypeHistorySync = "history_sync" // WhatsApp history import
+
+	// Conversation pin/mute/archive changes
+	TypeConversationState = "conversation_state"
+
+	// Disappearing messages that were purged
+	TypeMessagesExpired = "msg_expired"
+
+	// Group participants promoted or demoted
+	TypeParticipantRole = "participant_role"
 )
 
 // Message content types
@@ -43,11 +52,28 @@ const (
 
 // Outbox status values
 const (
-	OutboxStatusQueued  = "queued"
-	OutboxStatusSending = "sending"
-	OutboxStatusSent    = "sent"
-	OutboxStatusFailed  = "failed"
-	OutboxStatusRetry   = "retry"
+	OutboxStatusQueued    = "queued"
+	OutboxStatusSending   = "sending"
+	OutboxStatusSent      = "sent"
+	OutboxStatusDelivered = "delivered" // Reached the recipient's device
+	OutboxStatusRead      = "read"
+	OutboxStatusFailed    = "failed"
+	OutboxStatusRetry     = "retry"
+)
+
+// Contact and conversation avatars
+const (
+	// AvatarURLPrefix is the backend path stored avatars are served under. An
+	// avatar's URL is the prefix followed by the content hash of the picture.
+	AvatarURLPrefix = "/v1/media/avatars/"
+
+	// AvatarPictureIDKey is the platform metadata key holding the platform's
+	// ID of the picture an avatar is, so an unchanged picture isn't fetched again
+	AvatarPictureIDKey = "avatar_picture_id"
+
+	// SyncTypeAvatar is the sync type of conversation syncs that only carry
+	// new avatars; the conversations' other fields are left as stored
+	SyncTypeAvatar = "AVATAR"
 )
 
 // MessageInPayload represents the payload for inbound messages
@@ -99,6 +125,43 @@ type ContactUpdatePayload struct {
 	IsBlocked   bool   `json:"is_blocked"`
 }
 
+// ConversationStatePayload represents a change to a conversation's pin, mute
+// or archive state. Changed lists the JSON names of the fields that changed;
+// only those fields are set. A changed but unset mute_until means the mute no
+// longer has an expiry.
+type ConversationStatePayload struct {
+	Changed    []string   `json:"changed"`
+	IsPinned   *bool      `json:"is_pinned,omitempty"`
+	IsArchived *bool      `json:"is_archived,omitempty"`
+	IsMuted    *bool      `json:"is_muted,omitempty"`
+	MuteUntil  *time.Time `json:"mute_until,omitempty"`
+}
+
+// Conversation state fields, as named in ConversationStatePayload.Changed
+const (
+	ConversationFieldPinned    = "is_pinned"
+	ConversationFieldArchived  = "is_archived"
+	ConversationFieldMuted     = "is_muted"
+	ConversationFieldMuteUntil = "mute_until"
+)
+
+// MessagesExpiredPayload lists the messages of a conversation that were purged
+// when their disappearing timer ran out
+type MessagesExpiredPayload struct {
+	MessageIDs []string  `json:"message_ids"` // External message IDs
+	ExpiredAt  time.Time `json:"expired_at"`
+}
+
+// ParticipantRolePayload represents a group participant's role change.
+// PreviousRole is empty when the participant wasn't known before.
+type ParticipantRolePayload struct {
+	JID          string     `json:"jid"`
+	Role         string     `json:"role"` // "member", "admin", "owner", "moderator"
+	PreviousRole string     `json:"previous_role,omitempty"`
+	ChangedBy    string     `json:"changed_by,omitempty"`
+	ChangedAt    *time.Time `json:"changed_at,omitempty"`
+}
+
 // HistorySyncPayload represents history synchronization metadata
 type HistorySyncPayload struct {
 	ConversationCount int        `json:"conversation_count"`
@@ -110,6 +173,14 @@ type HistorySyncPayload struct {
 	CompletedAt       *time.Time `json:"completed_at,omitempty"`
 }
 
+// OversizedPayload stands in for an event payload larger than the backend
+// stores inline. The payload itself is referenced by the event's
+// attachment_ref and served at /v1/events/{seq}/payload.
+type OversizedPayload struct {
+	Oversized bool  `json:"oversized"`
+	SizeBytes int64 `json:"size_bytes"`
+}
+
 // AttachmentRef represents a reference to media content
 type AttachmentRef struct {
 	ContentHash string `json:"content_hash"`
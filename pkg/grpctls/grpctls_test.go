@@ -0,0 +1,181 @@
+package grpctls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// testPKI is a CA with a server and a client certificate it signed, written
+// to PEM files
+type testPKI struct {
+	caFile, serverCert, serverKey, clientCert, clientKey string
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage) (string, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certFile := writePEM(t, dir, name+".crt", "CERTIFICATE", der)
+		keyFile := writePEM(t, dir, name+".key", "EC PRIVATE KEY", keyDER)
+		return certFile, keyFile
+	}
+
+	pki := testPKI{caFile: writePEM(t, dir, "ca.crt", "CERTIFICATE", caDER)}
+	pki.serverCert, pki.serverKey = issue("backend", 2, x509.ExtKeyUsageServerAuth)
+	pki.clientCert, pki.clientKey = issue("bridge", 3, x509.ExtKeyUsageClientAuth)
+	return pki
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// serve starts a gRPC server with the health service and returns its address
+func serve(t *testing.T, config Config) string {
+	t.Helper()
+	option, err := ServerOption(config)
+	if err != nil {
+		t.Fatalf("ServerOption: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(option)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+// check calls the health service at addr
+func check(t *testing.T, addr string, config Config) error {
+	t.Helper()
+	option, err := DialOption(config)
+	if err != nil {
+		t.Fatalf("DialOption: %v", err)
+	}
+	conn, err := grpc.NewClient(addr, option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	addr := serve(t, Config{Enabled: true, CertFile: pki.serverCert, KeyFile: pki.serverKey})
+
+	if err := check(t, addr, Config{Enabled: true, CAFile: pki.caFile, ServerName: "backend"}); err != nil {
+		t.Errorf("expected a client trusting the CA to connect, got %v", err)
+	}
+	if err := check(t, addr, Config{}); err == nil {
+		t.Error("expected a plaintext client to be refused")
+	}
+	if err := check(t, addr, Config{Enabled: true, CAFile: pki.caFile, ServerName: "other"}); err == nil {
+		t.Error("expected a certificate for another name to be refused")
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	addr := serve(t, Config{Enabled: true, CAFile: pki.caFile, CertFile: pki.serverCert, KeyFile: pki.serverKey})
+
+	client := Config{Enabled: true, CAFile: pki.caFile, ServerName: "backend"}
+	if err := check(t, addr, client); err == nil {
+		t.Error("expected a client without a certificate to be refused")
+	}
+
+	client.CertFile, client.KeyFile = pki.clientCert, pki.clientKey
+	if err := check(t, addr, client); err != nil {
+		t.Errorf("expected a client with a certificate to connect, got %v", err)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	pki := newTestPKI(t)
+
+	if _, err := ServerCredentials(Config{Enabled: true}); err == nil {
+		t.Error("expected a server without a certificate to be rejected")
+	}
+	if _, err := ClientCredentials(Config{Enabled: true, CAFile: pki.serverKey}); err == nil {
+		t.Error("expected a CA file without certificates to be rejected")
+	}
+	if _, err := ClientCredentials(Config{Enabled: true, CertFile: pki.clientCert}); err == nil {
+		t.Error("expected a client certificate without its key to be rejected")
+	}
+
+	// Settings are ignored unless TLS is enabled
+	if _, err := ServerCredentials(Config{CAFile: "missing.pem"}); err != nil {
+		t.Errorf("expected TLS settings to be ignored when disabled, got %v", err)
+	}
+}
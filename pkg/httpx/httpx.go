@@ -0,0 +1,122 @@
+// Package httpx holds the JSON response and request helpers the HTTP
+// handlers share, so every service answers with the same shapes.
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/tennex/pkg/apierror"
+)
+
+// ErrorResponse is the body of every error response. It matches the
+// ErrorResponse schema of the OpenAPI specs.
+type ErrorResponse struct {
+	Error     string                  `json:"error"`
+	Code      *string                 `json:"code,omitempty"`
+	Details   *map[string]interface{} `json:"details,omitempty"`
+	Timestamp time.Time               `json:"timestamp"`
+}
+
+// Hooks let a service observe responses, e.g. to log them with its own logger
+type Hooks struct {
+	// Called with every error response before it is written
+	OnError func(e *apierror.E)
+
+	// Called when a response body fails to encode
+	OnEncodeError func(err error)
+}
+
+var hooks atomic.Pointer[Hooks]
+
+// SetHooks installs the hooks every response goes through. Services call it
+// once at startup.
+func SetHooks(h Hooks) {
+	hooks.Store(&h)
+}
+
+// JSON writes v as a JSON response with the given status
+func JSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		if h := hooks.Load(); h != nil && h.OnEncodeError != nil {
+			h.OnEncodeError(err)
+		}
+	}
+}
+
+// Error writes e as an ErrorResponse
+func Error(w http.ResponseWriter, e *apierror.E) {
+	if h := hooks.Load(); h != nil && h.OnError != nil {
+		h.OnError(e)
+	}
+
+	response := ErrorResponse{
+		Error:     e.Message,
+		Timestamp: time.Now().UTC(),
+	}
+	if e.Code != "" {
+		response.Code = &e.Code
+	}
+
+	details := make(map[string]interface{}, len(e.Details)+1)
+	for key, value := range e.Details {
+		details[key] = value
+	}
+	if e.Err != nil {
+		details["details"] = e.Err.Error()
+	}
+	if len(details) > 0 {
+		response.Details = &details
+	}
+
+	JSON(w, e.Status, response)
+}
+
+// MaxBodySize limits request bodies to maxBytes. Reading past the limit fails
+// and closes the connection once the response is written.
+func MaxBodySize(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Decode decodes a JSON request body into v, reading at most maxBytes of it.
+// A maxBytes of 0 or less leaves the limit to MaxBodySize. The error is 413
+// for an oversized body and 400 otherwise; an empty body's error wraps io.EOF.
+func Decode(r *http.Request, v interface{}, maxBytes int64) *apierror.E {
+	return decode(newDecoder(r, maxBytes), v)
+}
+
+// DecodeStrict is Decode, but also rejects fields v doesn't have, so
+// misspelled fields fail loudly instead of being dropped
+func DecodeStrict(r *http.Request, v interface{}, maxBytes int64) *apierror.E {
+	decoder := newDecoder(r, maxBytes)
+	decoder.DisallowUnknownFields()
+	return decode(decoder, v)
+}
+
+func newDecoder(r *http.Request, maxBytes int64) *json.Decoder {
+	if maxBytes > 0 {
+		r.Body = http.MaxBytesReader(nil, r.Body, maxBytes)
+	}
+	return json.NewDecoder(r.Body)
+}
+
+func decode(decoder *json.Decoder, v interface{}) *apierror.E {
+	if err := decoder.Decode(v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return apierror.Wrap(http.StatusRequestEntityTooLarge, "Request body too large", err).WithCode("request_too_large")
+		}
+		return apierror.Wrap(http.StatusBadRequest, "Invalid request body", err).WithCode("invalid_request")
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tennex/pkg/apierror"
+)
+
+func TestJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	JSON(rec, http.StatusCreated, map[string]string{"status": "ok"})
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("got content type %q", contentType)
+	}
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"status":"ok"}` {
+		t.Errorf("got body %s", body)
+	}
+}
+
+func TestErrorMatchesTheErrorResponseSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     *apierror.E
+		code    interface{}
+		details interface{}
+	}{
+		{
+			name: "message only",
+			err:  apierror.New(http.StatusNotFound, "Not found"),
+		},
+		{
+			name:    "with cause",
+			err:     apierror.Wrap(http.StatusInternalServerError, "Database error", errors.New("connection refused")),
+			details: map[string]interface{}{"details": "connection refused"},
+		},
+		{
+			name: "with code and details",
+			err: &apierror.E{
+				Status:  http.StatusConflict,
+				Code:    "already_connected",
+				Message: "Already connected",
+				Details: map[string]interface{}{"session_id": "s1"},
+			},
+			code:    "already_connected",
+			details: map[string]interface{}{"session_id": "s1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Error(rec, tt.err)
+
+			if rec.Code != tt.err.Status {
+				t.Errorf("got status %d, want %d", rec.Code, tt.err.Status)
+			}
+			var body map[string]interface{}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if body["error"] != tt.err.Message {
+				t.Errorf("got error %v, want %q", body["error"], tt.err.Message)
+			}
+			if _, ok := body["timestamp"].(string); !ok {
+				t.Errorf("missing timestamp in %v", body)
+			}
+			if body["code"] != tt.code {
+				t.Errorf("got code %v, want %v", body["code"], tt.code)
+			}
+			if tt.details == nil {
+				if _, ok := body["details"]; ok {
+					t.Errorf("expected no details, got %v", body["details"])
+				}
+			} else if got, _ := json.Marshal(body["details"]); string(got) != mustMarshal(t, tt.details) {
+				t.Errorf("got details %s, want %s", got, mustMarshal(t, tt.details))
+			}
+		})
+	}
+}
+
+func TestHooks(t *testing.T) {
+	var reported []*apierror.E
+	var encodeErrs []error
+	SetHooks(Hooks{
+		OnError:       func(e *apierror.E) { reported = append(reported, e) },
+		OnEncodeError: func(err error) { encodeErrs = append(encodeErrs, err) },
+	})
+	t.Cleanup(func() { SetHooks(Hooks{}) })
+
+	e := apierror.New(http.StatusBadRequest, "Bad")
+	Error(httptest.NewRecorder(), e)
+	JSON(httptest.NewRecorder(), http.StatusOK, func() {})
+
+	if len(reported) != 1 || reported[0] != e {
+		t.Errorf("expected the error to be reported once, got %v", reported)
+	}
+	if len(encodeErrs) != 1 {
+		t.Errorf("expected one encode error, got %v", encodeErrs)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	type request struct {
+		Text string `json:"text"`
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		strict   bool
+		maxBytes int64
+		want     int
+	}{
+		{name: "valid", body: `{"text":"hi"}`, maxBytes: 32, want: http.StatusOK},
+		{name: "no limit", body: `{"text":"` + strings.Repeat("x", 64) + `"}`, want: http.StatusOK},
+		{name: "oversized", body: `{"text":"` + strings.Repeat("x", 64) + `"}`, maxBytes: 32, want: http.StatusRequestEntityTooLarge},
+		{name: "malformed", body: `{"text":`, want: http.StatusBadRequest},
+		{name: "unknown field", body: `{"text":"hi","txet":"typo"}`, want: http.StatusOK},
+		{name: "strict unknown field", body: `{"text":"hi","txet":"typo"}`, strict: true, want: http.StatusBadRequest},
+		{name: "strict oversized", body: `{"text":"` + strings.Repeat("x", 64) + `"}`, strict: true, maxBytes: 32, want: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			var req request
+			var err *apierror.E
+			if tt.strict {
+				err = DecodeStrict(r, &req, tt.maxBytes)
+			} else {
+				err = Decode(r, &req, tt.maxBytes)
+			}
+
+			status := http.StatusOK
+			if err != nil {
+				status = err.Status
+			}
+			if status != tt.want {
+				t.Errorf("got status %d, want %d: %v", status, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestDecodeEmptyBodyWrapsEOF(t *testing.T) {
+	var req struct{}
+	err := Decode(httptest.NewRequest(http.MethodPost, "/", http.NoBody), &req, 0)
+	if err == nil || err.Status != http.StatusBadRequest || !errors.Is(err, io.EOF) {
+		t.Errorf("expected a 400 wrapping io.EOF, got %v", err)
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	handler := MaxBodySize(32)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Text string `json:"text"`
+		}
+		if err := Decode(r, &req, 0); err != nil {
+			Error(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for body, want := range map[string]int{
+		`{"text":"hi"}`: http.StatusOK,
+		`{"text":"` + strings.Repeat("x", 64) + `"}`: http.StatusRequestEntityTooLarge,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		if rec.Code != want {
+			t.Errorf("body %.20q: got status %d, want %d", body, rec.Code, want)
+		}
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.10"})
+	if err != nil {
+		t.Fatalf("failed to parse trusted proxies: %v", err)
+	}
+	var got string
+	handler := RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.RemoteAddr
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"direct client", "203.0.113.5:4000", nil, "203.0.113.5:4000"},
+		{"client spoofing X-Forwarded-For", "203.0.113.5:4000", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.5:4000"},
+		{"client spoofing X-Real-IP", "203.0.113.5:4000", map[string]string{"X-Real-IP": "198.51.100.1"}, "203.0.113.5:4000"},
+		{"trusted proxy", "10.1.2.3:4000", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		{"trusted single address", "192.0.2.10:4000", map[string]string{"X-Real-IP": "198.51.100.1"}, "198.51.100.1"},
+		{"client-supplied hops before the proxy's", "10.1.2.3:4000", map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1, 10.9.9.9"}, "198.51.100.1"},
+		{"trusted proxy without headers", "10.1.2.3:4000", nil, "10.1.2.3:4000"},
+		{"trusted proxy with a bad header", "10.1.2.3:4000", map[string]string{"X-Real-IP": "not-an-ip"}, "10.1.2.3:4000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("got RemoteAddr %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseTrustedProxies([]string{"proxy.local"}); err == nil {
+		t.Error("expected a hostname to be rejected")
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,105 @@
+// Package natsx holds the NATS helpers the services share: a publisher that
+// counts what it fails to deliver, draining on shutdown, and the connection
+// state reported by health checks.
+package natsx
+
+import (
+	"errors"
+	"expvar"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// publishRetryDelay is how long a publish that may succeed on a second attempt
+// waits before it. It's short, as publishers are on request paths.
+const publishRetryDelay = 100 * time.Millisecond
+
+// droppedPublishes counts the publishes that failed for good, across every
+// publisher in the process
+var droppedPublishes = expvar.NewInt("nats_dropped_publishes")
+
+// DroppedPublishes returns how many publishes failed for good
+func DroppedPublishes() int64 {
+	return droppedPublishes.Value()
+}
+
+// Publisher publishes messages to a NATS connection. A publish that failed
+// because the connection was closed or its reconnect buffer was full is
+// retried once; a publish that still fails is counted as dropped.
+type Publisher struct {
+	nc         *nats.Conn
+	retryDelay time.Duration
+	logger     *zap.Logger
+}
+
+// NewPublisher creates a publisher for nc
+func NewPublisher(nc *nats.Conn, logger *zap.Logger) *Publisher {
+	return &Publisher{
+		nc:         nc,
+		retryDelay: publishRetryDelay,
+		logger:     logger.Named("nats_publisher"),
+	}
+}
+
+// Publish publishes data to subject. It implements the publisher interfaces
+// of the services, which *nats.Conn implements too.
+func (p *Publisher) Publish(subject string, data []byte) error {
+	err := p.nc.Publish(subject, data)
+	if err != nil && retryable(err) {
+		time.Sleep(p.retryDelay)
+		err = p.nc.Publish(subject, data)
+	}
+	if err != nil {
+		droppedPublishes.Add(1)
+		p.logger.Warn("Dropped NATS publish",
+			zap.String("subject", subject),
+			zap.Int("bytes", len(data)),
+			zap.Error(err))
+	}
+	return err
+}
+
+// retryable reports whether a publish failed because of the connection rather
+// than the message, e.g. a reconnect buffer that empties once the connection
+// is back
+func retryable(err error) bool {
+	return errors.Is(err, nats.ErrConnectionClosed) || errors.Is(err, nats.ErrReconnectBufExceeded)
+}
+
+// Drain drains nc, so subscriptions stop after handling what they already
+// received and buffered publishes are flushed, then closes it. It waits up to
+// timeout for the drain and closes the connection regardless after that.
+func Drain(nc *nats.Conn, timeout time.Duration, logger *zap.Logger) {
+	if nc == nil || nc.IsClosed() {
+		return
+	}
+
+	if err := nc.Drain(); err != nil {
+		logger.Warn("Failed to drain NATS connection, closing it", zap.Error(err))
+		nc.Close()
+		return
+	}
+
+	deadline := time.Now().Add(timeout)
+	for !nc.IsClosed() {
+		if time.Now().After(deadline) {
+			logger.Warn("NATS drain timed out, closing the connection", zap.Duration("timeout", timeout))
+			nc.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	logger.Info("NATS connection drained")
+}
+
+// State returns the state of nc for health checks, e.g. "connected" or
+// "reconnecting"
+func State(nc *nats.Conn) string {
+	if nc == nil {
+		return "disconnected"
+	}
+	return strings.ToLower(nc.Status().String())
+}
@@ -0,0 +1,186 @@
+package natsx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// fakeServer speaks enough of the NATS client protocol for a connection to
+// publish, subscribe, flush and drain against it, recording the subjects
+// published. Messages are delivered back to the publishing connection only.
+type fakeServer struct {
+	listener net.Listener
+
+	mu        sync.Mutex
+	published []string
+}
+
+func startFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &fakeServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) URL() string {
+	return "nats://" + s.listener.Addr().String()
+}
+
+func (s *fakeServer) Published() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.published...)
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\",\"version\":\"2.10.0\",\"proto\":1,\"headers\":true,\"max_payload\":1048576}\r\n")
+	r := bufio.NewReader(conn)
+	subscriptions := make(map[string]string) // Subject to subscription ID
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "PING":
+			fmt.Fprintf(conn, "PONG\r\n")
+		case "SUB":
+			// SUB <subject> [queue group] <sid>
+			subscriptions[fields[1]] = fields[len(fields)-1]
+		case "PUB":
+			// PUB <subject> [reply-to] <#bytes>, then the payload and CRLF
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return
+			}
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.published = append(s.published, fields[1])
+			s.mu.Unlock()
+			if sid, ok := subscriptions[fields[1]]; ok {
+				fmt.Fprintf(conn, "MSG %s %s %d\r\n%s", fields[1], sid, size, payload)
+			}
+		}
+	}
+}
+
+func TestDrainFlushesBufferedPublishes(t *testing.T) {
+	server := startFakeServer(t)
+	nc, err := nats.Connect(server.URL())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+
+	publisher := NewPublisher(nc, zap.NewNop())
+	for i := 0; i < 3; i++ {
+		if err := publisher.Publish(fmt.Sprintf("notify.%d", i), []byte("{}")); err != nil {
+			t.Fatalf("publish %d failed: %v", i, err)
+		}
+	}
+
+	Drain(nc, time.Second, zap.NewNop())
+	if !nc.IsClosed() {
+		t.Fatal("expected the connection to be closed after draining")
+	}
+	if got := server.Published(); strings.Join(got, ",") != "notify.0,notify.1,notify.2" {
+		t.Errorf("expected every publish to reach the server before the connection closed, got %v", got)
+	}
+	if got := State(nc); got != "closed" {
+		t.Errorf("got state %q, want closed", got)
+	}
+}
+
+func TestDrainClosesAConnectionThatDoesntFinish(t *testing.T) {
+	server := startFakeServer(t)
+	nc, err := nats.Connect(server.URL(), nats.DrainTimeout(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	// A subscription whose handler is stuck keeps the drain from finishing
+	release := make(chan struct{})
+	defer close(release)
+	if _, err := nc.Subscribe("stuck", func(*nats.Msg) { <-release }); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if err := nc.Publish("stuck", nil); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	if err := nc.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+
+	start := time.Now()
+	Drain(nc, 100*time.Millisecond, zap.NewNop())
+	if !nc.IsClosed() {
+		t.Fatal("expected the connection to be closed once the drain timed out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected Drain to give up after its timeout, took %s", elapsed)
+	}
+}
+
+func TestPublishCountsDroppedMessages(t *testing.T) {
+	server := startFakeServer(t)
+	nc, err := nats.Connect(server.URL())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	publisher := NewPublisher(nc, zap.NewNop())
+	publisher.retryDelay = 0
+
+	before := DroppedPublishes()
+	if err := publisher.Publish("notify.ok", []byte("{}")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if got := DroppedPublishes() - before; got != 0 {
+		t.Errorf("expected nothing dropped, got %d", got)
+	}
+
+	nc.Close()
+	if err := publisher.Publish("notify.closed", []byte("{}")); err != nats.ErrConnectionClosed {
+		t.Errorf("got %v, want %v", err, nats.ErrConnectionClosed)
+	}
+	if got := DroppedPublishes() - before; got != 1 {
+		t.Errorf("expected 1 dropped publish, got %d", got)
+	}
+}
+
+func TestStateWithoutConnection(t *testing.T) {
+	if got := State(nil); got != "disconnected" {
+		t.Errorf("got %q, want disconnected", got)
+	}
+}
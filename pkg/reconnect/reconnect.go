@@ -0,0 +1,51 @@
+// Package reconnect paces reconnection to a dependency that went away. The
+// delay between attempts grows exponentially up to a ceiling and is jittered,
+// so the clients a restart drops don't all come back at the same moment and
+// hammer the service while it recovers.
+package reconnect
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff controls the delay between reconnection attempts
+type Backoff struct {
+	Initial time.Duration // Delay before the first attempt
+	Max     time.Duration // Ceiling on the delay between attempts
+
+	jitter func() float64 // In [0, 1); rand.Float64 when nil
+}
+
+// DefaultBackoff returns the backoff used unless configured otherwise
+func DefaultBackoff() Backoff {
+	return Backoff{
+		Initial: 2 * time.Second,
+		Max:     time.Minute,
+	}
+}
+
+// WithMax returns the backoff with a different ceiling. The initial delay is
+// lowered to match when it is above the ceiling.
+func (b Backoff) WithMax(ceiling time.Duration) Backoff {
+	b.Max = ceiling
+	b.Initial = min(b.Initial, ceiling)
+	return b
+}
+
+// Delay returns the delay before the given attempt, counting from 1: the
+// initial delay doubled per attempt, capped at the ceiling, with the upper
+// half jittered. It fits nats.CustomReconnectDelay.
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := b.Initial
+	for i := 1; i < attempt && delay < b.Max; i++ {
+		delay *= 2
+	}
+	delay = min(delay, b.Max)
+
+	jitter := b.jitter
+	if jitter == nil {
+		jitter = rand.Float64
+	}
+	return delay/2 + time.Duration(jitter()*float64(delay/2))
+}
@@ -0,0 +1,47 @@
+package reconnect
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: 8 * time.Second}
+
+	b.jitter = func() float64 { return 1 }
+	for i, want := range []time.Duration{1, 2, 4, 8, 8, 8} {
+		if got := b.Delay(i + 1); got != want*time.Second {
+			t.Errorf("Delay(%d) with full jitter = %s, want %s", i+1, got, want*time.Second)
+		}
+	}
+
+	// Jitter only ever shortens the delay, down to half
+	b.jitter = func() float64 { return 0 }
+	for i, want := range []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		if got := b.Delay(i + 1); got != want {
+			t.Errorf("Delay(%d) without jitter = %s, want %s", i+1, got, want)
+		}
+	}
+}
+
+func TestBackoffDelayStaysUnderTheCeiling(t *testing.T) {
+	b := DefaultBackoff()
+	for attempt := 1; attempt < 1000; attempt *= 3 {
+		if got := b.Delay(attempt); got > b.Max || got < b.Initial/2 {
+			t.Errorf("Delay(%d) = %s, want between %s and %s", attempt, got, b.Initial/2, b.Max)
+		}
+	}
+}
+
+func TestBackoffWithMax(t *testing.T) {
+	b := DefaultBackoff().WithMax(10 * time.Second)
+	if b.Max != 10*time.Second || b.Initial != DefaultBackoff().Initial {
+		t.Errorf("unexpected backoff %+v", b)
+	}
+
+	// A ceiling below the initial delay lowers it too
+	b = DefaultBackoff().WithMax(time.Second)
+	if b.Initial != time.Second || b.Max != time.Second {
+		t.Errorf("unexpected backoff %+v", b)
+	}
+}
@@ -0,0 +1,88 @@
+package requestid
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startServer serves the health service with the request ID interceptors and
+// returns a client sending request IDs, and the IDs the server's calls saw
+func startServer(t *testing.T) (healthpb.HealthClient, <-chan string) {
+	t.Helper()
+	seen := make(chan string, 10)
+	options := append(ServerOptions(),
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			seen <- FromContext(ctx)
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			seen <- FromContext(stream.Context())
+			return handler(srv, stream)
+		}),
+	)
+	server := grpc.NewServer(options...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	dialOptions := append(DialOptions(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufconn", dialOptions...)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn), seen
+}
+
+func TestRequestIDRoundTripsThroughGRPC(t *testing.T) {
+	client, seen := startServer(t)
+	ctx := NewContext(context.Background(), "req-123")
+
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if id := <-seen; id != "req-123" {
+		t.Errorf("unary call: server saw request ID %q, want req-123", id)
+	}
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if id := <-seen; id != "req-123" {
+		t.Errorf("stream: server saw request ID %q, want req-123", id)
+	}
+}
+
+func TestServerGivesCallsWithoutRequestIDANewOne(t *testing.T) {
+	client, seen := startServer(t)
+
+	for _, ctx := range []context.Context{
+		context.Background(),
+		NewContext(context.Background(), "has spaces"),
+	} {
+		if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+		if id := <-seen; id == "" || id == "has spaces" {
+			t.Errorf("expected a new request ID, got %q", id)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package requestid
+
+import (
+	"context"
+	"log/slog"
+
+	"go.uber.org/zap"
+)
+
+// Logger returns logger with ctx's request ID added to every entry, or logger
+// itself when ctx carries none
+func Logger(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	if FromContext(ctx) == "" {
+		return logger
+	}
+	return logger.With(Field(ctx))
+}
+
+// NewSlogHandler wraps handler so records logged with a context carrying a
+// request ID, e.g. through slog.InfoContext, get a request_id attribute
+func NewSlogHandler(handler slog.Handler) slog.Handler {
+	return slogHandler{handler}
+}
+
+type slogHandler struct {
+	slog.Handler
+}
+
+func (h slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := FromContext(ctx); id != "" {
+		record = record.Clone()
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return slogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h slogHandler) WithGroup(name string) slog.Handler {
+	return slogHandler{h.Handler.WithGroup(name)}
+}
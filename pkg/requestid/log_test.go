@@ -0,0 +1,50 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+
+	Logger(NewContext(context.Background(), "abc-123"), logger).Info("with an ID")
+	Logger(context.Background(), logger).Info("without one")
+
+	entries := logs.All()
+	if got := entries[0].ContextMap()["request_id"]; got != "abc-123" {
+		t.Errorf("expected request_id abc-123, got %v", got)
+	}
+	if _, ok := entries[1].ContextMap()["request_id"]; ok {
+		t.Errorf("expected no request_id without one in the context, got %v", entries[1].ContextMap())
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewSlogHandler(slog.NewTextHandler(&buf, nil))).With("component", "test")
+
+	logger.InfoContext(NewContext(context.Background(), "abc-123"), "with an ID")
+	logger.InfoContext(context.Background(), "without one")
+	logger.Info("without a context")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", buf.String())
+	}
+	if !strings.Contains(lines[0], "component=test") || !strings.Contains(lines[0], "request_id=abc-123") {
+		t.Errorf("expected the request ID and the logger's attributes, got %q", lines[0])
+	}
+	for _, line := range lines[1:] {
+		if strings.Contains(line, "request_id") {
+			t.Errorf("expected no request_id, got %q", line)
+		}
+	}
+}
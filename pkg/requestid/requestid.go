@@ -0,0 +1,72 @@
+// Package requestid carries a request's correlation ID across services: from
+// the X-Request-ID header of an HTTP request, through its context, to the gRPC
+// calls made while handling it, so every service logs the same ID.
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Header is the HTTP header a request ID is read from and echoed in
+const Header = "X-Request-ID"
+
+// maxLength is the longest request ID accepted from a caller
+const maxLength = 128
+
+type contextKey struct{}
+
+// New returns a new request ID
+func New() string {
+	return uuid.NewString()
+}
+
+// NewContext returns a copy of ctx carrying id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID ctx carries, or "" if none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Field returns the zap field logging ctx's request ID
+func Field(ctx context.Context) zap.Field {
+	return zap.String("request_id", FromContext(ctx))
+}
+
+// Middleware gives every request an ID: the caller's X-Request-ID when it's
+// valid, or a new one. The ID is put in the request's context, where chi's
+// request logger finds it too, and echoed in the response.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = New()
+		}
+		ctx := NewContext(r.Context(), id)
+		ctx = context.WithValue(ctx, middleware.RequestIDKey, id)
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// valid reports whether a caller's request ID can be passed on and logged as
+// is: short, and printable ASCII without spaces
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"caller's ID", "abc-123", true},
+		{"no ID", "", false},
+		{"ID with spaces", "abc 123", false},
+		{"ID too long", strings.Repeat("a", maxLength+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromContext, fromChi string
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fromContext = FromContext(r.Context())
+				fromChi = middleware.GetReqID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if fromContext == "" || fromChi != fromContext || rec.Header().Get(Header) != fromContext {
+				t.Errorf("context %q, chi %q, response %q: expected the same ID", fromContext, fromChi, rec.Header().Get(Header))
+			}
+			if kept := fromContext == tt.header; kept != tt.keep {
+				t.Errorf("got ID %q for header %q, expected kept=%v", fromContext, tt.header, tt.keep)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// shutdownTimeout bounds the whole shutdown sequence, so a stuck request or
+// batch can't keep the process from exiting
+const shutdownTimeout = 30 * time.Second
+
+// natsDrainTimeout bounds how long shutdown waits for NATS to flush the
+// notifications still buffered before closing the connection
+const natsDrainTimeout = 5 * time.Second
+
+// runner runs a part of the backend, like a server or a worker, in its own
+// goroutine until it's stopped
+type runner struct {
+	name     string
+	stop     func(ctx context.Context) // Asks run to return once its work in flight is done
+	stopOnce sync.Once
+	stopped  chan struct{} // Closed when stop returns
+	done     chan struct{} // Closed when run returns
+}
+
+// startRunner runs run in a new goroutine, logging the error it returns. stop
+// must make run return, and return itself once the work run started is done
+// or ctx is.
+func startRunner(name string, run func() error, stop func(ctx context.Context), logger *zap.Logger) *runner {
+	r := &runner{
+		name:    name,
+		stop:    stop,
+		stopped: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(r.done)
+		if err := run(); err != nil {
+			logger.Error("Runner failed", zap.String("runner", name), zap.Error(err))
+		}
+	}()
+	return r
+}
+
+// startWorker runs a worker that stops when its context is cancelled
+func startWorker(name string, run func(ctx context.Context) error, logger *zap.Logger) *runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return startRunner(name, func() error { return run(ctx) }, func(context.Context) { cancel() }, logger)
+}
+
+// serveHTTP serves server on listener. Stopping it stops accepting requests
+// and waits for the ones in flight.
+func serveHTTP(server *http.Server, listener net.Listener, logger *zap.Logger) *runner {
+	return startRunner("http", func() error {
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}, func(ctx context.Context) {
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Warn("HTTP server didn't shut down cleanly", zap.Error(err))
+		}
+	}, logger)
+}
+
+// serveGRPC serves server on listener. Stopping it stops accepting calls and
+// waits for the ones in flight, cancelling them once ctx is done.
+func serveGRPC(server *grpc.Server, listener net.Listener, logger *zap.Logger) *runner {
+	return startRunner("grpc", func() error {
+		return server.Serve(listener)
+	}, func(ctx context.Context) {
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			server.Stop()
+		}
+	}, logger)
+}
+
+// Stop stops the runner and waits for it to finish, or for ctx to be done
+func (r *runner) Stop(ctx context.Context) error {
+	r.stopOnce.Do(func() {
+		go func() {
+			defer close(r.stopped)
+			r.stop(ctx)
+		}()
+	})
+	for _, ch := range []chan struct{}{r.stopped, r.done} {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return fmt.Errorf("%s didn't stop in time: %w", r.name, ctx.Err())
+		}
+	}
+	return nil
+}
+
+// stopRunners stops runners concurrently and waits for them all
+func stopRunners(ctx context.Context, logger *zap.Logger, runners ...*runner) {
+	var wg sync.WaitGroup
+	for _, r := range runners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.Stop(ctx); err != nil {
+				logger.Error("Failed to stop", zap.String("runner", r.name), zap.Error(err))
+				return
+			}
+			logger.Info("Stopped", zap.String("runner", r.name))
+		}()
+	}
+	wg.Wait()
+}
+
+// shutdown stops the backend in order: first the servers and the workers that
+// take on new work, then the workers that drain work already taken on, and
+// only then closes the connections they all use, so nothing still running
+// finds them closed
+func shutdown(ctx context.Context, logger *zap.Logger, servers, drainers []*runner, closers ...func()) {
+	stopRunners(ctx, logger, servers...)
+	stopRunners(ctx, logger, drainers...)
+	for _, close := range closers {
+		close()
+	}
+}
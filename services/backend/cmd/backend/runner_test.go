@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// fakePool stands in for the database pool, logging queries made after it's
+// closed the way pgxpool fails them
+type fakePool struct {
+	mu      sync.Mutex
+	closed  bool
+	queries int
+	logger  *zap.Logger
+}
+
+func (p *fakePool) query() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		p.logger.Error("Query failed", zap.Error(errors.New("closed pool")))
+		return
+	}
+	p.queries++
+}
+
+func (p *fakePool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+}
+
+func TestShutdownClosesPoolAfterWorkInFlight(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+	pool := &fakePool{logger: logger}
+
+	// A request still being handled when shutdown starts
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		pool.query()
+	})}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpRunner := serveHTTP(server, listener, logger)
+
+	// A worker finishing its batch in flight once stopped
+	stop := make(chan struct{})
+	drainer := startRunner("outbox", func() error {
+		<-stop
+		time.Sleep(100 * time.Millisecond)
+		pool.query()
+		return nil
+	}, func(context.Context) { close(stop) }, logger)
+
+	responded := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err == nil {
+			resp.Body.Close()
+		}
+		responded <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdown(ctx, logger, []*runner{httpRunner}, []*runner{drainer}, pool.close)
+
+	if err := <-responded; err != nil {
+		t.Errorf("expected the request in flight to be answered, got %v", err)
+	}
+	if failed := logs.FilterMessage("Query failed").Len(); failed != 0 {
+		t.Errorf("expected no queries on the closed pool, got %d", failed)
+	}
+	if pool.queries != 2 {
+		t.Errorf("got %d queries, want 2", pool.queries)
+	}
+}
+
+func TestRunnerStopGivesUpWithContext(t *testing.T) {
+	logger := zap.NewNop()
+	stuck := make(chan struct{})
+	defer close(stuck)
+	r := startRunner("stuck", func() error {
+		<-stuck
+		return nil
+	}, func(context.Context) {}, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := r.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the stop to time out, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// maxLegacyAccountIDLength bounds legacy account IDs
+const maxLegacyAccountIDLength = 255
+
+// natsSubjectChars can't appear in an account ID, as account IDs are NATS
+// subject tokens: "." separates tokens and "*" and ">" are wildcards
+const natsSubjectChars = ".*>"
+
+// ErrInvalidAccountID is returned for an account ID that is neither a UUID nor
+// a well-formed legacy ID
+var ErrInvalidAccountID = errors.New("invalid account ID")
+
+// AccountIDForUser returns the event account ID of a user. Accounts are keyed
+// by the owner's user ID in canonical form, so a user always has the same
+// account ID however a client spelled it.
+func AccountIDForUser(userID uuid.UUID) string {
+	return userID.String()
+}
+
+// NormalizeAccountID validates an account ID taken from a request. No ID may
+// contain whitespace, control characters or the NATS subject characters ".",
+// "*" and ">". UUIDs, in any other form uuid.Parse accepts, are returned in
+// canonical form, as the event account of the user they name. Other IDs are
+// legacy accounts recorded in event_accounts and are returned unchanged; they
+// must be valid UTF-8.
+func NormalizeAccountID(accountID string) (string, error) {
+	if accountID == "" || len(accountID) > maxLegacyAccountIDLength || !utf8.ValidString(accountID) {
+		return "", ErrInvalidAccountID
+	}
+	for _, r := range accountID {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune(natsSubjectChars, r) {
+			return "", ErrInvalidAccountID
+		}
+	}
+
+	if userID, err := uuid.Parse(accountID); err == nil {
+		return AccountIDForUser(userID), nil
+	}
+	return accountID, nil
+}
@@ -0,0 +1,150 @@
+package core_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/grpc/server"
+	"github.com/tennex/backend/internal/http/handlers"
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/backend/internal/testutil"
+	dbgen "github.com/tennex/pkg/db/gen"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+func TestEventAccountsAreOnlyUsableByTheirOwner(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	logger := zap.NewNop()
+	ctx := context.Background()
+
+	alice, aliceToken := newAccountUser(t, pool, "legacy-alice")
+	_, bobToken := newAccountUser(t, pool)
+
+	eventRepo := repo.NewEventRepository(pool)
+	eventService := core.NewEventService(eventRepo, nil, "", false, logger)
+	outboxService := core.NewOutboxService(repo.NewOutboxRepository(pool), nil, logger)
+	accountService := core.NewAccountService(repo.NewAccountRepository(pool), logger)
+	integrationService := core.NewIntegrationService(repo.NewIntegrationRepository(pool), logger)
+	apiHandler := handlers.NewAPIHandler(eventService, outboxService, accountService, integrationService, nil, dbgen.New(pool), "test-secret", logger)
+
+	send := func(method, target, token string, body interface{}) int {
+		t.Helper()
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		apiHandler.Routes().ServeHTTP(rec, req)
+		return rec.Code
+	}
+	outboxMessage := func(accountID string) map[string]interface{} {
+		return map[string]interface{}{
+			"client_msg_uuid": uuid.NewString(),
+			"account_id":      accountID,
+			"convo_id":        "123456789@s.whatsapp.net",
+			"message_type":    "text",
+			"content":         map[string]interface{}{"text": "hello"},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		token  string
+		body   interface{}
+		want   int
+	}{
+		{"sync own account", http.MethodGet, "/sync?account_id=" + alice, aliceToken, nil, http.StatusOK},
+		{"sync own legacy account", http.MethodGet, "/sync?account_id=legacy-alice", aliceToken, nil, http.StatusOK},
+		{"sync without a token", http.MethodGet, "/sync?account_id=" + alice, "", nil, http.StatusUnauthorized},
+		{"sync another user's account", http.MethodGet, "/sync?account_id=" + alice, bobToken, nil, http.StatusForbidden},
+		{"sync another user's legacy account", http.MethodGet, "/sync?account_id=legacy-alice", bobToken, nil, http.StatusForbidden},
+		{"sync an unknown account", http.MethodGet, "/sync?account_id=acct-unknown", bobToken, nil, http.StatusForbidden},
+		{"sync own account in upper case", http.MethodGet, "/sync?account_id=" + strings.ToUpper(alice), aliceToken, nil, http.StatusOK},
+		{"sync a malformed account", http.MethodGet, "/sync?account_id=acct%20unknown", aliceToken, nil, http.StatusBadRequest},
+		{"export another user's account", http.MethodGet, "/accounts/" + alice + "/export", bobToken, nil, http.StatusForbidden},
+		{"send without a token", http.MethodPost, "/outbox", "", outboxMessage(alice), http.StatusUnauthorized},
+		{"send as another user", http.MethodPost, "/outbox", bobToken, outboxMessage(alice), http.StatusForbidden},
+		{"send as another user to a legacy account", http.MethodPost, "/outbox", bobToken, outboxMessage("legacy-alice"), http.StatusForbidden},
+		{"send as the owner", http.MethodPost, "/outbox", aliceToken, outboxMessage(alice), http.StatusCreated},
+		{"send to a malformed account", http.MethodPost, "/outbox", aliceToken, outboxMessage("acct\tunknown"), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := send(tt.method, tt.target, tt.token, tt.body); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// Only the owner's message was written to the account
+	written, err := eventService.GetEventsSince(ctx, alice, 0, 100, nil)
+	if err != nil {
+		t.Fatalf("GetEventsSince: %v", err)
+	}
+	if len(written) != 1 {
+		t.Errorf("expected only the owner's message event, got %d events", len(written))
+	}
+}
+
+func TestBridgeServerRejectsOtherUsersAccounts(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	logger := zap.NewNop()
+	ctx := context.Background()
+
+	alice, _ := newAccountUser(t, pool)
+	bob, _ := newAccountUser(t, pool)
+	const aliceJID = "972501111111@s.whatsapp.net"
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO user_integrations (user_id, integration_type, external_id, status)
+		VALUES ($1, 'whatsapp', $2, 'connected')`, alice, aliceJID); err != nil {
+		t.Fatalf("failed to create integration: %v", err)
+	}
+
+	integrationService := core.NewIntegrationService(repo.NewIntegrationRepository(pool), logger)
+	accountService := core.NewAccountService(repo.NewAccountRepository(pool), logger)
+	bridgeServer := server.NewBridgeServer(nil, nil, accountService, integrationService, logger)
+
+	update := func(accountID, waJID string) error {
+		_, err := bridgeServer.UpdateAccountStatus(ctx, &proto.UpdateAccountStatusRequest{
+			AccountId: accountID,
+			Status:    proto.AccountStatus_ACCOUNT_STATUS_DISCONNECTED,
+			Info:      &proto.AccountInfo{WaJid: waJID},
+		})
+		return err
+	}
+
+	if err := update(alice, aliceJID); err != nil {
+		t.Errorf("expected the owner's update to succeed, got %v", err)
+	}
+	if err := update(bob, aliceJID); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for another user's integration, got %v", err)
+	}
+	if err := update("acct-unknown", ""); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for an unknown account, got %v", err)
+	}
+
+	integration, err := integrationService.GetIntegrationByExternalID(ctx, core.IntegrationTypeWhatsApp, aliceJID)
+	if err != nil {
+		t.Fatalf("GetIntegrationByExternalID: %v", err)
+	}
+	if integration.UserID.String() != alice || integration.Status != "disconnected" {
+		t.Errorf("expected the integration to be disconnected by its owner's update only, got %+v", integration)
+	}
+}
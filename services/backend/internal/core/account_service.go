@@ -3,15 +3,22 @@ package core
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 
 	"github.com/tennex/backend/internal/repo"
 	"github.com/tennex/pkg/events"
 )
 
+// ErrAccountNotOwned is returned when a user uses an event account that
+// belongs to someone else, or that no user owns
+var ErrAccountNotOwned = errors.New("account is not owned by the user")
+
 // AccountService handles account business logic
 type AccountService struct {
 	accountRepo repo.AccountRepository
@@ -92,6 +99,41 @@ func (s *AccountService) GetAccountByWAJID(ctx context.Context, waJid string) (*
 	return &account, nil
 }
 
+// GetAccountOwner returns the ID of the user owning an event account. It
+// fails with pgx.ErrNoRows when no user owns the account.
+func (s *AccountService) GetAccountOwner(ctx context.Context, accountID string) (uuid.UUID, error) {
+	owner, err := s.accountRepo.GetAccountOwner(ctx, accountID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get account owner: %w", err)
+	}
+	return owner, nil
+}
+
+// AuthorizeAccount checks that an event account belongs to the user, and
+// returns ErrAccountNotOwned if it doesn't. It returns ErrInvalidAccountID for
+// a malformed account ID; see NormalizeAccountID.
+func (s *AccountService) AuthorizeAccount(ctx context.Context, userID uuid.UUID, accountID string) error {
+	accountID, err := NormalizeAccountID(accountID)
+	if err != nil {
+		return err
+	}
+
+	owner, err := s.GetAccountOwner(ctx, accountID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrAccountNotOwned
+	}
+	if err != nil {
+		return err
+	}
+	if owner != userID {
+		s.logger.Warn("Rejected access to another user's account",
+			zap.String("user_id", userID.String()),
+			zap.String("account_id", accountID))
+		return ErrAccountNotOwned
+	}
+	return nil
+}
+
 // UpdateAccountStatus updates the status of an account
 func (s *AccountService) UpdateAccountStatus(ctx context.Context, id, status string, lastSeen *time.Time) error {
 	s.logger.Debug("Updating account status",
@@ -120,18 +162,54 @@ func (s *AccountService) UpdateAccountStatus(ctx context.Context, id, status str
 	return nil
 }
 
-// ListAccounts retrieves a list of accounts with pagination
-func (s *AccountService) ListAccounts(ctx context.Context, limit, offset int32) ([]repo.Account, error) {
-	accounts, err := s.accountRepo.ListAccounts(ctx, repo.ListAccountsParams{
-		Limit:  limit,
-		Offset: offset,
-	})
+// ListAccounts retrieves a page of accounts, each with its integrations
+func (s *AccountService) ListAccounts(ctx context.Context, params repo.ListAccountsParams) ([]AccountSummary, error) {
+	accounts, err := s.accountRepo.ListAccounts(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list accounts: %w", err)
 	}
 
-	s.logger.Debug("Listed accounts", zap.Int("count", len(accounts)))
-	return accounts, nil
+	// One query for the page's integrations rather than one per account
+	ids := make([]string, len(accounts))
+	for i, account := range accounts {
+		ids[i] = account.ID
+	}
+	integrations, err := s.accountRepo.ListAccountIntegrations(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account integrations: %w", err)
+	}
+
+	s.logger.Debug("Listed accounts",
+		zap.Int("count", len(accounts)),
+		zap.Int("integrations", len(integrations)))
+	return summarizeAccounts(accounts, integrations), nil
+}
+
+// AccountSummary is an account with a summary of each of its integrations
+type AccountSummary struct {
+	repo.Account
+	Integrations []repo.AccountIntegrationSummary
+}
+
+// summarizeAccounts attaches each integration to its account, keeping the
+// accounts' order. Accounts without integrations get an empty list.
+func summarizeAccounts(accounts []repo.Account, integrations []repo.AccountIntegrationSummary) []AccountSummary {
+	byAccount := make(map[string][]repo.AccountIntegrationSummary)
+	for _, integration := range integrations {
+		byAccount[integration.AccountID] = append(byAccount[integration.AccountID], integration)
+	}
+
+	summaries := make([]AccountSummary, len(accounts))
+	for i, account := range accounts {
+		summaries[i] = AccountSummary{
+			Account:      account,
+			Integrations: byAccount[account.ID],
+		}
+		if summaries[i].Integrations == nil {
+			summaries[i].Integrations = []repo.AccountIntegrationSummary{}
+		}
+	}
+	return summaries
 }
 
 // GetConnectedAccounts retrieves all connected accounts
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/repo"
+)
+
+// memoryAccountRepo serves fixed accounts and integrations
+type memoryAccountRepo struct {
+	repo.AccountRepository
+	accounts     []repo.Account
+	integrations []repo.AccountIntegrationSummary
+	requested    []string             // Account IDs integrations were last listed for
+	owners       map[string]uuid.UUID // Owner of each account
+}
+
+func (r *memoryAccountRepo) GetAccountOwner(ctx context.Context, accountID string) (uuid.UUID, error) {
+	owner, ok := r.owners[accountID]
+	if !ok {
+		return uuid.Nil, fmt.Errorf("failed to get account owner: %w", pgx.ErrNoRows)
+	}
+	return owner, nil
+}
+
+func (r *memoryAccountRepo) ListAccounts(ctx context.Context, params repo.ListAccountsParams) ([]repo.Account, error) {
+	return r.accounts, nil
+}
+
+func (r *memoryAccountRepo) ListAccountIntegrations(ctx context.Context, accountIDs []string) ([]repo.AccountIntegrationSummary, error) {
+	r.requested = accountIDs
+	return r.integrations, nil
+}
+
+func TestListAccountsAttachesIntegrations(t *testing.T) {
+	accounts := &memoryAccountRepo{
+		accounts: []repo.Account{{ID: "user-1"}, {ID: "user-2"}, {ID: "user-3"}},
+		integrations: []repo.AccountIntegrationSummary{
+			{AccountID: "user-1", ID: 1, Status: "connected"},
+			{AccountID: "user-3", ID: 3, Status: "disconnected"},
+			{AccountID: "user-1", ID: 2, Status: "error"},
+		},
+	}
+	service := NewAccountService(accounts, zap.NewNop())
+
+	summaries, err := service.ListAccounts(context.Background(), repo.ListAccountsParams{Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts.requested) != 3 {
+		t.Errorf("expected integrations to be fetched for the whole page at once, got %v", accounts.requested)
+	}
+
+	want := map[string][]int32{"user-1": {1, 2}, "user-2": {}, "user-3": {3}}
+	if len(summaries) != len(want) {
+		t.Fatalf("got %d accounts, want %d", len(summaries), len(want))
+	}
+	for i, summary := range summaries {
+		if summary.ID != accounts.accounts[i].ID {
+			t.Errorf("account %d: got %s, want the listing's order", i, summary.ID)
+		}
+		if summary.Integrations == nil {
+			t.Errorf("%s: expected an empty list rather than nil", summary.ID)
+		}
+		var ids []int32
+		for _, integration := range summary.Integrations {
+			ids = append(ids, integration.ID)
+		}
+		if len(ids) != len(want[summary.ID]) {
+			t.Errorf("%s: got integrations %v, want %v", summary.ID, ids, want[summary.ID])
+			continue
+		}
+		for j := range ids {
+			if ids[j] != want[summary.ID][j] {
+				t.Errorf("%s: got integrations %v, want %v", summary.ID, ids, want[summary.ID])
+			}
+		}
+	}
+}
+
+func TestAuthorizeAccountRejectsOtherUsersAccounts(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	service := NewAccountService(&memoryAccountRepo{owners: map[string]uuid.UUID{
+		alice.String(): alice,
+		bob.String():   bob,
+		"legacy-alice": alice, // Legacy account mapped by the migration
+	}}, zap.NewNop())
+
+	tests := []struct {
+		name      string
+		accountID string
+		wantErr   error
+	}{
+		{"own account", alice.String(), nil},
+		{"own legacy account", "legacy-alice", nil},
+		{"another user's account", bob.String(), ErrAccountNotOwned},
+		{"unknown account", "acct-1", ErrAccountNotOwned},
+		{"own account in upper case", strings.ToUpper(alice.String()), nil},
+		{"malformed account", "acct 1", ErrInvalidAccountID},
+		{"empty account", "", ErrInvalidAccountID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.AuthorizeAccount(context.Background(), alice, tt.accountID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeAccountID(t *testing.T) {
+	userID := uuid.MustParse("6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b")
+
+	tests := []struct {
+		name      string
+		accountID string
+		want      string
+		wantErr   error
+	}{
+		{"canonical UUID", userID.String(), userID.String(), nil},
+		{"upper case UUID", strings.ToUpper(userID.String()), userID.String(), nil},
+		{"UUID without hyphens", strings.ReplaceAll(userID.String(), "-", ""), userID.String(), nil},
+		{"URN UUID", "urn:uuid:" + userID.String(), userID.String(), nil},
+		{"legacy ID", "legacy-alice", "legacy-alice", nil},
+		{"empty", "", "", ErrInvalidAccountID},
+		{"leading whitespace", " " + userID.String(), "", ErrInvalidAccountID},
+		{"trailing whitespace", "legacy-alice\t", "", ErrInvalidAccountID},
+		{"inner whitespace", "legacy alice", "", ErrInvalidAccountID},
+		{"non-ASCII whitespace", "legacy\u00a0alice", "", ErrInvalidAccountID},
+		{"legacy ID with a dot", "972501111111@s.whatsapp.net", "", ErrInvalidAccountID},
+		{"legacy ID with a wildcard", "legacy-*", "", ErrInvalidAccountID},
+		{"legacy ID with a full wildcard", "legacy->", "", ErrInvalidAccountID},
+		{"lone wildcard", "*", "", ErrInvalidAccountID},
+		{"lone full wildcard", ">", "", ErrInvalidAccountID},
+		{"braced UUID", "{" + userID.String() + "}", userID.String(), nil},
+		{"UUID with a subject separator", "urn.uuid." + userID.String(), "", ErrInvalidAccountID},
+		{"control character", "acct\x00", "", ErrInvalidAccountID},
+		{"invalid UTF-8", "acct\xff", "", ErrInvalidAccountID},
+		{"too long", strings.Repeat("a", maxLegacyAccountIDLength+1), "", ErrInvalidAccountID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeAccountID(tt.accountID)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("got %q, %v; want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	if got := AccountIDForUser(userID); got != userID.String() {
+		t.Errorf("got account ID %q for the user, want %q", got, userID.String())
+	}
+}
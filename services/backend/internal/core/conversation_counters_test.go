@@ -0,0 +1,173 @@
+package core_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/grpc/client"
+	"github.com/tennex/backend/internal/grpc/server"
+	"github.com/tennex/backend/internal/http/handlers"
+	"github.com/tennex/backend/internal/testutil"
+	"github.com/tennex/bridge/whatsapp"
+	dbgen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/shared/auth"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+func TestReadMarkerAndRecount(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	logger := zap.NewNop()
+	ctx := context.Background()
+
+	const chatJID = "972504444444@s.whatsapp.net"
+	var userID uuid.UUID
+	err := pool.QueryRow(ctx, `
+		INSERT INTO users (username, email, password_hash)
+		VALUES ('counters', 'counters@example.com', 'x')
+		RETURNING id`).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	var integrationID int32
+	err = pool.QueryRow(ctx, `
+		INSERT INTO user_integrations (user_id, integration_type, external_id, status)
+		VALUES ($1, 'whatsapp', '972500000000@s.whatsapp.net', 'connected')
+		RETURNING id`, userID).Scan(&integrationID)
+	if err != nil {
+		t.Fatalf("failed to create integration: %v", err)
+	}
+
+	const bridgeToken = "test-bridge-token"
+	sessions := whatsapp.NewSessionRegistry()
+	sessions.Register(userID.String(), &fakeSession{})
+	bridgeClient, err := client.NewBridgeClient(startBridge(t, sessions, bridgeToken), bridgeToken, logger)
+	if err != nil {
+		t.Fatalf("failed to create bridge client: %v", err)
+	}
+	defer bridgeClient.Close()
+
+	queries := dbgen.New(pool)
+	apiHandler := handlers.NewAPIHandler(nil, nil, nil, nil, bridgeClient, queries, "test-secret", logger)
+	integrationServer := server.NewIntegrationServer(nil, nil, pool, queries, 0, logger)
+	integrationCtx := &proto.IntegrationContext{
+		UserId:            userID.String(),
+		UserIntegrationId: integrationID,
+		IntegrationType:   core.IntegrationTypeWhatsApp,
+		PlatformUserId:    "972500000000@s.whatsapp.net",
+	}
+
+	token, _, err := auth.DefaultJWTConfig("test-secret").GenerateToken(userID, auth.RoleUser)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	post := func(token, path string, body interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		apiHandler.Routes().ServeHTTP(rec, req)
+		return rec
+	}
+	receive := func(id string, unix int64, fromMe bool) {
+		t.Helper()
+		_, err := integrationServer.ProcessMessage(ctx, &proto.ProcessMessageRequest{
+			Context: integrationCtx,
+			Message: &proto.Message{
+				PlatformId:     id,
+				ConversationId: chatJID,
+				SenderId:       chatJID,
+				MessageType:    proto.MessageType_MESSAGE_TYPE_TEXT,
+				Content:        id,
+				Timestamp:      timestamppb.New(time.Unix(unix, 0)),
+				IsFromMe:       fromMe,
+			},
+		})
+		if err != nil {
+			t.Fatalf("ProcessMessage(%s): %v", id, err)
+		}
+	}
+	markRead := func(ids ...string) float64 {
+		t.Helper()
+		rec := post(token, "/integrations/whatsapp/read", map[string]interface{}{
+			"convo_id":       chatJID,
+			"wa_message_ids": ids,
+		})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 from read, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response struct {
+			UnreadCount float64 `json:"unread_count"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode read response: %v", err)
+		}
+		return response.UnreadCount
+	}
+
+	receive("IN1", 1700000000, false)
+	receive("IN2", 1700000010, false)
+	receive("OWN1", 1700000020, true)
+	if unread := markRead("IN1"); unread != 1 {
+		t.Fatalf("expected 1 unread after reading the first message, got %v", unread)
+	}
+	receive("IN3", 1700000030, false)
+	if unread := markRead("IN3"); unread != 0 {
+		t.Fatalf("expected nothing unread after reading the newest message, got %v", unread)
+	}
+	// Reading an older message doesn't move the marker back
+	if unread := markRead("IN2"); unread != 0 {
+		t.Fatalf("expected reading an older message to change nothing, got %v unread", unread)
+	}
+	receive("IN4", 1700000040, false)
+
+	var conversationID uuid.UUID
+	err = pool.QueryRow(ctx, `
+		UPDATE conversations SET total_message_count = 99, unread_count = 42
+		WHERE external_conversation_id = $1
+		RETURNING id`, chatJID).Scan(&conversationID)
+	if err != nil {
+		t.Fatalf("failed to corrupt counters: %v", err)
+	}
+
+	rec := post(token, "/conversations/"+conversationID.String()+"/recount", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 from recount, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var recount struct {
+		TotalMessageCount int `json:"total_message_count"`
+		UnreadCount       int `json:"unread_count"`
+		Previous          struct {
+			TotalMessageCount int `json:"total_message_count"`
+			UnreadCount       int `json:"unread_count"`
+		} `json:"previous"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &recount); err != nil {
+		t.Fatalf("failed to decode recount response: %v", err)
+	}
+	if recount.TotalMessageCount != 5 || recount.UnreadCount != 1 {
+		t.Errorf("expected total 5 unread 1 after recount, got total %d unread %d", recount.TotalMessageCount, recount.UnreadCount)
+	}
+	if recount.Previous.TotalMessageCount != 99 || recount.Previous.UnreadCount != 42 {
+		t.Errorf("expected the previous counters 99 and 42, got %+v", recount.Previous)
+	}
+
+	// Another user can't recount the conversation
+	otherToken, _, err := auth.DefaultJWTConfig("test-secret").GenerateToken(uuid.New(), auth.RoleUser)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if rec := post(otherToken, "/conversations/"+conversationID.String()+"/recount", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 recounting another user's conversation, got %d", rec.Code)
+	}
+}
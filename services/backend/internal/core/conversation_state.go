@@ -0,0 +1,41 @@
+package core
+
+import (
+	"time"
+
+	"github.com/tennex/pkg/events"
+)
+
+// ConversationState is the pin, mute and archive state of a conversation. A
+// zero MuteUntil means the mute has no expiry.
+type ConversationState struct {
+	IsPinned   bool
+	IsArchived bool
+	IsMuted    bool
+	MuteUntil  time.Time
+}
+
+// ConversationStateChange returns the fields that differ between previous and
+// next, and whether any did
+func ConversationStateChange(previous, next ConversationState) (events.ConversationStatePayload, bool) {
+	change := events.ConversationStatePayload{Changed: []string{}}
+	if next.IsPinned != previous.IsPinned {
+		change.Changed = append(change.Changed, events.ConversationFieldPinned)
+		change.IsPinned = &next.IsPinned
+	}
+	if next.IsArchived != previous.IsArchived {
+		change.Changed = append(change.Changed, events.ConversationFieldArchived)
+		change.IsArchived = &next.IsArchived
+	}
+	if next.IsMuted != previous.IsMuted {
+		change.Changed = append(change.Changed, events.ConversationFieldMuted)
+		change.IsMuted = &next.IsMuted
+	}
+	if !next.MuteUntil.Equal(previous.MuteUntil) {
+		change.Changed = append(change.Changed, events.ConversationFieldMuteUntil)
+		if !next.MuteUntil.IsZero() {
+			change.MuteUntil = &next.MuteUntil
+		}
+	}
+	return change, len(change.Changed) > 0
+}
@@ -0,0 +1,253 @@
+package core_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/grpc/client"
+	"github.com/tennex/backend/internal/grpc/server"
+	"github.com/tennex/backend/internal/http/handlers"
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/backend/internal/testutil"
+	"github.com/tennex/bridge/whatsapp"
+	dbgen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/events"
+	"github.com/tennex/shared/auth"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+func TestConversationStateChangeSurvivesLateEcho(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	logger := zap.NewNop()
+	ctx := context.Background()
+
+	const chatJID = "972501111111@s.whatsapp.net"
+	var userID uuid.UUID
+	err := pool.QueryRow(ctx, `
+		INSERT INTO users (username, email, password_hash)
+		VALUES ('chat-state', 'chat-state@example.com', 'x')
+		RETURNING id`).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	var integrationID int32
+	err = pool.QueryRow(ctx, `
+		INSERT INTO user_integrations (user_id, integration_type, external_id, status)
+		VALUES ($1, 'whatsapp', '972500000000@s.whatsapp.net', 'connected')
+		RETURNING id`, userID).Scan(&integrationID)
+	if err != nil {
+		t.Fatalf("failed to create integration: %v", err)
+	}
+	var conversationID uuid.UUID
+	err = pool.QueryRow(ctx, `
+		INSERT INTO conversations (user_integration_id, external_conversation_id, integration_type, conversation_type)
+		VALUES ($1, $2, 'whatsapp', 'individual')
+		RETURNING id`, integrationID, chatJID).Scan(&conversationID)
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+
+	const bridgeToken = "test-bridge-token"
+	session := &fakeSession{stateChanges: make(chan stateChange, 10)}
+	sessions := whatsapp.NewSessionRegistry()
+	sessions.Register(userID.String(), session)
+	bridgeClient, err := client.NewBridgeClient(startBridge(t, sessions, bridgeToken), bridgeToken, logger)
+	if err != nil {
+		t.Fatalf("failed to create bridge client: %v", err)
+	}
+	defer bridgeClient.Close()
+
+	eventRepo := repo.NewEventRepository(pool)
+	eventService := core.NewEventService(eventRepo, nil, "", false, logger)
+	queries := dbgen.New(pool)
+	apiHandler := handlers.NewAPIHandler(eventService, nil, nil, nil, bridgeClient, queries, "test-secret", logger)
+	integrationServer := server.NewIntegrationServer(nil, eventService, pool, queries, 0, logger)
+	integrationCtx := &proto.IntegrationContext{
+		UserId:            userID.String(),
+		UserIntegrationId: integrationID,
+		IntegrationType:   core.IntegrationTypeWhatsApp,
+		PlatformUserId:    "972500000000@s.whatsapp.net",
+	}
+
+	token, _, err := auth.DefaultJWTConfig("test-secret").GenerateToken(userID, auth.RoleUser)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	patch := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPatch, "/conversations/"+conversationID.String()+"/state", bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		apiHandler.Routes().ServeHTTP(rec, req)
+		return rec
+	}
+	// change changes the state through the API and returns what the bridge was asked to do
+	change := func(body map[string]interface{}) stateChange {
+		t.Helper()
+		if rec := patch(body); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 from PATCH state, got %d: %s", rec.Code, rec.Body.String())
+		}
+		select {
+		case sc := <-session.stateChanges:
+			if sc.chatJID != chatJID {
+				t.Errorf("expected state change for %s, got %s", chatJID, sc.chatJID)
+			}
+			// Changes made within the same millisecond can't be told apart
+			time.Sleep(2 * time.Millisecond)
+			return sc
+		default:
+			t.Fatal("expected the bridge to receive a state change")
+			return stateChange{}
+		}
+	}
+	// echo delivers the app state events WhatsApp sends back for a change the
+	// way the bridge forwards them: one setting per update
+	echo := func(sc stateChange) {
+		t.Helper()
+		send := func(state *proto.ConversationState, field string) {
+			_, err := integrationServer.UpdateConversationState(ctx, &proto.UpdateConversationStateRequest{
+				Context:                integrationCtx,
+				ConversationExternalId: chatJID,
+				State:                  state,
+				Fields:                 []string{field},
+				ChangedAt:              timestamppb.New(sc.change.ChangedAt),
+			})
+			if err != nil {
+				t.Fatalf("UpdateConversationState: %v", err)
+			}
+		}
+		if sc.change.Archived != nil {
+			send(&proto.ConversationState{IsArchived: *sc.change.Archived}, events.ConversationFieldArchived)
+		}
+		if sc.change.Pinned != nil {
+			send(&proto.ConversationState{IsPinned: *sc.change.Pinned}, events.ConversationFieldPinned)
+		}
+		if sc.change.Muted != nil {
+			state := &proto.ConversationState{IsMuted: *sc.change.Muted}
+			if !sc.change.MuteUntil.IsZero() {
+				state.MuteUntil = timestamppb.New(sc.change.MuteUntil)
+			}
+			send(state, events.ConversationFieldMuted)
+		}
+	}
+	stateEvents := func() []events.ConversationStatePayload {
+		t.Helper()
+		stored, err := eventRepo.GetEventsSince(ctx, repo.GetEventsSinceParams{
+			AccountID: userID.String(),
+			Limit:     100,
+			Types:     []string{events.TypeConversationState},
+		})
+		if err != nil {
+			t.Fatalf("failed to get events: %v", err)
+		}
+		payloads := make([]events.ConversationStatePayload, len(stored))
+		for i, event := range stored {
+			if err := json.Unmarshal(event.Payload, &payloads[i]); err != nil {
+				t.Fatalf("failed to decode payload: %v", err)
+			}
+		}
+		return payloads
+	}
+	stored := func() dbgen.GetUserConversationStateRow {
+		t.Helper()
+		row, err := queries.GetUserConversationState(ctx, dbgen.GetUserConversationStateParams{
+			ConversationID: conversationID,
+			UserID:         userID,
+		})
+		if err != nil {
+			t.Fatalf("GetUserConversationState: %v", err)
+		}
+		return row
+	}
+
+	// Pin then unpin before WhatsApp echoes either change
+	pin := change(map[string]interface{}{"is_pinned": true})
+	if pin.change.Pinned == nil || !*pin.change.Pinned || pin.change.Archived != nil || pin.change.Muted != nil {
+		t.Fatalf("unexpected pin change: %+v", pin.change)
+	}
+	unpin := change(map[string]interface{}{"is_pinned": false})
+	if !unpin.change.ChangedAt.After(pin.change.ChangedAt) {
+		t.Fatalf("expected the unpin to be stamped after the pin, got %s and %s", pin.change.ChangedAt, unpin.change.ChangedAt)
+	}
+
+	// The pin's echo arrives after the unpin and must not pin it again
+	echo(pin)
+	if stored().IsPinned {
+		t.Fatal("late echo of the pin re-pinned the conversation")
+	}
+	echo(unpin)
+	if stored().IsPinned {
+		t.Fatal("echo of the unpin pinned the conversation")
+	}
+
+	payloads := stateEvents()
+	if len(payloads) != 2 {
+		t.Fatalf("expected 2 conversation_state events, got %+v", payloads)
+	}
+	if payloads[0].IsPinned == nil || !*payloads[0].IsPinned || payloads[1].IsPinned == nil || *payloads[1].IsPinned {
+		t.Errorf("expected pin then unpin events, got %+v", payloads)
+	}
+
+	// A mute's echo carries the same end time, so it isn't another change
+	muteUntil := time.Now().Add(time.Hour).Truncate(time.Millisecond).UTC()
+	mute := change(map[string]interface{}{"is_muted": true, "mute_until": muteUntil})
+	if !mute.change.MuteUntil.Equal(muteUntil) {
+		t.Errorf("expected the bridge to mute until %s, got %s", muteUntil, mute.change.MuteUntil)
+	}
+	echo(mute)
+	if row := stored(); !row.IsMuted || !row.MuteUntil.Time.Equal(muteUntil) {
+		t.Errorf("expected muted until %s, got %v %v", muteUntil, row.IsMuted, row.MuteUntil)
+	}
+
+	// Archiving also unpins, as it does on WhatsApp
+	archive := change(map[string]interface{}{"is_archived": true})
+	if archive.change.Archived == nil || !*archive.change.Archived || archive.change.Pinned == nil || *archive.change.Pinned {
+		t.Errorf("unexpected archive change: %+v", archive.change)
+	}
+	echo(archive)
+	if got := len(stateEvents()); got != 4 {
+		t.Errorf("expected 4 conversation_state events after mute and archive, got %d", got)
+	}
+
+	// A change made on the phone after those is applied
+	_, err = integrationServer.UpdateConversationState(ctx, &proto.UpdateConversationStateRequest{
+		Context:                integrationCtx,
+		ConversationExternalId: chatJID,
+		State:                  &proto.ConversationState{IsArchived: false},
+		Fields:                 []string{events.ConversationFieldArchived},
+		ChangedAt:              timestamppb.New(time.Now().Add(time.Second)),
+	})
+	if err != nil {
+		t.Fatalf("UpdateConversationState: %v", err)
+	}
+	if row := stored(); row.IsArchived || !row.IsMuted {
+		t.Errorf("expected the phone's unarchive to apply and keep the mute, got archived=%v muted=%v", row.IsArchived, row.IsMuted)
+	}
+
+	// Without a connected session the change is refused and nothing is stored
+	sessions.Unregister(userID.String(), session)
+	if rec := patch(map[string]interface{}{"is_pinned": true}); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 without a bridge session, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := pool.Exec(ctx, `UPDATE user_integrations SET status = 'disconnected' WHERE id = $1`, integrationID); err != nil {
+		t.Fatalf("failed to disconnect integration: %v", err)
+	}
+	if rec := patch(map[string]interface{}{"is_pinned": true}); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a disconnected integration, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if stored().IsPinned {
+		t.Error("a refused change was stored")
+	}
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestConversationStateChange(t *testing.T) {
+	muteUntil := time.Unix(1800000000, 0)
+	tests := []struct {
+		name           string
+		previous, next ConversationState
+		want           []string
+	}{
+		{name: "unchanged", previous: ConversationState{IsPinned: true}, next: ConversationState{IsPinned: true}},
+		{name: "archived", next: ConversationState{IsArchived: true}, want: []string{"is_archived"}},
+		{name: "muted", next: ConversationState{IsMuted: true, MuteUntil: muteUntil}, want: []string{"is_muted", "mute_until"}},
+		{name: "mute expiry cleared", previous: ConversationState{IsMuted: true, MuteUntil: muteUntil}, next: ConversationState{IsMuted: true}, want: []string{"mute_until"}},
+		{name: "same instant", previous: ConversationState{MuteUntil: muteUntil.UTC()}, next: ConversationState{MuteUntil: muteUntil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, changed := ConversationStateChange(tt.previous, tt.next)
+			if changed != (len(tt.want) > 0) {
+				t.Errorf("changed = %v, want %v", changed, len(tt.want) > 0)
+			}
+			if fmt.Sprint(change.Changed) != fmt.Sprint(tt.want) {
+				t.Errorf("Changed = %v, want %v", change.Changed, tt.want)
+			}
+		})
+	}
+
+	// Only changed fields carry values
+	change, _ := ConversationStateChange(ConversationState{IsPinned: true}, ConversationState{IsPinned: true, IsArchived: true})
+	if change.IsPinned != nil || change.IsArchived == nil || !*change.IsArchived {
+		t.Errorf("unexpected change: %+v", change)
+	}
+}
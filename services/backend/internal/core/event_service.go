@@ -1,9 +1,14 @@
 package core
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
@@ -11,30 +16,90 @@ import (
 
 	"github.com/tennex/backend/internal/repo"
 	"github.com/tennex/pkg/events"
+	"github.com/tennex/pkg/natsx"
 )
 
+// DefaultMaxEventPayloadBytes is the largest event payload stored inline
+const DefaultMaxEventPayloadBytes = 64 << 10
+
+// payloadMimeType is the type recorded for payloads stored by reference
+const payloadMimeType = "application/json"
+
+// ErrPayloadTooLarge is returned for an event whose payload is over the limit
+// and can't be stored by reference
+var ErrPayloadTooLarge = errors.New("event payload is too large")
+
 // EventService handles event business logic
 type EventService struct {
-	eventRepo repo.EventRepository
-	nats      *nats.Conn
-	logger    *zap.Logger
+	eventRepo       repo.EventRepository
+	nats            *natsx.Publisher
+	subjectPrefix   string
+	legacySubjects  bool
+	mutes           MuteChecker
+	maxPayloadBytes int         // Larger payloads aren't stored inline; 0 for no limit
+	payloads        *MediaStore // Where payloads over the limit are stored
+	logger          *zap.Logger
 }
 
-// NewEventService creates a new event service
-func NewEventService(eventRepo repo.EventRepository, natsConn *nats.Conn, logger *zap.Logger) *EventService {
+// MuteChecker tells whether a conversation is muted
+type MuteChecker interface {
+	IsConversationMuted(ctx context.Context, userIntegrationID int32, externalConversationID string, now time.Time) (bool, error)
+}
+
+// NewEventService creates a new event service. subjectPrefix namespaces NATS
+// subjects (e.g. "tennex.prod") so environments can share a NATS cluster.
+// legacySubjects also publishes notifications to the deprecated per-account
+// subject, for eventstreams that haven't moved to per-integration subjects.
+// Notifications that can't be published are counted by natsx.DroppedPublishes.
+func NewEventService(eventRepo repo.EventRepository, natsConn *nats.Conn, subjectPrefix string, legacySubjects bool, logger *zap.Logger) *EventService {
 	return &EventService{
-		eventRepo: eventRepo,
-		nats:      natsConn,
-		logger:    logger.Named("event_service"),
+		eventRepo:      eventRepo,
+		nats:           natsx.NewPublisher(natsConn, logger),
+		subjectPrefix:  subjectPrefix,
+		legacySubjects: legacySubjects,
+		logger:         logger.Named("event_service"),
 	}
 }
 
-// PublishInbound publishes an inbound event from the bridge
-func (s *EventService) PublishInbound(ctx context.Context, event *repo.Event) (int64, bool, error) {
+// SetMuteChecker makes notifications about muted conversations carry a muted
+// flag, so clients sync them without alerting the user
+func (s *EventService) SetMuteChecker(mutes MuteChecker) {
+	s.mutes = mutes
+}
+
+// SetPayloadLimit caps the size of the payloads stored inline in events, so
+// a huge message can't bloat the events table and every sync response that
+// includes it. A larger payload is stored in payloads and the event keeps an
+// OversizedPayload in its place, with the stored file in its attachment_ref.
+// Without a store, or for events that already have an attachment_ref, larger
+// payloads are rejected with ErrPayloadTooLarge. 0 removes the cap.
+func (s *EventService) SetPayloadLimit(maxBytes int, payloads *MediaStore) {
+	s.maxPayloadBytes = maxBytes
+	s.payloads = payloads
+}
+
+// PublishInbound publishes an inbound event from the bridge. Clients are
+// notified on the subject of integrationID, the user integration the event
+// came through; 0 for events that don't belong to one. The integration is
+// recorded on the event so it can be traced back to it later. The account ID
+// is normalized, and a malformed one is rejected with ErrInvalidAccountID.
+func (s *EventService) PublishInbound(ctx context.Context, event *repo.Event, integrationID int32) (int64, bool, error) {
 	s.logger.Debug("Publishing inbound event",
 		zap.String("event_id", event.ID.String()),
 		zap.String("type", event.Type),
-		zap.String("account_id", event.AccountID))
+		zap.String("account_id", event.AccountID),
+		zap.Int32("integration_id", integrationID))
+
+	accountID, err := NormalizeAccountID(event.AccountID)
+	if err != nil {
+		return 0, false, fmt.Errorf("%w: %q", err, event.AccountID)
+	}
+	event.AccountID = accountID
+
+	payload, attachmentRef, err := s.limitPayload(event)
+	if err != nil {
+		return 0, false, err
+	}
 
 	// Insert event (idempotent)
 	result, err := s.eventRepo.InsertEvent(ctx, repo.InsertEventParams{
@@ -45,8 +110,12 @@ func (s *EventService) PublishInbound(ctx context.Context, event *repo.Event) (i
 		ConvoID:       event.ConvoID,
 		WaMessageID:   event.WaMessageID,
 		SenderJid:     event.SenderJid,
-		Payload:       event.Payload,
-		AttachmentRef: event.AttachmentRef,
+		Payload:       payload,
+		AttachmentRef: attachmentRef,
+		UserIntegrationID: sql.NullInt32{
+			Int32: integrationID,
+			Valid: integrationID != 0,
+		},
 	})
 	if err != nil {
 		s.logger.Error("Failed to insert event", zap.Error(err))
@@ -57,7 +126,8 @@ func (s *EventService) PublishInbound(ctx context.Context, event *repo.Event) (i
 
 	if created {
 		// Publish notification to NATS
-		if err := s.publishNotification(event.AccountID, result.Seq); err != nil {
+		muted := s.isMuted(ctx, integrationID, event.ConvoID)
+		if err := s.publishNotification(event.AccountID, integrationID, event.ConvoID, result.Seq, muted); err != nil {
 			s.logger.Warn("Failed to publish notification", zap.Error(err))
 			// Don't fail the request if notification fails
 		}
@@ -74,17 +144,93 @@ func (s *EventService) PublishInbound(ctx context.Context, event *repo.Event) (i
 	return result.Seq, created, nil
 }
 
-// GetEventsSince retrieves events for an account since a sequence number
-func (s *EventService) GetEventsSince(ctx context.Context, accountID string, since int64, limit int32) ([]repo.Event, error) {
+// limitPayload returns the payload and attachment ref to store for an event.
+// A payload over the limit is moved to the payload store and replaced by an
+// OversizedPayload. Storing it is idempotent, like inserting the event.
+func (s *EventService) limitPayload(event *repo.Event) (json.RawMessage, json.RawMessage, error) {
+	if s.maxPayloadBytes <= 0 || len(event.Payload) <= s.maxPayloadBytes {
+		return event.Payload, event.AttachmentRef, nil
+	}
+
+	// An event has room for a single reference
+	if s.payloads == nil || len(event.AttachmentRef) > 0 {
+		s.logger.Warn("Rejected event with oversized payload",
+			zap.String("event_id", event.ID.String()),
+			zap.String("type", event.Type),
+			zap.String("account_id", event.AccountID),
+			zap.Int("payload_bytes", len(event.Payload)),
+			zap.Int("max_payload_bytes", s.maxPayloadBytes))
+		return nil, nil, fmt.Errorf("%w: %d bytes, the limit is %d", ErrPayloadTooLarge, len(event.Payload), s.maxPayloadBytes)
+	}
+
+	hash, storagePath, size, err := s.payloads.Add(bytes.NewReader(event.Payload))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to store event payload: %w", err)
+	}
+	payload, err := json.Marshal(events.OversizedPayload{Oversized: true, SizeBytes: size})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal oversized payload: %w", err)
+	}
+	attachmentRef, err := json.Marshal(events.AttachmentRef{
+		ContentHash: hash,
+		MimeType:    payloadMimeType,
+		SizeBytes:   size,
+		StorageURL:  storagePath,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal payload reference: %w", err)
+	}
+
+	s.logger.Info("Stored oversized event payload by reference",
+		zap.String("event_id", event.ID.String()),
+		zap.String("type", event.Type),
+		zap.String("content_hash", hash),
+		zap.Int64("payload_bytes", size))
+	return payload, attachmentRef, nil
+}
+
+// GetEvent returns the event with a sequence number
+func (s *EventService) GetEvent(ctx context.Context, seq int64) (repo.Event, error) {
+	return s.eventRepo.GetEventBySeq(ctx, seq)
+}
+
+// FullPayload returns an event's payload, reading it from the payload store
+// when it was too large to store inline
+func (s *EventService) FullPayload(event repo.Event) (json.RawMessage, error) {
+	var oversized events.OversizedPayload
+	if err := json.Unmarshal(event.Payload, &oversized); err != nil || !oversized.Oversized {
+		return event.Payload, nil
+	}
+
+	var ref events.AttachmentRef
+	if err := json.Unmarshal(event.AttachmentRef, &ref); err != nil {
+		return nil, fmt.Errorf("invalid payload reference: %w", err)
+	}
+	if s.payloads == nil {
+		return nil, ErrMediaNotFound
+	}
+	return s.payloads.Read(ref.ContentHash)
+}
+
+// GetEventsSince retrieves events for an account since a sequence number.
+// If types is non-empty, only events of those types are returned.
+func (s *EventService) GetEventsSince(ctx context.Context, accountID string, since int64, limit int32, types []string) ([]repo.Event, error) {
+	accountID, err := NormalizeAccountID(accountID)
+	if err != nil {
+		return nil, err
+	}
+
 	s.logger.Debug("Getting events since",
 		zap.String("account_id", accountID),
 		zap.Int64("since", since),
-		zap.Int32("limit", limit))
+		zap.Int32("limit", limit),
+		zap.Strings("types", types))
 
 	events, err := s.eventRepo.GetEventsSince(ctx, repo.GetEventsSinceParams{
 		AccountID: accountID,
 		Seq:       since,
 		Limit:     limit,
+		Types:     types,
 	})
 	if err != nil {
 		s.logger.Error("Failed to get events", zap.Error(err))
@@ -100,6 +246,11 @@ func (s *EventService) GetEventsSince(ctx context.Context, accountID string, sin
 
 // GetLatestEventSeq gets the latest sequence number for an account
 func (s *EventService) GetLatestEventSeq(ctx context.Context, accountID string) (int64, error) {
+	accountID, err := NormalizeAccountID(accountID)
+	if err != nil {
+		return 0, err
+	}
+
 	seq, err := s.eventRepo.GetLatestEventSeq(ctx, accountID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get latest event seq: %w", err)
@@ -107,13 +258,51 @@ func (s *EventService) GetLatestEventSeq(ctx context.Context, accountID string)
 	return seq, nil
 }
 
-// publishNotification publishes an ephemeral notification about new events
-func (s *EventService) publishNotification(accountID string, nextSeq int64) error {
-	subject := fmt.Sprintf("notify.account.%s", accountID)
+// GetLowWaterMark returns the highest seq deleted by event retention for an
+// account. Clients syncing from below it have missed events and need a snapshot.
+func (s *EventService) GetLowWaterMark(ctx context.Context, accountID string) (int64, error) {
+	accountID, err := NormalizeAccountID(accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	seq, err := s.eventRepo.GetLowWaterMark(ctx, accountID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get low-water mark: %w", err)
+	}
+	return seq, nil
+}
 
+// isMuted reports whether notifications about a conversation should be
+// flagged as muted. Events outside a conversation are never muted, and a
+// failed check notifies as usual.
+func (s *EventService) isMuted(ctx context.Context, integrationID int32, convoID string) bool {
+	if s.mutes == nil || integrationID == 0 || convoID == "" {
+		return false
+	}
+	muted, err := s.mutes.IsConversationMuted(ctx, integrationID, convoID, time.Now())
+	if err != nil {
+		s.logger.Warn("Failed to check conversation mute",
+			zap.Int32("integration_id", integrationID),
+			zap.String("conversation_id", convoID),
+			zap.Error(err))
+		return false
+	}
+	return muted
+}
+
+// publishNotification publishes an ephemeral notification about new events.
+// Notifications about muted conversations are still sent, so clients stay in
+// sync, but flagged so they don't alert.
+func (s *EventService) publishNotification(accountID string, integrationID int32, convoID string, nextSeq int64, muted bool) error {
 	notification := map[string]interface{}{
-		"account_id": accountID,
-		"next_seq":   nextSeq,
+		"account_id":      accountID,
+		"integration_id":  integrationID,
+		"conversation_id": convoID,
+		"next_seq":        nextSeq,
+	}
+	if muted {
+		notification["muted"] = true
 	}
 
 	data, err := json.Marshal(notification)
@@ -121,11 +310,108 @@ func (s *EventService) publishNotification(accountID string, nextSeq int64) erro
 		return fmt.Errorf("failed to marshal notification: %w", err)
 	}
 
-	return s.nats.Publish(subject, data)
+	if err := s.nats.Publish(notificationSubject(s.subjectPrefix, accountID, integrationID), data); err != nil {
+		return err
+	}
+	if s.legacySubjects {
+		return s.nats.Publish(legacyNotificationSubject(s.subjectPrefix, accountID), data)
+	}
+	return nil
+}
+
+// alertSubject is the NATS subject for operational alerts
+const alertSubject = "ops.alert"
+
+// notificationSubject returns the NATS subject for notifications about a
+// user's integration, e.g. "tennex.prod.notify.user.<id>.integration.7" for
+// the prefix "tennex.prod". Consumers watch all of a user's integrations with
+// a wildcard on the last token.
+func notificationSubject(prefix, userID string, integrationID int32) string {
+	return prefixedSubject(prefix, fmt.Sprintf("notify.user.%s.integration.%d", userID, integrationID))
+}
+
+// legacyNotificationSubject returns the per-account subject notifications
+// were published to before they were split by integration.
+//
+// Deprecated: consumers should subscribe to notificationSubject; this is kept
+// for one release behind the nats.legacy_subjects flag.
+func legacyNotificationSubject(prefix, accountID string) string {
+	return prefixedSubject(prefix, "notify.account."+accountID)
+}
+
+// prefixedSubject namespaces a NATS subject with prefix, if any
+func prefixedSubject(prefix, subject string) string {
+	if prefix = strings.Trim(prefix, "."); prefix != "" {
+		subject = prefix + "." + subject
+	}
+	return subject
+}
+
+// publishConversationEvent records an event about one of an integration's
+// conversations and notifies the account's connected clients. waMessageID is
+// the message the event is about, if any.
+func publishConversationEvent[T any](ctx context.Context, s *EventService, accountID string, integrationID int32, convoID, eventType, waMessageID string, payload T) (int64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal %s event: %w", eventType, err)
+	}
+
+	seq, _, err := s.PublishInbound(ctx, &repo.Event{
+		ID:          uuid.New(),
+		Type:        eventType,
+		AccountID:   accountID,
+		ConvoID:     convoID,
+		WaMessageID: sql.NullString{String: waMessageID, Valid: waMessageID != ""},
+		Payload:     data,
+	}, integrationID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish %s event: %w", eventType, err)
+	}
+
+	return seq, nil
+}
+
+// PublishConversationState records a conversation state change and notifies
+// the account's connected clients
+func (s *EventService) PublishConversationState(ctx context.Context, accountID string, integrationID int32, convoID string, change events.ConversationStatePayload) (int64, error) {
+	return publishConversationEvent(ctx, s, accountID, integrationID, convoID, events.TypeConversationState, "", change)
+}
+
+// PublishPresence records a contact's presence and notifies the account's
+// connected clients
+func (s *EventService) PublishPresence(ctx context.Context, accountID string, integrationID int32, convoID string, presence events.PresencePayload) (int64, error) {
+	return publishConversationEvent(ctx, s, accountID, integrationID, convoID, events.TypePresence, "", presence)
+}
+
+// PublishHistorySync records that a conversation's message history was
+// imported and notifies the account's connected clients
+func (s *EventService) PublishHistorySync(ctx context.Context, accountID string, integrationID int32, convoID string, sync events.HistorySyncPayload) (int64, error) {
+	return publishConversationEvent(ctx, s, accountID, integrationID, convoID, events.TypeHistorySync, "", sync)
+}
+
+// PublishParticipantRole records a group participant's role change and
+// notifies the account's connected clients
+func (s *EventService) PublishParticipantRole(ctx context.Context, accountID string, integrationID int32, convoID string, change events.ParticipantRolePayload) (int64, error) {
+	return publishConversationEvent(ctx, s, accountID, integrationID, convoID, events.TypeParticipantRole, "", change)
+}
+
+// PublishDelivery records a delivery or read receipt for a message the account
+// sent and notifies the account's connected clients
+func (s *EventService) PublishDelivery(ctx context.Context, accountID string, integrationID int32, convoID string, delivery events.DeliveryPayload) (int64, error) {
+	return publishConversationEvent(ctx, s, accountID, integrationID, convoID, events.TypeMessageDelivery, delivery.WAMessageID, delivery)
+}
+
+// PublishMessagesExpired records that a conversation's disappearing messages
+// were purged and notifies the account's connected clients
+func (s *EventService) PublishMessagesExpired(ctx context.Context, accountID string, integrationID int32, convoID string, expired events.MessagesExpiredPayload) (int64, error) {
+	return publishConversationEvent(ctx, s, accountID, integrationID, convoID, events.TypeMessagesExpired, "", expired)
 }
 
-// CreateMessageOutEvent creates a pending outbound message event
-func (s *EventService) CreateMessageOutEvent(ctx context.Context, accountID, convoID, clientMsgUUID string, payload json.RawMessage) (int64, error) {
+// CreateMessageOutEvent creates a pending outbound message event. Outbox
+// messages are addressed by account rather than integration; integrationID is
+// the integration the account resolves to, or 0 when it is ambiguous. It
+// fails with ErrInvalidAccountID for a malformed account ID.
+func (s *EventService) CreateMessageOutEvent(ctx context.Context, accountID string, integrationID int32, convoID, clientMsgUUID string, payload json.RawMessage) (int64, error) {
 	event := &repo.Event{
 		ID:        uuid.New(),
 		Type:      events.TypeMessageOutPending,
@@ -134,7 +420,7 @@ func (s *EventService) CreateMessageOutEvent(ctx context.Context, accountID, con
 		Payload:   payload,
 	}
 
-	seq, _, err := s.PublishInbound(ctx, event)
+	seq, _, err := s.PublishInbound(ctx, event, integrationID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create message out event: %w", err)
 	}
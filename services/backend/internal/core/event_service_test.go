@@ -0,0 +1,147 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/pkg/events"
+)
+
+func TestNotificationSubject(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{prefix: "", want: "notify.user.user-1.integration.7"},
+		{prefix: "tennex.prod", want: "tennex.prod.notify.user.user-1.integration.7"},
+		{prefix: "tennex.staging.", want: "tennex.staging.notify.user.user-1.integration.7"},
+	}
+
+	for _, tt := range tests {
+		if got := notificationSubject(tt.prefix, "user-1", 7); got != tt.want {
+			t.Errorf("notificationSubject(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestLegacyNotificationSubject(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{prefix: "", want: "notify.account.acct-1"},
+		{prefix: "tennex.prod", want: "tennex.prod.notify.account.acct-1"},
+	}
+
+	for _, tt := range tests {
+		if got := legacyNotificationSubject(tt.prefix, "acct-1"); got != tt.want {
+			t.Errorf("legacyNotificationSubject(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+// fakeMutes reports the conversations in muted as muted
+type fakeMutes struct {
+	muted map[string]bool
+	err   error
+	calls int
+}
+
+func (m *fakeMutes) IsConversationMuted(ctx context.Context, userIntegrationID int32, externalConversationID string, now time.Time) (bool, error) {
+	m.calls++
+	return m.muted[externalConversationID], m.err
+}
+
+func TestEventServiceIsMuted(t *testing.T) {
+	mutes := &fakeMutes{muted: map[string]bool{"muted@s.whatsapp.net": true}}
+	s := NewEventService(nil, nil, "", false, zap.NewNop())
+	if s.isMuted(context.Background(), 7, "muted@s.whatsapp.net") {
+		t.Error("expected nothing to be muted without a mute checker")
+	}
+
+	s.SetMuteChecker(mutes)
+	if !s.isMuted(context.Background(), 7, "muted@s.whatsapp.net") {
+		t.Error("expected the muted conversation to be muted")
+	}
+	if s.isMuted(context.Background(), 7, "other@s.whatsapp.net") {
+		t.Error("expected other conversations not to be muted")
+	}
+
+	// Events outside a conversation aren't checked
+	mutes.calls = 0
+	if s.isMuted(context.Background(), 0, "muted@s.whatsapp.net") || s.isMuted(context.Background(), 7, "") {
+		t.Error("expected events outside a conversation not to be muted")
+	}
+	if mutes.calls != 0 {
+		t.Errorf("expected no mute checks, got %d", mutes.calls)
+	}
+
+	// A failed check notifies as usual
+	mutes.err = errors.New("database down")
+	if s.isMuted(context.Background(), 7, "muted@s.whatsapp.net") {
+		t.Error("expected a failed check not to mute")
+	}
+}
+
+func TestEventServiceLimitPayload(t *testing.T) {
+	s := NewEventService(nil, nil, "", false, zap.NewNop())
+	large := json.RawMessage(`{"text":"` + strings.Repeat("a", 100) + `"}`)
+	event := &repo.Event{ID: uuid.New(), Type: events.TypeMessageIn, Payload: large}
+
+	// Without a limit every payload is stored inline
+	payload, ref, err := s.limitPayload(event)
+	if err != nil || string(payload) != string(large) || ref != nil {
+		t.Fatalf("expected the payload inline without a limit, got %s, %s, %v", payload, ref, err)
+	}
+
+	// Without a store an oversized payload is rejected
+	s.SetPayloadLimit(64, nil)
+	if _, _, err := s.limitPayload(event); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("got %v, want %v", err, ErrPayloadTooLarge)
+	}
+
+	// With one it's stored by reference
+	store := NewMediaStore(t.TempDir())
+	s.SetPayloadLimit(64, store)
+	payload, ref, err = s.limitPayload(event)
+	if err != nil {
+		t.Fatalf("failed to limit payload: %v", err)
+	}
+	var stub events.OversizedPayload
+	if err := json.Unmarshal(payload, &stub); err != nil || !stub.Oversized || stub.SizeBytes != int64(len(large)) {
+		t.Errorf("expected an oversized stub of %d bytes, got %s", len(large), payload)
+	}
+	var attachment events.AttachmentRef
+	if err := json.Unmarshal(ref, &attachment); err != nil || !store.Has(attachment.ContentHash) {
+		t.Fatalf("expected a reference to the stored payload, got %s", ref)
+	}
+
+	full, err := s.FullPayload(repo.Event{Payload: payload, AttachmentRef: ref})
+	if err != nil || string(full) != string(large) {
+		t.Errorf("expected the full payload back, got %s, %v", full, err)
+	}
+
+	// Small payloads stay inline and are returned as they are
+	small := &repo.Event{ID: uuid.New(), Type: events.TypeMessageIn, Payload: json.RawMessage(`{"text":"hi"}`)}
+	payload, ref, err = s.limitPayload(small)
+	if err != nil || string(payload) != `{"text":"hi"}` || ref != nil {
+		t.Errorf("expected a small payload inline, got %s, %s, %v", payload, ref, err)
+	}
+	if full, err := s.FullPayload(*small); err != nil || string(full) != `{"text":"hi"}` {
+		t.Errorf("got %s, %v for an inline payload", full, err)
+	}
+
+	// An event that already references an attachment has no room for the payload
+	event.AttachmentRef = json.RawMessage(`{"content_hash":"abc"}`)
+	if _, _, err := s.limitPayload(event); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("got %v, want %v for an event with an attachment", err, ErrPayloadTooLarge)
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 
 	"github.com/tennex/backend/internal/repo"
@@ -22,6 +24,10 @@ const (
 	IntegrationTypeSlack    = "slack"
 )
 
+// ErrMultipleIntegrations is returned by the single-integration lookups when a
+// user has connected more than one integration of the type
+var ErrMultipleIntegrations = errors.New("user has multiple integrations of this type")
+
 // IntegrationService handles user integration business logic
 type IntegrationService struct {
 	integrationRepo repo.IntegrationRepository
@@ -94,15 +100,54 @@ func (s *IntegrationService) UpsertUserIntegration(ctx context.Context, userID u
 	return &integration, nil
 }
 
-// GetUserIntegration retrieves a specific integration for a user
+// GetUserIntegration retrieves a user's only integration of a type. It
+// predates multiple integrations per type: it fails with pgx.ErrNoRows when the
+// user has none and with ErrMultipleIntegrations when they have several.
 func (s *IntegrationService) GetUserIntegration(ctx context.Context, userID uuid.UUID, integrationType string) (*repo.UserIntegration, error) {
-	integration, err := s.integrationRepo.GetUserIntegration(ctx, userID, integrationType)
+	integrations, err := s.GetUserIntegrations(ctx, userID, integrationType)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(integrations) {
+	case 0:
+		return nil, fmt.Errorf("failed to get user integration: %w", pgx.ErrNoRows)
+	case 1:
+		return &integrations[0], nil
+	default:
+		return nil, fmt.Errorf("failed to get user integration: %w", ErrMultipleIntegrations)
+	}
+}
+
+// GetUserIntegrations retrieves a user's integrations of a type, oldest first
+func (s *IntegrationService) GetUserIntegrations(ctx context.Context, userID uuid.UUID, integrationType string) ([]repo.UserIntegration, error) {
+	integrations, err := s.integrationRepo.GetUserIntegrations(ctx, userID, integrationType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user integrations: %w", err)
+	}
+	return integrations, nil
+}
+
+// GetUserIntegrationByExternalID retrieves the user's integration with an
+// external account, e.g. one WhatsApp number
+func (s *IntegrationService) GetUserIntegrationByExternalID(ctx context.Context, userID uuid.UUID, integrationType, externalID string) (*repo.UserIntegration, error) {
+	integration, err := s.integrationRepo.GetUserIntegration(ctx, userID, integrationType, externalID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user integration: %w", err)
 	}
 	return &integration, nil
 }
 
+// GetIntegrationByExternalID retrieves the integration with an external
+// account, whichever user it belongs to
+func (s *IntegrationService) GetIntegrationByExternalID(ctx context.Context, integrationType, externalID string) (*repo.UserIntegration, error) {
+	integration, err := s.integrationRepo.GetUserIntegrationByExternalID(ctx, integrationType, externalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get integration by external ID: %w", err)
+	}
+	return &integration, nil
+}
+
 // ListUserIntegrations retrieves all integrations for a user
 func (s *IntegrationService) ListUserIntegrations(ctx context.Context, userID uuid.UUID) ([]repo.UserIntegration, error) {
 	integrations, err := s.integrationRepo.ListUserIntegrations(ctx, userID)
@@ -122,73 +167,214 @@ func (s *IntegrationService) UpsertWhatsAppIntegration(ctx context.Context, user
 	return s.UpsertUserIntegration(ctx, userID, IntegrationTypeWhatsApp, waJid, displayName, avatarUrl, events.AccountStatusConnected, nil, &now)
 }
 
-// GetWhatsAppIntegration retrieves a user's WhatsApp integration
+// GetWhatsAppIntegration retrieves a user's only WhatsApp integration
 func (s *IntegrationService) GetWhatsAppIntegration(ctx context.Context, userID uuid.UUID) (*repo.UserIntegration, error) {
 	return s.GetUserIntegration(ctx, userID, IntegrationTypeWhatsApp)
 }
 
+// GetWhatsAppIntegrations retrieves all of a user's WhatsApp integrations
+func (s *IntegrationService) GetWhatsAppIntegrations(ctx context.Context, userID uuid.UUID) ([]repo.UserIntegration, error) {
+	return s.GetUserIntegrations(ctx, userID, IntegrationTypeWhatsApp)
+}
+
 // SetWhatsAppConnected marks a WhatsApp integration as connected
 func (s *IntegrationService) SetWhatsAppConnected(ctx context.Context, userID uuid.UUID, waJid, displayName string) error {
 	now := time.Now()
-	_, err := s.UpsertWhatsAppIntegration(ctx, userID, waJid, displayName, "")
+	integration, err := s.UpsertWhatsAppIntegration(ctx, userID, waJid, displayName, "")
 	if err != nil {
 		return err
 	}
 
-	return s.UpdateIntegrationStatus(ctx, userID, IntegrationTypeWhatsApp, events.AccountStatusConnected, &now)
+	return s.UpdateIntegrationStatus(ctx, integration.ID, events.AccountStatusConnected, &now, "", nil)
 }
 
 // SetWhatsAppDisconnected marks a WhatsApp integration as disconnected
-func (s *IntegrationService) SetWhatsAppDisconnected(ctx context.Context, userID uuid.UUID) error {
-	now := time.Now()
-	return s.UpdateIntegrationStatus(ctx, userID, IntegrationTypeWhatsApp, events.AccountStatusDisconnected, &now)
+func (s *IntegrationService) SetWhatsAppDisconnected(ctx context.Context, userID uuid.UUID, waJid string) error {
+	return s.setWhatsAppStatus(ctx, userID, waJid, events.AccountStatusDisconnected)
 }
 
 // SetWhatsAppError marks a WhatsApp integration as having an error
-func (s *IntegrationService) SetWhatsAppError(ctx context.Context, userID uuid.UUID) error {
+func (s *IntegrationService) SetWhatsAppError(ctx context.Context, userID uuid.UUID, waJid string) error {
+	return s.setWhatsAppStatus(ctx, userID, waJid, events.AccountStatusError)
+}
+
+func (s *IntegrationService) setWhatsAppStatus(ctx context.Context, userID uuid.UUID, waJid, status string) error {
+	integration, err := s.GetUserIntegrationByExternalID(ctx, userID, IntegrationTypeWhatsApp, waJid)
+	if err != nil {
+		return err
+	}
+
 	now := time.Now()
-	return s.UpdateIntegrationStatus(ctx, userID, IntegrationTypeWhatsApp, events.AccountStatusError, &now)
+	return s.UpdateIntegrationStatus(ctx, integration.ID, status, &now, "", nil)
 }
 
-// UpdateIntegrationStatus updates the status of a user integration
-func (s *IntegrationService) UpdateIntegrationStatus(ctx context.Context, userID uuid.UUID, integrationType, status string, lastSeen *time.Time) error {
+// UpdateIntegrationStatus updates the status of a user integration. A change
+// of status is recorded in the integration's status history along with the
+// reason and metadata.
+func (s *IntegrationService) UpdateIntegrationStatus(ctx context.Context, integrationID int32, status string, lastSeen *time.Time, reason string, metadata map[string]string) error {
 	var lastSeenNull sql.NullTime
 	if lastSeen != nil {
 		lastSeenNull = sql.NullTime{Time: *lastSeen, Valid: true}
 	}
 
-	err := s.integrationRepo.UpdateUserIntegrationStatus(ctx, userID, integrationType, status, lastSeenNull)
+	var metadataJSON json.RawMessage
+	if len(metadata) > 0 {
+		data, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal status metadata: %w", err)
+		}
+		metadataJSON = data
+	}
+
+	err := s.integrationRepo.UpdateUserIntegrationStatus(ctx, repo.UpdateUserIntegrationStatusParams{
+		ID:       integrationID,
+		Status:   status,
+		LastSeen: lastSeenNull,
+		Reason:   reason,
+		Metadata: metadataJSON,
+	})
 	if err != nil {
 		s.logger.Error("Failed to update integration status",
-			zap.String("user_id", userID.String()),
-			zap.String("integration_type", integrationType),
+			zap.Int32("integration_id", integrationID),
 			zap.String("status", status),
 			zap.Error(err))
 		return fmt.Errorf("failed to update integration status: %w", err)
 	}
 
 	s.logger.Info("Integration status updated",
-		zap.String("user_id", userID.String()),
-		zap.String("integration_type", integrationType),
-		zap.String("status", status))
+		zap.Int32("integration_id", integrationID),
+		zap.String("status", status),
+		zap.String("reason", reason))
 
 	return nil
 }
 
-// DeleteUserIntegration removes a user's integration
-func (s *IntegrationService) DeleteUserIntegration(ctx context.Context, userID uuid.UUID, integrationType string) error {
-	err := s.integrationRepo.DeleteUserIntegration(ctx, userID, integrationType)
+// GetIntegrationStatusHistory returns an integration's most recent status
+// transitions, newest first
+func (s *IntegrationService) GetIntegrationStatusHistory(ctx context.Context, integrationID int32, limit int32) ([]repo.IntegrationStatusEvent, error) {
+	history, err := s.integrationRepo.ListIntegrationStatusEvents(ctx, integrationID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get integration status history: %w", err)
+	}
+	return history, nil
+}
+
+// DeleteUserIntegration removes a user's integration with an external account
+func (s *IntegrationService) DeleteUserIntegration(ctx context.Context, userID uuid.UUID, integrationType, externalID string) error {
+	err := s.integrationRepo.DeleteUserIntegration(ctx, userID, integrationType, externalID)
 	if err != nil {
 		s.logger.Error("Failed to delete user integration",
 			zap.String("user_id", userID.String()),
 			zap.String("integration_type", integrationType),
+			zap.String("external_id", externalID),
 			zap.Error(err))
 		return fmt.Errorf("failed to delete user integration: %w", err)
 	}
 
 	s.logger.Info("User integration deleted",
 		zap.String("user_id", userID.String()),
-		zap.String("integration_type", integrationType))
+		zap.String("integration_type", integrationType),
+		zap.String("external_id", externalID))
 
 	return nil
 }
+
+// GetIntegrationByID retrieves an integration by its ID
+func (s *IntegrationService) GetIntegrationByID(ctx context.Context, id int32) (*repo.UserIntegration, error) {
+	integration, err := s.integrationRepo.GetUserIntegrationByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get integration: %w", err)
+	}
+	return &integration, nil
+}
+
+// Events are keyed by account ID, which is the ID of the user that owns the
+// integrations, while synced data is keyed by integration ID. The lookups
+// below translate between the two.
+
+// GetAccountIntegration resolves an event account ID to the account's only
+// integration of a type. Like GetUserIntegration, it fails with pgx.ErrNoRows
+// or ErrMultipleIntegrations when the account has none or several.
+func (s *IntegrationService) GetAccountIntegration(ctx context.Context, accountID, integrationType string) (*repo.UserIntegration, error) {
+	userID, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account ID %q: %w", accountID, err)
+	}
+	return s.GetUserIntegration(ctx, userID, integrationType)
+}
+
+// GetIntegrationAccountID returns the account ID that an integration's events
+// are recorded under
+func (s *IntegrationService) GetIntegrationAccountID(ctx context.Context, integrationID int32) (string, error) {
+	integration, err := s.GetIntegrationByID(ctx, integrationID)
+	if err != nil {
+		return "", err
+	}
+	return integration.UserID.String(), nil
+}
+
+// GetEventIntegration finds the integration an event belongs to. Events
+// recorded before integrations were tracked on them fall back to the
+// account's only integration.
+func (s *IntegrationService) GetEventIntegration(ctx context.Context, event *repo.Event) (*repo.UserIntegration, error) {
+	if event.UserIntegrationID.Valid {
+		return s.GetIntegrationByID(ctx, event.UserIntegrationID.Int32)
+	}
+
+	userID, err := uuid.Parse(event.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account ID %q: %w", event.AccountID, err)
+	}
+	integrations, err := s.ListUserIntegrations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(integrations) {
+	case 0:
+		return nil, fmt.Errorf("failed to get event integration: %w", pgx.ErrNoRows)
+	case 1:
+		return &integrations[0], nil
+	default:
+		return nil, fmt.Errorf("failed to get event integration: %w", ErrMultipleIntegrations)
+	}
+}
+
+// ListIntegrations retrieves a page of integrations across all users, along
+// with the total number of integrations matching the filters
+func (s *IntegrationService) ListIntegrations(ctx context.Context, params repo.ListIntegrationsParams) ([]repo.UserIntegration, int64, error) {
+	integrations, err := s.integrationRepo.ListIntegrations(ctx, params)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list integrations: %w", err)
+	}
+
+	total, err := s.integrationRepo.CountIntegrations(ctx, params)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count integrations: %w", err)
+	}
+
+	return integrations, total, nil
+}
+
+// GetIntegrationSyncStats summarizes the data synced for an integration
+func (s *IntegrationService) GetIntegrationSyncStats(ctx context.Context, id int32) (*repo.IntegrationSyncStats, error) {
+	stats, err := s.integrationRepo.GetIntegrationSyncStats(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get integration sync stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// ListIntegrationSyncStats summarizes the data synced for each of the given
+// integrations, keyed by integration ID. Integrations that don't exist are
+// left out.
+func (s *IntegrationService) ListIntegrationSyncStats(ctx context.Context, ids []int32) (map[int32]repo.IntegrationSyncStats, error) {
+	if len(ids) == 0 {
+		return map[int32]repo.IntegrationSyncStats{}, nil
+	}
+	stats, err := s.integrationRepo.ListIntegrationSyncStats(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list integration sync stats: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,248 @@
+package core
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginThrottleConfig controls how failed logins hold back further attempts
+type LoginThrottleConfig struct {
+	// Failed logins in a row that trigger a lockout, or delays
+	MaxFailures int
+
+	// Failures and lockouts are forgotten after this long without another
+	// failure
+	Window time.Duration
+
+	// Length of the first lockout. Each lockout after it within the window
+	// doubles, up to MaxLockout.
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+
+	// Slow attempts down instead of locking the key out: once MaxFailures
+	// have failed, each attempt first waits BaseDelay, doubling with every
+	// further failure up to MaxDelay
+	SoftLimit bool
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultLoginThrottleConfig returns the settings of the per-user login
+// throttle used unless configured otherwise. It only slows logins down, so
+// failing logins to someone's account can't lock its owner out.
+func DefaultLoginThrottleConfig() LoginThrottleConfig {
+	return LoginThrottleConfig{
+		MaxFailures: 5,
+		Window:      15 * time.Minute,
+		BaseLockout: time.Minute,
+		MaxLockout:  time.Hour,
+		SoftLimit:   true,
+		BaseDelay:   time.Second,
+		MaxDelay:    10 * time.Second,
+	}
+}
+
+// DefaultLoginIPThrottleConfig returns the settings of the per-IP login
+// throttle used unless configured otherwise. It locks IPs out, after more
+// failures than the per-user one, as many users can share an IP behind NAT.
+func DefaultLoginIPThrottleConfig() LoginThrottleConfig {
+	config := DefaultLoginThrottleConfig()
+	config.MaxFailures = 50
+	config.SoftLimit = false
+	return config
+}
+
+// LoginAttempts is the failed login state of one throttle key
+type LoginAttempts struct {
+	Failures    int       // Failures since the last lockout, reserved ones included
+	Lockouts    int       // Lockouts so far, each doubling the next
+	LockedUntil time.Time // Zero unless locked out
+	ExpiresAt   time.Time // When the entry can be forgotten
+}
+
+// LoginAttemptStore keeps failed login attempts by throttle key
+type LoginAttemptStore interface {
+	Get(key string) (LoginAttempts, bool)
+	Set(key string, attempts LoginAttempts)
+	Delete(key string)
+	// DeleteExpired forgets the entries that expired before now
+	DeleteExpired(now time.Time)
+}
+
+// MemoryLoginAttemptStore keeps login attempts in process memory. Attempts
+// aren't shared between backend instances and are lost on restart.
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]LoginAttempts
+}
+
+// NewMemoryLoginAttemptStore returns an empty in-memory store
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{attempts: make(map[string]LoginAttempts)}
+}
+
+func (s *MemoryLoginAttemptStore) Get(key string) (LoginAttempts, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempts, ok := s.attempts[key]
+	return attempts, ok
+}
+
+func (s *MemoryLoginAttemptStore) Set(key string, attempts LoginAttempts) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts[key] = attempts
+}
+
+func (s *MemoryLoginAttemptStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+}
+
+func (s *MemoryLoginAttemptStore) DeleteExpired(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, attempts := range s.attempts {
+		if !attempts.ExpiresAt.After(now) {
+			delete(s.attempts, key)
+		}
+	}
+}
+
+// LoginThrottler locks out or slows down a client after repeated failed
+// logins, for longer each time it keeps failing
+type LoginThrottler struct {
+	config LoginThrottleConfig
+	store  LoginAttemptStore
+
+	// Serializes read-modify-write of attempts
+	mu        sync.Mutex
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewLoginThrottler returns a throttler keeping its attempts in store
+func NewLoginThrottler(config LoginThrottleConfig, store LoginAttemptStore) *LoginThrottler {
+	return &LoginThrottler{
+		config: config,
+		store:  store,
+		now:    time.Now,
+	}
+}
+
+// LoginUserKey is the key failed logins to a user are counted by, whatever
+// IP they come from and whether its username or email was given, so neither
+// rotating IPs nor switching identifiers escapes the throttle
+func LoginUserKey(userID uuid.UUID) string {
+	return "user|" + userID.String()
+}
+
+// LoginUsernameKey is the key failed logins to an unknown username or email
+// are counted by
+func LoginUsernameKey(username string) string {
+	return "username|" + strings.ToLower(strings.TrimSpace(username))
+}
+
+// LoginIPKey is the key failed logins from an IP are counted by, whatever
+// users they try
+func LoginIPKey(ip string) string {
+	return "ip|" + ip
+}
+
+// Reserve counts a login attempt under key as failed before its password is
+// checked, so concurrent attempts can't all get past a check made before any
+// of them failed. An attempt that turns out to succeed is given back with
+// RecordSuccess or Release.
+//
+// It returns how long the attempt must wait before it's checked. Unless the
+// throttle is a soft limit, an attempt that must wait is refused instead,
+// without being counted, and wait is how much longer the key is locked out.
+func (t *LoginThrottler) Reserve(key string) (wait time.Duration, refused bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.sweep(now)
+
+	attempts, _ := t.get(key, now)
+	if t.config.SoftLimit {
+		if attempts.Failures >= t.config.MaxFailures {
+			wait = backoff(t.config.BaseDelay, t.config.MaxDelay, attempts.Failures-t.config.MaxFailures)
+		}
+	} else {
+		if remaining := attempts.LockedUntil.Sub(now); remaining > 0 {
+			return remaining, true
+		}
+		if attempts.Failures >= t.config.MaxFailures {
+			lockout := backoff(t.config.BaseLockout, t.config.MaxLockout, attempts.Lockouts)
+			attempts.Lockouts++
+			attempts.Failures = 0
+			attempts.LockedUntil = now.Add(lockout)
+			attempts.ExpiresAt = attempts.LockedUntil.Add(t.config.Window)
+			t.store.Set(key, attempts)
+			return lockout, true
+		}
+	}
+
+	attempts.Failures++
+	attempts.ExpiresAt = now.Add(t.config.Window)
+	if attempts.LockedUntil.After(now) {
+		attempts.ExpiresAt = attempts.LockedUntil.Add(t.config.Window)
+	}
+	t.store.Set(key, attempts)
+	return wait, false
+}
+
+// Release gives back an attempt reserved under key that succeeded, leaving
+// the key's other failures counted
+func (t *LoginThrottler) Release(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	attempts, ok := t.get(key, t.now())
+	if !ok || attempts.Failures == 0 {
+		return
+	}
+	attempts.Failures--
+	t.store.Set(key, attempts)
+}
+
+// RecordSuccess forgets the key's failed logins
+func (t *LoginThrottler) RecordSuccess(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.store.Delete(key)
+}
+
+// get returns the key's attempts unless they expired
+func (t *LoginThrottler) get(key string, now time.Time) (LoginAttempts, bool) {
+	attempts, ok := t.store.Get(key)
+	if !ok || !attempts.ExpiresAt.After(now) {
+		return LoginAttempts{}, false
+	}
+	return attempts, true
+}
+
+// backoff returns base doubled once for each of the given number of earlier
+// lockouts or delays, up to ceiling
+func backoff(base, ceiling time.Duration, earlier int) time.Duration {
+	d := base
+	for i := 0; i < earlier && d < ceiling; i++ {
+		d *= 2
+	}
+	return min(d, ceiling)
+}
+
+// sweep drops expired attempts at most once per window, so keys that stop
+// failing don't pile up
+func (t *LoginThrottler) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.config.Window {
+		return
+	}
+	t.store.DeleteExpired(now)
+	t.lastSweep = now
+}
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestLoginThrottler(now *time.Time, softLimit bool) *LoginThrottler {
+	throttler := NewLoginThrottler(LoginThrottleConfig{
+		MaxFailures: 3,
+		Window:      10 * time.Minute,
+		BaseLockout: time.Minute,
+		MaxLockout:  3 * time.Minute,
+		SoftLimit:   softLimit,
+		BaseDelay:   time.Second,
+		MaxDelay:    3 * time.Second,
+	}, NewMemoryLoginAttemptStore())
+	throttler.now = func() time.Time { return *now }
+	return throttler
+}
+
+func TestLoginThrottlerLocksOutWithExponentialBackoff(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttler := newTestLoginThrottler(&now, false)
+	key := LoginIPKey("10.0.0.1")
+
+	fail := func(times int) {
+		for i := 0; i < times; i++ {
+			if wait, refused := throttler.Reserve(key); refused {
+				t.Fatalf("attempt %d refused with %v left", i+1, wait)
+			}
+		}
+	}
+
+	// Failures up to the limit don't lock the key out
+	fail(3)
+
+	// The next attempt starts a lockout, and is refused until it ends
+	if wait, refused := throttler.Reserve(key); !refused || wait != time.Minute {
+		t.Fatalf("expected a 1m lockout, got %v refused=%v", wait, refused)
+	}
+	now = now.Add(30 * time.Second)
+	if wait, refused := throttler.Reserve(key); !refused || wait != 30*time.Second {
+		t.Errorf("expected 30s left, got %v refused=%v", wait, refused)
+	}
+	now = now.Add(30 * time.Second)
+
+	// Each lockout within the window doubles, up to the ceiling
+	for _, want := range []time.Duration{2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		fail(3)
+		if wait, refused := throttler.Reserve(key); !refused || wait != want {
+			t.Errorf("expected a %v lockout, got %v refused=%v", want, wait, refused)
+		}
+		now = now.Add(want)
+	}
+
+	// Other keys aren't affected
+	if _, refused := throttler.Reserve(LoginIPKey("10.0.0.2")); refused {
+		t.Error("expected another IP not to be locked out")
+	}
+}
+
+func TestLoginThrottlerSoftLimitDelaysInsteadOfLockingOut(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttler := newTestLoginThrottler(&now, true)
+	key := LoginUserKey(uuid.New())
+
+	// Attempts past the limit are never refused, but wait longer each time,
+	// up to the ceiling
+	for i, want := range []time.Duration{0, 0, 0, time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		wait, refused := throttler.Reserve(key)
+		if refused || wait != want {
+			t.Errorf("attempt %d: got wait %v refused=%v, want %v", i+1, wait, refused, want)
+		}
+	}
+
+	// A successful login forgets the failures
+	throttler.RecordSuccess(key)
+	if wait, _ := throttler.Reserve(key); wait != 0 {
+		t.Errorf("expected no delay after a successful login, got %v", wait)
+	}
+}
+
+func TestLoginThrottlerReservesConcurrentAttempts(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttler := newTestLoginThrottler(&now, false)
+	key := LoginIPKey("10.0.0.1")
+
+	// Attempts made at once are each counted before any is checked, so no
+	// more than the limit get through
+	var wg sync.WaitGroup
+	var admitted atomic.Int32
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, refused := throttler.Reserve(key); !refused {
+				admitted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := admitted.Load(); got != 3 {
+		t.Errorf("expected 3 concurrent attempts to get through, got %d", got)
+	}
+}
+
+func TestLoginThrottlerReleaseGivesBackOneAttempt(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttler := newTestLoginThrottler(&now, false)
+	key := LoginIPKey("10.0.0.1")
+
+	// Three attempts, one of which succeeded and is given back
+	throttler.Reserve(key)
+	throttler.Reserve(key)
+	throttler.Reserve(key)
+	throttler.Release(key)
+	if _, refused := throttler.Reserve(key); refused {
+		t.Fatal("expected the released attempt not to count")
+	}
+	if _, refused := throttler.Reserve(key); !refused {
+		t.Error("expected the failures before the success to still count")
+	}
+}
+
+func TestLoginThrottlerForgetsFailuresAfterWindow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttler := newTestLoginThrottler(&now, false)
+	key := LoginIPKey("10.0.0.1")
+
+	throttler.Reserve(key)
+	throttler.Reserve(key)
+
+	// Failures spread out over more than the window never add up to a lockout
+	now = now.Add(10 * time.Minute)
+	for i := 0; i < 3; i++ {
+		if _, refused := throttler.Reserve(key); refused {
+			t.Fatalf("expected old failures to be forgotten, refused attempt %d", i+1)
+		}
+	}
+
+	// A lockout long past doesn't make the next one longer
+	throttler.Reserve(key)
+	now = now.Add(time.Minute + 10*time.Minute)
+	for i := 0; i < 3; i++ {
+		throttler.Reserve(key)
+	}
+	if wait, refused := throttler.Reserve(key); !refused || wait != time.Minute {
+		t.Errorf("expected the base lockout again, got %v refused=%v", wait, refused)
+	}
+}
+
+func TestLoginKeys(t *testing.T) {
+	if LoginUsernameKey("Alice ") != LoginUsernameKey("alice") {
+		t.Error("expected usernames to be keyed however they're cased and padded")
+	}
+	userID := uuid.New()
+	if LoginUserKey(userID) == LoginUsernameKey(userID.String()) {
+		t.Error("expected a user's key not to be reachable through an unknown username")
+	}
+}
+
+func TestMemoryLoginAttemptStoreDeleteExpired(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryLoginAttemptStore()
+	store.Set("old", LoginAttempts{Failures: 1, ExpiresAt: now})
+	store.Set("new", LoginAttempts{Failures: 1, ExpiresAt: now.Add(time.Second)})
+
+	store.DeleteExpired(now)
+	if _, ok := store.Get("old"); ok {
+		t.Error("expected the expired entry to be deleted")
+	}
+	if _, ok := store.Get("new"); !ok {
+		t.Error("expected the live entry to be kept")
+	}
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+var (
+	ErrInvalidContentHash  = errors.New("content hash must be a hex SHA-256")
+	ErrContentHashMismatch = errors.New("media data does not match its content hash")
+	ErrMediaNotFound       = errors.New("media not found")
+)
+
+// MediaStore keeps media files in a directory, one file per content hash
+type MediaStore struct {
+	dir string
+}
+
+// NewMediaStore creates a media store in dir, which is also where the HTTP
+// API serves media from
+func NewMediaStore(dir string) *MediaStore {
+	return &MediaStore{dir: dir}
+}
+
+// ValidContentHash reports whether hash is a lowercase hex SHA-256
+func ValidContentHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// BlobPath returns where the file with a content hash is stored, relative to
+// the store's directory
+func BlobPath(hash string) string {
+	return path.Join("blobs", hash[:2], hash)
+}
+
+// Put stores the data read from r under its content hash and returns its
+// path relative to the store's directory and its size. The data is checked
+// against hash before it becomes visible, so a partial or corrupted upload is
+// never served.
+func (s *MediaStore) Put(hash string, r io.Reader) (string, int64, error) {
+	if !ValidContentHash(hash) {
+		return "", 0, ErrInvalidContentHash
+	}
+	_, blobPath, size, err := s.store(hash, r)
+	return blobPath, size, err
+}
+
+// Add stores the data read from r under the content hash computed from it,
+// and returns the hash, the file's path relative to the store's directory and
+// its size
+func (s *MediaStore) Add(r io.Reader) (string, string, int64, error) {
+	return s.store("", r)
+}
+
+// store writes r to a temporary file and moves it to its content hash's path.
+// A non-empty expected hash must match the data.
+func (s *MediaStore) store(expected string, r io.Reader) (string, string, int64, error) {
+	blobsDir := filepath.Join(s.dir, "blobs")
+	if err := os.MkdirAll(blobsDir, 0o755); err != nil {
+		return "", "", 0, fmt.Errorf("failed to create media directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(blobsDir, "upload.*.tmp")
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to create media file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to write media file: %w", err)
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	if expected != "" && hash != expected {
+		return "", "", 0, ErrContentHashMismatch
+	}
+
+	blobPath := BlobPath(hash)
+	target := filepath.Join(s.dir, filepath.FromSlash(blobPath))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", "", 0, fmt.Errorf("failed to create media directory: %w", err)
+	}
+
+	// Concurrent uploads of the same file write the same bytes, so whichever
+	// rename lands last is as good as the first
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", "", 0, fmt.Errorf("failed to store media file: %w", err)
+	}
+	return hash, blobPath, size, nil
+}
+
+// Read returns the file stored under a content hash
+func (s *MediaStore) Read(hash string) ([]byte, error) {
+	if !ValidContentHash(hash) {
+		return nil, ErrInvalidContentHash
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(BlobPath(hash))))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read media file: %w", err)
+	}
+	return data, nil
+}
+
+// Has reports whether a file is stored under a content hash
+func (s *MediaStore) Has(hash string) bool {
+	if !ValidContentHash(hash) {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(s.dir, filepath.FromSlash(BlobPath(hash))))
+	return err == nil
+}
+
+// Remove deletes the file stored under a content hash. A file that is already
+// gone is not an error.
+func (s *MediaStore) Remove(hash string) error {
+	if !ValidContentHash(hash) {
+		return ErrInvalidContentHash
+	}
+	err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(BlobPath(hash))))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove media file: %w", err)
+	}
+	return nil
+}
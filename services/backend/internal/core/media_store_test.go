@@ -0,0 +1,104 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMediaStorePut(t *testing.T) {
+	dir := t.TempDir()
+	store := NewMediaStore(dir)
+
+	const content = "identical forwarded image"
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+
+	blobPath, size, err := store.Put(hash, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if blobPath != "blobs/"+hash[:2]+"/"+hash || size != int64(len(content)) {
+		t.Errorf("unexpected path %q and size %d", blobPath, size)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, blobPath))
+	if err != nil || string(data) != content {
+		t.Fatalf("expected the stored file to hold the content, got %q: %v", data, err)
+	}
+
+	// Data that doesn't match its hash is rejected and leaves nothing behind
+	otherSum := sha256.Sum256([]byte("something else"))
+	otherHash := hex.EncodeToString(otherSum[:])
+	if _, _, err := store.Put(otherHash, strings.NewReader(content)); !errors.Is(err, ErrContentHashMismatch) {
+		t.Errorf("expected ErrContentHashMismatch, got %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "blobs", otherHash[:2]))
+	if len(entries) != 0 {
+		t.Errorf("expected no files after a mismatched upload, got %d", len(entries))
+	}
+
+	for _, bad := range []string{"", "../../etc/passwd", strings.ToUpper(hash), hash[:63] + "g"} {
+		if _, _, err := store.Put(bad, strings.NewReader(content)); !errors.Is(err, ErrInvalidContentHash) {
+			t.Errorf("Put(%q): expected ErrInvalidContentHash, got %v", bad, err)
+		}
+	}
+}
+
+func TestMediaStoreAddAndRead(t *testing.T) {
+	store := NewMediaStore(t.TempDir())
+
+	const content = "uploaded document"
+	sum := sha256.Sum256([]byte(content))
+	want := hex.EncodeToString(sum[:])
+
+	hash, blobPath, size, err := store.Add(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if hash != want || blobPath != BlobPath(want) || size != int64(len(content)) {
+		t.Errorf("unexpected hash %q, path %q and size %d", hash, blobPath, size)
+	}
+	if !store.Has(hash) {
+		t.Error("expected the store to have the added file")
+	}
+
+	data, err := store.Read(hash)
+	if err != nil || string(data) != content {
+		t.Fatalf("expected Read to return the content, got %q: %v", data, err)
+	}
+
+	otherSum := sha256.Sum256([]byte("never stored"))
+	if _, err := store.Read(hex.EncodeToString(otherSum[:])); !errors.Is(err, ErrMediaNotFound) {
+		t.Errorf("expected ErrMediaNotFound, got %v", err)
+	}
+	if _, err := store.Read("../../etc/passwd"); !errors.Is(err, ErrInvalidContentHash) {
+		t.Errorf("expected ErrInvalidContentHash, got %v", err)
+	}
+}
+
+func TestMediaStoreRemove(t *testing.T) {
+	store := NewMediaStore(t.TempDir())
+
+	hash, _, _, err := store.Add(strings.NewReader("expired photo"))
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := store.Remove(hash); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if store.Has(hash) {
+		t.Error("expected the file to be removed")
+	}
+
+	// Removing it again is fine
+	if err := store.Remove(hash); err != nil {
+		t.Errorf("expected removing a missing file to succeed, got %v", err)
+	}
+	if err := store.Remove("../../etc/passwd"); !errors.Is(err, ErrInvalidContentHash) {
+		t.Errorf("expected ErrInvalidContentHash, got %v", err)
+	}
+}
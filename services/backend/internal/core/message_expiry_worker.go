@@ -0,0 +1,200 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/pkg/events"
+)
+
+// MessageExpiryConfig controls how the message expiry worker purges
+// disappearing messages
+type MessageExpiryConfig struct {
+	// How often to look for expired messages
+	Interval time.Duration
+
+	// Messages deleted per statement
+	BatchSize int32
+}
+
+// MessageExpiryStats summarizes the message expiry worker's progress since
+// startup
+type MessageExpiryStats struct {
+	Runs      int64
+	Failures  int64
+	Purged    int64
+	LastRunAt time.Time
+}
+
+// MessageExpiryWorker purges disappearing messages once their timer runs out
+// and tells clients which messages are gone. The message repository's DeleteExpiredMessages documents which
+// copies of their content go with them.
+type MessageExpiryWorker struct {
+	messageRepo  repo.MessageRepository
+	eventService *EventService
+	mediaStore   *MediaStore
+	config       MessageExpiryConfig
+	logger       *zap.Logger
+	now          func() time.Time
+
+	mu    sync.Mutex
+	stats MessageExpiryStats
+}
+
+// NewMessageExpiryWorker creates a new message expiry worker. The files of
+// media only expired messages used are removed from mediaStore; a nil store
+// leaves them.
+func NewMessageExpiryWorker(messageRepo repo.MessageRepository, eventService *EventService, mediaStore *MediaStore, config MessageExpiryConfig, logger *zap.Logger) *MessageExpiryWorker {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+
+	return &MessageExpiryWorker{
+		messageRepo:  messageRepo,
+		eventService: eventService,
+		mediaStore:   mediaStore,
+		config:       config,
+		logger:       logger.Named("message_expiry_worker"),
+		now:          time.Now,
+	}
+}
+
+// Start purges expired messages until ctx is done
+func (w *MessageExpiryWorker) Start(ctx context.Context) {
+	w.logger.Info("Starting message expiry worker",
+		zap.Duration("interval", w.config.Interval),
+		zap.Int32("batch_size", w.config.BatchSize))
+	defer w.logger.Info("Message expiry worker stopped")
+
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("Message expiry pass failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce purges every message that has expired, a batch at a time
+func (w *MessageExpiryWorker) RunOnce(ctx context.Context) error {
+	started := w.now()
+
+	var runErr error
+	for {
+		expired, err := w.messageRepo.DeleteExpiredMessages(ctx, repo.DeleteExpiredMessagesParams{
+			Now:       started,
+			BatchSize: w.config.BatchSize,
+		})
+		if err != nil {
+			runErr = err
+			break
+		}
+
+		w.mu.Lock()
+		w.stats.Purged += int64(len(expired.Messages))
+		w.mu.Unlock()
+
+		// The messages are already gone, so a failed notification or file
+		// removal is logged rather than retried
+		w.removeMedia(expired.OrphanedMedia)
+		w.notify(ctx, expired.Messages, started)
+
+		if len(expired.Messages) < int(w.config.BatchSize) {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			runErr = err
+			break
+		}
+	}
+
+	w.mu.Lock()
+	w.stats.Runs++
+	if runErr != nil {
+		w.stats.Failures++
+	}
+	w.stats.LastRunAt = started
+	w.mu.Unlock()
+
+	return runErr
+}
+
+// removeMedia deletes the files no message uses anymore. A file uploaded
+// again between the purge and its removal is stored again by its next upload.
+func (w *MessageExpiryWorker) removeMedia(hashes []string) {
+	if w.mediaStore == nil {
+		return
+	}
+	for _, hash := range hashes {
+		if err := w.mediaStore.Remove(hash); err != nil {
+			w.logger.Warn("Failed to remove expired media",
+				zap.String("content_hash", hash),
+				zap.Error(err))
+		}
+	}
+}
+
+// expiredConversation identifies a conversation that messages expired from
+type expiredConversation struct {
+	userID                 string
+	userIntegrationID      int32
+	externalConversationID string
+}
+
+// notify publishes one event per conversation listing its purged messages
+func (w *MessageExpiryWorker) notify(ctx context.Context, expired []repo.ExpiredMessage, expiredAt time.Time) {
+	var order []expiredConversation
+	messageIDs := make(map[expiredConversation][]string)
+	for _, message := range expired {
+		key := expiredConversation{
+			userID:                 message.UserID.String(),
+			userIntegrationID:      message.UserIntegrationID,
+			externalConversationID: message.ExternalConversationID,
+		}
+		if _, ok := messageIDs[key]; !ok {
+			order = append(order, key)
+		}
+		messageIDs[key] = append(messageIDs[key], message.ExternalMessageID)
+	}
+
+	for _, conversation := range order {
+		_, err := w.eventService.PublishMessagesExpired(ctx, conversation.userID, conversation.userIntegrationID, conversation.externalConversationID, events.MessagesExpiredPayload{
+			MessageIDs: messageIDs[conversation],
+			ExpiredAt:  expiredAt,
+		})
+		if err != nil {
+			w.logger.Warn("Failed to publish expired messages",
+				zap.String("account_id", conversation.userID),
+				zap.String("conversation_id", conversation.externalConversationID),
+				zap.Int("count", len(messageIDs[conversation])),
+				zap.Error(err))
+			continue
+		}
+
+		w.logger.Debug("Purged expired messages",
+			zap.String("account_id", conversation.userID),
+			zap.String("conversation_id", conversation.externalConversationID),
+			zap.Int("count", len(messageIDs[conversation])))
+	}
+}
+
+// Stats returns a snapshot of the worker's statistics
+func (w *MessageExpiryWorker) Stats() MessageExpiryStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
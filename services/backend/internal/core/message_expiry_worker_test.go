@@ -0,0 +1,139 @@
+package core_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/pkg/events"
+)
+
+// expiringMessageRepo hands out its expired messages a batch at a time, with
+// its orphaned media in the first batch
+type expiringMessageRepo struct {
+	repo.MessageRepository
+	expired  []repo.ExpiredMessage
+	orphaned []string
+	calls    int
+}
+
+func (r *expiringMessageRepo) DeleteExpiredMessages(ctx context.Context, params repo.DeleteExpiredMessagesParams) (repo.ExpiredMessages, error) {
+	r.calls++
+	batch := r.expired[:min(len(r.expired), int(params.BatchSize))]
+	r.expired = r.expired[len(batch):]
+	orphaned := r.orphaned
+	r.orphaned = nil
+	return repo.ExpiredMessages{Messages: batch, OrphanedMedia: orphaned}, nil
+}
+
+// recordingEventRepo keeps the events inserted into it
+type recordingEventRepo struct {
+	repo.EventRepository
+	inserted []repo.InsertEventParams
+}
+
+func (r *recordingEventRepo) InsertEvent(ctx context.Context, params repo.InsertEventParams) (repo.InsertEventResult, error) {
+	r.inserted = append(r.inserted, params)
+	return repo.InsertEventResult{Seq: int64(len(r.inserted)), Ts: time.Now()}, nil
+}
+
+func TestMessageExpiryWorkerPurgesAndNotifiesPerConversation(t *testing.T) {
+	userID := uuid.New()
+	expired := func(conversation, message string) repo.ExpiredMessage {
+		return repo.ExpiredMessage{
+			UserID:                 userID,
+			UserIntegrationID:      7,
+			ExternalConversationID: conversation,
+			ExternalMessageID:      message,
+		}
+	}
+	messageRepo := &expiringMessageRepo{expired: []repo.ExpiredMessage{
+		expired("111@s.whatsapp.net", "A1"),
+		expired("222@s.whatsapp.net", "B1"),
+		expired("111@s.whatsapp.net", "A2"),
+	}}
+	eventRepo := &recordingEventRepo{}
+	eventService := core.NewEventService(eventRepo, nil, "", false, zap.NewNop())
+	worker := core.NewMessageExpiryWorker(messageRepo, eventService, nil, core.MessageExpiryConfig{BatchSize: 2}, zap.NewNop())
+
+	if err := worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	// A full batch of 2, then the last message
+	if messageRepo.calls != 2 {
+		t.Errorf("expected 2 batches, got %d", messageRepo.calls)
+	}
+	if stats := worker.Stats(); stats.Runs != 1 || stats.Purged != 3 {
+		t.Errorf("expected 1 run purging 3 messages, got %+v", stats)
+	}
+
+	// One event per conversation per batch
+	want := []struct {
+		convoID    string
+		messageIDs []string
+	}{
+		{"111@s.whatsapp.net", []string{"A1"}},
+		{"222@s.whatsapp.net", []string{"B1"}},
+		{"111@s.whatsapp.net", []string{"A2"}},
+	}
+	if len(eventRepo.inserted) != len(want) {
+		t.Fatalf("expected %d events, got %d", len(want), len(eventRepo.inserted))
+	}
+	for i, event := range eventRepo.inserted {
+		if event.Type != events.TypeMessagesExpired || event.AccountID != userID.String() || event.ConvoID != want[i].convoID {
+			t.Errorf("event %d: unexpected %s event for %s/%s", i, event.Type, event.AccountID, event.ConvoID)
+		}
+		if !event.UserIntegrationID.Valid || event.UserIntegrationID.Int32 != 7 {
+			t.Errorf("event %d: expected integration 7, got %v", i, event.UserIntegrationID)
+		}
+		var payload events.MessagesExpiredPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			t.Fatalf("failed to decode payload: %v", err)
+		}
+		if len(payload.MessageIDs) != len(want[i].messageIDs) || payload.MessageIDs[0] != want[i].messageIDs[0] {
+			t.Errorf("event %d: expected messages %v, got %v", i, want[i].messageIDs, payload.MessageIDs)
+		}
+	}
+}
+
+func TestMessageExpiryWorkerRemovesOrphanedMedia(t *testing.T) {
+	mediaStore := core.NewMediaStore(t.TempDir())
+	orphaned, _, _, err := mediaStore.Add(strings.NewReader("expired photo"))
+	if err != nil {
+		t.Fatalf("failed to store media: %v", err)
+	}
+	shared, _, _, err := mediaStore.Add(strings.NewReader("forwarded photo"))
+	if err != nil {
+		t.Fatalf("failed to store media: %v", err)
+	}
+
+	// The repository also reports a file that was never downloaded
+	messageRepo := &expiringMessageRepo{
+		expired: []repo.ExpiredMessage{{
+			UserID:                 uuid.New(),
+			ExternalConversationID: "111@s.whatsapp.net",
+			ExternalMessageID:      "A1",
+		}},
+		orphaned: []string{orphaned, strings.Repeat("0", 64)},
+	}
+	eventService := core.NewEventService(&recordingEventRepo{}, nil, "", false, zap.NewNop())
+	worker := core.NewMessageExpiryWorker(messageRepo, eventService, mediaStore, core.MessageExpiryConfig{}, zap.NewNop())
+
+	if err := worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if mediaStore.Has(orphaned) {
+		t.Error("expected the orphaned media file to be removed")
+	}
+	if !mediaStore.Has(shared) {
+		t.Error("expected media still in use to be kept")
+	}
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/repo"
+)
+
+// MuteExpiryConfig controls how the mute expiry worker lifts expired mutes
+type MuteExpiryConfig struct {
+	// How often to look for expired mutes
+	Interval time.Duration
+
+	// Conversations unmuted per statement
+	BatchSize int32
+}
+
+// MuteExpiryStats summarizes the mute expiry worker's progress since startup
+type MuteExpiryStats struct {
+	Runs      int64
+	Failures  int64
+	Unmuted   int64
+	LastRunAt time.Time
+}
+
+// MuteExpiryWorker unmutes conversations once their mute expires and tells
+// clients about it, like the phone does
+type MuteExpiryWorker struct {
+	messageRepo  repo.MessageRepository
+	eventService *EventService
+	config       MuteExpiryConfig
+	logger       *zap.Logger
+	now          func() time.Time
+
+	mu    sync.Mutex
+	stats MuteExpiryStats
+}
+
+// NewMuteExpiryWorker creates a new mute expiry worker
+func NewMuteExpiryWorker(messageRepo repo.MessageRepository, eventService *EventService, config MuteExpiryConfig, logger *zap.Logger) *MuteExpiryWorker {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+
+	return &MuteExpiryWorker{
+		messageRepo:  messageRepo,
+		eventService: eventService,
+		config:       config,
+		logger:       logger.Named("mute_expiry_worker"),
+		now:          time.Now,
+	}
+}
+
+// Start lifts expired mutes until ctx is done
+func (w *MuteExpiryWorker) Start(ctx context.Context) {
+	w.logger.Info("Starting mute expiry worker",
+		zap.Duration("interval", w.config.Interval),
+		zap.Int32("batch_size", w.config.BatchSize))
+	defer w.logger.Info("Mute expiry worker stopped")
+
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("Mute expiry pass failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce lifts every mute that has expired, a batch at a time
+func (w *MuteExpiryWorker) RunOnce(ctx context.Context) error {
+	started := w.now()
+
+	var runErr error
+	for {
+		expired, err := w.messageRepo.ClearExpiredMutes(ctx, repo.ClearExpiredMutesParams{
+			Now:       started,
+			BatchSize: w.config.BatchSize,
+		})
+		if err != nil {
+			runErr = err
+			break
+		}
+
+		w.mu.Lock()
+		w.stats.Unmuted += int64(len(expired))
+		w.mu.Unlock()
+
+		// The mutes are already lifted, so a failed notification is logged
+		// rather than retried
+		w.notify(ctx, expired)
+
+		if len(expired) < int(w.config.BatchSize) {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			runErr = err
+			break
+		}
+	}
+
+	w.mu.Lock()
+	w.stats.Runs++
+	if runErr != nil {
+		w.stats.Failures++
+	}
+	w.stats.LastRunAt = started
+	w.mu.Unlock()
+
+	return runErr
+}
+
+// notify publishes a conversation state change for each unmuted conversation
+func (w *MuteExpiryWorker) notify(ctx context.Context, expired []repo.ExpiredMute) {
+	for _, mute := range expired {
+		change, _ := ConversationStateChange(
+			ConversationState{IsMuted: true, MuteUntil: mute.MuteUntil},
+			ConversationState{},
+		)
+		_, err := w.eventService.PublishConversationState(ctx, mute.UserID.String(), mute.UserIntegrationID, mute.ExternalConversationID, change)
+		if err != nil {
+			w.logger.Warn("Failed to publish expired mute",
+				zap.String("account_id", mute.UserID.String()),
+				zap.String("conversation_id", mute.ExternalConversationID),
+				zap.Error(err))
+			continue
+		}
+
+		w.logger.Debug("Lifted expired mute",
+			zap.String("account_id", mute.UserID.String()),
+			zap.String("conversation_id", mute.ExternalConversationID),
+			zap.Time("mute_until", mute.MuteUntil))
+	}
+}
+
+// Stats returns a snapshot of the worker's statistics
+func (w *MuteExpiryWorker) Stats() MuteExpiryStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
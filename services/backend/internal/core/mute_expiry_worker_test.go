@@ -0,0 +1,74 @@
+package core_test
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/pkg/events"
+)
+
+// expiringMuteRepo hands out its expired mutes a batch at a time
+type expiringMuteRepo struct {
+	repo.MessageRepository
+	expired []repo.ExpiredMute
+	calls   int
+}
+
+func (r *expiringMuteRepo) ClearExpiredMutes(ctx context.Context, params repo.ClearExpiredMutesParams) ([]repo.ExpiredMute, error) {
+	r.calls++
+	batch := r.expired[:min(len(r.expired), int(params.BatchSize))]
+	r.expired = r.expired[len(batch):]
+	return batch, nil
+}
+
+func TestMuteExpiryWorkerUnmutesAndNotifies(t *testing.T) {
+	userID := uuid.New()
+	muteUntil := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	messageRepo := &expiringMuteRepo{expired: []repo.ExpiredMute{
+		{UserID: userID, UserIntegrationID: 7, ExternalConversationID: "111@s.whatsapp.net", MuteUntil: muteUntil},
+		{UserID: userID, UserIntegrationID: 7, ExternalConversationID: "222@s.whatsapp.net", MuteUntil: muteUntil},
+		{UserID: userID, UserIntegrationID: 7, ExternalConversationID: "333@s.whatsapp.net", MuteUntil: muteUntil},
+	}}
+	eventRepo := &recordingEventRepo{}
+	eventService := core.NewEventService(eventRepo, nil, "", false, zap.NewNop())
+	worker := core.NewMuteExpiryWorker(messageRepo, eventService, core.MuteExpiryConfig{BatchSize: 2}, zap.NewNop())
+
+	if err := worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	// A full batch of 2, then the last mute
+	if messageRepo.calls != 2 {
+		t.Errorf("expected 2 batches, got %d", messageRepo.calls)
+	}
+	if stats := worker.Stats(); stats.Runs != 1 || stats.Unmuted != 3 {
+		t.Errorf("expected 1 run unmuting 3 conversations, got %+v", stats)
+	}
+
+	// One unmute per conversation, as if the user had unmuted it
+	if len(eventRepo.inserted) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(eventRepo.inserted))
+	}
+	convoIDs := []string{"111@s.whatsapp.net", "222@s.whatsapp.net", "333@s.whatsapp.net"}
+	for i, event := range eventRepo.inserted {
+		if event.Type != events.TypeConversationState || event.AccountID != userID.String() || event.ConvoID != convoIDs[i] {
+			t.Errorf("event %d: unexpected %s event for %s/%s", i, event.Type, event.AccountID, event.ConvoID)
+		}
+		var payload events.ConversationStatePayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			t.Fatalf("failed to decode payload: %v", err)
+		}
+		want := []string{events.ConversationFieldMuted, events.ConversationFieldMuteUntil}
+		if !slices.Equal(payload.Changed, want) || payload.IsMuted == nil || *payload.IsMuted || payload.MuteUntil != nil {
+			t.Errorf("event %d: expected an unmute clearing mute_until, got %+v", i, payload)
+		}
+	}
+}
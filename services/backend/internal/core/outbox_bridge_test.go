@@ -0,0 +1,391 @@
+package core_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/grpc/client"
+	"github.com/tennex/backend/internal/http/handlers"
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/backend/internal/testutil"
+	"github.com/tennex/bridge/control"
+	"github.com/tennex/bridge/outbox"
+	"github.com/tennex/bridge/whatsapp"
+	dbgen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/events"
+	"github.com/tennex/shared/auth"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+type sentText struct {
+	toJID, text, replyTo, clientMsgUUID string
+}
+
+// fakeSession stands in for a whatsmeow client and records outgoing messages,
+// conversation state changes and presence subscriptions
+type fakeSession struct {
+	sent         chan sentText
+	stateChanges chan stateChange
+	groups       []whatsapp.Group
+	presence     *whatsapp.PresenceSubscriptions
+}
+
+type stateChange struct {
+	chatJID string
+	change  whatsapp.ConversationStateChange
+}
+
+func (s *fakeSession) SendText(ctx context.Context, toJID, text, replyToMessageID, clientMsgUUID string) (string, error) {
+	s.sent <- sentText{toJID: toJID, text: text, replyTo: replyToMessageID, clientMsgUUID: clientMsgUUID}
+	return "WA-MSG-1", nil
+}
+
+func (s *fakeSession) MarkRead(ctx context.Context, chatJID, senderJID string, messageIDs []string) error {
+	return nil
+}
+
+func (s *fakeSession) Logout(ctx context.Context) error {
+	return nil
+}
+
+func (s *fakeSession) Resync(ctx context.Context, fullSync bool) error {
+	return nil
+}
+
+func (s *fakeSession) JoinedGroups(ctx context.Context) ([]whatsapp.Group, error) {
+	return s.groups, nil
+}
+
+func (s *fakeSession) LoadOlderHistory(ctx context.Context, conversationJID string, count int) error {
+	return nil
+}
+
+func (s *fakeSession) SetConversationState(ctx context.Context, chatJID string, change whatsapp.ConversationStateChange) error {
+	s.stateChanges <- stateChange{chatJID: chatJID, change: change}
+	return nil
+}
+
+func (s *fakeSession) SubscribePresence(ctx context.Context, jids []string) ([]string, error) {
+	if _, err := s.presence.Add(jids); err != nil {
+		return nil, err
+	}
+	return s.presence.List(), nil
+}
+
+func (s *fakeSession) UnsubscribePresence(ctx context.Context, jids []string) ([]string, error) {
+	s.presence.Remove(jids)
+	return s.presence.List(), nil
+}
+
+// startBridge serves the bridge control service on a local port
+func startBridge(t *testing.T, sessions *whatsapp.SessionRegistry, token string) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(auth.TokenUnaryServerInterceptor(token)))
+	proto.RegisterBridgeControlServiceServer(grpcServer, control.NewServer(sessions, slog.Default()))
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	return listener.Addr().String()
+}
+
+// newAccountUser creates a user whose account ID is their user ID, maps any
+// legacy account IDs to them, and returns the account ID and an API token
+func newAccountUser(t *testing.T, pool *pgxpool.Pool, legacyAccountIDs ...string) (string, string) {
+	t.Helper()
+	ctx := context.Background()
+
+	name := "account-" + uuid.NewString()[:8]
+	var userID uuid.UUID
+	err := pool.QueryRow(ctx, `
+		INSERT INTO users (username, email, password_hash)
+		VALUES ($1, $1 || '@example.com', 'x')
+		RETURNING id`, name).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	for _, accountID := range legacyAccountIDs {
+		if _, err := pool.Exec(ctx, `INSERT INTO event_accounts (account_id, user_id) VALUES ($1, $2)`, accountID, userID); err != nil {
+			t.Fatalf("failed to map legacy account: %v", err)
+		}
+	}
+
+	token, _, err := auth.DefaultJWTConfig("test-secret").GenerateToken(userID, auth.RoleUser)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	return userID.String(), token
+}
+
+func TestOutboxMessageIsSentThroughBridge(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	logger := zap.NewNop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const token = "test-bridge-token"
+	accountID, apiToken := newAccountUser(t, pool)
+	session := &fakeSession{sent: make(chan sentText, 1)}
+	sessions := whatsapp.NewSessionRegistry()
+	sessions.Register(accountID, session)
+	bridgeAddr := startBridge(t, sessions, token)
+
+	bridgeClient, err := client.NewBridgeClient(bridgeAddr, token, logger)
+	if err != nil {
+		t.Fatalf("failed to create bridge client: %v", err)
+	}
+	defer bridgeClient.Close()
+
+	eventRepo := repo.NewEventRepository(pool)
+	eventService := core.NewEventService(eventRepo, nil, "", false, logger)
+	outboxService := core.NewOutboxService(repo.NewOutboxRepository(pool), nil, logger)
+	integrationService := core.NewIntegrationService(repo.NewIntegrationRepository(pool), logger)
+	accountService := core.NewAccountService(repo.NewAccountRepository(pool), logger)
+	apiHandler := handlers.NewAPIHandler(eventService, outboxService, accountService, integrationService, bridgeClient, dbgen.New(pool), "test-secret", logger)
+
+	clientMsgUUID := uuid.New()
+	body, _ := json.Marshal(map[string]interface{}{
+		"client_msg_uuid": clientMsgUUID.String(),
+		"account_id":      accountID,
+		"convo_id":        "123456789@s.whatsapp.net",
+		"message_type":    "text",
+		"content":         map[string]interface{}{"text": "hello from the backend"},
+		"reply_to":        "PARENT-WA-ID",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/outbox", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+apiToken)
+	rec := httptest.NewRecorder()
+	apiHandler.Routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 from /outbox, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	go core.NewOutboxWorker(outboxService, bridgeClient, nil, core.OutboxWorkerConfig{}, logger).Start(ctx)
+
+	select {
+	case sent := <-session.sent:
+		if sent.toJID != "123456789@s.whatsapp.net" {
+			t.Errorf("expected message to 123456789@s.whatsapp.net, got %s", sent.toJID)
+		}
+		if sent.text != "hello from the backend" {
+			t.Errorf("expected text %q, got %q", "hello from the backend", sent.text)
+		}
+		if sent.replyTo != "PARENT-WA-ID" {
+			t.Errorf("expected reply to PARENT-WA-ID, got %q", sent.replyTo)
+		}
+		if sent.clientMsgUUID != clientMsgUUID.String() {
+			t.Errorf("expected client_msg_uuid %s, got %q", clientMsgUUID, sent.clientMsgUUID)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("timed out waiting for SendMessage to reach the bridge")
+	}
+
+	// The worker marks the entry sent once the bridge acknowledges it
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		entry, err := outboxService.GetEntry(ctx, clientMsgUUID)
+		if err != nil {
+			t.Fatalf("failed to get outbox entry: %v", err)
+		}
+		if entry.Status == events.OutboxStatusSent {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected outbox status %q, got %q", events.OutboxStatusSent, entry.Status)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestOutboxMessageIsSentThroughNATSQueue(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	nc := testutil.SetupTestNATS(t)
+	logger := zap.NewNop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("failed to get JetStream context: %v", err)
+	}
+	prefix := "test.outbox." + uuid.NewString()[:8]
+	t.Cleanup(func() {
+		js.DeleteStream(events.OutboxStreamName(prefix))
+		js.DeleteKeyValue(events.OutboxSentBucket(prefix))
+	})
+
+	accountID, apiToken := newAccountUser(t, pool)
+	session := &fakeSession{sent: make(chan sentText, 1)}
+	sessions := whatsapp.NewSessionRegistry()
+	sessions.Register(accountID, session)
+
+	eventRepo := repo.NewEventRepository(pool)
+	eventService := core.NewEventService(eventRepo, nil, "", false, logger)
+	outboxService := core.NewOutboxService(repo.NewOutboxRepository(pool), nil, logger)
+	integrationService := core.NewIntegrationService(repo.NewIntegrationRepository(pool), logger)
+	accountService := core.NewAccountService(repo.NewAccountRepository(pool), logger)
+	apiHandler := handlers.NewAPIHandler(eventService, outboxService, accountService, integrationService, nil, dbgen.New(pool), "test-secret", logger)
+
+	queueMessage := func(accountID, apiToken, text string) uuid.UUID {
+		t.Helper()
+		clientMsgUUID := uuid.New()
+		body, _ := json.Marshal(map[string]interface{}{
+			"client_msg_uuid": clientMsgUUID.String(),
+			"account_id":      accountID,
+			"convo_id":        "123456789@s.whatsapp.net",
+			"message_type":    "text",
+			"content":         map[string]interface{}{"text": text},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/outbox", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+apiToken)
+		rec := httptest.NewRecorder()
+		apiHandler.Routes().ServeHTTP(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201 from /outbox, got %d: %s", rec.Code, rec.Body.String())
+		}
+		return clientMsgUUID
+	}
+	delivered := queueMessage(accountID, apiToken, "hello through the queue")
+	// No bridge holds a session for this account
+	orphanedAccountID, orphanedToken := newAccountUser(t, pool)
+	orphaned := queueMessage(orphanedAccountID, orphanedToken, "nobody can send this")
+
+	queue := core.NewOutboxQueue(js, prefix, logger)
+	if err := queue.EnsureStream(); err != nil {
+		t.Fatalf("EnsureStream: %v", err)
+	}
+	worker := core.NewQueuedOutboxWorker(outboxService, queue, nil, core.OutboxWorkerConfig{PollInterval: 100 * time.Millisecond}, logger)
+	go worker.Start(ctx)
+	go queue.ConsumeResults(ctx, worker.ApplySendResult)
+	go outbox.NewConsumer(js, control.NewServer(sessions, slog.Default()), outbox.Config{
+		Prefix:          prefix,
+		IntegrationType: core.IntegrationTypeWhatsApp,
+		RedeliverDelay:  100 * time.Millisecond,
+		MaxDeliver:      3,
+	}).Run(ctx)
+
+	select {
+	case sent := <-session.sent:
+		if sent.text != "hello through the queue" {
+			t.Errorf("expected text %q, got %q", "hello through the queue", sent.text)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("timed out waiting for the queued message to reach the session")
+	}
+
+	// Each entry is resolved by the result its bridge reported
+	for id, want := range map[uuid.UUID]string{delivered: events.OutboxStatusSent, orphaned: events.OutboxStatusFailed} {
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			entry, err := outboxService.GetEntry(ctx, id)
+			if err != nil {
+				t.Fatalf("failed to get outbox entry: %v", err)
+			}
+			if entry.Status == want {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("entry %s: expected outbox status %q, got %q", id, want, entry.Status)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+}
+
+func TestBridgeRejectsCallsWithoutServiceToken(t *testing.T) {
+	sessions := whatsapp.NewSessionRegistry()
+	bridgeAddr := startBridge(t, sessions, "expected-token")
+
+	bridgeClient, err := client.NewBridgeClient(bridgeAddr, "wrong-token", zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to create bridge client: %v", err)
+	}
+	defer bridgeClient.Close()
+
+	err = bridgeClient.Logout(context.Background(), uuid.New().String())
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated for the wrong token, got %v", err)
+	}
+}
+
+func TestWhatsAppGroupsAreListedThroughBridge(t *testing.T) {
+	const token = "test-bridge-token"
+	userID := uuid.New()
+	createdAt := time.Unix(1700000000, 0).UTC()
+	session := &fakeSession{groups: []whatsapp.Group{{
+		JID:              "120363000000000000@g.us",
+		Name:             "Family",
+		OwnerJID:         "123456789@s.whatsapp.net",
+		ParticipantCount: 2000,
+		IsAnnounce:       true,
+		CreatedAt:        createdAt,
+	}}}
+	sessions := whatsapp.NewSessionRegistry()
+	sessions.Register(userID.String(), session)
+	bridgeAddr := startBridge(t, sessions, token)
+
+	bridgeClient, err := client.NewBridgeClient(bridgeAddr, token, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to create bridge client: %v", err)
+	}
+	defer bridgeClient.Close()
+
+	const jwtSecret = "test-secret"
+	userToken, _, err := auth.DefaultJWTConfig(jwtSecret).GenerateToken(userID, auth.RoleUser)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	apiHandler := handlers.NewAPIHandler(nil, nil, nil, nil, bridgeClient, nil, jwtSecret, zap.NewNop())
+	req := httptest.NewRequest(http.MethodGet, "/integrations/whatsapp/groups", nil)
+	req.Header.Set("Authorization", "Bearer "+userToken)
+	rec := httptest.NewRecorder()
+	apiHandler.Routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 from /integrations/whatsapp/groups, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response struct {
+		Groups []struct {
+			JID              string    `json:"jid"`
+			Name             string    `json:"name"`
+			OwnerJID         string    `json:"owner_jid"`
+			ParticipantCount int       `json:"participant_count"`
+			IsAnnounce       bool      `json:"is_announce"`
+			CreatedAt        time.Time `json:"created_at"`
+		} `json:"groups"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Groups) != 1 {
+		t.Fatalf("expected 1 group, got %d", len(response.Groups))
+	}
+	group := response.Groups[0]
+	if group.JID != "120363000000000000@g.us" || group.Name != "Family" || group.OwnerJID != "123456789@s.whatsapp.net" {
+		t.Errorf("unexpected group identity: %+v", group)
+	}
+	if group.ParticipantCount != 2000 || !group.IsAnnounce || !group.CreatedAt.Equal(createdAt) {
+		t.Errorf("unexpected group details: %+v", group)
+	}
+}
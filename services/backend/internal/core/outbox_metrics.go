@@ -0,0 +1,123 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tennex/pkg/events"
+)
+
+// Outbox failure classes reported in OutboxStats.FailuresByClass. Bridge
+// call failures are classed by gRPC code instead, e.g. "bridge_unavailable".
+const (
+	OutboxFailureStore          = "store"           // Reading or updating the outbox table
+	OutboxFailureInvalidMessage = "invalid_message" // Payload can't be turned into a bridge request
+	OutboxFailureBridgeRejected = "bridge_rejected" // Bridge answered but couldn't send
+	OutboxFailureQueue          = "queue"           // Publishing to the NATS outbox queue
+)
+
+// sendLatencyBuckets are the upper bounds, in seconds, of the queued-to-sent
+// histogram
+var sendLatencyBuckets = []float64{0.5, 1, 2.5, 5, 10, 30, 60, 300, 900, 3600}
+
+// LatencyHistogram is a cumulative histogram in the Prometheus layout:
+// Counts[i] is the number of observations <= Bounds[i]
+type LatencyHistogram struct {
+	Bounds []float64
+	Counts []uint64
+	Count  uint64
+	Sum    float64 // Seconds
+}
+
+func newLatencyHistogram(bounds []float64) LatencyHistogram {
+	return LatencyHistogram{Bounds: bounds, Counts: make([]uint64, len(bounds))}
+}
+
+func (h *LatencyHistogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+	for i, bound := range h.Bounds {
+		if seconds <= bound {
+			h.Counts[i]++
+		}
+	}
+	h.Count++
+	h.Sum += seconds
+}
+
+func (h LatencyHistogram) clone() LatencyHistogram {
+	h.Counts = append([]uint64(nil), h.Counts...)
+	return h
+}
+
+// OutboxStats summarizes the outbox worker's view of the queue
+type OutboxStats struct {
+	// Entries per status as of LastCheckAt; every status is present
+	ByStatus map[string]int64
+	// Unsent entries older than the stuck threshold as of LastCheckAt
+	Stuck int64
+	// Stuck-outbox incidents alerted since startup
+	StuckAlerts int64
+	LastCheckAt time.Time
+
+	// Time from queueing to the bridge confirming the send
+	SendLatency LatencyHistogram
+	// Failed sends per failure class since startup
+	FailuresByClass map[string]int64
+}
+
+var outboxStatuses = []string{
+	events.OutboxStatusQueued,
+	events.OutboxStatusSending,
+	events.OutboxStatusSent,
+	events.OutboxStatusFailed,
+	events.OutboxStatusRetry,
+}
+
+// outboxError tags a failed send with its failure class
+type outboxError struct {
+	class string
+	err   error
+}
+
+func (e *outboxError) Error() string { return e.err.Error() }
+func (e *outboxError) Unwrap() error { return e.err }
+
+func classifyOutboxError(class string, err error) error {
+	return &outboxError{class: class, err: err}
+}
+
+// outboxFailureClass returns the failure class of an error from processEntry
+func outboxFailureClass(err error) string {
+	var classified *outboxError
+	if errors.As(err, &classified) {
+		return classified.class
+	}
+
+	// Bridge client errors carry the gRPC status of the failed call
+	switch code := status.Code(err); code {
+	case codes.Unknown:
+		return OutboxFailureBridgeRejected
+	default:
+		return "bridge_" + snakeCase(code.String())
+	}
+}
+
+// snakeCase converts a gRPC code name like "DeadlineExceeded" to "deadline_exceeded"
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
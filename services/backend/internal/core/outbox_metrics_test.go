@@ -0,0 +1,157 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/pkg/events"
+)
+
+// stuckOutboxRepo reports entries as stuck when they were last updated
+// before the cutoff
+type stuckOutboxRepo struct {
+	repo.OutboxRepository
+	updatedAt []time.Time
+}
+
+func (r *stuckOutboxRepo) CountOutboxByStatus(ctx context.Context) (map[string]int64, error) {
+	return map[string]int64{events.OutboxStatusQueued: int64(len(r.updatedAt))}, nil
+}
+
+func (r *stuckOutboxRepo) GetStuckOutboxSummary(ctx context.Context, cutoff time.Time) (repo.OutboxStuckSummary, error) {
+	var summary repo.OutboxStuckSummary
+	for _, updatedAt := range r.updatedAt {
+		if !updatedAt.Before(cutoff) {
+			continue
+		}
+		summary.Count++
+		if !summary.Oldest.Valid || updatedAt.Before(summary.Oldest.Time) {
+			summary.Oldest = sql.NullTime{Time: updatedAt, Valid: true}
+		}
+	}
+	return summary, nil
+}
+
+type publishedAlert struct {
+	subject string
+	data    []byte
+}
+
+type fakeAlertPublisher struct {
+	published []publishedAlert
+}
+
+func (p *fakeAlertPublisher) Publish(subject string, data []byte) error {
+	p.published = append(p.published, publishedAlert{subject: subject, data: data})
+	return nil
+}
+
+func TestOutboxWorkerAlertsOncePerStuckIncident(t *testing.T) {
+	outboxRepo := &stuckOutboxRepo{}
+	alerts := &fakeAlertPublisher{}
+	worker := NewOutboxWorker(NewOutboxService(outboxRepo, nil, zap.NewNop()), nil, alerts, OutboxWorkerConfig{
+		StuckThreshold:     10 * time.Minute,
+		AlertSubjectPrefix: "tennex.test",
+	}, zap.NewNop())
+	clock := time.Unix(1700000000, 0)
+	worker.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	check := func(wantAlerts int) {
+		t.Helper()
+		if err := worker.CheckOutbox(ctx); err != nil {
+			t.Fatalf("CheckOutbox: %v", err)
+		}
+		if len(alerts.published) != wantAlerts {
+			t.Fatalf("expected %d alerts, got %d", wantAlerts, len(alerts.published))
+		}
+	}
+
+	// A recently queued entry isn't stuck
+	outboxRepo.updatedAt = []time.Time{clock.Add(-time.Minute)}
+	check(0)
+
+	// It becomes stuck once it passes the threshold, and stays one incident
+	clock = clock.Add(10 * time.Minute)
+	check(1)
+	clock = clock.Add(time.Minute)
+	check(1)
+	outboxRepo.updatedAt = append(outboxRepo.updatedAt, clock.Add(-time.Hour))
+	check(1)
+
+	alert := alerts.published[0]
+	if alert.subject != "tennex.test.ops.alert" {
+		t.Errorf("expected subject tennex.test.ops.alert, got %s", alert.subject)
+	}
+	var payload outboxStuckAlert
+	if err := json.Unmarshal(alert.data, &payload); err != nil {
+		t.Fatalf("failed to decode alert: %v", err)
+	}
+	if payload.Alert != "outbox_stuck" || payload.StuckCount != 1 || payload.ThresholdSeconds != 600 {
+		t.Errorf("unexpected alert payload: %+v", payload)
+	}
+
+	stats := worker.Stats()
+	if stats.Stuck != 2 || stats.StuckAlerts != 1 {
+		t.Errorf("expected 2 stuck entries and 1 alert, got %d and %d", stats.Stuck, stats.StuckAlerts)
+	}
+	if got, ok := stats.ByStatus[events.OutboxStatusSent]; !ok || got != 0 {
+		t.Errorf("expected sent entries reported with 0, got %d (present=%v)", got, ok)
+	}
+
+	// Draining the outbox ends the incident; the next stuck entry is a new one
+	outboxRepo.updatedAt = nil
+	check(1)
+	outboxRepo.updatedAt = []time.Time{clock.Add(-time.Hour)}
+	check(2)
+
+	if got := worker.Stats().StuckAlerts; got != 2 {
+		t.Errorf("expected 2 alerts in stats, got %d", got)
+	}
+}
+
+func TestOutboxFailureClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{classifyOutboxError(OutboxFailureStore, errors.New("connection refused")), OutboxFailureStore},
+		{classifyOutboxError(OutboxFailureInvalidMessage, errors.New("no text")), OutboxFailureInvalidMessage},
+		{fmt.Errorf("failed to call bridge SendMessage: %w", status.Error(codes.DeadlineExceeded, "timeout")), "bridge_deadline_exceeded"},
+		{fmt.Errorf("failed to call bridge SendMessage: %w", status.Error(codes.Unavailable, "down")), "bridge_unavailable"},
+		{errors.New("bridge failed to send message: not connected"), OutboxFailureBridgeRejected},
+	}
+
+	for _, tt := range tests {
+		if got := outboxFailureClass(tt.err); got != tt.want {
+			t.Errorf("outboxFailureClass(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestLatencyHistogramIsCumulative(t *testing.T) {
+	h := newLatencyHistogram([]float64{1, 5, 10})
+	h.observe(500 * time.Millisecond)
+	h.observe(3 * time.Second)
+	h.observe(time.Minute)
+
+	want := []uint64{1, 2, 2}
+	for i, count := range h.Counts {
+		if count != want[i] {
+			t.Errorf("bucket le=%g: got %d, want %d", h.Bounds[i], count, want[i])
+		}
+	}
+	if h.Count != 3 || h.Sum != 63.5 {
+		t.Errorf("expected count 3 and sum 63.5, got %d and %g", h.Count, h.Sum)
+	}
+}
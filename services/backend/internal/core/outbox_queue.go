@@ -0,0 +1,210 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/tennex/pkg/events"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+// Outbox transports, chosen by the outbox.transport config option
+const (
+	OutboxTransportGRPC = "grpc" // Call the configured bridge directly
+	OutboxTransportNATS = "nats" // Publish to the JetStream outbox work queue
+)
+
+const (
+	// Republishing an entry within this window is ignored by the stream
+	outboxQueueDuplicateWindow = 10 * time.Minute
+
+	// Unconsumed messages are dropped after this long; their entries are
+	// then reported stuck
+	outboxQueueMaxAge = 24 * time.Hour
+
+	// How long to wait for the stream to store a published message
+	outboxQueuePublishTimeout = 10 * time.Second
+
+	// Durable consumer the backend reads send results with
+	outboxResultConsumer = "backend-results"
+
+	// Results applied per fetch, and how long a fetch waits for them
+	outboxResultBatch     = 50
+	outboxResultFetchWait = 5 * time.Second
+
+	// A result that fails to apply is retried after outboxResultRetryDelay,
+	// and moved to the dead-letter subject after outboxResultMaxDeliver
+	// deliveries
+	outboxResultRetryDelay = 5 * time.Second
+	outboxResultMaxDeliver = 10
+)
+
+// OutboxQueue is the backend's side of the NATS outbox work queue: it
+// publishes outbound messages for bridges and reads back their results
+type OutboxQueue struct {
+	js     nats.JetStreamContext
+	prefix string
+	logger *zap.Logger
+}
+
+// NewOutboxQueue creates an outbox queue using subjects under prefix
+func NewOutboxQueue(js nats.JetStreamContext, prefix string, logger *zap.Logger) *OutboxQueue {
+	return &OutboxQueue{
+		js:     js,
+		prefix: prefix,
+		logger: logger.Named("outbox_queue"),
+	}
+}
+
+// EnsureStream creates the outbox stream, or updates it to the current
+// configuration, and the durable consumer results are read with
+func (q *OutboxQueue) EnsureStream() error {
+	streamConfig := &nats.StreamConfig{
+		Name:       events.OutboxStreamName(q.prefix),
+		Subjects:   events.OutboxStreamSubjects(q.prefix),
+		Retention:  nats.WorkQueuePolicy,
+		Storage:    nats.FileStorage,
+		MaxAge:     outboxQueueMaxAge,
+		Duplicates: outboxQueueDuplicateWindow,
+	}
+
+	_, err := q.js.StreamInfo(streamConfig.Name)
+	switch {
+	case errors.Is(err, nats.ErrStreamNotFound):
+		_, err = q.js.AddStream(streamConfig)
+	case err == nil:
+		_, err = q.js.UpdateStream(streamConfig)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set up outbox stream %s: %w", streamConfig.Name, err)
+	}
+
+	consumerConfig := &nats.ConsumerConfig{
+		Durable:       outboxResultConsumer,
+		FilterSubject: events.OutboxResultSubject(q.prefix),
+		AckPolicy:     nats.AckExplicitPolicy,
+		AckWait:       30 * time.Second,
+		MaxDeliver:    outboxResultMaxDeliver,
+	}
+	_, err = q.js.AddConsumer(streamConfig.Name, consumerConfig)
+	if errors.Is(err, nats.ErrConsumerNameAlreadyInUse) {
+		// Created by an earlier version with another configuration
+		_, err = q.js.UpdateConsumer(streamConfig.Name, consumerConfig)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set up outbox results consumer: %w", err)
+	}
+
+	q.logger.Info("Outbox stream ready",
+		zap.String("stream", streamConfig.Name),
+		zap.Strings("subjects", streamConfig.Subjects))
+	return nil
+}
+
+// QueueMessage publishes req for the bridges of integrationType. It returns
+// once the stream has stored the message; the send result arrives later on
+// the results subject. Publishing the same entry twice within the duplicate
+// window queues it once.
+func (q *OutboxQueue) QueueMessage(ctx context.Context, integrationType string, req *proto.SendMessageRequest) error {
+	data, err := protobuf.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal send request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, outboxQueuePublishTimeout)
+	defer cancel()
+
+	msg := nats.NewMsg(events.OutboxSendSubject(q.prefix, integrationType))
+	msg.Data = data
+	if _, err := q.js.PublishMsg(msg, nats.MsgId(req.ClientMsgUuid), nats.Context(ctx)); err != nil {
+		return fmt.Errorf("failed to publish to outbox queue: %w", err)
+	}
+	return nil
+}
+
+// ConsumeResults passes each send result reported by bridges to handle until
+// ctx is done. Results handle fails on are redelivered, and moved to the
+// dead-letter subject once they have failed outboxResultMaxDeliver times.
+func (q *OutboxQueue) ConsumeResults(ctx context.Context, handle func(context.Context, *proto.SendMessageResult) error) error {
+	sub, err := q.js.PullSubscribe(events.OutboxResultSubject(q.prefix), outboxResultConsumer,
+		nats.Bind(events.OutboxStreamName(q.prefix), outboxResultConsumer))
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to outbox results: %w", err)
+	}
+
+	for ctx.Err() == nil {
+		fetchCtx, cancel := context.WithTimeout(ctx, outboxResultFetchWait)
+		msgs, err := sub.Fetch(outboxResultBatch, nats.Context(fetchCtx))
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, nats.ErrTimeout) {
+				q.logger.Warn("Failed to fetch outbox results", zap.Error(err))
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		for _, msg := range msgs {
+			q.applyResult(ctx, msg, handle)
+		}
+	}
+	return nil
+}
+
+func (q *OutboxQueue) applyResult(ctx context.Context, msg *nats.Msg, handle func(context.Context, *proto.SendMessageResult) error) {
+	var result proto.SendMessageResult
+	if err := protobuf.Unmarshal(msg.Data, &result); err != nil {
+		q.logger.Error("Dropping malformed outbox result", zap.Error(err))
+		msg.Term()
+		return
+	}
+
+	if err := handle(ctx, &result); err != nil {
+		q.logger.Error("Failed to apply outbox result",
+			zap.String("client_msg_uuid", result.ClientMsgUuid),
+			zap.Error(err))
+		if q.lastDelivery(msg) {
+			q.deadLetter(ctx, msg, result.ClientMsgUuid)
+			return
+		}
+		msg.NakWithDelay(outboxResultRetryDelay)
+		return
+	}
+	msg.Ack()
+}
+
+// lastDelivery reports whether msg won't be delivered again if it isn't acked
+func (q *OutboxQueue) lastDelivery(msg *nats.Msg) bool {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return true
+	}
+	return meta.NumDelivered >= outboxResultMaxDeliver
+}
+
+// deadLetter moves a result that can't be applied to the dead-letter subject,
+// where it's kept for inspection until the stream's max age. The entry it
+// belongs to is reported stuck.
+func (q *OutboxQueue) deadLetter(ctx context.Context, msg *nats.Msg, clientMsgUUID string) {
+	ctx, cancel := context.WithTimeout(ctx, outboxQueuePublishTimeout)
+	defer cancel()
+
+	dead := nats.NewMsg(events.OutboxDeadLetterSubject(q.prefix))
+	dead.Data = msg.Data
+	if _, err := q.js.PublishMsg(dead, nats.MsgId("dead-"+clientMsgUUID), nats.Context(ctx)); err != nil {
+		// Leave the result to expire with the stream rather than lose it now
+		q.logger.Error("Failed to dead-letter outbox result",
+			zap.String("client_msg_uuid", clientMsgUUID),
+			zap.Error(err))
+		return
+	}
+	q.logger.Warn("Moved outbox result to the dead-letter subject",
+		zap.String("client_msg_uuid", clientMsgUUID))
+	msg.Term()
+}
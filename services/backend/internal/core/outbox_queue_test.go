@@ -0,0 +1,251 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/pkg/events"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+// memoryOutboxRepo keeps outbox entries in memory
+type memoryOutboxRepo struct {
+	repo.OutboxRepository
+	entries map[uuid.UUID]*repo.Outbox
+}
+
+func (r *memoryOutboxRepo) GetPendingOutboxEntries(ctx context.Context, limit int32) ([]repo.Outbox, error) {
+	var pending []repo.Outbox
+	for _, entry := range r.entries {
+		if entry.Status == events.OutboxStatusQueued || entry.Status == events.OutboxStatusRetry {
+			pending = append(pending, *entry)
+		}
+	}
+	return pending, nil
+}
+
+func (r *memoryOutboxRepo) UpdateOutboxStatus(ctx context.Context, params repo.UpdateOutboxStatusParams) error {
+	entry, ok := r.entries[params.ClientMsgUuid]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	entry.Status = params.Status
+	entry.LastError = params.LastError
+	return nil
+}
+
+func (r *memoryOutboxRepo) GetOutboxEntry(ctx context.Context, clientMsgUuid uuid.UUID) (repo.Outbox, error) {
+	entry, ok := r.entries[clientMsgUuid]
+	if !ok {
+		return repo.Outbox{}, fmt.Errorf("failed to get outbox entry: %w", pgx.ErrNoRows)
+	}
+	return *entry, nil
+}
+
+type queuedMessage struct {
+	integrationType string
+	req             *proto.SendMessageRequest
+}
+
+type fakeMessageQueue struct {
+	queued []queuedMessage
+}
+
+func (q *fakeMessageQueue) QueueMessage(ctx context.Context, integrationType string, req *proto.SendMessageRequest) error {
+	q.queued = append(q.queued, queuedMessage{integrationType: integrationType, req: req})
+	return nil
+}
+
+func TestQueuedOutboxWorkerWaitsForSendResult(t *testing.T) {
+	createdAt := time.Unix(1700000000, 0)
+	sent := uuid.New()
+	rejected := uuid.New()
+
+	payload, _ := json.Marshal(events.MessageOutPayload{
+		ContentType: "text",
+		Content:     map[string]interface{}{"text": "hello"},
+	})
+	outboxRepo := &memoryOutboxRepo{entries: map[uuid.UUID]*repo.Outbox{
+		sent: {
+			ClientMsgUuid: sent,
+			AccountID:     "acct-1",
+			ConvoID:       "123@s.whatsapp.net",
+			ServerMsgID:   sql.NullInt64{Int64: 1, Valid: true},
+			Status:        events.OutboxStatusQueued,
+			CreatedAt:     createdAt,
+			Payload:       payload,
+		},
+		rejected: {
+			ClientMsgUuid: rejected,
+			AccountID:     "acct-1",
+			ConvoID:       "123@s.whatsapp.net",
+			ServerMsgID:   sql.NullInt64{Int64: 2, Valid: true},
+			Status:        events.OutboxStatusQueued,
+			CreatedAt:     createdAt,
+			Payload:       payload,
+		},
+	}}
+
+	queue := &fakeMessageQueue{}
+	worker := NewQueuedOutboxWorker(NewOutboxService(outboxRepo, nil, zap.NewNop()), queue, nil, OutboxWorkerConfig{}, zap.NewNop())
+	worker.now = func() time.Time { return createdAt.Add(3 * time.Second) }
+	ctx := context.Background()
+
+	// Queued entries are published and stay sending until a bridge reports back
+	worker.processOutboxEntries(ctx)
+	if len(queue.queued) != 2 {
+		t.Fatalf("expected 2 queued messages, got %d", len(queue.queued))
+	}
+	for _, queued := range queue.queued {
+		if queued.integrationType != IntegrationTypeWhatsApp || queued.req.GetContent().GetText().GetText() != "hello" {
+			t.Errorf("unexpected queued message %+v", queued)
+		}
+	}
+	for id, entry := range outboxRepo.entries {
+		if entry.Status != events.OutboxStatusSending {
+			t.Errorf("entry %s: expected status %q, got %q", id, events.OutboxStatusSending, entry.Status)
+		}
+	}
+
+	if err := worker.ApplySendResult(ctx, &proto.SendMessageResult{
+		ClientMsgUuid: sent.String(),
+		Success:       true,
+		WaMessageId:   "WA-1",
+	}); err != nil {
+		t.Fatalf("ApplySendResult: %v", err)
+	}
+	if err := worker.ApplySendResult(ctx, &proto.SendMessageResult{
+		ClientMsgUuid: rejected.String(),
+		Error:         "no connected WhatsApp session",
+	}); err != nil {
+		t.Fatalf("ApplySendResult: %v", err)
+	}
+
+	// A redelivered result doesn't change an entry that was already resolved
+	if err := worker.ApplySendResult(ctx, &proto.SendMessageResult{ClientMsgUuid: sent.String(), Error: "late"}); err != nil {
+		t.Fatalf("ApplySendResult: %v", err)
+	}
+	// Results for entries that don't exist are dropped rather than retried
+	if err := worker.ApplySendResult(ctx, &proto.SendMessageResult{ClientMsgUuid: uuid.New().String(), Success: true}); err != nil {
+		t.Fatalf("expected unknown entry to be ignored, got %v", err)
+	}
+
+	if status := outboxRepo.entries[sent].Status; status != events.OutboxStatusSent {
+		t.Errorf("expected sent entry status %q, got %q", events.OutboxStatusSent, status)
+	}
+	if entry := outboxRepo.entries[rejected]; entry.Status != events.OutboxStatusFailed || entry.LastError.String != "no connected WhatsApp session" {
+		t.Errorf("expected rejected entry to fail with the bridge's error, got %q %q", entry.Status, entry.LastError.String)
+	}
+
+	stats := worker.Stats()
+	if stats.SendLatency.Count != 1 || stats.SendLatency.Sum != 3 {
+		t.Errorf("expected one 3s send latency, got count %d sum %v", stats.SendLatency.Count, stats.SendLatency.Sum)
+	}
+	if got := stats.FailuresByClass[OutboxFailureBridgeRejected]; got != 1 {
+		t.Errorf("expected 1 %s failure, got %d", OutboxFailureBridgeRejected, got)
+	}
+}
+
+func TestOutboxWorkerSendsFromTheEntryAlone(t *testing.T) {
+	reply := uuid.New()
+	legacy := uuid.New()
+
+	payload, _ := json.Marshal(events.MessageOutPayload{
+		ContentType:      "text",
+		Content:          map[string]interface{}{"text": "straight from the outbox"},
+		ReplyToMessageID: "PARENT-WA-ID",
+	})
+	// No event repository: the entries are all the worker gets
+	outboxRepo := &memoryOutboxRepo{entries: map[uuid.UUID]*repo.Outbox{
+		reply: {
+			ClientMsgUuid: reply,
+			AccountID:     "acct-1",
+			ConvoID:       "123@s.whatsapp.net",
+			ServerMsgID:   sql.NullInt64{Int64: 7, Valid: true},
+			Status:        events.OutboxStatusQueued,
+			Payload:       payload,
+		},
+		// Queued before entries carried their message, and its event is gone
+		legacy: {
+			ClientMsgUuid: legacy,
+			AccountID:     "acct-1",
+			ConvoID:       "123@s.whatsapp.net",
+			ServerMsgID:   sql.NullInt64{Int64: 8, Valid: true},
+			Status:        events.OutboxStatusQueued,
+		},
+	}}
+
+	queue := &fakeMessageQueue{}
+	worker := NewQueuedOutboxWorker(NewOutboxService(outboxRepo, nil, zap.NewNop()), queue, nil, OutboxWorkerConfig{}, zap.NewNop())
+	worker.processOutboxEntries(context.Background())
+
+	if len(queue.queued) != 1 {
+		t.Fatalf("expected 1 queued message, got %d", len(queue.queued))
+	}
+	req := queue.queued[0].req
+	if req.ClientMsgUuid != reply.String() || req.GetContent().GetText().GetText() != "straight from the outbox" || req.ReplyToWaMessageId != "PARENT-WA-ID" {
+		t.Errorf("unexpected request %+v", req)
+	}
+
+	entry := outboxRepo.entries[legacy]
+	if entry.Status != events.OutboxStatusFailed || entry.LastError.String == "" {
+		t.Errorf("expected the entry without a payload to fail, got %q %q", entry.Status, entry.LastError.String)
+	}
+	if got := worker.Stats().FailuresByClass[OutboxFailureInvalidMessage]; got != 1 {
+		t.Errorf("expected 1 %s failure, got %d", OutboxFailureInvalidMessage, got)
+	}
+}
+
+// recordingJetStream records the messages published through it
+type recordingJetStream struct {
+	nats.JetStreamContext
+	published []*nats.Msg
+}
+
+func (js *recordingJetStream) PublishMsg(msg *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	js.published = append(js.published, msg)
+	return &nats.PubAck{}, nil
+}
+
+func TestOutboxQueueDeadLettersResultsThatKeepFailing(t *testing.T) {
+	js := &recordingJetStream{}
+	queue := NewOutboxQueue(js, "test", zap.NewNop())
+	data, _ := protobuf.Marshal(&proto.SendMessageResult{ClientMsgUuid: uuid.NewString(), Success: true})
+	failing := func(ctx context.Context, result *proto.SendMessageResult) error {
+		return fmt.Errorf("database unavailable")
+	}
+	// Bound to a subscription without a connection, so acks go nowhere
+	result := func(delivered int) *nats.Msg {
+		msg := nats.NewMsg(events.OutboxResultSubject("test"))
+		msg.Data = data
+		msg.Sub = &nats.Subscription{}
+		msg.Reply = fmt.Sprintf("$JS.ACK.TEST_OUTBOX.%s.%d.1.1.1700000000000000000.0", outboxResultConsumer, delivered)
+		return msg
+	}
+
+	// Retried until the last delivery
+	queue.applyResult(context.Background(), result(outboxResultMaxDeliver-1), failing)
+	if len(js.published) != 0 {
+		t.Fatalf("expected a result with deliveries left to be retried, got %d published", len(js.published))
+	}
+
+	queue.applyResult(context.Background(), result(outboxResultMaxDeliver), failing)
+	if len(js.published) != 1 {
+		t.Fatalf("expected the result to be dead-lettered, got %d published", len(js.published))
+	}
+	dead := js.published[0]
+	if dead.Subject != events.OutboxDeadLetterSubject("test") || string(dead.Data) != string(data) {
+		t.Errorf("unexpected dead letter on %s", dead.Subject)
+	}
+}
@@ -3,34 +3,44 @@ package core
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 
 	"github.com/tennex/backend/internal/repo"
 	"github.com/tennex/pkg/events"
+	proto "github.com/tennex/shared/proto/gen/proto"
 )
 
 // OutboxService handles outbound message queue
 type OutboxService struct {
 	outboxRepo repo.OutboxRepository
-	eventRepo  repo.EventRepository
+	media      *MediaStore // Files attached to outbound media messages
 	logger     *zap.Logger
 }
 
-// NewOutboxService creates a new outbox service
-func NewOutboxService(outboxRepo repo.OutboxRepository, eventRepo repo.EventRepository, logger *zap.Logger) *OutboxService {
+// NewOutboxService creates a new outbox service. media may be nil, in which
+// case only text messages can be sent.
+func NewOutboxService(outboxRepo repo.OutboxRepository, media *MediaStore, logger *zap.Logger) *OutboxService {
 	return &OutboxService{
 		outboxRepo: outboxRepo,
-		eventRepo:  eventRepo,
+		media:      media,
 		logger:     logger.Named("outbox_service"),
 	}
 }
 
-// CreateOutboxEntry creates a new outbox entry with transactional guarantees
-func (s *OutboxService) CreateOutboxEntry(ctx context.Context, clientMsgUUID uuid.UUID, accountID, convoID string, serverMsgID int64) error {
+// CreateOutboxEntry creates a new outbox entry with transactional guarantees.
+// The entry carries payload, the message's MessageOutPayload, so it can be
+// sent without its msg_out_pending event, which serverMsgID links it to.
+func (s *OutboxService) CreateOutboxEntry(ctx context.Context, clientMsgUUID uuid.UUID, accountID, convoID string, serverMsgID int64, payload json.RawMessage) error {
 	s.logger.Debug("Creating outbox entry",
 		zap.String("client_msg_uuid", clientMsgUUID.String()),
 		zap.String("account_id", accountID),
@@ -42,6 +52,7 @@ func (s *OutboxService) CreateOutboxEntry(ctx context.Context, clientMsgUUID uui
 		ConvoID:       convoID,
 		ServerMsgID:   sql.NullInt64{Int64: serverMsgID, Valid: true},
 		Status:        events.OutboxStatusQueued,
+		Payload:       payload,
 	})
 	if err != nil {
 		s.logger.Error("Failed to create outbox entry", zap.Error(err))
@@ -55,6 +66,25 @@ func (s *OutboxService) CreateOutboxEntry(ctx context.Context, clientMsgUUID uui
 	return nil
 }
 
+// StoreMedia stores a file to attach to outbound messages and returns its
+// content hash and size
+func (s *OutboxService) StoreMedia(r io.Reader) (string, int64, error) {
+	if s.media == nil {
+		return "", 0, fmt.Errorf("media storage is not configured")
+	}
+	hash, _, size, err := s.media.Add(r)
+	if err != nil {
+		return "", 0, err
+	}
+	return hash, size, nil
+}
+
+// HasMedia reports whether a file to attach to outbound messages is stored
+// under a content hash
+func (s *OutboxService) HasMedia(hash string) bool {
+	return s.media != nil && s.media.Has(hash)
+}
+
 // GetPendingEntries retrieves pending outbox entries for processing
 func (s *OutboxService) GetPendingEntries(ctx context.Context, limit int32) ([]repo.Outbox, error) {
 	entries, err := s.outboxRepo.GetPendingOutboxEntries(ctx, limit)
@@ -100,30 +130,151 @@ func (s *OutboxService) GetEntry(ctx context.Context, clientMsgUUID uuid.UUID) (
 	return &entry, nil
 }
 
+// CountByStatus returns the number of outbox entries in each status
+func (s *OutboxService) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	counts, err := s.outboxRepo.CountOutboxByStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count outbox entries: %w", err)
+	}
+	return counts, nil
+}
+
+// GetStuckSummary summarizes unsent entries whose status hasn't changed since cutoff
+func (s *OutboxService) GetStuckSummary(ctx context.Context, cutoff time.Time) (repo.OutboxStuckSummary, error) {
+	summary, err := s.outboxRepo.GetStuckOutboxSummary(ctx, cutoff)
+	if err != nil {
+		return repo.OutboxStuckSummary{}, fmt.Errorf("failed to get stuck outbox entries: %w", err)
+	}
+	return summary, nil
+}
+
+// entryPayload decodes the outbound message an outbox entry carries
+func entryPayload(entry repo.Outbox) (*events.MessageOutPayload, error) {
+	if len(entry.Payload) == 0 {
+		return nil, fmt.Errorf("outbox entry %s has no payload", entry.ClientMsgUuid)
+	}
+
+	var payload events.MessageOutPayload
+	if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message payload: %w", err)
+	}
+
+	return &payload, nil
+}
+
+// MessageSender delivers outbound messages to WhatsApp
+type MessageSender interface {
+	SendMessage(ctx context.Context, req *proto.SendMessageRequest) (string, error)
+}
+
+// MessageQueue hands outbound messages to bridges through a work queue
+// (implemented by *OutboxQueue). Each send result arrives later and is
+// recorded with OutboxWorker.ApplySendResult.
+type MessageQueue interface {
+	QueueMessage(ctx context.Context, integrationType string, req *proto.SendMessageRequest) error
+}
+
+// AlertPublisher publishes operational alerts (implemented by *nats.Conn and
+// *natsx.Publisher)
+type AlertPublisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// OutboxWorkerConfig controls outbox polling and the stuck-message alert
+type OutboxWorkerConfig struct {
+	// How often to send pending entries
+	PollInterval time.Duration
+
+	// How often to refresh the per-status counts and look for stuck entries
+	CheckInterval time.Duration
+
+	// Unsent entries whose status hasn't changed for this long are stuck
+	StuckThreshold time.Duration
+
+	// NATS subject prefix for ops.alert messages, e.g. "tennex.prod"
+	AlertSubjectPrefix string
+}
+
 // OutboxWorker processes outbox entries
 type OutboxWorker struct {
 	outboxService *OutboxService
+	sender        MessageSender
+	queue         MessageQueue
+	alerts        AlertPublisher
+	config        OutboxWorkerConfig
 	logger        *zap.Logger
+	now           func() time.Time
 	stopCh        chan struct{}
+	stopOnce      sync.Once
+	doneCh        chan struct{} // Closed when Start returns
+
+	mu    sync.Mutex
+	stats OutboxStats
+	// Whether stuck entries were seen on the last check; an incident is alerted
+	// once when it starts and cleared when no entries are stuck
+	stuckIncident bool
 }
 
-// NewOutboxWorker creates a new outbox worker
-func NewOutboxWorker(outboxService *OutboxService, logger *zap.Logger) *OutboxWorker {
+// NewOutboxWorker creates a new outbox worker. alerts may be nil, in which
+// case stuck entries are only logged.
+func NewOutboxWorker(outboxService *OutboxService, sender MessageSender, alerts AlertPublisher, config OutboxWorkerConfig, logger *zap.Logger) *OutboxWorker {
+	if config.PollInterval <= 0 {
+		config.PollInterval = 5 * time.Second
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = time.Minute
+	}
+	if config.StuckThreshold <= 0 {
+		config.StuckThreshold = 10 * time.Minute
+	}
+
+	byStatus := make(map[string]int64, len(outboxStatuses))
+	for _, status := range outboxStatuses {
+		byStatus[status] = 0
+	}
+
 	return &OutboxWorker{
 		outboxService: outboxService,
+		sender:        sender,
+		alerts:        alerts,
+		config:        config,
 		logger:        logger.Named("outbox_worker"),
+		now:           time.Now,
 		stopCh:        make(chan struct{}),
+		doneCh:        make(chan struct{}),
+		stats: OutboxStats{
+			ByStatus:        byStatus,
+			SendLatency:     newLatencyHistogram(sendLatencyBuckets),
+			FailuresByClass: make(map[string]int64),
+		},
 	}
 }
 
-// Start starts the outbox worker
+// NewQueuedOutboxWorker creates an outbox worker that publishes entries to a
+// work queue instead of sending them. Entries stay sending until the result
+// reported by a bridge is passed to ApplySendResult.
+func NewQueuedOutboxWorker(outboxService *OutboxService, queue MessageQueue, alerts AlertPublisher, config OutboxWorkerConfig, logger *zap.Logger) *OutboxWorker {
+	w := NewOutboxWorker(outboxService, nil, alerts, config, logger)
+	w.queue = queue
+	return w
+}
+
+// Start runs the outbox worker until ctx is cancelled or Stop is called.
+// Batches run under ctx, so cancelling it aborts the batch in flight; use
+// Stop to let it finish.
 func (w *OutboxWorker) Start(ctx context.Context) {
-	w.logger.Info("Starting outbox worker")
+	w.logger.Info("Starting outbox worker",
+		zap.Duration("poll_interval", w.config.PollInterval),
+		zap.Duration("stuck_threshold", w.config.StuckThreshold))
+	defer close(w.doneCh)
 	defer w.logger.Info("Outbox worker stopped")
 
-	ticker := time.NewTicker(5 * time.Second) // Process every 5 seconds
+	ticker := time.NewTicker(w.config.PollInterval)
 	defer ticker.Stop()
+	checkTicker := time.NewTicker(w.config.CheckInterval)
+	defer checkTicker.Stop()
 
+	w.check(ctx)
 	for {
 		select {
 		case <-ctx.Done():
@@ -132,13 +283,118 @@ func (w *OutboxWorker) Start(ctx context.Context) {
 			return
 		case <-ticker.C:
 			w.processOutboxEntries(ctx)
+		case <-checkTicker.C:
+			w.check(ctx)
 		}
 	}
 }
 
-// Stop stops the outbox worker
+// Stop stops the outbox worker and waits for the batch in flight, if any, to
+// finish. It must only be called once Start is running.
 func (w *OutboxWorker) Stop() {
-	close(w.stopCh)
+	w.stopOnce.Do(func() { close(w.stopCh) })
+	<-w.doneCh
+}
+
+func (w *OutboxWorker) check(ctx context.Context) {
+	if err := w.CheckOutbox(ctx); err != nil && ctx.Err() == nil {
+		w.logger.Error("Outbox check failed", zap.Error(err))
+	}
+}
+
+// CheckOutbox refreshes the per-status counts and alerts when entries have
+// been waiting longer than the stuck threshold. Each incident is alerted once,
+// until a check finds no stuck entries.
+func (w *OutboxWorker) CheckOutbox(ctx context.Context) error {
+	now := w.now()
+
+	counts, err := w.outboxService.CountByStatus(ctx)
+	if err != nil {
+		return err
+	}
+	stuck, err := w.outboxService.GetStuckSummary(ctx, now.Add(-w.config.StuckThreshold))
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	for _, status := range outboxStatuses {
+		w.stats.ByStatus[status] = counts[status]
+	}
+	w.stats.Stuck = stuck.Count
+	w.stats.LastCheckAt = now
+
+	wasStuck := w.stuckIncident
+	w.stuckIncident = stuck.Count > 0
+	started := w.stuckIncident && !wasStuck
+	recovered := wasStuck && !w.stuckIncident
+	if started {
+		w.stats.StuckAlerts++
+	}
+	w.mu.Unlock()
+
+	switch {
+	case started:
+		w.alertStuck(stuck, now)
+	case recovered:
+		w.logger.Info("Outbox is no longer stuck")
+	}
+	return nil
+}
+
+// outboxStuckAlert is the ops.alert payload for a stuck outbox
+type outboxStuckAlert struct {
+	Alert            string    `json:"alert"`
+	Severity         string    `json:"severity"`
+	StuckCount       int64     `json:"stuck_count"`
+	OldestUpdatedAt  time.Time `json:"oldest_updated_at"`
+	ThresholdSeconds float64   `json:"threshold_seconds"`
+	DetectedAt       time.Time `json:"detected_at"`
+}
+
+func (w *OutboxWorker) alertStuck(stuck repo.OutboxStuckSummary, now time.Time) {
+	w.logger.Error("Outbox entries are stuck",
+		zap.Int64("stuck_count", stuck.Count),
+		zap.Time("oldest_updated_at", stuck.Oldest.Time),
+		zap.Duration("threshold", w.config.StuckThreshold))
+
+	if w.alerts == nil {
+		return
+	}
+
+	data, err := json.Marshal(outboxStuckAlert{
+		Alert:            "outbox_stuck",
+		Severity:         "error",
+		StuckCount:       stuck.Count,
+		OldestUpdatedAt:  stuck.Oldest.Time,
+		ThresholdSeconds: w.config.StuckThreshold.Seconds(),
+		DetectedAt:       now,
+	})
+	if err != nil {
+		w.logger.Error("Failed to marshal outbox alert", zap.Error(err))
+		return
+	}
+	if err := w.alerts.Publish(prefixedSubject(w.config.AlertSubjectPrefix, alertSubject), data); err != nil {
+		w.logger.Error("Failed to publish outbox alert", zap.Error(err))
+	}
+}
+
+// Stats returns a snapshot of the worker's statistics
+func (w *OutboxWorker) Stats() OutboxStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stats := w.stats
+	stats.ByStatus = make(map[string]int64, len(w.stats.ByStatus))
+	for status, count := range w.stats.ByStatus {
+		stats.ByStatus[status] = count
+	}
+	stats.FailuresByClass = make(map[string]int64, len(w.stats.FailuresByClass))
+	for class, count := range w.stats.FailuresByClass {
+		stats.FailuresByClass[class] = count
+	}
+	stats.SendLatency = w.stats.SendLatency.clone()
+	return stats
 }
 
 // processOutboxEntries processes pending outbox entries
@@ -157,10 +413,16 @@ func (w *OutboxWorker) processOutboxEntries(ctx context.Context) {
 
 	for _, entry := range entries {
 		if err := w.processEntry(ctx, entry); err != nil {
+			class := outboxFailureClass(err)
 			w.logger.Error("Failed to process outbox entry",
 				zap.String("client_msg_uuid", entry.ClientMsgUuid.String()),
+				zap.String("class", class),
 				zap.Error(err))
 
+			w.mu.Lock()
+			w.stats.FailuresByClass[class]++
+			w.mu.Unlock()
+
 			// Mark as failed
 			if updateErr := w.outboxService.UpdateEntryStatus(ctx, entry.ClientMsgUuid, events.OutboxStatusFailed, err.Error()); updateErr != nil {
 				w.logger.Error("Failed to mark entry as failed", zap.Error(updateErr))
@@ -173,21 +435,223 @@ func (w *OutboxWorker) processOutboxEntries(ctx context.Context) {
 func (w *OutboxWorker) processEntry(ctx context.Context, entry repo.Outbox) error {
 	// Mark as sending
 	if err := w.outboxService.UpdateEntryStatus(ctx, entry.ClientMsgUuid, events.OutboxStatusSending, ""); err != nil {
-		return fmt.Errorf("failed to mark as sending: %w", err)
+		return classifyOutboxError(OutboxFailureStore, fmt.Errorf("failed to mark as sending: %w", err))
+	}
+
+	payload, err := entryPayload(entry)
+	if err != nil {
+		return classifyOutboxError(OutboxFailureInvalidMessage, err)
+	}
+
+	req, err := buildSendMessageRequest(entry, payload, w.outboxService.media)
+	if err != nil {
+		return classifyOutboxError(OutboxFailureInvalidMessage, err)
+	}
+
+	if w.queue != nil {
+		if err := w.queue.QueueMessage(ctx, IntegrationTypeWhatsApp, req); err != nil {
+			return classifyOutboxError(OutboxFailureQueue, err)
+		}
+		w.logger.Debug("Message queued for bridges",
+			zap.String("client_msg_uuid", entry.ClientMsgUuid.String()),
+			zap.String("account_id", entry.AccountID))
+		return nil
+	}
+
+	waMessageID, err := w.sender.SendMessage(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return w.markSent(ctx, entry, waMessageID)
+}
+
+// ApplySendResult records the outcome a bridge reported for a queued entry.
+// Results for unknown entries, or entries no longer sending, are ignored.
+func (w *OutboxWorker) ApplySendResult(ctx context.Context, result *proto.SendMessageResult) error {
+	clientMsgUUID, err := uuid.Parse(result.ClientMsgUuid)
+	if err != nil {
+		w.logger.Warn("Ignoring send result with invalid client_msg_uuid", zap.String("client_msg_uuid", result.ClientMsgUuid))
+		return nil
+	}
+
+	entry, err := w.outboxService.GetEntry(ctx, clientMsgUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		w.logger.Warn("Ignoring send result for unknown outbox entry", zap.String("client_msg_uuid", result.ClientMsgUuid))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if entry.Status != events.OutboxStatusSending {
+		w.logger.Debug("Ignoring send result for entry that isn't sending",
+			zap.String("client_msg_uuid", result.ClientMsgUuid),
+			zap.String("status", entry.Status))
+		return nil
+	}
+
+	if !result.Success {
+		w.logger.Error("Bridge failed to send queued message",
+			zap.String("client_msg_uuid", result.ClientMsgUuid),
+			zap.String("class", OutboxFailureBridgeRejected),
+			zap.String("error", result.Error))
+
+		w.mu.Lock()
+		w.stats.FailuresByClass[OutboxFailureBridgeRejected]++
+		w.mu.Unlock()
+
+		return w.outboxService.UpdateEntryStatus(ctx, clientMsgUUID, events.OutboxStatusFailed, result.Error)
 	}
 
-	// TODO: Call bridge service to actually send the message
-	// For now, just simulate success after a short delay
-	time.Sleep(100 * time.Millisecond)
+	return w.markSent(ctx, *entry, result.WaMessageId)
+}
 
-	// Mark as sent
+// markSent marks an entry sent and records how long it waited
+func (w *OutboxWorker) markSent(ctx context.Context, entry repo.Outbox, waMessageID string) error {
 	if err := w.outboxService.UpdateEntryStatus(ctx, entry.ClientMsgUuid, events.OutboxStatusSent, ""); err != nil {
-		return fmt.Errorf("failed to mark as sent: %w", err)
+		return classifyOutboxError(OutboxFailureStore, fmt.Errorf("failed to mark as sent: %w", err))
 	}
 
+	w.mu.Lock()
+	w.stats.SendLatency.observe(w.now().Sub(entry.CreatedAt))
+	w.mu.Unlock()
+
 	w.logger.Info("Message sent successfully",
 		zap.String("client_msg_uuid", entry.ClientMsgUuid.String()),
-		zap.String("account_id", entry.AccountID))
+		zap.String("account_id", entry.AccountID),
+		zap.String("wa_message_id", waMessageID))
 
 	return nil
 }
+
+// buildSendMessageRequest converts an outbox entry and its payload into a
+// bridge request. Media messages carry the stored file they reference.
+func buildSendMessageRequest(entry repo.Outbox, payload *events.MessageOutPayload, media *MediaStore) (*proto.SendMessageRequest, error) {
+	content := &proto.MessageContent{}
+	switch payload.ContentType {
+	case events.ContentTypeText:
+		text, _ := payload.Content["text"].(string)
+		if text == "" {
+			return nil, fmt.Errorf("text message has no content")
+		}
+		content.Content = &proto.MessageContent_Text{
+			Text: &proto.TextContent{Text: text},
+		}
+
+	case events.ContentTypeImage, events.ContentTypeDocument:
+		outbound, err := ParseOutboundMedia(payload.Content)
+		if err != nil {
+			return nil, err
+		}
+		if media == nil {
+			return nil, fmt.Errorf("media storage is not configured")
+		}
+		data, err := media.Read(outbound.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s %s: %w", payload.ContentType, outbound.Hash, err)
+		}
+
+		if payload.ContentType == events.ContentTypeImage {
+			content.Content = &proto.MessageContent_Image{
+				Image: &proto.ImageContent{Data: data, MimeType: outbound.MimeType, Caption: outbound.Caption},
+			}
+		} else {
+			content.Content = &proto.MessageContent_Document{
+				Document: &proto.DocumentContent{Data: data, MimeType: outbound.MimeType, Filename: outbound.FileName},
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", payload.ContentType)
+	}
+
+	toJID := payload.ToJID
+	if toJID == "" {
+		toJID = entry.ConvoID
+	}
+
+	return &proto.SendMessageRequest{
+		ClientMsgUuid:      entry.ClientMsgUuid.String(),
+		AccountId:          entry.AccountID,
+		ConvoId:            entry.ConvoID,
+		ToJid:              toJID,
+		ReplyToWaMessageId: payload.ReplyToMessageID,
+		Content:            content,
+	}, nil
+}
+
+// OutboundMedia is the content of an outbound image or document message: a
+// file in the media store and how to present it
+type OutboundMedia struct {
+	Hash     string // Content hash of the stored file
+	MimeType string
+	Caption  string
+	FileName string
+}
+
+// ParseOutboundMedia reads the media fields of an outbound message's content
+func ParseOutboundMedia(content map[string]interface{}) (OutboundMedia, error) {
+	media := OutboundMedia{}
+	media.Hash, _ = content["media_hash"].(string)
+	media.MimeType, _ = content["mime_type"].(string)
+	media.Caption, _ = content["caption"].(string)
+	media.FileName, _ = content["file_name"].(string)
+
+	if !ValidContentHash(media.Hash) {
+		return OutboundMedia{}, fmt.Errorf("media message needs a media_hash: %w", ErrInvalidContentHash)
+	}
+	if media.MimeType == "" {
+		return OutboundMedia{}, fmt.Errorf("media message needs a mime_type")
+	}
+	return media, nil
+}
+
+// ValidateOutboundContent checks that content has what a message of
+// contentType needs to be sent, so a malformed message is rejected when it's
+// submitted rather than failing later in the outbox worker. The error says
+// which field is wrong.
+func ValidateOutboundContent(contentType string, content map[string]interface{}) error {
+	switch contentType {
+	case events.ContentTypeText:
+		text, err := contentString(content, "text")
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(text) == "" {
+			return fmt.Errorf("text message needs a non-empty text")
+		}
+		return nil
+
+	case events.ContentTypeImage, events.ContentTypeDocument:
+		for _, field := range []string{"media_hash", "mime_type", "caption", "file_name"} {
+			if _, err := contentString(content, field); err != nil {
+				return err
+			}
+		}
+		media, err := ParseOutboundMedia(content)
+		if err != nil {
+			return err
+		}
+		if contentType == events.ContentTypeImage && !strings.HasPrefix(media.MimeType, "image/") {
+			return fmt.Errorf("image message needs an image mime_type, got %q", media.MimeType)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("message type %q can't be sent", contentType)
+	}
+}
+
+// contentString returns the string field of a message's content, or "" when
+// it's absent. It fails when the field holds anything but a string.
+func contentString(content map[string]interface{}, field string) (string, error) {
+	value, ok := content[field]
+	if !ok || value == nil {
+		return "", nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("content field %s must be a string", field)
+	}
+	return s, nil
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/pkg/events"
+)
+
+func TestBuildSendMessageRequestMedia(t *testing.T) {
+	store := NewMediaStore(t.TempDir())
+	hash, _, _, err := store.Add(strings.NewReader("png bytes"))
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	entry := repo.Outbox{ClientMsgUuid: uuid.New(), AccountID: "acct-1", ConvoID: "123@s.whatsapp.net"}
+
+	req, err := buildSendMessageRequest(entry, &events.MessageOutPayload{
+		ContentType: events.ContentTypeImage,
+		Content:     map[string]interface{}{"media_hash": hash, "mime_type": "image/png", "caption": "look"},
+	}, store)
+	if err != nil {
+		t.Fatalf("image: %v", err)
+	}
+	image := req.GetContent().GetImage()
+	if string(image.GetData()) != "png bytes" || image.GetMimeType() != "image/png" || image.GetCaption() != "look" {
+		t.Errorf("unexpected image content %+v", image)
+	}
+	if req.ToJid != entry.ConvoID {
+		t.Errorf("expected the message to go to the conversation, got %q", req.ToJid)
+	}
+
+	req, err = buildSendMessageRequest(entry, &events.MessageOutPayload{
+		ContentType: events.ContentTypeDocument,
+		Content:     map[string]interface{}{"media_hash": hash, "mime_type": "application/pdf", "file_name": "report.pdf"},
+	}, store)
+	if err != nil {
+		t.Fatalf("document: %v", err)
+	}
+	if document := req.GetContent().GetDocument(); document.GetFilename() != "report.pdf" || len(document.GetData()) == 0 {
+		t.Errorf("unexpected document content %+v", document)
+	}
+
+	// A file that was never uploaded can't be sent
+	_, err = buildSendMessageRequest(entry, &events.MessageOutPayload{
+		ContentType: events.ContentTypeImage,
+		Content:     map[string]interface{}{"media_hash": strings.Repeat("a", 64), "mime_type": "image/png"},
+	}, store)
+	if !errors.Is(err, ErrMediaNotFound) {
+		t.Errorf("expected ErrMediaNotFound, got %v", err)
+	}
+
+	_, err = buildSendMessageRequest(entry, &events.MessageOutPayload{
+		ContentType: events.ContentTypeImage,
+		Content:     map[string]interface{}{"mime_type": "image/png"},
+	}, store)
+	if !errors.Is(err, ErrInvalidContentHash) {
+		t.Errorf("expected ErrInvalidContentHash, got %v", err)
+	}
+}
+
+func TestValidateOutboundContent(t *testing.T) {
+	hash := strings.Repeat("a", 64)
+	tests := []struct {
+		name        string
+		contentType string
+		content     map[string]interface{}
+		wantErr     string
+	}{
+		{"text", events.ContentTypeText, map[string]interface{}{"text": "hi"}, ""},
+		{"text without text", events.ContentTypeText, map[string]interface{}{"body": "hi"}, "text message needs a non-empty text"},
+		{"blank text", events.ContentTypeText, map[string]interface{}{"text": "  \n"}, "text message needs a non-empty text"},
+		{"text that isn't a string", events.ContentTypeText, map[string]interface{}{"text": 42.0}, "content field text must be a string"},
+		{"image", events.ContentTypeImage, map[string]interface{}{"media_hash": hash, "mime_type": "image/jpeg", "caption": "look"}, ""},
+		{"image without media", events.ContentTypeImage, map[string]interface{}{"mime_type": "image/jpeg"}, "media message needs a media_hash"},
+		{"image without mime type", events.ContentTypeImage, map[string]interface{}{"media_hash": hash}, "media message needs a mime_type"},
+		{"image with another mime type", events.ContentTypeImage, map[string]interface{}{"media_hash": hash, "mime_type": "application/pdf"}, `image message needs an image mime_type, got "application/pdf"`},
+		{"image with a caption that isn't a string", events.ContentTypeImage, map[string]interface{}{"media_hash": hash, "mime_type": "image/png", "caption": []interface{}{"x"}}, "content field caption must be a string"},
+		{"document", events.ContentTypeDocument, map[string]interface{}{"media_hash": hash, "mime_type": "application/pdf", "file_name": "report.pdf"}, ""},
+		{"document with a file name that isn't a string", events.ContentTypeDocument, map[string]interface{}{"media_hash": hash, "mime_type": "application/pdf", "file_name": 7.0}, "content field file_name must be a string"},
+		{"unsendable type", events.ContentTypeAudio, map[string]interface{}{"media_hash": hash}, `message type "audio" can't be sent`},
+		{"unknown type", "poll", nil, `message type "poll" can't be sent`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOutboundContent(tt.contentType, tt.content)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected the content to be accepted, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package core_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/grpc/client"
+	"github.com/tennex/backend/internal/http/handlers"
+	"github.com/tennex/bridge/whatsapp"
+	"github.com/tennex/shared/auth"
+)
+
+func TestPresenceSubscriptionsThroughBridge(t *testing.T) {
+	const token = "test-bridge-token"
+	userID := uuid.New()
+	session := &fakeSession{presence: whatsapp.NewPresenceSubscriptions()}
+	sessions := whatsapp.NewSessionRegistry()
+	sessions.Register(userID.String(), session)
+
+	bridgeClient, err := client.NewBridgeClient(startBridge(t, sessions, token), token, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to create bridge client: %v", err)
+	}
+	defer bridgeClient.Close()
+
+	const jwtSecret = "test-secret"
+	userToken, _, err := auth.DefaultJWTConfig(jwtSecret).GenerateToken(userID, auth.RoleUser)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	apiHandler := handlers.NewAPIHandler(nil, nil, nil, nil, bridgeClient, nil, jwtSecret, zap.NewNop())
+
+	post := func(action string, jids []string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(map[string][]string{"jids": jids})
+		req := httptest.NewRequest(http.MethodPost, "/integrations/whatsapp/presence/"+action, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+userToken)
+		rec := httptest.NewRecorder()
+		apiHandler.Routes().ServeHTTP(rec, req)
+		return rec
+	}
+	subscribed := func(rec *httptest.ResponseRecorder) []string {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response struct {
+			SubscribedJIDs []string `json:"subscribed_jids"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return response.SubscribedJIDs
+	}
+
+	const alice, bob = "972501111111@s.whatsapp.net", "972502222222@s.whatsapp.net"
+	if got := subscribed(post("subscribe", []string{bob, alice})); !slices.Equal(got, []string{alice, bob}) {
+		t.Errorf("expected both contacts subscribed, got %v", got)
+	}
+	if got := subscribed(post("unsubscribe", []string{bob})); !slices.Equal(got, []string{alice}) {
+		t.Errorf("expected only %s left, got %v", alice, got)
+	}
+	if !session.presence.Has(alice) || session.presence.Has(bob) {
+		t.Errorf("bridge subscriptions don't match the API's: %v", session.presence.List())
+	}
+
+	if rec := post("subscribe", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without jids, got %d", rec.Code)
+	}
+	// The bridge's cap on subscriptions is a conflict, not a bridge failure
+	full := make([]string, whatsapp.MaxPresenceSubscriptions-1)
+	for i := range full {
+		full[i] = fmt.Sprintf("97250%07d@s.whatsapp.net", i)
+	}
+	if _, err := session.presence.Add(full); err != nil {
+		t.Fatalf("failed to fill subscriptions: %v", err)
+	}
+	if rec := post("subscribe", []string{bob}); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 over the subscription cap, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	sessions.Unregister(userID.String(), session)
+	if rec := post("unsubscribe", []string{alice}); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 without a bridge session, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
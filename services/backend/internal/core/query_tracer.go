@@ -0,0 +1,139 @@
+package core
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// queryLatencyBuckets are the upper bounds, in seconds, of the per-query
+// duration histograms
+var queryLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// unnamedQuery labels queries without an sqlc or prepared statement name
+const unnamedQuery = "unnamed"
+
+// QueryTracerConfig holds QueryTracer settings
+type QueryTracerConfig struct {
+	// Queries taking at least this long are logged; <= 0 logs none
+	SlowThreshold time.Duration
+	// Fraction of slow queries logged, from 0 to 1. Every query is counted
+	// in the histograms regardless.
+	SampleRate float64
+}
+
+// QueryStats summarizes query durations by query name
+type QueryStats struct {
+	Durations map[string]LatencyHistogram
+	// Queries at or over the slow threshold, whether or not they were logged
+	Slow map[string]int64
+}
+
+// QueryTracer is a pgx tracer that times every query by its sqlc name and
+// logs slow ones
+type QueryTracer struct {
+	slowThreshold time.Duration
+	sampleRate    float64
+	sample        func() float64 // Returns a number in [0, 1); replaced in tests
+	logger        *zap.Logger
+
+	mu        sync.Mutex
+	durations map[string]*LatencyHistogram
+	slow      map[string]int64
+}
+
+// queryTraceKey is the context key TraceQueryStart stores its queryTrace under
+type queryTraceKey struct{}
+
+type queryTrace struct {
+	name  string
+	start time.Time
+}
+
+// NewQueryTracer creates a new query tracer
+func NewQueryTracer(config QueryTracerConfig, logger *zap.Logger) *QueryTracer {
+	return &QueryTracer{
+		slowThreshold: config.SlowThreshold,
+		sampleRate:    config.SampleRate,
+		sample:        rand.Float64,
+		logger:        logger.Named("query_tracer"),
+		durations:     make(map[string]*LatencyHistogram),
+		slow:          make(map[string]int64),
+	}
+}
+
+// TraceQueryStart implements pgx.QueryTracer
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryTraceKey{}, queryTrace{name: queryName(data.SQL), start: time.Now()})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace, ok := ctx.Value(queryTraceKey{}).(queryTrace)
+	if !ok {
+		return
+	}
+	t.observe(trace.name, time.Since(trace.start), data)
+}
+
+func (t *QueryTracer) observe(name string, duration time.Duration, data pgx.TraceQueryEndData) {
+	slow := t.slowThreshold > 0 && duration >= t.slowThreshold
+
+	t.mu.Lock()
+	histogram, ok := t.durations[name]
+	if !ok {
+		h := newLatencyHistogram(queryLatencyBuckets)
+		histogram = &h
+		t.durations[name] = histogram
+	}
+	histogram.observe(duration)
+	if slow {
+		t.slow[name]++
+	}
+	t.mu.Unlock()
+
+	if slow && t.sample() < t.sampleRate {
+		t.logger.Warn("Slow query",
+			zap.String("query", name),
+			zap.Duration("duration", duration),
+			zap.Int64("rows", data.CommandTag.RowsAffected()),
+			zap.Error(data.Err))
+	}
+}
+
+// Stats returns a snapshot of the query durations
+func (t *QueryTracer) Stats() QueryStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := QueryStats{
+		Durations: make(map[string]LatencyHistogram, len(t.durations)),
+		Slow:      make(map[string]int64, len(t.slow)),
+	}
+	for name, histogram := range t.durations {
+		stats.Durations[name] = histogram.clone()
+	}
+	for name, count := range t.slow {
+		stats.Slow[name] = count
+	}
+	return stats
+}
+
+// queryName returns the sqlc name of a query ("-- name: GetUser :one"), the
+// statement name if the query is a prepared statement, or unnamedQuery
+func queryName(sql string) string {
+	if rest, ok := strings.CutPrefix(sql, "-- name: "); ok {
+		if name, _, ok := strings.Cut(rest, " "); ok && name != "" {
+			return name
+		}
+	}
+	if sql != "" && !strings.ContainsAny(sql, " \t\r\n") {
+		return sql
+	}
+	return unnamedQuery
+}
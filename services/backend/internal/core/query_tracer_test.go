@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/tennex/backend/internal/testutil"
+)
+
+func TestQueryName(t *testing.T) {
+	for sql, want := range map[string]string{
+		"-- name: GetUserConversations :many\nSELECT 1": "GetUserConversations",
+		"stmtcache_1a2b3c":       "stmtcache_1a2b3c",
+		"SELECT seq FROM events": unnamedQuery,
+		"":                       unnamedQuery,
+	} {
+		if got := queryName(sql); got != want {
+			t.Errorf("queryName(%q) = %q, want %q", sql, got, want)
+		}
+	}
+}
+
+func TestQueryTracerSampling(t *testing.T) {
+	observed, logs := observer.New(zapcore.WarnLevel)
+	tracer := NewQueryTracer(QueryTracerConfig{SlowThreshold: 100 * time.Millisecond, SampleRate: 0.5}, zap.New(observed))
+	samples := []float64{0.9, 0.1}
+	tracer.sample = func() float64 {
+		sample := samples[0]
+		samples = samples[1:]
+		return sample
+	}
+
+	end := pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 3")}
+	tracer.observe("ListEvents", 10*time.Millisecond, end)
+	tracer.observe("ListEvents", 200*time.Millisecond, end) // Slow, not sampled
+	tracer.observe("ListEvents", 300*time.Millisecond, end) // Slow, sampled
+
+	if logs.Len() != 1 {
+		t.Fatalf("expected 1 slow query logged, got %d", logs.Len())
+	}
+	fields := logs.All()[0].ContextMap()
+	if fields["query"] != "ListEvents" || fields["rows"] != int64(3) || fields["duration"] != 300*time.Millisecond {
+		t.Errorf("unexpected slow query fields: %v", fields)
+	}
+
+	stats := tracer.Stats()
+	if histogram := stats.Durations["ListEvents"]; histogram.Count != 3 {
+		t.Errorf("expected 3 queries timed, got %d", histogram.Count)
+	}
+	if stats.Slow["ListEvents"] != 2 {
+		t.Errorf("expected 2 slow queries counted, got %d", stats.Slow["ListEvents"])
+	}
+}
+
+func TestQueryTracerLogsSlowQuery(t *testing.T) {
+	testPool := testutil.SetupTestDB(t)
+	ctx := context.Background()
+
+	observed, logs := observer.New(zapcore.WarnLevel)
+	tracer := NewQueryTracer(QueryTracerConfig{SlowThreshold: 100 * time.Millisecond, SampleRate: 1}, zap.New(observed))
+
+	poolConfig := testPool.Config()
+	poolConfig.ConnConfig.Tracer = tracer
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	if _, err := pool.Exec(ctx, "-- name: FastQuery :exec\nSELECT 1"); err != nil {
+		t.Fatalf("fast query: %v", err)
+	}
+	if _, err := pool.Exec(ctx, "-- name: SleepQuery :exec\nSELECT pg_sleep(0.2)"); err != nil {
+		t.Fatalf("slow query: %v", err)
+	}
+
+	slow := logs.FilterMessage("Slow query").All()
+	if len(slow) != 1 {
+		t.Fatalf("expected 1 slow query logged, got %d", len(slow))
+	}
+	fields := slow[0].ContextMap()
+	if fields["query"] != "SleepQuery" {
+		t.Errorf("expected SleepQuery logged, got %v", fields["query"])
+	}
+	if duration, _ := fields["duration"].(time.Duration); duration < 200*time.Millisecond {
+		t.Errorf("expected a duration of at least 200ms, got %v", fields["duration"])
+	}
+
+	stats := tracer.Stats()
+	if stats.Durations["FastQuery"].Count != 1 || stats.Durations["SleepQuery"].Count != 1 {
+		t.Errorf("expected both queries timed, got %+v", stats.Durations)
+	}
+}
@@ -0,0 +1,260 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/repo"
+)
+
+// RetentionConfig controls which events the retention worker deletes
+type RetentionConfig struct {
+	// Maximum age per event type; types without a TTL are kept forever
+	TTLs map[string]time.Duration
+
+	// Maximum age of events of any type, for accounts without a window in
+	// AccountMaxAges; 0 keeps them
+	MaxAge time.Duration
+
+	// Maximum age of events of any type per account, overriding MaxAge; 0
+	// keeps the account's events
+	AccountMaxAges map[string]time.Duration
+
+	// Copy events to events_archive before deleting them
+	Archive bool
+
+	// Only count the events each pass would delete, for checking a new
+	// policy before it takes effect
+	DryRun bool
+
+	// How often to run a retention pass
+	Interval time.Duration
+
+	// Events deleted per statement, and the pause between statements, so a
+	// large backlog doesn't hold locks on the events table for long
+	BatchSize  int32
+	BatchPause time.Duration
+}
+
+// RetentionStats summarizes the retention worker's progress since startup
+type RetentionStats struct {
+	Runs          int64
+	Failures      int64
+	DeletedByType map[string]int64
+	DeletedByAge  int64 // Deleted by the age windows, of any type
+	LastRunAt     time.Time
+	LastRunTime   time.Duration
+
+	// Events the last dry run would have deleted. An event expired by both a
+	// TTL and an age window is counted twice.
+	DryRunEligible int64
+}
+
+// RetentionWorker deletes expired events in bounded batches
+type RetentionWorker struct {
+	eventRepo repo.EventRepository
+	config    RetentionConfig
+	logger    *zap.Logger
+	now       func() time.Time
+
+	mu    sync.Mutex
+	stats RetentionStats
+}
+
+// NewRetentionWorker creates a new retention worker
+func NewRetentionWorker(eventRepo repo.EventRepository, config RetentionConfig, logger *zap.Logger) *RetentionWorker {
+	if config.Interval <= 0 {
+		config.Interval = time.Hour
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1000
+	}
+
+	// Report every configured type, even before anything is deleted
+	deletedByType := make(map[string]int64, len(config.TTLs))
+	for eventType := range config.TTLs {
+		deletedByType[eventType] = 0
+	}
+
+	return &RetentionWorker{
+		eventRepo: eventRepo,
+		config:    config,
+		logger:    logger.Named("retention_worker"),
+		now:       time.Now,
+		stats:     RetentionStats{DeletedByType: deletedByType},
+	}
+}
+
+// Start runs retention passes until ctx is done
+func (w *RetentionWorker) Start(ctx context.Context) {
+	w.logger.Info("Starting retention worker",
+		zap.Duration("interval", w.config.Interval),
+		zap.Int("policies", len(w.policies(w.now()))),
+		zap.Bool("archive", w.config.Archive),
+		zap.Bool("dry_run", w.config.DryRun))
+	defer w.logger.Info("Retention worker stopped")
+
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("Retention pass failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce deletes all currently expired events, or counts them in a dry run
+func (w *RetentionWorker) RunOnce(ctx context.Context) error {
+	started := w.now()
+
+	var runErr error
+	var eligible int64
+	for _, policy := range w.policies(started) {
+		if w.config.DryRun {
+			count, err := w.eventRepo.CountExpiredEvents(ctx, policy)
+			if err != nil {
+				runErr = err
+				break
+			}
+			w.logger.Info("Retention dry run",
+				zap.String("type", policy.Type),
+				zap.String("account_id", policy.AccountID),
+				zap.Time("cutoff", policy.Cutoff),
+				zap.Int64("count", count))
+			eligible += count
+			continue
+		}
+		if err := w.deleteExpired(ctx, policy); err != nil {
+			runErr = err
+			break
+		}
+	}
+
+	w.mu.Lock()
+	w.stats.Runs++
+	if runErr != nil {
+		w.stats.Failures++
+	}
+	if w.config.DryRun && runErr == nil {
+		w.stats.DryRunEligible = eligible
+	}
+	w.stats.LastRunAt = started
+	w.stats.LastRunTime = w.now().Sub(started)
+	w.mu.Unlock()
+
+	return runErr
+}
+
+// policies returns what a pass starting at now deletes: each type with a TTL,
+// then each account with its own window, then every other account's events
+// under MaxAge
+func (w *RetentionWorker) policies(now time.Time) []repo.DeleteExpiredEventsParams {
+	var policies []repo.DeleteExpiredEventsParams
+
+	types := make([]string, 0, len(w.config.TTLs))
+	for eventType := range w.config.TTLs {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+	for _, eventType := range types {
+		policies = append(policies, repo.DeleteExpiredEventsParams{
+			Type:   eventType,
+			Cutoff: now.Add(-w.config.TTLs[eventType]),
+		})
+	}
+
+	accounts := make([]string, 0, len(w.config.AccountMaxAges))
+	for accountID := range w.config.AccountMaxAges {
+		accounts = append(accounts, accountID)
+	}
+	sort.Strings(accounts)
+	for _, accountID := range accounts {
+		if maxAge := w.config.AccountMaxAges[accountID]; maxAge > 0 {
+			policies = append(policies, repo.DeleteExpiredEventsParams{
+				AccountID: accountID,
+				Cutoff:    now.Add(-maxAge),
+			})
+		}
+	}
+
+	if w.config.MaxAge > 0 {
+		policies = append(policies, repo.DeleteExpiredEventsParams{
+			ExcludeAccountIDs: accounts,
+			Cutoff:            now.Add(-w.config.MaxAge),
+		})
+	}
+
+	for i := range policies {
+		policies[i].BatchSize = w.config.BatchSize
+		policies[i].Archive = w.config.Archive
+	}
+	return policies
+}
+
+// deleteExpired deletes the events a policy selects, a batch at a time
+func (w *RetentionWorker) deleteExpired(ctx context.Context, policy repo.DeleteExpiredEventsParams) error {
+	var total int64
+	for {
+		deleted, err := w.eventRepo.DeleteExpiredEvents(ctx, policy)
+		if err != nil {
+			if policy.Type != "" {
+				return fmt.Errorf("failed to delete expired %s events: %w", policy.Type, err)
+			}
+			return fmt.Errorf("failed to delete expired events: %w", err)
+		}
+
+		total += deleted
+		w.mu.Lock()
+		if policy.Type != "" {
+			w.stats.DeletedByType[policy.Type] += deleted
+		} else {
+			w.stats.DeletedByAge += deleted
+		}
+		w.mu.Unlock()
+
+		if deleted < int64(policy.BatchSize) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.config.BatchPause):
+		}
+	}
+
+	if total > 0 {
+		w.logger.Info("Deleted expired events",
+			zap.String("type", policy.Type),
+			zap.String("account_id", policy.AccountID),
+			zap.Time("cutoff", policy.Cutoff),
+			zap.Bool("archived", policy.Archive),
+			zap.Int64("count", total))
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the worker's statistics
+func (w *RetentionWorker) Stats() RetentionStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stats := w.stats
+	stats.DeletedByType = make(map[string]int64, len(w.stats.DeletedByType))
+	for eventType, count := range w.stats.DeletedByType {
+		stats.DeletedByType[eventType] = count
+	}
+	return stats
+}
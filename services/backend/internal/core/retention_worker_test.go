@@ -0,0 +1,340 @@
+package core_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/http/handlers"
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/backend/internal/testutil"
+	dbgen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/events"
+)
+
+// batchingEventRepo records DeleteExpiredEvents calls and reports a fixed
+// number of deleted events per call
+type batchingEventRepo struct {
+	repo.EventRepository
+	remaining map[string]int64
+	calls     []repo.DeleteExpiredEventsParams
+}
+
+func (r *batchingEventRepo) DeleteExpiredEvents(ctx context.Context, params repo.DeleteExpiredEventsParams) (int64, error) {
+	r.calls = append(r.calls, params)
+	deleted := min(r.remaining[params.Type], int64(params.BatchSize))
+	r.remaining[params.Type] -= deleted
+	return deleted, nil
+}
+
+func (r *batchingEventRepo) CountExpiredEvents(ctx context.Context, params repo.DeleteExpiredEventsParams) (int64, error) {
+	r.calls = append(r.calls, params)
+	return r.remaining[params.Type], nil
+}
+
+func TestRetentionWorkerDeletesInBatches(t *testing.T) {
+	eventRepo := &batchingEventRepo{remaining: map[string]int64{
+		events.TypePresence:        250,
+		events.TypeMessageDelivery: 0,
+	}}
+	worker := core.NewRetentionWorker(eventRepo, core.RetentionConfig{
+		TTLs: map[string]time.Duration{
+			events.TypePresence:        7 * 24 * time.Hour,
+			events.TypeMessageDelivery: 30 * 24 * time.Hour,
+		},
+		BatchSize: 100,
+	}, zap.NewNop())
+
+	if err := worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	// One call for the empty delivery backlog, then 100 + 100 + 50 presence events
+	if len(eventRepo.calls) != 4 {
+		t.Fatalf("expected 4 batches, got %d", len(eventRepo.calls))
+	}
+	for _, call := range eventRepo.calls {
+		if call.BatchSize != 100 {
+			t.Errorf("expected batch size 100, got %d", call.BatchSize)
+		}
+	}
+
+	stats := worker.Stats()
+	if stats.Runs != 1 || stats.Failures != 0 {
+		t.Errorf("expected 1 successful run, got %d runs and %d failures", stats.Runs, stats.Failures)
+	}
+	if got := stats.DeletedByType[events.TypePresence]; got != 250 {
+		t.Errorf("expected 250 presence events deleted, got %d", got)
+	}
+	if got, ok := stats.DeletedByType[events.TypeMessageDelivery]; !ok || got != 0 {
+		t.Errorf("expected delivery events reported with 0 deleted, got %d (present=%v)", got, ok)
+	}
+}
+
+func TestRetentionWorkerAgeWindows(t *testing.T) {
+	eventRepo := &batchingEventRepo{remaining: map[string]int64{}}
+	worker := core.NewRetentionWorker(eventRepo, core.RetentionConfig{
+		TTLs:   map[string]time.Duration{events.TypePresence: 7 * 24 * time.Hour},
+		MaxAge: 90 * 24 * time.Hour,
+		AccountMaxAges: map[string]time.Duration{
+			"acct-short": 24 * time.Hour,
+			"acct-keep":  0,
+		},
+		Archive:   true,
+		BatchSize: 100,
+	}, zap.NewNop())
+
+	if err := worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	// The TTL, then the account's own window, then everyone else's; the
+	// account that keeps its events has no window of its own
+	if len(eventRepo.calls) != 3 {
+		t.Fatalf("expected 3 policies, got %+v", eventRepo.calls)
+	}
+	ttl, account, rest := eventRepo.calls[0], eventRepo.calls[1], eventRepo.calls[2]
+	if ttl.Type != events.TypePresence || ttl.AccountID != "" {
+		t.Errorf("expected the presence TTL first, got %+v", ttl)
+	}
+	if account.Type != "" || account.AccountID != "acct-short" || rest.Cutoff.Sub(account.Cutoff) != -89*24*time.Hour {
+		t.Errorf("expected acct-short's 1 day window, got %+v", account)
+	}
+	if rest.Type != "" || rest.AccountID != "" || len(rest.ExcludeAccountIDs) != 2 {
+		t.Errorf("expected the 90 day window excluding both configured accounts, got %+v", rest)
+	}
+	for _, call := range eventRepo.calls {
+		if !call.Archive {
+			t.Errorf("expected every policy to archive, got %+v", call)
+		}
+	}
+}
+
+func TestRetentionWorkerDryRunOnlyCounts(t *testing.T) {
+	eventRepo := &batchingEventRepo{remaining: map[string]int64{
+		events.TypePresence: 250,
+		"":                  40,
+	}}
+	worker := core.NewRetentionWorker(eventRepo, core.RetentionConfig{
+		TTLs:      map[string]time.Duration{events.TypePresence: 7 * 24 * time.Hour},
+		MaxAge:    90 * 24 * time.Hour,
+		DryRun:    true,
+		BatchSize: 100,
+	}, zap.NewNop())
+
+	if err := worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	// One count per policy and nothing deleted
+	if len(eventRepo.calls) != 2 || eventRepo.remaining[events.TypePresence] != 250 {
+		t.Fatalf("expected 2 counts and no deletes, got %+v", eventRepo.calls)
+	}
+	stats := worker.Stats()
+	if stats.DryRunEligible != 290 {
+		t.Errorf("expected 290 eligible events, got %d", stats.DryRunEligible)
+	}
+	if stats.DeletedByType[events.TypePresence] != 0 || stats.DeletedByAge != 0 {
+		t.Errorf("expected nothing deleted in a dry run, got %+v", stats)
+	}
+}
+
+func TestRetentionLowWaterMarkRequiresSnapshot(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	ctx := context.Background()
+
+	eventRepo := repo.NewEventRepository(pool)
+	eventService := core.NewEventService(eventRepo, nil, "", false, zap.NewNop())
+
+	insert := func(accountID, eventType string, age time.Duration) int64 {
+		t.Helper()
+		result, err := eventRepo.InsertEvent(ctx, repo.InsertEventParams{
+			ID:        uuid.New(),
+			Type:      eventType,
+			AccountID: accountID,
+			ConvoID:   "123@s.whatsapp.net",
+			Payload:   json.RawMessage(`{}`),
+		})
+		if err != nil {
+			t.Fatalf("failed to insert event: %v", err)
+		}
+		if _, err := pool.Exec(ctx, `UPDATE events SET ts = NOW() - make_interval(secs => $2) WHERE seq = $1`, result.Seq, age.Seconds()); err != nil {
+			t.Fatalf("failed to backdate event: %v", err)
+		}
+		return result.Seq
+	}
+
+	oldMessage := insert("acct-1", events.TypeMessageIn, 60*24*time.Hour)
+	oldPresence := insert("acct-1", events.TypePresence, 10*24*time.Hour)
+	newPresence := insert("acct-1", events.TypePresence, time.Hour)
+	otherAccount := insert("acct-2", events.TypeMessageIn, 60*24*time.Hour)
+
+	worker := core.NewRetentionWorker(eventRepo, core.RetentionConfig{
+		TTLs:      map[string]time.Duration{events.TypePresence: 7 * 24 * time.Hour},
+		BatchSize: 1,
+	}, zap.NewNop())
+	if err := worker.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	// Only the expired presence event is deleted; messages are kept forever
+	remaining, err := eventService.GetEventsSince(ctx, "acct-1", 0, 100, nil)
+	if err != nil {
+		t.Fatalf("GetEventsSince: %v", err)
+	}
+	if len(remaining) != 2 || remaining[0].Seq != oldMessage || remaining[1].Seq != newPresence {
+		t.Fatalf("expected events %d and %d to remain, got %+v", oldMessage, newPresence, remaining)
+	}
+
+	lowWaterSeq, err := eventService.GetLowWaterMark(ctx, "acct-1")
+	if err != nil {
+		t.Fatalf("GetLowWaterMark: %v", err)
+	}
+	if lowWaterSeq != oldPresence {
+		t.Fatalf("expected low-water mark %d, got %d", oldPresence, lowWaterSeq)
+	}
+
+	// /sync tells a client that synced before the deleted event to take a
+	// snapshot; one that already saw it can keep syncing incrementally
+	// acct-1 predates keying accounts by user ID, so it's mapped to its owner
+	_, token := newAccountUser(t, pool, "acct-1")
+	accountService := core.NewAccountService(repo.NewAccountRepository(pool), zap.NewNop())
+	apiHandler := handlers.NewAPIHandler(eventService, nil, accountService, nil, nil, dbgen.New(pool), "test-secret", zap.NewNop())
+	for _, tc := range []struct {
+		since            int64
+		snapshotRequired bool
+	}{
+		{since: 0, snapshotRequired: true},
+		{since: oldMessage, snapshotRequired: true},
+		{since: oldPresence, snapshotRequired: false},
+	} {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/sync?account_id=acct-1&since=%d", tc.since), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		apiHandler.Routes().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 from /sync, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var response struct {
+			SnapshotRequired bool  `json:"snapshot_required"`
+			LowWaterSeq      int64 `json:"low_water_seq"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode /sync response: %v", err)
+		}
+		if response.SnapshotRequired != tc.snapshotRequired || response.LowWaterSeq != oldPresence {
+			t.Errorf("since=%d: expected snapshot_required=%v low_water_seq=%d, got %+v",
+				tc.since, tc.snapshotRequired, oldPresence, response)
+		}
+	}
+
+	// Accounts without deleted events have no low-water mark
+	otherLowWater, err := eventService.GetLowWaterMark(ctx, "acct-2")
+	if err != nil {
+		t.Fatalf("GetLowWaterMark: %v", err)
+	}
+	if otherLowWater != 0 {
+		t.Fatalf("expected no low-water mark for acct-2 (event %d), got %d", otherAccount, otherLowWater)
+	}
+
+	if got := worker.Stats().DeletedByType[events.TypePresence]; got != 1 {
+		t.Fatalf("expected 1 presence event deleted, got %d", got)
+	}
+}
+
+func TestRetentionAgeWindowArchivesAndKeepsLatestSeq(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	ctx := context.Background()
+
+	eventRepo := repo.NewEventRepository(pool)
+	eventService := core.NewEventService(eventRepo, nil, "", false, zap.NewNop())
+
+	insert := func(accountID string, age time.Duration) int64 {
+		t.Helper()
+		result, err := eventRepo.InsertEvent(ctx, repo.InsertEventParams{
+			ID:        uuid.New(),
+			Type:      events.TypeMessageIn,
+			AccountID: accountID,
+			ConvoID:   "123@s.whatsapp.net",
+			Payload:   json.RawMessage(`{}`),
+		})
+		if err != nil {
+			t.Fatalf("failed to insert event: %v", err)
+		}
+		if _, err := pool.Exec(ctx, `UPDATE events SET ts = NOW() - make_interval(secs => $2) WHERE seq = $1`, result.Seq, age.Seconds()); err != nil {
+			t.Fatalf("failed to backdate event: %v", err)
+		}
+		return result.Seq
+	}
+
+	insert("acct-1", 40*24*time.Hour)
+	latest := insert("acct-1", 35*24*time.Hour)
+	kept := insert("acct-2", 40*24*time.Hour)
+
+	config := core.RetentionConfig{
+		MaxAge:         30 * 24 * time.Hour,
+		AccountMaxAges: map[string]time.Duration{"acct-2": 0},
+		Archive:        true,
+		DryRun:         true,
+		BatchSize:      1,
+	}
+
+	// A dry run counts acct-1's events and deletes nothing
+	dryRun := core.NewRetentionWorker(eventRepo, config, zap.NewNop())
+	if err := dryRun.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if got := dryRun.Stats().DryRunEligible; got != 2 {
+		t.Fatalf("expected 2 eligible events, got %d", got)
+	}
+	if remaining, err := eventService.GetEventsSince(ctx, "acct-1", 0, 100, nil); err != nil || len(remaining) != 2 {
+		t.Fatalf("expected the dry run to keep acct-1's 2 events, got %d: %v", len(remaining), err)
+	}
+
+	config.DryRun = false
+	worker := core.NewRetentionWorker(eventRepo, config, zap.NewNop())
+	if err := worker.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if got := worker.Stats().DeletedByAge; got != 2 {
+		t.Fatalf("expected 2 events deleted by age, got %d", got)
+	}
+
+	// Every acct-1 event is gone but its seq doesn't move back
+	remaining, err := eventService.GetEventsSince(ctx, "acct-1", 0, 100, nil)
+	if err != nil {
+		t.Fatalf("GetEventsSince: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Fatalf("expected acct-1's events deleted, got %+v", remaining)
+	}
+	latestSeq, err := eventService.GetLatestEventSeq(ctx, "acct-1")
+	if err != nil {
+		t.Fatalf("GetLatestEventSeq: %v", err)
+	}
+	if latestSeq != latest {
+		t.Errorf("expected latest seq %d after retention, got %d", latest, latestSeq)
+	}
+
+	var archived int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM events_archive WHERE account_id = 'acct-1'`).Scan(&archived); err != nil {
+		t.Fatalf("failed to count archived events: %v", err)
+	}
+	if archived != 2 {
+		t.Errorf("expected 2 archived events, got %d", archived)
+	}
+
+	// acct-2 keeps its events
+	if remaining, err := eventService.GetEventsSince(ctx, "acct-2", 0, 100, nil); err != nil || len(remaining) != 1 || remaining[0].Seq != kept {
+		t.Errorf("expected acct-2's event %d kept, got %+v: %v", kept, remaining, err)
+	}
+}
@@ -0,0 +1,218 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/pkg/events"
+)
+
+// ErrInvalidWebhook is returned for webhook settings that can't be saved
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Shortest secret a user may supply; generated secrets are 32 bytes
+const minWebhookSecretLength = 16
+
+// webhookEventTypes are the event types a webhook can subscribe to
+var webhookEventTypes = map[string]bool{
+	events.TypeMessageIn:         true,
+	events.TypeMessageOutPending: true,
+	events.TypeMessageOutSent:    true,
+	events.TypeMessageDelivery:   true,
+	events.TypePresence:          true,
+	events.TypeContactUpdate:     true,
+	events.TypeHistorySync:       true,
+	events.TypeConversationState: true,
+}
+
+// WebhookService manages users' webhook subscriptions
+type WebhookService struct {
+	webhookRepo repo.WebhookRepository
+	eventRepo   repo.EventRepository
+	logger      *zap.Logger
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(webhookRepo repo.WebhookRepository, eventRepo repo.EventRepository, logger *zap.Logger) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		eventRepo:   eventRepo,
+		logger:      logger.Named("webhook_service"),
+	}
+}
+
+// WebhookUpdate changes a webhook's settings; nil fields are left unchanged
+type WebhookUpdate struct {
+	URL        *string
+	Secret     *string
+	EventTypes *[]string
+	Enabled    *bool
+}
+
+// CreateWebhook subscribes a URL to the user's events. Only events after the
+// webhook is created are delivered. A secret is generated when none is given.
+func (s *WebhookService) CreateWebhook(ctx context.Context, userID uuid.UUID, rawURL, secret string, eventTypes []string) (*repo.Webhook, error) {
+	if err := validateWebhookURL(rawURL); err != nil {
+		return nil, err
+	}
+	if err := validateWebhookEventTypes(eventTypes); err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		generated, err := generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	} else if err := validateWebhookSecret(secret); err != nil {
+		return nil, err
+	}
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+
+	latestSeq, err := s.eventRepo.GetLatestEventSeq(ctx, AccountIDForUser(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest event seq: %w", err)
+	}
+
+	webhook, err := s.webhookRepo.CreateWebhook(ctx, repo.CreateWebhookParams{
+		UserID:       userID,
+		URL:          rawURL,
+		Secret:       secret,
+		EventTypes:   eventTypes,
+		LastEventSeq: latestSeq,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Webhook created",
+		zap.String("webhook_id", webhook.ID.String()),
+		zap.String("user_id", userID.String()),
+		zap.Strings("event_types", eventTypes))
+
+	return &webhook, nil
+}
+
+// GetWebhook returns one of the user's webhooks
+func (s *WebhookService) GetWebhook(ctx context.Context, userID, id uuid.UUID) (*repo.Webhook, error) {
+	webhook, err := s.webhookRepo.GetWebhook(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks returns the user's webhooks, oldest first
+func (s *WebhookService) ListWebhooks(ctx context.Context, userID uuid.UUID) ([]repo.Webhook, error) {
+	return s.webhookRepo.ListWebhooks(ctx, userID)
+}
+
+// UpdateWebhook applies update to one of the user's webhooks. Re-enabling a
+// webhook that was disabled after failing resumes delivery where it stopped.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, userID, id uuid.UUID, update WebhookUpdate) (*repo.Webhook, error) {
+	webhook, err := s.webhookRepo.GetWebhook(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	params := repo.UpdateWebhookParams{
+		ID:         id,
+		UserID:     userID,
+		URL:        webhook.URL,
+		Secret:     webhook.Secret,
+		EventTypes: webhook.EventTypes,
+		Enabled:    webhook.Enabled,
+	}
+	if update.URL != nil {
+		if err := validateWebhookURL(*update.URL); err != nil {
+			return nil, err
+		}
+		params.URL = *update.URL
+	}
+	if update.Secret != nil {
+		if err := validateWebhookSecret(*update.Secret); err != nil {
+			return nil, err
+		}
+		params.Secret = *update.Secret
+	}
+	if update.EventTypes != nil {
+		if err := validateWebhookEventTypes(*update.EventTypes); err != nil {
+			return nil, err
+		}
+		params.EventTypes = *update.EventTypes
+		if params.EventTypes == nil {
+			params.EventTypes = []string{}
+		}
+	}
+	if update.Enabled != nil {
+		params.Enabled = *update.Enabled
+	}
+
+	updated, err := s.webhookRepo.UpdateWebhook(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteWebhook removes one of the user's webhooks and its delivery history
+func (s *WebhookService) DeleteWebhook(ctx context.Context, userID, id uuid.UUID) error {
+	return s.webhookRepo.DeleteWebhook(ctx, userID, id)
+}
+
+// ListDeliveries returns a webhook's most recent deliveries, newest first
+func (s *WebhookService) ListDeliveries(ctx context.Context, userID, id uuid.UUID, limit int32) ([]repo.WebhookDelivery, error) {
+	if _, err := s.webhookRepo.GetWebhook(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.ListWebhookDeliveries(ctx, id, limit)
+}
+
+func validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	return nil
+}
+
+func validateWebhookSecret(secret string) error {
+	if len(secret) < minWebhookSecretLength {
+		return fmt.Errorf("%w: secret must be at least %d characters", ErrInvalidWebhook, minWebhookSecretLength)
+	}
+	return nil
+}
+
+func validateWebhookEventTypes(eventTypes []string) error {
+	for _, eventType := range eventTypes {
+		if !webhookEventTypes[eventType] {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, eventType)
+		}
+	}
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
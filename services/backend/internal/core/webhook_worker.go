@@ -0,0 +1,484 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/repo"
+)
+
+// Headers sent with every webhook delivery
+const (
+	WebhookSignatureHeader = "X-Tennex-Signature" // "sha256=" + hex HMAC-SHA256 of the body
+	WebhookEventHeader     = "X-Tennex-Event"
+	WebhookDeliveryHeader  = "X-Tennex-Delivery"
+)
+
+// ErrWebhookAddressNotAllowed is returned for a delivery to an address that
+// isn't on the public internet, such as loopback, a private network or a
+// cloud metadata endpoint
+var ErrWebhookAddressNotAllowed = errors.New("webhook address is not allowed")
+
+// nonPublicPrefixes are the special-purpose ranges not covered by the
+// netip.Addr predicates isPublicWebhookAddr checks
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // Documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // Documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // Documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, which can reach private IPv4
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+}
+
+// isPublicWebhookAddr reports whether deliveries may be sent to an address
+func isPublicWebhookAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// publicAddressOnly is a net.Dialer Control function refusing connections to
+// non-public addresses. It runs on the address a host name resolved to, right
+// before connecting, so a name can't be rebound to an internal address after
+// it was checked.
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWebhookAddressNotAllowed, err)
+	}
+	if !isPublicWebhookAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrWebhookAddressNotAllowed, addrPort.Addr())
+	}
+	return nil
+}
+
+// webhookAttemptError is what a failed attempt records on its delivery. The
+// delivery list is shown to the webhook's owner, so it carries no response
+// body or network error text that would tell them about the backend's
+// network.
+func webhookAttemptError(statusCode int, err error) string {
+	var netErr net.Error
+	switch {
+	case statusCode != 0:
+		return fmt.Sprintf("endpoint responded with status %d", statusCode)
+	case errors.Is(err, ErrWebhookAddressNotAllowed):
+		return "destination address is not allowed"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "request timed out"
+	default:
+		return "request failed"
+	}
+}
+
+// SignWebhookPayload returns the signature header value for a delivery body
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookEvent is the JSON body POSTed for each event
+type webhookEvent struct {
+	ID             string          `json:"id"`
+	Seq            int64           `json:"seq"`
+	Type           string          `json:"type"`
+	Timestamp      time.Time       `json:"timestamp"`
+	AccountID      string          `json:"account_id"`
+	ConversationID string          `json:"conversation_id"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+// WebhookWorkerConfig controls webhook delivery and retries
+type WebhookWorkerConfig struct {
+	// How often to queue new events and send due deliveries
+	PollInterval time.Duration
+
+	// Per-request timeout
+	Timeout time.Duration
+
+	// Deliveries are retried with exponential backoff from BaseBackoff up to
+	// MaxBackoff, and marked failed after MaxAttempts
+	MaxAttempts int32
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	// A webhook is disabled after this many failed attempts in a row
+	DisableAfter int32
+
+	// Events queued per webhook, and deliveries sent, per pass
+	BatchSize int32
+
+	// Deliveries sent at the same time
+	Concurrency int
+
+	// Delivered and failed deliveries are deleted this long after their last
+	// attempt
+	Retention time.Duration
+
+	// Deliver to loopback and private network addresses too. Only for local
+	// development: it lets webhooks reach services on the backend's network.
+	AllowPrivateAddresses bool
+}
+
+// WebhookWorker queues users' events for their webhooks and delivers them
+type WebhookWorker struct {
+	webhookRepo repo.WebhookRepository
+	eventRepo   repo.EventRepository
+	client      *http.Client
+	config      WebhookWorkerConfig
+	logger      *zap.Logger
+	now         func() time.Time
+
+	lastPruned time.Time // When finished deliveries were last deleted
+}
+
+// How often finished deliveries are deleted
+const webhookPruneInterval = time.Hour
+
+// NewWebhookWorker creates a new webhook worker
+func NewWebhookWorker(webhookRepo repo.WebhookRepository, eventRepo repo.EventRepository, config WebhookWorkerConfig, logger *zap.Logger) *WebhookWorker {
+	if config.PollInterval <= 0 {
+		config.PollInterval = 2 * time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 8
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = 10 * time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = time.Hour
+	}
+	if config.DisableAfter <= 0 {
+		config.DisableAfter = 20
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 8
+	}
+	if config.Retention <= 0 {
+		config.Retention = 7 * 24 * time.Hour
+	}
+
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivateAddresses {
+		dialer.Control = publicAddressOnly
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the connection, out of reach of the address check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &WebhookWorker{
+		webhookRepo: webhookRepo,
+		eventRepo:   eventRepo,
+		client: &http.Client{
+			Transport: transport,
+			// A redirect is reported as a failed delivery rather than
+			// followed, so it can't lead past the address check either
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		config: config,
+		logger: logger.Named("webhook_worker"),
+		now:    time.Now,
+	}
+}
+
+// Start delivers webhooks until ctx is done
+func (w *WebhookWorker) Start(ctx context.Context) {
+	w.logger.Info("Starting webhook worker",
+		zap.Duration("poll_interval", w.config.PollInterval),
+		zap.Int32("max_attempts", w.config.MaxAttempts),
+		zap.Int32("disable_after", w.config.DisableAfter))
+	defer w.logger.Info("Webhook worker stopped")
+
+	ticker := time.NewTicker(w.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("Webhook pass failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce queues new events for every enabled webhook, then sends the
+// deliveries that are due. Finished deliveries past retention are deleted
+// once every webhookPruneInterval.
+func (w *WebhookWorker) RunOnce(ctx context.Context) error {
+	if err := w.queueEvents(ctx); err != nil {
+		return err
+	}
+	if err := w.deliverDue(ctx); err != nil {
+		return err
+	}
+	return w.pruneDeliveries(ctx)
+}
+
+// pruneDeliveries deletes finished deliveries older than the retention, if
+// it wasn't done within webhookPruneInterval
+func (w *WebhookWorker) pruneDeliveries(ctx context.Context) error {
+	now := w.now()
+	if now.Sub(w.lastPruned) < webhookPruneInterval {
+		return nil
+	}
+
+	deleted, err := w.webhookRepo.DeleteFinishedWebhookDeliveries(ctx, now.Add(-w.config.Retention))
+	if err != nil {
+		return err
+	}
+	w.lastPruned = now
+	if deleted > 0 {
+		w.logger.Info("Deleted finished webhook deliveries", zap.Int64("deleted", deleted))
+	}
+	return nil
+}
+
+// queueEvents records a delivery for each event after a webhook's cursor
+func (w *WebhookWorker) queueEvents(ctx context.Context) error {
+	webhooks, err := w.webhookRepo.ListEnabledWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		if err := w.queueWebhookEvents(ctx, webhook); err != nil {
+			w.logger.Error("Failed to queue webhook events",
+				zap.String("webhook_id", webhook.ID.String()),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (w *WebhookWorker) queueWebhookEvents(ctx context.Context, webhook repo.Webhook) error {
+	pending, err := w.eventRepo.GetEventsSince(ctx, repo.GetEventsSinceParams{
+		AccountID: AccountIDForUser(webhook.UserID),
+		Seq:       webhook.LastEventSeq,
+		Limit:     w.config.BatchSize,
+		Types:     webhook.EventTypes,
+	})
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// Deliveries only reference their event, so an event removed by
+	// retention or message expiry isn't kept on here
+	deliveries := make([]repo.NewWebhookDelivery, 0, len(pending))
+	for _, event := range pending {
+		deliveries = append(deliveries, repo.NewWebhookDelivery{
+			EventSeq:  event.Seq,
+			EventType: event.Type,
+		})
+	}
+
+	return w.webhookRepo.QueueWebhookDeliveries(ctx, repo.QueueWebhookDeliveriesParams{
+		WebhookID:    webhook.ID,
+		Deliveries:   deliveries,
+		LastEventSeq: pending[len(pending)-1].Seq,
+	})
+}
+
+// deliverDue sends due deliveries, a few at a time
+func (w *WebhookWorker) deliverDue(ctx context.Context) error {
+	now := w.now()
+	due, err := w.webhookRepo.ClaimDueWebhookDeliveries(ctx, repo.ClaimWebhookDeliveriesParams{
+		Now: now,
+		// Held long enough for the request to finish or time out
+		LeaseUntil: now.Add(2 * w.config.Timeout),
+		Limit:      w.config.BatchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	sem := make(chan struct{}, w.config.Concurrency)
+	var wg sync.WaitGroup
+	for _, delivery := range due {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(delivery repo.DueWebhookDelivery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			w.deliver(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// deliver sends one delivery and records the outcome
+func (w *WebhookWorker) deliver(ctx context.Context, delivery repo.DueWebhookDelivery) {
+	if !delivery.EventFound {
+		w.dropDelivery(ctx, delivery)
+		return
+	}
+
+	statusCode, sendErr := w.send(ctx, delivery)
+	attempts := delivery.Attempts + 1
+
+	params := repo.RecordWebhookAttemptParams{
+		DeliveryID:    delivery.ID,
+		WebhookID:     delivery.WebhookID,
+		Status:        WebhookDeliveryDelivered,
+		Succeeded:     sendErr == nil,
+		NextAttemptAt: w.now(),
+		DisableAfter:  w.config.DisableAfter,
+	}
+	if statusCode != 0 {
+		params.StatusCode = sql.NullInt32{Int32: int32(statusCode), Valid: true}
+	}
+	if sendErr != nil {
+		params.Error = sql.NullString{String: webhookAttemptError(statusCode, sendErr), Valid: true}
+
+		if attempts >= w.config.MaxAttempts {
+			params.Status = WebhookDeliveryFailed
+		} else {
+			params.Status = WebhookDeliveryPending
+			params.NextAttemptAt = params.NextAttemptAt.Add(w.backoff(attempts))
+		}
+	}
+
+	disabled, err := w.webhookRepo.RecordWebhookAttempt(ctx, params)
+	if err != nil {
+		w.logger.Error("Failed to record webhook attempt",
+			zap.Int64("delivery_id", delivery.ID),
+			zap.Error(err))
+		return
+	}
+
+	if sendErr != nil {
+		w.logger.Warn("Webhook delivery failed",
+			zap.Int64("delivery_id", delivery.ID),
+			zap.String("webhook_id", delivery.WebhookID.String()),
+			zap.Int32("attempts", attempts),
+			zap.String("status", params.Status),
+			zap.Error(sendErr))
+	}
+	if disabled {
+		w.logger.Warn("Webhook disabled after repeated failures",
+			zap.String("webhook_id", delivery.WebhookID.String()),
+			zap.Int32("failures", w.config.DisableAfter))
+	}
+}
+
+// dropDelivery marks failed a delivery whose event is gone, without sending
+// anything
+func (w *WebhookWorker) dropDelivery(ctx context.Context, delivery repo.DueWebhookDelivery) {
+	_, err := w.webhookRepo.RecordWebhookAttempt(ctx, repo.RecordWebhookAttemptParams{
+		DeliveryID:    delivery.ID,
+		WebhookID:     delivery.WebhookID,
+		Status:        WebhookDeliveryFailed,
+		Error:         sql.NullString{String: "event no longer exists", Valid: true},
+		NextAttemptAt: w.now(),
+		Skipped:       true,
+	})
+	if err != nil {
+		w.logger.Error("Failed to drop webhook delivery",
+			zap.Int64("delivery_id", delivery.ID),
+			zap.Error(err))
+	}
+}
+
+// send POSTs a delivery's event, returning the response status if there was
+// one
+func (w *WebhookWorker) send(ctx context.Context, delivery repo.DueWebhookDelivery) (int, error) {
+	event := delivery.Event
+	body, err := json.Marshal(webhookEvent{
+		ID:             event.ID.String(),
+		Seq:            event.Seq,
+		Type:           event.Type,
+		Timestamp:      event.Ts,
+		AccountID:      event.AccountID,
+		ConversationID: event.ConvoID,
+		Payload:        event.Payload,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal event %d: %w", event.Seq, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Tennex-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(delivery.Secret, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the wait before the attempt after the given number of
+// failed attempts
+func (w *WebhookWorker) backoff(attempts int32) time.Duration {
+	delay := w.config.BaseBackoff
+	for i := int32(1); i < attempts && delay < w.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > w.config.MaxBackoff {
+		delay = w.config.MaxBackoff
+	}
+	return delay
+}
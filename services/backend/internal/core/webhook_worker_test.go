@@ -0,0 +1,515 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/pkg/events"
+)
+
+// memoryWebhookRepo keeps webhooks and deliveries in memory, following the
+// same rules as the Postgres repository
+type memoryWebhookRepo struct {
+	repo.WebhookRepository
+
+	mu         sync.Mutex
+	webhooks   map[uuid.UUID]*repo.Webhook
+	deliveries []*repo.WebhookDelivery
+	events     *memoryEventRepo // Where claimed deliveries' events are looked up
+}
+
+func newMemoryWebhookRepo(webhooks ...repo.Webhook) *memoryWebhookRepo {
+	r := &memoryWebhookRepo{webhooks: make(map[uuid.UUID]*repo.Webhook)}
+	for i := range webhooks {
+		r.webhooks[webhooks[i].ID] = &webhooks[i]
+	}
+	return r
+}
+
+func (r *memoryWebhookRepo) ListEnabledWebhooks(ctx context.Context) ([]repo.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var enabled []repo.Webhook
+	for _, webhook := range r.webhooks {
+		if webhook.Enabled {
+			enabled = append(enabled, *webhook)
+		}
+	}
+	return enabled, nil
+}
+
+func (r *memoryWebhookRepo) QueueWebhookDeliveries(ctx context.Context, params repo.QueueWebhookDeliveriesParams) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, delivery := range params.Deliveries {
+		r.deliveries = append(r.deliveries, &repo.WebhookDelivery{
+			ID:        int64(len(r.deliveries) + 1),
+			WebhookID: params.WebhookID,
+			EventSeq:  delivery.EventSeq,
+			EventType: delivery.EventType,
+			Status:    WebhookDeliveryPending,
+		})
+	}
+	webhook := r.webhooks[params.WebhookID]
+	webhook.LastEventSeq = max(webhook.LastEventSeq, params.LastEventSeq)
+	return nil
+}
+
+func (r *memoryWebhookRepo) ClaimDueWebhookDeliveries(ctx context.Context, params repo.ClaimWebhookDeliveriesParams) ([]repo.DueWebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []repo.DueWebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery == nil {
+			continue
+		}
+		webhook := r.webhooks[delivery.WebhookID]
+		if delivery.Status != WebhookDeliveryPending || delivery.NextAttemptAt.After(params.Now) || !webhook.Enabled {
+			continue
+		}
+		delivery.NextAttemptAt = params.LeaseUntil
+		claimed := repo.DueWebhookDelivery{WebhookDelivery: *delivery, URL: webhook.URL, Secret: webhook.Secret}
+		for _, event := range r.events.events {
+			if event.Seq == delivery.EventSeq {
+				claimed.Event, claimed.EventFound = event, true
+			}
+		}
+		due = append(due, claimed)
+	}
+	return due, nil
+}
+
+func (r *memoryWebhookRepo) RecordWebhookAttempt(ctx context.Context, params repo.RecordWebhookAttemptParams) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery := r.deliveries[params.DeliveryID-1]
+	delivery.Status = params.Status
+	delivery.Attempts++
+	delivery.NextAttemptAt = params.NextAttemptAt
+	delivery.LastStatusCode = params.StatusCode
+	delivery.LastError = params.Error
+	delivery.UpdatedAt = params.NextAttemptAt
+
+	webhook := r.webhooks[params.WebhookID]
+	if params.Skipped {
+		return false, nil
+	}
+	if params.Succeeded {
+		webhook.ConsecutiveFailures = 0
+		return false, nil
+	}
+	webhook.ConsecutiveFailures++
+	if webhook.Enabled && webhook.ConsecutiveFailures >= params.DisableAfter {
+		webhook.Enabled = false
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *memoryWebhookRepo) DeleteFinishedWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for i, delivery := range r.deliveries {
+		if delivery != nil && delivery.Status != WebhookDeliveryPending && delivery.UpdatedAt.Before(before) {
+			r.deliveries[i] = nil // Keeps the other deliveries at their ID
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *memoryWebhookRepo) delivery(id int64) repo.WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.deliveries[id-1]
+}
+
+func (r *memoryWebhookRepo) webhook(id uuid.UUID) repo.Webhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.webhooks[id]
+}
+
+// memoryEventRepo serves GetEventsSince from a fixed list of events
+type memoryEventRepo struct {
+	repo.EventRepository
+	events []repo.Event
+}
+
+func (r *memoryEventRepo) GetEventsSince(ctx context.Context, params repo.GetEventsSinceParams) ([]repo.Event, error) {
+	var result []repo.Event
+	for _, event := range r.events {
+		if event.AccountID != params.AccountID || event.Seq <= params.Seq {
+			continue
+		}
+		if len(params.Types) > 0 && !containsString(params.Types, event.Type) {
+			continue
+		}
+		result = append(result, event)
+	}
+	return result, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookRequest is a delivery as received by the test endpoint
+type webhookRequest struct {
+	body      []byte
+	signature string
+	eventType string
+}
+
+// webhookEndpoint is an httptest server answering deliveries with the given
+// status codes in turn, repeating the last one
+func webhookEndpoint(t *testing.T, statuses ...int) (*httptest.Server, func() []webhookRequest) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []webhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		requests = append(requests, webhookRequest{
+			body:      body,
+			signature: r.Header.Get(WebhookSignatureHeader),
+			eventType: r.Header.Get(WebhookEventHeader),
+		})
+		status := statuses[min(len(requests), len(statuses))-1]
+		mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []webhookRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]webhookRequest(nil), requests...)
+	}
+}
+
+func TestWebhookWorkerRetriesUntilDelivered(t *testing.T) {
+	server, received := webhookEndpoint(t, http.StatusInternalServerError, http.StatusOK)
+
+	userID := uuid.New()
+	webhook := repo.Webhook{
+		ID:         uuid.New(),
+		UserID:     userID,
+		URL:        server.URL,
+		Secret:     "0123456789abcdef-secret",
+		EventTypes: []string{events.TypeMessageIn},
+		Enabled:    true,
+	}
+	webhookRepo := newMemoryWebhookRepo(webhook)
+	eventRepo := &memoryEventRepo{events: []repo.Event{
+		{Seq: 1, ID: uuid.New(), Type: events.TypeMessageIn, AccountID: userID.String(), ConvoID: "123@s.whatsapp.net", Payload: json.RawMessage(`{"text":"hi"}`)},
+		{Seq: 2, ID: uuid.New(), Type: events.TypePresence, AccountID: userID.String(), Payload: json.RawMessage(`{}`)},
+	}}
+	webhookRepo.events = eventRepo
+
+	worker := NewWebhookWorker(webhookRepo, eventRepo, WebhookWorkerConfig{
+		BaseBackoff:           time.Minute,
+		DisableAfter:          5,
+		AllowPrivateAddresses: true, // The test endpoint is on loopback
+	}, zap.NewNop())
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	worker.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	// The first attempt gets a 500 and is scheduled for retry
+	if err := worker.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	delivery := webhookRepo.delivery(1)
+	if delivery.Status != WebhookDeliveryPending || delivery.Attempts != 1 {
+		t.Fatalf("expected a pending delivery after 1 attempt, got %s after %d", delivery.Status, delivery.Attempts)
+	}
+	if !delivery.LastStatusCode.Valid || delivery.LastStatusCode.Int32 != http.StatusInternalServerError {
+		t.Errorf("expected last status 500, got %+v", delivery.LastStatusCode)
+	}
+	if delivery.LastError.String != "endpoint responded with status 500" {
+		t.Errorf("expected only the status in the recorded error, got %q", delivery.LastError.String)
+	}
+	if want := clock.Add(time.Minute); !delivery.NextAttemptAt.Equal(want) {
+		t.Errorf("expected retry at %v, got %v", want, delivery.NextAttemptAt)
+	}
+	if got := webhookRepo.webhook(webhook.ID).ConsecutiveFailures; got != 1 {
+		t.Errorf("expected 1 consecutive failure, got %d", got)
+	}
+
+	// Nothing is sent before the backoff has passed
+	if err := worker.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if got := len(received()); got != 1 {
+		t.Fatalf("expected no retry before the backoff, got %d requests", got)
+	}
+
+	clock = clock.Add(time.Minute)
+	if err := worker.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	delivery = webhookRepo.delivery(1)
+	if delivery.Status != WebhookDeliveryDelivered || delivery.Attempts != 2 {
+		t.Fatalf("expected delivered after 2 attempts, got %s after %d", delivery.Status, delivery.Attempts)
+	}
+	if got := webhookRepo.webhook(webhook.ID).ConsecutiveFailures; got != 0 {
+		t.Errorf("expected failures reset after success, got %d", got)
+	}
+
+	// Both attempts carried the same signed msg_in event; presence was filtered out
+	requests := received()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	for _, req := range requests {
+		mac := hmac.New(sha256.New, []byte(webhook.Secret))
+		mac.Write(req.body)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.signature != want {
+			t.Errorf("signature %q does not match body, want %q", req.signature, want)
+		}
+		if req.eventType != events.TypeMessageIn {
+			t.Errorf("expected event header %q, got %q", events.TypeMessageIn, req.eventType)
+		}
+
+		var body webhookEvent
+		if err := json.Unmarshal(req.body, &body); err != nil {
+			t.Fatalf("invalid delivery body: %v", err)
+		}
+		if body.Seq != 1 || body.ConversationID != "123@s.whatsapp.net" || string(body.Payload) != `{"text":"hi"}` {
+			t.Errorf("unexpected delivery body: %s", req.body)
+		}
+	}
+}
+
+func TestWebhookWorkerDisablesFailingWebhook(t *testing.T) {
+	server, received := webhookEndpoint(t, http.StatusInternalServerError)
+
+	userID := uuid.New()
+	webhook := repo.Webhook{
+		ID:      uuid.New(),
+		UserID:  userID,
+		URL:     server.URL,
+		Secret:  "0123456789abcdef-secret",
+		Enabled: true,
+	}
+	webhookRepo := newMemoryWebhookRepo(webhook)
+	eventRepo := &memoryEventRepo{events: []repo.Event{
+		{Seq: 1, ID: uuid.New(), Type: events.TypeMessageIn, AccountID: userID.String(), Payload: json.RawMessage(`{}`)},
+	}}
+	webhookRepo.events = eventRepo
+
+	worker := NewWebhookWorker(webhookRepo, eventRepo, WebhookWorkerConfig{
+		BaseBackoff:           time.Minute,
+		MaxAttempts:           2,
+		DisableAfter:          2,
+		AllowPrivateAddresses: true,
+	}, zap.NewNop())
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	worker.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := worker.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+		clock = clock.Add(time.Hour)
+	}
+
+	delivery := webhookRepo.delivery(1)
+	if delivery.Status != WebhookDeliveryFailed || delivery.Attempts != 2 {
+		t.Errorf("expected failed after 2 attempts, got %s after %d", delivery.Status, delivery.Attempts)
+	}
+	if webhookRepo.webhook(webhook.ID).Enabled {
+		t.Fatal("expected the webhook to be disabled after 2 failures in a row")
+	}
+
+	// New events aren't queued or sent to a disabled webhook
+	eventRepo.events = append(eventRepo.events, repo.Event{Seq: 2, ID: uuid.New(), Type: events.TypeMessageIn, AccountID: userID.String(), Payload: json.RawMessage(`{}`)})
+	if err := worker.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if got := len(received()); got != 2 {
+		t.Errorf("expected 2 requests in total, got %d", got)
+	}
+}
+
+func TestWebhookWorkerRefusesNonPublicAddresses(t *testing.T) {
+	server, received := webhookEndpoint(t, http.StatusOK)
+
+	userID := uuid.New()
+	webhook := repo.Webhook{
+		ID:      uuid.New(),
+		UserID:  userID,
+		URL:     server.URL, // On loopback
+		Secret:  "0123456789abcdef-secret",
+		Enabled: true,
+	}
+	webhookRepo := newMemoryWebhookRepo(webhook)
+	eventRepo := &memoryEventRepo{events: []repo.Event{
+		{Seq: 1, ID: uuid.New(), Type: events.TypeMessageIn, AccountID: userID.String(), Payload: json.RawMessage(`{}`)},
+	}}
+	webhookRepo.events = eventRepo
+
+	worker := NewWebhookWorker(webhookRepo, eventRepo, WebhookWorkerConfig{}, zap.NewNop())
+	if err := worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	if got := len(received()); got != 0 {
+		t.Fatalf("expected no request to reach a loopback endpoint, got %d", got)
+	}
+	delivery := webhookRepo.delivery(1)
+	if delivery.Status != WebhookDeliveryPending || delivery.LastStatusCode.Valid ||
+		delivery.LastError.String != "destination address is not allowed" {
+		t.Errorf("expected a refused attempt without details, got %+v", delivery)
+	}
+}
+
+func TestIsPublicWebhookAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // Cloud metadata
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+	for _, tt := range tests {
+		if got := isPublicWebhookAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("isPublicWebhookAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookWorkerDropsDeliveriesOfRemovedEvents(t *testing.T) {
+	server, received := webhookEndpoint(t, http.StatusOK)
+
+	userID := uuid.New()
+	webhook := repo.Webhook{
+		ID:      uuid.New(),
+		UserID:  userID,
+		URL:     server.URL,
+		Secret:  "0123456789abcdef-secret",
+		Enabled: true,
+	}
+	webhookRepo := newMemoryWebhookRepo(webhook)
+	eventRepo := &memoryEventRepo{}
+	webhookRepo.events = eventRepo
+	if err := webhookRepo.QueueWebhookDeliveries(context.Background(), repo.QueueWebhookDeliveriesParams{
+		WebhookID:    webhook.ID,
+		Deliveries:   []repo.NewWebhookDelivery{{EventSeq: 1, EventType: events.TypeMessageIn}},
+		LastEventSeq: 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The event was deleted, e.g. as an expired message, before it was sent
+	worker := NewWebhookWorker(webhookRepo, eventRepo, WebhookWorkerConfig{AllowPrivateAddresses: true}, zap.NewNop())
+	if err := worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	if got := len(received()); got != 0 {
+		t.Errorf("expected nothing to be sent for a removed event, got %d requests", got)
+	}
+	delivery := webhookRepo.delivery(1)
+	if delivery.Status != WebhookDeliveryFailed || delivery.LastError.String != "event no longer exists" {
+		t.Errorf("expected the delivery to be dropped, got %+v", delivery)
+	}
+	if got := webhookRepo.webhook(webhook.ID).ConsecutiveFailures; got != 0 {
+		t.Errorf("expected a dropped delivery not to count as a failure, got %d", got)
+	}
+}
+
+func TestWebhookWorkerPrunesFinishedDeliveries(t *testing.T) {
+	server, _ := webhookEndpoint(t, http.StatusOK)
+
+	userID := uuid.New()
+	webhook := repo.Webhook{
+		ID:      uuid.New(),
+		UserID:  userID,
+		URL:     server.URL,
+		Secret:  "0123456789abcdef-secret",
+		Enabled: true,
+	}
+	webhookRepo := newMemoryWebhookRepo(webhook)
+	eventRepo := &memoryEventRepo{events: []repo.Event{
+		{Seq: 1, ID: uuid.New(), Type: events.TypeMessageIn, AccountID: userID.String(), Payload: json.RawMessage(`{}`)},
+	}}
+	webhookRepo.events = eventRepo
+
+	worker := NewWebhookWorker(webhookRepo, eventRepo, WebhookWorkerConfig{
+		Retention:             24 * time.Hour,
+		AllowPrivateAddresses: true,
+	}, zap.NewNop())
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	worker.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	if err := worker.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if got := webhookRepo.delivery(1).Status; got != WebhookDeliveryDelivered {
+		t.Fatalf("expected the delivery to be delivered, got %s", got)
+	}
+
+	// Kept within the retention
+	clock = clock.Add(23 * time.Hour)
+	if err := worker.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if webhookRepo.deliveries[0] == nil {
+		t.Fatal("expected the delivery to be kept within the retention")
+	}
+
+	clock = clock.Add(2 * time.Hour)
+	if err := worker.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if webhookRepo.deliveries[0] != nil {
+		t.Error("expected the delivery to be deleted after the retention")
+	}
+}
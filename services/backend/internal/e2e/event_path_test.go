@@ -0,0 +1,398 @@
+// Package e2e tests the event path across services: bridge requests replayed
+// into the backend's gRPC server, stored in Postgres, published on NATS and
+// pushed to a WebSocket client by the eventstream.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/grpc/server"
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/backend/internal/testutil"
+	gen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/events"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+func TestHistorySyncReachesWebSocketClients(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	nc := testutil.SetupTestNATS(t)
+	prefix := fmt.Sprintf("tennex.e2e%d", time.Now().UnixNano())
+	logger := zap.NewNop()
+	ctx := context.Background()
+
+	eventService := core.NewEventService(repo.NewEventRepository(pool), nc, prefix, false, logger)
+	integrationService := core.NewIntegrationService(repo.NewIntegrationRepository(pool), logger)
+	client := startIntegrationServer(t, server.NewIntegrationServer(integrationService, eventService, pool, gen.New(pool), 0, logger))
+	eventstreamURL := startEventstream(t, nc.ConnectedUrl(), prefix)
+
+	userID := createUser(t, pool)
+	ws := dialWebSocket(t, "ws"+strings.TrimPrefix(eventstreamURL, "http")+"/ws?account_id="+userID)
+	waitForSubscription(t, nc, prefix, userID, ws)
+
+	integrationID := replaySession(t, client, "testdata/history_sync", userID)
+
+	// The history landed in the database
+	var conversations, messages, replies int
+	err := pool.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM conversations WHERE user_integration_id = $1),
+			(SELECT COUNT(*) FROM messages m JOIN conversations c ON c.id = m.conversation_id WHERE c.user_integration_id = $1),
+			(SELECT COUNT(*) FROM messages m JOIN conversations c ON c.id = m.conversation_id
+				WHERE c.user_integration_id = $1 AND m.reply_to_message_id IS NOT NULL)`,
+		integrationID).Scan(&conversations, &messages, &replies)
+	if err != nil {
+		t.Fatalf("failed to count synced rows: %v", err)
+	}
+	if conversations != 2 || messages != 5 || replies != 1 {
+		t.Errorf("expected 2 conversations, 5 messages and 1 reply, got %d, %d and %d", conversations, messages, replies)
+	}
+
+	syncEvents, err := eventService.GetEventsSince(ctx, userID, 0, 100, []string{events.TypeHistorySync})
+	if err != nil {
+		t.Fatalf("GetEventsSince: %v", err)
+	}
+	syncSeqs := make(map[string]int64)
+	for _, event := range syncEvents {
+		syncSeqs[event.ConvoID] = event.Seq
+
+		var payload events.HistorySyncPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			t.Fatalf("failed to decode history sync payload: %v", err)
+		}
+		if payload.MessageCount == 0 || payload.StartTime.After(payload.EndTime) {
+			t.Errorf("unexpected history sync payload for %s: %+v", event.ConvoID, payload)
+		}
+	}
+	if len(syncSeqs) != 2 {
+		t.Fatalf("expected a history_sync event per conversation, got %+v", syncEvents)
+	}
+
+	// Each conversation's notification reached the WebSocket
+	deadline := time.Now().Add(10 * time.Second)
+	for len(syncSeqs) > 0 {
+		data, err := ws.readText(deadline)
+		if err != nil {
+			t.Fatalf("expected notifications for %v, read failed: %v", syncSeqs, err)
+		}
+
+		var notification struct {
+			Type           string `json:"type"`
+			NextSeq        int64  `json:"next_seq"`
+			ConversationID string `json:"conversation_id"`
+			IntegrationID  int32  `json:"integration_id"`
+		}
+		if err := json.Unmarshal(data, &notification); err != nil {
+			t.Fatalf("failed to decode frame %s: %v", data, err)
+		}
+		if notification.Type != "notification" || notification.ConversationID == probeConversationID {
+			continue
+		}
+
+		seq, ok := syncSeqs[notification.ConversationID]
+		if !ok {
+			t.Fatalf("unexpected notification %s", data)
+		}
+		if notification.NextSeq != seq {
+			t.Errorf("expected next_seq %d for %s, got %d", seq, notification.ConversationID, notification.NextSeq)
+		}
+		if notification.IntegrationID != integrationID {
+			t.Errorf("expected integration_id %d for %s, got %d", integrationID, notification.ConversationID, notification.IntegrationID)
+		}
+		delete(syncSeqs, notification.ConversationID)
+	}
+}
+
+// startIntegrationServer serves the integration gRPC service on a local port
+func startIntegrationServer(t *testing.T, integrationServer *server.IntegrationServer) proto.IntegrationServiceClient {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	proto.RegisterIntegrationServiceServer(grpcServer, integrationServer)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial integration server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return proto.NewIntegrationServiceClient(conn)
+}
+
+// lockedBuffer collects a subprocess's output for the test log
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// startEventstream builds and runs the eventstream service and returns its
+// base URL once it's healthy
+func startEventstream(t *testing.T, natsURL, prefix string) string {
+	t.Helper()
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not found; needed to build the eventstream")
+	}
+	bin := filepath.Join(t.TempDir(), "eventstream")
+	build := exec.Command(goBin, "build", "-o", bin, "./cmd/eventstream")
+	build.Dir = eventstreamDir()
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("failed to build eventstream: %v\n%s", err, out)
+	}
+
+	port := freePort(t)
+	cmd := exec.Command(bin)
+	cmd.Dir = t.TempDir() // No config.yaml; everything comes from the environment
+	cmd.Env = []string{
+		"TENNEX_EVENTSTREAM_HTTP_HOST=127.0.0.1",
+		fmt.Sprintf("TENNEX_EVENTSTREAM_HTTP_PORT=%d", port),
+		"TENNEX_EVENTSTREAM_NATS_URL=" + natsURL,
+		"TENNEX_EVENTSTREAM_NATS_PREFIX=" + prefix,
+		"TENNEX_EVENTSTREAM_LOG_LEVEL=debug",
+	}
+	output := &lockedBuffer{}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start eventstream: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Signal(os.Interrupt)
+		cmd.Wait()
+		if t.Failed() {
+			t.Logf("eventstream output:\n%s", output.String())
+		}
+	})
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+	deadline := time.Now().Add(15 * time.Second)
+	for {
+		resp, err := http.Get(baseURL + "/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return baseURL
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("eventstream not healthy after 15s: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// eventstreamDir locates services/eventstream relative to this source file
+func eventstreamDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "eventstream")
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func createUser(t *testing.T, pool *pgxpool.Pool) string {
+	t.Helper()
+
+	var userID uuid.UUID
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO users (username, email, password_hash)
+		VALUES ('e2e', 'e2e@example.com', 'x')
+		RETURNING id`).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return userID.String()
+}
+
+const probeConversationID = "e2e-probe"
+
+// waitForSubscription publishes probe notifications until one reaches the
+// WebSocket, so that later notifications can't race the eventstream's NATS
+// subscription. The probes go to integration 0; the eventstream subscribes to
+// all of the account's integrations.
+func waitForSubscription(t *testing.T, nc *nats.Conn, prefix, accountID string, ws *wsConn) {
+	t.Helper()
+
+	subject := prefix + ".notify.user." + accountID + ".integration.0"
+	probe := fmt.Sprintf(`{"account_id":%q,"integration_id":0,"conversation_id":%q,"next_seq":0}`, accountID, probeConversationID)
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if err := nc.Publish(subject, []byte(probe)); err != nil {
+			t.Fatalf("failed to publish probe: %v", err)
+		}
+
+		data, err := ws.readText(time.Now().Add(200 * time.Millisecond))
+		if errors.Is(err, context.DeadlineExceeded) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("failed to read probe: %v", err)
+		}
+		if bytes.Contains(data, []byte(probeConversationID)) {
+			return
+		}
+	}
+	t.Fatal("eventstream didn't deliver a probe notification within 10s")
+}
+
+// recordedSession is the manifest written by the bridge's request recorder
+type recordedSession struct {
+	Recordings []struct {
+		RequestType string `json:"request_type"`
+		PayloadFile string `json:"payload_file"`
+	} `json:"recordings"`
+}
+
+// replaySession sends a recorded bridge session to the integration service
+// the way the bridge does, as userID. It returns the integration created.
+func replaySession(t *testing.T, client proto.IntegrationServiceClient, dir, userID string) int32 {
+	t.Helper()
+	ctx := context.Background()
+
+	manifest, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	var session recordedSession
+	if err := json.Unmarshal(manifest, &session); err != nil {
+		t.Fatalf("failed to decode manifest: %v", err)
+	}
+
+	// IDs in the recording belong to the database it was recorded against
+	var integrationID int32
+	rebind := func(integrationCtx *proto.IntegrationContext) *proto.IntegrationContext {
+		return &proto.IntegrationContext{
+			UserId:            userID,
+			UserIntegrationId: integrationID,
+			IntegrationType:   integrationCtx.GetIntegrationType(),
+			PlatformUserId:    integrationCtx.GetPlatformUserId(),
+		}
+	}
+
+	for _, recording := range session.Recordings {
+		payload, err := os.ReadFile(filepath.Join(dir, recording.PayloadFile))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", recording.PayloadFile, err)
+		}
+
+		switch recording.RequestType {
+		case "CreateUserIntegration":
+			var req proto.CreateUserIntegrationRequest
+			unmarshalRecording(t, recording.PayloadFile, payload, &req)
+			req.UserId = userID
+			resp, err := client.CreateUserIntegration(ctx, &req)
+			if err != nil {
+				t.Fatalf("CreateUserIntegration: %v", err)
+			}
+			integrationID = resp.UserIntegrationId
+
+		case "SyncConversations":
+			var req proto.SyncConversationsRequest
+			unmarshalRecording(t, recording.PayloadFile, payload, &req)
+			req.Context = rebind(req.Context)
+			stream, err := client.SyncConversations(ctx)
+			if err != nil {
+				t.Fatalf("SyncConversations: %v", err)
+			}
+			if err := stream.Send(&req); err != nil {
+				t.Fatalf("SyncConversations send: %v", err)
+			}
+			closeSyncStream(t, "SyncConversations", stream, &proto.SyncConversationsResponse{})
+
+		case "SyncMessages":
+			var req proto.SyncMessagesRequest
+			unmarshalRecording(t, recording.PayloadFile, payload, &req)
+			req.Context = rebind(req.Context)
+			stream, err := client.SyncMessages(ctx)
+			if err != nil {
+				t.Fatalf("SyncMessages: %v", err)
+			}
+			if err := stream.Send(&req); err != nil {
+				t.Fatalf("SyncMessages send: %v", err)
+			}
+			closeSyncStream(t, "SyncMessages", stream, &proto.SyncMessagesResponse{})
+
+		default:
+			t.Fatalf("%s: replaying %s isn't supported", recording.PayloadFile, recording.RequestType)
+		}
+	}
+
+	if integrationID == 0 {
+		t.Fatal("recording didn't create an integration")
+	}
+	return integrationID
+}
+
+func unmarshalRecording(t *testing.T, name string, payload []byte, req protobuf.Message) {
+	t.Helper()
+	if err := protobuf.Unmarshal(payload, req); err != nil {
+		t.Fatalf("failed to unmarshal %s: %v", name, err)
+	}
+}
+
+// closeSyncStream waits for the acknowledgement of the batch just sent, then
+// closes the stream and waits for the server to finish with it. ack receives
+// the acknowledgement.
+func closeSyncStream(t *testing.T, method string, stream grpc.ClientStream, ack protobuf.Message) {
+	t.Helper()
+	if err := stream.RecvMsg(ack); err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("%s close: %v", method, err)
+	}
+	if err := stream.RecvMsg(ack); err != io.EOF {
+		t.Fatalf("%s: expected the stream to end, got %v", method, err)
+	}
+}
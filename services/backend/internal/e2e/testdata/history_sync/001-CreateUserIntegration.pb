@@ -0,0 +1,5 @@
+
+$5f1c2a9b-3d4e-4f60-8a7b-9c0d1e2f3a4bwhatsapp972501234567@s.whatsapp.net2+
+	device_id972501234567:12@s.whatsapp.net2
+platform_typedesktop2
+qr_codes_issued1
\ No newline at end of file
@@ -0,0 +1,5 @@
+
+O
+$5f1c2a9b-3d4e-4f60-8a7b-9c0d1e2f3a4bwhatsapp"972501234567@s.whatsapp.netINITIAL_BOOTSTRAP/
+972509876543@s.whatsapp.netDanaPj-
+120363025246125486@g.usFamily j𛍾 (
\ No newline at end of file
@@ -0,0 +1,6 @@
+
+O
+$5f1c2a9b-3d4e-4f60-8a7b-9c0d1e2f3a4bwhatsapp"972501234567@s.whatsapp.net972509876543@s.whatsapp.net
+3EB0A1B2C3D4E5F60001972509876543@s.whatsapp.net972509876543@s.whatsapp.net"Dana*8BAre we still on for tomorrow?}
+3EB0A1B2C3D4E5F60002972509876543@s.whatsapp.net972501234567@s.whatsapp.net*8B	Yes, 10amHj3EB0A1B2C3D4E5F60001p
+3EB0A1B2C3D4E5F60003972509876543@s.whatsapp.net972509876543@s.whatsapp.net"Dana*8BGreat, see you (
\ No newline at end of file
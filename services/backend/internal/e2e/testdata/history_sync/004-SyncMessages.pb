@@ -0,0 +1,5 @@
+
+O
+$5f1c2a9b-3d4e-4f60-8a7b-9c0d1e2f3a4bwhatsapp"972501234567@s.whatsapp.net120363025246125486@g.usn
+3EB0F9E8D7C6B5A40001120363025246125486@g.us972505555555@s.whatsapp.net"Mom*8BDinner on Friday?g
+3EB0F9E8D7C6B5A40002120363025246125486@g.us972501234567@s.whatsapp.net*𛍾8BI'll be thereH (
\ No newline at end of file
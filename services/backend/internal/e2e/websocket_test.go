@@ -0,0 +1,77 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+// wsConn reads the eventstream's text messages in the background. Reading
+// with a deadline on the connection itself would close it when the deadline
+// passes, and waitForSubscription waits in short rounds.
+type wsConn struct {
+	messages <-chan []byte
+	done     <-chan struct{}
+	err      error
+}
+
+func dialWebSocket(t *testing.T, url string) *wsConn {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial %s: %v", url, err)
+	}
+
+	messages := make(chan []byte, 16)
+	done := make(chan struct{})
+	ws := &wsConn{messages: messages, done: done}
+	go func() {
+		defer close(done)
+		for {
+			_, data, err := conn.Read(context.Background())
+			if err != nil {
+				ws.err = err
+				return
+			}
+			messages <- data
+		}
+	}()
+	t.Cleanup(func() {
+		conn.Close(websocket.StatusNormalClosure, "")
+		for {
+			select {
+			case <-messages:
+			case <-done:
+				return
+			}
+		}
+	})
+
+	return ws
+}
+
+// readText returns the next text message, or context.DeadlineExceeded if none
+// arrives before the deadline
+func (c *wsConn) readText(deadline time.Time) ([]byte, error) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case data := <-c.messages:
+		return data, nil
+	case <-c.done:
+		select {
+		case data := <-c.messages:
+			return data, nil
+		default:
+			return nil, c.err
+		}
+	case <-timer.C:
+		return nil, context.DeadlineExceeded
+	}
+}
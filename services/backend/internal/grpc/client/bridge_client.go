@@ -0,0 +1,208 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/tennex/pkg/requestid"
+	"github.com/tennex/shared/auth"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+// Per-RPC deadlines for calls to the bridge
+const (
+	sendMessageTimeout = 15 * time.Second
+	sendMediaTimeout   = 2 * time.Minute // Covers uploading the file to WhatsApp
+	markReadTimeout    = 10 * time.Second
+	logoutTimeout      = 10 * time.Second
+	resyncTimeout      = 60 * time.Second
+	listGroupsTimeout  = 30 * time.Second
+	chatStateTimeout   = 15 * time.Second
+	presenceTimeout    = 15 * time.Second
+)
+
+// BridgeClient calls the bridge control service for backend-initiated operations
+type BridgeClient struct {
+	client proto.BridgeControlServiceClient
+	conn   *grpc.ClientConn
+	logger *zap.Logger
+}
+
+// NewBridgeClient creates a bridge gRPC client that authenticates with the
+// shared service token. The connection is plaintext unless opts set other
+// transport credentials; opts are added after the client's own.
+func NewBridgeClient(bridgeAddr, token string, logger *zap.Logger, opts ...grpc.DialOption) (*BridgeClient, error) {
+	opts = append(append(requestid.DialOptions(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(auth.ServiceTokenCredentials{Token: token, AllowInsecure: true}),
+	), opts...)
+	conn, err := grpc.NewClient(bridgeAddr, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bridge client for %s: %w", bridgeAddr, err)
+	}
+
+	return &BridgeClient{
+		client: proto.NewBridgeControlServiceClient(conn),
+		conn:   conn,
+		logger: logger.Named("bridge_client"),
+	}, nil
+}
+
+// Close closes the gRPC connection
+func (c *BridgeClient) Close() error {
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// SendMessage sends a message through the account's WhatsApp session and returns the WhatsApp message ID
+func (c *BridgeClient) SendMessage(ctx context.Context, req *proto.SendMessageRequest) (string, error) {
+	timeout := sendMessageTimeout
+	if req.GetContent().GetText() == nil {
+		timeout = sendMediaTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := c.client.SendMessage(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call bridge SendMessage: %w", err)
+	}
+	if !resp.Success {
+		return "", fmt.Errorf("bridge failed to send message: %s", resp.Error)
+	}
+
+	c.logger.Debug("Message sent through bridge",
+		zap.String("account_id", req.AccountId),
+		zap.String("client_msg_uuid", req.ClientMsgUuid),
+		zap.String("wa_message_id", resp.WaMessageId))
+
+	return resp.WaMessageId, nil
+}
+
+// MarkRead marks messages in a conversation as read
+func (c *BridgeClient) MarkRead(ctx context.Context, accountID, convoID, senderJID string, waMessageIDs []string) error {
+	ctx, cancel := context.WithTimeout(ctx, markReadTimeout)
+	defer cancel()
+
+	resp, err := c.client.MarkRead(ctx, &proto.MarkReadRequest{
+		AccountId:    accountID,
+		ConvoId:      convoID,
+		SenderJid:    senderJID,
+		WaMessageIds: waMessageIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to call bridge MarkRead: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("bridge failed to mark messages as read: %s", resp.Error)
+	}
+	return nil
+}
+
+// Logout logs the account out of WhatsApp
+func (c *BridgeClient) Logout(ctx context.Context, accountID string) error {
+	ctx, cancel := context.WithTimeout(ctx, logoutTimeout)
+	defer cancel()
+
+	resp, err := c.client.Logout(ctx, &proto.LogoutRequest{AccountId: accountID})
+	if err != nil {
+		return fmt.Errorf("failed to call bridge Logout: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("bridge failed to log out: %s", resp.Error)
+	}
+	return nil
+}
+
+// TriggerResync asks the bridge to re-fetch app state for the account
+func (c *BridgeClient) TriggerResync(ctx context.Context, accountID string, fullSync bool) error {
+	ctx, cancel := context.WithTimeout(ctx, resyncTimeout)
+	defer cancel()
+
+	resp, err := c.client.TriggerResync(ctx, &proto.TriggerResyncRequest{
+		AccountId: accountID,
+		FullSync:  fullSync,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to call bridge TriggerResync: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("bridge failed to resync: %s", resp.Error)
+	}
+	return nil
+}
+
+// ListGroups lists the account's WhatsApp groups; the bridge also syncs them as conversations
+func (c *BridgeClient) ListGroups(ctx context.Context, accountID string) ([]*proto.Group, error) {
+	ctx, cancel := context.WithTimeout(ctx, listGroupsTimeout)
+	defer cancel()
+
+	resp, err := c.client.ListGroups(ctx, &proto.ListGroupsRequest{AccountId: accountID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call bridge ListGroups: %w", err)
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("bridge failed to list groups: %s", resp.Error)
+	}
+	return resp.Groups, nil
+}
+
+// SetConversationState pins, archives or mutes a conversation on WhatsApp
+func (c *BridgeClient) SetConversationState(ctx context.Context, req *proto.SetConversationStateRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, chatStateTimeout)
+	defer cancel()
+
+	resp, err := c.client.SetConversationState(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to call bridge SetConversationState: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("bridge failed to set conversation state: %s", resp.Error)
+	}
+	return nil
+}
+
+// SubscribePresence starts forwarding the presence of the given contacts and
+// returns every JID the account is now subscribed to
+func (c *BridgeClient) SubscribePresence(ctx context.Context, accountID string, jids []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, presenceTimeout)
+	defer cancel()
+
+	resp, err := c.client.SubscribePresence(ctx, &proto.SubscribePresenceRequest{
+		AccountId: accountID,
+		Jids:      jids,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call bridge SubscribePresence: %w", err)
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("bridge failed to subscribe to presence: %s", resp.Error)
+	}
+	return resp.SubscribedJids, nil
+}
+
+// UnsubscribePresence stops forwarding the presence of the given contacts and
+// returns every JID the account is still subscribed to
+func (c *BridgeClient) UnsubscribePresence(ctx context.Context, accountID string, jids []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, presenceTimeout)
+	defer cancel()
+
+	resp, err := c.client.UnsubscribePresence(ctx, &proto.UnsubscribePresenceRequest{
+		AccountId: accountID,
+		Jids:      jids,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call bridge UnsubscribePresence: %w", err)
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("bridge failed to unsubscribe from presence: %s", resp.Error)
+	}
+	return resp.SubscribedJids, nil
+}
@@ -2,12 +2,17 @@ package server
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/pkg/requestid"
 	proto "github.com/tennex/shared/proto/gen/proto"
 )
 
@@ -32,9 +37,14 @@ func NewBridgeServer(eventService *core.EventService, outboxService *core.Outbox
 	}
 }
 
+// log returns the server's logger tagged with ctx's request ID
+func (s *BridgeServer) log(ctx context.Context) *zap.Logger {
+	return requestid.Logger(ctx, s.logger)
+}
+
 // UpdateAccountStatus updates account status and info via gRPC
 func (s *BridgeServer) UpdateAccountStatus(ctx context.Context, req *proto.UpdateAccountStatusRequest) (*proto.UpdateAccountStatusResponse, error) {
-	s.logger.Debug("UpdateAccountStatus gRPC call received",
+	s.log(ctx).Debug("UpdateAccountStatus gRPC call received",
 		zap.String("account_id", req.AccountId),
 		zap.String("status", req.Status.String()))
 
@@ -53,36 +63,81 @@ func (s *BridgeServer) UpdateAccountStatus(ctx context.Context, req *proto.Updat
 	}
 
 	// Convert proto status to string
-	status := convertProtoStatusToString(req.Status)
+	accountStatus := convertProtoStatusToString(req.Status)
 
-	// Parse account ID as UUID
-	userID, err := uuid.Parse(req.AccountId)
+	// The account must belong to a user, and the integrations updated are
+	// that user's
+	userID, err := s.accountService.GetAccountOwner(ctx, req.AccountId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "unknown account %q", req.AccountId)
+	}
 	if err != nil {
-		s.logger.Error("Failed to parse account_id as UUID",
+		s.log(ctx).Error("Failed to get account owner",
 			zap.String("account_id", req.AccountId),
 			zap.Error(err))
 		return nil, err
 	}
 
+	// Without a JID the bridge is reporting on the whole account, e.g. a
+	// disconnect requested through its API
+	if waJid == "" {
+		if err := s.updateWhatsAppStatuses(ctx, userID, accountStatus, lastSeen); err != nil {
+			s.log(ctx).Error("Failed to update WhatsApp integrations",
+				zap.String("account_id", req.AccountId),
+				zap.Error(err))
+			return nil, err
+		}
+		return &proto.UpdateAccountStatusResponse{
+			Success: true,
+		}, nil
+	}
+
+	// A WhatsApp account connected to another user must not be taken over
+	existing, err := s.integrationService.GetIntegrationByExternalID(ctx, core.IntegrationTypeWhatsApp, waJid)
+	if err == nil && existing.UserID != userID {
+		s.log(ctx).Warn("Rejected status update for another user's integration",
+			zap.String("account_id", req.AccountId),
+			zap.Int32("integration_id", existing.ID))
+		return nil, status.Errorf(codes.PermissionDenied, "WhatsApp account %s is connected to another user", waJid)
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
 	// Use UpsertUserIntegration to create or update the WhatsApp integration
-	_, err = s.integrationService.UpsertUserIntegration(ctx, userID, core.IntegrationTypeWhatsApp, waJid, displayName, avatarUrl, status, nil, lastSeen)
+	_, err = s.integrationService.UpsertUserIntegration(ctx, userID, core.IntegrationTypeWhatsApp, waJid, displayName, avatarUrl, accountStatus, nil, lastSeen)
 	if err != nil {
-		s.logger.Error("Failed to upsert WhatsApp integration",
+		s.log(ctx).Error("Failed to upsert WhatsApp integration",
 			zap.String("account_id", req.AccountId),
 			zap.Error(err))
 		return nil, err
 	}
 
-	s.logger.Info("WhatsApp integration updated successfully",
+	s.log(ctx).Info("WhatsApp integration updated successfully",
 		zap.String("account_id", req.AccountId),
 		zap.String("wa_jid", waJid),
-		zap.String("status", status))
+		zap.String("status", accountStatus))
 
 	return &proto.UpdateAccountStatusResponse{
 		Success: true,
 	}, nil
 }
 
+// updateWhatsAppStatuses sets the status of each of a user's WhatsApp integrations
+func (s *BridgeServer) updateWhatsAppStatuses(ctx context.Context, userID uuid.UUID, status string, lastSeen *time.Time) error {
+	integrations, err := s.integrationService.GetWhatsAppIntegrations(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, integration := range integrations {
+		if err := s.integrationService.UpdateIntegrationStatus(ctx, integration.ID, status, lastSeen, "", nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Helper functions for protobuf conversion
 
 func convertProtoStatusToString(status proto.AccountStatus) string {
@@ -3,15 +3,29 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/repo"
 	gen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/events"
+	"github.com/tennex/pkg/requestid"
 	proto "github.com/tennex/shared/proto/gen/proto"
 )
 
@@ -19,30 +33,90 @@ import (
 type IntegrationServer struct {
 	proto.UnimplementedIntegrationServiceServer
 	integrationService *core.IntegrationService
+	eventService       *core.EventService
+	pool               *pgxpool.Pool
 	db                 *gen.Queries
+	maxSyncBatchSize   int
+	syncCursors        *syncCursors
 	logger             *zap.Logger
 }
 
-// NewIntegrationServer creates a new integration gRPC server
-func NewIntegrationServer(integrationService *core.IntegrationService, db *gen.Queries, logger *zap.Logger) *IntegrationServer {
+// DefaultMaxSyncBatchSize is the most conversations, contacts or messages a
+// sync stream message may carry when no maximum is configured. The bridge
+// sends at most 200.
+const DefaultMaxSyncBatchSize = 1000
+
+// NewIntegrationServer creates a new integration gRPC server. eventService may
+// be nil, in which case conversation state changes and history syncs aren't
+// published. maxSyncBatchSize <= 0 uses DefaultMaxSyncBatchSize.
+func NewIntegrationServer(integrationService *core.IntegrationService, eventService *core.EventService, pool *pgxpool.Pool, db *gen.Queries, maxSyncBatchSize int, logger *zap.Logger) *IntegrationServer {
+	if maxSyncBatchSize <= 0 {
+		maxSyncBatchSize = DefaultMaxSyncBatchSize
+	}
 	return &IntegrationServer{
 		integrationService: integrationService,
+		eventService:       eventService,
+		pool:               pool,
 		db:                 db,
+		maxSyncBatchSize:   maxSyncBatchSize,
+		syncCursors:        newSyncCursors(syncCursorsSize, syncCursorTTL),
 		logger:             logger.Named("integration_server"),
 	}
 }
 
+// log returns the server's logger tagged with ctx's request ID
+func (s *IntegrationServer) log(ctx context.Context) *zap.Logger {
+	return requestid.Logger(ctx, s.logger)
+}
+
+// checkSyncBatch rejects a sync stream message carrying more items than the
+// configured maximum, so a misbehaving client can't make the server hold an
+// arbitrarily large batch
+func (s *IntegrationServer) checkSyncBatch(kind string, size int) error {
+	if size <= s.maxSyncBatchSize {
+		return nil
+	}
+	s.logger.Warn("Rejecting oversized sync batch",
+		zap.String("kind", kind),
+		zap.Int("size", size),
+		zap.Int("max_size", s.maxSyncBatchSize))
+	return status.Errorf(codes.InvalidArgument, "batch of %d %s exceeds the maximum of %d", size, kind, s.maxSyncBatchSize)
+}
+
+// storeSyncBatch runs store for a batch of a sync stream, unless the sync was
+// resumed on a new stream and the batch was already stored. Without a sync ID
+// the batch is always stored. It returns whether store ran.
+func (s *IntegrationServer) storeSyncBatch(ctx context.Context, syncID string, batch int32, store func()) bool {
+	if syncID == "" {
+		store()
+		return true
+	}
+
+	done, pending := s.syncCursors.begin(syncID, batch)
+	if pending {
+		store()
+	}
+	// A batch cut short by the stream breaking is stored again when resent
+	done(ctx.Err() == nil)
+	return pending
+}
+
 // CreateUserIntegration creates a new user integration
 func (s *IntegrationServer) CreateUserIntegration(ctx context.Context, req *proto.CreateUserIntegrationRequest) (*proto.CreateUserIntegrationResponse, error) {
-	s.logger.Debug("CreateUserIntegration gRPC call received",
+	s.log(ctx).Debug("CreateUserIntegration gRPC call received",
 		zap.String("user_id", req.UserId),
 		zap.String("integration_type", req.IntegrationType))
 
 	userID, err := uuid.Parse(req.UserId)
 	if err != nil {
-		s.logger.Error("Failed to parse user_id as UUID", zap.Error(err))
+		s.log(ctx).Error("Failed to parse user_id as UUID", zap.Error(err))
 		return nil, fmt.Errorf("invalid user_id format: %w", err)
 	}
+	// A user can connect several accounts of a type; the platform user ID
+	// says which one this is
+	if req.PlatformUserId == "" {
+		return nil, fmt.Errorf("platform_user_id is required")
+	}
 
 	// Convert metadata map to JSON
 	var metadataJSON json.RawMessage = []byte("{}")
@@ -54,8 +128,82 @@ func (s *IntegrationServer) CreateUserIntegration(ctx context.Context, req *prot
 		metadataJSON = data
 	}
 
-	// Upsert the integration
-	integration, err := s.db.UpsertUserIntegration(ctx, gen.UpsertUserIntegrationParams{
+	// Upsert the integration and record the pairing in its status history
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	qtx := s.db.WithTx(tx)
+
+	// A retried pairing returns the integration the first attempt created
+	if req.IdempotencyKey != "" {
+		if err := qtx.LockIntegrationPairing(ctx, gen.LockIntegrationPairingParams{
+			UserID:         userID.String(),
+			IdempotencyKey: req.IdempotencyKey,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to lock pairing: %w", err)
+		}
+
+		pairing, err := qtx.GetIntegrationPairing(ctx, gen.GetIntegrationPairingParams{
+			UserID:         userID,
+			IdempotencyKey: req.IdempotencyKey,
+		})
+		if err == nil {
+			if pairing.IntegrationType != req.IntegrationType || pairing.ExternalID != req.PlatformUserId {
+				return &proto.CreateUserIntegrationResponse{
+					Success: false,
+					Error: fmt.Sprintf("idempotency key %q already paired %s account %s",
+						req.IdempotencyKey, pairing.IntegrationType, pairing.ExternalID),
+				}, nil
+			}
+			s.log(ctx).Info("Pairing already recorded, returning its integration",
+				zap.String("user_id", req.UserId),
+				zap.Int32("integration_id", pairing.ID))
+			return &proto.CreateUserIntegrationResponse{
+				Success:           true,
+				UserIntegrationId: pairing.ID,
+			}, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+	}
+
+	// An external account belongs to one user; pairing it to another must not
+	// take over the first user's integration and data
+	owner, err := qtx.GetUserIntegrationByExternalID(ctx, gen.GetUserIntegrationByExternalIDParams{
+		IntegrationType: req.IntegrationType,
+		ExternalID:      req.PlatformUserId,
+	})
+	if err == nil && owner.UserID != userID {
+		s.log(ctx).Warn("Rejected pairing of an account connected to another user",
+			zap.String("user_id", req.UserId),
+			zap.String("integration_type", req.IntegrationType),
+			zap.Int32("existing_integration_id", owner.ID))
+		return &proto.CreateUserIntegrationResponse{
+			Success: false,
+			Error:   fmt.Sprintf("%s account %s is connected to another user", req.IntegrationType, req.PlatformUserId),
+		}, nil
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	// A re-pairing records the status the integration had before
+	var oldStatus string
+	existing, err := qtx.GetUserIntegration(ctx, gen.GetUserIntegrationParams{
+		UserID:          userID,
+		IntegrationType: req.IntegrationType,
+		ExternalID:      req.PlatformUserId,
+	})
+	if err == nil {
+		oldStatus = existing.Status
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	integration, err := qtx.UpsertUserIntegration(ctx, gen.UpsertUserIntegrationParams{
 		UserID:          userID,
 		IntegrationType: req.IntegrationType,
 		ExternalID:      req.PlatformUserId,
@@ -66,10 +214,41 @@ func (s *IntegrationServer) CreateUserIntegration(ctx context.Context, req *prot
 		LastSeen:        time.Now(),
 	})
 	if err != nil {
-		s.logger.Error("Failed to create user integration", zap.Error(err))
+		s.log(ctx).Error("Failed to create user integration", zap.Error(err))
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 
+	err = qtx.InsertIntegrationStatusEvent(ctx, gen.InsertIntegrationStatusEventParams{
+		UserIntegrationID: integration.ID,
+		OldStatus:         oldStatus,
+		NewStatus:         integration.Status,
+		Reason:            "paired",
+		Metadata:          metadataJSON,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record pairing: %w", err)
+	}
+	if _, err := qtx.TrimIntegrationStatusEvents(ctx, gen.TrimIntegrationStatusEventsParams{
+		UserIntegrationID: integration.ID,
+		Keep:              repo.MaxIntegrationStatusEvents,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to trim status events: %w", err)
+	}
+
+	if req.IdempotencyKey != "" {
+		if err := qtx.InsertIntegrationPairing(ctx, gen.InsertIntegrationPairingParams{
+			UserID:            userID,
+			IdempotencyKey:    req.IdempotencyKey,
+			UserIntegrationID: integration.ID,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to record pairing key: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit user integration: %w", err)
+	}
+
 	return &proto.CreateUserIntegrationResponse{
 		Success:           true,
 		UserIntegrationId: integration.ID,
@@ -78,7 +257,7 @@ func (s *IntegrationServer) CreateUserIntegration(ctx context.Context, req *prot
 
 // UpdateConnectionStatus updates the connection status of an integration
 func (s *IntegrationServer) UpdateConnectionStatus(ctx context.Context, req *proto.UpdateConnectionStatusRequest) (*proto.UpdateConnectionStatusResponse, error) {
-	s.logger.Debug("UpdateConnectionStatus gRPC call received",
+	s.log(ctx).Debug("UpdateConnectionStatus gRPC call received",
 		zap.String("user_id", req.Context.UserId),
 		zap.String("integration_type", req.Context.IntegrationType),
 		zap.String("status", req.Status.String()))
@@ -97,10 +276,23 @@ func (s *IntegrationServer) UpdateConnectionStatus(ctx context.Context, req *pro
 		lastSeen = &t
 	}
 
+	// The bridge sends the cause as metadata; fall back to the detailed
+	// connection status, which several statuses collapse into
+	reason := req.Metadata["reason"]
+	if reason == "" {
+		reason = strings.ToLower(strings.TrimPrefix(req.Status.String(), "CONNECTION_STATUS_"))
+	}
+
+	integrationID, err := s.resolveIntegrationID(ctx, userID, req.Context)
+	if err != nil {
+		s.log(ctx).Error("Failed to resolve integration", zap.Error(err))
+		return nil, fmt.Errorf("failed to resolve integration: %w", err)
+	}
+
 	// Update integration status
-	err = s.integrationService.UpdateIntegrationStatus(ctx, userID, req.Context.IntegrationType, status, lastSeen)
+	err = s.integrationService.UpdateIntegrationStatus(ctx, integrationID, status, lastSeen, reason, req.Metadata)
 	if err != nil {
-		s.logger.Error("Failed to update connection status", zap.Error(err))
+		s.log(ctx).Error("Failed to update connection status", zap.Error(err))
 		return nil, fmt.Errorf("failed to update status: %w", err)
 	}
 
@@ -109,136 +301,254 @@ func (s *IntegrationServer) UpdateConnectionStatus(ctx context.Context, req *pro
 	}, nil
 }
 
-// SyncConversations handles streaming conversation synchronization
+// resolveIntegrationID returns the integration an IntegrationContext refers
+// to: its user_integration_id when set, otherwise the user's integration with
+// the platform user ID (e.g. the WhatsApp JID)
+func (s *IntegrationServer) resolveIntegrationID(ctx context.Context, userID uuid.UUID, integrationCtx *proto.IntegrationContext) (int32, error) {
+	if integrationCtx.UserIntegrationId != 0 {
+		return integrationCtx.UserIntegrationId, nil
+	}
+
+	var integration *repo.UserIntegration
+	var err error
+	if integrationCtx.PlatformUserId != "" {
+		integration, err = s.integrationService.GetUserIntegrationByExternalID(ctx, userID, integrationCtx.IntegrationType, integrationCtx.PlatformUserId)
+	} else {
+		integration, err = s.integrationService.GetUserIntegration(ctx, userID, integrationCtx.IntegrationType)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return integration.ID, nil
+}
+
+// SyncConversations handles streaming conversation synchronization,
+// acknowledging each batch once it's stored
 func (s *IntegrationServer) SyncConversations(stream proto.IntegrationService_SyncConversationsServer) error {
-	s.logger.Debug("SyncConversations stream started")
+	s.log(stream.Context()).Debug("SyncConversations stream started")
 
-	var totalProcessed int32
 	var batchCount int32
+	participants := make(participantCache)
 
 	for {
 		req, err := stream.Recv()
 		if err == io.EOF {
-			// Client finished sending
-			return stream.SendAndClose(&proto.SyncConversationsResponse{
-				Success:        true,
-				ProcessedCount: totalProcessed,
-				TotalBatches:   batchCount,
-			})
+			// Client finished sending, and every batch was acknowledged
+			return nil
 		}
 		if err != nil {
-			s.logger.Error("Error receiving conversations", zap.Error(err))
+			s.log(stream.Context()).Error("Error receiving conversations", zap.Error(err))
+			return err
+		}
+		if err := s.checkSyncBatch("conversations", len(req.Conversations)); err != nil {
 			return err
 		}
 
 		batchCount++
-		s.logger.Debug("Processing conversation batch",
+		s.log(stream.Context()).Debug("Processing conversation batch",
+			zap.String("sync_id", req.SyncId),
 			zap.Int32("batch_number", req.BatchNumber),
 			zap.Int("conversations_count", len(req.Conversations)),
 			zap.Bool("is_final_batch", req.IsFinalBatch))
 
 		// Process each conversation in the batch
-		for _, conv := range req.Conversations {
-			err := s.upsertConversation(stream.Context(), req.Context, conv)
-			if err != nil {
-				s.logger.Error("Failed to upsert conversation",
-					zap.String("platform_id", conv.PlatformId),
-					zap.Error(err))
-				continue
+		var processed int32
+		stored := s.storeSyncBatch(stream.Context(), req.SyncId, req.BatchNumber, func() {
+			for _, conv := range req.Conversations {
+				var err error
+				if req.SyncType == events.SyncTypeAvatar {
+					err = s.updateConversationAvatar(stream.Context(), req.Context, conv)
+				} else {
+					err = s.upsertConversation(stream.Context(), req.Context, conv, participants)
+				}
+				if err != nil {
+					s.log(stream.Context()).Error("Failed to upsert conversation",
+						zap.String("platform_id", conv.PlatformId),
+						zap.Error(err))
+					continue
+				}
+				processed++
 			}
-			totalProcessed++
-		}
+		})
 
-		s.logger.Debug("Processed conversation batch",
+		s.log(stream.Context()).Debug("Processed conversation batch",
+			zap.String("sync_id", req.SyncId),
 			zap.Int32("batch_number", req.BatchNumber),
-			zap.Int32("processed_count", totalProcessed))
+			zap.Int32("processed_count", processed),
+			zap.Bool("already_stored", !stored))
+
+		if err := stream.Send(&proto.SyncConversationsResponse{
+			Success:        true,
+			ProcessedCount: processed,
+			TotalBatches:   batchCount,
+			BatchNumber:    req.BatchNumber,
+		}); err != nil {
+			return err
+		}
 	}
 }
 
-// SyncContacts handles streaming contact synchronization
+// SyncContacts handles streaming contact synchronization, acknowledging each
+// batch once it's stored
 func (s *IntegrationServer) SyncContacts(stream proto.IntegrationService_SyncContactsServer) error {
-	s.logger.Debug("SyncContacts stream started")
-
-	var totalProcessed int32
+	s.log(stream.Context()).Debug("SyncContacts stream started")
 
 	for {
 		req, err := stream.Recv()
 		if err == io.EOF {
-			return stream.SendAndClose(&proto.SyncContactsResponse{
-				Success:        true,
-				ProcessedCount: totalProcessed,
-			})
+			return nil
 		}
 		if err != nil {
-			s.logger.Error("Error receiving contacts", zap.Error(err))
+			s.log(stream.Context()).Error("Error receiving contacts", zap.Error(err))
+			return err
+		}
+		if err := s.checkSyncBatch("contacts", len(req.Contacts)); err != nil {
 			return err
 		}
 
-		s.logger.Debug("Processing contact batch",
+		s.log(stream.Context()).Debug("Processing contact batch",
+			zap.String("sync_id", req.SyncId),
 			zap.Int32("batch_number", req.BatchNumber),
 			zap.Int("contacts_count", len(req.Contacts)))
 
 		// Process each contact in the batch
-		for _, contact := range req.Contacts {
-			err := s.upsertContact(stream.Context(), req.Context, contact)
-			if err != nil {
-				s.logger.Error("Failed to upsert contact",
-					zap.String("platform_id", contact.PlatformId),
-					zap.Error(err))
-				continue
+		var processed int32
+		s.storeSyncBatch(stream.Context(), req.SyncId, req.BatchNumber, func() {
+			for _, contact := range req.Contacts {
+				err := s.upsertContact(stream.Context(), req.Context, contact)
+				if err != nil {
+					s.log(stream.Context()).Error("Failed to upsert contact",
+						zap.String("platform_id", contact.PlatformId),
+						zap.Error(err))
+					continue
+				}
+				processed++
 			}
-			totalProcessed++
+		})
+
+		if err := stream.Send(&proto.SyncContactsResponse{
+			Success:        true,
+			ProcessedCount: processed,
+			BatchNumber:    req.BatchNumber,
+		}); err != nil {
+			return err
 		}
 	}
 }
 
-// SyncMessages handles streaming message synchronization
+// SyncMessages handles streaming message synchronization, acknowledging each
+// batch once it's stored
 func (s *IntegrationServer) SyncMessages(stream proto.IntegrationService_SyncMessagesServer) error {
-	s.logger.Debug("SyncMessages stream started")
+	s.log(stream.Context()).Debug("SyncMessages stream started")
 
-	var totalProcessed int32
+	synced := make(map[string]*historySync)
+	// However the stream ends, the batches it stored are stored, so clients
+	// should hear of them. A broken stream's context is already canceled.
+	defer s.publishHistorySyncs(context.WithoutCancel(stream.Context()), synced)
 
 	for {
 		req, err := stream.Recv()
 		if err == io.EOF {
-			return stream.SendAndClose(&proto.SyncMessagesResponse{
-				Success:        true,
-				ProcessedCount: totalProcessed,
-			})
+			return nil
 		}
 		if err != nil {
-			s.logger.Error("Error receiving messages", zap.Error(err))
+			s.log(stream.Context()).Error("Error receiving messages", zap.Error(err))
+			return err
+		}
+		if err := s.checkSyncBatch("messages", len(req.Messages)); err != nil {
 			return err
 		}
 
-		s.logger.Debug("Processing message batch",
+		s.log(stream.Context()).Debug("Processing message batch",
+			zap.String("sync_id", req.SyncId),
 			zap.Int32("batch_number", req.BatchNumber),
 			zap.Int("messages_count", len(req.Messages)),
 			zap.String("conversation_id", req.ConversationExternalId))
 
 		// Process each message in the batch
-		for _, message := range req.Messages {
-			err := s.upsertMessage(stream.Context(), req.Context, req.ConversationExternalId, message)
-			if err != nil {
-				s.logger.Error("Failed to upsert message",
-					zap.String("platform_id", message.PlatformId),
-					zap.Error(err))
-				continue
+		var processed int32
+		s.storeSyncBatch(stream.Context(), req.SyncId, req.BatchNumber, func() {
+			for _, message := range req.Messages {
+				err := s.upsertMessage(stream.Context(), req.Context, req.ConversationExternalId, message, false)
+				if err != nil {
+					s.log(stream.Context()).Error("Failed to upsert message",
+						zap.String("platform_id", message.PlatformId),
+						zap.Error(err))
+					continue
+				}
+				processed++
+
+				tally, ok := synced[req.ConversationExternalId]
+				if !ok {
+					tally = &historySync{accountID: req.Context.UserId, integrationID: req.Context.UserIntegrationId}
+					synced[req.ConversationExternalId] = tally
+				}
+				tally.add(message.Timestamp.AsTime())
 			}
-			totalProcessed++
+		})
+
+		if err := stream.Send(&proto.SyncMessagesResponse{
+			Success:        true,
+			ProcessedCount: processed,
+			BatchNumber:    req.BatchNumber,
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+// historySync tallies the messages a SyncMessages stream stored for one conversation
+type historySync struct {
+	accountID     string
+	integrationID int32
+	messages      int
+	start, end    time.Time
+}
+
+func (h *historySync) add(ts time.Time) {
+	if h.messages == 0 || ts.Before(h.start) {
+		h.start = ts
+	}
+	if h.messages == 0 || ts.After(h.end) {
+		h.end = ts
+	}
+	h.messages++
+}
+
+// publishHistorySyncs tells clients which conversations gained history. A
+// failed publish is logged; the messages are stored either way.
+func (s *IntegrationServer) publishHistorySyncs(ctx context.Context, synced map[string]*historySync) {
+	if s.eventService == nil {
+		return
+	}
+
+	completedAt := time.Now()
+	for conversationID, tally := range synced {
+		_, err := s.eventService.PublishHistorySync(ctx, tally.accountID, tally.integrationID, conversationID, events.HistorySyncPayload{
+			ConversationCount: 1,
+			MessageCount:      tally.messages,
+			StartTime:         tally.start,
+			EndTime:           tally.end,
+			Progress:          1,
+			CompletedAt:       &completedAt,
+		})
+		if err != nil {
+			s.log(ctx).Warn("Failed to publish history sync",
+				zap.String("conversation_id", conversationID),
+				zap.Error(err))
 		}
 	}
 }
 
 // ProcessMessage handles real-time message processing
 func (s *IntegrationServer) ProcessMessage(ctx context.Context, req *proto.ProcessMessageRequest) (*proto.ProcessMessageResponse, error) {
-	s.logger.Debug("ProcessMessage gRPC call received",
+	s.log(ctx).Debug("ProcessMessage gRPC call received",
 		zap.String("message_id", req.Message.PlatformId),
 		zap.String("conversation_id", req.Message.ConversationId))
 
-	err := s.upsertMessage(ctx, req.Context, req.Message.ConversationId, req.Message)
+	err := s.upsertMessage(ctx, req.Context, req.Message.ConversationId, req.Message, true)
 	if err != nil {
-		s.logger.Error("Failed to process message", zap.Error(err))
+		s.log(ctx).Error("Failed to process message", zap.Error(err))
 		return nil, fmt.Errorf("failed to process message: %w", err)
 	}
 
@@ -247,38 +557,485 @@ func (s *IntegrationServer) ProcessMessage(ctx context.Context, req *proto.Proce
 	}, nil
 }
 
+// ProcessPollVote records a voter's latest selection on a poll
+func (s *IntegrationServer) ProcessPollVote(ctx context.Context, req *proto.ProcessPollVoteRequest) (*proto.ProcessPollVoteResponse, error) {
+	vote := req.Vote
+	s.log(ctx).Debug("ProcessPollVote gRPC call received",
+		zap.String("poll_message_id", vote.PollMessageId),
+		zap.String("conversation_id", vote.ConversationId),
+		zap.Int("selected_options", len(vote.SelectedOptionHashes)))
+
+	conversationExternalID, err := s.canonicalJID(ctx, req.Context, vote.ConversationId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process poll vote: %w", err)
+	}
+	voterID, err := s.canonicalJID(ctx, req.Context, vote.VoterId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process poll vote: %w", err)
+	}
+
+	conversationID, err := s.ensureConversation(ctx, req.Context, conversationExternalID)
+	if err != nil {
+		s.log(ctx).Error("Failed to process poll vote", zap.Error(err))
+		return nil, fmt.Errorf("failed to process poll vote: %w", err)
+	}
+
+	selected := vote.SelectedOptionHashes
+	if selected == nil {
+		selected = []string{}
+	}
+
+	votedAt := time.Now()
+	if vote.Timestamp != nil {
+		votedAt = vote.Timestamp.AsTime()
+	}
+
+	err = s.db.UpsertPollVote(ctx, gen.UpsertPollVoteParams{
+		ConversationID:        conversationID,
+		PollExternalMessageID: vote.PollMessageId,
+		VoterExternalID:       voterID,
+		SelectedOptionHashes:  selected,
+		VotedAt:               votedAt,
+	})
+	if err != nil {
+		s.log(ctx).Error("Failed to store poll vote", zap.Error(err))
+		return nil, fmt.Errorf("failed to store poll vote: %w", err)
+	}
+
+	return &proto.ProcessPollVoteResponse{
+		Success: true,
+	}, nil
+}
+
+// SyncIdentityMappings records which phone-number JID each LID belongs to and
+// merges anything already stored under a newly mapped LID into it
+func (s *IntegrationServer) SyncIdentityMappings(ctx context.Context, req *proto.SyncIdentityMappingsRequest) (*proto.SyncIdentityMappingsResponse, error) {
+	s.log(ctx).Debug("SyncIdentityMappings gRPC call received",
+		zap.Int("mappings", len(req.Mappings)))
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	qtx := s.db.WithTx(tx)
+
+	var merged int32
+	for _, mapping := range req.Mappings {
+		if mapping.LidJid == "" || mapping.PnJid == "" || mapping.LidJid == mapping.PnJid {
+			continue
+		}
+
+		changed, err := qtx.UpsertContactIdentity(ctx, gen.UpsertContactIdentityParams{
+			UserIntegrationID: req.Context.UserIntegrationId,
+			LidJid:            mapping.LidJid,
+			PnJid:             mapping.PnJid,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to store identity mapping: %w", err)
+		}
+		if changed == 0 {
+			continue
+		}
+
+		conversationMerged, err := qtx.MergeContactIdentity(ctx, gen.MergeContactIdentityParams{
+			UserIntegrationID: req.Context.UserIntegrationId,
+			LidJid:            mapping.LidJid,
+			PnJid:             mapping.PnJid,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge identity %s: %w", mapping.LidJid, err)
+		}
+		if conversationMerged {
+			merged++
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit identity mappings: %w", err)
+	}
+	if merged > 0 {
+		s.log(ctx).Info("Merged LID conversations into phone-number conversations",
+			zap.Int32("count", merged))
+	}
+
+	return &proto.SyncIdentityMappingsResponse{
+		Success:     true,
+		MergedCount: merged,
+	}, nil
+}
+
 // UpdateConversationState handles conversation state updates
 func (s *IntegrationServer) UpdateConversationState(ctx context.Context, req *proto.UpdateConversationStateRequest) (*proto.UpdateConversationStateResponse, error) {
-	s.logger.Debug("UpdateConversationState gRPC call received",
+	s.log(ctx).Debug("UpdateConversationState gRPC call received",
 		zap.String("conversation_id", req.ConversationExternalId),
-		zap.Bool("is_pinned", req.State.IsPinned))
+		zap.Bool("is_pinned", req.State.IsPinned),
+		zap.Strings("fields", req.Fields))
 
-	var muteUntil time.Time
-	if req.State.MuteUntil != nil {
-		muteUntil = req.State.MuteUntil.AsTime()
+	conversationExternalID, err := s.canonicalJID(ctx, req.Context, req.ConversationExternalId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update conversation state: %w", err)
 	}
 
-	err := s.db.UpdateConversationState(ctx, gen.UpdateConversationStateParams{
+	params := gen.UpdateConversationStateParams{
 		UserIntegrationID:      req.Context.UserIntegrationId,
-		ExternalConversationID: req.ConversationExternalId,
-		IsArchived:             req.State.IsArchived,
-		IsPinned:               req.State.IsPinned,
-		IsMuted:                req.State.IsMuted,
-		MuteUntil:              muteUntil,
-	})
+		ExternalConversationID: conversationExternalID,
+	}
+	sets := func(field string) bool {
+		return len(req.Fields) == 0 || slices.Contains(req.Fields, field)
+	}
+	if sets(events.ConversationFieldPinned) {
+		params.IsPinned = pgtype.Bool{Bool: req.State.IsPinned, Valid: true}
+	}
+	if sets(events.ConversationFieldArchived) {
+		params.IsArchived = pgtype.Bool{Bool: req.State.IsArchived, Valid: true}
+	}
+	if sets(events.ConversationFieldMuted) {
+		params.IsMuted = pgtype.Bool{Bool: req.State.IsMuted, Valid: true}
+		if req.State.IsMuted && req.State.MuteUntil != nil {
+			params.MuteUntil = pgtype.Timestamptz{Time: req.State.MuteUntil.AsTime(), Valid: true}
+		}
+	}
+	if req.ChangedAt != nil {
+		params.ChangedAt = pgtype.Timestamptz{Time: req.ChangedAt.AsTime(), Valid: true}
+	}
+
+	row, err := s.db.UpdateConversationState(ctx, params)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Either not synced yet, and the conversation sync will carry its
+		// state, or older than the conversation's latest change, like the echo
+		// of a change made through the API that has since been changed again
+		s.log(ctx).Debug("Conversation state update not applied",
+			zap.String("conversation_id", conversationExternalID))
+		return &proto.UpdateConversationStateResponse{Success: true}, nil
+	}
 	if err != nil {
-		s.logger.Error("Failed to update conversation state", zap.Error(err))
+		s.log(ctx).Error("Failed to update conversation state", zap.Error(err))
 		return nil, fmt.Errorf("failed to update conversation state: %w", err)
 	}
 
+	previous := core.ConversationState{
+		IsPinned:   row.PreviousIsPinned,
+		IsArchived: row.PreviousIsArchived,
+		IsMuted:    row.PreviousIsMuted,
+		MuteUntil:  row.PreviousMuteUntil.Time,
+	}
+	next := core.ConversationState{
+		IsPinned:   row.IsPinned,
+		IsArchived: row.IsArchived,
+		IsMuted:    row.IsMuted,
+		MuteUntil:  row.MuteUntil.Time,
+	}
+	if change, changed := core.ConversationStateChange(previous, next); changed && s.eventService != nil {
+		// The state is stored; clients that miss the event see it on their next conversation sync
+		if _, err := s.eventService.PublishConversationState(ctx, req.Context.UserId, req.Context.UserIntegrationId, conversationExternalID, change); err != nil {
+			s.log(ctx).Warn("Failed to publish conversation state change",
+				zap.String("conversation_id", conversationExternalID),
+				zap.Error(err))
+		}
+	}
+
 	return &proto.UpdateConversationStateResponse{
 		Success: true,
 	}, nil
 }
 
+// UpdatePresence publishes the presence of a contact the user subscribed to.
+// Presence isn't stored anywhere else; clients that miss the event see the
+// next one.
+func (s *IntegrationServer) UpdatePresence(ctx context.Context, req *proto.UpdatePresenceRequest) (*proto.UpdatePresenceResponse, error) {
+	s.log(ctx).Debug("UpdatePresence gRPC call received",
+		zap.String("external_user_id", req.Presence.ExternalUserId),
+		zap.Bool("is_online", req.Presence.IsOnline),
+		zap.Bool("is_typing", req.Presence.IsTyping))
+
+	if s.eventService == nil {
+		return &proto.UpdatePresenceResponse{Success: true}, nil
+	}
+
+	userJID, err := s.canonicalJID(ctx, req.Context, req.Presence.ExternalUserId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update presence: %w", err)
+	}
+	conversationExternalID := userJID
+	if req.Presence.ConversationExternalId != "" {
+		conversationExternalID, err = s.canonicalJID(ctx, req.Context, req.Presence.ConversationExternalId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update presence: %w", err)
+		}
+	}
+
+	presence := events.PresencePayload{
+		JID:         userJID,
+		IsOnline:    req.Presence.IsOnline,
+		IsTyping:    req.Presence.IsTyping,
+		IsRecording: req.Presence.IsRecording,
+	}
+	if req.Presence.LastSeen != nil {
+		lastSeen := req.Presence.LastSeen.AsTime()
+		presence.LastSeen = &lastSeen
+	}
+	if _, err := s.eventService.PublishPresence(ctx, req.Context.UserId, req.Context.UserIntegrationId, conversationExternalID, presence); err != nil {
+		s.log(ctx).Error("Failed to publish presence", zap.Error(err))
+		return nil, fmt.Errorf("failed to update presence: %w", err)
+	}
+
+	return &proto.UpdatePresenceResponse{
+		Success: true,
+	}, nil
+}
+
+// participantRoles are the roles a conversation participant may have
+var participantRoles = []string{"member", "admin", "owner", "moderator"}
+
+// UpdateParticipantRoles stores group participants' new roles and publishes
+// each change. Participants that aren't known yet are added with their role.
+func (s *IntegrationServer) UpdateParticipantRoles(ctx context.Context, req *proto.UpdateParticipantRolesRequest) (*proto.UpdateParticipantRolesResponse, error) {
+	s.log(ctx).Debug("UpdateParticipantRoles gRPC call received",
+		zap.String("conversation_id", req.ConversationExternalId),
+		zap.Int("participants", len(req.Participants)))
+
+	for _, participant := range req.Participants {
+		if !slices.Contains(participantRoles, participant.Role) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid role %q for participant %s", participant.Role, participant.ExternalUserId)
+		}
+	}
+
+	conversationExternalID, err := s.canonicalJID(ctx, req.Context, req.ConversationExternalId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update participant roles: %w", err)
+	}
+	changedBy, err := s.canonicalJID(ctx, req.Context, req.ChangedByExternalId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update participant roles: %w", err)
+	}
+	var changedAt *time.Time
+	if req.ChangedAt != nil {
+		at := req.ChangedAt.AsTime()
+		changedAt = &at
+	}
+
+	conversationID, err := s.ensureConversation(ctx, req.Context, conversationExternalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update participant roles: %w", err)
+	}
+
+	var changed int32
+	for _, participant := range req.Participants {
+		participantJID, err := s.canonicalJID(ctx, req.Context, participant.ExternalUserId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update participant roles: %w", err)
+		}
+
+		previousRole, err := s.db.SetParticipantRole(ctx, gen.SetParticipantRoleParams{
+			ConversationID:  conversationID,
+			ExternalUserID:  participantJID,
+			IntegrationType: req.Context.IntegrationType,
+			Role:            participant.Role,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Already had the role, like a promotion that the group sync
+			// stored first
+			continue
+		}
+		if err != nil {
+			s.log(ctx).Error("Failed to set participant role",
+				zap.String("conversation_id", conversationExternalID),
+				zap.String("participant", participantJID),
+				zap.Error(err))
+			return nil, fmt.Errorf("failed to update participant roles: %w", err)
+		}
+		changed++
+
+		if s.eventService == nil {
+			continue
+		}
+		// The role is stored; clients that miss the event see it in the
+		// conversation's admins
+		_, err = s.eventService.PublishParticipantRole(ctx, req.Context.UserId, req.Context.UserIntegrationId, conversationExternalID, events.ParticipantRolePayload{
+			JID:          participantJID,
+			Role:         participant.Role,
+			PreviousRole: previousRole,
+			ChangedBy:    changedBy,
+			ChangedAt:    changedAt,
+		})
+		if err != nil {
+			s.log(ctx).Warn("Failed to publish participant role change",
+				zap.String("conversation_id", conversationExternalID),
+				zap.String("participant", participantJID),
+				zap.Error(err))
+		}
+	}
+
+	return &proto.UpdateParticipantRolesResponse{
+		Success:      true,
+		ChangedCount: changed,
+	}, nil
+}
+
+// outboxDeliveryStatuses maps the receipt statuses a bridge reports to outbox
+// statuses
+var outboxDeliveryStatuses = map[proto.MessageStatus]string{
+	proto.MessageStatus_MESSAGE_STATUS_DELIVERED: events.OutboxStatusDelivered,
+	proto.MessageStatus_MESSAGE_STATUS_READ:      events.OutboxStatusRead,
+}
+
+// UpdateOutboxDelivery advances a message sent from the outbox when the
+// recipient's device receives or reads it, and publishes the receipt
+func (s *IntegrationServer) UpdateOutboxDelivery(ctx context.Context, req *proto.UpdateOutboxDeliveryRequest) (*proto.UpdateOutboxDeliveryResponse, error) {
+	s.log(ctx).Debug("UpdateOutboxDelivery gRPC call received",
+		zap.String("client_msg_uuid", req.ClientMsgUuid),
+		zap.String("wa_message_id", req.WaMessageId),
+		zap.String("status", req.Status.String()))
+
+	deliveryStatus, ok := outboxDeliveryStatuses[req.Status]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid delivery status %s", req.Status)
+	}
+	clientMsgUUID, err := uuid.Parse(req.ClientMsgUuid)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid client_msg_uuid %q", req.ClientMsgUuid)
+	}
+	at := time.Now()
+	if req.Timestamp != nil {
+		at = req.Timestamp.AsTime()
+	}
+
+	convoID, err := s.db.AdvanceOutboxDelivery(ctx, gen.AdvanceOutboxDeliveryParams{
+		Status:        deliveryStatus,
+		ClientMsgUuid: clientMsgUUID,
+		AccountID:     req.Context.UserId,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Already this far, like a group member's delivery receipt after
+		// another member read the message
+		return &proto.UpdateOutboxDeliveryResponse{Success: true}, nil
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to advance outbox entry",
+			zap.String("client_msg_uuid", req.ClientMsgUuid),
+			zap.Error(err))
+		return nil, fmt.Errorf("failed to update outbox delivery: %w", err)
+	}
+
+	if req.WaMessageId != "" {
+		// The sent message is only stored once it's synced back from the phone
+		_, err := s.db.AdvanceMessageDeliveryStatus(ctx, gen.AdvanceMessageDeliveryStatusParams{
+			DeliveryStatus:    deliveryStatus,
+			UserIntegrationID: req.Context.UserIntegrationId,
+			ExternalMessageID: req.WaMessageId,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update message delivery status: %w", err)
+		}
+	}
+
+	if s.eventService != nil {
+		delivery := events.DeliveryPayload{
+			WAMessageID:   req.WaMessageId,
+			Status:        deliveryStatus,
+			ClientMsgUUID: req.ClientMsgUuid,
+		}
+		if deliveryStatus == events.OutboxStatusRead {
+			delivery.ReadAt = &at
+		} else {
+			delivery.DeliveredAt = &at
+		}
+		// The status is stored, so a failed publish is only logged
+		if _, err := s.eventService.PublishDelivery(ctx, req.Context.UserId, req.Context.UserIntegrationId, convoID, delivery); err != nil {
+			s.log(ctx).Warn("Failed to publish delivery",
+				zap.String("client_msg_uuid", req.ClientMsgUuid),
+				zap.Error(err))
+		}
+	}
+
+	return &proto.UpdateOutboxDeliveryResponse{
+		Success: true,
+		Applied: true,
+	}, nil
+}
+
+// UpdateMessageDelivery advances a message the account sent from another
+// device when the recipient's device receives or reads it, and publishes the
+// receipt, so clients syncing events see its status change like an outbox
+// message's
+func (s *IntegrationServer) UpdateMessageDelivery(ctx context.Context, req *proto.UpdateMessageDeliveryRequest) (*proto.UpdateMessageDeliveryResponse, error) {
+	s.log(ctx).Debug("UpdateMessageDelivery gRPC call received",
+		zap.String("wa_message_id", req.WaMessageId),
+		zap.String("status", req.Status.String()))
+
+	deliveryStatus, ok := outboxDeliveryStatuses[req.Status]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid delivery status %s", req.Status)
+	}
+	if req.WaMessageId == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing wa_message_id")
+	}
+	at := time.Now()
+	if req.Timestamp != nil {
+		at = req.Timestamp.AsTime()
+	}
+
+	conversationExternalID, err := s.canonicalJID(ctx, req.Context, req.ConversationExternalId)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.db.AdvanceMessageDeliveryStatus(ctx, gen.AdvanceMessageDeliveryStatusParams{
+		DeliveryStatus:    deliveryStatus,
+		UserIntegrationID: req.Context.UserIntegrationId,
+		ExternalMessageID: req.WaMessageId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update message delivery status: %w", err)
+	}
+	if updated == 0 {
+		// Not synced yet, or already this far
+		return &proto.UpdateMessageDeliveryResponse{Success: true}, nil
+	}
+
+	if s.eventService != nil {
+		delivery := events.DeliveryPayload{
+			WAMessageID: req.WaMessageId,
+			Status:      deliveryStatus,
+		}
+		if deliveryStatus == events.OutboxStatusRead {
+			delivery.ReadAt = &at
+		} else {
+			delivery.DeliveredAt = &at
+		}
+		// The status is stored, so a failed publish is only logged
+		if _, err := s.eventService.PublishDelivery(ctx, req.Context.UserId, req.Context.UserIntegrationId, conversationExternalID, delivery); err != nil {
+			s.log(ctx).Warn("Failed to publish delivery",
+				zap.String("wa_message_id", req.WaMessageId),
+				zap.Error(err))
+		}
+	}
+
+	return &proto.UpdateMessageDeliveryResponse{
+		Success: true,
+		Applied: true,
+	}, nil
+}
+
 // Helper functions
 
-func (s *IntegrationServer) upsertConversation(ctx context.Context, integrationCtx *proto.IntegrationContext, conv *proto.Conversation) error {
+// upsertConversation stores a conversation and its participants. Participants
+// already written with the same data earlier in the stream are skipped.
+func (s *IntegrationServer) upsertConversation(ctx context.Context, integrationCtx *proto.IntegrationContext, conv *proto.Conversation, seen participantCache) error {
+	// Store a LID conversation and its LID participants under their
+	// phone-number JIDs when the mapping is known
+	externalConversationID, err := s.canonicalJID(ctx, integrationCtx, conv.PlatformId)
+	if err != nil {
+		return err
+	}
+	for _, participant := range conv.Participants {
+		participant.ExternalUserId, err = s.canonicalJID(ctx, integrationCtx, participant.ExternalUserId)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Convert platform metadata
 	var platformMetadata json.RawMessage = []byte("{}")
 	if len(conv.PlatformMetadata) > 0 {
@@ -303,10 +1060,17 @@ func (s *IntegrationServer) upsertConversation(ctx context.Context, integrationC
 		muteUntil = conv.MuteUntil.AsTime()
 	}
 
+	// A conversation synced with nothing unread starts read up to its last
+	// message, so messages synced later count as unread only when newer
+	var lastReadAt pgtype.Timestamptz
+	if conv.UnreadCount == 0 && conv.LastMessageAt != nil {
+		lastReadAt = pgtype.Timestamptz{Time: lastMessageAt, Valid: true}
+	}
+
 	// Upsert conversation
 	conversation, err := s.db.UpsertConversation(ctx, gen.UpsertConversationParams{
 		UserIntegrationID:      integrationCtx.UserIntegrationId,
-		ExternalConversationID: conv.PlatformId,
+		ExternalConversationID: externalConversationID,
 		IntegrationType:        integrationCtx.IntegrationType,
 		ConversationType:       convertProtoConversationType(conv.Type),
 		Name:                   conv.Name,
@@ -323,63 +1087,147 @@ func (s *IntegrationServer) upsertConversation(ctx context.Context, integrationC
 		TotalMessageCount:      conv.TotalMessageCount,
 		LastMessageAt:          lastMessageAt,
 		LastActivityAt:         lastActivityAt,
+		LastReadAt:             lastReadAt,
+		DisappearingSeconds:    metadataSeconds(conv.PlatformMetadata, "disappearing_seconds"),
 		PlatformMetadata:       platformMetadata,
 	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The stored conversation has newer activity than this snapshot
+		s.log(ctx).Debug("Skipping stale conversation update",
+			zap.String("conversation_id", conv.PlatformId),
+			zap.Time("last_activity_at", lastActivityAt))
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to upsert conversation: %w", err)
 	}
 
 	// Upsert participants
-	for _, participant := range conv.Participants {
-		err := s.upsertConversationParticipant(ctx, conversation.ID, integrationCtx, participant)
-		if err != nil {
-			s.logger.Error("Failed to upsert participant",
-				zap.String("conversation_id", conv.PlatformId),
-				zap.String("participant_id", participant.ExternalUserId),
-				zap.Error(err))
-		}
+	if err := s.upsertConversationParticipants(ctx, conversation.ID, integrationCtx, conv.Participants, seen); err != nil {
+		s.log(ctx).Error("Failed to upsert participants",
+			zap.String("conversation_id", conv.PlatformId),
+			zap.Int("participants", len(conv.Participants)),
+			zap.Error(err))
 	}
 
 	return nil
 }
 
-func (s *IntegrationServer) upsertConversationParticipant(ctx context.Context, conversationID uuid.UUID, integrationCtx *proto.IntegrationContext, participant *proto.ConversationParticipant) error {
-	var platformMetadata json.RawMessage = []byte("{}")
-	if len(participant.PlatformMetadata) > 0 {
-		data, err := json.Marshal(participant.PlatformMetadata)
-		if err != nil {
-			return fmt.Errorf("failed to marshal participant metadata: %w", err)
+// participantCache maps a conversation participant to a hash of the data last
+// written for it during one sync stream. A nil cache disables skipping.
+type participantCache map[string]uint64
+
+func participantKey(conversationID uuid.UUID, externalUserID string) string {
+	return conversationID.String() + "/" + externalUserID
+}
+
+// participantHash hashes the stored fields of a participant
+func participantHash(participant *proto.ConversationParticipant) uint64 {
+	h := fnv.New64a()
+	write := func(value string) {
+		h.Write([]byte(value))
+		h.Write([]byte{0})
+	}
+
+	write(participant.DisplayName)
+	write(participant.Role)
+	write(strconv.FormatBool(participant.IsActive))
+	write(participant.JoinedAt.AsTime().String())
+	write(participant.LeftAt.AsTime().String())
+	write(participant.AddedByExternalId)
+
+	keys := make([]string, 0, len(participant.PlatformMetadata))
+	for key := range participant.PlatformMetadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		write(key)
+		write(participant.PlatformMetadata[key])
+	}
+
+	return h.Sum64()
+}
+
+// upsertConversationParticipants writes a conversation's participants in one
+// statement. Repeated participants keep their last entry.
+func (s *IntegrationServer) upsertConversationParticipants(ctx context.Context, conversationID uuid.UUID, integrationCtx *proto.IntegrationContext, participants []*proto.ConversationParticipant, seen participantCache) error {
+	indexByUser := make(map[string]int, len(participants))
+	pending := make([]*proto.ConversationParticipant, 0, len(participants))
+	hashes := make([]uint64, 0, len(participants))
+	for _, participant := range participants {
+		hash := participantHash(participant)
+		if previous, ok := seen[participantKey(conversationID, participant.ExternalUserId)]; ok && previous == hash {
+			continue
 		}
-		platformMetadata = data
+		if i, ok := indexByUser[participant.ExternalUserId]; ok {
+			pending[i], hashes[i] = participant, hash
+			continue
+		}
+		indexByUser[participant.ExternalUserId] = len(pending)
+		pending = append(pending, participant)
+		hashes = append(hashes, hash)
+	}
+	if len(pending) == 0 {
+		return nil
 	}
 
-	var joinedAt, leftAt time.Time
-	if participant.JoinedAt != nil {
-		joinedAt = participant.JoinedAt.AsTime()
+	params := gen.UpsertConversationParticipantsParams{
+		ConversationID:     conversationID,
+		IntegrationType:    integrationCtx.IntegrationType,
+		ExternalUserIds:    make([]string, len(pending)),
+		DisplayNames:       make([]string, len(pending)),
+		Roles:              make([]string, len(pending)),
+		IsActive:           make([]bool, len(pending)),
+		JoinedAt:           make([]time.Time, len(pending)),
+		LeftAt:             make([]time.Time, len(pending)),
+		AddedByExternalIds: make([]string, len(pending)),
+		PlatformMetadata:   make([]json.RawMessage, len(pending)),
 	}
-	if participant.LeftAt != nil {
-		leftAt = participant.LeftAt.AsTime()
+	for i, participant := range pending {
+		var platformMetadata json.RawMessage = []byte("{}")
+		if len(participant.PlatformMetadata) > 0 {
+			data, err := json.Marshal(participant.PlatformMetadata)
+			if err != nil {
+				return fmt.Errorf("failed to marshal participant metadata: %w", err)
+			}
+			platformMetadata = data
+		}
+
+		var joinedAt, leftAt time.Time
+		if participant.JoinedAt != nil {
+			joinedAt = participant.JoinedAt.AsTime()
+		}
+		if participant.LeftAt != nil {
+			leftAt = participant.LeftAt.AsTime()
+		}
+
+		// Default role to 'member' if not specified
+		role := participant.Role
+		if role == "" {
+			role = "member"
+		}
+
+		params.ExternalUserIds[i] = participant.ExternalUserId
+		params.DisplayNames[i] = participant.DisplayName
+		params.Roles[i] = role
+		params.IsActive[i] = participant.IsActive
+		params.JoinedAt[i] = joinedAt
+		params.LeftAt[i] = leftAt
+		params.AddedByExternalIds[i] = participant.AddedByExternalId
+		params.PlatformMetadata[i] = platformMetadata
 	}
 
-	// Default role to 'member' if not specified
-	role := participant.Role
-	if role == "" {
-		role = "member"
+	if err := s.db.UpsertConversationParticipants(ctx, params); err != nil {
+		return fmt.Errorf("failed to upsert %d participants: %w", len(pending), err)
 	}
 
-	_, err := s.db.UpsertConversationParticipant(ctx, gen.UpsertConversationParticipantParams{
-		ConversationID:    conversationID,
-		ExternalUserID:    participant.ExternalUserId,
-		IntegrationType:   integrationCtx.IntegrationType,
-		DisplayName:       participant.DisplayName,
-		Role:              role,
-		IsActive:          participant.IsActive,
-		JoinedAt:          joinedAt,
-		LeftAt:            leftAt,
-		AddedByExternalID: participant.AddedByExternalId,
-		PlatformMetadata:  platformMetadata,
-	})
-	return err
+	if seen != nil {
+		for i, participant := range pending {
+			seen[participantKey(conversationID, participant.ExternalUserId)] = hashes[i]
+		}
+	}
+	return nil
 }
 
 func (s *IntegrationServer) upsertContact(ctx context.Context, integrationCtx *proto.IntegrationContext, contact *proto.Contact) error {
@@ -397,9 +1245,14 @@ func (s *IntegrationServer) upsertContact(ctx context.Context, integrationCtx *p
 		lastSeen = contact.LastSeen.AsTime()
 	}
 
-	_, err := s.db.UpsertContact(ctx, gen.UpsertContactParams{
+	externalContactID, err := s.canonicalJID(ctx, integrationCtx, contact.PlatformId)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.UpsertContact(ctx, gen.UpsertContactParams{
 		UserIntegrationID: integrationCtx.UserIntegrationId,
-		ExternalContactID: contact.PlatformId,
+		ExternalContactID: externalContactID,
 		IntegrationType:   integrationCtx.IntegrationType,
 		DisplayName:       contact.DisplayName,
 		FirstName:         contact.FirstName,
@@ -415,37 +1268,24 @@ func (s *IntegrationServer) upsertContact(ctx context.Context, integrationCtx *p
 	return err
 }
 
-func (s *IntegrationServer) upsertMessage(ctx context.Context, integrationCtx *proto.IntegrationContext, conversationExternalID string, message *proto.Message) error {
-	// First, get the conversation ID from external ID
-	conversation, err := s.db.GetConversationByExternalID(ctx, gen.GetConversationByExternalIDParams{
-		UserIntegrationID:      integrationCtx.UserIntegrationId,
-		ExternalConversationID: conversationExternalID,
-	})
+// upsertMessage stores a message and counts it in its conversation the first
+// time it is stored. An incoming message also counts as unread when it is newer
+// than the conversation's read marker. live is set for real-time messages,
+// which count as unread when there is no marker yet; WhatsApp's unread count
+// for the conversation already covers its history.
+func (s *IntegrationServer) upsertMessage(ctx context.Context, integrationCtx *proto.IntegrationContext, conversationExternalID string, message *proto.Message, live bool) error {
+	conversationExternalID, err := s.canonicalJID(ctx, integrationCtx, conversationExternalID)
 	if err != nil {
-		// Conversation doesn't exist - auto-create it for real-time messages
-		s.logger.Info("Conversation not found, auto-creating for real-time message",
-			zap.String("conversation_id", conversationExternalID),
-			zap.String("message_id", message.PlatformId))
-
-		// Create minimal conversation
-		err = s.upsertConversation(ctx, integrationCtx, &proto.Conversation{
-			PlatformId:       conversationExternalID,
-			Type:             proto.ConversationType_CONVERSATION_TYPE_INDIVIDUAL, // Default to individual
-			Name:             "",                                                  // Will be updated later
-			PlatformMetadata: make(map[string]string),
-		})
-		if err != nil {
-			return fmt.Errorf("failed to auto-create conversation %s: %w", conversationExternalID, err)
-		}
+		return err
+	}
+	senderID, err := s.canonicalJID(ctx, integrationCtx, message.SenderId)
+	if err != nil {
+		return err
+	}
 
-		// Try to get it again
-		conversation, err = s.db.GetConversationByExternalID(ctx, gen.GetConversationByExternalIDParams{
-			UserIntegrationID:      integrationCtx.UserIntegrationId,
-			ExternalConversationID: conversationExternalID,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to find conversation after auto-create %s: %w", conversationExternalID, err)
-		}
+	conversationID, err := s.ensureConversation(ctx, integrationCtx, conversationExternalID)
+	if err != nil {
+		return err
 	}
 
 	// Convert platform metadata
@@ -467,13 +1307,32 @@ func (s *IntegrationServer) upsertMessage(ctx context.Context, integrationCtx *p
 		deletedAt = message.DeletedAt.AsTime()
 	}
 
+	// Store the message and its reply links in one transaction
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	qtx := s.db.WithTx(tx)
+
+	err = lockReplyLinks(ctx, qtx, conversationID, message.PlatformId, message.ReplyToExternalId)
+	if err != nil {
+		return err
+	}
+
+	// Resolve the replied-to message if it is already stored
+	replyToMessageID, err := resolveReplyToMessageID(ctx, qtx, conversationID, message.ReplyToExternalId)
+	if err != nil {
+		return err
+	}
+
 	// Upsert message
-	msg, err := s.db.UpsertMessage(ctx, gen.UpsertMessageParams{
-		ConversationID:    conversation.ID,
+	msg, err := qtx.UpsertMessage(ctx, gen.UpsertMessageParams{
+		ConversationID:    conversationID,
 		ExternalMessageID: message.PlatformId,
 		ExternalServerID:  "", // Not used in this context
 		IntegrationType:   integrationCtx.IntegrationType,
-		SenderExternalID:  message.SenderId,
+		SenderExternalID:  senderID,
 		SenderDisplayName: message.SenderDisplayName,
 		MessageType:       convertProtoMessageType(message.MessageType),
 		Content:           message.Content,
@@ -483,7 +1342,7 @@ func (s *IntegrationServer) upsertMessage(ctx context.Context, integrationCtx *p
 		IsForwarded:       message.IsForwarded,
 		IsDeleted:         message.IsDeleted,
 		DeletedAt:         deletedAt,
-		ReplyToMessageID:  uuid.Nil, // Will implement reply lookup if needed
+		ReplyToMessageID:  replyToMessageID, // uuid.Nil is stored as NULL until the parent arrives
 		ReplyToExternalID: message.ReplyToExternalId,
 		DeliveryStatus:    convertProtoMessageStatus(message.Status),
 		PlatformMetadata:  platformMetadata,
@@ -491,12 +1350,65 @@ func (s *IntegrationServer) upsertMessage(ctx context.Context, integrationCtx *p
 	if err != nil {
 		return fmt.Errorf("failed to upsert message: %w", err)
 	}
+	if msg.Inserted {
+		err = qtx.CountNewMessage(ctx, gen.CountNewMessageParams{
+			ConversationID:   conversationID,
+			Incoming:         !message.IsFromMe,
+			MessageTimestamp: message.Timestamp.AsTime(),
+			Live:             live,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to count message: %w", err)
+		}
+
+		err = qtx.ScheduleMessageExpiry(ctx, gen.ScheduleMessageExpiryParams{
+			MessageID:         msg.ID,
+			ConversationID:    conversationID,
+			ExpirationSeconds: metadataSeconds(message.PlatformMetadata, "expiration_seconds"),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to schedule message expiry: %w", err)
+		}
+	}
+
+	// Record or clear the pending reply link for this message
+	if message.ReplyToExternalId != "" && replyToMessageID == uuid.Nil {
+		err = qtx.CreatePendingMessageReply(ctx, gen.CreatePendingMessageReplyParams{
+			MessageID:               msg.ID,
+			ConversationID:          conversationID,
+			ParentExternalMessageID: message.ReplyToExternalId,
+		})
+	} else {
+		err = qtx.DeletePendingMessageReply(ctx, msg.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update pending reply link: %w", err)
+	}
+
+	// Link replies that were synced before this message
+	resolved, err := qtx.ResolvePendingReplies(ctx, gen.ResolvePendingRepliesParams{
+		ParentMessageID:         msg.ID,
+		ConversationID:          conversationID,
+		ParentExternalMessageID: message.PlatformId,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resolve pending replies: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit message: %w", err)
+	}
+	if resolved > 0 {
+		s.log(ctx).Debug("Resolved pending replies",
+			zap.String("message_id", message.PlatformId),
+			zap.Int64("count", resolved))
+	}
 
 	// Upsert media attachments
 	for _, media := range message.Media {
 		err := s.upsertMessageMedia(ctx, msg.ID, media)
 		if err != nil {
-			s.logger.Error("Failed to upsert message media",
+			s.log(ctx).Error("Failed to upsert message media",
 				zap.String("message_id", message.PlatformId),
 				zap.Error(err))
 		}
@@ -505,6 +1417,165 @@ func (s *IntegrationServer) upsertMessage(ctx context.Context, integrationCtx *p
 	return nil
 }
 
+// ensureConversation returns the internal ID of a conversation, creating a minimal
+// one for real-time events that arrive before the conversation was synced
+func (s *IntegrationServer) ensureConversation(ctx context.Context, integrationCtx *proto.IntegrationContext, conversationExternalID string) (uuid.UUID, error) {
+	params := gen.GetConversationByExternalIDParams{
+		UserIntegrationID:      integrationCtx.UserIntegrationId,
+		ExternalConversationID: conversationExternalID,
+	}
+	conversation, err := s.db.GetConversationByExternalID(ctx, params)
+	if err == nil {
+		return conversation.ID, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("failed to get conversation %s: %w", conversationExternalID, err)
+	}
+
+	// History sync doesn't guarantee conversations arrive before their messages.
+	// Create a placeholder that the conversation sync fills in later.
+	s.log(ctx).Info("Conversation not synced yet, creating placeholder",
+		zap.String("conversation_id", conversationExternalID))
+
+	conversationID, err := s.db.CreatePlaceholderConversation(ctx, gen.CreatePlaceholderConversationParams{
+		UserIntegrationID:      integrationCtx.UserIntegrationId,
+		ExternalConversationID: conversationExternalID,
+		IntegrationType:        integrationCtx.IntegrationType,
+		ConversationType:       placeholderConversationType(conversationExternalID),
+	})
+	if err == nil {
+		return conversationID, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("failed to create placeholder conversation %s: %w", conversationExternalID, err)
+	}
+
+	// The conversation was synced concurrently; use it as is
+	conversation, err = s.db.GetConversationByExternalID(ctx, params)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get conversation %s: %w", conversationExternalID, err)
+	}
+	return conversation.ID, nil
+}
+
+// updateConversationAvatar stores the avatar of a conversation synced with
+// SyncTypeAvatar. Only the avatar is taken from conv; the rest of it may be
+// older than what's stored.
+func (s *IntegrationServer) updateConversationAvatar(ctx context.Context, integrationCtx *proto.IntegrationContext, conv *proto.Conversation) error {
+	externalConversationID, err := s.canonicalJID(ctx, integrationCtx, conv.PlatformId)
+	if err != nil {
+		return err
+	}
+
+	updated, err := s.db.UpdateConversationAvatar(ctx, gen.UpdateConversationAvatarParams{
+		AvatarUrl:              conv.AvatarUrl,
+		PictureID:              conv.PlatformMetadata[events.AvatarPictureIDKey],
+		PictureIDKey:           events.AvatarPictureIDKey,
+		UserIntegrationID:      integrationCtx.UserIntegrationId,
+		ExternalConversationID: externalConversationID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update conversation avatar: %w", err)
+	}
+	if updated > 0 {
+		s.log(ctx).Debug("Updated conversation avatar",
+			zap.String("external_conversation_id", externalConversationID),
+			zap.Bool("removed", conv.AvatarUrl == ""))
+	}
+	return nil
+}
+
+// canonicalJID returns the phone-number JID of a LID with a known mapping, so
+// that everything from one contact is stored under a single ID. Other IDs are
+// returned unchanged.
+func (s *IntegrationServer) canonicalJID(ctx context.Context, integrationCtx *proto.IntegrationContext, jid string) (string, error) {
+	if !strings.HasSuffix(jid, "@lid") {
+		return jid, nil
+	}
+
+	pnJID, err := s.db.GetContactIdentityByLID(ctx, gen.GetContactIdentityByLIDParams{
+		UserIntegrationID: integrationCtx.UserIntegrationId,
+		LidJid:            jid,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return jid, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve identity %s: %w", jid, err)
+	}
+	return pnJID, nil
+}
+
+// metadataSeconds reads a duration in seconds from platform metadata, or 0
+// when it's missing or malformed
+func metadataSeconds(metadata map[string]string, key string) int32 {
+	seconds, err := strconv.ParseInt(metadata[key], 10, 32)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return int32(seconds)
+}
+
+// placeholderConversationType guesses the conversation type from a WhatsApp JID
+func placeholderConversationType(conversationExternalID string) string {
+	switch {
+	case strings.HasSuffix(conversationExternalID, "@g.us"):
+		return "group"
+	case strings.HasSuffix(conversationExternalID, "@broadcast"):
+		return "broadcast"
+	case strings.HasSuffix(conversationExternalID, "@newsletter"):
+		return "channel"
+	default:
+		return "individual"
+	}
+}
+
+// lockReplyLinks takes transaction-scoped locks on the given external message IDs.
+// A reply locks its parent's ID and every message locks its own, so when a parent
+// and its reply are upserted concurrently the second one sees the first one's rows.
+// Locks are taken in sorted order to avoid deadlocks.
+func lockReplyLinks(ctx context.Context, q *gen.Queries, conversationID uuid.UUID, externalMessageIDs ...string) error {
+	keys := make([]string, 0, len(externalMessageIDs))
+	for _, id := range externalMessageIDs {
+		if id != "" {
+			keys = append(keys, id)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		err := q.LockMessageReplyLink(ctx, gen.LockMessageReplyLinkParams{
+			ConversationID:    conversationID.String(),
+			ExternalMessageID: key,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to lock reply link %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// resolveReplyToMessageID looks up the internal ID of the message being replied to.
+// It returns uuid.Nil when there is no reply or the parent hasn't been synced yet.
+func resolveReplyToMessageID(ctx context.Context, q *gen.Queries, conversationID uuid.UUID, replyToExternalID string) (uuid.UUID, error) {
+	if replyToExternalID == "" {
+		return uuid.Nil, nil
+	}
+
+	parent, err := q.GetMessageByExternalID(ctx, gen.GetMessageByExternalIDParams{
+		ConversationID:    conversationID,
+		ExternalMessageID: replyToExternalID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, fmt.Errorf("failed to look up reply parent %s: %w", replyToExternalID, err)
+	}
+
+	return parent.ID, nil
+}
+
 func (s *IntegrationServer) upsertMessageMedia(ctx context.Context, messageID uuid.UUID, media *proto.MessageMedia) error {
 	var platformMetadata json.RawMessage = []byte("{}")
 	if len(media.PlatformMetadata) > 0 {
@@ -530,6 +1601,7 @@ func (s *IntegrationServer) upsertMessageMedia(ctx context.Context, messageID uu
 		DownloadStatus:   convertProtoDownloadStatus(media.DownloadStatus),
 		DownloadedAt:     time.Time{}, // Zero time value
 		PlatformMetadata: platformMetadata,
+		ContentHash:      media.ContentHash,
 	})
 	return err
 }
@@ -0,0 +1,1798 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/http/handlers"
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/backend/internal/testutil"
+	gen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/events"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+func createTestIntegration(t testing.TB, pool *pgxpool.Pool) *proto.IntegrationContext {
+	t.Helper()
+	ctx := context.Background()
+
+	var userID uuid.UUID
+	err := pool.QueryRow(ctx, `
+		INSERT INTO users (username, email, password_hash)
+		VALUES ('reply-test', 'reply-test@example.com', 'x')
+		RETURNING id`).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	var integrationID int32
+	err = pool.QueryRow(ctx, `
+		INSERT INTO user_integrations (user_id, integration_type, external_id, status)
+		VALUES ($1, 'whatsapp', 'reply-test@s.whatsapp.net', 'connected')
+		RETURNING id`, userID).Scan(&integrationID)
+	if err != nil {
+		t.Fatalf("failed to create integration: %v", err)
+	}
+
+	return &proto.IntegrationContext{
+		UserId:            userID.String(),
+		UserIntegrationId: integrationID,
+		IntegrationType:   "whatsapp",
+		PlatformUserId:    "reply-test@s.whatsapp.net",
+	}
+}
+
+func TestUpsertMessageResolvesReplyWhenParentArrivesLater(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	queries := gen.New(pool)
+	server := NewIntegrationServer(nil, nil, pool, queries, 0, zap.NewNop())
+	ctx := context.Background()
+
+	const chatID = "123456789@s.whatsapp.net"
+	child := &proto.Message{
+		PlatformId:        "CHILD",
+		ConversationId:    chatID,
+		SenderId:          chatID,
+		MessageType:       proto.MessageType_MESSAGE_TYPE_TEXT,
+		Content:           "reply",
+		Timestamp:         timestamppb.New(time.Unix(1700000100, 0)),
+		ReplyToExternalId: "PARENT",
+	}
+	parent := &proto.Message{
+		PlatformId:     "PARENT",
+		ConversationId: chatID,
+		SenderId:       chatID,
+		MessageType:    proto.MessageType_MESSAGE_TYPE_TEXT,
+		Content:        "original",
+		Timestamp:      timestamppb.New(time.Unix(1700000000, 0)),
+	}
+
+	// History sync delivers the reply before the message it replies to
+	if err := server.upsertMessage(ctx, integrationCtx, chatID, child, false); err != nil {
+		t.Fatalf("failed to upsert child: %v", err)
+	}
+
+	var childSeqBefore int64
+	var replyTo *uuid.UUID
+	err := pool.QueryRow(ctx, `SELECT seq, reply_to_message_id FROM messages WHERE external_message_id = 'CHILD'`).
+		Scan(&childSeqBefore, &replyTo)
+	if err != nil {
+		t.Fatalf("failed to read child: %v", err)
+	}
+	if replyTo != nil {
+		t.Fatalf("expected unresolved reply, got reply_to_message_id %s", replyTo)
+	}
+	if count := countPendingReplies(t, pool); count != 1 {
+		t.Fatalf("expected 1 pending reply after child, got %d", count)
+	}
+
+	if err := server.upsertMessage(ctx, integrationCtx, chatID, parent, false); err != nil {
+		t.Fatalf("failed to upsert parent: %v", err)
+	}
+
+	var parentID uuid.UUID
+	var parentSeq int64
+	err = pool.QueryRow(ctx, `SELECT id, seq FROM messages WHERE external_message_id = 'PARENT'`).
+		Scan(&parentID, &parentSeq)
+	if err != nil {
+		t.Fatalf("failed to read parent: %v", err)
+	}
+
+	var childSeqAfter int64
+	err = pool.QueryRow(ctx, `SELECT seq, reply_to_message_id FROM messages WHERE external_message_id = 'CHILD'`).
+		Scan(&childSeqAfter, &replyTo)
+	if err != nil {
+		t.Fatalf("failed to read child: %v", err)
+	}
+	if replyTo == nil || *replyTo != parentID {
+		t.Fatalf("expected reply_to_message_id %s, got %v", parentID, replyTo)
+	}
+	if childSeqAfter <= childSeqBefore || childSeqAfter <= parentSeq {
+		t.Fatalf("expected child seq to be bumped past %d and %d, got %d", childSeqBefore, parentSeq, childSeqAfter)
+	}
+	if count := countPendingReplies(t, pool); count != 0 {
+		t.Fatalf("expected pending reply to be deleted, got %d", count)
+	}
+
+	// The message history endpoint exposes the link as a UUID string or null
+	apiHandler := handlers.NewAPIHandler(nil, nil, nil, nil, nil, queries, "test-secret", zap.NewNop())
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/sync/messages/%d", integrationCtx.UserIntegrationId), nil)
+	rec := httptest.NewRecorder()
+	apiHandler.Routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 from /sync/messages, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response struct {
+		Messages []map[string]json.RawMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	replyLinks := make(map[string]string)
+	for _, message := range response.Messages {
+		var externalID string
+		json.Unmarshal(message["external_message_id"], &externalID)
+		replyLinks[externalID] = string(message["reply_to_message_id"])
+	}
+	if got, want := replyLinks["CHILD"], `"`+parentID.String()+`"`; got != want {
+		t.Errorf("expected child reply_to_message_id %s, got %s", want, got)
+	}
+	if got := replyLinks["PARENT"]; got != "null" {
+		t.Errorf("expected parent reply_to_message_id null, got %s", got)
+	}
+}
+
+func countPendingReplies(t *testing.T, pool *pgxpool.Pool) int64 {
+	t.Helper()
+
+	var count int64
+	if err := pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM pending_message_replies`).Scan(&count); err != nil {
+		t.Fatalf("failed to count pending replies: %v", err)
+	}
+	return count
+}
+
+func TestUpsertMessageBeforeConversationCreatesPlaceholder(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	server := NewIntegrationServer(nil, nil, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	const groupID = "120363000000000000@g.us"
+	message := &proto.Message{
+		PlatformId:     "GROUP-MSG",
+		ConversationId: groupID,
+		SenderId:       "123456789@s.whatsapp.net",
+		MessageType:    proto.MessageType_MESSAGE_TYPE_TEXT,
+		Content:        "hello group",
+		Timestamp:      timestamppb.New(time.Unix(1700000000, 0)),
+	}
+
+	// History sync delivers the message before its conversation
+	if err := server.upsertMessage(ctx, integrationCtx, groupID, message, false); err != nil {
+		t.Fatalf("failed to upsert message: %v", err)
+	}
+
+	var placeholderID uuid.UUID
+	var conversationType string
+	var metadata map[string]string
+	err := pool.QueryRow(ctx, `SELECT id, conversation_type, platform_metadata FROM conversations WHERE external_conversation_id = $1`, groupID).
+		Scan(&placeholderID, &conversationType, &metadata)
+	if err != nil {
+		t.Fatalf("failed to read placeholder conversation: %v", err)
+	}
+	if conversationType != "group" || metadata["placeholder"] != "true" {
+		t.Fatalf("expected group placeholder, got type %q metadata %v", conversationType, metadata)
+	}
+
+	var messageConversationID uuid.UUID
+	err = pool.QueryRow(ctx, `SELECT conversation_id FROM messages WHERE external_message_id = 'GROUP-MSG'`).Scan(&messageConversationID)
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	if messageConversationID != placeholderID {
+		t.Fatalf("expected message in conversation %s, got %s", placeholderID, messageConversationID)
+	}
+
+	// The conversation sync fills in the placeholder
+	err = server.upsertConversation(ctx, integrationCtx, &proto.Conversation{
+		PlatformId:       groupID,
+		Type:             proto.ConversationType_CONVERSATION_TYPE_GROUP,
+		Name:             "Weekend plans",
+		PlatformMetadata: map[string]string{},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to upsert conversation: %v", err)
+	}
+
+	// Later messages don't overwrite the synced conversation
+	message.PlatformId = "GROUP-MSG-2"
+	if err := server.upsertMessage(ctx, integrationCtx, groupID, message, false); err != nil {
+		t.Fatalf("failed to upsert second message: %v", err)
+	}
+
+	var conversationID uuid.UUID
+	var name string
+	err = pool.QueryRow(ctx, `SELECT id, name, platform_metadata FROM conversations WHERE external_conversation_id = $1`, groupID).
+		Scan(&conversationID, &name, &metadata)
+	if err != nil {
+		t.Fatalf("failed to read conversation: %v", err)
+	}
+	if conversationID != placeholderID || name != "Weekend plans" {
+		t.Fatalf("expected conversation %s named %q, got %s named %q", placeholderID, "Weekend plans", conversationID, name)
+	}
+	if _, ok := metadata["placeholder"]; ok {
+		t.Fatalf("expected placeholder marker to be cleared, got metadata %v", metadata)
+	}
+}
+
+func TestMessageDeliveredTwiceCountsOnce(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	server := NewIntegrationServer(nil, nil, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	counters := func() (total, unread int32) {
+		t.Helper()
+		err := pool.QueryRow(ctx, `SELECT total_message_count, unread_count FROM conversations WHERE external_conversation_id = $1`, testPN).
+			Scan(&total, &unread)
+		if err != nil {
+			t.Fatalf("failed to read counters: %v", err)
+		}
+		return total, unread
+	}
+	live := func(msg *proto.Message) {
+		t.Helper()
+		_, err := server.ProcessMessage(ctx, &proto.ProcessMessageRequest{Context: integrationCtx, Message: msg})
+		if err != nil {
+			t.Fatalf("ProcessMessage(%s): %v", msg.PlatformId, err)
+		}
+	}
+	history := func(msg *proto.Message) {
+		t.Helper()
+		if err := server.upsertMessage(ctx, integrationCtx, testPN, msg, false); err != nil {
+			t.Fatalf("upsertMessage(%s): %v", msg.PlatformId, err)
+		}
+	}
+
+	// After pairing, a message arrives live and then again in the history sync
+	first := testMessage("DUP1", testPN, testPN, "", 1700000000)
+	live(first)
+	history(first)
+	live(first)
+	if total, unread := counters(); total != 1 || unread != 1 {
+		t.Fatalf("expected the message counted once, got total %d unread %d", total, unread)
+	}
+
+	// The other order: history has it first, so it was already counted in
+	// WhatsApp's unread count
+	second := testMessage("DUP2", testPN, testPN, "", 1700000010)
+	history(second)
+	live(second)
+	if total, unread := counters(); total != 2 || unread != 1 {
+		t.Fatalf("expected 2 messages with 1 unread, got total %d unread %d", total, unread)
+	}
+
+	// The account's own messages aren't unread
+	own := testMessage("OWN1", testPN, "reply-test@s.whatsapp.net", "", 1700000020)
+	own.IsFromMe = true
+	live(own)
+	if total, unread := counters(); total != 3 || unread != 1 {
+		t.Errorf("expected 3 messages with 1 unread, got total %d unread %d", total, unread)
+	}
+}
+
+func TestDisappearingMessagesArePurged(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	eventRepo := repo.NewEventRepository(pool)
+	eventService := core.NewEventService(eventRepo, nil, "", false, zap.NewNop())
+	server := NewIntegrationServer(nil, eventService, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	live := func(msg *proto.Message) {
+		t.Helper()
+		if _, err := server.ProcessMessage(ctx, &proto.ProcessMessageRequest{Context: integrationCtx, Message: msg}); err != nil {
+			t.Fatalf("ProcessMessage(%s): %v", msg.PlatformId, err)
+		}
+	}
+	now := time.Now()
+
+	// A message whose one-day timer ran out, one still within it, and one
+	// sent before disappearing messages were turned on
+	expired := testMessage("GONE", testPN, testPN, "", now.Add(-25*time.Hour).Unix())
+	expired.PlatformMetadata = map[string]string{"expiration_seconds": "86400"}
+	fresh := testMessage("FRESH", testPN, testPN, "", now.Add(-time.Hour).Unix())
+	fresh.PlatformMetadata = map[string]string{"expiration_seconds": "86400"}
+	kept := testMessage("KEPT", testPN, testPN, "", now.Add(-48*time.Hour).Unix())
+	live(expired)
+	live(fresh)
+	live(kept)
+
+	// Copies of the expired message's content: its event, archived once, and
+	// an attachment no other message uses
+	messageEvent := func(waMessageID string) repo.InsertEventResult {
+		t.Helper()
+		result, err := eventRepo.InsertEvent(ctx, repo.InsertEventParams{
+			ID:          uuid.New(),
+			Type:        events.TypeMessageIn,
+			AccountID:   integrationCtx.UserId,
+			ConvoID:     testPN,
+			WaMessageID: sql.NullString{String: waMessageID, Valid: true},
+			Payload:     json.RawMessage(`{"content_type":"text","content":{"text":"secret"}}`),
+		})
+		if err != nil {
+			t.Fatalf("failed to insert event: %v", err)
+		}
+		return result
+	}
+	goneEvent := messageEvent("GONE")
+	freshEvent := messageEvent("FRESH")
+	_, err := pool.Exec(ctx, `
+		INSERT INTO events_archive (seq, id, ts, type, account_id, convo_id, wa_message_id, payload)
+		SELECT seq, id, ts, type, account_id, convo_id, wa_message_id, payload FROM events WHERE seq = $1`, goneEvent.Seq)
+	if err != nil {
+		t.Fatalf("failed to archive event: %v", err)
+	}
+	mediaStore := core.NewMediaStore(t.TempDir())
+	hash, blobPath, size, err := mediaStore.Add(strings.NewReader("expiring photo"))
+	if err != nil {
+		t.Fatalf("failed to store media: %v", err)
+	}
+	_, err = pool.Exec(ctx, `
+		INSERT INTO media_blobs (content_hash, mime_type, size_bytes, storage_url) VALUES ($1, 'image/jpeg', $2, $3)`,
+		hash, size, blobPath)
+	if err != nil {
+		t.Fatalf("failed to insert media blob: %v", err)
+	}
+	_, err = pool.Exec(ctx, `
+		INSERT INTO message_media (message_id, media_type, content_hash)
+		SELECT id, 'image', $1 FROM messages WHERE external_message_id = 'GONE'`, hash)
+	if err != nil {
+		t.Fatalf("failed to attach media: %v", err)
+	}
+
+	worker := core.NewMessageExpiryWorker(repo.NewMessageRepository(pool), eventService, mediaStore, core.MessageExpiryConfig{}, zap.NewNop())
+	if err := worker.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if purged := worker.Stats().Purged; purged != 1 {
+		t.Fatalf("expected 1 message purged, got %d", purged)
+	}
+
+	rows, err := pool.Query(ctx, `SELECT external_message_id FROM messages ORDER BY external_message_id`)
+	if err != nil {
+		t.Fatalf("failed to list messages: %v", err)
+	}
+	remaining, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatalf("failed to read messages: %v", err)
+	}
+	if !slices.Equal(remaining, []string{"FRESH", "KEPT"}) {
+		t.Errorf("expected FRESH and KEPT to remain, got %v", remaining)
+	}
+
+	var total, unread int32
+	err = pool.QueryRow(ctx, `SELECT total_message_count, unread_count FROM conversations WHERE external_conversation_id = $1`, testPN).
+		Scan(&total, &unread)
+	if err != nil {
+		t.Fatalf("failed to read counters: %v", err)
+	}
+	if total != 2 || unread != 2 {
+		t.Errorf("expected 2 messages with 2 unread after the purge, got total %d unread %d", total, unread)
+	}
+
+	stored, err := eventRepo.GetEventsSince(ctx, repo.GetEventsSinceParams{
+		AccountID: integrationCtx.UserId,
+		Limit:     100,
+		Types:     []string{events.TypeMessagesExpired},
+	})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	if len(stored) != 1 || stored[0].ConvoID != testPN {
+		t.Fatalf("expected 1 expiry event for %s, got %+v", testPN, stored)
+	}
+	var payload events.MessagesExpiredPayload
+	if err := json.Unmarshal(stored[0].Payload, &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if !slices.Equal(payload.MessageIDs, []string{"GONE"}) {
+		t.Errorf("expected GONE to be reported, got %v", payload.MessageIDs)
+	}
+
+	// The expired message's content is gone from its event and archived
+	// event, the fresh message's isn't
+	content := func(table string, seq int64) string {
+		t.Helper()
+		var content string
+		err := pool.QueryRow(ctx, `SELECT payload->>'content' FROM `+table+` WHERE seq = $1`, seq).Scan(&content)
+		if err != nil {
+			t.Fatalf("failed to read %s %d: %v", table, seq, err)
+		}
+		return content
+	}
+	if got := content("events", goneEvent.Seq); got != "{}" {
+		t.Errorf("expected the expired message's event to be redacted, got %s", got)
+	}
+	if got := content("events_archive", goneEvent.Seq); got != "{}" {
+		t.Errorf("expected the expired message's archived event to be redacted, got %s", got)
+	}
+	if got := content("events", freshEvent.Seq); got == "{}" {
+		t.Error("expected the fresh message's event to keep its content")
+	}
+
+	// Its attachment is gone with it
+	var blobs int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM media_blobs WHERE content_hash = $1`, hash).Scan(&blobs); err != nil {
+		t.Fatalf("failed to count media blobs: %v", err)
+	}
+	if blobs != 0 {
+		t.Errorf("expected the expired message's media blob to be deleted, got %d", blobs)
+	}
+	if mediaStore.Has(hash) {
+		t.Error("expected the expired message's media file to be removed")
+	}
+}
+
+func TestConversationCountersFollowStoredMessages(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	server := NewIntegrationServer(nil, nil, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	const unreadChat = "972502222222@s.whatsapp.net"
+	const readChat = "972503333333@s.whatsapp.net"
+	snapshot := func(chatID string, unread int32, lastMessage int64) {
+		t.Helper()
+		err := server.upsertConversation(ctx, integrationCtx, &proto.Conversation{
+			PlatformId:       chatID,
+			Type:             proto.ConversationType_CONVERSATION_TYPE_INDIVIDUAL,
+			UnreadCount:      unread,
+			LastMessageAt:    timestamppb.New(time.Unix(lastMessage, 0)),
+			LastActivityAt:   timestamppb.New(time.Unix(lastMessage, 0)),
+			PlatformMetadata: map[string]string{},
+		}, nil)
+		if err != nil {
+			t.Fatalf("failed to upsert conversation %s: %v", chatID, err)
+		}
+	}
+	store := func(chatID, id string, unix int64, live bool) {
+		t.Helper()
+		if err := server.upsertMessage(ctx, integrationCtx, chatID, testMessage(id, chatID, chatID, "", unix), live); err != nil {
+			t.Fatalf("upsertMessage(%s): %v", id, err)
+		}
+	}
+	counters := func(chatID string) (total, unread int32) {
+		t.Helper()
+		err := pool.QueryRow(ctx, `SELECT total_message_count, unread_count FROM conversations WHERE external_conversation_id = $1`, chatID).
+			Scan(&total, &unread)
+		if err != nil {
+			t.Fatalf("failed to read counters: %v", err)
+		}
+		return total, unread
+	}
+
+	// WhatsApp's unread count seeds a new conversation and covers its history
+	snapshot(unreadChat, 2, 1700000100)
+	store(unreadChat, "H1", 1700000000, false)
+	store(unreadChat, "H2", 1700000050, false)
+	store(unreadChat, "H3", 1700000100, false)
+	if total, unread := counters(unreadChat); total != 3 || unread != 2 {
+		t.Fatalf("after history: expected total 3 unread 2, got total %d unread %d", total, unread)
+	}
+	store(unreadChat, "LIVE1", 1700000200, true)
+	if total, unread := counters(unreadChat); total != 4 || unread != 3 {
+		t.Fatalf("after a live message: expected total 4 unread 3, got total %d unread %d", total, unread)
+	}
+
+	// A later snapshot doesn't overwrite the counters
+	snapshot(unreadChat, 0, 1700000200)
+	if total, unread := counters(unreadChat); total != 4 || unread != 3 {
+		t.Fatalf("after a later snapshot: expected total 4 unread 3, got total %d unread %d", total, unread)
+	}
+
+	// A conversation synced with nothing unread is read up to its last
+	// message: history after it, like messages received while the bridge was
+	// offline, is unread
+	snapshot(readChat, 0, 1700000100)
+	store(readChat, "OLD", 1700000100, false)
+	store(readChat, "OFFLINE", 1700000150, false)
+	store(readChat, "LIVE2", 1700000200, true)
+	if total, unread := counters(readChat); total != 3 || unread != 2 {
+		t.Errorf("expected total 3 unread 2 in the read conversation, got total %d unread %d", total, unread)
+	}
+}
+
+func TestUpsertConversationIgnoresStaleSnapshot(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	server := NewIntegrationServer(nil, nil, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	const chatID = "123456789@s.whatsapp.net"
+	upsert := func(name string, lastActivity time.Time) {
+		t.Helper()
+		err := server.upsertConversation(ctx, integrationCtx, &proto.Conversation{
+			PlatformId:       chatID,
+			Type:             proto.ConversationType_CONVERSATION_TYPE_INDIVIDUAL,
+			Name:             name,
+			LastMessageAt:    timestamppb.New(lastActivity),
+			LastActivityAt:   timestamppb.New(lastActivity),
+			PlatformMetadata: map[string]string{},
+		}, nil)
+		if err != nil {
+			t.Fatalf("failed to upsert conversation %q: %v", name, err)
+		}
+	}
+	read := func() (string, time.Time) {
+		t.Helper()
+		var name string
+		var lastMessageAt time.Time
+		err := pool.QueryRow(ctx, `SELECT name, last_message_at FROM conversations WHERE external_conversation_id = $1`, chatID).
+			Scan(&name, &lastMessageAt)
+		if err != nil {
+			t.Fatalf("failed to read conversation: %v", err)
+		}
+		return name, lastMessageAt
+	}
+
+	live := time.Unix(1700000200, 0)
+	upsert("Live", live)
+
+	// An older history sync batch arrives after the live update
+	upsert("Stale history", time.Unix(1700000100, 0))
+	if name, lastMessageAt := read(); name != "Live" || !lastMessageAt.Equal(live) {
+		t.Fatalf("stale snapshot overwrote conversation: name %q, last_message_at %v", name, lastMessageAt)
+	}
+
+	// Newer snapshots still apply
+	newer := time.Unix(1700000300, 0)
+	upsert("Newer", newer)
+	if name, lastMessageAt := read(); name != "Newer" || !lastMessageAt.Equal(newer) {
+		t.Fatalf("expected newer snapshot to apply, got name %q, last_message_at %v", name, lastMessageAt)
+	}
+}
+
+func TestPlaceholderConversationType(t *testing.T) {
+	tests := map[string]string{
+		"123456789@s.whatsapp.net":      "individual",
+		"120363000000000000@g.us":       "group",
+		"status@broadcast":              "broadcast",
+		"120363000000000000@newsletter": "channel",
+	}
+	for jid, want := range tests {
+		if got := placeholderConversationType(jid); got != want {
+			t.Errorf("placeholderConversationType(%q) = %q, want %q", jid, got, want)
+		}
+	}
+}
+
+// createTestConversation creates a group conversation to attach participants to
+func createTestConversation(tb testing.TB, pool *pgxpool.Pool, integrationCtx *proto.IntegrationContext) uuid.UUID {
+	tb.Helper()
+
+	var conversationID uuid.UUID
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO conversations (user_integration_id, external_conversation_id, integration_type, conversation_type)
+		VALUES ($1, '120363000000000000@g.us', 'whatsapp', 'group')
+		RETURNING id`, integrationCtx.UserIntegrationId).Scan(&conversationID)
+	if err != nil {
+		tb.Fatalf("failed to create conversation: %v", err)
+	}
+	return conversationID
+}
+
+func syntheticParticipants(n int) []*proto.ConversationParticipant {
+	participants := make([]*proto.ConversationParticipant, n)
+	for i := range participants {
+		participants[i] = &proto.ConversationParticipant{
+			ExternalUserId: fmt.Sprintf("%d@s.whatsapp.net", 972500000000+i),
+			DisplayName:    fmt.Sprintf("Member %d", i),
+			IsActive:       true,
+		}
+	}
+	return participants
+}
+
+func TestUpsertConversationParticipantsSkipsUnchanged(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	conversationID := createTestConversation(t, pool, integrationCtx)
+	server := NewIntegrationServer(nil, nil, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	// xmin changes whenever a row is rewritten
+	versions := func() map[string]string {
+		t.Helper()
+		rows, err := pool.Query(ctx, `SELECT external_user_id, xmin::text FROM conversation_participants WHERE conversation_id = $1`, conversationID)
+		if err != nil {
+			t.Fatalf("failed to read participants: %v", err)
+		}
+		defer rows.Close()
+
+		result := make(map[string]string)
+		for rows.Next() {
+			var externalUserID, xmin string
+			if err := rows.Scan(&externalUserID, &xmin); err != nil {
+				t.Fatalf("failed to scan participant: %v", err)
+			}
+			result[externalUserID] = xmin
+		}
+		return result
+	}
+
+	participants := syntheticParticipants(3)
+	seen := make(participantCache)
+
+	// A repeated participant is written once, with its last entry
+	renamed := &proto.ConversationParticipant{ExternalUserId: participants[0].ExternalUserId, DisplayName: "Renamed", Role: "admin", IsActive: true}
+	if err := server.upsertConversationParticipants(ctx, conversationID, integrationCtx, append(participants, renamed), seen); err != nil {
+		t.Fatalf("failed to upsert participants: %v", err)
+	}
+	before := versions()
+	if len(before) != 3 {
+		t.Fatalf("expected 3 participants, got %d", len(before))
+	}
+	var name, role string
+	err := pool.QueryRow(ctx, `SELECT display_name, role FROM conversation_participants WHERE conversation_id = $1 AND external_user_id = $2`,
+		conversationID, renamed.ExternalUserId).Scan(&name, &role)
+	if err != nil {
+		t.Fatalf("failed to read participant: %v", err)
+	}
+	if name != "Renamed" || role != "admin" {
+		t.Fatalf("expected last entry to win, got name %q role %q", name, role)
+	}
+
+	// A later batch in the same stream only rewrites the changed participant
+	changed := &proto.ConversationParticipant{ExternalUserId: participants[1].ExternalUserId, DisplayName: "Changed", IsActive: true}
+	if err := server.upsertConversationParticipants(ctx, conversationID, integrationCtx, []*proto.ConversationParticipant{renamed, changed, participants[2]}, seen); err != nil {
+		t.Fatalf("failed to upsert participants: %v", err)
+	}
+	after := versions()
+	for externalUserID, xmin := range after {
+		rewritten := xmin != before[externalUserID]
+		if want := externalUserID == changed.ExternalUserId; rewritten != want {
+			t.Errorf("participant %s: expected rewritten=%v, got %v", externalUserID, want, rewritten)
+		}
+	}
+}
+
+// BenchmarkUpsertConversationParticipants compares writing a 2000-member group
+// one row at a time with the single-statement upsert. The ways of writing are
+// a benchstat column:
+//
+//	TENNEX_TEST_DATABASE_URL=... go test ./internal/grpc/server -run '^$' \
+//	    -bench UpsertConversationParticipants -benchmem -count 10 > participants.txt
+//	benchstat -col /write participants.txt
+func BenchmarkUpsertConversationParticipants(b *testing.B) {
+	pool := testutil.SetupTestDB(b)
+	integrationCtx := createTestIntegration(b, pool)
+	conversationID := createTestConversation(b, pool, integrationCtx)
+	queries := gen.New(pool)
+	server := NewIntegrationServer(nil, nil, pool, queries, 0, zap.NewNop())
+	ctx := context.Background()
+	participants := syntheticParticipants(2000)
+
+	b.Run("write=per_row", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, participant := range participants {
+				_, err := queries.UpsertConversationParticipant(ctx, gen.UpsertConversationParticipantParams{
+					ConversationID:   conversationID,
+					ExternalUserID:   participant.ExternalUserId,
+					IntegrationType:  integrationCtx.IntegrationType,
+					DisplayName:      participant.DisplayName,
+					Role:             "member",
+					IsActive:         participant.IsActive,
+					PlatformMetadata: json.RawMessage("{}"),
+				})
+				if err != nil {
+					b.Fatalf("failed to upsert participant: %v", err)
+				}
+			}
+		}
+	})
+
+	b.Run("write=bulk", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := server.upsertConversationParticipants(ctx, conversationID, integrationCtx, participants, nil); err != nil {
+				b.Fatalf("failed to upsert participants: %v", err)
+			}
+		}
+	})
+
+	b.Run("write=bulk_unchanged_in_stream", func(b *testing.B) {
+		seen := make(participantCache)
+		if err := server.upsertConversationParticipants(ctx, conversationID, integrationCtx, participants, seen); err != nil {
+			b.Fatalf("failed to upsert participants: %v", err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := server.upsertConversationParticipants(ctx, conversationID, integrationCtx, participants, seen); err != nil {
+				b.Fatalf("failed to upsert participants: %v", err)
+			}
+		}
+	})
+}
+
+func TestUpdateConnectionStatusRecordsTransitions(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	integrationService := core.NewIntegrationService(repo.NewIntegrationRepository(pool), zap.NewNop())
+	server := NewIntegrationServer(integrationService, nil, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	for _, update := range []struct {
+		status   proto.ConnectionStatus
+		metadata map[string]string
+	}{
+		// Already connected: not a transition
+		{status: proto.ConnectionStatus_CONNECTION_STATUS_CONNECTED, metadata: map[string]string{"event_type": "connected"}},
+		{status: proto.ConnectionStatus_CONNECTION_STATUS_DISCONNECTED, metadata: map[string]string{"reason": "logged_out", "logout_reason": "401"}},
+		{status: proto.ConnectionStatus_CONNECTION_STATUS_QR_GENERATED},
+		{status: proto.ConnectionStatus_CONNECTION_STATUS_CONNECTED, metadata: map[string]string{"event_type": "connected"}},
+	} {
+		_, err := server.UpdateConnectionStatus(ctx, &proto.UpdateConnectionStatusRequest{
+			Context:   integrationCtx,
+			Status:    update.status,
+			Timestamp: timestamppb.Now(),
+			Metadata:  update.metadata,
+		})
+		if err != nil {
+			t.Fatalf("UpdateConnectionStatus(%s): %v", update.status, err)
+		}
+	}
+
+	history, err := integrationService.GetIntegrationStatusHistory(ctx, integrationCtx.UserIntegrationId, 10)
+	if err != nil {
+		t.Fatalf("GetIntegrationStatusHistory: %v", err)
+	}
+
+	// Newest first
+	want := []struct{ oldStatus, newStatus, reason string }{
+		{"connecting", "connected", "connected"},
+		{"disconnected", "connecting", "qr_generated"},
+		{"connected", "disconnected", "logged_out"},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("expected %d status events, got %d: %+v", len(want), len(history), history)
+	}
+	for i, w := range want {
+		got := history[i]
+		if got.OldStatus.String != w.oldStatus || got.NewStatus != w.newStatus || got.Reason.String != w.reason {
+			t.Errorf("event %d: expected %s -> %s (%s), got %s -> %s (%s)", i,
+				w.oldStatus, w.newStatus, w.reason, got.OldStatus.String, got.NewStatus, got.Reason.String)
+		}
+	}
+
+	var metadata map[string]string
+	if err := json.Unmarshal(history[2].Metadata, &metadata); err != nil {
+		t.Fatalf("failed to decode metadata: %v", err)
+	}
+	if metadata["logout_reason"] != "401" {
+		t.Errorf("expected request metadata to be recorded, got %v", metadata)
+	}
+}
+
+func TestUpdateConnectionStatusTrimsHistory(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	integrationService := core.NewIntegrationService(repo.NewIntegrationRepository(pool), zap.NewNop())
+	server := NewIntegrationServer(integrationService, nil, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `
+		INSERT INTO integration_status_events (user_integration_id, old_status, new_status)
+		SELECT $1, 'disconnected', 'connected' FROM generate_series(1, $2)`,
+		integrationCtx.UserIntegrationId, repo.MaxIntegrationStatusEvents)
+	if err != nil {
+		t.Fatalf("failed to seed status events: %v", err)
+	}
+
+	_, err = server.UpdateConnectionStatus(ctx, &proto.UpdateConnectionStatusRequest{
+		Context: integrationCtx,
+		Status:  proto.ConnectionStatus_CONNECTION_STATUS_DISCONNECTED,
+	})
+	if err != nil {
+		t.Fatalf("UpdateConnectionStatus: %v", err)
+	}
+
+	var count int
+	err = pool.QueryRow(ctx, `SELECT COUNT(*) FROM integration_status_events WHERE user_integration_id = $1`,
+		integrationCtx.UserIntegrationId).Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to count status events: %v", err)
+	}
+	if count != repo.MaxIntegrationStatusEvents {
+		t.Errorf("expected history trimmed to %d events, got %d", repo.MaxIntegrationStatusEvents, count)
+	}
+
+	history, err := integrationService.GetIntegrationStatusHistory(ctx, integrationCtx.UserIntegrationId, 1)
+	if err != nil {
+		t.Fatalf("GetIntegrationStatusHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].NewStatus != "disconnected" {
+		t.Errorf("expected the newest transition to survive the trim, got %+v", history)
+	}
+}
+
+const (
+	testLID = "123456789012345@lid"
+	testPN  = "972501234567@s.whatsapp.net"
+)
+
+func testMessage(id, chatID, senderID, replyTo string, unix int64) *proto.Message {
+	return &proto.Message{
+		PlatformId:        id,
+		ConversationId:    chatID,
+		SenderId:          senderID,
+		MessageType:       proto.MessageType_MESSAGE_TYPE_TEXT,
+		Content:           id,
+		Timestamp:         timestamppb.New(time.Unix(unix, 0)),
+		ReplyToExternalId: replyTo,
+	}
+}
+
+// conversationIDs returns the external IDs of the integration's conversations
+func conversationIDs(t *testing.T, pool *pgxpool.Pool, integrationCtx *proto.IntegrationContext) []string {
+	t.Helper()
+
+	rows, err := pool.Query(context.Background(), `
+		SELECT external_conversation_id FROM conversations
+		WHERE user_integration_id = $1 ORDER BY external_conversation_id`, integrationCtx.UserIntegrationId)
+	if err != nil {
+		t.Fatalf("failed to list conversations: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("failed to scan conversation: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestSyncIdentityMappingsMergesEarlierLIDConversation(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	server := NewIntegrationServer(nil, nil, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	// Messages from the LID arrive before anything says who it belongs to
+	for _, msg := range []*proto.Message{
+		testMessage("PN1", testPN, testPN, "", 1700000000),
+		testMessage("LID1", testLID, testLID, "", 1700000100),
+		testMessage("LID2", testLID, testLID, "PN1", 1700000200),
+		// The same message delivered under both identities
+		testMessage("BOTH", testPN, testPN, "", 1700000300),
+		testMessage("BOTH", testLID, testLID, "", 1700000300),
+	} {
+		if err := server.upsertMessage(ctx, integrationCtx, msg.ConversationId, msg, false); err != nil {
+			t.Fatalf("failed to upsert %s: %v", msg.PlatformId, err)
+		}
+	}
+	if err := server.upsertContact(ctx, integrationCtx, &proto.Contact{PlatformId: testLID, DisplayName: "Dana"}); err != nil {
+		t.Fatalf("failed to upsert contact: %v", err)
+	}
+	if ids := conversationIDs(t, pool, integrationCtx); len(ids) != 2 {
+		t.Fatalf("expected separate LID and phone-number conversations before the mapping, got %v", ids)
+	}
+
+	var seqBefore int64
+	if err := pool.QueryRow(ctx, `SELECT seq FROM messages WHERE external_message_id = 'LID1'`).Scan(&seqBefore); err != nil {
+		t.Fatalf("failed to read LID1: %v", err)
+	}
+
+	req := &proto.SyncIdentityMappingsRequest{
+		Context:  integrationCtx,
+		Mappings: []*proto.IdentityMapping{{LidJid: testLID, PnJid: testPN}},
+	}
+	resp, err := server.SyncIdentityMappings(ctx, req)
+	if err != nil {
+		t.Fatalf("SyncIdentityMappings: %v", err)
+	}
+	if resp.MergedCount != 1 {
+		t.Errorf("expected 1 merged conversation, got %d", resp.MergedCount)
+	}
+
+	if ids := conversationIDs(t, pool, integrationCtx); len(ids) != 1 || ids[0] != testPN {
+		t.Fatalf("expected only the phone-number conversation after the mapping, got %v", ids)
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT m.external_message_id, m.sender_external_id, m.seq
+		FROM messages m JOIN conversations c ON c.id = m.conversation_id
+		WHERE c.external_conversation_id = $1`, testPN)
+	if err != nil {
+		t.Fatalf("failed to list messages: %v", err)
+	}
+	defer rows.Close()
+	messages := make(map[string]string)
+	for rows.Next() {
+		var id, sender string
+		var seq int64
+		if err := rows.Scan(&id, &sender, &seq); err != nil {
+			t.Fatalf("failed to scan message: %v", err)
+		}
+		messages[id] = sender
+		if id == "LID1" && seq <= seqBefore {
+			t.Errorf("expected moved message to get a new seq, got %d (was %d)", seq, seqBefore)
+		}
+	}
+	if len(messages) != 4 {
+		t.Errorf("expected 4 messages in the merged conversation, got %v", messages)
+	}
+	for id, sender := range messages {
+		if sender != testPN {
+			t.Errorf("message %s: expected sender %s, got %s", id, testPN, sender)
+		}
+	}
+
+	// The reply across the split resolves once both sides are merged
+	var parentMatches bool
+	err = pool.QueryRow(ctx, `
+		SELECT child.reply_to_message_id = parent.id
+		FROM messages child, messages parent
+		WHERE child.external_message_id = 'LID2' AND parent.external_message_id = 'PN1'`).Scan(&parentMatches)
+	if err != nil {
+		t.Fatalf("failed to read reply: %v", err)
+	}
+	if !parentMatches {
+		t.Error("expected LID2 to reply to PN1 after the merge")
+	}
+	if count := countPendingReplies(t, pool); count != 0 {
+		t.Errorf("expected no pending replies after the merge, got %d", count)
+	}
+
+	var contactID string
+	if err := pool.QueryRow(ctx, `SELECT external_contact_id FROM contacts WHERE display_name = 'Dana'`).Scan(&contactID); err != nil {
+		t.Fatalf("failed to read contact: %v", err)
+	}
+	if contactID != testPN {
+		t.Errorf("expected LID contact to move to %s, got %s", testPN, contactID)
+	}
+
+	// A repeated mapping has nothing left to merge
+	resp, err = server.SyncIdentityMappings(ctx, req)
+	if err != nil {
+		t.Fatalf("SyncIdentityMappings: %v", err)
+	}
+	if resp.MergedCount != 0 {
+		t.Errorf("expected a known mapping not to merge again, got %d", resp.MergedCount)
+	}
+}
+
+func TestSyncIdentityMappingsResolvesLaterLIDMessages(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	server := NewIntegrationServer(nil, nil, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	resp, err := server.SyncIdentityMappings(ctx, &proto.SyncIdentityMappingsRequest{
+		Context:  integrationCtx,
+		Mappings: []*proto.IdentityMapping{{LidJid: testLID, PnJid: testPN}},
+	})
+	if err != nil {
+		t.Fatalf("SyncIdentityMappings: %v", err)
+	}
+	if resp.MergedCount != 0 {
+		t.Errorf("expected nothing to merge before any messages, got %d", resp.MergedCount)
+	}
+
+	if err := server.upsertMessage(ctx, integrationCtx, testLID, testMessage("LID1", testLID, testLID, "", 1700000000), false); err != nil {
+		t.Fatalf("failed to upsert message: %v", err)
+	}
+	_, err = server.ProcessPollVote(ctx, &proto.ProcessPollVoteRequest{
+		Context: integrationCtx,
+		Vote: &proto.PollVote{
+			PollMessageId:  "POLL",
+			ConversationId: testLID,
+			VoterId:        testLID,
+			Timestamp:      timestamppb.New(time.Unix(1700000100, 0)),
+		},
+	})
+	if err != nil {
+		t.Fatalf("ProcessPollVote: %v", err)
+	}
+	if err := server.upsertContact(ctx, integrationCtx, &proto.Contact{PlatformId: testLID, DisplayName: "Dana"}); err != nil {
+		t.Fatalf("failed to upsert contact: %v", err)
+	}
+
+	if ids := conversationIDs(t, pool, integrationCtx); len(ids) != 1 || ids[0] != testPN {
+		t.Fatalf("expected the LID message to land in the phone-number conversation, got %v", ids)
+	}
+
+	var sender, voter, contactID string
+	err = pool.QueryRow(ctx, `
+		SELECT m.sender_external_id, v.voter_external_id, c.external_contact_id
+		FROM messages m, poll_votes v, contacts c
+		WHERE m.external_message_id = 'LID1'`).Scan(&sender, &voter, &contactID)
+	if err != nil {
+		t.Fatalf("failed to read stored identities: %v", err)
+	}
+	if sender != testPN || voter != testPN || contactID != testPN {
+		t.Errorf("expected sender, voter and contact stored as %s, got %s, %s, %s", testPN, sender, voter, contactID)
+	}
+}
+
+func TestUpdateConversationStatePublishesChangedFields(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	eventRepo := repo.NewEventRepository(pool)
+	eventService := core.NewEventService(eventRepo, nil, "", false, zap.NewNop())
+	server := NewIntegrationServer(nil, eventService, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	if err := server.upsertMessage(ctx, integrationCtx, testPN, testMessage("PN1", testPN, testPN, "", 1700000000), false); err != nil {
+		t.Fatalf("failed to upsert message: %v", err)
+	}
+
+	update := func(state *proto.ConversationState) {
+		t.Helper()
+		_, err := server.UpdateConversationState(ctx, &proto.UpdateConversationStateRequest{
+			Context:                integrationCtx,
+			ConversationExternalId: testPN,
+			State:                  state,
+		})
+		if err != nil {
+			t.Fatalf("UpdateConversationState: %v", err)
+		}
+	}
+	stateEvents := func() []events.ConversationStatePayload {
+		t.Helper()
+		stored, err := eventRepo.GetEventsSince(ctx, repo.GetEventsSinceParams{
+			AccountID: integrationCtx.UserId,
+			Limit:     100,
+			Types:     []string{events.TypeConversationState},
+		})
+		if err != nil {
+			t.Fatalf("failed to get events: %v", err)
+		}
+		payloads := make([]events.ConversationStatePayload, len(stored))
+		for i, event := range stored {
+			if event.ConvoID != testPN {
+				t.Errorf("expected event for %s, got %s", testPN, event.ConvoID)
+			}
+			if err := json.Unmarshal(event.Payload, &payloads[i]); err != nil {
+				t.Fatalf("failed to decode payload: %v", err)
+			}
+		}
+		return payloads
+	}
+
+	update(&proto.ConversationState{IsPinned: true})
+	// Repeating the same state isn't a change
+	update(&proto.ConversationState{IsPinned: true})
+	muteUntil := time.Unix(1800000000, 0).UTC()
+	update(&proto.ConversationState{IsPinned: true, IsMuted: true, MuteUntil: timestamppb.New(muteUntil)})
+
+	payloads := stateEvents()
+	if len(payloads) != 2 {
+		t.Fatalf("expected 2 conversation_state events, got %d", len(payloads))
+	}
+	if pin := payloads[0]; len(pin.Changed) != 1 || pin.Changed[0] != "is_pinned" || pin.IsPinned == nil || !*pin.IsPinned {
+		t.Errorf("unexpected pin event: %+v", pin)
+	}
+	mute := payloads[1]
+	if len(mute.Changed) != 2 || mute.IsPinned != nil || mute.IsMuted == nil || !*mute.IsMuted {
+		t.Errorf("unexpected mute event: %+v", mute)
+	}
+	if mute.MuteUntil == nil || !mute.MuteUntil.Equal(muteUntil) {
+		t.Errorf("expected mute_until %s, got %v", muteUntil, mute.MuteUntil)
+	}
+
+	// Conversations that haven't been synced yet are ignored
+	_, err := server.UpdateConversationState(ctx, &proto.UpdateConversationStateRequest{
+		Context:                integrationCtx,
+		ConversationExternalId: "999@s.whatsapp.net",
+		State:                  &proto.ConversationState{IsArchived: true},
+	})
+	if err != nil {
+		t.Fatalf("UpdateConversationState for unknown conversation: %v", err)
+	}
+	if got := len(stateEvents()); got != 2 {
+		t.Errorf("expected no event for an unknown conversation, got %d events", got)
+	}
+}
+
+func TestUpdatePresencePublishesUnderPhoneNumber(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	eventRepo := repo.NewEventRepository(pool)
+	eventService := core.NewEventService(eventRepo, nil, "", false, zap.NewNop())
+	server := NewIntegrationServer(nil, eventService, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	if _, err := server.SyncIdentityMappings(ctx, &proto.SyncIdentityMappingsRequest{
+		Context:  integrationCtx,
+		Mappings: []*proto.IdentityMapping{{LidJid: testLID, PnJid: testPN}},
+	}); err != nil {
+		t.Fatalf("SyncIdentityMappings: %v", err)
+	}
+
+	lastSeen := time.Unix(1700000000, 0).UTC()
+	for _, presence := range []*proto.Presence{
+		{ExternalUserId: testPN, LastSeen: timestamppb.New(lastSeen)},
+		{ExternalUserId: testLID, IsOnline: true, ConversationExternalId: testLID, IsTyping: true},
+	} {
+		if _, err := server.UpdatePresence(ctx, &proto.UpdatePresenceRequest{Context: integrationCtx, Presence: presence}); err != nil {
+			t.Fatalf("UpdatePresence: %v", err)
+		}
+	}
+
+	stored, err := eventRepo.GetEventsSince(ctx, repo.GetEventsSinceParams{
+		AccountID: integrationCtx.UserId,
+		Limit:     100,
+		Types:     []string{events.TypePresence},
+	})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("expected 2 presence events, got %d", len(stored))
+	}
+	payloads := make([]events.PresencePayload, len(stored))
+	for i, event := range stored {
+		// Presence from a LID is published for the phone number's conversation
+		if event.ConvoID != testPN {
+			t.Errorf("expected event for %s, got %s", testPN, event.ConvoID)
+		}
+		if !event.UserIntegrationID.Valid || event.UserIntegrationID.Int32 != integrationCtx.UserIntegrationId {
+			t.Errorf("expected event for integration %d, got %v", integrationCtx.UserIntegrationId, event.UserIntegrationID)
+		}
+		if err := json.Unmarshal(event.Payload, &payloads[i]); err != nil {
+			t.Fatalf("failed to decode payload: %v", err)
+		}
+	}
+	if offline := payloads[0]; offline.JID != testPN || offline.IsOnline || offline.LastSeen == nil || !offline.LastSeen.Equal(lastSeen) {
+		t.Errorf("unexpected offline presence: %+v", offline)
+	}
+	if typing := payloads[1]; typing.JID != testPN || !typing.IsOnline || !typing.IsTyping {
+		t.Errorf("unexpected typing presence: %+v", typing)
+	}
+}
+
+func TestUpdateParticipantRolesPublishesChanges(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	eventRepo := repo.NewEventRepository(pool)
+	eventService := core.NewEventService(eventRepo, nil, "", false, zap.NewNop())
+	queries := gen.New(pool)
+	server := NewIntegrationServer(nil, eventService, pool, queries, 0, zap.NewNop())
+	ctx := context.Background()
+
+	const (
+		group  = "120363000000000001@g.us"
+		owner  = "111@s.whatsapp.net"
+		member = "222@s.whatsapp.net"
+		joiner = "333@s.whatsapp.net"
+	)
+	err := server.upsertConversation(ctx, integrationCtx, &proto.Conversation{
+		PlatformId: group,
+		Type:       proto.ConversationType_CONVERSATION_TYPE_GROUP,
+		Participants: []*proto.ConversationParticipant{
+			{ExternalUserId: owner, Role: "owner", IsActive: true},
+			{ExternalUserId: member, Role: "member", IsActive: true},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to upsert group: %v", err)
+	}
+
+	changedAt := time.Unix(1700000000, 0).UTC()
+	resp, err := server.UpdateParticipantRoles(ctx, &proto.UpdateParticipantRolesRequest{
+		Context:                integrationCtx,
+		ConversationExternalId: group,
+		Participants: []*proto.ConversationParticipant{
+			{ExternalUserId: owner, Role: "owner"}, // Unchanged
+			{ExternalUserId: member, Role: "admin"},
+			{ExternalUserId: joiner, Role: "admin"}, // Not synced yet
+		},
+		ChangedByExternalId: owner,
+		ChangedAt:           timestamppb.New(changedAt),
+	})
+	if err != nil {
+		t.Fatalf("UpdateParticipantRoles: %v", err)
+	}
+	if resp.ChangedCount != 2 {
+		t.Errorf("expected 2 changed roles, got %d", resp.ChangedCount)
+	}
+
+	stored, err := eventRepo.GetEventsSince(ctx, repo.GetEventsSinceParams{
+		AccountID: integrationCtx.UserId,
+		Limit:     100,
+		Types:     []string{events.TypeParticipantRole},
+	})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	want := []events.ParticipantRolePayload{
+		{JID: member, Role: "admin", PreviousRole: "member"},
+		{JID: joiner, Role: "admin"},
+	}
+	if len(stored) != len(want) {
+		t.Fatalf("expected %d participant_role events, got %d", len(want), len(stored))
+	}
+	for i, event := range stored {
+		var payload events.ParticipantRolePayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			t.Fatalf("failed to decode payload: %v", err)
+		}
+		if event.ConvoID != group || payload.JID != want[i].JID || payload.Role != want[i].Role || payload.PreviousRole != want[i].PreviousRole {
+			t.Errorf("event %d: expected %+v for %s, got %+v for %s", i, want[i], group, payload, event.ConvoID)
+		}
+		if payload.ChangedBy != owner || payload.ChangedAt == nil || !payload.ChangedAt.Equal(changedAt) {
+			t.Errorf("event %d: expected change by %s at %s, got %q at %v", i, owner, changedAt, payload.ChangedBy, payload.ChangedAt)
+		}
+	}
+
+	conversation, err := queries.GetConversationByExternalID(ctx, gen.GetConversationByExternalIDParams{
+		UserIntegrationID:      integrationCtx.UserIntegrationId,
+		ExternalConversationID: group,
+	})
+	if err != nil {
+		t.Fatalf("failed to get group: %v", err)
+	}
+	admins, err := queries.ListConversationAdmins(ctx, conversation.ID)
+	if err != nil {
+		t.Fatalf("failed to list admins: %v", err)
+	}
+	if len(admins) != 3 {
+		t.Errorf("expected the owner and 2 admins, got %d admins", len(admins))
+	}
+
+	_, err = server.UpdateParticipantRoles(ctx, &proto.UpdateParticipantRolesRequest{
+		Context:                integrationCtx,
+		ConversationExternalId: group,
+		Participants:           []*proto.ConversationParticipant{{ExternalUserId: member, Role: "superadmin"}},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for an unknown role, got %v", err)
+	}
+}
+
+func TestUpdateOutboxDeliveryAdvancesEntry(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	eventRepo := repo.NewEventRepository(pool)
+	eventService := core.NewEventService(eventRepo, nil, "", false, zap.NewNop())
+	outboxRepo := repo.NewOutboxRepository(pool)
+	server := NewIntegrationServer(nil, eventService, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	const convo = "123456789@s.whatsapp.net"
+	clientMsgUUID := uuid.New()
+	if _, err := outboxRepo.CreateOutboxEntry(ctx, repo.CreateOutboxEntryParams{
+		ClientMsgUuid: clientMsgUUID,
+		AccountID:     integrationCtx.UserId,
+		ConvoID:       convo,
+		Status:        events.OutboxStatusSending,
+	}); err != nil {
+		t.Fatalf("failed to create outbox entry: %v", err)
+	}
+
+	update := func(messageStatus proto.MessageStatus) *proto.UpdateOutboxDeliveryResponse {
+		t.Helper()
+		resp, err := server.UpdateOutboxDelivery(ctx, &proto.UpdateOutboxDeliveryRequest{
+			Context:                integrationCtx,
+			ClientMsgUuid:          clientMsgUUID.String(),
+			WaMessageId:            "WA-OUT-1",
+			ConversationExternalId: convo,
+			Status:                 messageStatus,
+			Timestamp:              timestamppb.New(time.Unix(1700000000, 0)),
+		})
+		if err != nil {
+			t.Fatalf("UpdateOutboxDelivery(%s): %v", messageStatus, err)
+		}
+		return resp
+	}
+
+	// The delivery receipt can beat the send result
+	if !update(proto.MessageStatus_MESSAGE_STATUS_DELIVERED).Applied {
+		t.Error("expected the delivery of a sending entry to apply")
+	}
+	if err := outboxRepo.UpdateOutboxStatus(ctx, repo.UpdateOutboxStatusParams{
+		ClientMsgUuid: clientMsgUUID,
+		Status:        events.OutboxStatusSent,
+	}); err != nil {
+		t.Fatalf("failed to mark the entry sent: %v", err)
+	}
+	if !update(proto.MessageStatus_MESSAGE_STATUS_READ).Applied {
+		t.Error("expected the read to apply")
+	}
+	if update(proto.MessageStatus_MESSAGE_STATUS_DELIVERED).Applied {
+		t.Error("a delivery after the read was applied")
+	}
+
+	entry, err := outboxRepo.GetOutboxEntry(ctx, clientMsgUUID)
+	if err != nil {
+		t.Fatalf("failed to get outbox entry: %v", err)
+	}
+	if entry.Status != events.OutboxStatusRead {
+		t.Errorf("expected the entry to be read, got %s", entry.Status)
+	}
+
+	stored, err := eventRepo.GetEventsSince(ctx, repo.GetEventsSinceParams{
+		AccountID: integrationCtx.UserId,
+		Limit:     100,
+		Types:     []string{events.TypeMessageDelivery},
+	})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	want := []string{events.OutboxStatusDelivered, events.OutboxStatusRead}
+	if len(stored) != len(want) {
+		t.Fatalf("expected %d msg_delivery events, got %d", len(want), len(stored))
+	}
+	for i, event := range stored {
+		var payload events.DeliveryPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			t.Fatalf("failed to decode payload: %v", err)
+		}
+		if event.ConvoID != convo || payload.Status != want[i] || payload.ClientMsgUUID != clientMsgUUID.String() || payload.WAMessageID != "WA-OUT-1" {
+			t.Errorf("event %d: unexpected %+v for %s", i, payload, event.ConvoID)
+		}
+	}
+
+	_, err = server.UpdateOutboxDelivery(ctx, &proto.UpdateOutboxDeliveryRequest{
+		Context:       integrationCtx,
+		ClientMsgUuid: clientMsgUUID.String(),
+		Status:        proto.MessageStatus_MESSAGE_STATUS_FAILED,
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a failed status, got %v", err)
+	}
+}
+
+func TestUpdateMessageDeliveryPublishesTransitions(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	eventRepo := repo.NewEventRepository(pool)
+	eventService := core.NewEventService(eventRepo, nil, "", false, zap.NewNop())
+	server := NewIntegrationServer(nil, eventService, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	// Sent from the phone, so it only reaches the backend through sync
+	const convo = "123456789@s.whatsapp.net"
+	if err := server.upsertMessage(ctx, integrationCtx, convo, &proto.Message{
+		PlatformId:     "WA-PHONE-1",
+		ConversationId: convo,
+		SenderId:       integrationCtx.PlatformUserId,
+		MessageType:    proto.MessageType_MESSAGE_TYPE_TEXT,
+		Content:        "sent from the phone",
+		Timestamp:      timestamppb.New(time.Unix(1700000000, 0)),
+		IsFromMe:       true,
+	}, false); err != nil {
+		t.Fatalf("failed to upsert message: %v", err)
+	}
+
+	update := func(waMessageID string, messageStatus proto.MessageStatus) *proto.UpdateMessageDeliveryResponse {
+		t.Helper()
+		resp, err := server.UpdateMessageDelivery(ctx, &proto.UpdateMessageDeliveryRequest{
+			Context:                integrationCtx,
+			WaMessageId:            waMessageID,
+			ConversationExternalId: convo,
+			Status:                 messageStatus,
+			Timestamp:              timestamppb.New(time.Unix(1700000100, 0)),
+		})
+		if err != nil {
+			t.Fatalf("UpdateMessageDelivery(%s): %v", messageStatus, err)
+		}
+		return resp
+	}
+
+	if !update("WA-PHONE-1", proto.MessageStatus_MESSAGE_STATUS_DELIVERED).Applied {
+		t.Error("expected the delivery to apply")
+	}
+	if !update("WA-PHONE-1", proto.MessageStatus_MESSAGE_STATUS_READ).Applied {
+		t.Error("expected the read to apply")
+	}
+	if update("WA-PHONE-1", proto.MessageStatus_MESSAGE_STATUS_DELIVERED).Applied {
+		t.Error("a delivery after the read was applied")
+	}
+	if update("WA-UNKNOWN", proto.MessageStatus_MESSAGE_STATUS_READ).Applied {
+		t.Error("a receipt for a message that isn't synced was applied")
+	}
+
+	var deliveryStatus string
+	if err := pool.QueryRow(ctx, `SELECT delivery_status FROM messages WHERE external_message_id = 'WA-PHONE-1'`).Scan(&deliveryStatus); err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	if deliveryStatus != events.OutboxStatusRead {
+		t.Errorf("expected the message to be read, got %s", deliveryStatus)
+	}
+
+	// Each transition is an event, in order
+	stored, err := eventRepo.GetEventsSince(ctx, repo.GetEventsSinceParams{
+		AccountID: integrationCtx.UserId,
+		Limit:     100,
+		Types:     []string{events.TypeMessageDelivery},
+	})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	want := []string{events.OutboxStatusDelivered, events.OutboxStatusRead}
+	if len(stored) != len(want) {
+		t.Fatalf("expected %d msg_delivery events, got %d", len(want), len(stored))
+	}
+	for i, event := range stored {
+		var payload events.DeliveryPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			t.Fatalf("failed to decode payload: %v", err)
+		}
+		if event.ConvoID != convo || payload.Status != want[i] || payload.WAMessageID != "WA-PHONE-1" || payload.ClientMsgUUID != "" {
+			t.Errorf("event %d: unexpected %+v for %s", i, payload, event.ConvoID)
+		}
+	}
+
+	_, err = server.UpdateMessageDelivery(ctx, &proto.UpdateMessageDeliveryRequest{
+		Context:     integrationCtx,
+		WaMessageId: "WA-PHONE-1",
+		Status:      proto.MessageStatus_MESSAGE_STATUS_SENT,
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a sent status, got %v", err)
+	}
+}
+
+func TestEventsResolveToTheirIntegration(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	eventRepo := repo.NewEventRepository(pool)
+	eventService := core.NewEventService(eventRepo, nil, "", false, zap.NewNop())
+	integrationService := core.NewIntegrationService(repo.NewIntegrationRepository(pool), zap.NewNop())
+	ctx := context.Background()
+
+	seq, err := eventService.PublishPresence(ctx, integrationCtx.UserId, integrationCtx.UserIntegrationId, testPN, events.PresencePayload{JID: testPN, IsOnline: true})
+	if err != nil {
+		t.Fatalf("PublishPresence: %v", err)
+	}
+	event, err := eventRepo.GetEventBySeq(ctx, seq)
+	if err != nil {
+		t.Fatalf("failed to get event: %v", err)
+	}
+	integration, err := integrationService.GetEventIntegration(ctx, &event)
+	if err != nil {
+		t.Fatalf("GetEventIntegration: %v", err)
+	}
+	if integration.ID != integrationCtx.UserIntegrationId {
+		t.Errorf("expected integration %d, got %d", integrationCtx.UserIntegrationId, integration.ID)
+	}
+
+	// Events without an integration fall back to the account's only one
+	legacy := event
+	legacy.UserIntegrationID.Valid = false
+	if integration, err := integrationService.GetEventIntegration(ctx, &legacy); err != nil || integration.ID != integrationCtx.UserIntegrationId {
+		t.Errorf("expected fallback to integration %d, got %v (%v)", integrationCtx.UserIntegrationId, integration, err)
+	}
+
+	accountID, err := integrationService.GetIntegrationAccountID(ctx, integrationCtx.UserIntegrationId)
+	if err != nil || accountID != integrationCtx.UserId {
+		t.Errorf("expected account %s, got %q (%v)", integrationCtx.UserId, accountID, err)
+	}
+	if integration, err := integrationService.GetAccountIntegration(ctx, integrationCtx.UserId, core.IntegrationTypeWhatsApp); err != nil || integration.ID != integrationCtx.UserIntegrationId {
+		t.Errorf("expected account integration %d, got %v (%v)", integrationCtx.UserIntegrationId, integration, err)
+	}
+
+	// Once the account has a second integration the fallback is ambiguous
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO user_integrations (user_id, integration_type, external_id, status)
+		VALUES ($1, 'whatsapp', 'second@s.whatsapp.net', 'connected')`, integrationCtx.UserId); err != nil {
+		t.Fatalf("failed to create second integration: %v", err)
+	}
+	if _, err := integrationService.GetEventIntegration(ctx, &legacy); !errors.Is(err, core.ErrMultipleIntegrations) {
+		t.Errorf("expected ErrMultipleIntegrations, got %v", err)
+	}
+	if integration, err := integrationService.GetEventIntegration(ctx, &event); err != nil || integration.ID != integrationCtx.UserIntegrationId {
+		t.Errorf("expected recorded integration %d, got %v (%v)", integrationCtx.UserIntegrationId, integration, err)
+	}
+}
+
+func TestTwoWhatsAppNumbersSyncConcurrently(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationService := core.NewIntegrationService(repo.NewIntegrationRepository(pool), zap.NewNop())
+	server := NewIntegrationServer(integrationService, nil, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	var userID uuid.UUID
+	err := pool.QueryRow(ctx, `
+		INSERT INTO users (username, email, password_hash)
+		VALUES ('two-numbers', 'two-numbers@example.com', 'x')
+		RETURNING id`).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	jids := []string{"972501111111@s.whatsapp.net", "972502222222@s.whatsapp.net"}
+	const chatID = "972509999999@s.whatsapp.net"
+	const messagesPerNumber = 20
+
+	// Both numbers pair and sync the same chat at the same time
+	integrationCtxs := make([]*proto.IntegrationContext, len(jids))
+	errs := make(chan error, len(jids))
+	var wg sync.WaitGroup
+	for i, jid := range jids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			resp, err := server.CreateUserIntegration(ctx, &proto.CreateUserIntegrationRequest{
+				UserId:          userID.String(),
+				IntegrationType: core.IntegrationTypeWhatsApp,
+				PlatformUserId:  jid,
+			})
+			if err != nil {
+				errs <- fmt.Errorf("CreateUserIntegration(%s): %w", jid, err)
+				return
+			}
+
+			integrationCtx := &proto.IntegrationContext{
+				UserId:            userID.String(),
+				UserIntegrationId: resp.UserIntegrationId,
+				IntegrationType:   core.IntegrationTypeWhatsApp,
+				PlatformUserId:    jid,
+			}
+			integrationCtxs[i] = integrationCtx
+
+			for n := 0; n < messagesPerNumber; n++ {
+				msg := testMessage(fmt.Sprintf("%s-%d", jid, n), chatID, chatID, "", 1700000000+int64(n))
+				if err := server.upsertMessage(ctx, integrationCtx, chatID, msg, false); err != nil {
+					errs <- fmt.Errorf("upsertMessage(%s): %w", jid, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if integrationCtxs[0].UserIntegrationId == integrationCtxs[1].UserIntegrationId {
+		t.Fatalf("expected one integration per number, both got %d", integrationCtxs[0].UserIntegrationId)
+	}
+	for i, integrationCtx := range integrationCtxs {
+		if got := conversationIDs(t, pool, integrationCtx); len(got) != 1 || got[0] != chatID {
+			t.Errorf("%s: expected conversation %s, got %v", jids[i], chatID, got)
+		}
+
+		var count int
+		err := pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM messages m
+			JOIN conversations c ON c.id = m.conversation_id
+			WHERE c.user_integration_id = $1`, integrationCtx.UserIntegrationId).Scan(&count)
+		if err != nil {
+			t.Fatalf("failed to count messages: %v", err)
+		}
+		if count != messagesPerNumber {
+			t.Errorf("%s: expected %d messages, got %d", jids[i], messagesPerNumber, count)
+		}
+	}
+
+	// A status update without an integration ID is resolved by the JID
+	_, err = server.UpdateConnectionStatus(ctx, &proto.UpdateConnectionStatusRequest{
+		Context: &proto.IntegrationContext{
+			UserId:          userID.String(),
+			IntegrationType: core.IntegrationTypeWhatsApp,
+			PlatformUserId:  jids[1],
+		},
+		Status: proto.ConnectionStatus_CONNECTION_STATUS_DISCONNECTED,
+	})
+	if err != nil {
+		t.Fatalf("UpdateConnectionStatus: %v", err)
+	}
+
+	// Re-pairing a number keeps its integration
+	resp, err := server.CreateUserIntegration(ctx, &proto.CreateUserIntegrationRequest{
+		UserId:          userID.String(),
+		IntegrationType: core.IntegrationTypeWhatsApp,
+		PlatformUserId:  jids[0],
+	})
+	if err != nil {
+		t.Fatalf("CreateUserIntegration: %v", err)
+	}
+	if resp.UserIntegrationId != integrationCtxs[0].UserIntegrationId {
+		t.Errorf("expected re-pairing to reuse integration %d, got %d", integrationCtxs[0].UserIntegrationId, resp.UserIntegrationId)
+	}
+
+	integrations, err := integrationService.GetWhatsAppIntegrations(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetWhatsAppIntegrations: %v", err)
+	}
+	statuses := make(map[string]string)
+	for _, integration := range integrations {
+		statuses[integration.ExternalID] = integration.Status
+	}
+	if len(integrations) != 2 || statuses[jids[0]] != "connected" || statuses[jids[1]] != "disconnected" {
+		t.Errorf("expected %s connected and %s disconnected, got %v", jids[0], jids[1], statuses)
+	}
+
+	if _, err := integrationService.GetWhatsAppIntegration(ctx, userID); !errors.Is(err, core.ErrMultipleIntegrations) {
+		t.Errorf("expected ErrMultipleIntegrations, got %v", err)
+	}
+}
+
+func TestCreateUserIntegrationIdempotency(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationService := core.NewIntegrationService(repo.NewIntegrationRepository(pool), zap.NewNop())
+	server := NewIntegrationServer(integrationService, nil, pool, gen.New(pool), 0, zap.NewNop())
+	ctx := context.Background()
+
+	createUser := func(name string) uuid.UUID {
+		t.Helper()
+		var userID uuid.UUID
+		err := pool.QueryRow(ctx, `
+			INSERT INTO users (username, email, password_hash)
+			VALUES ($1, $1 || '@example.com', 'x')
+			RETURNING id`, name).Scan(&userID)
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		return userID
+	}
+	pair := func(userID uuid.UUID, jid, key string) *proto.CreateUserIntegrationResponse {
+		t.Helper()
+		resp, err := server.CreateUserIntegration(ctx, &proto.CreateUserIntegrationRequest{
+			UserId:          userID.String(),
+			IntegrationType: core.IntegrationTypeWhatsApp,
+			PlatformUserId:  jid,
+			IdempotencyKey:  key,
+		})
+		if err != nil {
+			t.Fatalf("CreateUserIntegration(%s, %s): %v", jid, key, err)
+		}
+		return resp
+	}
+
+	alice := createUser("pairing-alice")
+	bob := createUser("pairing-bob")
+	const aliceJID = "972503333333@s.whatsapp.net"
+	const deviceKey = "972503333333:12@s.whatsapp.net"
+
+	first := pair(alice, aliceJID, deviceKey)
+	if !first.Success {
+		t.Fatalf("expected pairing to succeed: %s", first.Error)
+	}
+
+	// A retry returns the same integration without recording the pairing again
+	retry := pair(alice, aliceJID, deviceKey)
+	if !retry.Success || retry.UserIntegrationId != first.UserIntegrationId {
+		t.Fatalf("expected retry to return integration %d, got %+v", first.UserIntegrationId, retry)
+	}
+	history, err := integrationService.GetIntegrationStatusHistory(ctx, first.UserIntegrationId, 10)
+	if err != nil {
+		t.Fatalf("GetIntegrationStatusHistory: %v", err)
+	}
+	if len(history) != 1 {
+		t.Errorf("expected 1 pairing recorded, got %d", len(history))
+	}
+
+	// The key can't be reused to pair a different account
+	if resp := pair(alice, "972504444444@s.whatsapp.net", deviceKey); resp.Success {
+		t.Errorf("expected a reused key for another account to be rejected, got integration %d", resp.UserIntegrationId)
+	}
+
+	// Another user can't take over the account
+	if resp := pair(bob, aliceJID, "972503333333:13@s.whatsapp.net"); resp.Success {
+		t.Errorf("expected pairing another user's account to be rejected, got integration %d", resp.UserIntegrationId)
+	}
+	integration, err := integrationService.GetUserIntegrationByExternalID(ctx, alice, core.IntegrationTypeWhatsApp, aliceJID)
+	if err != nil {
+		t.Fatalf("GetUserIntegrationByExternalID: %v", err)
+	}
+	if integration.ID != first.UserIntegrationId || integration.Status != "connected" {
+		t.Errorf("expected alice's integration %d to stay connected, got %d (%s)", first.UserIntegrationId, integration.ID, integration.Status)
+	}
+
+	// A new device for the same number re-pairs the existing integration
+	repaired := pair(alice, aliceJID, "972503333333:14@s.whatsapp.net")
+	if !repaired.Success || repaired.UserIntegrationId != first.UserIntegrationId {
+		t.Errorf("expected re-pairing to reuse integration %d, got %+v", first.UserIntegrationId, repaired)
+	}
+}
+
+// contactStream feeds SyncContacts a fixed list of requests
+type contactStream struct {
+	grpc.ServerStream
+	requests  []*proto.SyncContactsRequest
+	responses []*proto.SyncContactsResponse
+}
+
+func (s *contactStream) Recv() (*proto.SyncContactsRequest, error) {
+	if len(s.requests) == 0 {
+		return nil, io.EOF
+	}
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+	return req, nil
+}
+
+func (s *contactStream) Send(response *proto.SyncContactsResponse) error {
+	s.responses = append(s.responses, response)
+	return nil
+}
+
+func (s *contactStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestSyncRejectsOversizedBatch(t *testing.T) {
+	// With no database, reaching the upsert would panic
+	server := NewIntegrationServer(nil, nil, nil, nil, 2, zap.NewNop())
+
+	stream := &contactStream{requests: []*proto.SyncContactsRequest{
+		{BatchNumber: 1},
+		{BatchNumber: 2, Contacts: []*proto.Contact{{}, {}, {}}},
+	}}
+	err := server.SyncContacts(stream)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for a batch of 3, got %v", err)
+	}
+	if len(stream.responses) != 1 || stream.responses[0].BatchNumber != 1 {
+		t.Errorf("expected only the first batch to be acknowledged, got %+v", stream.responses)
+	}
+
+	stream = &contactStream{requests: []*proto.SyncContactsRequest{{BatchNumber: 1}}}
+	if err := server.SyncContacts(stream); err != nil {
+		t.Fatalf("SyncContacts: %v", err)
+	}
+	if len(stream.responses) != 1 || !stream.responses[0].Success {
+		t.Errorf("expected a successful acknowledgement, got %+v", stream.responses)
+	}
+}
+
+func TestSyncAcknowledgesResentBatchWithoutStoringIt(t *testing.T) {
+	// With no database, reaching the upsert would panic
+	server := NewIntegrationServer(nil, nil, nil, nil, 0, zap.NewNop())
+
+	stream := &contactStream{requests: []*proto.SyncContactsRequest{
+		{SyncId: "sync-1", BatchNumber: 1},
+	}}
+	if err := server.SyncContacts(stream); err != nil {
+		t.Fatalf("SyncContacts: %v", err)
+	}
+
+	// The stream broke before the client saw the acknowledgement, so the
+	// resumed stream resends the batch
+	stream = &contactStream{requests: []*proto.SyncContactsRequest{
+		{SyncId: "sync-1", BatchNumber: 1, Contacts: []*proto.Contact{{PlatformId: "contact@s.whatsapp.net"}}},
+	}}
+	if err := server.SyncContacts(stream); err != nil {
+		t.Fatalf("SyncContacts: %v", err)
+	}
+	if len(stream.responses) != 1 {
+		t.Fatalf("expected the resent batch to be acknowledged, got %+v", stream.responses)
+	}
+	if ack := stream.responses[0]; !ack.Success || ack.BatchNumber != 1 || ack.ProcessedCount != 0 {
+		t.Errorf("expected batch 1 to be acknowledged with nothing stored, got %+v", ack)
+	}
+}
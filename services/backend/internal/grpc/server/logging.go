@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/tennex/pkg/requestid"
+)
+
+// LoggingUnaryInterceptor logs every call with its request ID, so a call can
+// be matched with the bridge's log of it. It must be chained after the
+// requestid interceptors.
+func LoggingUnaryInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	logger = logger.Named("grpc")
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(logger, ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// LoggingStreamInterceptor logs every stream with its request ID once it ends
+func LoggingStreamInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	logger = logger.Named("grpc")
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		logCall(logger, stream.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(logger *zap.Logger, ctx context.Context, method string, start time.Time, err error) {
+	fields := []zap.Field{
+		zap.String("method", method),
+		requestid.Field(ctx),
+		zap.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		logger.Warn("gRPC call failed", append(fields, zap.String("code", status.Code(err).String()), zap.Error(err))...)
+		return
+	}
+	logger.Debug("gRPC call", fields...)
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/core"
+	gen "github.com/tennex/pkg/db/gen"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+// maxMissingMediaHashes caps the hashes one GetMissingMedia call can ask about
+const maxMissingMediaHashes = 500
+
+// MediaServer implements the media gRPC service. Bridges ask which files are
+// missing before downloading them, so a file forwarded to many chats is
+// downloaded and stored once.
+type MediaServer struct {
+	proto.UnimplementedMediaServiceServer
+	store  *core.MediaStore
+	db     *gen.Queries
+	logger *zap.Logger
+}
+
+// NewMediaServer creates a new media gRPC server storing files in store
+func NewMediaServer(store *core.MediaStore, db *gen.Queries, logger *zap.Logger) *MediaServer {
+	return &MediaServer{
+		store:  store,
+		db:     db,
+		logger: logger.Named("media_server"),
+	}
+}
+
+// GetMissingMedia returns the hashes that have no stored file yet
+func (s *MediaServer) GetMissingMedia(ctx context.Context, req *proto.GetMissingMediaRequest) (*proto.GetMissingMediaResponse, error) {
+	if len(req.ContentHashes) > maxMissingMediaHashes {
+		return nil, fmt.Errorf("at most %d content hashes per request", maxMissingMediaHashes)
+	}
+	for _, hash := range req.ContentHashes {
+		if !core.ValidContentHash(hash) {
+			return nil, fmt.Errorf("%w: %q", core.ErrInvalidContentHash, hash)
+		}
+	}
+
+	stored, err := s.db.ListStoredMediaBlobHashes(ctx, req.ContentHashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up media: %w", err)
+	}
+	storedSet := make(map[string]bool, len(stored))
+	for _, hash := range stored {
+		storedSet[hash] = true
+	}
+
+	missing := make([]string, 0, len(req.ContentHashes)-len(stored))
+	for _, hash := range req.ContentHashes {
+		if !storedSet[hash] {
+			missing = append(missing, hash)
+			storedSet[hash] = true // Report duplicates once
+		}
+	}
+	return &proto.GetMissingMediaResponse{MissingHashes: missing}, nil
+}
+
+// UploadMedia stores a file under its content hash and completes every
+// attachment that was waiting for it
+func (s *MediaServer) UploadMedia(stream proto.MediaService_UploadMediaServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	header := first.GetHeader()
+	if header == nil {
+		return stream.SendAndClose(&proto.UploadMediaResponse{Success: false, Error: "first request must carry the header"})
+	}
+	logger := s.logger.With(
+		zap.String("user_id", header.Context.GetUserId()),
+		zap.String("content_hash", header.ContentHash))
+
+	blobPath, size, err := s.store.Put(header.ContentHash, &uploadReader{stream: stream, pending: first.Data})
+	if errors.Is(err, core.ErrInvalidContentHash) || errors.Is(err, core.ErrContentHashMismatch) {
+		logger.Warn("Rejected media upload", zap.Error(err))
+		return stream.SendAndClose(&proto.UploadMediaResponse{Success: false, Error: err.Error()})
+	}
+	if err != nil {
+		logger.Error("Failed to store media", zap.Error(err))
+		return err
+	}
+
+	row, err := s.db.StoreMediaBlob(stream.Context(), gen.StoreMediaBlobParams{
+		ContentHash: header.ContentHash,
+		MimeType:    header.MimeType,
+		SizeBytes:   size,
+		StorageUrl:  blobPath,
+	})
+	if err != nil {
+		logger.Error("Failed to record stored media", zap.Error(err))
+		return fmt.Errorf("failed to record media: %w", err)
+	}
+
+	logger.Debug("Media stored",
+		zap.Int64("size_bytes", size),
+		zap.Bool("already_stored", !row.Inserted),
+		zap.Int64("linked_media", row.LinkedCount))
+	return stream.SendAndClose(&proto.UploadMediaResponse{
+		Success:          true,
+		AlreadyStored:    !row.Inserted,
+		LinkedMediaCount: row.LinkedCount,
+	})
+}
+
+// uploadReader reads the file data from the requests of an upload stream
+type uploadReader struct {
+	stream  proto.MediaService_UploadMediaServer
+	pending []byte
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err // io.EOF once the bridge has sent everything
+		}
+		r.pending = req.Data
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/http/handlers"
+	"github.com/tennex/backend/internal/testutil"
+	gen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/shared/auth"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+func TestForwardedMediaIsStoredOnce(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationCtx := createTestIntegration(t, pool)
+	queries := gen.New(pool)
+	server := NewIntegrationServer(nil, nil, pool, queries, 0, zap.NewNop())
+	mediaDir := t.TempDir()
+	ctx := context.Background()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	proto.RegisterMediaServiceServer(grpcServer, NewMediaServer(core.NewMediaStore(mediaDir), queries, zap.NewNop()))
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	client := proto.NewMediaServiceClient(conn)
+
+	content := []byte("the same image forwarded to two chats")
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	// The image arrives in two chats, and one of them syncs it twice
+	const otherChat = "972509999999@s.whatsapp.net"
+	for _, m := range []struct{ id, chat string }{{"FWD1", testPN}, {"FWD2", otherChat}, {"FWD2", otherChat}} {
+		msg := testMessage(m.id, m.chat, m.chat, "", 1700000000)
+		msg.MessageType = proto.MessageType_MESSAGE_TYPE_IMAGE
+		msg.Media = []*proto.MessageMedia{{
+			MediaType:   proto.MediaType_MEDIA_TYPE_IMAGE,
+			MimeType:    "image/jpeg",
+			ContentHash: hash,
+		}}
+		if err := server.upsertMessage(ctx, integrationCtx, m.chat, msg, false); err != nil {
+			t.Fatalf("upsertMessage(%s): %v", m.id, err)
+		}
+	}
+	var attachments int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM message_media WHERE content_hash = $1`, hash).Scan(&attachments); err != nil {
+		t.Fatalf("failed to count attachments: %v", err)
+	}
+	if attachments != 2 {
+		t.Fatalf("expected one attachment per message, got %d", attachments)
+	}
+
+	missing, err := client.GetMissingMedia(ctx, &proto.GetMissingMediaRequest{Context: integrationCtx, ContentHashes: []string{hash, hash}})
+	if err != nil {
+		t.Fatalf("GetMissingMedia: %v", err)
+	}
+	if len(missing.MissingHashes) != 1 || missing.MissingHashes[0] != hash {
+		t.Fatalf("expected the hash to be missing once, got %v", missing.MissingHashes)
+	}
+
+	upload := func(data []byte) *proto.UploadMediaResponse {
+		t.Helper()
+		stream, err := client.UploadMedia(ctx)
+		if err != nil {
+			t.Fatalf("UploadMedia: %v", err)
+		}
+		err = stream.Send(&proto.UploadMediaRequest{Header: &proto.UploadMediaHeader{
+			Context:     integrationCtx,
+			ContentHash: hash,
+			MimeType:    "image/jpeg",
+		}})
+		if err != nil {
+			t.Fatalf("failed to send header: %v", err)
+		}
+		// Split the data so the server has to join chunks
+		for _, chunk := range [][]byte{data[:10], data[10:]} {
+			if err := stream.Send(&proto.UploadMediaRequest{Data: chunk}); err != nil {
+				t.Fatalf("failed to send data: %v", err)
+			}
+		}
+		resp, err := stream.CloseAndRecv()
+		if err != nil {
+			t.Fatalf("UploadMedia: %v", err)
+		}
+		return resp
+	}
+
+	// Data that doesn't match the hash is refused
+	if resp := upload([]byte("a different image than the hash names")); resp.Success {
+		t.Fatal("expected an upload with the wrong data to fail")
+	}
+
+	resp := upload(content)
+	if !resp.Success || resp.AlreadyStored || resp.LinkedMediaCount != 2 {
+		t.Fatalf("expected the upload to complete both attachments, got %+v", resp)
+	}
+	missing, err = client.GetMissingMedia(ctx, &proto.GetMissingMediaRequest{Context: integrationCtx, ContentHashes: []string{hash}})
+	if err != nil || len(missing.MissingHashes) != 0 {
+		t.Fatalf("expected nothing missing after the upload, got %v: %v", missing.GetMissingHashes(), err)
+	}
+	if resp := upload(content); !resp.Success || !resp.AlreadyStored || resp.LinkedMediaCount != 0 {
+		t.Errorf("expected a repeated upload to find the file stored, got %+v", resp)
+	}
+
+	// A later forward is complete as soon as it arrives
+	later := testMessage("FWD3", testPN, testPN, "", 1700000100)
+	later.MessageType = proto.MessageType_MESSAGE_TYPE_IMAGE
+	later.Media = []*proto.MessageMedia{{MediaType: proto.MediaType_MEDIA_TYPE_IMAGE, MimeType: "image/jpeg", ContentHash: hash}}
+	if err := server.upsertMessage(ctx, integrationCtx, testPN, later, false); err != nil {
+		t.Fatalf("upsertMessage(FWD3): %v", err)
+	}
+
+	var blobs, completed int
+	err = pool.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM media_blobs),
+			(SELECT COUNT(*) FROM message_media WHERE content_hash = $1 AND download_status = 'completed')`, hash).
+		Scan(&blobs, &completed)
+	if err != nil {
+		t.Fatalf("failed to count media: %v", err)
+	}
+	if blobs != 1 || completed != 3 {
+		t.Errorf("expected 1 stored file for 3 completed attachments, got %d and %d", blobs, completed)
+	}
+	files, err := os.ReadDir(filepath.Join(mediaDir, "blobs", hash[:2]))
+	if err != nil || len(files) != 1 {
+		t.Errorf("expected exactly one file on disk, got %d: %v", len(files), err)
+	}
+
+	// Every message serves the one file
+	token, _, err := auth.DefaultJWTConfig("test-secret").GenerateToken(uuid.MustParse(integrationCtx.UserId), auth.RoleUser)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	mediaHandler := handlers.NewMediaHandler(queries, mediaDir, auth.DefaultJWTConfig("test-secret"), zap.NewNop())
+	for _, id := range []string{"FWD1", "FWD2", "FWD3"} {
+		var messageID uuid.UUID
+		if err := pool.QueryRow(ctx, `SELECT id FROM messages WHERE external_message_id = $1`, id).Scan(&messageID); err != nil {
+			t.Fatalf("failed to read %s: %v", id, err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/"+messageID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		mediaHandler.Routes().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Body.String() != string(content) {
+			t.Errorf("expected %s to serve the stored file, got %d: %q", id, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// syncCursorsSize is how many syncs syncCursors remembers
+const syncCursorsSize = 1024
+
+// syncCursorTTL is how long syncCursors remembers a sync after its last batch
+const syncCursorTTL = time.Hour
+
+// syncCursors remembers the last batch stored for each recent sync stream, by
+// the client's sync_id. A client whose stream breaks resends the batches it
+// has no acknowledgement for on a new stream, and a batch stored before its
+// acknowledgement was lost is then acknowledged again without being stored
+// twice. Storing a batch holds its sync's cursor, so a batch still being
+// stored by a broken stream isn't also stored by the resumed one.
+type syncCursors struct {
+	mu       sync.Mutex
+	size     int
+	ttl      time.Duration
+	now      func() time.Time
+	order    *list.List               // Least recently used first
+	elements map[string]*list.Element // sync_id -> its element in order
+}
+
+type syncCursor struct {
+	mu        sync.Mutex // Held while a batch of the sync is stored
+	syncID    string
+	lastBatch int32
+	usedAt    time.Time
+}
+
+// newSyncCursors creates a set of sync cursors remembering up to size syncs
+// for up to ttl each
+func newSyncCursors(size int, ttl time.Duration) *syncCursors {
+	return &syncCursors{
+		size:     size,
+		ttl:      ttl,
+		now:      time.Now,
+		order:    list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+// begin waits until no other stream is storing a batch of syncID and reports
+// whether batch still has to be stored. The caller must call done once it's
+// finished with the batch, reporting whether it's now stored.
+func (c *syncCursors) begin(syncID string, batch int32) (done func(stored bool), pending bool) {
+	cursor := c.cursor(syncID)
+	cursor.mu.Lock()
+	pending = batch > cursor.lastBatch
+	return func(stored bool) {
+		if pending && stored {
+			cursor.lastBatch = batch
+		}
+		cursor.mu.Unlock()
+	}, pending
+}
+
+// cursor returns syncID's cursor, creating it if needed
+func (c *syncCursors) cursor(syncID string) *syncCursor {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.expire(now)
+	if element, ok := c.elements[syncID]; ok {
+		cursor := element.Value.(*syncCursor)
+		cursor.usedAt = now
+		c.order.MoveToBack(element)
+		return cursor
+	}
+
+	cursor := &syncCursor{syncID: syncID, usedAt: now}
+	c.elements[syncID] = c.order.PushBack(cursor)
+	if c.order.Len() > c.size {
+		c.remove(c.order.Front())
+	}
+	return cursor
+}
+
+// expire forgets the syncs last used more than ttl before now
+func (c *syncCursors) expire(now time.Time) {
+	for element := c.order.Front(); element != nil; element = c.order.Front() {
+		if now.Sub(element.Value.(*syncCursor).usedAt) <= c.ttl {
+			return
+		}
+		c.remove(element)
+	}
+}
+
+func (c *syncCursors) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.elements, element.Value.(*syncCursor).syncID)
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyncCursorsSkipStoredBatches(t *testing.T) {
+	cursors := newSyncCursors(10, time.Hour)
+
+	done, pending := cursors.begin("sync-1", 1)
+	if !pending {
+		t.Fatal("expected the first batch to be pending")
+	}
+	done(true)
+
+	done, pending = cursors.begin("sync-1", 1)
+	done(true)
+	if pending {
+		t.Error("expected a stored batch not to be stored again")
+	}
+
+	// A batch cut short isn't recorded, so it's stored when resent
+	done, _ = cursors.begin("sync-1", 2)
+	done(false)
+	done, pending = cursors.begin("sync-1", 2)
+	done(true)
+	if !pending {
+		t.Error("expected a batch cut short to be stored when resent")
+	}
+
+	done, pending = cursors.begin("sync-2", 1)
+	done(true)
+	if !pending {
+		t.Error("expected another sync's batch to be pending")
+	}
+}
+
+func TestSyncCursorsWaitForStoringBatch(t *testing.T) {
+	cursors := newSyncCursors(10, time.Hour)
+
+	done, _ := cursors.begin("sync-1", 1)
+	resumed := make(chan bool)
+	go func() {
+		done, pending := cursors.begin("sync-1", 1)
+		done(true)
+		resumed <- pending
+	}()
+
+	select {
+	case <-resumed:
+		t.Fatal("expected the resumed stream to wait for the batch being stored")
+	case <-time.After(50 * time.Millisecond):
+	}
+	done(true)
+	if pending := <-resumed; pending {
+		t.Error("expected the resumed stream to skip the batch stored meanwhile")
+	}
+}
+
+func TestSyncCursorsEviction(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cursors := newSyncCursors(2, time.Hour)
+	cursors.now = func() time.Time { return now }
+
+	for _, syncID := range []string{"sync-1", "sync-2", "sync-3"} {
+		done, _ := cursors.begin(syncID, 1)
+		done(true)
+	}
+
+	// Full, so the least recently used sync made room
+	done, pending := cursors.begin("sync-1", 1)
+	done(false)
+	if !pending {
+		t.Error("expected the least recently used sync to be forgotten")
+	}
+
+	// Past the TTL the sync is forgotten too
+	now = now.Add(time.Hour + time.Second)
+	done, pending = cursors.begin("sync-3", 1)
+	done(false)
+	if !pending {
+		t.Error("expected an expired sync to be forgotten")
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/shared/auth"
+)
+
+func TestAccountRoutesRequireAdminRole(t *testing.T) {
+	// No account service: requests that get past authorization with valid
+	// parameters would panic, so these cases must all be rejected up front
+	handler := NewAPIHandler(nil, nil, nil, nil, nil, nil, "test-secret", zap.NewNop()).Routes()
+
+	token := func(role string) string {
+		t.Helper()
+		tokenString, _, err := auth.DefaultJWTConfig("test-secret").GenerateToken(uuid.New(), role)
+		if err != nil {
+			t.Fatalf("GenerateToken: %v", err)
+		}
+		return "Bearer " + tokenString
+	}
+
+	for _, tc := range []struct {
+		name          string
+		path          string
+		authorization string
+		want          int
+	}{
+		{"anonymous listing", "/accounts", "", http.StatusUnauthorized},
+		{"anonymous account", "/accounts/" + uuid.NewString(), "", http.StatusUnauthorized},
+		{"user listing", "/accounts", token(auth.RoleUser), http.StatusForbidden},
+		{"user account", "/accounts/" + uuid.NewString(), token(auth.RoleUser), http.StatusForbidden},
+		// An admin gets through to the handler, which rejects the limit
+		{"admin listing", "/accounts?limit=0", token(auth.RoleAdmin), http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestParseAccountFilters(t *testing.T) {
+	tests := []struct {
+		query string
+		want  repo.ListAccountsParams
+	}{
+		{"", repo.ListAccountsParams{SortBy: repo.AccountSortCreatedAt}},
+		{"status=connected", repo.ListAccountsParams{Status: "connected", SortBy: repo.AccountSortCreatedAt}},
+		{"search=+Alice+", repo.ListAccountsParams{Search: "Alice", SortBy: repo.AccountSortCreatedAt}},
+		{"sort=last_seen", repo.ListAccountsParams{SortBy: repo.AccountSortLastSeen}},
+		{"status=error&search=972&sort=created_at", repo.ListAccountsParams{Status: "error", Search: "972", SortBy: repo.AccountSortCreatedAt}},
+	}
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		got, err := parseAccountFilters(query)
+		if err != nil {
+			t.Errorf("parseAccountFilters(%q): %v", tt.query, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseAccountFilters(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"status=online", "sort=name", "sort=last_seen&status=gone"} {
+		query, _ := url.ParseQuery(bad)
+		if _, err := parseAccountFilters(query); err == nil {
+			t.Errorf("parseAccountFilters(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestConvertAccountSummariesToAPI(t *testing.T) {
+	lastSeen := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	h := &APIHandler{}
+	accounts := h.convertAccountSummariesToAPI([]core.AccountSummary{
+		{
+			Account: repo.Account{ID: "user-1", Status: "connected"},
+			Integrations: []repo.AccountIntegrationSummary{
+				{ID: 7, IntegrationType: "whatsapp", Status: "connected", LastSeen: sql.NullTime{Time: lastSeen, Valid: true}},
+			},
+		},
+		{Account: repo.Account{ID: "user-2", Status: "disconnected"}, Integrations: []repo.AccountIntegrationSummary{}},
+	})
+
+	integrations := accounts[0]["integrations"].([]map[string]interface{})
+	if len(integrations) != 1 || integrations[0]["connected"] != true || integrations[0]["last_seen"] != lastSeen {
+		t.Errorf("unexpected integrations %v", integrations)
+	}
+	if _, ok := integrations[0]["last_event_at"]; ok {
+		t.Errorf("expected no last_event_at for an integration without events, got %v", integrations[0])
+	}
+
+	// Accounts without integrations list none rather than leaving the field out
+	if empty, ok := accounts[1]["integrations"].([]map[string]interface{}); !ok || len(empty) != 0 {
+		t.Errorf("expected an empty integrations list, got %v", accounts[1]["integrations"])
+	}
+	if accounts[1]["id"] != "user-2" || accounts[1]["status"] != "disconnected" {
+		t.Errorf("expected the account's own fields to be kept, got %v", accounts[1])
+	}
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/pkg/apierror"
+	"github.com/tennex/pkg/events"
+	"github.com/tennex/pkg/httpx"
+	"github.com/tennex/shared/auth"
+)
+
+const (
+	defaultAdminPageSize = 50
+	maxAdminPageSize     = 200
+
+	// Status history entries returned by default and at most
+	defaultStatusHistoryLimit = 50
+	maxStatusHistoryLimit     = 500
+)
+
+// BridgeLogouter logs an account out of its bridge session (implemented by *client.BridgeClient)
+type BridgeLogouter interface {
+	Logout(ctx context.Context, accountID string) error
+}
+
+// AdminHandler serves operator endpoints for managing user integrations.
+// Every route requires a JWT carrying the admin role.
+type AdminHandler struct {
+	integrationService *core.IntegrationService
+	bridge             BridgeLogouter
+	jwtConfig          *auth.JWTConfig
+	logger             *zap.Logger
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(integrationService *core.IntegrationService, bridge BridgeLogouter, jwtConfig *auth.JWTConfig, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		integrationService: integrationService,
+		bridge:             bridge,
+		jwtConfig:          jwtConfig,
+		logger:             logger.Named("admin_handler"),
+	}
+}
+
+// Routes returns the admin routes
+func (h *AdminHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Use(h.jwtConfig.ChiMiddleware())
+	r.Use(auth.RequireRole(auth.RoleAdmin))
+
+	r.Get("/integrations", h.ListIntegrations)
+	r.Get("/integrations/{id}", h.GetIntegration)
+	r.Post("/integrations/{id}/disconnect", h.DisconnectIntegration)
+
+	return r
+}
+
+// ListIntegrations lists integrations across all users, optionally filtered by
+// status and type
+func (h *AdminHandler) ListIntegrations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := defaultAdminPageSize
+	if v := query.Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid limit", err))
+			return
+		}
+		limit = min(parsed, maxAdminPageSize)
+	}
+
+	offset := 0
+	if v := query.Get("offset"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid offset", err))
+			return
+		}
+		offset = parsed
+	}
+
+	integrations, total, err := h.integrationService.ListIntegrations(r.Context(), repo.ListIntegrationsParams{
+		Status:          query.Get("status"),
+		IntegrationType: query.Get("type"),
+		Limit:           int32(limit),
+		Offset:          int32(offset),
+	})
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to list integrations", err))
+		return
+	}
+
+	result := make([]map[string]interface{}, len(integrations))
+	for i, integration := range integrations {
+		result[i] = convertIntegrationToAPI(integration)
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]interface{}{
+		"integrations": result,
+		"total":        total,
+		"limit":        limit,
+		"offset":       offset,
+	})
+}
+
+// GetIntegration returns a single integration with its sync statistics and
+// recent status history
+func (h *AdminHandler) GetIntegration(w http.ResponseWriter, r *http.Request) {
+	integration, ok := h.loadIntegration(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.integrationService.GetIntegrationSyncStats(r.Context(), integration.ID)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get sync stats", err))
+		return
+	}
+
+	history, err := h.integrationService.GetIntegrationStatusHistory(r.Context(), integration.ID, defaultStatusHistoryLimit)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get status history", err))
+		return
+	}
+
+	response := convertIntegrationToAPI(*integration)
+	response["sync_stats"] = stats
+	response["status_history"] = convertStatusHistoryToAPI(history)
+	httpx.JSON(w, http.StatusOK, response)
+}
+
+// DisconnectIntegration marks an integration disconnected and, for WhatsApp,
+// logs its session out of the bridge
+func (h *AdminHandler) DisconnectIntegration(w http.ResponseWriter, r *http.Request) {
+	integration, ok := h.loadIntegration(w, r)
+	if !ok {
+		return
+	}
+
+	adminID, _ := auth.GetUserIDFromContext(r.Context())
+	now := time.Now()
+	err := h.integrationService.UpdateIntegrationStatus(r.Context(), integration.ID, events.AccountStatusDisconnected, &now,
+		"admin_disconnect", map[string]string{"admin_id": adminID.String()})
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to update integration status", err))
+		return
+	}
+	integration.Status = events.AccountStatusDisconnected
+
+	// The bridge has nothing to log out if the session is already gone
+	if integration.IntegrationType == core.IntegrationTypeWhatsApp {
+		err := h.bridge.Logout(r.Context(), integration.UserID.String())
+		if err != nil && status.Code(err) != codes.NotFound {
+			httpx.Error(w, apierror.Wrap(http.StatusBadGateway, "Integration marked disconnected but bridge logout failed", err))
+			return
+		}
+	}
+
+	h.logger.Info("Integration disconnected by admin",
+		zap.Int32("integration_id", integration.ID),
+		zap.String("user_id", integration.UserID.String()),
+		zap.String("integration_type", integration.IntegrationType),
+		zap.String("admin_id", adminID.String()))
+
+	httpx.JSON(w, http.StatusOK, convertIntegrationToAPI(*integration))
+}
+
+// loadIntegration resolves the {id} URL parameter, writing an error response
+// and returning false if it is invalid or unknown
+func (h *AdminHandler) loadIntegration(w http.ResponseWriter, r *http.Request) (*repo.UserIntegration, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid integration ID", err))
+		return nil, false
+	}
+
+	integration, err := h.integrationService.GetIntegrationByID(r.Context(), int32(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		httpx.Error(w, apierror.New(http.StatusNotFound, "Integration not found"))
+		return nil, false
+	}
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get integration", err))
+		return nil, false
+	}
+
+	return integration, true
+}
+
+func convertIntegrationToAPI(integration repo.UserIntegration) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":               integration.ID,
+		"user_id":          integration.UserID,
+		"integration_type": integration.IntegrationType,
+		"external_id":      integration.ExternalID,
+		"status":           integration.Status,
+		"metadata":         integration.Metadata,
+		"created_at":       integration.CreatedAt,
+		"updated_at":       integration.UpdatedAt,
+	}
+
+	if integration.DisplayName.Valid {
+		result["display_name"] = integration.DisplayName.String
+	}
+	if integration.AvatarUrl.Valid {
+		result["avatar_url"] = integration.AvatarUrl.String
+	}
+	if integration.LastSeen.Valid {
+		result["last_seen"] = integration.LastSeen.Time
+	}
+
+	return result
+}
+
+func convertStatusHistoryToAPI(history []repo.IntegrationStatusEvent) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(history))
+	for i, event := range history {
+		result[i] = map[string]interface{}{
+			"id":         event.ID,
+			"new_status": event.NewStatus,
+			"metadata":   event.Metadata,
+			"created_at": event.CreatedAt,
+		}
+		if event.OldStatus.Valid {
+			result[i]["old_status"] = event.OldStatus.String
+		}
+		if event.Reason.Valid {
+			result[i]["reason"] = event.Reason.String
+		}
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tennex/shared/auth"
+)
+
+func TestAdminRoutesRequireAdminRole(t *testing.T) {
+	jwtConfig := auth.DefaultJWTConfig("test-secret")
+	// No service or bridge: requests that get past authorization with a
+	// valid ID would panic, so these cases must all be rejected up front
+	handler := NewAdminHandler(nil, nil, jwtConfig, zap.NewNop()).Routes()
+
+	token := func(config *auth.JWTConfig, role string) string {
+		t.Helper()
+		tokenString, _, err := config.GenerateToken(uuid.New(), role)
+		if err != nil {
+			t.Fatalf("GenerateToken: %v", err)
+		}
+		return "Bearer " + tokenString
+	}
+
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/integrations?status=connected&type=whatsapp"},
+		{http.MethodGet, "/integrations/1"},
+		{http.MethodPost, "/integrations/1/disconnect"},
+	}
+	for _, tc := range []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{name: "no token", authorization: "", want: http.StatusUnauthorized},
+		{name: "foreign token", authorization: token(auth.DefaultJWTConfig("other-secret"), auth.RoleAdmin), want: http.StatusUnauthorized},
+		{name: "user token", authorization: token(jwtConfig, auth.RoleUser), want: http.StatusForbidden},
+		{name: "token without role", authorization: token(jwtConfig, ""), want: http.StatusForbidden},
+	} {
+		for _, route := range routes {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.want {
+				t.Errorf("%s: %s %s: expected %d, got %d: %s", tc.name, route.method, route.path, tc.want, rec.Code, rec.Body.String())
+			}
+		}
+	}
+
+	// An admin token gets through to the handler, which rejects the malformed ID
+	req := httptest.NewRequest(http.MethodGet, "/integrations/not-a-number", nil)
+	req.Header.Set("Authorization", token(jwtConfig, auth.RoleAdmin))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected admin request to reach the handler and get 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
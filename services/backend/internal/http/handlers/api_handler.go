@@ -3,18 +3,28 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/nats-io/nats.go"
 	"go.uber.org/zap"
 
 	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/grpc/client"
 	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/pkg/apierror"
 	dbgen "github.com/tennex/pkg/db/gen"
 	"github.com/tennex/pkg/events"
+	"github.com/tennex/pkg/httpx"
+	"github.com/tennex/pkg/natsx"
 	"github.com/tennex/shared/auth"
 )
 
@@ -24,14 +34,17 @@ type APIHandler struct {
 	outboxService      *core.OutboxService
 	accountService     *core.AccountService
 	integrationService *core.IntegrationService
+	bridgeClient       *client.BridgeClient
 	queries            *dbgen.Queries
 	authHandler        *AuthHandler
+	adminHandler       *AdminHandler
 	jwtConfig          *auth.JWTConfig
+	nats               *nats.Conn
 	logger             *zap.Logger
 }
 
 // NewAPIHandler creates a new API handler
-func NewAPIHandler(eventService *core.EventService, outboxService *core.OutboxService, accountService *core.AccountService, integrationService *core.IntegrationService, queries *dbgen.Queries, jwtSecret string, logger *zap.Logger) *APIHandler {
+func NewAPIHandler(eventService *core.EventService, outboxService *core.OutboxService, accountService *core.AccountService, integrationService *core.IntegrationService, bridgeClient *client.BridgeClient, queries *dbgen.Queries, jwtSecret string, logger *zap.Logger) *APIHandler {
 	authHandler := NewAuthHandler(queries, jwtSecret, logger)
 	jwtConfig := auth.DefaultJWTConfig(jwtSecret)
 
@@ -40,30 +53,80 @@ func NewAPIHandler(eventService *core.EventService, outboxService *core.OutboxSe
 		outboxService:      outboxService,
 		accountService:     accountService,
 		integrationService: integrationService,
+		bridgeClient:       bridgeClient,
 		queries:            queries,
 		authHandler:        authHandler,
+		adminHandler:       NewAdminHandler(integrationService, bridgeClient, jwtConfig, logger),
 		jwtConfig:          jwtConfig,
 		logger:             logger.Named("api_handler"),
 	}
 }
 
-// Routes returns the HTTP routes
+// SetAuthConfig replaces the password hashing cost and login throttlers used
+// by the authentication routes
+func (h *APIHandler) SetAuthConfig(config AuthConfig) {
+	h.authHandler.SetConfig(config)
+}
+
+// SetRevocationCheck sets the check rejecting tokens of ended sessions on
+// every route the handler authenticates
+func (h *APIHandler) SetRevocationCheck(check auth.RevocationCheck) {
+	h.jwtConfig.RevocationCheck = check
+	h.authHandler.SetRevocationCheck(check)
+}
+
+// Routes returns the HTTP routes of the current API version, which the
+// server mounts under /v1. GetHealth is served at the root instead.
 func (h *APIHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 
-	// Public routes
-	r.Get("/health", h.GetHealth)
-
 	// Authentication routes
 	r.Mount("/auth", h.authHandler.Routes())
 
+	// Admin routes (require a JWT with the admin role)
+	r.Mount("/admin", h.adminHandler.Routes())
+
 	// Protected routes (in a real app, you'd add JWT middleware here)
 	r.Post("/outbox", h.CreateOutboxMessage)
 	r.Get("/sync", h.SyncEvents)
+	r.Get("/events/{seq}/payload", h.GetEventPayload)
 	r.Get("/qr", h.GetQRCode)
-	r.Get("/accounts", h.ListAccounts)
-	r.Get("/accounts/{account_id}", h.GetAccount)
+	// Account listings span every user, so they're for admins only
+	r.Group(func(r chi.Router) {
+		r.Use(h.jwtConfig.ChiMiddleware())
+		r.Use(auth.RequireRole(auth.RoleAdmin))
+		r.Get("/accounts", h.ListAccounts)
+		r.Get("/accounts/{account_id}", h.GetAccount)
+	})
+	r.Get("/accounts/{account_id}/export", h.ExportEvents)
 	r.Get("/settings", h.GetSettings)
+	r.Get("/me", h.GetMe)
+
+	// WhatsApp session operations (forwarded to the bridge)
+	r.Post("/integrations/whatsapp/read", h.MarkWhatsAppRead)
+	r.Post("/integrations/whatsapp/logout", h.LogoutWhatsApp)
+	r.Post("/integrations/whatsapp/resync", h.ResyncWhatsApp)
+	r.Get("/integrations/whatsapp/groups", h.ListWhatsAppGroups)
+	r.Post("/integrations/whatsapp/presence/subscribe", h.SubscribeWhatsAppPresence)
+	r.Post("/integrations/whatsapp/presence/unsubscribe", h.UnsubscribeWhatsAppPresence)
+
+	// Integration status history
+	r.Get("/integrations/{type}/status-history", h.GetIntegrationStatusHistory)
+
+	// Conversation endpoints
+	r.Patch("/conversations/{id}/state", h.UpdateConversationState)
+	r.Post("/conversations/{id}/recount", h.RecountConversation)
+	r.Get("/conversations/{id}/admins", h.ListConversationAdmins)
+
+	// Message endpoints
+	r.Get("/messages/{id}/poll", h.GetMessagePoll)
+	r.Get("/messages/{id}/thread", h.GetMessageThread)
+
+	// Search endpoints
+	r.Get("/search/messages", h.SearchMessages)
+
+	// Contact endpoints
+	r.Get("/contacts", h.ListContacts)
 
 	// Data sync endpoints
 	r.Get("/sync/conversations/{integration_id}", h.SyncConversations)
@@ -74,15 +137,24 @@ func (h *APIHandler) Routes() chi.Router {
 	return r
 }
 
+// SetNATS sets the NATS connection whose state the health check reports
+func (h *APIHandler) SetNATS(nc *nats.Conn) {
+	h.nats = nc
+}
+
 // GetHealth handles health check requests
 func (h *APIHandler) GetHealth(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
 		"status":    "ok",
 		"timestamp": time.Now().UTC(),
 		"version":   "1.0.0",
+		"nats": map[string]interface{}{
+			"state":             natsx.State(h.nats),
+			"dropped_publishes": natsx.DroppedPublishes(),
+		},
 	}
 
-	h.writeJSON(w, http.StatusOK, response)
+	httpx.JSON(w, http.StatusOK, response)
 }
 
 // CreateOutboxMessage handles message sending requests
@@ -96,68 +168,148 @@ func (h *APIHandler) CreateOutboxMessage(w http.ResponseWriter, r *http.Request)
 		ReplyTo       string                 `json:"reply_to,omitempty"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid JSON", err)
+	if err := httpx.DecodeStrict(r, &req, 0); err != nil {
+		httpx.Error(w, err)
 		return
 	}
 
 	// Validate required fields
 	if req.ClientMsgUUID == "" || req.AccountID == "" || req.ConvoID == "" || req.MessageType == "" {
-		h.writeError(w, http.StatusBadRequest, "Missing required fields", nil)
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Missing required fields"))
 		return
 	}
 
 	clientUUID, err := uuid.Parse(req.ClientMsgUUID)
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid client_msg_uuid", err)
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid client_msg_uuid", err))
 		return
 	}
 
-	// Create message payload
-	payload := events.MessageOutPayload{
+	accountID, ok := h.authorizeAccount(w, r, req.AccountID)
+	if !ok {
+		return
+	}
+
+	// Reject content the outbox worker couldn't send now, while the client
+	// can still be told why
+	if err := core.ValidateOutboundContent(req.MessageType, req.Content); err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid message content", err))
+		return
+	}
+	if req.MessageType == events.ContentTypeImage || req.MessageType == events.ContentTypeDocument {
+		media, _ := core.ParseOutboundMedia(req.Content) // Validated above
+		if !h.outboxService.HasMedia(media.Hash) {
+			httpx.Error(w, apierror.New(http.StatusBadRequest, "Media has not been uploaded"))
+			return
+		}
+	}
+
+	h.queueOutboxMessage(w, r, clientUUID, accountID, req.ConvoID, events.MessageOutPayload{
 		ContentType:      req.MessageType,
 		Content:          req.Content,
 		ClientMsgUUID:    req.ClientMsgUUID,
 		ReplyToMessageID: req.ReplyTo,
+	})
+}
+
+// authorizeAccount checks that the event account belongs to the request's
+// user and returns its normalized ID. It responds with an error and returns
+// false if the ID is malformed or the account isn't the user's.
+func (h *APIHandler) authorizeAccount(w http.ResponseWriter, r *http.Request, accountID string) (string, bool) {
+	accountID, ok := normalizeAccountID(w, accountID)
+	if !ok {
+		return "", false
 	}
 
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
+		return "", false
+	}
+
+	if err := h.accountService.AuthorizeAccount(r.Context(), userID, accountID); err != nil {
+		if errors.Is(err, core.ErrAccountNotOwned) {
+			httpx.Error(w, apierror.New(http.StatusForbidden, "Account belongs to another user"))
+			return "", false
+		}
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to check account owner", err))
+		return "", false
+	}
+	return accountID, true
+}
+
+// normalizeAccountID validates an account ID from a request and returns its
+// normalized form. It responds with an error and returns false if it's
+// malformed.
+func normalizeAccountID(w http.ResponseWriter, accountID string) (string, bool) {
+	normalized, err := core.NormalizeAccountID(accountID)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid account_id", err))
+		return "", false
+	}
+	return normalized, true
+}
+
+// queueOutboxMessage records an outbound message event and its outbox entry,
+// and responds with the message's server ID
+func (h *APIHandler) queueOutboxMessage(w http.ResponseWriter, r *http.Request, clientUUID uuid.UUID, accountID, convoID string, payload events.MessageOutPayload) {
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, "Failed to marshal payload", err)
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to marshal payload", err))
 		return
 	}
 
+	// Tie the message to the account's integration when there's only one
+	var integrationID int32
+	if integration, err := h.integrationService.GetAccountIntegration(r.Context(), accountID, core.IntegrationTypeWhatsApp); err == nil {
+		integrationID = integration.ID
+	} else {
+		h.logger.Debug("Outbox message not tied to an integration",
+			zap.String("account_id", accountID),
+			zap.Error(err))
+	}
+
 	// Create event and outbox entry
-	serverMsgID, err := h.eventService.CreateMessageOutEvent(r.Context(), req.AccountID, req.ConvoID, req.ClientMsgUUID, payloadBytes)
+	serverMsgID, err := h.eventService.CreateMessageOutEvent(r.Context(), accountID, integrationID, convoID, payload.ClientMsgUUID, payloadBytes)
+	if errors.Is(err, core.ErrPayloadTooLarge) {
+		httpx.Error(w, apierror.Wrap(http.StatusRequestEntityTooLarge, "Message is too large", err))
+		return
+	}
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, "Failed to create event", err)
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to create event", err))
 		return
 	}
 
-	if err := h.outboxService.CreateOutboxEntry(r.Context(), clientUUID, req.AccountID, req.ConvoID, serverMsgID); err != nil {
-		h.writeError(w, http.StatusInternalServerError, "Failed to create outbox entry", err)
+	if err := h.outboxService.CreateOutboxEntry(r.Context(), clientUUID, accountID, convoID, serverMsgID, payloadBytes); err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to create outbox entry", err))
 		return
 	}
 
 	response := map[string]interface{}{
 		"server_msg_id":   serverMsgID,
 		"status":          events.OutboxStatusQueued,
-		"client_msg_uuid": req.ClientMsgUUID,
+		"client_msg_uuid": payload.ClientMsgUUID,
 	}
 
 	h.logger.Info("Message queued for sending",
-		zap.String("client_msg_uuid", req.ClientMsgUUID),
+		zap.String("client_msg_uuid", payload.ClientMsgUUID),
+		zap.String("content_type", payload.ContentType),
 		zap.Int64("server_msg_id", serverMsgID),
-		zap.String("account_id", req.AccountID))
+		zap.String("account_id", accountID))
 
-	h.writeJSON(w, http.StatusCreated, response)
+	httpx.JSON(w, http.StatusCreated, response)
 }
 
 // SyncEvents handles event synchronization requests
 func (h *APIHandler) SyncEvents(w http.ResponseWriter, r *http.Request) {
 	accountID := r.URL.Query().Get("account_id")
 	if accountID == "" {
-		h.writeError(w, http.StatusBadRequest, "Missing account_id parameter", nil)
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Missing account_id parameter"))
+		return
+	}
+
+	accountID, ok := h.authorizeAccount(w, r, accountID)
+	if !ok {
 		return
 	}
 
@@ -167,7 +319,7 @@ func (h *APIHandler) SyncEvents(w http.ResponseWriter, r *http.Request) {
 		var err error
 		since, err = strconv.ParseInt(sinceStr, 10, 64)
 		if err != nil {
-			h.writeError(w, http.StatusBadRequest, "Invalid since parameter", err)
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid since parameter", err))
 			return
 		}
 	}
@@ -177,15 +329,31 @@ func (h *APIHandler) SyncEvents(w http.ResponseWriter, r *http.Request) {
 	if limitStr != "" {
 		limitInt, err := strconv.Atoi(limitStr)
 		if err != nil || limitInt < 1 || limitInt > 1000 {
-			h.writeError(w, http.StatusBadRequest, "Invalid limit parameter (must be 1-1000)", nil)
+			httpx.Error(w, apierror.New(http.StatusBadRequest, "Invalid limit parameter (must be 1-1000)"))
 			return
 		}
 		limit = int32(limitInt)
 	}
 
-	events, err := h.eventService.GetEventsSince(r.Context(), accountID, since, limit)
+	// Optional repeated type filter (?type=msg_in&type=msg_delivery); default is all types
+	types, err := parseEventTypes(r.URL.Query()["type"])
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid type parameter", err))
+		return
+	}
+
+	// Events up to the low-water mark were deleted by retention, so a client
+	// syncing from before it must rebuild its state from a snapshot
+	lowWaterSeq, err := h.eventService.GetLowWaterMark(r.Context(), accountID)
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, "Failed to get events", err)
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get retention state", err))
+		return
+	}
+	snapshotRequired := since < lowWaterSeq
+
+	events, err := h.eventService.GetEventsSince(r.Context(), accountID, since, limit, types)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get events", err))
 		return
 	}
 
@@ -199,25 +367,33 @@ func (h *APIHandler) SyncEvents(w http.ResponseWriter, r *http.Request) {
 	hasMore := len(events) == int(limit)
 
 	response := map[string]interface{}{
-		"events":   h.convertEventsToAPI(events),
-		"next_seq": nextSeq,
-		"has_more": hasMore,
+		"events":            h.convertEventsToAPI(events),
+		"next_seq":          nextSeq,
+		"has_more":          hasMore,
+		"snapshot_required": snapshotRequired,
+		"low_water_seq":     lowWaterSeq,
 	}
 
 	h.logger.Debug("Sync events response",
 		zap.String("account_id", accountID),
 		zap.Int64("since", since),
+		zap.Strings("types", types),
 		zap.Int("count", len(events)),
-		zap.Bool("has_more", hasMore))
+		zap.Bool("has_more", hasMore),
+		zap.Bool("snapshot_required", snapshotRequired))
 
-	h.writeJSON(w, http.StatusOK, response)
+	httpx.JSON(w, http.StatusOK, response)
 }
 
 // GetQRCode handles QR code generation requests
 func (h *APIHandler) GetQRCode(w http.ResponseWriter, r *http.Request) {
 	accountID := r.URL.Query().Get("account_id")
 	if accountID == "" {
-		h.writeError(w, http.StatusBadRequest, "Missing account_id parameter", nil)
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Missing account_id parameter"))
+		return
+	}
+	accountID, ok := normalizeAccountID(w, accountID)
+	if !ok {
 		return
 	}
 
@@ -232,7 +408,7 @@ func (h *APIHandler) GetQRCode(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("QR code requested",
 		zap.String("account_id", accountID))
 
-	h.writeJSON(w, http.StatusOK, response)
+	httpx.JSON(w, http.StatusOK, response)
 }
 
 // ListAccounts handles account listing requests
@@ -242,7 +418,7 @@ func (h *APIHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
 	if limitStr != "" {
 		limitInt, err := strconv.Atoi(limitStr)
 		if err != nil || limitInt < 1 || limitInt > 100 {
-			h.writeError(w, http.StatusBadRequest, "Invalid limit parameter (must be 1-100)", nil)
+			httpx.Error(w, apierror.New(http.StatusBadRequest, "Invalid limit parameter (must be 1-100)"))
 			return
 		}
 		limit = int32(limitInt)
@@ -253,95 +429,227 @@ func (h *APIHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
 	if offsetStr != "" {
 		offsetInt, err := strconv.Atoi(offsetStr)
 		if err != nil || offsetInt < 0 {
-			h.writeError(w, http.StatusBadRequest, "Invalid offset parameter", nil)
+			httpx.Error(w, apierror.New(http.StatusBadRequest, "Invalid offset parameter"))
 			return
 		}
 		offset = int32(offsetInt)
 	}
 
-	accounts, err := h.accountService.ListAccounts(r.Context(), limit, offset)
+	params, err := parseAccountFilters(r.URL.Query())
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, "Failed to list accounts", err)
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid account filter", err))
+		return
+	}
+	params.Limit = limit
+	params.Offset = offset
+
+	accounts, err := h.accountService.ListAccounts(r.Context(), params)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to list accounts", err))
 		return
 	}
 
 	response := map[string]interface{}{
-		"accounts": h.convertAccountsToAPI(accounts),
+		"accounts": h.convertAccountSummariesToAPI(accounts),
 		"total":    len(accounts), // TODO: Get actual total count
 	}
 
-	h.writeJSON(w, http.StatusOK, response)
+	httpx.JSON(w, http.StatusOK, response)
+}
+
+// Longest accepted account search, in characters
+const maxAccountSearchLength = 128
+
+// parseAccountFilters reads an account listing's status, search and sort
+// query parameters
+func parseAccountFilters(query url.Values) (repo.ListAccountsParams, error) {
+	params := repo.ListAccountsParams{
+		Status: query.Get("status"),
+		Search: strings.TrimSpace(query.Get("search")),
+		SortBy: query.Get("sort"),
+	}
+
+	switch params.Status {
+	case "", events.AccountStatusConnected, events.AccountStatusConnecting,
+		events.AccountStatusDisconnected, events.AccountStatusError:
+	default:
+		return repo.ListAccountsParams{}, fmt.Errorf("unknown status %q", params.Status)
+	}
+
+	if len([]rune(params.Search)) > maxAccountSearchLength {
+		return repo.ListAccountsParams{}, fmt.Errorf("search longer than %d characters", maxAccountSearchLength)
+	}
+
+	switch params.SortBy {
+	case "":
+		params.SortBy = repo.AccountSortCreatedAt
+	case repo.AccountSortCreatedAt, repo.AccountSortLastSeen:
+	default:
+		return repo.ListAccountsParams{}, fmt.Errorf("unknown sort %q (must be created_at or last_seen)", params.SortBy)
+	}
+
+	return params, nil
 }
 
 // GetAccount handles individual account requests
 func (h *APIHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 	accountID := chi.URLParam(r, "account_id")
 	if accountID == "" {
-		h.writeError(w, http.StatusBadRequest, "Missing account_id", nil)
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Missing account_id"))
 		return
 	}
 
 	account, err := h.accountService.GetAccount(r.Context(), accountID)
 	if err != nil {
-		h.writeError(w, http.StatusNotFound, "Account not found", err)
+		httpx.Error(w, apierror.Wrap(http.StatusNotFound, "Account not found", err))
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, h.convertAccountToAPI(*account))
+	httpx.JSON(w, http.StatusOK, h.convertAccountToAPI(*account))
 }
 
-// Helper methods
+// GetEventPayload returns the full payload of one of the user's events. Sync
+// responses carry an OversizedPayload in place of payloads too large to store
+// inline; clients fetch those here.
+func (h *APIHandler) GetEventPayload(w http.ResponseWriter, r *http.Request) {
+	seq, err := strconv.ParseInt(chi.URLParam(r, "seq"), 10, 64)
+	if err != nil || seq <= 0 {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Invalid event seq"))
+		return
+	}
 
-func (h *APIHandler) writeJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		h.logger.Error("Failed to encode JSON response", zap.Error(err))
+	event, err := h.eventService.GetEvent(r.Context(), seq)
+	if errors.Is(err, pgx.ErrNoRows) {
+		httpx.Error(w, apierror.New(http.StatusNotFound, "Event not found"))
+		return
 	}
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get event", err))
+		return
+	}
+	if _, ok := h.authorizeAccount(w, r, event.AccountID); !ok {
+		return
+	}
+
+	payload, err := h.eventService.FullPayload(event)
+	if errors.Is(err, core.ErrMediaNotFound) {
+		httpx.Error(w, apierror.New(http.StatusNotFound, "Event payload is missing"))
+		return
+	}
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to read event payload", err))
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, payload)
 }
 
-func (h *APIHandler) writeError(w http.ResponseWriter, status int, message string, err error) {
-	h.logger.Error("API error",
-		zap.String("message", message),
-		zap.Error(err),
-		zap.Int("status", status))
+// GetIntegrationStatusHistory returns the recent status transitions of one of
+// the user's integrations, newest first
+func (h *APIHandler) GetIntegrationStatusHistory(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
+		return
+	}
 
-	response := map[string]interface{}{
-		"error":     message,
-		"timestamp": time.Now().UTC(),
+	limit := defaultStatusHistoryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid limit", err))
+			return
+		}
+		limit = min(parsed, maxStatusHistoryLimit)
 	}
 
+	// Users with several integrations of the type pick one by external ID
+	var integration *repo.UserIntegration
+	integrationType := chi.URLParam(r, "type")
+	if externalID := r.URL.Query().Get("external_id"); externalID != "" {
+		integration, err = h.integrationService.GetUserIntegrationByExternalID(r.Context(), userID, integrationType, externalID)
+	} else {
+		integration, err = h.integrationService.GetUserIntegration(r.Context(), userID, integrationType)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		httpx.Error(w, apierror.New(http.StatusNotFound, "Integration not found"))
+		return
+	}
+	if errors.Is(err, core.ErrMultipleIntegrations) {
+		httpx.Error(w, apierror.New(http.StatusConflict, "Multiple integrations of this type; specify external_id"))
+		return
+	}
 	if err != nil {
-		response["details"] = err.Error()
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get integration", err))
+		return
 	}
 
-	h.writeJSON(w, status, response)
+	history, err := h.integrationService.GetIntegrationStatusHistory(r.Context(), integration.ID, int32(limit))
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get status history", err))
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]interface{}{
+		"integration_id":   integration.ID,
+		"integration_type": integration.IntegrationType,
+		"status":           integration.Status,
+		"history":          convertStatusHistoryToAPI(history),
+	})
 }
 
+// Helper methods
+
 func (h *APIHandler) convertEventsToAPI(events []repo.Event) []map[string]interface{} {
 	result := make([]map[string]interface{}, len(events))
 	for i, event := range events {
-		result[i] = map[string]interface{}{
-			"seq":            event.Seq,
-			"id":             event.ID,
-			"timestamp":      event.Ts,
-			"type":           event.Type,
-			"account_id":     event.AccountID,
-			"device_id":      event.DeviceID,
-			"convo_id":       event.ConvoID,
-			"wa_message_id":  event.WaMessageID,
-			"sender_jid":     event.SenderJid,
-			"payload":        json.RawMessage(event.Payload),
-			"attachment_ref": json.RawMessage(event.AttachmentRef),
-		}
+		result[i] = convertEventToAPI(event)
+	}
+	return result
+}
+
+func convertEventToAPI(event repo.Event) map[string]interface{} {
+	result := map[string]interface{}{
+		"seq":            event.Seq,
+		"id":             event.ID,
+		"timestamp":      event.Ts,
+		"type":           event.Type,
+		"account_id":     event.AccountID,
+		"device_id":      event.DeviceID,
+		"convo_id":       event.ConvoID,
+		"wa_message_id":  event.WaMessageID,
+		"sender_jid":     event.SenderJid,
+		"payload":        json.RawMessage(event.Payload),
+		"attachment_ref": json.RawMessage(event.AttachmentRef),
+	}
+	if event.UserIntegrationID.Valid {
+		result["user_integration_id"] = event.UserIntegrationID.Int32
 	}
 	return result
 }
 
-func (h *APIHandler) convertAccountsToAPI(accounts []repo.Account) []map[string]interface{} {
+func (h *APIHandler) convertAccountSummariesToAPI(accounts []core.AccountSummary) []map[string]interface{} {
 	result := make([]map[string]interface{}, len(accounts))
 	for i, account := range accounts {
-		result[i] = h.convertAccountToAPI(account)
+		result[i] = h.convertAccountToAPI(account.Account)
+
+		integrations := make([]map[string]interface{}, len(account.Integrations))
+		for j, integration := range account.Integrations {
+			integrations[j] = map[string]interface{}{
+				"integration_id":   integration.ID,
+				"integration_type": integration.IntegrationType,
+				"external_id":      integration.ExternalID,
+				"status":           integration.Status,
+				"connected":        integration.Status == events.AccountStatusConnected,
+			}
+			if integration.LastSeen.Valid {
+				integrations[j]["last_seen"] = integration.LastSeen.Time
+			}
+			if integration.LastEventAt.Valid {
+				integrations[j]["last_event_at"] = integration.LastEventAt.Time
+			}
+		}
+		result[i]["integrations"] = integrations
 	}
 	return result
 }
@@ -351,53 +659,162 @@ func (h *APIHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
 	// Extract user ID from JWT token (same pattern as auth handler)
 	userID, err := h.extractUserFromToken(r)
 	if err != nil {
-		h.writeError(w, http.StatusUnauthorized, "Invalid or missing token", err)
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
 		return
 	}
 
-	// Get user's WhatsApp integration information
-	whatsappIntegration, err := h.integrationService.GetWhatsAppIntegration(r.Context(), userID)
+	// A user can connect several WhatsApp numbers; list all of them
+	whatsappIntegrations, err := h.integrationService.GetWhatsAppIntegrations(r.Context(), userID)
 	if err != nil {
-		// If integration doesn't exist, return default settings (no WhatsApp connected)
-		h.logger.Debug("WhatsApp integration not found, returning default settings",
-			zap.String("user_id", userID.String()),
-			zap.Error(err))
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get WhatsApp integrations", err))
+		return
+	}
 
-		response := map[string]interface{}{
-			"user_id": userID,
-			"whatsapp": map[string]interface{}{
-				"connected": false,
-				"status":    "disconnected",
-			},
+	whatsapp := make([]map[string]interface{}, len(whatsappIntegrations))
+	for i, integration := range whatsappIntegrations {
+		whatsappInfo := map[string]interface{}{
+			"integration_id": integration.ID,
+			"connected":      integration.Status == "connected",
+			"status":         integration.Status,
+			"wa_jid":         integration.ExternalID,
 		}
-		h.writeJSON(w, http.StatusOK, response)
+
+		if integration.DisplayName.Valid {
+			whatsappInfo["display_name"] = integration.DisplayName.String
+		}
+		if integration.AvatarUrl.Valid {
+			whatsappInfo["avatar_url"] = integration.AvatarUrl.String
+		}
+		if integration.LastSeen.Valid {
+			whatsappInfo["last_seen"] = integration.LastSeen.Time
+		}
+		whatsapp[i] = whatsappInfo
+	}
+
+	response := map[string]interface{}{
+		"user_id":  userID,
+		"whatsapp": whatsapp,
+	}
+
+	h.logger.Debug("Settings retrieved", zap.String("user_id", userID.String()))
+	httpx.JSON(w, http.StatusOK, response)
+}
+
+// MarkWhatsAppRead marks messages in a WhatsApp conversation as read
+func (h *APIHandler) MarkWhatsAppRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
 		return
 	}
 
-	// Build WhatsApp connection info
-	whatsappInfo := map[string]interface{}{
-		"connected": whatsappIntegration.Status == "connected",
-		"status":    whatsappIntegration.Status,
-		"wa_jid":    whatsappIntegration.ExternalID,
+	var req struct {
+		ConvoID      string   `json:"convo_id"`
+		SenderJID    string   `json:"sender_jid,omitempty"`
+		WAMessageIDs []string `json:"wa_message_ids"`
+	}
+	if err := httpx.Decode(r, &req, 0); err != nil {
+		httpx.Error(w, err)
+		return
+	}
+	if req.ConvoID == "" || len(req.WAMessageIDs) == 0 {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Missing required fields"))
+		return
 	}
 
-	if whatsappIntegration.DisplayName.Valid {
-		whatsappInfo["display_name"] = whatsappIntegration.DisplayName.String
+	if err := h.bridgeClient.MarkRead(r.Context(), userID.String(), req.ConvoID, req.SenderJID, req.WAMessageIDs); err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadGateway, "Failed to mark messages as read", err))
+		return
 	}
-	if whatsappIntegration.AvatarUrl.Valid {
-		whatsappInfo["avatar_url"] = whatsappIntegration.AvatarUrl.String
+
+	// Move the conversation's read marker, which its unread count follows
+	response := map[string]interface{}{"success": true}
+	read, err := h.queries.MarkConversationRead(r.Context(), dbgen.MarkConversationReadParams{
+		UserID:                 userID,
+		IntegrationType:        core.IntegrationTypeWhatsApp,
+		ExternalConversationID: req.ConvoID,
+		ExternalMessageIds:     req.WAMessageIDs,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to update read marker", err))
+		return
 	}
-	if whatsappIntegration.LastSeen.Valid {
-		whatsappInfo["last_seen"] = whatsappIntegration.LastSeen.Time
+	if err == nil { // No row when none of the messages are stored yet
+		response["unread_count"] = read.UnreadCount
 	}
 
-	response := map[string]interface{}{
-		"user_id":  userID,
-		"whatsapp": whatsappInfo,
+	httpx.JSON(w, http.StatusOK, response)
+}
+
+// LogoutWhatsApp logs the user's WhatsApp session out through the bridge
+func (h *APIHandler) LogoutWhatsApp(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
+		return
 	}
 
-	h.logger.Debug("Settings retrieved", zap.String("user_id", userID.String()))
-	h.writeJSON(w, http.StatusOK, response)
+	if err := h.bridgeClient.Logout(r.Context(), userID.String()); err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadGateway, "Failed to log out of WhatsApp", err))
+		return
+	}
+
+	h.logger.Info("WhatsApp logout requested", zap.String("user_id", userID.String()))
+	httpx.JSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
+
+// ResyncWhatsApp asks the bridge to re-fetch WhatsApp app state for the user
+func (h *APIHandler) ResyncWhatsApp(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
+		return
+	}
+
+	fullSync := r.URL.Query().Get("full") == "true"
+	if err := h.bridgeClient.TriggerResync(r.Context(), userID.String(), fullSync); err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadGateway, "Failed to resync WhatsApp", err))
+		return
+	}
+
+	h.logger.Info("WhatsApp resync requested",
+		zap.String("user_id", userID.String()),
+		zap.Bool("full_sync", fullSync))
+	httpx.JSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
+
+// ListWhatsAppGroups lists the groups the user's WhatsApp account belongs to.
+// The bridge syncs each group as a conversation while listing them.
+func (h *APIHandler) ListWhatsAppGroups(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
+		return
+	}
+
+	groups, err := h.bridgeClient.ListGroups(r.Context(), userID.String())
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadGateway, "Failed to list WhatsApp groups", err))
+		return
+	}
+
+	result := make([]map[string]interface{}, len(groups))
+	for i, group := range groups {
+		result[i] = map[string]interface{}{
+			"jid":               group.Jid,
+			"name":              group.Name,
+			"topic":             group.Topic,
+			"owner_jid":         group.OwnerJid,
+			"participant_count": group.ParticipantCount,
+			"is_announce":       group.IsAnnounce,
+			"is_locked":         group.IsLocked,
+		}
+		if group.CreatedAt != nil {
+			result[i]["created_at"] = group.CreatedAt.AsTime()
+		}
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]interface{}{"groups": result})
 }
 
 // Helper methods
@@ -411,7 +828,7 @@ func (h *APIHandler) extractUserFromToken(r *http.Request) (uuid.UUID, error) {
 	}
 
 	// Validate token using JWT config
-	claims, err := h.jwtConfig.ValidateToken(tokenString)
+	claims, err := h.jwtConfig.ValidateTokenContext(r.Context(), tokenString)
 	if err != nil {
 		return uuid.Nil, errors.New("invalid token")
 	}
@@ -448,7 +865,7 @@ func (h *APIHandler) SyncConversations(w http.ResponseWriter, r *http.Request) {
 	integrationIDStr := chi.URLParam(r, "integration_id")
 	integrationID, err := strconv.Atoi(integrationIDStr)
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid integration_id", err)
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid integration_id", err))
 		return
 	}
 
@@ -457,7 +874,7 @@ func (h *APIHandler) SyncConversations(w http.ResponseWriter, r *http.Request) {
 	if sinceSeqStr != "" {
 		sinceSeq, err = strconv.ParseInt(sinceSeqStr, 10, 64)
 		if err != nil {
-			h.writeError(w, http.StatusBadRequest, "Invalid since_seq parameter", err)
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid since_seq parameter", err))
 			return
 		}
 	}
@@ -467,19 +884,26 @@ func (h *APIHandler) SyncConversations(w http.ResponseWriter, r *http.Request) {
 	if limitStr != "" {
 		limitInt, err := strconv.Atoi(limitStr)
 		if err != nil || limitInt < 1 || limitInt > 1000 {
-			h.writeError(w, http.StatusBadRequest, "Invalid limit parameter (must be 1-1000)", nil)
+			httpx.Error(w, apierror.New(http.StatusBadRequest, "Invalid limit parameter (must be 1-1000)"))
 			return
 		}
 		limit = int32(limitInt)
 	}
 
+	conversationTypes, err := parseIncludeTypes(r.URL.Query().Get("include_types"))
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid include_types parameter", err))
+		return
+	}
+
 	conversations, err := h.queries.ListUserIntegrationConversationsSinceSeq(r.Context(), dbgen.ListUserIntegrationConversationsSinceSeqParams{
 		UserIntegrationID: int32(integrationID),
 		SinceSeq:          sinceSeq,
+		ConversationTypes: conversationTypes,
 		LimitCount:        limit,
 	})
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, "Failed to fetch conversations", err)
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to fetch conversations", err))
 		return
 	}
 
@@ -492,7 +916,7 @@ func (h *APIHandler) SyncConversations(w http.ResponseWriter, r *http.Request) {
 	hasMore := len(conversations) == int(limit)
 
 	response := map[string]interface{}{
-		"conversations": conversations,
+		"conversations": convertConversationsToAPI(conversations),
 		"latest_seq":    latestSeq,
 		"has_more":      hasMore,
 		"total_count":   len(conversations),
@@ -504,7 +928,65 @@ func (h *APIHandler) SyncConversations(w http.ResponseWriter, r *http.Request) {
 		zap.Int("count", len(conversations)),
 		zap.Bool("has_more", hasMore))
 
-	h.writeJSON(w, http.StatusOK, response)
+	httpx.JSON(w, http.StatusOK, response)
+}
+
+// defaultConversationTypes are the conversation types a conversation sync
+// returns. Broadcast lists (including status updates) and channels are only
+// returned when asked for with include_types.
+var defaultConversationTypes = []string{"individual", "group"}
+
+// optionalConversationTypes are the types include_types can add
+var optionalConversationTypes = []string{"broadcast", "channel"}
+
+// parseIncludeTypes returns the conversation types to sync given a
+// comma-separated include_types parameter
+func parseIncludeTypes(includeTypes string) ([]string, error) {
+	types := slices.Clone(defaultConversationTypes)
+	if includeTypes == "" {
+		return types, nil
+	}
+	for _, conversationType := range strings.Split(includeTypes, ",") {
+		conversationType = strings.TrimSpace(conversationType)
+		switch {
+		case slices.Contains(types, conversationType):
+		case slices.Contains(optionalConversationTypes, conversationType):
+			types = append(types, conversationType)
+		default:
+			return nil, fmt.Errorf("unknown conversation type %q", conversationType)
+		}
+	}
+	return types, nil
+}
+
+// syncEventTypes are the event types /sync can be filtered by
+var syncEventTypes = []string{
+	events.TypeMessageIn,
+	events.TypeMessageOutPending,
+	events.TypeMessageOutSent,
+	events.TypeMessageDelivery,
+	events.TypePresence,
+	events.TypeContactUpdate,
+	events.TypeHistorySync,
+	events.TypeConversationState,
+	events.TypeMessagesExpired,
+	events.TypeParticipantRole,
+}
+
+// parseEventTypes returns the event types to sync given the repeated type
+// parameter, or nil for all types
+func parseEventTypes(values []string) ([]string, error) {
+	var types []string
+	for _, eventType := range values {
+		switch {
+		case eventType == "" || slices.Contains(types, eventType):
+		case slices.Contains(syncEventTypes, eventType):
+			types = append(types, eventType)
+		default:
+			return nil, fmt.Errorf("unknown event type %q", eventType)
+		}
+	}
+	return types, nil
 }
 
 // SyncMessages handles message sync requests
@@ -512,7 +994,7 @@ func (h *APIHandler) SyncMessages(w http.ResponseWriter, r *http.Request) {
 	integrationIDStr := chi.URLParam(r, "integration_id")
 	integrationID, err := strconv.Atoi(integrationIDStr)
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid integration_id", err)
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid integration_id", err))
 		return
 	}
 
@@ -521,7 +1003,7 @@ func (h *APIHandler) SyncMessages(w http.ResponseWriter, r *http.Request) {
 	if sinceSeqStr != "" {
 		sinceSeq, err = strconv.ParseInt(sinceSeqStr, 10, 64)
 		if err != nil {
-			h.writeError(w, http.StatusBadRequest, "Invalid since_seq parameter", err)
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid since_seq parameter", err))
 			return
 		}
 	}
@@ -531,7 +1013,7 @@ func (h *APIHandler) SyncMessages(w http.ResponseWriter, r *http.Request) {
 	if limitStr != "" {
 		limitInt, err := strconv.Atoi(limitStr)
 		if err != nil || limitInt < 1 || limitInt > 2000 {
-			h.writeError(w, http.StatusBadRequest, "Invalid limit parameter (must be 1-2000)", nil)
+			httpx.Error(w, apierror.New(http.StatusBadRequest, "Invalid limit parameter (must be 1-2000)"))
 			return
 		}
 		limit = int32(limitInt)
@@ -543,7 +1025,7 @@ func (h *APIHandler) SyncMessages(w http.ResponseWriter, r *http.Request) {
 		LimitCount:        limit,
 	})
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, "Failed to fetch messages", err)
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to fetch messages", err))
 		return
 	}
 
@@ -556,7 +1038,7 @@ func (h *APIHandler) SyncMessages(w http.ResponseWriter, r *http.Request) {
 	hasMore := len(messages) == int(limit)
 
 	response := map[string]interface{}{
-		"messages":    messages,
+		"messages":    convertMessagesToAPI(messages),
 		"latest_seq":  latestSeq,
 		"has_more":    hasMore,
 		"total_count": len(messages),
@@ -568,7 +1050,7 @@ func (h *APIHandler) SyncMessages(w http.ResponseWriter, r *http.Request) {
 		zap.Int("count", len(messages)),
 		zap.Bool("has_more", hasMore))
 
-	h.writeJSON(w, http.StatusOK, response)
+	httpx.JSON(w, http.StatusOK, response)
 }
 
 // SyncContacts handles contact sync requests
@@ -576,7 +1058,7 @@ func (h *APIHandler) SyncContacts(w http.ResponseWriter, r *http.Request) {
 	integrationIDStr := chi.URLParam(r, "integration_id")
 	integrationID, err := strconv.Atoi(integrationIDStr)
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid integration_id", err)
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid integration_id", err))
 		return
 	}
 
@@ -585,7 +1067,7 @@ func (h *APIHandler) SyncContacts(w http.ResponseWriter, r *http.Request) {
 	if sinceSeqStr != "" {
 		sinceSeq, err = strconv.ParseInt(sinceSeqStr, 10, 64)
 		if err != nil {
-			h.writeError(w, http.StatusBadRequest, "Invalid since_seq parameter", err)
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid since_seq parameter", err))
 			return
 		}
 	}
@@ -595,7 +1077,7 @@ func (h *APIHandler) SyncContacts(w http.ResponseWriter, r *http.Request) {
 	if limitStr != "" {
 		limitInt, err := strconv.Atoi(limitStr)
 		if err != nil || limitInt < 1 || limitInt > 1000 {
-			h.writeError(w, http.StatusBadRequest, "Invalid limit parameter (must be 1-1000)", nil)
+			httpx.Error(w, apierror.New(http.StatusBadRequest, "Invalid limit parameter (must be 1-1000)"))
 			return
 		}
 		limit = int32(limitInt)
@@ -607,7 +1089,7 @@ func (h *APIHandler) SyncContacts(w http.ResponseWriter, r *http.Request) {
 		LimitCount:        limit,
 	})
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, "Failed to fetch contacts", err)
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to fetch contacts", err))
 		return
 	}
 
@@ -620,7 +1102,7 @@ func (h *APIHandler) SyncContacts(w http.ResponseWriter, r *http.Request) {
 	hasMore := len(contacts) == int(limit)
 
 	response := map[string]interface{}{
-		"contacts":    contacts,
+		"contacts":    convertSyncContactsToAPI(contacts),
 		"latest_seq":  latestSeq,
 		"has_more":    hasMore,
 		"total_count": len(contacts),
@@ -632,48 +1114,59 @@ func (h *APIHandler) SyncContacts(w http.ResponseWriter, r *http.Request) {
 		zap.Int("count", len(contacts)),
 		zap.Bool("has_more", hasMore))
 
-	h.writeJSON(w, http.StatusOK, response)
+	httpx.JSON(w, http.StatusOK, response)
 }
 
-// GetSyncStatus handles sync status requests
+// GetSyncStatus returns the latest seq and row count of each entity synced
+// for one of the user's integrations. Seqs and counts are 0 until the first
+// sync lands.
 func (h *APIHandler) GetSyncStatus(w http.ResponseWriter, r *http.Request) {
-	integrationIDStr := chi.URLParam(r, "integration_id")
-	integrationID, err := strconv.Atoi(integrationIDStr)
+	userID, err := h.extractUserFromToken(r)
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid integration_id", err)
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
 		return
 	}
 
-	// Get latest seq numbers for each entity type
-	latestConvSeq, err := h.queries.GetUserIntegrationLatestConversationSeq(r.Context(), int32(integrationID))
+	integrationID, err := strconv.ParseInt(chi.URLParam(r, "integration_id"), 10, 32)
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, "Failed to get latest conversation seq", err)
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid integration_id", err))
 		return
 	}
 
-	latestMsgSeq, err := h.queries.GetUserIntegrationLatestMessageSeq(r.Context(), int32(integrationID))
+	integration, err := h.integrationService.GetIntegrationByID(r.Context(), int32(integrationID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		httpx.Error(w, apierror.New(http.StatusNotFound, "Integration not found"))
+		return
+	}
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, "Failed to get latest message seq", err)
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get integration", err))
+		return
+	}
+	if integration.UserID != userID {
+		httpx.Error(w, apierror.New(http.StatusForbidden, "Integration belongs to another user"))
 		return
 	}
 
-	latestContactSeq, err := h.queries.GetUserIntegrationLatestContactSeq(r.Context(), int32(integrationID))
+	stats, err := h.integrationService.GetIntegrationSyncStats(r.Context(), integration.ID)
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, "Failed to get latest contact seq", err)
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get sync status", err))
 		return
 	}
 
 	response := map[string]interface{}{
-		"latest_conversation_seq": latestConvSeq,
-		"latest_message_seq":      latestMsgSeq,
-		"latest_contact_seq":      latestContactSeq,
+		"latest_conversation_seq": stats.LatestConversationSeq,
+		"latest_message_seq":      stats.LatestMessageSeq,
+		"latest_contact_seq":      stats.LatestContactSeq,
+		"conversation_count":      stats.Conversations,
+		"message_count":           stats.Messages,
+		"contact_count":           stats.Contacts,
 	}
 
 	h.logger.Debug("Sync status response",
-		zap.Int("integration_id", integrationID),
-		zap.Any("latest_conv_seq", latestConvSeq),
-		zap.Any("latest_msg_seq", latestMsgSeq),
-		zap.Any("latest_contact_seq", latestContactSeq))
+		zap.Int32("integration_id", integration.ID),
+		zap.Int64("latest_conv_seq", stats.LatestConversationSeq),
+		zap.Int64("latest_msg_seq", stats.LatestMessageSeq),
+		zap.Int64("latest_contact_seq", stats.LatestContactSeq))
 
-	h.writeJSON(w, http.StatusOK, response)
+	httpx.JSON(w, http.StatusOK, response)
 }
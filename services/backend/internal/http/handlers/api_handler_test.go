@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/backend/internal/testutil"
+	dbgen "github.com/tennex/pkg/db/gen"
+)
+
+func TestParseIncludeTypes(t *testing.T) {
+	tests := []struct {
+		includeTypes string
+		want         []string
+	}{
+		{"", []string{"individual", "group"}},
+		{"channel", []string{"individual", "group", "channel"}},
+		{"broadcast, channel", []string{"individual", "group", "broadcast", "channel"}},
+		// Default types can be named without being repeated
+		{"group,channel,channel", []string{"individual", "group", "channel"}},
+	}
+	for _, tt := range tests {
+		got, err := parseIncludeTypes(tt.includeTypes)
+		if err != nil {
+			t.Errorf("parseIncludeTypes(%q): %v", tt.includeTypes, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parseIncludeTypes(%q) = %v, want %v", tt.includeTypes, got, tt.want)
+		}
+	}
+
+	if _, err := parseIncludeTypes("broadcast,spam"); err == nil {
+		t.Error("expected an error for an unknown type")
+	}
+}
+
+func TestParseEventTypes(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   []string
+	}{
+		{nil, nil},
+		{[]string{""}, nil},
+		{[]string{"msg_in"}, []string{"msg_in"}},
+		{[]string{"msg_in", "msg_delivery", "msg_in"}, []string{"msg_in", "msg_delivery"}},
+	}
+	for _, tt := range tests {
+		got, err := parseEventTypes(tt.values)
+		if err != nil {
+			t.Errorf("parseEventTypes(%q): %v", tt.values, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parseEventTypes(%q) = %v, want %v", tt.values, got, tt.want)
+		}
+	}
+
+	if _, err := parseEventTypes([]string{"msg_in", "msg_spam"}); err == nil {
+		t.Error("expected an error for an unknown type")
+	}
+}
+
+func TestSyncConversationsFiltersBroadcastsAndChannels(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	apiHandler := NewAPIHandler(nil, nil, nil, nil, nil, dbgen.New(pool), testJWTSecret, zap.NewNop())
+
+	const individual = "972501234567@s.whatsapp.net"
+	userID := createTestUser(t, pool, "sync-conversations")
+	integrationID := createTestIntegration(t, pool, userID, "972500000000@s.whatsapp.net")
+	for jid, conversationType := range map[string]string{
+		individual:                      "individual",
+		"120363000000000000@g.us":       "group",
+		"1700000000@broadcast":          "broadcast",
+		"status@broadcast":              "broadcast",
+		"120363000000000000@newsletter": "channel",
+	} {
+		createTestConversation(t, pool, integrationID, jid, conversationType)
+	}
+
+	token := testToken(t, userID)
+	sync := func(query string) (int, []string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/sync/conversations/%d%s", integrationID, query), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		apiHandler.Routes().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			return rec.Code, nil
+		}
+		var response struct {
+			Conversations []struct {
+				ExternalConversationID string `json:"external_conversation_id"`
+			} `json:"conversations"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		var jids []string
+		for _, conversation := range response.Conversations {
+			jids = append(jids, conversation.ExternalConversationID)
+		}
+		slices.Sort(jids)
+		return rec.Code, jids
+	}
+
+	if _, jids := sync(""); !slices.Equal(jids, []string{"120363000000000000@g.us", individual}) {
+		t.Errorf("expected only the individual and group conversations by default, got %v", jids)
+	}
+	if _, jids := sync("?include_types=channel"); !slices.Equal(jids, []string{"120363000000000000@g.us", "120363000000000000@newsletter", individual}) {
+		t.Errorf("expected the channel to be included, got %v", jids)
+	}
+	if _, jids := sync("?include_types=broadcast,channel"); len(jids) != 5 {
+		t.Errorf("expected all 5 conversations, got %v", jids)
+	}
+	if code, _ := sync("?include_types=spam"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown type, got %d", code)
+	}
+}
+
+func TestGetSyncStatusForNewIntegration(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationService := core.NewIntegrationService(repo.NewIntegrationRepository(pool), zap.NewNop())
+	apiHandler := NewAPIHandler(nil, nil, nil, integrationService, nil, dbgen.New(pool), testJWTSecret, zap.NewNop())
+
+	const contact = "972501234567@s.whatsapp.net"
+	ownerID := createTestUser(t, pool, "sync-status-owner")
+	integrationID := createTestIntegration(t, pool, ownerID, "972500000000@s.whatsapp.net")
+	ownerToken := testToken(t, ownerID)
+
+	type syncStatus struct {
+		LatestConversationSeq int64 `json:"latest_conversation_seq"`
+		LatestMessageSeq      int64 `json:"latest_message_seq"`
+		LatestContactSeq      int64 `json:"latest_contact_seq"`
+		ConversationCount     int64 `json:"conversation_count"`
+		MessageCount          int64 `json:"message_count"`
+		ContactCount          int64 `json:"contact_count"`
+	}
+	get := func(token string, integrationID int32) (*httptest.ResponseRecorder, syncStatus) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/sync/status/%d", integrationID), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		apiHandler.Routes().ServeHTTP(rec, req)
+		var status syncStatus
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return rec, status
+	}
+
+	// Nothing synced yet is an empty status, not an error
+	rec, status := get(ownerToken, integrationID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a new integration, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if status != (syncStatus{}) {
+		t.Errorf("expected an empty status, got %+v", status)
+	}
+
+	conversationID := createTestConversation(t, pool, integrationID, contact, "individual")
+	createTestMessage(t, pool, conversationID, "PN1", "text", "hello", time.Unix(1700000000, 0))
+	_, err := pool.Exec(context.Background(), `
+		INSERT INTO contacts (user_integration_id, external_contact_id, integration_type, display_name)
+		VALUES ($1, $2, 'whatsapp', 'Dana')`, integrationID, contact)
+	if err != nil {
+		t.Fatalf("failed to create contact: %v", err)
+	}
+	_, status = get(ownerToken, integrationID)
+	if status.ConversationCount != 1 || status.MessageCount != 1 || status.ContactCount != 1 {
+		t.Errorf("expected one of each entity, got %+v", status)
+	}
+	if status.LatestConversationSeq == 0 || status.LatestMessageSeq == 0 || status.LatestContactSeq == 0 {
+		t.Errorf("expected seqs once synced, got %+v", status)
+	}
+
+	otherToken := testToken(t, createTestUser(t, pool, "sync-status-other"))
+	if rec, _ := get(otherToken, integrationID); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for another user's integration, got %d", rec.Code)
+	}
+	if rec, _ := get(ownerToken, integrationID+1000); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown integration, got %d", rec.Code)
+	}
+	if rec, _ := get("", integrationID); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rec.Code)
+	}
+}
+
+func TestGetSettingsListsEveryWhatsAppNumber(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	integrationService := core.NewIntegrationService(repo.NewIntegrationRepository(pool), zap.NewNop())
+	apiHandler := NewAPIHandler(nil, nil, nil, integrationService, nil, dbgen.New(pool), testJWTSecret, zap.NewNop())
+
+	userID := createTestUser(t, pool, "two-numbers")
+	createTestIntegration(t, pool, userID, "972501111111@s.whatsapp.net")
+	createTestIntegration(t, pool, userID, "972502222222@s.whatsapp.net")
+
+	req := httptest.NewRequest(http.MethodGet, "/settings", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken(t, userID))
+	rec := httptest.NewRecorder()
+	apiHandler.Routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 from /settings, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var settings struct {
+		WhatsApp []struct {
+			WaJID     string `json:"wa_jid"`
+			Connected bool   `json:"connected"`
+		} `json:"whatsapp"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &settings); err != nil {
+		t.Fatalf("failed to decode settings: %v", err)
+	}
+	if len(settings.WhatsApp) != 2 {
+		t.Fatalf("expected 2 WhatsApp numbers in settings, got %s", rec.Body.String())
+	}
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
-	"encoding/json"
+	"crypto/rand"
 	"errors"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -13,8 +16,11 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/oapi-codegen/runtime/types"
+	"github.com/tennex/backend/internal/core"
 	api "github.com/tennex/pkg/api/gen" // Generated API types
-	db "github.com/tennex/pkg/db/gen"   // Generated DB types and functions
+	"github.com/tennex/pkg/apierror"
+	db "github.com/tennex/pkg/db/gen" // Generated DB types and functions
+	"github.com/tennex/pkg/httpx"
 	"github.com/tennex/shared/auth"
 )
 
@@ -24,29 +30,88 @@ var (
 	ErrInvalidToken = errors.New("invalid token")
 )
 
+const (
+	// bcrypt cost used unless configured otherwise
+	DefaultBcryptCost = 12
+
+	// Lowest bcrypt cost accepted in configuration
+	MinBcryptCost = 10
+)
+
+// AuthConfig holds the password hashing and login throttling settings
+type AuthConfig struct {
+	BcryptCost  int
+	Throttler   *core.LoginThrottler // Counts failed logins per username
+	IPThrottler *core.LoginThrottler // Counts failed logins per client IP
+	ResetSender PasswordResetSender  // Delivers password reset tokens; nil keeps the current one
+}
+
 // AuthHandler handles authentication requests using generated types
 type AuthHandler struct {
-	queries   *db.Queries
-	jwtConfig *auth.JWTConfig
-	logger    *zap.Logger
+	queries     *db.Queries
+	jwtConfig   *auth.JWTConfig
+	resetSender PasswordResetSender
+	bcryptCost  int
+	throttler   *core.LoginThrottler
+	ipThrottler *core.LoginThrottler
+	logger      *zap.Logger
+
+	// Limit password reset requests per email and per client IP
+	resetThrottler   *core.LoginThrottler
+	resetIPThrottler *core.LoginThrottler
+
+	// Compared against when a login names no known user, so it takes as long
+	// as one with a wrong password
+	dummyHashOnce sync.Once
+	dummyHash     []byte
 }
 
 // NewAuthHandler creates a new authentication handler
 func NewAuthHandler(queries *db.Queries, jwtSecret string, logger *zap.Logger) *AuthHandler {
 	return &AuthHandler{
-		queries:   queries,
-		jwtConfig: auth.DefaultJWTConfig(jwtSecret),
-		logger:    logger.Named("auth_handler"),
+		queries:     queries,
+		jwtConfig:   auth.DefaultJWTConfig(jwtSecret),
+		resetSender: NewLogPasswordResetSender(logger, false),
+		bcryptCost:  DefaultBcryptCost,
+		throttler:   core.NewLoginThrottler(core.DefaultLoginThrottleConfig(), core.NewMemoryLoginAttemptStore()),
+		ipThrottler: core.NewLoginThrottler(core.DefaultLoginIPThrottleConfig(), core.NewMemoryLoginAttemptStore()),
+		logger:      logger.Named("auth_handler"),
+
+		resetThrottler:   core.NewLoginThrottler(defaultPasswordResetThrottleConfig(3), core.NewMemoryLoginAttemptStore()),
+		resetIPThrottler: core.NewLoginThrottler(defaultPasswordResetThrottleConfig(20), core.NewMemoryLoginAttemptStore()),
 	}
 }
 
+// SetConfig replaces the password hashing cost, login throttlers and, if
+// given, the password reset sender
+func (h *AuthHandler) SetConfig(config AuthConfig) {
+	h.bcryptCost = config.BcryptCost
+	h.throttler = config.Throttler
+	h.ipThrottler = config.IPThrottler
+	if config.ResetSender != nil {
+		h.resetSender = config.ResetSender
+	}
+}
+
+// SetRevocationCheck sets the check rejecting tokens of ended sessions, such
+// as those issued before a password reset
+func (h *AuthHandler) SetRevocationCheck(check auth.RevocationCheck) {
+	h.jwtConfig.RevocationCheck = check
+}
+
 // Routes returns the authentication routes
 func (h *AuthHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 
+	// Credentials are small, so auth gets a tighter limit than the rest of
+	// the API
+	r.Use(httpx.MaxBodySize(MaxAuthBodyBytes))
+
 	r.Post("/register", h.RegisterUser)
 	r.Post("/login", h.LoginUser)
 	r.Get("/me", h.GetCurrentUser)
+	r.Post("/password-reset/request", h.RequestPasswordReset)
+	r.Post("/password-reset/confirm", h.ConfirmPasswordReset)
 
 	return r
 }
@@ -55,14 +120,14 @@ func (h *AuthHandler) Routes() chi.Router {
 func (h *AuthHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	// Use generated API type for request validation
 	var req api.RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid JSON", err)
+	if err := httpx.DecodeStrict(r, &req, 0); err != nil {
+		httpx.Error(w, err)
 		return
 	}
 
 	// Validate required fields (OpenAPI validation happens automatically)
-	if len(req.Password) < 8 {
-		h.writeError(w, http.StatusBadRequest, "Password must be at least 8 characters", nil)
+	if err := validatePassword(req.Password, req.Username, string(req.Email)); err != nil {
+		httpx.Error(w, err)
 		return
 	}
 
@@ -70,11 +135,11 @@ func (h *AuthHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	usernameExists, err := h.queries.CheckUsernameExists(r.Context(), req.Username)
 	if err != nil {
 		h.logger.Error("Failed to check username", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Database error", err)
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Database error", err))
 		return
 	}
 	if usernameExists {
-		h.writeError(w, http.StatusBadRequest, "Username already exists", nil)
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Username already exists"))
 		return
 	}
 
@@ -82,19 +147,19 @@ func (h *AuthHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	emailExists, err := h.queries.CheckEmailExists(r.Context(), string(req.Email))
 	if err != nil {
 		h.logger.Error("Failed to check email", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Database error", err)
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Database error", err))
 		return
 	}
 	if emailExists {
-		h.writeError(w, http.StatusBadRequest, "Email already exists", nil)
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Email already exists"))
 		return
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), h.bcryptCost)
 	if err != nil {
 		h.logger.Error("Failed to hash password", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to process password", err)
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to process password", err))
 		return
 	}
 
@@ -113,15 +178,15 @@ func (h *AuthHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	user, err := h.queries.CreateUser(r.Context(), createParams)
 	if err != nil {
 		h.logger.Error("Failed to create user", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to create user", err)
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to create user", err))
 		return
 	}
 
 	// Generate JWT token
-	token, expiresAt, err := h.generateJWT(user.ID)
+	token, expiresAt, err := h.generateJWT(user.ID, user.Role)
 	if err != nil {
 		h.logger.Error("Failed to generate token", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to generate token", err)
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to generate token", err))
 		return
 	}
 
@@ -148,38 +213,73 @@ func (h *AuthHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 		zap.String("user_id", user.ID.String()),
 		zap.String("username", user.Username))
 
-	h.writeJSON(w, http.StatusCreated, response)
+	httpx.JSON(w, http.StatusCreated, response)
 }
 
 // LoginUser handles user login using generated types
 func (h *AuthHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 	// Use generated API type for request validation
 	var req api.LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid JSON", err)
+	if err := httpx.DecodeStrict(r, &req, 0); err != nil {
+		httpx.Error(w, err)
+		return
+	}
+
+	// IPs that keep failing are locked out for longer each time, and logins
+	// to users that keep failing are slowed down. They're counted apart, so
+	// neither trying many users from one IP nor one user from many IPs
+	// escapes the throttle. Each attempt is counted as failed until it
+	// succeeds, so concurrent guesses can't all start before one fails.
+	ipKey := core.LoginIPKey(clientIP(r))
+	if remaining, refused := h.ipThrottler.Reserve(ipKey); refused {
+		h.logger.Warn("Login refused while the client IP is locked out",
+			zap.String("key", ipKey),
+			zap.Duration("remaining", remaining))
+		w.Header().Set("Retry-After", strconv.Itoa(int(remaining.Round(time.Second).Seconds())))
+		httpx.Error(w, apierror.New(http.StatusTooManyRequests, "Too many failed login attempts"))
 		return
 	}
 
-	// Find user by username or email using generated DB function
+	// Find user by username or email using generated DB function. Unknown
+	// users get the same error, after the same delay and bcrypt work, as a
+	// wrong password.
 	user, err := h.queries.GetUserByUsernameOrEmail(r.Context(), req.Username)
+	userKey := core.LoginUsernameKey(req.Username)
+	if err == nil {
+		userKey = core.LoginUserKey(user.ID)
+	}
+	if delay, _ := h.throttler.Reserve(userKey); delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
 	if err != nil {
 		h.logger.Debug("User not found", zap.String("username", req.Username))
-		h.writeError(w, http.StatusUnauthorized, "Invalid credentials", nil)
+		bcrypt.CompareHashAndPassword(h.getDummyHash(), []byte(req.Password))
+		httpx.Error(w, errInvalidCredentials)
 		return
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
 		h.logger.Debug("Invalid password", zap.String("user_id", user.ID.String()))
-		h.writeError(w, http.StatusUnauthorized, "Invalid credentials", nil)
+		httpx.Error(w, errInvalidCredentials)
 		return
 	}
+	// The user's failures are forgotten, but only this attempt is given back
+	// to the IP; its failures would otherwise be reset by logging in to an
+	// account of one's own between guesses
+	h.throttler.RecordSuccess(userKey)
+	h.ipThrottler.Release(ipKey)
 
 	// Generate JWT token
-	token, expiresAt, err := h.generateJWT(user.ID)
+	token, expiresAt, err := h.generateJWT(user.ID, user.Role)
 	if err != nil {
 		h.logger.Error("Failed to generate token", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to generate token", err)
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to generate token", err))
 		return
 	}
 
@@ -206,7 +306,7 @@ func (h *AuthHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 		zap.String("user_id", user.ID.String()),
 		zap.String("username", user.Username))
 
-	h.writeJSON(w, http.StatusOK, response)
+	httpx.JSON(w, http.StatusOK, response)
 }
 
 // GetCurrentUser handles getting current user info using generated types
@@ -214,7 +314,7 @@ func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	// Extract user ID from JWT token (middleware would normally do this)
 	userID, err := h.extractUserFromToken(r)
 	if err != nil {
-		h.writeError(w, http.StatusUnauthorized, "Invalid or missing token", err)
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
 		return
 	}
 
@@ -222,7 +322,7 @@ func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	user, err := h.queries.GetUserByID(r.Context(), userID)
 	if err != nil {
 		h.logger.Error("Failed to get user", zap.Error(err))
-		h.writeError(w, http.StatusNotFound, "User not found", err)
+		httpx.Error(w, apierror.Wrap(http.StatusNotFound, "User not found", err))
 		return
 	}
 
@@ -241,13 +341,36 @@ func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt: user.UpdatedAt,
 	}
 
-	h.writeJSON(w, http.StatusOK, response)
+	httpx.JSON(w, http.StatusOK, response)
 }
 
 // Helper methods
 
-func (h *AuthHandler) generateJWT(userID uuid.UUID) (string, time.Time, error) {
-	return h.jwtConfig.GenerateToken(userID)
+// errInvalidCredentials is the error given for any bad credentials
+var errInvalidCredentials = apierror.New(http.StatusUnauthorized, "Invalid credentials")
+
+// getDummyHash returns a hash of a random password at the configured cost
+func (h *AuthHandler) getDummyHash() []byte {
+	h.dummyHashOnce.Do(func() {
+		password := make([]byte, 16)
+		rand.Read(password)
+		h.dummyHash, _ = bcrypt.GenerateFromPassword(password, h.bcryptCost)
+	})
+	return h.dummyHash
+}
+
+// clientIP returns the IP a request came from. The RealIP middleware has
+// already replaced RemoteAddr with the client IP forwarded by a trusted proxy,
+// if any.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func (h *AuthHandler) generateJWT(userID uuid.UUID, role string) (string, time.Time, error) {
+	return h.jwtConfig.GenerateToken(userID, role)
 }
 
 func (h *AuthHandler) extractUserFromToken(r *http.Request) (uuid.UUID, error) {
@@ -259,38 +382,10 @@ func (h *AuthHandler) extractUserFromToken(r *http.Request) (uuid.UUID, error) {
 	}
 
 	// Validate token using shared auth package
-	claims, err := h.jwtConfig.ValidateToken(tokenString)
+	claims, err := h.jwtConfig.ValidateTokenContext(r.Context(), tokenString)
 	if err != nil {
 		return uuid.Nil, ErrInvalidToken
 	}
 
 	return claims.UserID, nil
 }
-
-func (h *AuthHandler) writeJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		h.logger.Error("Failed to encode JSON response", zap.Error(err))
-	}
-}
-
-func (h *AuthHandler) writeError(w http.ResponseWriter, status int, message string, err error) {
-	h.logger.Error("API error",
-		zap.String("message", message),
-		zap.Error(err),
-		zap.Int("status", status))
-
-	// Use generated API type for error response
-	response := api.ErrorResponse{
-		Error:     message,
-		Timestamp: time.Now().UTC(),
-	}
-
-	if err != nil {
-		details := map[string]interface{}{"details": err.Error()}
-		response.Details = &details
-	}
-
-	h.writeJSON(w, status, response)
-}
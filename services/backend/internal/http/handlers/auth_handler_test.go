@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/core"
+)
+
+func TestLoginRejectsLockedOutClient(t *testing.T) {
+	handler := NewAuthHandler(nil, "test-secret", zap.NewNop())
+	ipThrottler := core.NewLoginThrottler(core.LoginThrottleConfig{
+		MaxFailures: 2,
+		Window:      time.Hour,
+		BaseLockout: time.Minute,
+		MaxLockout:  time.Hour,
+	}, core.NewMemoryLoginAttemptStore())
+	handler.SetConfig(AuthConfig{
+		BcryptCost:  MinBcryptCost,
+		Throttler:   core.NewLoginThrottler(core.DefaultLoginThrottleConfig(), core.NewMemoryLoginAttemptStore()),
+		IPThrottler: ipThrottler,
+	})
+	routes := handler.Routes()
+
+	for range 2 {
+		ipThrottler.Reserve(core.LoginIPKey("192.0.2.1"))
+	}
+
+	login := func(remoteAddr, username string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"`+username+`","password":"whatever-it-is"}`))
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Any username from a locked out IP is refused before credentials are
+	// checked, for as long as the lockout lasts
+	for _, username := range []string{"alice", "bob"} {
+		rec := login("192.0.2.1:51234", username)
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("%s: got status %d, want 429", username, rec.Code)
+		}
+		if rec.Header().Get("Retry-After") != "60" {
+			t.Errorf("%s: got Retry-After %q, want 60", username, rec.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	for remoteAddr, want := range map[string]string{
+		"192.0.2.1:51234":   "192.0.2.1",
+		"[2001:db8::1]:443": "2001:db8::1",
+		"192.0.2.1":         "192.0.2.1", // Set by the RealIP middleware
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		if got := clientIP(req); got != want {
+			t.Errorf("clientIP(%q) = %q, want %q", remoteAddr, got, want)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package handlers
+
+// DefaultMaxBodyBytes is the request body limit used when none is configured
+const DefaultMaxBodyBytes = 1 << 20 // 1 MiB
+
+// DefaultMaxMediaBytes is the media upload limit used when none is
+// configured, matching the largest image WhatsApp accepts
+const DefaultMaxMediaBytes = 16 << 20 // 16 MiB
+
+// MaxAuthBodyBytes limits auth requests, which only carry credentials
+const MaxAuthBodyBytes = 64 << 10 // 64 KiB
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	api "github.com/tennex/pkg/api/gen"
+	"github.com/tennex/pkg/httpx"
+)
+
+func TestAuthRoutesRejectOversizedAndUnknownFieldBodies(t *testing.T) {
+	routes := NewAuthHandler(nil, "test-secret", zap.NewNop()).Routes()
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		want    int
+		message string
+	}{
+		{
+			name:    "oversized login",
+			path:    "/login",
+			body:    `{"username":"` + strings.Repeat("x", MaxAuthBodyBytes) + `","password":"secret123"}`,
+			want:    http.StatusRequestEntityTooLarge,
+			message: "Request body too large",
+		},
+		{
+			name:    "unknown login field",
+			path:    "/login",
+			body:    `{"username":"alice","password":"secret123","admin":true}`,
+			want:    http.StatusBadRequest,
+			message: "Invalid request body",
+		},
+		{
+			name:    "unknown register field",
+			path:    "/register",
+			body:    `{"username":"alice","email":"alice@example.com","password":"secret123","role":"admin"}`,
+			want:    http.StatusBadRequest,
+			message: "Invalid request body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			routes.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.want)
+			}
+
+			var response api.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode error response: %v", err)
+			}
+			if response.Error != tt.message || response.Details == nil {
+				t.Errorf("unexpected error response: %+v", response)
+			}
+		})
+	}
+}
+
+func TestCreateOutboxMessageRejectsOversizedAndUnknownFieldBodies(t *testing.T) {
+	h := NewAPIHandler(nil, nil, nil, nil, nil, nil, "test-secret", zap.NewNop())
+	handler := httpx.MaxBodySize(DefaultMaxBodyBytes)(http.HandlerFunc(h.CreateOutboxMessage))
+
+	tests := []struct {
+		name    string
+		body    string
+		want    int
+		message string
+	}{
+		{
+			name:    "oversized",
+			body:    `{"client_msg_uuid":"c1","account_id":"a1","convo_id":"x","message_type":"text","content":{"text":"` + strings.Repeat("x", DefaultMaxBodyBytes) + `"}}`,
+			want:    http.StatusRequestEntityTooLarge,
+			message: "Request body too large",
+		},
+		{
+			name:    "unknown field",
+			body:    `{"client_msg_uuid":"c1","account_id":"a1","convo_id":"x","message_type":"text","content":{"text":"hi"},"reply_too":"m1"}`,
+			want:    http.StatusBadRequest,
+			message: "Invalid request body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/outbox", strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.want)
+			}
+
+			var response api.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode error response: %v", err)
+			}
+			if response.Error != tt.message || response.Details == nil {
+				t.Errorf("unexpected error response: %+v", response)
+			}
+		})
+	}
+}
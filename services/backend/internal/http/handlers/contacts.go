@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"github.com/tennex/pkg/apierror"
+	dbgen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/httpx"
+)
+
+const (
+	// Contacts returned by default and at most
+	defaultContactsLimit = 500
+	maxContactsLimit     = 1000
+
+	// Longest accepted name prefix, in characters
+	maxContactPrefixLength = 128
+)
+
+// ListContacts lists an integration's synced contacts alphabetically by name,
+// optionally only those whose name starts with a prefix, for contact pickers
+func (h *APIHandler) ListContacts(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("integration_id") == "" {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Missing integration_id"))
+		return
+	}
+	integrationID, err := strconv.ParseInt(query.Get("integration_id"), 10, 32)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid integration_id", err))
+		return
+	}
+
+	prefix := strings.TrimSpace(query.Get("prefix"))
+	if len([]rune(prefix)) > maxContactPrefixLength {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Name prefix too long"))
+		return
+	}
+
+	limit := defaultContactsLimit
+	if v := query.Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid limit", err))
+			return
+		}
+		limit = min(parsed, maxContactsLimit)
+	}
+
+	offset := 0
+	if v := query.Get("offset"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid offset", err))
+			return
+		}
+		offset = parsed
+	}
+
+	integration, err := h.integrationService.GetIntegrationByID(r.Context(), int32(integrationID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		httpx.Error(w, apierror.New(http.StatusNotFound, "Integration not found"))
+		return
+	}
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get integration", err))
+		return
+	}
+	if integration.UserID != userID {
+		httpx.Error(w, apierror.New(http.StatusForbidden, "Integration belongs to another user"))
+		return
+	}
+
+	rows, err := h.queries.ListContactsByName(r.Context(), dbgen.ListContactsByNameParams{
+		UserIntegrationID: integration.ID,
+		NamePrefix:        prefix,
+		LimitCount:        int32(limit),
+		OffsetCount:       int32(offset),
+	})
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to list contacts", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"contacts":    convertContactsToAPI(rows),
+		"total_count": len(rows),
+		"has_more":    len(rows) == limit,
+	}
+
+	h.logger.Debug("Contacts listed",
+		zap.Int32("integration_id", integration.ID),
+		zap.String("prefix", prefix),
+		zap.Int("count", len(rows)))
+	httpx.JSON(w, http.StatusOK, response)
+}
+
+func convertContactsToAPI(rows []dbgen.ListContactsByNameRow) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		result[i] = map[string]interface{}{
+			"id":                  row.ID,
+			"external_contact_id": row.ExternalContactID,
+			"name":                row.Name,
+			"is_favorite":         row.IsFavorite,
+			"is_blocked":          row.IsBlocked,
+		}
+		optional := map[string]pgtype.Text{
+			"display_name": row.DisplayName,
+			"first_name":   row.FirstName,
+			"last_name":    row.LastName,
+			"phone_number": row.PhoneNumber,
+			"username":     row.Username,
+			"avatar_url":   row.AvatarUrl,
+		}
+		for key, value := range optional {
+			if value.Valid {
+				result[i][key] = value.String
+			}
+		}
+	}
+	return result
+}
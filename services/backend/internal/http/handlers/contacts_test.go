@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	dbgen "github.com/tennex/pkg/db/gen"
+)
+
+func TestConvertContactsToAPI(t *testing.T) {
+	rows := []dbgen.ListContactsByNameRow{
+		{
+			ID:          uuid.New(),
+			Name:        "Alice Cohen",
+			FirstName:   pgtype.Text{String: "Alice", Valid: true},
+			LastName:    pgtype.Text{String: "Cohen", Valid: true},
+			PhoneNumber: pgtype.Text{String: "+972500000000", Valid: true},
+			IsFavorite:  true,
+		},
+		{ID: uuid.New(), Name: "123@s.whatsapp.net", ExternalContactID: "123@s.whatsapp.net"},
+	}
+
+	contacts := convertContactsToAPI(rows)
+	if len(contacts) != 2 {
+		t.Fatalf("got %d contacts, want 2", len(contacts))
+	}
+	if contacts[0]["name"] != "Alice Cohen" || contacts[0]["first_name"] != "Alice" || contacts[0]["is_favorite"] != true {
+		t.Errorf("unexpected first contact %v", contacts[0])
+	}
+	if _, ok := contacts[0]["display_name"]; ok {
+		t.Errorf("expected no display_name for a null name, got %v", contacts[0])
+	}
+	for _, key := range []string{"first_name", "last_name", "phone_number", "username", "avatar_url"} {
+		if _, ok := contacts[1][key]; ok {
+			t.Errorf("expected no %s for a contact without one, got %v", key, contacts[1])
+		}
+	}
+}
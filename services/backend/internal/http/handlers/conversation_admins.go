@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/tennex/pkg/apierror"
+	dbgen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/httpx"
+)
+
+// conversationAdmin is an active participant who administers a group
+type conversationAdmin struct {
+	ExternalUserID string    `json:"external_user_id"`
+	DisplayName    string    `json:"display_name,omitempty"`
+	Role           string    `json:"role"`
+	JoinedAt       time.Time `json:"joined_at"`
+}
+
+// ListConversationAdmins returns the owner and admins of one of the user's
+// group conversations. Roles follow promotions and demotions as they happen.
+func (h *APIHandler) ListConversationAdmins(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
+		return
+	}
+
+	conversationID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid conversation ID", err))
+		return
+	}
+
+	_, err = h.queries.GetUserConversationState(r.Context(), dbgen.GetUserConversationStateParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.Error(w, apierror.New(http.StatusNotFound, "Conversation not found"))
+			return
+		}
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get conversation", err))
+		return
+	}
+
+	admins, err := h.queries.ListConversationAdmins(r.Context(), conversationID)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to list conversation admins", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"conversation_id": conversationID,
+		"admins":          convertAdminsToAPI(admins),
+		"total_count":     len(admins),
+	}
+	httpx.JSON(w, http.StatusOK, response)
+}
+
+func convertAdminsToAPI(participants []dbgen.ConversationParticipant) []conversationAdmin {
+	result := make([]conversationAdmin, len(participants))
+	for i, participant := range participants {
+		result[i] = conversationAdmin{
+			ExternalUserID: participant.ExternalUserID,
+			DisplayName:    participant.DisplayName.String,
+			Role:           participant.Role,
+			JoinedAt:       participant.JoinedAt,
+		}
+	}
+	return result
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	dbgen "github.com/tennex/pkg/db/gen"
+)
+
+func TestConvertAdminsToAPI(t *testing.T) {
+	joinedAt := time.Unix(1700000000, 0).UTC()
+	admins := convertAdminsToAPI([]dbgen.ConversationParticipant{
+		{ExternalUserID: "111@s.whatsapp.net", DisplayName: pgtype.Text{String: "Dana", Valid: true}, Role: "owner", JoinedAt: joinedAt},
+		{ExternalUserID: "222@s.whatsapp.net", Role: "admin", JoinedAt: joinedAt},
+	})
+
+	data, err := json.Marshal(admins)
+	if err != nil {
+		t.Fatalf("failed to encode admins: %v", err)
+	}
+	want := `[{"external_user_id":"111@s.whatsapp.net","display_name":"Dana","role":"owner","joined_at":"2023-11-14T22:13:20Z"},` +
+		`{"external_user_id":"222@s.whatsapp.net","role":"admin","joined_at":"2023-11-14T22:13:20Z"}]`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
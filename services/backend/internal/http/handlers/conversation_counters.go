@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/tennex/pkg/apierror"
+	dbgen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/httpx"
+)
+
+// RecountConversation recomputes one of the user's conversations' message
+// counters from its stored messages and returns them before and after. The
+// counters are kept as messages arrive, so a difference points at a bug.
+func (h *APIHandler) RecountConversation(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
+		return
+	}
+
+	conversationID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid conversation ID", err))
+		return
+	}
+
+	_, err = h.queries.GetUserConversationState(r.Context(), dbgen.GetUserConversationStateParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.Error(w, apierror.New(http.StatusNotFound, "Conversation not found"))
+			return
+		}
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get conversation", err))
+		return
+	}
+
+	counts, err := h.queries.RecountConversation(r.Context(), conversationID)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to recount conversation", err))
+		return
+	}
+
+	if counts.TotalMessageCount != counts.PreviousTotalMessageCount || counts.UnreadCount != counts.PreviousUnreadCount {
+		h.logger.Warn("Conversation counters had drifted",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Int32("total_message_count", counts.PreviousTotalMessageCount),
+			zap.Int32("recounted_total_message_count", counts.TotalMessageCount),
+			zap.Int32("unread_count", counts.PreviousUnreadCount),
+			zap.Int32("recounted_unread_count", counts.UnreadCount))
+	}
+
+	response := map[string]interface{}{
+		"conversation_id":     conversationID,
+		"total_message_count": counts.TotalMessageCount,
+		"unread_count":        counts.UnreadCount,
+		"previous": map[string]interface{}{
+			"total_message_count": counts.PreviousTotalMessageCount,
+			"unread_count":        counts.PreviousUnreadCount,
+		},
+	}
+	if counts.LastReadAt.Valid {
+		response["last_read_at"] = counts.LastReadAt.Time
+	}
+	httpx.JSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/pkg/apierror"
+	dbgen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/events"
+	"github.com/tennex/pkg/httpx"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+// conversationStateRequest is the body of PATCH /conversations/{id}/state.
+// Omitted fields are left unchanged.
+type conversationStateRequest struct {
+	IsPinned   *bool      `json:"is_pinned"`
+	IsArchived *bool      `json:"is_archived"`
+	IsMuted    *bool      `json:"is_muted"`
+	MuteUntil  *time.Time `json:"mute_until"` // With is_muted; omitted mutes with no expiry
+}
+
+// UpdateConversationState pins, archives or mutes one of the user's
+// conversations on WhatsApp, then stores the new state. WhatsApp echoes the
+// change back through the bridge; the echo carries this change's timestamp,
+// so once a newer change is stored the echo is ignored instead of undoing it.
+func (h *APIHandler) UpdateConversationState(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
+		return
+	}
+
+	conversationID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid conversation ID", err))
+		return
+	}
+
+	var req conversationStateRequest
+	if err := httpx.Decode(r, &req, 0); err != nil {
+		httpx.Error(w, err)
+		return
+	}
+	if req.IsPinned == nil && req.IsArchived == nil && req.IsMuted == nil {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "No state fields to change"))
+		return
+	}
+	if req.MuteUntil != nil && (req.IsMuted == nil || !*req.IsMuted) {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "mute_until requires is_muted to be true"))
+		return
+	}
+	if req.MuteUntil != nil && !req.MuteUntil.After(time.Now()) {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "mute_until must be in the future"))
+		return
+	}
+	if req.IsArchived != nil && *req.IsArchived {
+		if req.IsPinned != nil && *req.IsPinned {
+			httpx.Error(w, apierror.New(http.StatusBadRequest, "An archived conversation can't be pinned"))
+			return
+		}
+		// WhatsApp unpins a chat when it's archived
+		unpinned := false
+		req.IsPinned = &unpinned
+	}
+
+	conversation, err := h.queries.GetUserConversationState(r.Context(), dbgen.GetUserConversationStateParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.Error(w, apierror.New(http.StatusNotFound, "Conversation not found"))
+			return
+		}
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get conversation", err))
+		return
+	}
+	if conversation.IntegrationType != core.IntegrationTypeWhatsApp {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Conversation state can only be changed for WhatsApp conversations"))
+		return
+	}
+	if conversation.IntegrationStatus != "connected" {
+		httpx.Error(w, apierror.New(http.StatusConflict, "Integration is not connected"))
+		return
+	}
+
+	// WhatsApp keeps mutation timestamps to the millisecond, so the echo's
+	// timestamp only matches this one if it's truncated the same way
+	changedAt := time.Now().Truncate(time.Millisecond)
+	bridgeReq := &proto.SetConversationStateRequest{
+		AccountId: userID.String(),
+		ConvoId:   conversation.ExternalConversationID,
+		ChangedAt: timestamppb.New(changedAt),
+	}
+	params := dbgen.UpdateConversationStateParams{
+		UserIntegrationID:      conversation.UserIntegrationID,
+		ExternalConversationID: conversation.ExternalConversationID,
+		ChangedAt:              pgtype.Timestamptz{Time: changedAt, Valid: true},
+	}
+	if req.IsPinned != nil {
+		bridgeReq.IsPinned = *req.IsPinned
+		bridgeReq.Fields = append(bridgeReq.Fields, events.ConversationFieldPinned)
+		params.IsPinned = pgtype.Bool{Bool: *req.IsPinned, Valid: true}
+	}
+	if req.IsArchived != nil {
+		bridgeReq.IsArchived = *req.IsArchived
+		bridgeReq.Fields = append(bridgeReq.Fields, events.ConversationFieldArchived)
+		params.IsArchived = pgtype.Bool{Bool: *req.IsArchived, Valid: true}
+	}
+	if req.IsMuted != nil {
+		bridgeReq.IsMuted = *req.IsMuted
+		bridgeReq.Fields = append(bridgeReq.Fields, events.ConversationFieldMuted)
+		params.IsMuted = pgtype.Bool{Bool: *req.IsMuted, Valid: true}
+		if req.MuteUntil != nil {
+			muteUntil := req.MuteUntil.Truncate(time.Millisecond)
+			bridgeReq.MuteUntil = timestamppb.New(muteUntil)
+			params.MuteUntil = pgtype.Timestamptz{Time: muteUntil, Valid: true}
+		}
+	}
+
+	// WhatsApp first, so a change it rejects isn't stored
+	if err := h.bridgeClient.SetConversationState(r.Context(), bridgeReq); err != nil {
+		if status.Code(err) == codes.NotFound {
+			httpx.Error(w, apierror.Wrap(http.StatusConflict, "Integration is not connected", err))
+			return
+		}
+		httpx.Error(w, apierror.Wrap(http.StatusBadGateway, "Failed to update conversation on WhatsApp", err))
+		return
+	}
+
+	row, err := h.queries.UpdateConversationState(r.Context(), params)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// A newer change, e.g. from the phone, was stored in the meantime
+		current, err := h.queries.GetUserConversationState(r.Context(), dbgen.GetUserConversationStateParams{
+			ConversationID: conversationID,
+			UserID:         userID,
+		})
+		if err != nil {
+			httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get conversation", err))
+			return
+		}
+		httpx.JSON(w, http.StatusOK, conversationStateToAPI(conversationID, core.ConversationState{
+			IsPinned:   current.IsPinned,
+			IsArchived: current.IsArchived,
+			IsMuted:    current.IsMuted,
+			MuteUntil:  current.MuteUntil.Time,
+		}))
+		return
+	}
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to update conversation state", err))
+		return
+	}
+
+	previous := core.ConversationState{
+		IsPinned:   row.PreviousIsPinned,
+		IsArchived: row.PreviousIsArchived,
+		IsMuted:    row.PreviousIsMuted,
+		MuteUntil:  row.PreviousMuteUntil.Time,
+	}
+	next := core.ConversationState{
+		IsPinned:   row.IsPinned,
+		IsArchived: row.IsArchived,
+		IsMuted:    row.IsMuted,
+		MuteUntil:  row.MuteUntil.Time,
+	}
+	// Unchanged if the echo was stored first; it published the change then
+	if change, changed := core.ConversationStateChange(previous, next); changed && h.eventService != nil {
+		if _, err := h.eventService.PublishConversationState(r.Context(), userID.String(), conversation.UserIntegrationID, conversation.ExternalConversationID, change); err != nil {
+			h.logger.Warn("Failed to publish conversation state change",
+				zap.String("conversation_id", conversationID.String()),
+				zap.Error(err))
+		}
+	}
+
+	h.logger.Info("Conversation state updated",
+		zap.String("user_id", userID.String()),
+		zap.String("conversation_id", conversationID.String()),
+		zap.Strings("fields", bridgeReq.Fields))
+	httpx.JSON(w, http.StatusOK, conversationStateToAPI(conversationID, next))
+}
+
+func conversationStateToAPI(conversationID uuid.UUID, state core.ConversationState) map[string]interface{} {
+	result := map[string]interface{}{
+		"conversation_id": conversationID,
+		"is_pinned":       state.IsPinned,
+		"is_archived":     state.IsArchived,
+		"is_muted":        state.IsMuted,
+	}
+	if state.IsMuted && !state.MuteUntil.IsZero() {
+		result["mute_until"] = state.MuteUntil
+	}
+	return result
+}
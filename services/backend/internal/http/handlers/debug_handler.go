@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/pkg/httpx"
+)
+
+// PoolStatter reports connection pool statistics (implemented by *pgxpool.Pool)
+type PoolStatter interface {
+	Stat() *pgxpool.Stat
+}
+
+// RetentionStatter reports event retention progress (implemented by *core.RetentionWorker)
+type RetentionStatter interface {
+	Stats() core.RetentionStats
+}
+
+// OutboxStatter reports outbox queue statistics (implemented by *core.OutboxWorker)
+type OutboxStatter interface {
+	Stats() core.OutboxStats
+}
+
+// QueryStatter reports query durations (implemented by *core.QueryTracer)
+type QueryStatter interface {
+	Stats() core.QueryStats
+}
+
+// dbPoolStats is a serializable snapshot of pgxpool.Stat
+type dbPoolStats struct {
+	AcquiredConns        int32   `json:"acquired_conns"`
+	IdleConns            int32   `json:"idle_conns"`
+	ConstructingConns    int32   `json:"constructing_conns"`
+	TotalConns           int32   `json:"total_conns"`
+	MaxConns             int32   `json:"max_conns"`
+	AcquireCount         int64   `json:"acquire_count"`
+	EmptyAcquireCount    int64   `json:"empty_acquire_count"`
+	CanceledAcquireCount int64   `json:"canceled_acquire_count"`
+	AcquireWaitSeconds   float64 `json:"acquire_wait_seconds"`
+	NewConnsCount        int64   `json:"new_conns_count"`
+}
+
+// DebugHandler exposes operational diagnostics
+type DebugHandler struct {
+	pool      PoolStatter
+	retention RetentionStatter
+	outbox    OutboxStatter
+	queries   QueryStatter
+	logger    *zap.Logger
+}
+
+// NewDebugHandler creates a new debug handler. retention may be nil when the
+// retention worker is disabled, and queries when queries aren't traced.
+func NewDebugHandler(pool PoolStatter, retention RetentionStatter, outbox OutboxStatter, queries QueryStatter, logger *zap.Logger) *DebugHandler {
+	return &DebugHandler{
+		pool:      pool,
+		retention: retention,
+		outbox:    outbox,
+		queries:   queries,
+		logger:    logger.Named("debug_handler"),
+	}
+}
+
+// GetDBStats returns database connection pool statistics as JSON
+func (h *DebugHandler) GetDBStats(w http.ResponseWriter, r *http.Request) {
+	httpx.JSON(w, http.StatusOK, h.poolStats())
+}
+
+// GetMetrics returns database pool, query, event retention and outbox metrics
+// in the Prometheus text exposition format
+func (h *DebugHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := writePoolMetrics(w, h.poolStats()); err != nil {
+		h.logger.Error("Failed to write metrics", zap.Error(err))
+		return
+	}
+	if h.queries != nil {
+		if err := writeQueryMetrics(w, h.queries.Stats()); err != nil {
+			h.logger.Error("Failed to write query metrics", zap.Error(err))
+			return
+		}
+	}
+	if h.retention != nil {
+		if err := writeRetentionMetrics(w, h.retention.Stats()); err != nil {
+			h.logger.Error("Failed to write retention metrics", zap.Error(err))
+			return
+		}
+	}
+	if h.outbox != nil {
+		if err := writeOutboxMetrics(w, h.outbox.Stats()); err != nil {
+			h.logger.Error("Failed to write outbox metrics", zap.Error(err))
+		}
+	}
+}
+
+func (h *DebugHandler) poolStats() dbPoolStats {
+	stat := h.pool.Stat()
+	return dbPoolStats{
+		AcquiredConns:        stat.AcquiredConns(),
+		IdleConns:            stat.IdleConns(),
+		ConstructingConns:    stat.ConstructingConns(),
+		TotalConns:           stat.TotalConns(),
+		MaxConns:             stat.MaxConns(),
+		AcquireCount:         stat.AcquireCount(),
+		EmptyAcquireCount:    stat.EmptyAcquireCount(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		AcquireWaitSeconds:   stat.AcquireDuration().Seconds(),
+		NewConnsCount:        stat.NewConnsCount(),
+	}
+}
+
+// writePoolMetrics writes pool statistics as Prometheus metrics
+func writePoolMetrics(w io.Writer, stats dbPoolStats) error {
+	metrics := []struct {
+		name, kind, help string
+		value            float64
+	}{
+		{"tennex_db_pool_acquired_conns", "gauge", "Connections currently acquired from the pool.", float64(stats.AcquiredConns)},
+		{"tennex_db_pool_idle_conns", "gauge", "Idle connections in the pool.", float64(stats.IdleConns)},
+		{"tennex_db_pool_constructing_conns", "gauge", "Connections currently being established.", float64(stats.ConstructingConns)},
+		{"tennex_db_pool_total_conns", "gauge", "Total connections in the pool.", float64(stats.TotalConns)},
+		{"tennex_db_pool_max_conns", "gauge", "Maximum size of the pool.", float64(stats.MaxConns)},
+		{"tennex_db_pool_acquire_total", "counter", "Successful connection acquires.", float64(stats.AcquireCount)},
+		{"tennex_db_pool_empty_acquire_total", "counter", "Acquires that had to wait for a connection.", float64(stats.EmptyAcquireCount)},
+		{"tennex_db_pool_canceled_acquire_total", "counter", "Acquires canceled by their context.", float64(stats.CanceledAcquireCount)},
+		{"tennex_db_pool_acquire_wait_seconds_total", "counter", "Total time spent waiting to acquire connections.", stats.AcquireWaitSeconds},
+		{"tennex_db_pool_new_conns_total", "counter", "Connections opened by the pool.", float64(stats.NewConnsCount)},
+	}
+
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.name, m.help, m.name, m.kind, m.name, m.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeQueryMetrics writes query duration histograms, labeled by query name,
+// as Prometheus metrics
+func writeQueryMetrics(w io.Writer, stats core.QueryStats) error {
+	names := make([]string, 0, len(stats.Durations))
+	for name := range stats.Durations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const duration = "tennex_db_query_duration_seconds"
+	if _, err := fmt.Fprintf(w, "# HELP %s Query durations by query name.\n# TYPE %s histogram\n", duration, duration); err != nil {
+		return err
+	}
+	for _, name := range names {
+		histogram := stats.Durations[name]
+		for i, bound := range histogram.Bounds {
+			if _, err := fmt.Fprintf(w, "%s_bucket{query=%q,le=\"%g\"} %d\n", duration, name, bound, histogram.Counts[i]); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket{query=%q,le=\"+Inf\"} %d\n%s_sum{query=%q} %g\n%s_count{query=%q} %d\n",
+			duration, name, histogram.Count, duration, name, histogram.Sum, duration, name, histogram.Count); err != nil {
+			return err
+		}
+	}
+
+	const slow = "tennex_db_slow_queries_total"
+	if _, err := fmt.Fprintf(w, "# HELP %s Queries over the slow query threshold.\n# TYPE %s counter\n", slow, slow); err != nil {
+		return err
+	}
+	return writeLabeled(w, slow, "query", stats.Slow)
+}
+
+// writeRetentionMetrics writes event retention statistics as Prometheus metrics
+func writeRetentionMetrics(w io.Writer, stats core.RetentionStats) error {
+	var lastRun float64
+	if !stats.LastRunAt.IsZero() {
+		lastRun = float64(stats.LastRunAt.Unix())
+	}
+
+	metrics := []struct {
+		name, kind, help string
+		value            float64
+	}{
+		{"tennex_event_retention_runs_total", "counter", "Completed retention passes.", float64(stats.Runs)},
+		{"tennex_event_retention_failures_total", "counter", "Retention passes that failed.", float64(stats.Failures)},
+		{"tennex_event_retention_last_run_timestamp_seconds", "gauge", "Start time of the last retention pass.", lastRun},
+		{"tennex_event_retention_last_run_duration_seconds", "gauge", "Duration of the last retention pass.", stats.LastRunTime.Seconds()},
+		{"tennex_event_retention_age_deleted_total", "counter", "Events of any type deleted by the retention age windows.", float64(stats.DeletedByAge)},
+		{"tennex_event_retention_dry_run_eligible", "gauge", "Events the last retention dry run would have deleted.", float64(stats.DryRunEligible)},
+	}
+
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.name, m.help, m.name, m.kind, m.name, m.value); err != nil {
+			return err
+		}
+	}
+
+	// Deleted events are labeled by event type
+	const deleted = "tennex_event_retention_deleted_total"
+	if _, err := fmt.Fprintf(w, "# HELP %s Events deleted by retention.\n# TYPE %s counter\n", deleted, deleted); err != nil {
+		return err
+	}
+	return writeLabeled(w, deleted, "type", stats.DeletedByType)
+}
+
+// writeOutboxMetrics writes outbox queue statistics as Prometheus metrics
+func writeOutboxMetrics(w io.Writer, stats core.OutboxStats) error {
+	const entries = "tennex_outbox_entries"
+	if _, err := fmt.Fprintf(w, "# HELP %s Outbox entries by status.\n# TYPE %s gauge\n", entries, entries); err != nil {
+		return err
+	}
+	if err := writeLabeled(w, entries, "status", stats.ByStatus); err != nil {
+		return err
+	}
+
+	metrics := []struct {
+		name, kind, help string
+		value            float64
+	}{
+		{"tennex_outbox_stuck_entries", "gauge", "Unsent outbox entries past the stuck threshold.", float64(stats.Stuck)},
+		{"tennex_outbox_stuck_alerts_total", "counter", "Stuck outbox incidents alerted.", float64(stats.StuckAlerts)},
+	}
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.name, m.help, m.name, m.kind, m.name, m.value); err != nil {
+			return err
+		}
+	}
+
+	const latency = "tennex_outbox_send_latency_seconds"
+	if _, err := fmt.Fprintf(w, "# HELP %s Time from queueing an outbox entry to sending it.\n# TYPE %s histogram\n", latency, latency); err != nil {
+		return err
+	}
+	for i, bound := range stats.SendLatency.Bounds {
+		if _, err := fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", latency, bound, stats.SendLatency.Counts[i]); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %g\n%s_count %d\n",
+		latency, stats.SendLatency.Count, latency, stats.SendLatency.Sum, latency, stats.SendLatency.Count); err != nil {
+		return err
+	}
+
+	const failures = "tennex_outbox_failures_total"
+	if _, err := fmt.Fprintf(w, "# HELP %s Failed outbox sends by error class.\n# TYPE %s counter\n", failures, failures); err != nil {
+		return err
+	}
+	return writeLabeled(w, failures, "class", stats.FailuresByClass)
+}
+
+// writeLabeled writes one sample per map entry, sorted by label value
+func writeLabeled(w io.Writer, name, label string, values map[string]int64) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, err := fmt.Fprintf(w, "%s{%s=%q} %d\n", name, label, key, values[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tennex/backend/internal/core"
+)
+
+func TestWritePoolMetrics(t *testing.T) {
+	var b strings.Builder
+	err := writePoolMetrics(&b, dbPoolStats{
+		AcquiredConns:      3,
+		IdleConns:          2,
+		TotalConns:         5,
+		MaxConns:           10,
+		AcquireWaitSeconds: 1.5,
+	})
+	if err != nil {
+		t.Fatalf("writePoolMetrics: %v", err)
+	}
+
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE tennex_db_pool_acquired_conns gauge\ntennex_db_pool_acquired_conns 3\n",
+		"tennex_db_pool_idle_conns 2\n",
+		"tennex_db_pool_total_conns 5\n",
+		"tennex_db_pool_max_conns 10\n",
+		"# TYPE tennex_db_pool_acquire_wait_seconds_total counter\ntennex_db_pool_acquire_wait_seconds_total 1.5\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteQueryMetrics(t *testing.T) {
+	var b strings.Builder
+	err := writeQueryMetrics(&b, core.QueryStats{
+		Durations: map[string]core.LatencyHistogram{
+			"ListEvents": {Bounds: []float64{0.01, 0.1}, Counts: []uint64{5, 6}, Count: 7, Sum: 0.75},
+		},
+		Slow: map[string]int64{"ListEvents": 1},
+	})
+	if err != nil {
+		t.Fatalf("writeQueryMetrics: %v", err)
+	}
+
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE tennex_db_query_duration_seconds histogram\n" +
+			"tennex_db_query_duration_seconds_bucket{query=\"ListEvents\",le=\"0.01\"} 5\n" +
+			"tennex_db_query_duration_seconds_bucket{query=\"ListEvents\",le=\"0.1\"} 6\n" +
+			"tennex_db_query_duration_seconds_bucket{query=\"ListEvents\",le=\"+Inf\"} 7\n" +
+			"tennex_db_query_duration_seconds_sum{query=\"ListEvents\"} 0.75\n" +
+			"tennex_db_query_duration_seconds_count{query=\"ListEvents\"} 7\n",
+		"# TYPE tennex_db_slow_queries_total counter\ntennex_db_slow_queries_total{query=\"ListEvents\"} 1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteRetentionMetrics(t *testing.T) {
+	var b strings.Builder
+	err := writeRetentionMetrics(&b, core.RetentionStats{
+		Runs:           2,
+		DeletedByType:  map[string]int64{"presence": 1500, "msg_delivery": 0},
+		DeletedByAge:   40,
+		LastRunAt:      time.Unix(1700000000, 0),
+		LastRunTime:    250 * time.Millisecond,
+		DryRunEligible: 7,
+	})
+	if err != nil {
+		t.Fatalf("writeRetentionMetrics: %v", err)
+	}
+
+	out := b.String()
+	for _, want := range []string{
+		"tennex_event_retention_runs_total 2\n",
+		"tennex_event_retention_failures_total 0\n",
+		"tennex_event_retention_last_run_timestamp_seconds 1.7e+09\n",
+		"tennex_event_retention_last_run_duration_seconds 0.25\n",
+		"tennex_event_retention_age_deleted_total 40\n",
+		"tennex_event_retention_dry_run_eligible 7\n",
+		"# TYPE tennex_event_retention_deleted_total counter\n" +
+			"tennex_event_retention_deleted_total{type=\"msg_delivery\"} 0\n" +
+			"tennex_event_retention_deleted_total{type=\"presence\"} 1500\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteOutboxMetrics(t *testing.T) {
+	var b strings.Builder
+	err := writeOutboxMetrics(&b, core.OutboxStats{
+		ByStatus:    map[string]int64{"queued": 4, "sent": 120},
+		Stuck:       1,
+		StuckAlerts: 1,
+		SendLatency: core.LatencyHistogram{
+			Bounds: []float64{1, 5},
+			Counts: []uint64{2, 3},
+			Count:  4,
+			Sum:    42.5,
+		},
+		FailuresByClass: map[string]int64{"bridge_unavailable": 2},
+	})
+	if err != nil {
+		t.Fatalf("writeOutboxMetrics: %v", err)
+	}
+
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE tennex_outbox_entries gauge\n" +
+			"tennex_outbox_entries{status=\"queued\"} 4\n" +
+			"tennex_outbox_entries{status=\"sent\"} 120\n",
+		"tennex_outbox_stuck_entries 1\n",
+		"tennex_outbox_stuck_alerts_total 1\n",
+		"# TYPE tennex_outbox_send_latency_seconds histogram\n" +
+			"tennex_outbox_send_latency_seconds_bucket{le=\"1\"} 2\n" +
+			"tennex_outbox_send_latency_seconds_bucket{le=\"5\"} 3\n" +
+			"tennex_outbox_send_latency_seconds_bucket{le=\"+Inf\"} 4\n" +
+			"tennex_outbox_send_latency_seconds_sum 42.5\n" +
+			"tennex_outbox_send_latency_seconds_count 4\n",
+		"tennex_outbox_failures_total{class=\"bridge_unavailable\"} 2\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q:\n%s", want, out)
+		}
+	}
+}
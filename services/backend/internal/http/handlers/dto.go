@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	dbgen "github.com/tennex/pkg/db/gen"
+)
+
+// The types below are the API shapes of synced rows. Handlers convert sqlc
+// rows into them rather than encoding the rows, so a schema change doesn't
+// change the API unless the converters do. Nullable fields are pointers and
+// encode as null, so every field is always present.
+
+// conversationDTO is a conversation as the API returns it
+type conversationDTO struct {
+	Seq                    int64           `json:"seq"`
+	ID                     uuid.UUID       `json:"id"`
+	UserIntegrationID      int32           `json:"user_integration_id"`
+	ExternalConversationID string          `json:"external_conversation_id"`
+	IntegrationType        string          `json:"integration_type"`
+	ConversationType       string          `json:"conversation_type"`
+	Name                   *string         `json:"name"`
+	Description            *string         `json:"description"`
+	AvatarURL              *string         `json:"avatar_url"`
+	IsArchived             bool            `json:"is_archived"`
+	IsPinned               bool            `json:"is_pinned"`
+	IsMuted                bool            `json:"is_muted"`
+	MuteUntil              *time.Time      `json:"mute_until"`
+	IsReadOnly             bool            `json:"is_read_only"`
+	IsLocked               bool            `json:"is_locked"`
+	UnreadCount            int32           `json:"unread_count"`
+	UnreadMentionCount     int32           `json:"unread_mention_count"`
+	TotalMessageCount      int32           `json:"total_message_count"`
+	LastMessageAt          *time.Time      `json:"last_message_at"`
+	LastActivityAt         *time.Time      `json:"last_activity_at"`
+	PlatformMetadata       json.RawMessage `json:"platform_metadata"`
+	CreatedAt              time.Time       `json:"created_at"`
+	UpdatedAt              time.Time       `json:"updated_at"`
+}
+
+// messageDTO is a message as the API returns it
+type messageDTO struct {
+	Seq               int64           `json:"seq"`
+	ID                uuid.UUID       `json:"id"`
+	ConversationID    uuid.UUID       `json:"conversation_id"`
+	ExternalMessageID string          `json:"external_message_id"`
+	ExternalServerID  *string         `json:"external_server_id"`
+	IntegrationType   string          `json:"integration_type"`
+	SenderExternalID  string          `json:"sender_external_id"`
+	SenderDisplayName *string         `json:"sender_display_name"`
+	MessageType       string          `json:"message_type"`
+	Content           *string         `json:"content"`
+	Timestamp         time.Time       `json:"timestamp"`
+	EditTimestamp     *time.Time      `json:"edit_timestamp"`
+	IsFromMe          bool            `json:"is_from_me"`
+	IsForwarded       bool            `json:"is_forwarded"`
+	IsDeleted         bool            `json:"is_deleted"`
+	DeletedAt         *time.Time      `json:"deleted_at"`
+	ReplyToMessageID  *uuid.UUID      `json:"reply_to_message_id"`
+	ReplyToExternalID *string         `json:"reply_to_external_id"`
+	DeliveryStatus    string          `json:"delivery_status"`
+	PlatformMetadata  json.RawMessage `json:"platform_metadata"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+}
+
+// contactDTO is a contact as the API returns it
+type contactDTO struct {
+	Seq               int64           `json:"seq"`
+	ID                uuid.UUID       `json:"id"`
+	UserIntegrationID int32           `json:"user_integration_id"`
+	ExternalContactID string          `json:"external_contact_id"`
+	IntegrationType   string          `json:"integration_type"`
+	DisplayName       *string         `json:"display_name"`
+	FirstName         *string         `json:"first_name"`
+	LastName          *string         `json:"last_name"`
+	PhoneNumber       *string         `json:"phone_number"`
+	Username          *string         `json:"username"`
+	IsBlocked         bool            `json:"is_blocked"`
+	IsFavorite        bool            `json:"is_favorite"`
+	LastSeen          *time.Time      `json:"last_seen"`
+	AvatarURL         *string         `json:"avatar_url"`
+	PlatformMetadata  json.RawMessage `json:"platform_metadata"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+}
+
+// threadMessageDTO is a reply in a thread, with its depth below the root
+type threadMessageDTO struct {
+	messageDTO
+	Depth int32 `json:"depth"`
+}
+
+func convertConversationsToAPI(rows []dbgen.ListUserIntegrationConversationsSinceSeqRow) []conversationDTO {
+	result := make([]conversationDTO, len(rows))
+	for i, row := range rows {
+		result[i] = conversationDTO{
+			Seq:                    row.Seq.Int64,
+			ID:                     row.ID,
+			UserIntegrationID:      row.UserIntegrationID,
+			ExternalConversationID: row.ExternalConversationID,
+			IntegrationType:        row.IntegrationType,
+			ConversationType:       row.ConversationType,
+			Name:                   textOrNil(row.Name),
+			Description:            textOrNil(row.Description),
+			AvatarURL:              textOrNil(row.AvatarUrl),
+			IsArchived:             row.IsArchived,
+			IsPinned:               row.IsPinned,
+			IsMuted:                row.IsMuted,
+			MuteUntil:              timeOrNil(row.MuteUntil),
+			IsReadOnly:             row.IsReadOnly,
+			IsLocked:               row.IsLocked,
+			UnreadCount:            row.UnreadCount,
+			UnreadMentionCount:     row.UnreadMentionCount,
+			TotalMessageCount:      row.TotalMessageCount,
+			LastMessageAt:          timeOrNil(row.LastMessageAt),
+			LastActivityAt:         timeOrNil(row.LastActivityAt),
+			PlatformMetadata:       metadataOrEmpty(row.PlatformMetadata),
+			CreatedAt:              row.CreatedAt,
+			UpdatedAt:              row.UpdatedAt,
+		}
+	}
+	return result
+}
+
+func convertMessagesToAPI(rows []dbgen.ListUserIntegrationMessagesSinceSeqRow) []messageDTO {
+	result := make([]messageDTO, len(rows))
+	for i, row := range rows {
+		result[i] = messageDTO{
+			Seq:               row.Seq.Int64,
+			ID:                row.ID,
+			ConversationID:    row.ConversationID,
+			ExternalMessageID: row.ExternalMessageID,
+			ExternalServerID:  textOrNil(row.ExternalServerID),
+			IntegrationType:   row.IntegrationType,
+			SenderExternalID:  row.SenderExternalID,
+			SenderDisplayName: textOrNil(row.SenderDisplayName),
+			MessageType:       row.MessageType,
+			Content:           textOrNil(row.Content),
+			Timestamp:         row.Timestamp,
+			EditTimestamp:     timeOrNil(row.EditTimestamp),
+			IsFromMe:          row.IsFromMe,
+			IsForwarded:       row.IsForwarded,
+			IsDeleted:         row.IsDeleted,
+			DeletedAt:         timeOrNil(row.DeletedAt),
+			ReplyToMessageID:  uuidOrNil(row.ReplyToMessageID),
+			ReplyToExternalID: textOrNil(row.ReplyToExternalID),
+			DeliveryStatus:    row.DeliveryStatus,
+			PlatformMetadata:  metadataOrEmpty(row.PlatformMetadata),
+			CreatedAt:         row.CreatedAt,
+			UpdatedAt:         row.UpdatedAt,
+		}
+	}
+	return result
+}
+
+// convertThreadToAPI converts a thread's replies. The thread query doesn't
+// select edit, deletion or server ID columns, so those are always null.
+func convertThreadToAPI(rows []dbgen.GetMessageThreadRow) []threadMessageDTO {
+	result := make([]threadMessageDTO, len(rows))
+	for i, row := range rows {
+		result[i] = threadMessageDTO{
+			messageDTO: messageDTO{
+				Seq:               row.Seq.Int64,
+				ID:                row.ID,
+				ConversationID:    row.ConversationID,
+				ExternalMessageID: row.ExternalMessageID,
+				IntegrationType:   row.IntegrationType,
+				SenderExternalID:  row.SenderExternalID,
+				SenderDisplayName: textOrNil(row.SenderDisplayName),
+				MessageType:       row.MessageType,
+				Content:           textOrNil(row.Content),
+				Timestamp:         row.Timestamp,
+				IsFromMe:          row.IsFromMe,
+				IsForwarded:       row.IsForwarded,
+				IsDeleted:         row.IsDeleted,
+				ReplyToMessageID:  uuidOrNil(row.ReplyToMessageID),
+				ReplyToExternalID: textOrNil(row.ReplyToExternalID),
+				DeliveryStatus:    row.DeliveryStatus,
+				PlatformMetadata:  metadataOrEmpty(row.PlatformMetadata),
+				CreatedAt:         row.CreatedAt,
+				UpdatedAt:         row.UpdatedAt,
+			},
+			Depth: row.Depth,
+		}
+	}
+	return result
+}
+
+func convertSyncContactsToAPI(rows []dbgen.ListUserIntegrationContactsSinceSeqRow) []contactDTO {
+	result := make([]contactDTO, len(rows))
+	for i, row := range rows {
+		result[i] = contactDTO{
+			Seq:               row.Seq.Int64,
+			ID:                row.ID,
+			UserIntegrationID: row.UserIntegrationID,
+			ExternalContactID: row.ExternalContactID,
+			IntegrationType:   row.IntegrationType,
+			DisplayName:       textOrNil(row.DisplayName),
+			FirstName:         textOrNil(row.FirstName),
+			LastName:          textOrNil(row.LastName),
+			PhoneNumber:       textOrNil(row.PhoneNumber),
+			Username:          textOrNil(row.Username),
+			IsBlocked:         row.IsBlocked,
+			IsFavorite:        row.IsFavorite,
+			LastSeen:          timeOrNil(row.LastSeen),
+			AvatarURL:         textOrNil(row.AvatarUrl),
+			PlatformMetadata:  metadataOrEmpty(row.PlatformMetadata),
+			CreatedAt:         row.CreatedAt,
+			UpdatedAt:         row.UpdatedAt,
+		}
+	}
+	return result
+}
+
+func textOrNil(text pgtype.Text) *string {
+	if !text.Valid {
+		return nil
+	}
+	return &text.String
+}
+
+func timeOrNil(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func uuidOrNil(id pgtype.UUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	value := uuid.UUID(id.Bytes)
+	return &value
+}
+
+// metadataOrEmpty returns metadata, or an empty object for rows without any
+func metadataOrEmpty(metadata json.RawMessage) json.RawMessage {
+	if len(metadata) == 0 {
+		return json.RawMessage("{}")
+	}
+	return metadata
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	dbgen "github.com/tennex/pkg/db/gen"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// assertGolden compares the JSON encoding of v with testdata/<name>.golden.json.
+// A mismatch means the API shape changed: if that's intended, rerun with
+// -update and review the diff.
+func assertGolden(t *testing.T, name string, v interface{}) {
+	t.Helper()
+
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to encode %s: %v", name, err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".golden.json")
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s doesn't match %s\ngot:\n%s\nwant:\n%s", name, path, got, want)
+	}
+}
+
+var (
+	goldenTime           = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	goldenConversationID = uuid.MustParse("7d3c1a52-8f0e-4b8a-9a44-0c6a1d2e3f41")
+	goldenMessageID      = uuid.MustParse("0b9e6c3e-2d54-4f7b-8c1d-5e6f7a8b9c0d")
+	goldenReplyID        = uuid.MustParse("c4a1f2e3-6b7d-4e8f-9a0b-1c2d3e4f5a6b")
+	goldenContactID      = uuid.MustParse("e8f7d6c5-b4a3-4210-8fed-cba987654321")
+)
+
+func TestConversationDTOGolden(t *testing.T) {
+	assertGolden(t, "sync_conversations", convertConversationsToAPI([]dbgen.ListUserIntegrationConversationsSinceSeqRow{
+		{
+			Seq:                    pgtype.Int8{Int64: 41, Valid: true},
+			ID:                     goldenConversationID,
+			UserIntegrationID:      3,
+			ExternalConversationID: "120363025246125486@g.us",
+			IntegrationType:        "whatsapp",
+			ConversationType:       "group",
+			Name:                   pgtype.Text{String: "Climbing", Valid: true},
+			AvatarUrl:              pgtype.Text{String: "/v1/media/avatars/120363025246125486", Valid: true},
+			IsMuted:                true,
+			MuteUntil:              pgtype.Timestamptz{Time: goldenTime.Add(8 * time.Hour), Valid: true},
+			UnreadCount:            2,
+			UnreadMentionCount:     1,
+			TotalMessageCount:      130,
+			LastMessageAt:          pgtype.Timestamptz{Time: goldenTime, Valid: true},
+			LastActivityAt:         pgtype.Timestamptz{Time: goldenTime, Valid: true},
+			PlatformMetadata:       json.RawMessage(`{"ephemeral_expiration":0}`),
+			CreatedAt:              goldenTime.Add(-24 * time.Hour),
+			UpdatedAt:              goldenTime,
+		},
+		{
+			// Every nullable column null
+			Seq:                    pgtype.Int8{Int64: 42, Valid: true},
+			ID:                     goldenReplyID,
+			UserIntegrationID:      3,
+			ExternalConversationID: "972501111111@s.whatsapp.net",
+			IntegrationType:        "whatsapp",
+			ConversationType:       "individual",
+			CreatedAt:              goldenTime,
+			UpdatedAt:              goldenTime,
+		},
+	}))
+}
+
+func TestMessageDTOGolden(t *testing.T) {
+	assertGolden(t, "sync_messages", convertMessagesToAPI([]dbgen.ListUserIntegrationMessagesSinceSeqRow{
+		{
+			Seq:               pgtype.Int8{Int64: 250, Valid: true},
+			ID:                goldenReplyID,
+			ConversationID:    goldenConversationID,
+			ExternalMessageID: "3EB0C431C26A1916E07A",
+			ExternalServerID:  pgtype.Text{String: "1024", Valid: true},
+			IntegrationType:   "whatsapp",
+			SenderExternalID:  "972501111111@s.whatsapp.net",
+			SenderDisplayName: pgtype.Text{String: "Dana", Valid: true},
+			MessageType:       "text",
+			Content:           pgtype.Text{String: "See you there", Valid: true},
+			Timestamp:         goldenTime,
+			EditTimestamp:     pgtype.Timestamptz{Time: goldenTime.Add(time.Minute), Valid: true},
+			IsForwarded:       true,
+			ReplyToMessageID:  pgtype.UUID{Bytes: goldenMessageID, Valid: true},
+			ReplyToExternalID: pgtype.Text{String: "3EB0C431C26A1916E079", Valid: true},
+			DeliveryStatus:    "read",
+			PlatformMetadata:  json.RawMessage(`{"push_name":"Dana"}`),
+			CreatedAt:         goldenTime,
+			UpdatedAt:         goldenTime.Add(time.Minute),
+		},
+		{
+			// Every nullable column null
+			Seq:               pgtype.Int8{Int64: 251, Valid: true},
+			ID:                goldenMessageID,
+			ConversationID:    goldenConversationID,
+			ExternalMessageID: "3EB0C431C26A1916E07B",
+			IntegrationType:   "whatsapp",
+			SenderExternalID:  "972502222222@s.whatsapp.net",
+			MessageType:       "image",
+			Timestamp:         goldenTime,
+			IsFromMe:          true,
+			DeliveryStatus:    "sent",
+			CreatedAt:         goldenTime,
+			UpdatedAt:         goldenTime,
+		},
+	}))
+}
+
+func TestThreadMessageDTOGolden(t *testing.T) {
+	assertGolden(t, "message_thread", convertThreadToAPI([]dbgen.GetMessageThreadRow{
+		{
+			Seq:               pgtype.Int8{Int64: 250, Valid: true},
+			ID:                goldenReplyID,
+			ConversationID:    goldenConversationID,
+			ExternalMessageID: "3EB0C431C26A1916E07A",
+			IntegrationType:   "whatsapp",
+			SenderExternalID:  "972501111111@s.whatsapp.net",
+			SenderDisplayName: pgtype.Text{String: "Dana", Valid: true},
+			MessageType:       "text",
+			Content:           pgtype.Text{String: "See you there", Valid: true},
+			Timestamp:         goldenTime,
+			ReplyToMessageID:  pgtype.UUID{Bytes: goldenMessageID, Valid: true},
+			ReplyToExternalID: pgtype.Text{String: "3EB0C431C26A1916E079", Valid: true},
+			DeliveryStatus:    "delivered",
+			PlatformMetadata:  json.RawMessage(`{}`),
+			CreatedAt:         goldenTime,
+			UpdatedAt:         goldenTime,
+			Depth:             1,
+		},
+	}))
+}
+
+func TestContactDTOGolden(t *testing.T) {
+	assertGolden(t, "sync_contacts", convertSyncContactsToAPI([]dbgen.ListUserIntegrationContactsSinceSeqRow{
+		{
+			Seq:               pgtype.Int8{Int64: 30, Valid: true},
+			ID:                goldenContactID,
+			UserIntegrationID: 3,
+			ExternalContactID: "972501111111@s.whatsapp.net",
+			IntegrationType:   "whatsapp",
+			DisplayName:       pgtype.Text{String: "Dana Levi", Valid: true},
+			FirstName:         pgtype.Text{String: "Dana", Valid: true},
+			LastName:          pgtype.Text{String: "Levi", Valid: true},
+			PhoneNumber:       pgtype.Text{String: "+972501111111", Valid: true},
+			IsFavorite:        true,
+			LastSeen:          pgtype.Timestamptz{Time: goldenTime, Valid: true},
+			AvatarUrl:         pgtype.Text{String: "/v1/media/avatars/972501111111", Valid: true},
+			PlatformMetadata:  json.RawMessage(`{"business":false}`),
+			CreatedAt:         goldenTime,
+			UpdatedAt:         goldenTime,
+		},
+		{
+			// Every nullable column null
+			Seq:               pgtype.Int8{Int64: 31, Valid: true},
+			ID:                goldenReplyID,
+			UserIntegrationID: 3,
+			ExternalContactID: "972502222222@s.whatsapp.net",
+			IntegrationType:   "whatsapp",
+			IsBlocked:         true,
+			CreatedAt:         goldenTime,
+			UpdatedAt:         goldenTime,
+		},
+	}))
+}
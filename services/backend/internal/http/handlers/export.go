@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/pkg/apierror"
+	"github.com/tennex/pkg/httpx"
+)
+
+const (
+	// Events read from the database per page of an export
+	exportPageSize = 500
+
+	// How long the client gets to take each page before the export is
+	// abandoned
+	exportPageWriteTimeout = 30 * time.Second
+)
+
+// eventPager returns up to a page of an account's events after since, in
+// order
+type eventPager func(ctx context.Context, since int64) ([]repo.Event, error)
+
+// ExportEvents streams all of an account's events as newline-delimited JSON.
+// Events are read a page at a time and each page is flushed before the next
+// one is read, so a slow client holds back the export instead of it being
+// buffered in memory.
+func (h *APIHandler) ExportEvents(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := h.authorizeAccount(w, r, chi.URLParam(r, "account_id"))
+	if !ok {
+		return
+	}
+
+	// An interrupted export can be resumed after the last seq received
+	since := int64(0)
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		since, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || since < 0 {
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid since parameter", err))
+			return
+		}
+	}
+
+	// The export outlives the handler timeout. It still stops when the
+	// client goes away, which cancels the request context or fails a write.
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	defer cancel()
+	stop := context.AfterFunc(r.Context(), func() {
+		if !errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+			cancel()
+		}
+	})
+	defer stop()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "events-"+accountID+".ndjson"))
+	w.WriteHeader(http.StatusOK)
+
+	count, err := writeEventsNDJSON(ctx, w, since, func(ctx context.Context, since int64) ([]repo.Event, error) {
+		return h.eventService.GetEventsSince(ctx, accountID, since, exportPageSize, nil)
+	})
+	if err != nil {
+		// The status is already sent, so the client only sees the stream end early
+		h.logger.Warn("Event export interrupted",
+			zap.String("account_id", accountID),
+			zap.Int("count", count),
+			zap.Error(err))
+		return
+	}
+
+	h.logger.Info("Events exported",
+		zap.String("account_id", accountID),
+		zap.Int64("since", since),
+		zap.Int("count", count))
+}
+
+// writeEventsNDJSON writes the events after since to w, one JSON object per
+// line, flushing after each page. It returns the number of events written.
+func writeEventsNDJSON(ctx context.Context, w http.ResponseWriter, since int64, nextPage eventPager) (int, error) {
+	rc := http.NewResponseController(w)
+	encoder := json.NewEncoder(w)
+	count := 0
+	for {
+		page, err := nextPage(ctx, since)
+		if err != nil {
+			return count, err
+		}
+
+		// Not every writer supports deadlines, e.g. in tests
+		if err := rc.SetWriteDeadline(time.Now().Add(exportPageWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return count, err
+		}
+		for _, event := range page {
+			if err := encoder.Encode(convertEventToAPI(event)); err != nil {
+				return count, err
+			}
+			count++
+			since = event.Seq
+		}
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return count, err
+		}
+
+		if len(page) < exportPageSize {
+			return count, nil
+		}
+	}
+}
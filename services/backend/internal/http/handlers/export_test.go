@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tennex/backend/internal/repo"
+)
+
+// pagedEvents serves seqs 1 to total a page at a time, like GetEventsSince
+func pagedEvents(total int64, calls *int) eventPager {
+	return func(ctx context.Context, since int64) ([]repo.Event, error) {
+		*calls++
+		var page []repo.Event
+		for seq := since + 1; seq <= total && len(page) < exportPageSize; seq++ {
+			page = append(page, repo.Event{Seq: seq, Type: "msg_in", Payload: []byte(`{}`)})
+		}
+		return page, nil
+	}
+}
+
+func TestWriteEventsNDJSON(t *testing.T) {
+	total := int64(exportPageSize + exportPageSize/2)
+	calls := 0
+	rec := httptest.NewRecorder()
+
+	count, err := writeEventsNDJSON(context.Background(), rec, 0, pagedEvents(total, &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(count) != total || calls != 2 {
+		t.Errorf("got %d events in %d pages, want %d in 2", count, calls, total)
+	}
+	if !rec.Flushed {
+		t.Error("expected pages to be flushed")
+	}
+
+	// One event per line, in seq order
+	scanner := bufio.NewScanner(rec.Body)
+	want := int64(1)
+	for scanner.Scan() {
+		var event struct {
+			Seq int64 `json:"seq"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("line %d: %v", want, err)
+		}
+		if event.Seq != want {
+			t.Fatalf("got seq %d, want %d", event.Seq, want)
+		}
+		want++
+	}
+	if want-1 != total {
+		t.Errorf("got %d lines, want %d", want-1, total)
+	}
+}
+
+func TestWriteEventsNDJSONFullLastPage(t *testing.T) {
+	// A full page may be the last one, which takes an empty page to tell
+	calls := 0
+	count, err := writeEventsNDJSON(context.Background(), httptest.NewRecorder(), 0, pagedEvents(exportPageSize, &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != exportPageSize || calls != 2 {
+		t.Errorf("got %d events in %d pages, want %d in 2", count, calls, exportPageSize)
+	}
+}
+
+func TestWriteEventsNDJSONStopsOnError(t *testing.T) {
+	calls := 0
+	pages := pagedEvents(3*exportPageSize, &calls)
+	failing := func(ctx context.Context, since int64) ([]repo.Event, error) {
+		if since >= exportPageSize {
+			return nil, errors.New("database down")
+		}
+		return pages(ctx, since)
+	}
+
+	count, err := writeEventsNDJSON(context.Background(), httptest.NewRecorder(), 0, failing)
+	if err == nil {
+		t.Fatal("expected the error to be returned")
+	}
+	if count != exportPageSize {
+		t.Errorf("got %d events before the error, want %d", count, exportPageSize)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/tennex/shared/auth"
+)
+
+// testJWTSecret signs the access tokens of handler tests
+const testJWTSecret = "test-secret"
+
+// createTestUser inserts a user
+func createTestUser(t *testing.T, pool *pgxpool.Pool, username string) uuid.UUID {
+	t.Helper()
+	var userID uuid.UUID
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO users (username, email, password_hash)
+		VALUES ($1, $1 || '@example.com', 'x')
+		RETURNING id`, username).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user %s: %v", username, err)
+	}
+	return userID
+}
+
+// createTestIntegration inserts a connected WhatsApp integration for a user
+func createTestIntegration(t *testing.T, pool *pgxpool.Pool, userID uuid.UUID, jid string) int32 {
+	t.Helper()
+	var integrationID int32
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO user_integrations (user_id, integration_type, external_id, status)
+		VALUES ($1, 'whatsapp', $2, 'connected')
+		RETURNING id`, userID, jid).Scan(&integrationID)
+	if err != nil {
+		t.Fatalf("failed to create integration %s: %v", jid, err)
+	}
+	return integrationID
+}
+
+// createTestConversation inserts a WhatsApp conversation
+func createTestConversation(t *testing.T, pool *pgxpool.Pool, integrationID int32, jid, conversationType string) uuid.UUID {
+	t.Helper()
+	var conversationID uuid.UUID
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO conversations (user_integration_id, external_conversation_id, integration_type, conversation_type)
+		VALUES ($1, $2, 'whatsapp', $3)
+		RETURNING id`, integrationID, jid, conversationType).Scan(&conversationID)
+	if err != nil {
+		t.Fatalf("failed to create conversation %s: %v", jid, err)
+	}
+	return conversationID
+}
+
+// createTestMessage inserts a message into a conversation, sent by the
+// conversation's contact
+func createTestMessage(t *testing.T, pool *pgxpool.Pool, conversationID uuid.UUID, externalID, messageType, content string, timestamp time.Time) uuid.UUID {
+	t.Helper()
+	var messageID uuid.UUID
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO messages (conversation_id, external_message_id, integration_type, sender_external_id, message_type, content, timestamp)
+		SELECT $1, $2, 'whatsapp', c.external_conversation_id, $3, NULLIF($4, ''), $5
+		FROM conversations c WHERE c.id = $1
+		RETURNING id`, conversationID, externalID, messageType, content, timestamp).Scan(&messageID)
+	if err != nil {
+		t.Fatalf("failed to create message %s: %v", externalID, err)
+	}
+	return messageID
+}
+
+// testToken returns an access token for a user, signed with testJWTSecret
+func testToken(t *testing.T, userID uuid.UUID) string {
+	t.Helper()
+	token, _, err := auth.DefaultJWTConfig(testJWTSecret).GenerateToken(userID, auth.RoleUser)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	return token
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/oapi-codegen/runtime/types"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/repo"
+	api "github.com/tennex/pkg/api/gen"
+	"github.com/tennex/pkg/apierror"
+	"github.com/tennex/pkg/events"
+	"github.com/tennex/pkg/httpx"
+)
+
+// GetMe returns the current user together with each of their integrations,
+// its connection status and its latest sync seqs, so a client can bootstrap
+// in one call instead of hitting /auth/me, /settings and /sync/status
+func (h *APIHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
+		return
+	}
+
+	user, err := h.queries.GetUserByID(r.Context(), userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		httpx.Error(w, apierror.New(http.StatusNotFound, "User not found"))
+		return
+	}
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get user", err))
+		return
+	}
+
+	integrations, err := h.integrationService.ListUserIntegrations(r.Context(), userID)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to list integrations", err))
+		return
+	}
+
+	ids := make([]int32, len(integrations))
+	for i, integration := range integrations {
+		ids[i] = integration.ID
+	}
+	stats, err := h.integrationService.ListIntegrationSyncStats(r.Context(), ids)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get sync status", err))
+		return
+	}
+
+	var fullName *string
+	if user.FullName.Valid {
+		fullName = &user.FullName.String
+	}
+
+	response := map[string]interface{}{
+		"user": api.User{
+			Id:        user.ID,
+			Username:  user.Username,
+			Email:     types.Email(user.Email),
+			FullName:  fullName,
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+		},
+		"integrations": convertMeIntegrationsToAPI(integrations, stats),
+	}
+
+	h.logger.Debug("Me retrieved",
+		zap.String("user_id", userID.String()),
+		zap.Int("integrations", len(integrations)))
+	httpx.JSON(w, http.StatusOK, response)
+}
+
+// convertMeIntegrationsToAPI lists each integration with its sync status.
+// Integrations missing from stats haven't synced anything yet and report 0.
+func convertMeIntegrationsToAPI(integrations []repo.UserIntegration, stats map[int32]repo.IntegrationSyncStats) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(integrations))
+	for i, integration := range integrations {
+		result[i] = map[string]interface{}{
+			"integration_id":   integration.ID,
+			"integration_type": integration.IntegrationType,
+			"external_id":      integration.ExternalID,
+			"status":           integration.Status,
+			"connected":        integration.Status == events.AccountStatusConnected,
+		}
+		if integration.DisplayName.Valid {
+			result[i]["display_name"] = integration.DisplayName.String
+		}
+		if integration.AvatarUrl.Valid {
+			result[i]["avatar_url"] = integration.AvatarUrl.String
+		}
+		if integration.LastSeen.Valid {
+			result[i]["last_seen"] = integration.LastSeen.Time
+		}
+
+		sync := stats[integration.ID]
+		result[i]["sync"] = map[string]interface{}{
+			"latest_conversation_seq": sync.LatestConversationSeq,
+			"latest_message_seq":      sync.LatestMessageSeq,
+			"latest_contact_seq":      sync.LatestContactSeq,
+		}
+	}
+	return result
+}
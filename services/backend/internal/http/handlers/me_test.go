@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/tennex/backend/internal/repo"
+)
+
+func TestConvertMeIntegrationsToAPI(t *testing.T) {
+	integrations := convertMeIntegrationsToAPI([]repo.UserIntegration{
+		{ID: 1, IntegrationType: "whatsapp", ExternalID: "972501111111@s.whatsapp.net", Status: "connected", DisplayName: sql.NullString{String: "Dana", Valid: true}},
+		{ID: 2, IntegrationType: "whatsapp", ExternalID: "972502222222@s.whatsapp.net", Status: "disconnected"},
+	}, map[int32]repo.IntegrationSyncStats{
+		1: {LatestConversationSeq: 10, LatestMessageSeq: 250, LatestContactSeq: 30},
+	})
+
+	if len(integrations) != 2 {
+		t.Fatalf("expected 2 integrations, got %v", integrations)
+	}
+	if integrations[0]["connected"] != true || integrations[0]["display_name"] != "Dana" {
+		t.Errorf("unexpected first integration %v", integrations[0])
+	}
+	sync := integrations[0]["sync"].(map[string]interface{})
+	if sync["latest_conversation_seq"] != int64(10) || sync["latest_message_seq"] != int64(250) || sync["latest_contact_seq"] != int64(30) {
+		t.Errorf("unexpected sync seqs %v", sync)
+	}
+
+	// An integration that hasn't synced yet reports 0 rather than leaving
+	// the seqs out
+	if integrations[1]["connected"] != false {
+		t.Errorf("expected a disconnected integration, got %v", integrations[1])
+	}
+	if _, ok := integrations[1]["display_name"]; ok {
+		t.Errorf("expected no display_name, got %v", integrations[1])
+	}
+	sync = integrations[1]["sync"].(map[string]interface{})
+	if sync["latest_message_seq"] != int64(0) {
+		t.Errorf("expected seqs of 0 before the first sync, got %v", sync)
+	}
+}
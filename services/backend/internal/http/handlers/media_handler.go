@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/pkg/apierror"
+	dbgen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/events"
+	"github.com/tennex/pkg/httpx"
+	"github.com/tennex/shared/auth"
+)
+
+// MediaHandler serves downloaded message media, and the avatars of contacts
+// and conversations, to the user who owns them
+type MediaHandler struct {
+	queries   *dbgen.Queries
+	mediaDir  string // Root that stored media paths are relative to
+	jwtConfig *auth.JWTConfig
+	logger    *zap.Logger
+}
+
+// NewMediaHandler creates a new media handler serving files from mediaDir
+func NewMediaHandler(queries *dbgen.Queries, mediaDir string, jwtConfig *auth.JWTConfig, logger *zap.Logger) *MediaHandler {
+	return &MediaHandler{
+		queries:   queries,
+		mediaDir:  mediaDir,
+		jwtConfig: jwtConfig,
+		logger:    logger.Named("media_handler"),
+	}
+}
+
+// Routes returns the media routes
+func (h *MediaHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Use(h.jwtConfig.ChiMiddleware())
+
+	r.Get("/avatars/{content_hash}", h.GetAvatar)
+	r.Head("/avatars/{content_hash}", h.GetAvatar)
+	r.Get("/{message_id}", h.GetMessageMedia)
+	r.Head("/{message_id}", h.GetMessageMedia)
+
+	return r
+}
+
+// GetMessageMedia streams a message's downloaded media. Range requests are
+// supported so players can seek without fetching the whole file.
+func (h *MediaHandler) GetMessageMedia(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	messageID, err := uuid.Parse(chi.URLParam(r, "message_id"))
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid message ID", err))
+		return
+	}
+
+	// Other users' media is reported as missing rather than forbidden, so
+	// message IDs can't be probed
+	media, err := h.queries.GetUserMessageMedia(r.Context(), dbgen.GetUserMessageMediaParams{
+		MessageID: messageID,
+		UserID:    userID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		httpx.Error(w, apierror.New(http.StatusNotFound, "Media not found"))
+		return
+	}
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get media", err))
+		return
+	}
+	if media.DownloadStatus != "completed" || media.LocalFilePath.String == "" {
+		httpx.Error(w, apierror.New(http.StatusConflict, "Media has not been downloaded yet"))
+		return
+	}
+
+	name := media.FileName.String
+	if name == "" {
+		name = media.ID.String()
+	}
+	h.serveFile(w, r, media.LocalFilePath.String, media.MimeType.String, name)
+}
+
+// GetAvatar streams the avatar of one of the user's contacts or
+// conversations, stored by the hash of its content
+func (h *MediaHandler) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	contentHash := chi.URLParam(r, "content_hash")
+	if !core.ValidContentHash(contentHash) {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Invalid avatar hash"))
+		return
+	}
+
+	// Avatars none of the user's contacts or conversations have are reported
+	// as missing, like other users' message media
+	owned, err := h.queries.UserHasAvatar(r.Context(), dbgen.UserHasAvatarParams{
+		UserID:    userID,
+		AvatarUrl: events.AvatarURLPrefix + contentHash,
+	})
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get avatar", err))
+		return
+	}
+	if !owned {
+		httpx.Error(w, apierror.New(http.StatusNotFound, "Avatar not found"))
+		return
+	}
+
+	blob, err := h.queries.GetMediaBlob(r.Context(), contentHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		httpx.Error(w, apierror.New(http.StatusNotFound, "Avatar not found"))
+		return
+	}
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get avatar", err))
+		return
+	}
+
+	h.serveFile(w, r, blob.StorageUrl, blob.MimeType, contentHash)
+}
+
+// inlineMediaTypes are the media types shown in the browser. Anything else,
+// such as HTML or SVG a contact sent as a document, is downloaded instead so
+// it can't run in the API's origin.
+var inlineMediaTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+	"audio/ogg":  true,
+	"audio/mpeg": true,
+	"audio/mp4":  true,
+	"audio/aac":  true,
+	"video/mp4":  true,
+	"video/3gpp": true,
+}
+
+// contentDisposition returns how a file of a media type is served
+func contentDisposition(mimeType string) string {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err == nil && inlineMediaTypes[mediaType] {
+		return "inline"
+	}
+	return "attachment"
+}
+
+// serveFile streams a stored file, relative to the media directory, as name.
+// Range requests are supported so players can seek without fetching the whole
+// file.
+func (h *MediaHandler) serveFile(w http.ResponseWriter, r *http.Request, path, mimeType, name string) {
+	// Opening through the root keeps a stored path from escaping the media directory
+	root, err := os.OpenRoot(h.mediaDir)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Media storage is unavailable", err))
+		return
+	}
+	defer root.Close()
+
+	file, err := root.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		h.logger.Warn("Stored media file is missing", zap.String("path", path))
+		httpx.Error(w, apierror.New(http.StatusNotFound, "Media file is missing"))
+		return
+	}
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to open media", err))
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to read media", err))
+		return
+	}
+
+	// Without a stored type, ServeContent guesses one from the name or content
+	if mimeType != "" {
+		w.Header().Set("Content-Type", mimeType)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(contentDisposition(mimeType), map[string]string{"filename": name}))
+
+	// Large videos take longer to stream than the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		h.logger.Debug("Failed to clear write deadline", zap.Error(err))
+	}
+
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
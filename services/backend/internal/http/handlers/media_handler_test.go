@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/testutil"
+	dbgen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/shared/auth"
+)
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		want     string
+	}{
+		{"image/jpeg", "inline"},
+		{"audio/ogg; codecs=opus", "inline"},
+		{"VIDEO/MP4", "inline"},
+		{"text/html", "attachment"},
+		{"image/svg+xml", "attachment"},
+		{"application/pdf", "attachment"},
+		{"", "attachment"},
+		{"not a type", "attachment"},
+	}
+	for _, tt := range tests {
+		if got := contentDisposition(tt.mimeType); got != tt.want {
+			t.Errorf("contentDisposition(%q) = %q, want %q", tt.mimeType, got, tt.want)
+		}
+	}
+}
+
+func TestGetMessageMediaServesRanges(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	mediaDir := t.TempDir()
+	mediaHandler := NewMediaHandler(dbgen.New(pool), mediaDir, auth.DefaultJWTConfig(testJWTSecret), zap.NewNop())
+	ctx := context.Background()
+
+	const chatID = "972501234567@s.whatsapp.net"
+	ownerID := createTestUser(t, pool, "media-owner")
+	conversationID := createTestConversation(t, pool, createTestIntegration(t, pool, ownerID, "972500000000@s.whatsapp.net"), chatID, "individual")
+	messageID := createTestMessage(t, pool, conversationID, "IMG1", "image", "", time.Unix(1700000000, 0))
+	var mediaID uuid.UUID
+	err := pool.QueryRow(ctx, `
+		INSERT INTO message_media (message_id, media_type, file_name, mime_type)
+		VALUES ($1, 'image', 'photo.jpg', 'image/jpeg')
+		RETURNING id`, messageID).Scan(&mediaID)
+	if err != nil {
+		t.Fatalf("failed to create media: %v", err)
+	}
+
+	ownerToken := testToken(t, ownerID)
+	get := func(token, rangeHeader string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/"+messageID.String(), nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		rec := httptest.NewRecorder()
+		mediaHandler.Routes().ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Not downloaded yet
+	if rec := get(ownerToken, ""); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 before download, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	const content = "0123456789"
+	if err := os.MkdirAll(filepath.Join(mediaDir, "images"), 0o755); err != nil {
+		t.Fatalf("failed to create media dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(mediaDir, "images", "photo.jpg"), []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write media: %v", err)
+	}
+	setPath := func(path string) {
+		t.Helper()
+		_, err := pool.Exec(ctx, `
+			UPDATE message_media SET download_status = 'completed', local_file_path = $2
+			WHERE id = $1`, mediaID, path)
+		if err != nil {
+			t.Fatalf("failed to mark media downloaded: %v", err)
+		}
+	}
+	setPath("images/photo.jpg")
+
+	rec := get(ownerToken, "")
+	if rec.Code != http.StatusOK || rec.Body.String() != content {
+		t.Fatalf("expected the whole file, got %d: %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "image/jpeg" {
+		t.Errorf("expected image/jpeg, got %q", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "inline;") {
+		t.Errorf("expected an image to be shown inline, got %q", got)
+	}
+	if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("expected nosniff, got %q", got)
+	}
+
+	rec = get(ownerToken, "bytes=2-5")
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "2345" {
+		t.Errorf("expected bytes 2-5, got %d: %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes 2-5/10" {
+		t.Errorf("expected Content-Range bytes 2-5/10, got %q", got)
+	}
+
+	// A document that a browser would render is downloaded instead
+	if _, err := pool.Exec(ctx, `UPDATE message_media SET mime_type = 'text/html' WHERE id = $1`, mediaID); err != nil {
+		t.Fatalf("failed to change media type: %v", err)
+	}
+	rec = get(ownerToken, "")
+	if got := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment;") {
+		t.Errorf("expected HTML to be served as an attachment, got %q", got)
+	}
+	if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("expected nosniff, got %q", got)
+	}
+
+	// Another user's media looks the same as missing media
+	otherToken := testToken(t, createTestUser(t, pool, "media-other"))
+	if rec := get(otherToken, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for another user's media, got %d", rec.Code)
+	}
+	if rec := get("", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rec.Code)
+	}
+
+	// A stored path can't reach outside the media directory
+	setPath("../secret")
+	if rec := get(ownerToken, ""); rec.Code == http.StatusOK {
+		t.Errorf("expected an escaping path to be refused, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tennex/pkg/apierror"
+	"github.com/tennex/pkg/events"
+	"github.com/tennex/pkg/httpx"
+)
+
+// multipartMemory is how much of a multipart upload is held in memory; the
+// rest is spooled to a temporary file
+const multipartMemory = 1 << 20
+
+// CreateOutboxMediaMessage queues an image or document message uploaded as
+// multipart/form-data. The "file" part holds the attachment; the other fields
+// match CreateOutboxMessage, plus an optional caption. A file already in the
+// media store, e.g. one sent or received before, can be sent again by content
+// hash through CreateOutboxMessage instead.
+//
+// The route is registered outside Routes so it can have a larger body limit
+// than the rest of the API.
+func (h *APIHandler) CreateOutboxMediaMessage(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			httpx.Error(w, apierror.Wrap(http.StatusRequestEntityTooLarge, "Media file is too large", err))
+			return
+		}
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid multipart body", err))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	clientMsgUUID := r.FormValue("client_msg_uuid")
+	accountID := r.FormValue("account_id")
+	convoID := r.FormValue("convo_id")
+	if clientMsgUUID == "" || accountID == "" || convoID == "" {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Missing required fields"))
+		return
+	}
+
+	clientUUID, err := uuid.Parse(clientMsgUUID)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid client_msg_uuid", err))
+		return
+	}
+
+	accountID, ok := h.authorizeAccount(w, r, accountID)
+	if !ok {
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Missing file", err))
+		return
+	}
+	defer file.Close()
+
+	mimeType := r.FormValue("mime_type")
+	if mimeType == "" {
+		mimeType = header.Header.Get("Content-Type")
+	}
+	mimeType, _, err = mime.ParseMediaType(mimeType)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid mime_type", err))
+		return
+	}
+
+	// Images can be sent as documents to keep their original quality
+	messageType := r.FormValue("message_type")
+	if messageType == "" {
+		messageType = events.ContentTypeDocument
+		if strings.HasPrefix(mimeType, "image/") {
+			messageType = events.ContentTypeImage
+		}
+	}
+	if messageType != events.ContentTypeImage && messageType != events.ContentTypeDocument {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "message_type must be image or document"))
+		return
+	}
+
+	hash, size, err := h.outboxService.StoreMedia(file)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to store media", err))
+		return
+	}
+
+	h.logger.Debug("Stored outbound media",
+		zap.String("client_msg_uuid", clientMsgUUID),
+		zap.String("media_hash", hash),
+		zap.Int64("size", size))
+
+	content := map[string]interface{}{
+		"media_hash": hash,
+		"mime_type":  mimeType,
+		"size":       size,
+	}
+	if caption := r.FormValue("caption"); caption != "" {
+		content["caption"] = caption
+	}
+	if header.Filename != "" {
+		content["file_name"] = header.Filename
+	}
+
+	h.queueOutboxMessage(w, r, clientUUID, accountID, convoID, events.MessageOutPayload{
+		ContentType:      messageType,
+		Content:          content,
+		ClientMsgUUID:    clientMsgUUID,
+		ReplyToMessageID: r.FormValue("reply_to"),
+	})
+}
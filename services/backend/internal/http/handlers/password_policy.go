@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	_ "embed"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tennex/pkg/apierror"
+)
+
+const (
+	// Shortest accepted password, in characters
+	MinPasswordLength = 10
+
+	// Longest accepted password, in bytes. bcrypt ignores anything past it.
+	MaxPasswordBytes = 72
+)
+
+//go:embed breached_passwords.txt
+var breachedPasswordList string
+
+// breachedPasswords holds the embedded breached passwords, lowercased
+var breachedPasswords = parseBreachedPasswords(breachedPasswordList)
+
+func parseBreachedPasswords(list string) map[string]struct{} {
+	passwords := make(map[string]struct{})
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords[strings.ToLower(line)] = struct{}{}
+	}
+	return passwords
+}
+
+// validatePassword enforces the password policy for new passwords. The
+// username and email are those of the account the password is for.
+func validatePassword(password, username, email string) *apierror.E {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return apierror.New(http.StatusBadRequest, "Password must be at least 10 characters")
+	}
+	if len(password) > MaxPasswordBytes {
+		return apierror.New(http.StatusBadRequest, "Password must be at most 72 bytes")
+	}
+
+	lower := strings.ToLower(password)
+	if _, ok := breachedPasswords[lower]; ok {
+		return apierror.New(http.StatusBadRequest, "Password is too common")
+	}
+	if lower == strings.ToLower(username) || lower == strings.ToLower(email) {
+		return apierror.New(http.StatusBadRequest, "Password must not match the username or email")
+	}
+	return nil
+}
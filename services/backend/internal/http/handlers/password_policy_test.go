@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  string
+	}{
+		{"long enough", "correct horse battery", ""},
+		{"exactly the minimum", "z8#kq!w2vm", ""},
+		{"too short", "z8#kq!w2v", "Password must be at least 10 characters"},
+		{"multi-byte characters count once", "ĉĝĥĵŝŭĉĝĥ", "Password must be at least 10 characters"},
+		{"longer than bcrypt reads", strings.Repeat("x", MaxPasswordBytes+1), "Password must be at most 72 bytes"},
+		{"breached", "password123", "Password is too common"},
+		{"breached in another case", "QwertyUIOP", "Password is too common"},
+		{"same as the username", "alice-the-great", "Password must not match the username or email"},
+		{"same as the email", "Alice@Example.com", "Password must not match the username or email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePassword(tt.password, "alice-the-great", "alice@example.com")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected the password to be accepted, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("got %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBreachedPasswordsAreLoaded(t *testing.T) {
+	if len(breachedPasswords) == 0 {
+		t.Fatal("expected the embedded breached password list to be loaded")
+	}
+	if _, ok := breachedPasswords["# commonly breached passwords, one per line, compared case-insensitively."]; ok {
+		t.Error("expected comments to be skipped")
+	}
+}
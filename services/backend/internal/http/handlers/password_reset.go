@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/tennex/backend/internal/core"
+	api "github.com/tennex/pkg/api/gen"
+	"github.com/tennex/pkg/apierror"
+	db "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/httpx"
+)
+
+// passwordResetTTL is how long a reset token can be used after it's sent
+const passwordResetTTL = 30 * time.Minute
+
+// PasswordResetSender delivers password reset tokens to users
+type PasswordResetSender interface {
+	SendPasswordReset(ctx context.Context, email, token string, expiresAt time.Time) error
+}
+
+// logPasswordResetSender logs reset tokens instead of delivering them, until
+// there's a way to email users. Only a fingerprint of each token is logged,
+// unless revealTokens is set, as anyone who can read the logs could otherwise
+// take over the account.
+type logPasswordResetSender struct {
+	logger       *zap.Logger
+	revealTokens bool
+}
+
+// NewLogPasswordResetSender returns a sender that only logs reset tokens. The
+// tokens themselves are logged only if revealTokens is set, which is for
+// local development.
+func NewLogPasswordResetSender(logger *zap.Logger, revealTokens bool) PasswordResetSender {
+	return &logPasswordResetSender{logger: logger.Named("password_reset"), revealTokens: revealTokens}
+}
+
+func (s *logPasswordResetSender) SendPasswordReset(ctx context.Context, email, token string, expiresAt time.Time) error {
+	fields := []zap.Field{
+		zap.String("email", email),
+		zap.String("token_fingerprint", passwordResetTokenFingerprint(token)),
+		zap.Time("expires_at", expiresAt),
+	}
+	if s.revealTokens {
+		fields = append(fields, zap.String("token", token))
+	}
+	s.logger.Info("Password reset requested", fields...)
+	return nil
+}
+
+// RequestPasswordReset sends a reset token to the user with the email. It
+// accepts the request whether or not there is one, so it doesn't reveal who
+// is registered.
+func (h *AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req api.PasswordResetRequest
+	if err := httpx.DecodeStrict(r, &req, 0); err != nil {
+		httpx.Error(w, err)
+		return
+	}
+	email := string(req.Email)
+
+	// Requests are limited per email, whether or not it's registered, and per
+	// IP, so nobody can flood a user's inbox or churn through tokens
+	for _, limit := range []struct {
+		throttler *core.LoginThrottler
+		key       string
+	}{
+		{h.resetIPThrottler, core.LoginIPKey(clientIP(r))},
+		{h.resetThrottler, passwordResetEmailKey(email)},
+	} {
+		if remaining, refused := limit.throttler.Reserve(limit.key); refused {
+			w.Header().Set("Retry-After", strconv.Itoa(int(remaining.Round(time.Second).Seconds())))
+			httpx.Error(w, apierror.New(http.StatusTooManyRequests, "Too many password reset requests"))
+			return
+		}
+	}
+
+	user, err := h.queries.GetUserByEmail(r.Context(), email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Debug("Password reset for unknown email")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Database error", err))
+		return
+	}
+
+	token, tokenHash, err := newPasswordResetToken()
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to generate token", err))
+		return
+	}
+	expiresAt := time.Now().Add(passwordResetTTL)
+
+	// Only the latest token sent can be used
+	if err := h.queries.RevokePasswordResetTokens(r.Context(), user.ID); err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Database error", err))
+		return
+	}
+	err = h.queries.CreatePasswordResetToken(r.Context(), db.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Database error", err))
+		return
+	}
+
+	// A failed delivery looks the same to the caller; they can ask again
+	if err := h.resetSender.SendPasswordReset(r.Context(), user.Email, token, expiresAt); err != nil {
+		h.logger.Error("Failed to send password reset",
+			zap.String("user_id", user.ID.String()),
+			zap.Error(err))
+	}
+
+	h.logger.Info("Password reset token issued", zap.String("user_id", user.ID.String()))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ConfirmPasswordReset spends a reset token to set a new password
+func (h *AuthHandler) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req api.PasswordResetConfirmRequest
+	if err := httpx.DecodeStrict(r, &req, 0); err != nil {
+		httpx.Error(w, err)
+		return
+	}
+
+	// The policy needs the username; an unknown email fails like a bad token
+	user, err := h.queries.GetUserByEmail(r.Context(), string(req.Email))
+	if errors.Is(err, pgx.ErrNoRows) {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Invalid or expired reset token"))
+		return
+	}
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Database error", err))
+		return
+	}
+	if err := validatePassword(req.Password, user.Username, user.Email); err != nil {
+		httpx.Error(w, err)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), h.bcryptCost)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to process password", err))
+		return
+	}
+
+	userID, err := h.queries.ResetPasswordWithToken(r.Context(), db.ResetPasswordWithTokenParams{
+		TokenHash:    hashPasswordResetToken(req.Token),
+		Email:        string(req.Email),
+		PasswordHash: string(hashedPassword),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Invalid or expired reset token"))
+		return
+	}
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to reset password", err))
+		return
+	}
+
+	h.logger.Info("Password reset", zap.String("user_id", userID.String()))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newPasswordResetToken returns a random token to send and the hash to store
+func newPasswordResetToken() (token, tokenHash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashPasswordResetToken(token), nil
+}
+
+// hashPasswordResetToken returns the hash a token is stored and looked up by
+func hashPasswordResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// passwordResetTokenFingerprint returns enough of a token's hash to match a
+// log line to a stored token, without revealing the token
+func passwordResetTokenFingerprint(token string) string {
+	return hashPasswordResetToken(token)[:12]
+}
+
+// passwordResetEmailKey is the key reset requests for an email are limited by
+func passwordResetEmailKey(email string) string {
+	return "reset|" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// defaultPasswordResetThrottleConfig returns the limit on reset requests for
+// one email: a few an hour, then a pause that doubles while they keep coming.
+// Requests from one IP get the same pause after more of them.
+func defaultPasswordResetThrottleConfig(maxRequests int) core.LoginThrottleConfig {
+	return core.LoginThrottleConfig{
+		MaxFailures: maxRequests,
+		Window:      time.Hour,
+		BaseLockout: 15 * time.Minute,
+		MaxLockout:  time.Hour,
+	}
+}
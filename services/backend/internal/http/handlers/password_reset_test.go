@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/testutil"
+	db "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/shared/auth"
+)
+
+// recordingResetSender keeps the last token sent to each email
+type recordingResetSender struct {
+	tokens map[string]string
+}
+
+func (s *recordingResetSender) SendPasswordReset(ctx context.Context, email, token string, expiresAt time.Time) error {
+	s.tokens[email] = token
+	return nil
+}
+
+// passwordResetFixture is an auth handler on a test database with two users
+type passwordResetFixture struct {
+	t         *testing.T
+	queries   *db.Queries
+	sender    *recordingResetSender
+	jwtConfig *auth.JWTConfig
+	routes    http.Handler
+}
+
+func newPasswordResetFixture(t *testing.T) *passwordResetFixture {
+	pool := testutil.SetupTestDB(t)
+	queries := db.New(pool)
+	for _, username := range []string{"alice", "bob"} {
+		hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := queries.CreateUser(context.Background(), db.CreateUserParams{
+			Username:     username,
+			Email:        username + "@example.com",
+			PasswordHash: string(hash),
+		}); err != nil {
+			t.Fatalf("failed to create %s: %v", username, err)
+		}
+	}
+
+	handler := NewAuthHandler(queries, "test-secret", zap.NewNop())
+	sender := &recordingResetSender{tokens: make(map[string]string)}
+	handler.resetSender = sender
+	handler.SetRevocationCheck(auth.RevokeSessionsBeforePasswordReset(func(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+		changedAt, err := queries.GetUserPasswordChangedAt(ctx, userID)
+		return changedAt.Time, err
+	}))
+	return &passwordResetFixture{t: t, queries: queries, sender: sender, jwtConfig: handler.jwtConfig, routes: handler.Routes()}
+}
+
+func (f *passwordResetFixture) post(path, body string) int {
+	f.t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	f.routes.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func (f *passwordResetFixture) request(email string) string {
+	f.t.Helper()
+	if code := f.post("/password-reset/request", `{"email":"`+email+`"}`); code != http.StatusAccepted {
+		f.t.Fatalf("request for %s: got status %d, want 202", email, code)
+	}
+	return f.sender.tokens[email]
+}
+
+func (f *passwordResetFixture) confirm(email, token, password string) int {
+	f.t.Helper()
+	return f.post("/password-reset/confirm", `{"email":"`+email+`","token":"`+token+`","password":"`+password+`"}`)
+}
+
+func (f *passwordResetFixture) hasPassword(email, password string) bool {
+	f.t.Helper()
+	user, err := f.queries.GetUserByEmail(context.Background(), email)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil
+}
+
+// login returns an access token of the user with the email
+func (f *passwordResetFixture) login(email string) string {
+	f.t.Helper()
+	user, err := f.queries.GetUserByEmail(context.Background(), email)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	token, _, err := f.jwtConfig.GenerateToken(user.ID, auth.RoleUser)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return token
+}
+
+// me returns the status of fetching the current user with a token
+func (f *passwordResetFixture) me(token string) int {
+	f.t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	f.routes.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestPasswordResetIsSingleUse(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	token := f.request("alice@example.com")
+	if token == "" {
+		t.Fatal("expected a token to be sent")
+	}
+	if code := f.confirm("alice@example.com", token, "new-password"); code != http.StatusNoContent {
+		t.Fatalf("confirm: got status %d, want 204", code)
+	}
+	if !f.hasPassword("alice@example.com", "new-password") {
+		t.Error("expected the password to be changed")
+	}
+
+	// Reusing the token fails and leaves the new password alone
+	if code := f.confirm("alice@example.com", token, "another-password"); code != http.StatusBadRequest {
+		t.Errorf("reused token: got status %d, want 400", code)
+	}
+	if !f.hasPassword("alice@example.com", "new-password") {
+		t.Error("expected a reused token not to change the password")
+	}
+}
+
+func TestPasswordResetRejectsExpiredToken(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	user, err := f.queries.GetUserByEmail(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, tokenHash, err := newPasswordResetToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.queries.CreatePasswordResetToken(context.Background(), db.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := f.confirm("alice@example.com", token, "new-password"); code != http.StatusBadRequest {
+		t.Errorf("expired token: got status %d, want 400", code)
+	}
+	if !f.hasPassword("alice@example.com", "old-password") {
+		t.Error("expected an expired token not to change the password")
+	}
+}
+
+func TestPasswordResetRejectsAnotherUsersToken(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	token := f.request("alice@example.com")
+	if code := f.confirm("bob@example.com", token, "new-password"); code != http.StatusBadRequest {
+		t.Errorf("another user's token: got status %d, want 400", code)
+	}
+	if !f.hasPassword("bob@example.com", "old-password") || !f.hasPassword("alice@example.com", "old-password") {
+		t.Error("expected neither password to change")
+	}
+
+	// The token is still good for its own user
+	if code := f.confirm("alice@example.com", token, "new-password"); code != http.StatusNoContent {
+		t.Errorf("confirm: got status %d, want 204", code)
+	}
+}
+
+func TestPasswordResetRevokesEarlierTokens(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	first := f.request("alice@example.com")
+	second := f.request("alice@example.com")
+	if code := f.confirm("alice@example.com", first, "new-password"); code != http.StatusBadRequest {
+		t.Errorf("earlier token: got status %d, want 400", code)
+	}
+	if code := f.confirm("alice@example.com", second, "new-password"); code != http.StatusNoContent {
+		t.Errorf("latest token: got status %d, want 204", code)
+	}
+}
+
+func TestPasswordResetRevokesSessions(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	aliceSession := f.login("alice@example.com")
+	bobSession := f.login("bob@example.com")
+	if code := f.me(aliceSession); code != http.StatusOK {
+		t.Fatalf("before the reset: got status %d, want 200", code)
+	}
+
+	token := f.request("alice@example.com")
+	if code := f.confirm("alice@example.com", token, "new-password"); code != http.StatusNoContent {
+		t.Fatalf("confirm: got status %d, want 204", code)
+	}
+
+	if code := f.me(aliceSession); code != http.StatusUnauthorized {
+		t.Errorf("token issued before the reset: got status %d, want 401", code)
+	}
+	if code := f.me(bobSession); code != http.StatusOK {
+		t.Errorf("another user's token: got status %d, want 200", code)
+	}
+}
+
+func TestPasswordResetRequest(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	// Unknown emails are accepted the same way, without sending anything
+	f.request("nobody@example.com")
+	if len(f.sender.tokens) != 0 {
+		t.Errorf("expected no token for an unknown email, got %v", f.sender.tokens)
+	}
+
+	// The new password has to meet the registration policy
+	token := f.request("alice@example.com")
+	if code := f.confirm("alice@example.com", token, "short"); code != http.StatusBadRequest {
+		t.Errorf("short password: got status %d, want 400", code)
+	}
+	if code := f.confirm("alice@example.com", token, "long-enough"); code != http.StatusNoContent {
+		t.Errorf("expected the token to survive a rejected password, got status %d", code)
+	}
+}
+
+func TestHashPasswordResetToken(t *testing.T) {
+	token, tokenHash, err := newPasswordResetToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokenHash != hashPasswordResetToken(token) || strings.Contains(tokenHash, token) {
+		t.Errorf("expected the stored hash to be derived from, and not contain, the token")
+	}
+	other, _, _ := newPasswordResetToken()
+	if other == token {
+		t.Error("expected tokens to be random")
+	}
+}
+
+func TestPasswordResetRequestsAreRateLimited(t *testing.T) {
+	handler := NewAuthHandler(nil, "test-secret", zap.NewNop())
+	routes := handler.Routes()
+
+	request := func(remoteAddr, email string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/password-reset/request", strings.NewReader(`{"email":"`+email+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// An email that has had its requests is refused from any IP, however
+	// it's cased, and an IP that has had its requests is refused for any
+	// email, before anything is looked up
+	for range 3 {
+		handler.resetThrottler.Reserve(passwordResetEmailKey("alice@example.com"))
+	}
+	for range 20 {
+		handler.resetIPThrottler.Reserve(core.LoginIPKey("192.0.2.1"))
+	}
+	for _, attempt := range []struct{ remoteAddr, email string }{
+		{"198.51.100.7:51234", "alice@example.com"},
+		{"203.0.113.9:51234", "Alice@Example.com"},
+		{"192.0.2.1:51234", "bob@example.com"},
+	} {
+		rec := request(attempt.remoteAddr, attempt.email)
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("%s from %s: got status %d, want 429", attempt.email, attempt.remoteAddr, rec.Code)
+		}
+		if rec.Header().Get("Retry-After") == "" {
+			t.Errorf("%s from %s: expected a Retry-After header", attempt.email, attempt.remoteAddr)
+		}
+	}
+}
+
+func TestLogPasswordResetSenderOnlyLogsFingerprints(t *testing.T) {
+	token, tokenHash, err := newPasswordResetToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, revealTokens := range []bool{false, true} {
+		observed, logs := observer.New(zapcore.InfoLevel)
+		sender := NewLogPasswordResetSender(zap.New(observed), revealTokens)
+		if err := sender.SendPasswordReset(context.Background(), "alice@example.com", token, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		fields := logs.All()[0].ContextMap()
+		if fingerprint, _ := fields["token_fingerprint"].(string); !strings.HasPrefix(tokenHash, fingerprint) || fingerprint == "" {
+			t.Errorf("revealTokens=%v: expected a fingerprint of the stored hash, got %q", revealTokens, fingerprint)
+		}
+		if _, logged := fields["token"]; logged != revealTokens {
+			t.Errorf("revealTokens=%v: token logged = %v", revealTokens, logged)
+		}
+	}
+}
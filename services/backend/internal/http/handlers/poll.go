@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/tennex/pkg/apierror"
+	dbgen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/httpx"
+)
+
+// pollOptionTally is the vote count for one poll option
+type pollOptionTally struct {
+	Name   string   `json:"name"`
+	Votes  int      `json:"votes"`
+	Voters []string `json:"voters"`
+}
+
+// GetMessagePoll returns the poll question, options and running vote tallies for a poll message
+func (h *APIHandler) GetMessagePoll(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
+		return
+	}
+
+	messageID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid message ID", err))
+		return
+	}
+
+	message, err := h.queries.GetUserMessage(r.Context(), dbgen.GetUserMessageParams{
+		MessageID: messageID,
+		UserID:    userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.Error(w, apierror.New(http.StatusNotFound, "Message not found"))
+			return
+		}
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get message", err))
+		return
+	}
+	if message.MessageType != "poll" {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Message is not a poll"))
+		return
+	}
+
+	// Poll details are stored in the message's platform metadata by the bridge
+	var metadata map[string]string
+	if err := json.Unmarshal(message.PlatformMetadata, &metadata); err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to parse poll metadata", err))
+		return
+	}
+	var options []string
+	if err := json.Unmarshal([]byte(metadata["poll_options"]), &options); err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to parse poll options", err))
+		return
+	}
+	selectableCount, _ := strconv.Atoi(metadata["poll_selectable_count"])
+
+	votes, err := h.queries.ListPollVotes(r.Context(), dbgen.ListPollVotesParams{
+		ConversationID:        message.ConversationID,
+		PollExternalMessageID: message.ExternalMessageID,
+	})
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get poll votes", err))
+		return
+	}
+
+	tallies, totalVoters := tallyPollVotes(options, votes)
+
+	response := map[string]interface{}{
+		"message_id":       message.ID,
+		"question":         metadata["poll_question"],
+		"selectable_count": selectableCount,
+		"options":          tallies,
+		"total_voters":     totalVoters,
+	}
+
+	h.logger.Debug("Poll tallies retrieved",
+		zap.String("message_id", message.ID.String()),
+		zap.Int("total_voters", totalVoters))
+	httpx.JSON(w, http.StatusOK, response)
+}
+
+// tallyPollVotes counts each voter's latest selection per option.
+// WhatsApp identifies selected options by the SHA-256 of the option name.
+// Voters who retracted their vote are not counted.
+func tallyPollVotes(options []string, votes []dbgen.ListPollVotesRow) ([]pollOptionTally, int) {
+	tallies := make([]pollOptionTally, len(options))
+	byHash := make(map[string]int, len(options))
+	for i, option := range options {
+		tallies[i] = pollOptionTally{Name: option, Voters: []string{}}
+		hash := sha256.Sum256([]byte(option))
+		byHash[hex.EncodeToString(hash[:])] = i
+	}
+
+	totalVoters := 0
+	for _, vote := range votes {
+		counted := false
+		for _, hash := range vote.SelectedOptionHashes {
+			i, ok := byHash[hash]
+			if !ok {
+				continue
+			}
+			tallies[i].Votes++
+			tallies[i].Voters = append(tallies[i].Voters, vote.VoterExternalID)
+			counted = true
+		}
+		if counted {
+			totalVoters++
+		}
+	}
+
+	return tallies, totalVoters
+}
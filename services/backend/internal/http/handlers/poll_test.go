@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	dbgen "github.com/tennex/pkg/db/gen"
+)
+
+func optionHash(name string) string {
+	hash := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(hash[:])
+}
+
+func TestTallyPollVotes(t *testing.T) {
+	options := []string{"Pizza", "Sushi", "Tacos"}
+	votes := []dbgen.ListPollVotesRow{
+		{VoterExternalID: "alice", SelectedOptionHashes: []string{optionHash("Pizza")}, VotedAt: time.Unix(1, 0)},
+		{VoterExternalID: "bob", SelectedOptionHashes: []string{optionHash("Pizza"), optionHash("Tacos")}, VotedAt: time.Unix(2, 0)},
+		{VoterExternalID: "carol", SelectedOptionHashes: []string{}, VotedAt: time.Unix(3, 0)},
+		{VoterExternalID: "dave", SelectedOptionHashes: []string{optionHash("Burgers")}, VotedAt: time.Unix(4, 0)},
+	}
+
+	tallies, totalVoters := tallyPollVotes(options, votes)
+
+	if totalVoters != 2 {
+		t.Errorf("totalVoters = %d, want 2", totalVoters)
+	}
+
+	want := []struct {
+		name   string
+		votes  int
+		voters []string
+	}{
+		{"Pizza", 2, []string{"alice", "bob"}},
+		{"Sushi", 0, []string{}},
+		{"Tacos", 1, []string{"bob"}},
+	}
+	if len(tallies) != len(want) {
+		t.Fatalf("got %d tallies, want %d", len(tallies), len(want))
+	}
+	for i, w := range want {
+		got := tallies[i]
+		if got.Name != w.name || got.Votes != w.votes {
+			t.Errorf("tallies[%d] = %s:%d, want %s:%d", i, got.Name, got.Votes, w.name, w.votes)
+		}
+		if len(got.Voters) != len(w.voters) {
+			t.Errorf("tallies[%d].Voters = %v, want %v", i, got.Voters, w.voters)
+			continue
+		}
+		for j := range w.voters {
+			if got.Voters[j] != w.voters[j] {
+				t.Errorf("tallies[%d].Voters = %v, want %v", i, got.Voters, w.voters)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tennex/pkg/apierror"
+	"github.com/tennex/pkg/httpx"
+)
+
+// maxPresenceJIDs caps the contacts one request can subscribe to or unsubscribe from
+const maxPresenceJIDs = 100
+
+// presenceRequest is the body of the presence subscribe and unsubscribe endpoints
+type presenceRequest struct {
+	JIDs []string `json:"jids"`
+}
+
+// SubscribeWhatsAppPresence starts forwarding the presence of the given
+// contacts as presence events, e.g. while their chats are open. WhatsApp
+// only sends presence for contacts the account subscribed to.
+func (h *APIHandler) SubscribeWhatsAppPresence(w http.ResponseWriter, r *http.Request) {
+	h.updatePresenceSubscriptions(w, r, true)
+}
+
+// UnsubscribeWhatsAppPresence stops forwarding the presence of the given contacts
+func (h *APIHandler) UnsubscribeWhatsAppPresence(w http.ResponseWriter, r *http.Request) {
+	h.updatePresenceSubscriptions(w, r, false)
+}
+
+func (h *APIHandler) updatePresenceSubscriptions(w http.ResponseWriter, r *http.Request, subscribe bool) {
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
+		return
+	}
+
+	var req presenceRequest
+	if err := httpx.Decode(r, &req, 0); err != nil {
+		httpx.Error(w, err)
+		return
+	}
+	if len(req.JIDs) == 0 {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Missing required field jids"))
+		return
+	}
+	if len(req.JIDs) > maxPresenceJIDs {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Too many JIDs in one request"))
+		return
+	}
+
+	var subscribed []string
+	if subscribe {
+		subscribed, err = h.bridgeClient.SubscribePresence(r.Context(), userID.String(), req.JIDs)
+	} else {
+		subscribed, err = h.bridgeClient.UnsubscribePresence(r.Context(), userID.String(), req.JIDs)
+	}
+	if err != nil {
+		switch status.Code(err) {
+		case codes.InvalidArgument:
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid JIDs", err))
+		case codes.ResourceExhausted:
+			httpx.Error(w, apierror.Wrap(http.StatusConflict, "Too many presence subscriptions", err))
+		case codes.NotFound:
+			httpx.Error(w, apierror.Wrap(http.StatusConflict, "Integration is not connected", err))
+		default:
+			httpx.Error(w, apierror.Wrap(http.StatusBadGateway, "Failed to update presence subscriptions", err))
+		}
+		return
+	}
+
+	h.logger.Debug("Presence subscriptions updated",
+		zap.String("user_id", userID.String()),
+		zap.Bool("subscribe", subscribe),
+		zap.Int("subscribed", len(subscribed)))
+	if subscribed == nil {
+		subscribed = []string{}
+	}
+	httpx.JSON(w, http.StatusOK, map[string]interface{}{"subscribed_jids": subscribed})
+}
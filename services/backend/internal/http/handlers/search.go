@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"github.com/tennex/pkg/apierror"
+	dbgen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/httpx"
+)
+
+const (
+	// Search results returned by default and at most
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+
+	// Newest matches ranked per search, so a term found in most messages
+	// doesn't rank the user's whole history
+	maxSearchCandidates = 1000
+
+	// Longest accepted search query, in characters
+	maxSearchQueryLength = 256
+)
+
+// The database marks matched words in snippets with these control characters
+const (
+	snippetMatchStart = "\x02"
+	snippetMatchEnd   = "\x03"
+)
+
+// SearchMessages searches the user's synced messages across all of their
+// integrations, best matches first
+func (h *APIHandler) SearchMessages(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Missing search query"))
+		return
+	}
+	if len([]rune(query)) > maxSearchQueryLength {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Search query too long"))
+		return
+	}
+
+	params := dbgen.SearchUserMessagesParams{
+		UserID:        userID,
+		Query:         query,
+		MaxCandidates: maxSearchCandidates,
+		ResultLimit:   defaultSearchLimit,
+	}
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid limit", err))
+			return
+		}
+		params.ResultLimit = int32(min(parsed, maxSearchLimit))
+	}
+
+	if v := r.URL.Query().Get("conversation_id"); v != "" {
+		conversationID, err := uuid.Parse(v)
+		if err != nil {
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid conversation_id", err))
+			return
+		}
+		params.ConversationID = pgtype.UUID{Bytes: conversationID, Valid: true}
+	}
+
+	if v := r.URL.Query().Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid from timestamp (must be RFC 3339)", err))
+			return
+		}
+		params.FromTime = pgtype.Timestamptz{Time: from, Valid: true}
+	}
+
+	if v := r.URL.Query().Get("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid to timestamp (must be RFC 3339)", err))
+			return
+		}
+		params.ToTime = pgtype.Timestamptz{Time: to, Valid: true}
+	}
+
+	if params.FromTime.Valid && params.ToTime.Valid && params.ToTime.Time.Before(params.FromTime.Time) {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "from must not be after to"))
+		return
+	}
+
+	rows, err := h.queries.SearchUserMessages(r.Context(), params)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to search messages", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"query":       query,
+		"results":     convertSearchResultsToAPI(rows),
+		"total_count": len(rows),
+	}
+
+	h.logger.Debug("Messages searched",
+		zap.String("user_id", userID.String()),
+		zap.Int("results", len(rows)))
+	httpx.JSON(w, http.StatusOK, response)
+}
+
+func convertSearchResultsToAPI(rows []dbgen.SearchUserMessagesRow) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		result[i] = map[string]interface{}{
+			"message_id":               row.ID,
+			"conversation_id":          row.ConversationID,
+			"user_integration_id":      row.UserIntegrationID,
+			"external_conversation_id": row.ExternalConversationID,
+			"external_message_id":      row.ExternalMessageID,
+			"sender_external_id":       row.SenderExternalID,
+			"message_type":             row.MessageType,
+			"timestamp":                row.Timestamp,
+			"is_from_me":               row.IsFromMe,
+			"rank":                     row.Rank,
+			"snippet":                  highlightSnippet(row.Snippet),
+		}
+		if row.ConversationName.Valid {
+			result[i]["conversation_name"] = row.ConversationName.String
+		}
+		if row.SenderDisplayName.Valid {
+			result[i]["sender_display_name"] = row.SenderDisplayName.String
+		}
+	}
+	return result
+}
+
+// highlightSnippet HTML-escapes a snippet and wraps its matched words in
+// <mark> tags, so clients can render it as HTML
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, snippetMatchStart, "<mark>")
+	return strings.ReplaceAll(escaped, snippetMatchEnd, "</mark>")
+}
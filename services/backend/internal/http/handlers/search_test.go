@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/testutil"
+	dbgen "github.com/tennex/pkg/db/gen"
+)
+
+func TestConvertSearchResultsToAPI(t *testing.T) {
+	rows := []dbgen.SearchUserMessagesRow{
+		{
+			ID:                uuid.New(),
+			ConversationName:  pgtype.Text{String: "Team", Valid: true},
+			SenderDisplayName: pgtype.Text{String: "Alice", Valid: true},
+			Snippet:           "\x02budget\x03 review <b>",
+		},
+		{ID: uuid.New(), Snippet: "no names"},
+	}
+
+	results := convertSearchResultsToAPI(rows)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0]["conversation_name"] != "Team" || results[0]["sender_display_name"] != "Alice" {
+		t.Errorf("expected names on the first result, got %v", results[0])
+	}
+	if results[0]["snippet"] != "<mark>budget</mark> review &lt;b&gt;" {
+		t.Errorf("expected escaped snippet with highlights, got %v", results[0]["snippet"])
+	}
+	if _, ok := results[1]["conversation_name"]; ok {
+		t.Errorf("expected no conversation_name for a null name, got %v", results[1])
+	}
+	if _, ok := results[1]["sender_display_name"]; ok {
+		t.Errorf("expected no sender_display_name for a null name, got %v", results[1])
+	}
+}
+
+func TestSearchMessagesScopedToUser(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	apiHandler := NewAPIHandler(nil, nil, nil, nil, nil, dbgen.New(pool), testJWTSecret, zap.NewNop())
+
+	const chatID = "972507777777@s.whatsapp.net"
+	seed := func(name, jid string, contents map[string]string) string {
+		t.Helper()
+		userID := createTestUser(t, pool, name)
+		conversationID := createTestConversation(t, pool, createTestIntegration(t, pool, userID, jid), chatID, "individual")
+		n := int64(0)
+		for id, content := range contents {
+			createTestMessage(t, pool, conversationID, id, "text", content, time.Unix(1700000000+n, 0))
+			n++
+		}
+		return testToken(t, userID)
+	}
+
+	aliceToken := seed("search-alice", "972505555555@s.whatsapp.net", map[string]string{
+		"A-CLOSE": "budget review at noon",
+		"A-FAR":   "the budget looks fine but the review of everything else waits until next week",
+		"A-OTHER": "lunch at noon?",
+	})
+	bobToken := seed("search-bob", "972506666666@s.whatsapp.net", map[string]string{
+		"B-CLOSE": "budget review moved to friday",
+	})
+
+	type searchResult struct {
+		ExternalMessageID string  `json:"external_message_id"`
+		Rank              float64 `json:"rank"`
+		Snippet           string  `json:"snippet"`
+	}
+	search := func(token, query string) []searchResult {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/search/messages?q="+url.QueryEscape(query), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		apiHandler.Routes().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 from /search/messages, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response struct {
+			Results []searchResult `json:"results"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return response.Results
+	}
+
+	// Both of Alice's matches, the one with the words together first
+	results := search(aliceToken, "budget review")
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results for alice, got %+v", results)
+	}
+	if results[0].ExternalMessageID != "A-CLOSE" || results[1].ExternalMessageID != "A-FAR" {
+		t.Errorf("expected A-CLOSE ranked above A-FAR, got %+v", results)
+	}
+	if results[0].Rank <= results[1].Rank {
+		t.Errorf("expected descending ranks, got %+v", results)
+	}
+	if !strings.Contains(results[0].Snippet, "<mark>budget</mark> <mark>review</mark>") {
+		t.Errorf("expected highlighted snippet, got %q", results[0].Snippet)
+	}
+
+	// Bob's identical chat is never visible to Alice, and vice versa
+	results = search(bobToken, "budget review")
+	if len(results) != 1 || results[0].ExternalMessageID != "B-CLOSE" {
+		t.Errorf("expected only B-CLOSE for bob, got %+v", results)
+	}
+	if results := search(aliceToken, "friday"); len(results) != 0 {
+		t.Errorf("expected alice not to find bob's message, got %+v", results)
+	}
+
+	// An empty query is rejected rather than matching everything
+	req := httptest.NewRequest(http.MethodGet, "/search/messages?q=%20", nil)
+	req.Header.Set("Authorization", "Bearer "+aliceToken)
+	rec := httptest.NewRecorder()
+	apiHandler.Routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty query, got %d", rec.Code)
+	}
+}
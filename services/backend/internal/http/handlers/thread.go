@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/tennex/pkg/apierror"
+	dbgen "github.com/tennex/pkg/db/gen"
+	"github.com/tennex/pkg/httpx"
+)
+
+// GetMessageThread returns every reply below a message, directly or through other replies
+func (h *APIHandler) GetMessageThread(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserFromToken(r)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusUnauthorized, "Invalid or missing token", err))
+		return
+	}
+
+	messageID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid message ID", err))
+		return
+	}
+
+	// Only walk threads of the user's own messages
+	root, err := h.queries.GetUserMessage(r.Context(), dbgen.GetUserMessageParams{
+		MessageID: messageID,
+		UserID:    userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.Error(w, apierror.New(http.StatusNotFound, "Message not found"))
+			return
+		}
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get message", err))
+		return
+	}
+
+	replies, err := h.queries.GetMessageThread(r.Context(), root.ID)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to get message thread", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"root_message_id": root.ID,
+		"conversation_id": root.ConversationID,
+		"messages":        convertThreadToAPI(replies),
+		"total_count":     len(replies),
+	}
+
+	h.logger.Debug("Message thread retrieved",
+		zap.String("message_id", root.ID.String()),
+		zap.Int("replies", len(replies)))
+	httpx.JSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	dbgen "github.com/tennex/pkg/db/gen"
+)
+
+func TestConvertThreadToAPI(t *testing.T) {
+	root := uuid.New()
+	reply := uuid.New()
+	replies := []dbgen.GetMessageThreadRow{
+		{ID: reply, ReplyToMessageID: pgtype.UUID{Bytes: root, Valid: true}, Depth: 1},
+		{ID: uuid.New(), ReplyToMessageID: pgtype.UUID{Bytes: reply, Valid: true}, Depth: 2},
+	}
+
+	data, err := json.Marshal(convertThreadToAPI(replies))
+	if err != nil {
+		t.Fatalf("failed to encode thread: %v", err)
+	}
+
+	var decoded []struct {
+		ID               uuid.UUID `json:"id"`
+		ReplyToMessageID uuid.UUID `json:"reply_to_message_id"`
+		Depth            int       `json:"depth"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode thread: %v", err)
+	}
+
+	want := []struct {
+		replyTo uuid.UUID
+		depth   int
+	}{{root, 1}, {reply, 2}}
+	if len(decoded) != len(want) {
+		t.Fatalf("got %d messages, want %d", len(decoded), len(want))
+	}
+	for i, w := range want {
+		if decoded[i].ReplyToMessageID != w.replyTo || decoded[i].Depth != w.depth {
+			t.Errorf("message %d: reply_to_message_id=%s depth=%d, want %s depth %d",
+				i, decoded[i].ReplyToMessageID, decoded[i].Depth, w.replyTo, w.depth)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestLegacyRoutesAreDeprecatedAliasesOfV1(t *testing.T) {
+	v1 := chi.NewRouter()
+	v1.Get("/sync", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	router := chi.NewRouter()
+	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router.Mount("/v1", v1)
+	router.Mount("/", Deprecated("/v1")(v1))
+
+	tests := []struct {
+		path       string
+		want       int
+		deprecated bool
+	}{
+		{"/health", http.StatusOK, false},
+		{"/v1/sync", http.StatusNoContent, false},
+		{"/sync", http.StatusNoContent, true},
+		{"/v1/health", http.StatusNotFound, false},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d", tt.path, rec.Code, tt.want)
+		}
+		if deprecated := rec.Header().Get("Deprecation") == "true"; deprecated != tt.deprecated {
+			t.Errorf("%s: got deprecated %v, want %v", tt.path, deprecated, tt.deprecated)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sync", nil))
+	if link := rec.Header().Get("Link"); link != `</v1/sync>; rel="successor-version"` {
+		t.Errorf("got successor link %q", link)
+	}
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/tennex/backend/internal/core"
+	"github.com/tennex/backend/internal/repo"
+	"github.com/tennex/pkg/apierror"
+	"github.com/tennex/pkg/httpx"
+	"github.com/tennex/shared/auth"
+)
+
+const (
+	// Deliveries returned by default and at most
+	defaultWebhookDeliveryLimit = 50
+	maxWebhookDeliveryLimit     = 500
+)
+
+// WebhookHandler serves the authenticated user's webhook subscriptions
+type WebhookHandler struct {
+	webhookService *core.WebhookService
+	jwtConfig      *auth.JWTConfig
+	logger         *zap.Logger
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(webhookService *core.WebhookService, jwtConfig *auth.JWTConfig, logger *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+		jwtConfig:      jwtConfig,
+		logger:         logger.Named("webhook_handler"),
+	}
+}
+
+// Routes returns the webhook routes
+func (h *WebhookHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Use(h.jwtConfig.ChiMiddleware())
+
+	r.Post("/", h.CreateWebhook)
+	r.Get("/", h.ListWebhooks)
+	r.Get("/{id}", h.GetWebhook)
+	r.Patch("/{id}", h.UpdateWebhook)
+	r.Delete("/{id}", h.DeleteWebhook)
+	r.Get("/{id}/deliveries", h.ListDeliveries)
+
+	return r
+}
+
+// CreateWebhook subscribes a URL to the user's events. The response is the
+// only one that includes the signing secret.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	var req struct {
+		URL        string   `json:"url"`
+		Secret     string   `json:"secret"`
+		EventTypes []string `json:"event_types"`
+	}
+	if err := httpx.Decode(r, &req, 0); err != nil {
+		httpx.Error(w, err)
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(r.Context(), userID, req.URL, req.Secret, req.EventTypes)
+	if err != nil {
+		h.writeServiceError(w, "Failed to create webhook", err)
+		return
+	}
+
+	response := convertWebhookToAPI(*webhook)
+	response["secret"] = webhook.Secret
+	httpx.JSON(w, http.StatusCreated, response)
+}
+
+// ListWebhooks lists the user's webhooks
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	webhooks, err := h.webhookService.ListWebhooks(r.Context(), userID)
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, "Failed to list webhooks", err))
+		return
+	}
+
+	result := make([]map[string]interface{}, len(webhooks))
+	for i, webhook := range webhooks {
+		result[i] = convertWebhookToAPI(webhook)
+	}
+	httpx.JSON(w, http.StatusOK, map[string]interface{}{"webhooks": result})
+}
+
+// GetWebhook returns one of the user's webhooks
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	id, ok := h.webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.webhookService.GetWebhook(r.Context(), userID, id)
+	if err != nil {
+		h.writeServiceError(w, "Failed to get webhook", err)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, convertWebhookToAPI(*webhook))
+}
+
+// UpdateWebhook changes the fields present in the request body. Setting
+// enabled to true resumes a webhook that was disabled after failing.
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	id, ok := h.webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		URL        *string   `json:"url"`
+		Secret     *string   `json:"secret"`
+		EventTypes *[]string `json:"event_types"`
+		Enabled    *bool     `json:"enabled"`
+	}
+	if err := httpx.Decode(r, &req, 0); err != nil {
+		httpx.Error(w, err)
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(r.Context(), userID, id, core.WebhookUpdate{
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+		Enabled:    req.Enabled,
+	})
+	if err != nil {
+		h.writeServiceError(w, "Failed to update webhook", err)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, convertWebhookToAPI(*webhook))
+}
+
+// DeleteWebhook removes one of the user's webhooks
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	id, ok := h.webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(r.Context(), userID, id); err != nil {
+		h.writeServiceError(w, "Failed to delete webhook", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries returns a webhook's most recent deliveries, newest first
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	id, ok := h.webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultWebhookDeliveryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid limit", err))
+			return
+		}
+		limit = min(parsed, maxWebhookDeliveryLimit)
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(r.Context(), userID, id, int32(limit))
+	if err != nil {
+		h.writeServiceError(w, "Failed to list webhook deliveries", err)
+		return
+	}
+
+	result := make([]map[string]interface{}, len(deliveries))
+	for i, delivery := range deliveries {
+		result[i] = convertWebhookDeliveryToAPI(delivery)
+	}
+	httpx.JSON(w, http.StatusOK, map[string]interface{}{"deliveries": result})
+}
+
+// webhookID parses the {id} URL parameter, writing an error response and
+// returning false if it is invalid
+func (h *WebhookHandler) webhookID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httpx.Error(w, apierror.Wrap(http.StatusBadRequest, "Invalid webhook ID", err))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func convertWebhookToAPI(webhook repo.Webhook) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":                   webhook.ID,
+		"url":                  webhook.URL,
+		"event_types":          webhook.EventTypes,
+		"enabled":              webhook.Enabled,
+		"consecutive_failures": webhook.ConsecutiveFailures,
+		"created_at":           webhook.CreatedAt,
+		"updated_at":           webhook.UpdatedAt,
+	}
+
+	if webhook.DisabledAt.Valid {
+		result["disabled_at"] = webhook.DisabledAt.Time
+	}
+
+	return result
+}
+
+func convertWebhookDeliveryToAPI(delivery repo.WebhookDelivery) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":              delivery.ID,
+		"event_seq":       delivery.EventSeq,
+		"event_type":      delivery.EventType,
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"created_at":      delivery.CreatedAt,
+		"updated_at":      delivery.UpdatedAt,
+	}
+
+	if delivery.LastStatusCode.Valid {
+		result["last_status_code"] = delivery.LastStatusCode.Int32
+	}
+	if delivery.LastError.Valid {
+		result["last_error"] = delivery.LastError.String
+	}
+	if delivery.DeliveredAt.Valid {
+		result["delivered_at"] = delivery.DeliveredAt.Time
+	}
+
+	return result
+}
+
+// writeServiceError maps webhook service errors to responses
+func (h *WebhookHandler) writeServiceError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, core.ErrInvalidWebhook):
+		httpx.Error(w, apierror.New(http.StatusBadRequest, err.Error()))
+	case errors.Is(err, pgx.ErrNoRows):
+		httpx.Error(w, apierror.New(http.StatusNotFound, "Webhook not found"))
+	default:
+		httpx.Error(w, apierror.Wrap(http.StatusInternalServerError, message, err))
+	}
+}
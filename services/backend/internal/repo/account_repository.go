@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -124,14 +125,66 @@ func (r *accountRepository) UpdateAccountStatus(ctx context.Context, params Upda
 	return nil
 }
 
+// accountOrderings maps each sort option to its ORDER BY clause
+var accountOrderings = map[string]string{
+	AccountSortCreatedAt: "created_at DESC, id",
+	AccountSortLastSeen:  "last_seen DESC NULLS LAST, id",
+}
+
+// ListAccounts lists the event accounts of active users. Accounts are keyed
+// by user ID; the WhatsApp JID, display name and avatar are those of the
+// user's most recently seen WhatsApp integration, and the status is the best
+// of the user's integration statuses.
 func (r *accountRepository) ListAccounts(ctx context.Context, params ListAccountsParams) ([]Account, error) {
+	orderBy, ok := accountOrderings[params.SortBy]
+	if params.SortBy == "" {
+		orderBy, ok = accountOrderings[AccountSortCreatedAt], true
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown account sort %q", params.SortBy)
+	}
+
 	query := `
+		WITH accounts AS (
+			SELECT u.id::text AS id,
+				wa.external_id AS wa_jid,
+				COALESCE(wa.display_name, u.full_name) AS display_name,
+				wa.avatar_url,
+				integrations.status,
+				integrations.last_seen,
+				u.created_at,
+				u.updated_at
+			FROM users u
+			LEFT JOIN LATERAL (
+				SELECT external_id, display_name, avatar_url
+				FROM user_integrations
+				WHERE user_id = u.id AND integration_type = 'whatsapp'
+				ORDER BY last_seen DESC NULLS LAST, id DESC
+				LIMIT 1
+			) wa ON true
+			CROSS JOIN LATERAL (
+				SELECT CASE
+						WHEN bool_or(status = 'connected') THEN 'connected'
+						WHEN bool_or(status = 'connecting') THEN 'connecting'
+						WHEN bool_or(status = 'error') THEN 'error'
+						ELSE 'disconnected'
+					END AS status,
+					MAX(last_seen) AS last_seen
+				FROM user_integrations
+				WHERE user_id = u.id
+			) integrations
+			WHERE u.is_active
+		)
 		SELECT id, wa_jid, display_name, avatar_url, status, last_seen, created_at, updated_at
-		FROM accounts 
-		ORDER BY created_at DESC
+		FROM accounts
+		WHERE ($3 = '' OR status = $3)
+			AND ($4 = '' OR strpos(lower(id), lower($4)) > 0
+				OR strpos(lower(wa_jid), lower($4)) > 0
+				OR strpos(lower(display_name), lower($4)) > 0)
+		ORDER BY ` + orderBy + `
 		LIMIT $1 OFFSET $2`
 
-	rows, err := r.db.Query(ctx, query, params.Limit, params.Offset)
+	rows, err := r.db.Query(ctx, query, params.Limit, params.Offset, params.Status, params.Search)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list accounts: %w", err)
 	}
@@ -163,6 +216,47 @@ func (r *accountRepository) ListAccounts(ctx context.Context, params ListAccount
 	return accounts, nil
 }
 
+// ListAccountIntegrations returns the integrations of the given accounts,
+// whose IDs are the IDs of the users owning the integrations
+func (r *accountRepository) ListAccountIntegrations(ctx context.Context, accountIDs []string) ([]AccountIntegrationSummary, error) {
+	query := `
+		SELECT ui.user_id::text, ui.id, ui.integration_type, ui.external_id, ui.status, ui.last_seen,
+			(SELECT MAX(e.ts) FROM events e WHERE e.user_integration_id = ui.id)
+		FROM user_integrations ui
+		WHERE ui.user_id::text = ANY($1)
+		ORDER BY ui.user_id, ui.id`
+
+	rows, err := r.db.Query(ctx, query, accountIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account integrations: %w", err)
+	}
+	defer rows.Close()
+
+	var integrations []AccountIntegrationSummary
+	for rows.Next() {
+		var integration AccountIntegrationSummary
+		err := rows.Scan(
+			&integration.AccountID,
+			&integration.ID,
+			&integration.IntegrationType,
+			&integration.ExternalID,
+			&integration.Status,
+			&integration.LastSeen,
+			&integration.LastEventAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan account integration: %w", err)
+		}
+		integrations = append(integrations, integration)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return integrations, nil
+}
+
 func (r *accountRepository) GetConnectedAccounts(ctx context.Context) ([]Account, error) {
 	query := `
 		SELECT id, wa_jid, display_name, avatar_url, status, last_seen, created_at, updated_at
@@ -201,3 +295,27 @@ func (r *accountRepository) GetConnectedAccounts(ctx context.Context) ([]Account
 
 	return accounts, nil
 }
+
+// GetAccountOwner returns the ID of the user owning an event account. Accounts
+// are keyed by the owner's user ID, apart from legacy accounts recorded in
+// event_accounts. Returns pgx.ErrNoRows when no user owns the account.
+func (r *accountRepository) GetAccountOwner(ctx context.Context, accountID string) (uuid.UUID, error) {
+	query := `
+		SELECT user_id FROM event_accounts WHERE account_id = $1
+		UNION ALL
+		SELECT id FROM users WHERE id = $2
+		LIMIT 1`
+
+	// An account ID that isn't a UUID can only be a legacy account; a NULL
+	// user ID matches no user
+	var ownerID *uuid.UUID
+	if id, err := uuid.Parse(accountID); err == nil {
+		ownerID = &id
+	}
+
+	var userID uuid.UUID
+	if err := r.db.QueryRow(ctx, query, accountID, ownerID).Scan(&userID); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get account owner: %w", err)
+	}
+	return userID, nil
+}
@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/tennex/backend/internal/testutil"
+)
+
+// insertAccountUser creates an active user with the given integrations
+func insertAccountUser(t *testing.T, pool *pgxpool.Pool, username, fullName string, createdAt time.Time, integrations ...testIntegration) string {
+	t.Helper()
+	ctx := context.Background()
+
+	var userID uuid.UUID
+	err := pool.QueryRow(ctx, `
+		INSERT INTO users (username, email, password_hash, full_name, created_at)
+		VALUES ($1, $1 || '@example.com', 'x', NULLIF($2, ''), $3)
+		RETURNING id`, username, fullName, createdAt).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	for _, integration := range integrations {
+		_, err := pool.Exec(ctx, `
+			INSERT INTO user_integrations (user_id, integration_type, external_id, status, display_name, last_seen)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)`,
+			userID, integration.integrationType, integration.externalID, integration.status, integration.displayName, integration.lastSeen)
+		if err != nil {
+			t.Fatalf("failed to create integration: %v", err)
+		}
+	}
+	return userID.String()
+}
+
+type testIntegration struct {
+	integrationType string
+	externalID      string
+	status          string
+	displayName     string
+	lastSeen        *time.Time
+}
+
+func accountIDs(accounts []Account) []string {
+	ids := make([]string, len(accounts))
+	for i, account := range accounts {
+		ids[i] = account.ID
+	}
+	return ids
+}
+
+func TestListAccountsReadsUsersAndIntegrations(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	accountRepo := NewAccountRepository(pool)
+	ctx := context.Background()
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	earlier, later := base.Add(time.Hour), base.Add(2*time.Hour)
+
+	// Two WhatsApp numbers, one of them connected; the newer one names the account
+	alice := insertAccountUser(t, pool, "alice", "Alice A", base,
+		testIntegration{"whatsapp", "972501111111@s.whatsapp.net", "disconnected", "Alice Old", &earlier},
+		testIntegration{"whatsapp", "972502222222@s.whatsapp.net", "connected", "Alice New", &later},
+	)
+	// An integration in error and no display name of its own
+	bob := insertAccountUser(t, pool, "bob", "Bob B", base.Add(time.Minute),
+		testIntegration{"whatsapp", "972503333333@s.whatsapp.net", "error", "", &earlier},
+	)
+	// No integrations at all
+	carol := insertAccountUser(t, pool, "carol", "", base.Add(2*time.Minute))
+	// Deactivated users aren't listed
+	dave := insertAccountUser(t, pool, "dave", "", base.Add(3*time.Minute))
+	if _, err := pool.Exec(ctx, `UPDATE users SET is_active = false WHERE id = $1`, dave); err != nil {
+		t.Fatalf("failed to deactivate user: %v", err)
+	}
+
+	accounts, err := accountRepo.ListAccounts(ctx, ListAccountsParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListAccounts: %v", err)
+	}
+	if got, want := accountIDs(accounts), []string{carol, bob, alice}; !slices.Equal(got, want) {
+		t.Fatalf("got accounts %v, want %v, newest first", got, want)
+	}
+
+	byID := make(map[string]Account)
+	for _, account := range accounts {
+		byID[account.ID] = account
+	}
+	if a := byID[alice]; a.Status != "connected" || a.WaJid.String != "972502222222@s.whatsapp.net" ||
+		a.DisplayName.String != "Alice New" || !a.LastSeen.Time.Equal(later) {
+		t.Errorf("got alice %+v, want the connected status and the newest number's JID, name and last seen", a)
+	}
+	if b := byID[bob]; b.Status != "error" || b.DisplayName.String != "Bob B" {
+		t.Errorf("got bob %+v, want the error status and the user's full name", b)
+	}
+	if c := byID[carol]; c.Status != "disconnected" || c.WaJid.Valid || c.DisplayName.Valid || c.LastSeen.Valid {
+		t.Errorf("got carol %+v, want a disconnected account without integration details", c)
+	}
+
+	tests := []struct {
+		name   string
+		params ListAccountsParams
+		want   []string
+	}{
+		{"by status", ListAccountsParams{Status: "connected", Limit: 10}, []string{alice}},
+		{"by JID", ListAccountsParams{Search: "972502222222", Limit: 10}, []string{alice}},
+		{"by display name", ListAccountsParams{Search: "bob b", Limit: 10}, []string{bob}},
+		{"by last seen", ListAccountsParams{SortBy: AccountSortLastSeen, Limit: 10}, []string{alice, bob, carol}},
+		{"paged", ListAccountsParams{Limit: 1, Offset: 1}, []string{bob}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accounts, err := accountRepo.ListAccounts(ctx, tt.params)
+			if err != nil {
+				t.Fatalf("ListAccounts: %v", err)
+			}
+			if got := accountIDs(accounts); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := accountRepo.ListAccounts(ctx, ListAccountsParams{SortBy: "username", Limit: 10}); err == nil {
+		t.Error("expected an unknown sort to be rejected")
+	}
+}
+
+func TestGetAccountOwner(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	accountRepo := NewAccountRepository(pool)
+	ctx := context.Background()
+
+	owner := insertAccountUser(t, pool, "owner", "", time.Now())
+	const legacyAccount = "972501111111@s.whatsapp.net"
+	if _, err := pool.Exec(ctx, `INSERT INTO event_accounts (account_id, user_id) VALUES ($1, $2)`, legacyAccount, owner); err != nil {
+		t.Fatalf("failed to map legacy account: %v", err)
+	}
+
+	for _, accountID := range []string{owner, legacyAccount} {
+		got, err := accountRepo.GetAccountOwner(ctx, accountID)
+		if err != nil {
+			t.Fatalf("GetAccountOwner(%q): %v", accountID, err)
+		}
+		if got.String() != owner {
+			t.Errorf("GetAccountOwner(%q) = %s, want %s", accountID, got, owner)
+		}
+	}
+
+	for _, accountID := range []string{uuid.NewString(), "972502222222@s.whatsapp.net", ""} {
+		if _, err := accountRepo.GetAccountOwner(ctx, accountID); !errors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("GetAccountOwner(%q) = %v, want pgx.ErrNoRows", accountID, err)
+		}
+	}
+}
@@ -20,8 +20,8 @@ func NewEventRepository(db *pgxpool.Pool) EventRepository {
 
 func (r *eventRepository) InsertEvent(ctx context.Context, params InsertEventParams) (InsertEventResult, error) {
 	query := `
-		INSERT INTO events (id, type, account_id, device_id, convo_id, wa_message_id, sender_jid, payload, attachment_ref)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO events (id, type, account_id, device_id, convo_id, wa_message_id, sender_jid, payload, attachment_ref, user_integration_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		ON CONFLICT (id) DO NOTHING
 		RETURNING seq, ts`
 
@@ -36,6 +36,7 @@ func (r *eventRepository) InsertEvent(ctx context.Context, params InsertEventPar
 		params.SenderJid,
 		params.Payload,
 		params.AttachmentRef,
+		params.UserIntegrationID,
 	).Scan(&result.Seq, &result.Ts)
 
 	if err != nil {
@@ -51,13 +52,24 @@ func (r *eventRepository) InsertEvent(ctx context.Context, params InsertEventPar
 
 func (r *eventRepository) GetEventsSince(ctx context.Context, params GetEventsSinceParams) ([]Event, error) {
 	query := `
-		SELECT seq, id, ts, type, account_id, device_id, convo_id, wa_message_id, sender_jid, payload, attachment_ref
+		SELECT seq, id, ts, type, account_id, device_id, convo_id, wa_message_id, sender_jid, payload, attachment_ref, user_integration_id
 		FROM events 
 		WHERE account_id = $1 AND seq > $2
 		ORDER BY seq ASC
 		LIMIT $3`
+	args := []interface{}{params.AccountID, params.Seq, params.Limit}
 
-	rows, err := r.db.Query(ctx, query, params.AccountID, params.Seq, params.Limit)
+	if len(params.Types) > 0 {
+		query = `
+		SELECT seq, id, ts, type, account_id, device_id, convo_id, wa_message_id, sender_jid, payload, attachment_ref, user_integration_id
+		FROM events 
+		WHERE account_id = $1 AND seq > $2 AND type = ANY($4::text[])
+		ORDER BY seq ASC
+		LIMIT $3`
+		args = append(args, params.Types)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query events: %w", err)
 	}
@@ -78,6 +90,7 @@ func (r *eventRepository) GetEventsSince(ctx context.Context, params GetEventsSi
 			&event.SenderJid,
 			&event.Payload,
 			&event.AttachmentRef,
+			&event.UserIntegrationID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan event: %w", err)
@@ -93,7 +106,13 @@ func (r *eventRepository) GetEventsSince(ctx context.Context, params GetEventsSi
 }
 
 func (r *eventRepository) GetLatestEventSeq(ctx context.Context, accountID string) (int64, error) {
-	query := `SELECT COALESCE(MAX(seq), 0) as latest_seq FROM events WHERE account_id = $1`
+	// Retention may have deleted the newest events, so the seq never falls
+	// below the low-water mark
+	query := `
+		SELECT GREATEST(
+			(SELECT COALESCE(MAX(seq), 0) FROM events WHERE account_id = $1),
+			(SELECT COALESCE(MAX(low_water_seq), 0) FROM event_retention WHERE account_id = $1)
+		) AS latest_seq`
 
 	var latestSeq int64
 	err := r.db.QueryRow(ctx, query, accountID).Scan(&latestSeq)
@@ -106,7 +125,7 @@ func (r *eventRepository) GetLatestEventSeq(ctx context.Context, accountID strin
 
 func (r *eventRepository) GetEventByID(ctx context.Context, id uuid.UUID) (Event, error) {
 	query := `
-		SELECT seq, id, ts, type, account_id, device_id, convo_id, wa_message_id, sender_jid, payload, attachment_ref
+		SELECT seq, id, ts, type, account_id, device_id, convo_id, wa_message_id, sender_jid, payload, attachment_ref, user_integration_id
 		FROM events 
 		WHERE id = $1`
 
@@ -123,6 +142,7 @@ func (r *eventRepository) GetEventByID(ctx context.Context, id uuid.UUID) (Event
 		&event.SenderJid,
 		&event.Payload,
 		&event.AttachmentRef,
+		&event.UserIntegrationID,
 	)
 	if err != nil {
 		return Event{}, fmt.Errorf("failed to get event by ID: %w", err)
@@ -131,6 +151,34 @@ func (r *eventRepository) GetEventByID(ctx context.Context, id uuid.UUID) (Event
 	return event, nil
 }
 
+func (r *eventRepository) GetEventBySeq(ctx context.Context, seq int64) (Event, error) {
+	query := `
+		SELECT seq, id, ts, type, account_id, device_id, convo_id, wa_message_id, sender_jid, payload, attachment_ref, user_integration_id
+		FROM events 
+		WHERE seq = $1`
+
+	var event Event
+	err := r.db.QueryRow(ctx, query, seq).Scan(
+		&event.Seq,
+		&event.ID,
+		&event.Ts,
+		&event.Type,
+		&event.AccountID,
+		&event.DeviceID,
+		&event.ConvoID,
+		&event.WaMessageID,
+		&event.SenderJid,
+		&event.Payload,
+		&event.AttachmentRef,
+		&event.UserIntegrationID,
+	)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to get event by seq: %w", err)
+	}
+
+	return event, nil
+}
+
 func (r *eventRepository) CountEventsByAccount(ctx context.Context, accountID string) (int64, error) {
 	query := `SELECT COUNT(*) as total_events FROM events WHERE account_id = $1`
 
@@ -142,3 +190,83 @@ func (r *eventRepository) CountEventsByAccount(ctx context.Context, accountID st
 
 	return count, nil
 }
+
+// expiredEventsFilter selects the events DeleteExpiredEventsParams describes,
+// as e, with $1 to $4 bound by expiredEventsArgs. Events an outbox entry still
+// points at are never expired.
+const expiredEventsFilter = `
+	e.ts < $1
+	AND ($2 = '' OR e.type = $2)
+	AND ($3 = '' OR e.account_id = $3)
+	AND e.account_id <> ALL(COALESCE($4::text[], '{}'))
+	AND NOT EXISTS (SELECT 1 FROM outbox o WHERE o.server_msg_id = e.seq)`
+
+func expiredEventsArgs(params DeleteExpiredEventsParams) []interface{} {
+	return []interface{}{params.Cutoff, params.Type, params.AccountID, params.ExcludeAccountIDs}
+}
+
+// DeleteExpiredEvents deletes one batch of expired events, archiving them if
+// asked, and raises the low-water mark of each affected account. It returns
+// the number deleted.
+func (r *eventRepository) DeleteExpiredEvents(ctx context.Context, params DeleteExpiredEventsParams) (int64, error) {
+	query := `
+		WITH deleted AS (
+			DELETE FROM events
+			WHERE seq IN (
+				SELECT e.seq FROM events e
+				WHERE ` + expiredEventsFilter + `
+				ORDER BY e.seq
+				LIMIT $5
+			)
+			RETURNING *
+		), archived AS (
+			INSERT INTO events_archive (seq, id, ts, type, account_id, device_id, convo_id, wa_message_id, sender_jid, payload, attachment_ref, user_integration_id)
+			SELECT seq, id, ts, type, account_id, device_id, convo_id, wa_message_id, sender_jid, payload, attachment_ref, user_integration_id
+			FROM deleted
+			WHERE $6::bool
+			ON CONFLICT (seq) DO NOTHING
+		), marks AS (
+			INSERT INTO event_retention (account_id, low_water_seq)
+			SELECT account_id, MAX(seq) FROM deleted GROUP BY account_id
+			ON CONFLICT (account_id) DO UPDATE
+			SET low_water_seq = GREATEST(event_retention.low_water_seq, excluded.low_water_seq),
+				updated_at = NOW()
+		)
+		SELECT COUNT(*) FROM deleted`
+
+	args := append(expiredEventsArgs(params), params.BatchSize, params.Archive)
+	var deleted int64
+	err := r.db.QueryRow(ctx, query, args...).Scan(&deleted)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired events: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// CountExpiredEvents counts the events DeleteExpiredEvents would delete,
+// ignoring the batch size
+func (r *eventRepository) CountExpiredEvents(ctx context.Context, params DeleteExpiredEventsParams) (int64, error) {
+	query := `SELECT COUNT(*) FROM events e WHERE ` + expiredEventsFilter
+
+	var count int64
+	err := r.db.QueryRow(ctx, query, expiredEventsArgs(params)...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count expired events: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetLowWaterMark returns the highest seq deleted by retention for an account, or 0
+func (r *eventRepository) GetLowWaterMark(ctx context.Context, accountID string) (int64, error) {
+	query := `SELECT COALESCE(MAX(low_water_seq), 0) FROM event_retention WHERE account_id = $1`
+
+	var lowWaterSeq int64
+	err := r.db.QueryRow(ctx, query, accountID).Scan(&lowWaterSeq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get low-water mark: %w", err)
+	}
+
+	return lowWaterSeq, nil
+}
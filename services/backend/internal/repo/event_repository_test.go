@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/tennex/backend/internal/testutil"
+	"github.com/tennex/pkg/events"
+)
+
+func TestGetEventsSinceFiltersByType(t *testing.T) {
+	pool := testutil.SetupTestDB(t)
+	eventRepo := NewEventRepository(pool)
+	ctx := context.Background()
+
+	insert := func(accountID, eventType string) int64 {
+		t.Helper()
+		result, err := eventRepo.InsertEvent(ctx, InsertEventParams{
+			ID:        uuid.New(),
+			Type:      eventType,
+			AccountID: accountID,
+			ConvoID:   "123@s.whatsapp.net",
+			Payload:   json.RawMessage(`{}`),
+		})
+		if err != nil {
+			t.Fatalf("failed to insert event: %v", err)
+		}
+		return result.Seq
+	}
+	message := insert("acct-1", events.TypeMessageIn)
+	presence := insert("acct-1", events.TypePresence)
+	delivery := insert("acct-1", events.TypeMessageDelivery)
+	insert("acct-2", events.TypeMessageIn)
+
+	seqs := func(types ...string) []int64 {
+		t.Helper()
+		stored, err := eventRepo.GetEventsSince(ctx, GetEventsSinceParams{
+			AccountID: "acct-1",
+			Limit:     100,
+			Types:     types,
+		})
+		if err != nil {
+			t.Fatalf("GetEventsSince(%v): %v", types, err)
+		}
+		result := make([]int64, len(stored))
+		for i, event := range stored {
+			result[i] = event.Seq
+		}
+		return result
+	}
+
+	if got, want := seqs(), []int64{message, presence, delivery}; !slices.Equal(got, want) {
+		t.Errorf("no filter: expected %v, got %v", want, got)
+	}
+	if got, want := seqs(events.TypePresence), []int64{presence}; !slices.Equal(got, want) {
+		t.Errorf("one type: expected %v, got %v", want, got)
+	}
+	if got, want := seqs(events.TypeMessageIn, events.TypeMessageDelivery), []int64{message, delivery}; !slices.Equal(got, want) {
+		t.Errorf("two types: expected %v, got %v", want, got)
+	}
+	if got := seqs(events.TypeHistorySync); len(got) != 0 {
+		t.Errorf("unmatched type: expected no events, got %v", got)
+	}
+}
@@ -3,12 +3,14 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"encoding/json"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -54,8 +56,7 @@ func (r *integrationRepository) UpsertUserIntegration(ctx context.Context, param
 			user_id, integration_type, external_id, status, display_name, avatar_url, metadata, last_seen
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8
-		) ON CONFLICT (user_id, integration_type) DO UPDATE SET
-			external_id = EXCLUDED.external_id,
+		) ON CONFLICT (user_id, integration_type, external_id) DO UPDATE SET
 			status = EXCLUDED.status,
 			display_name = EXCLUDED.display_name,
 			avatar_url = EXCLUDED.avatar_url,
@@ -94,14 +95,14 @@ func (r *integrationRepository) UpsertUserIntegration(ctx context.Context, param
 	return integration, nil
 }
 
-func (r *integrationRepository) GetUserIntegration(ctx context.Context, userID uuid.UUID, integrationType string) (UserIntegration, error) {
+func (r *integrationRepository) GetUserIntegration(ctx context.Context, userID uuid.UUID, integrationType, externalID string) (UserIntegration, error) {
 	query := `
 		SELECT id, user_id, integration_type, external_id, status, display_name, avatar_url, metadata, last_seen, created_at, updated_at
 		FROM user_integrations 
-		WHERE user_id = $1 AND integration_type = $2`
+		WHERE user_id = $1 AND integration_type = $2 AND external_id = $3`
 
 	var integration UserIntegration
-	err := r.db.QueryRow(ctx, query, userID, integrationType).Scan(
+	err := r.db.QueryRow(ctx, query, userID, integrationType, externalID).Scan(
 		&integration.ID,
 		&integration.UserID,
 		&integration.IntegrationType,
@@ -121,6 +122,49 @@ func (r *integrationRepository) GetUserIntegration(ctx context.Context, userID u
 	return integration, nil
 }
 
+// GetUserIntegrations returns a user's integrations of one type, oldest first
+func (r *integrationRepository) GetUserIntegrations(ctx context.Context, userID uuid.UUID, integrationType string) ([]UserIntegration, error) {
+	query := `
+		SELECT id, user_id, integration_type, external_id, status, display_name, avatar_url, metadata, last_seen, created_at, updated_at
+		FROM user_integrations 
+		WHERE user_id = $1 AND integration_type = $2
+		ORDER BY created_at, id`
+
+	rows, err := r.db.Query(ctx, query, userID, integrationType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user integrations: %w", err)
+	}
+	defer rows.Close()
+
+	var integrations []UserIntegration
+	for rows.Next() {
+		var integration UserIntegration
+		err := rows.Scan(
+			&integration.ID,
+			&integration.UserID,
+			&integration.IntegrationType,
+			&integration.ExternalID,
+			&integration.Status,
+			&integration.DisplayName,
+			&integration.AvatarUrl,
+			&integration.Metadata,
+			&integration.LastSeen,
+			&integration.CreatedAt,
+			&integration.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan integration: %w", err)
+		}
+		integrations = append(integrations, integration)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return integrations, nil
+}
+
 func (r *integrationRepository) GetUserIntegrationByExternalID(ctx context.Context, integrationType, externalID string) (UserIntegration, error) {
 	query := `
 		SELECT id, user_id, integration_type, external_id, status, display_name, avatar_url, metadata, last_seen, created_at, updated_at
@@ -190,35 +234,322 @@ func (r *integrationRepository) ListUserIntegrations(ctx context.Context, userID
 	return integrations, nil
 }
 
-func (r *integrationRepository) UpdateUserIntegrationStatus(ctx context.Context, userID uuid.UUID, integrationType, status string, lastSeen sql.NullTime) error {
-	query := `
-		UPDATE user_integrations 
-		SET status = $3, last_seen = $4, updated_at = NOW()
-		WHERE user_id = $1 AND integration_type = $2`
+// MaxIntegrationStatusEvents is how many status transitions are kept per
+// integration; older ones are trimmed as new transitions are recorded
+const MaxIntegrationStatusEvents = 500
+
+// UpdateUserIntegrationStatusParams holds a status update and why it happened
+type UpdateUserIntegrationStatusParams struct {
+	ID       int32
+	Status   string
+	LastSeen sql.NullTime
+	Reason   string
+	Metadata json.RawMessage
+}
+
+// IntegrationStatusEvent is one recorded status transition of an integration
+type IntegrationStatusEvent struct {
+	ID                int64           `json:"id"`
+	UserIntegrationID int32           `json:"user_integration_id"`
+	OldStatus         sql.NullString  `json:"old_status"`
+	NewStatus         string          `json:"new_status"`
+	Reason            sql.NullString  `json:"reason"`
+	Metadata          json.RawMessage `json:"metadata"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
+
+// UpdateUserIntegrationStatus sets an integration's status and, if the status
+// changed, appends the transition to its status history
+func (r *integrationRepository) UpdateUserIntegrationStatus(ctx context.Context, params UpdateUserIntegrationStatusParams) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the row so concurrent updates record transitions in the order applied
+	integrationID := params.ID
+	var oldStatus string
+	err = tx.QueryRow(ctx, `
+		SELECT status FROM user_integrations
+		WHERE id = $1
+		FOR UPDATE`, integrationID).Scan(&oldStatus)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("integration %d not found", integrationID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock integration: %w", err)
+	}
 
-	result, err := r.db.Exec(ctx, query, userID, integrationType, status, lastSeen)
+	_, err = tx.Exec(ctx, `
+		UPDATE user_integrations 
+		SET status = $2, last_seen = $3, updated_at = NOW()
+		WHERE id = $1`, integrationID, params.Status, params.LastSeen)
 	if err != nil {
 		return fmt.Errorf("failed to update integration status: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("integration not found for user %s and type %s", userID, integrationType)
+	if oldStatus != params.Status {
+		metadata := params.Metadata
+		if metadata == nil {
+			metadata = json.RawMessage("{}")
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO integration_status_events (user_integration_id, old_status, new_status, reason, metadata)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5)`,
+			integrationID, oldStatus, params.Status, params.Reason, metadata)
+		if err != nil {
+			return fmt.Errorf("failed to record status event: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			DELETE FROM integration_status_events
+			WHERE user_integration_id = $1 AND id <= (
+				SELECT id FROM integration_status_events
+				WHERE user_integration_id = $1
+				ORDER BY id DESC
+				OFFSET $2 LIMIT 1
+			)`, integrationID, MaxIntegrationStatusEvents)
+		if err != nil {
+			return fmt.Errorf("failed to trim status events: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit status update: %w", err)
 	}
 
 	return nil
 }
 
-func (r *integrationRepository) DeleteUserIntegration(ctx context.Context, userID uuid.UUID, integrationType string) error {
-	query := `DELETE FROM user_integrations WHERE user_id = $1 AND integration_type = $2`
+// ListIntegrationStatusEvents returns an integration's most recent status
+// transitions, newest first
+func (r *integrationRepository) ListIntegrationStatusEvents(ctx context.Context, integrationID int32, limit int32) ([]IntegrationStatusEvent, error) {
+	query := `
+		SELECT id, user_integration_id, old_status, new_status, reason, metadata, created_at
+		FROM integration_status_events
+		WHERE user_integration_id = $1
+		ORDER BY id DESC
+		LIMIT $2`
 
-	result, err := r.db.Exec(ctx, query, userID, integrationType)
+	rows, err := r.db.Query(ctx, query, integrationID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list status events: %w", err)
+	}
+	defer rows.Close()
+
+	statusEvents := []IntegrationStatusEvent{}
+	for rows.Next() {
+		var event IntegrationStatusEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.UserIntegrationID,
+			&event.OldStatus,
+			&event.NewStatus,
+			&event.Reason,
+			&event.Metadata,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan status event: %w", err)
+		}
+		statusEvents = append(statusEvents, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return statusEvents, nil
+}
+
+func (r *integrationRepository) DeleteUserIntegration(ctx context.Context, userID uuid.UUID, integrationType, externalID string) error {
+	query := `DELETE FROM user_integrations WHERE user_id = $1 AND integration_type = $2 AND external_id = $3`
+
+	result, err := r.db.Exec(ctx, query, userID, integrationType, externalID)
 	if err != nil {
 		return fmt.Errorf("failed to delete integration: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("integration not found for user %s and type %s", userID, integrationType)
+		return fmt.Errorf("integration %s not found for user %s and type %s", externalID, userID, integrationType)
 	}
 
 	return nil
 }
+
+// ListIntegrationsParams filters and pages integrations across all users.
+// Empty Status or IntegrationType match any value.
+type ListIntegrationsParams struct {
+	Status          string
+	IntegrationType string
+	Limit           int32
+	Offset          int32
+}
+
+// IntegrationSyncStats summarizes how much data has been synced for an integration
+type IntegrationSyncStats struct {
+	Conversations         int64 `json:"conversations"`
+	Messages              int64 `json:"messages"`
+	Contacts              int64 `json:"contacts"`
+	LatestConversationSeq int64 `json:"latest_conversation_seq"`
+	LatestMessageSeq      int64 `json:"latest_message_seq"`
+	LatestContactSeq      int64 `json:"latest_contact_seq"`
+}
+
+func (r *integrationRepository) GetUserIntegrationByID(ctx context.Context, id int32) (UserIntegration, error) {
+	query := `
+		SELECT id, user_id, integration_type, external_id, status, display_name, avatar_url, metadata, last_seen, created_at, updated_at
+		FROM user_integrations 
+		WHERE id = $1`
+
+	var integration UserIntegration
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&integration.ID,
+		&integration.UserID,
+		&integration.IntegrationType,
+		&integration.ExternalID,
+		&integration.Status,
+		&integration.DisplayName,
+		&integration.AvatarUrl,
+		&integration.Metadata,
+		&integration.LastSeen,
+		&integration.CreatedAt,
+		&integration.UpdatedAt,
+	)
+	if err != nil {
+		return UserIntegration{}, fmt.Errorf("failed to get user integration by ID: %w", err)
+	}
+
+	return integration, nil
+}
+
+func (r *integrationRepository) ListIntegrations(ctx context.Context, params ListIntegrationsParams) ([]UserIntegration, error) {
+	query := `
+		SELECT id, user_id, integration_type, external_id, status, display_name, avatar_url, metadata, last_seen, created_at, updated_at
+		FROM user_integrations 
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR integration_type = $2)
+		ORDER BY id
+		LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.Query(ctx, query, params.Status, params.IntegrationType, params.Limit, params.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list integrations: %w", err)
+	}
+	defer rows.Close()
+
+	var integrations []UserIntegration
+	for rows.Next() {
+		var integration UserIntegration
+		err := rows.Scan(
+			&integration.ID,
+			&integration.UserID,
+			&integration.IntegrationType,
+			&integration.ExternalID,
+			&integration.Status,
+			&integration.DisplayName,
+			&integration.AvatarUrl,
+			&integration.Metadata,
+			&integration.LastSeen,
+			&integration.CreatedAt,
+			&integration.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan integration: %w", err)
+		}
+		integrations = append(integrations, integration)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return integrations, nil
+}
+
+func (r *integrationRepository) CountIntegrations(ctx context.Context, params ListIntegrationsParams) (int64, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM user_integrations 
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR integration_type = $2)`
+
+	var total int64
+	if err := r.db.QueryRow(ctx, query, params.Status, params.IntegrationType).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count integrations: %w", err)
+	}
+
+	return total, nil
+}
+
+func (r *integrationRepository) GetIntegrationSyncStats(ctx context.Context, id int32) (IntegrationSyncStats, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM conversations WHERE user_integration_id = $1),
+			(SELECT COUNT(*) FROM messages m JOIN conversations c ON m.conversation_id = c.id WHERE c.user_integration_id = $1),
+			(SELECT COUNT(*) FROM contacts WHERE user_integration_id = $1),
+			(SELECT COALESCE(MAX(seq), 0) FROM conversations WHERE user_integration_id = $1),
+			(SELECT COALESCE(MAX(m.seq), 0) FROM messages m JOIN conversations c ON m.conversation_id = c.id WHERE c.user_integration_id = $1),
+			(SELECT COALESCE(MAX(seq), 0) FROM contacts WHERE user_integration_id = $1)`
+
+	var stats IntegrationSyncStats
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&stats.Conversations,
+		&stats.Messages,
+		&stats.Contacts,
+		&stats.LatestConversationSeq,
+		&stats.LatestMessageSeq,
+		&stats.LatestContactSeq,
+	)
+	if err != nil {
+		return IntegrationSyncStats{}, fmt.Errorf("failed to get integration sync stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// ListIntegrationSyncStats summarizes the synced data of several integrations
+// in one query, keyed by integration ID
+func (r *integrationRepository) ListIntegrationSyncStats(ctx context.Context, ids []int32) (map[int32]IntegrationSyncStats, error) {
+	query := `
+		SELECT ui.id,
+			(SELECT COUNT(*) FROM conversations WHERE user_integration_id = ui.id),
+			(SELECT COUNT(*) FROM messages m JOIN conversations c ON m.conversation_id = c.id WHERE c.user_integration_id = ui.id),
+			(SELECT COUNT(*) FROM contacts WHERE user_integration_id = ui.id),
+			(SELECT COALESCE(MAX(seq), 0) FROM conversations WHERE user_integration_id = ui.id),
+			(SELECT COALESCE(MAX(m.seq), 0) FROM messages m JOIN conversations c ON m.conversation_id = c.id WHERE c.user_integration_id = ui.id),
+			(SELECT COALESCE(MAX(seq), 0) FROM contacts WHERE user_integration_id = ui.id)
+		FROM user_integrations ui
+		WHERE ui.id = ANY($1)`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list integration sync stats: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int32]IntegrationSyncStats, len(ids))
+	for rows.Next() {
+		var id int32
+		var stats IntegrationSyncStats
+		err := rows.Scan(
+			&id,
+			&stats.Conversations,
+			&stats.Messages,
+			&stats.Contacts,
+			&stats.LatestConversationSeq,
+			&stats.LatestMessageSeq,
+			&stats.LatestContactSeq,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan integration sync stats: %w", err)
+		}
+		result[id] = stats
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return result, nil
+}
@@ -12,28 +12,30 @@ import (
 // Generated types from sqlc (placeholder - will be replaced by actual generated types)
 
 type Event struct {
-	Seq           int64           `json:"seq"`
-	ID            uuid.UUID       `json:"id"`
-	Ts            time.Time       `json:"ts"`
-	Type          string          `json:"type"`
-	AccountID     string          `json:"account_id"`
-	DeviceID      sql.NullString  `json:"device_id"`
-	ConvoID       string          `json:"convo_id"`
-	WaMessageID   sql.NullString  `json:"wa_message_id"`
-	SenderJid     sql.NullString  `json:"sender_jid"`
-	Payload       json.RawMessage `json:"payload"`
-	AttachmentRef json.RawMessage `json:"attachment_ref"`
+	Seq               int64           `json:"seq"`
+	ID                uuid.UUID       `json:"id"`
+	Ts                time.Time       `json:"ts"`
+	Type              string          `json:"type"`
+	AccountID         string          `json:"account_id"`
+	DeviceID          sql.NullString  `json:"device_id"`
+	ConvoID           string          `json:"convo_id"`
+	WaMessageID       sql.NullString  `json:"wa_message_id"`
+	SenderJid         sql.NullString  `json:"sender_jid"`
+	Payload           json.RawMessage `json:"payload"`
+	AttachmentRef     json.RawMessage `json:"attachment_ref"`
+	UserIntegrationID sql.NullInt32   `json:"user_integration_id"`
 }
 
 type Outbox struct {
-	ClientMsgUuid uuid.UUID      `json:"client_msg_uuid"`
-	AccountID     string         `json:"account_id"`
-	ConvoID       string         `json:"convo_id"`
-	ServerMsgID   sql.NullInt64  `json:"server_msg_id"`
-	Status        string         `json:"status"`
-	LastError     sql.NullString `json:"last_error"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
+	ClientMsgUuid uuid.UUID       `json:"client_msg_uuid"`
+	AccountID     string          `json:"account_id"`
+	ConvoID       string          `json:"convo_id"`
+	ServerMsgID   sql.NullInt64   `json:"server_msg_id"`
+	Status        string          `json:"status"`
+	LastError     sql.NullString  `json:"last_error"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	Payload       json.RawMessage `json:"payload"` // The MessageOutPayload to send
 }
 
 type Account struct {
@@ -57,15 +59,16 @@ type MediaBlob struct {
 
 // Repository parameter types
 type InsertEventParams struct {
-	ID            uuid.UUID
-	Type          string
-	AccountID     string
-	DeviceID      sql.NullString
-	ConvoID       string
-	WaMessageID   sql.NullString
-	SenderJid     sql.NullString
-	Payload       json.RawMessage
-	AttachmentRef json.RawMessage
+	ID                uuid.UUID
+	Type              string
+	AccountID         string
+	DeviceID          sql.NullString
+	ConvoID           string
+	WaMessageID       sql.NullString
+	SenderJid         sql.NullString
+	Payload           json.RawMessage
+	AttachmentRef     json.RawMessage
+	UserIntegrationID sql.NullInt32
 }
 
 type InsertEventResult struct {
@@ -77,6 +80,18 @@ type GetEventsSinceParams struct {
 	AccountID string
 	Seq       int64
 	Limit     int32
+	Types     []string // Empty means all event types
+}
+
+// DeleteExpiredEventsParams selects events older than Cutoff. An empty Type
+// or AccountID matches every type or account.
+type DeleteExpiredEventsParams struct {
+	Type              string
+	AccountID         string
+	ExcludeAccountIDs []string // Accounts that have a retention window of their own
+	Cutoff            time.Time
+	BatchSize         int32
+	Archive           bool // Copy deleted events to events_archive
 }
 
 type CreateOutboxEntryParams struct {
@@ -85,6 +100,7 @@ type CreateOutboxEntryParams struct {
 	ConvoID       string
 	ServerMsgID   sql.NullInt64
 	Status        string
+	Payload       json.RawMessage
 }
 
 type CreateOutboxEntryResult struct {
@@ -98,6 +114,13 @@ type UpdateOutboxStatusParams struct {
 	LastError     sql.NullString
 }
 
+// OutboxStuckSummary describes unsent outbox entries that haven't changed
+// status since a cutoff
+type OutboxStuckSummary struct {
+	Count  int64
+	Oldest sql.NullTime // updated_at of the longest-stuck entry
+}
+
 type UpsertAccountParams struct {
 	ID          string
 	WaJid       sql.NullString
@@ -113,18 +136,45 @@ type UpdateAccountStatusParams struct {
 	LastSeen sql.NullTime
 }
 
+// Orders accounts can be listed in, most recent first
+const (
+	AccountSortCreatedAt = "created_at"
+	AccountSortLastSeen  = "last_seen"
+)
+
+// ListAccountsParams filters and pages an account listing. Empty filters
+// match every account.
 type ListAccountsParams struct {
+	Status string
+	Search string // Case-insensitive substring of the ID, WhatsApp JID or display name
+	SortBy string // AccountSortCreatedAt (the default) or AccountSortLastSeen
 	Limit  int32
 	Offset int32
 }
 
+// AccountIntegrationSummary is one of an account's integrations, as listed
+// alongside the account
+type AccountIntegrationSummary struct {
+	AccountID       string
+	ID              int32
+	IntegrationType string
+	ExternalID      string
+	Status          string
+	LastSeen        sql.NullTime
+	LastEventAt     sql.NullTime // When an event last came through the integration
+}
+
 // Repository interfaces
 type EventRepository interface {
 	InsertEvent(ctx context.Context, params InsertEventParams) (InsertEventResult, error)
 	GetEventsSince(ctx context.Context, params GetEventsSinceParams) ([]Event, error)
 	GetLatestEventSeq(ctx context.Context, accountID string) (int64, error)
 	GetEventByID(ctx context.Context, id uuid.UUID) (Event, error)
+	GetEventBySeq(ctx context.Context, seq int64) (Event, error)
 	CountEventsByAccount(ctx context.Context, accountID string) (int64, error)
+	DeleteExpiredEvents(ctx context.Context, params DeleteExpiredEventsParams) (int64, error)
+	CountExpiredEvents(ctx context.Context, params DeleteExpiredEventsParams) (int64, error)
+	GetLowWaterMark(ctx context.Context, accountID string) (int64, error)
 }
 
 type OutboxRepository interface {
@@ -134,6 +184,8 @@ type OutboxRepository interface {
 	GetOutboxEntry(ctx context.Context, clientMsgUuid uuid.UUID) (Outbox, error)
 	GetFailedOutboxEntries(ctx context.Context) ([]Outbox, error)
 	RetryOutboxEntry(ctx context.Context, clientMsgUuid uuid.UUID) error
+	CountOutboxByStatus(ctx context.Context) (map[string]int64, error)
+	GetStuckOutboxSummary(ctx context.Context, cutoff time.Time) (OutboxStuckSummary, error)
 }
 
 type AccountRepository interface {
@@ -142,14 +194,43 @@ type AccountRepository interface {
 	GetAccountByWAJID(ctx context.Context, waJid string) (Account, error)
 	UpdateAccountStatus(ctx context.Context, params UpdateAccountStatusParams) error
 	ListAccounts(ctx context.Context, params ListAccountsParams) ([]Account, error)
+	ListAccountIntegrations(ctx context.Context, accountIDs []string) ([]AccountIntegrationSummary, error)
 	GetConnectedAccounts(ctx context.Context) ([]Account, error)
+	GetAccountOwner(ctx context.Context, accountID string) (uuid.UUID, error)
 }
 
 type IntegrationRepository interface {
 	UpsertUserIntegration(ctx context.Context, params UpsertUserIntegrationParams) (UserIntegration, error)
-	GetUserIntegration(ctx context.Context, userID uuid.UUID, integrationType string) (UserIntegration, error)
+	GetUserIntegration(ctx context.Context, userID uuid.UUID, integrationType, externalID string) (UserIntegration, error)
+	GetUserIntegrations(ctx context.Context, userID uuid.UUID, integrationType string) ([]UserIntegration, error)
 	GetUserIntegrationByExternalID(ctx context.Context, integrationType, externalID string) (UserIntegration, error)
 	ListUserIntegrations(ctx context.Context, userID uuid.UUID) ([]UserIntegration, error)
-	UpdateUserIntegrationStatus(ctx context.Context, userID uuid.UUID, integrationType, status string, lastSeen sql.NullTime) error
-	DeleteUserIntegration(ctx context.Context, userID uuid.UUID, integrationType string) error
+	UpdateUserIntegrationStatus(ctx context.Context, params UpdateUserIntegrationStatusParams) error
+	DeleteUserIntegration(ctx context.Context, userID uuid.UUID, integrationType, externalID string) error
+	GetUserIntegrationByID(ctx context.Context, id int32) (UserIntegration, error)
+	ListIntegrations(ctx context.Context, params ListIntegrationsParams) ([]UserIntegration, error)
+	CountIntegrations(ctx context.Context, params ListIntegrationsParams) (int64, error)
+	GetIntegrationSyncStats(ctx context.Context, id int32) (IntegrationSyncStats, error)
+	ListIntegrationSyncStats(ctx context.Context, ids []int32) (map[int32]IntegrationSyncStats, error)
+	ListIntegrationStatusEvents(ctx context.Context, integrationID int32, limit int32) ([]IntegrationStatusEvent, error)
+}
+
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, params CreateWebhookParams) (Webhook, error)
+	GetWebhook(ctx context.Context, userID, id uuid.UUID) (Webhook, error)
+	ListWebhooks(ctx context.Context, userID uuid.UUID) ([]Webhook, error)
+	UpdateWebhook(ctx context.Context, params UpdateWebhookParams) (Webhook, error)
+	DeleteWebhook(ctx context.Context, userID, id uuid.UUID) error
+	ListEnabledWebhooks(ctx context.Context) ([]Webhook, error)
+	QueueWebhookDeliveries(ctx context.Context, params QueueWebhookDeliveriesParams) error
+	ClaimDueWebhookDeliveries(ctx context.Context, params ClaimWebhookDeliveriesParams) ([]DueWebhookDelivery, error)
+	RecordWebhookAttempt(ctx context.Context, params RecordWebhookAttemptParams) (bool, error)
+	ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, limit int32) ([]WebhookDelivery, error)
+	DeleteFinishedWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+type MessageRepository interface {
+	DeleteExpiredMessages(ctx context.Context, params DeleteExpiredMessagesParams) (ExpiredMessages, error)
+	ClearExpiredMutes(ctx context.Context, params ClearExpiredMutesParams) ([]ExpiredMute, error)
+	IsConversationMuted(ctx context.Context, userIntegrationID int32, externalConversationID string, now time.Time) (bool, error)
 }
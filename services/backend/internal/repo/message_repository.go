@@ -0,0 +1,313 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/tennex/pkg/events"
+)
+
+type messageRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewMessageRepository creates a new message repository
+func NewMessageRepository(db *pgxpool.Pool) MessageRepository {
+	return &messageRepository{db: db}
+}
+
+// DeleteExpiredMessagesParams selects disappearing messages that expired by Now
+type DeleteExpiredMessagesParams struct {
+	Now       time.Time
+	BatchSize int32
+}
+
+// ExpiredMessage is a disappearing message that was purged, with the
+// conversation and account it belonged to
+type ExpiredMessage struct {
+	UserID                 uuid.UUID
+	UserIntegrationID      int32
+	ExternalConversationID string
+	ExternalMessageID      string
+	ExpiresAt              time.Time
+}
+
+// ExpiredMessages is a batch of purged disappearing messages
+type ExpiredMessages struct {
+	Messages []ExpiredMessage
+
+	// Content hashes of the media files only the purged messages used. Their
+	// media_blobs rows are deleted; the files are left for the caller.
+	OrphanedMedia []string
+}
+
+// DeleteExpiredMessages deletes one batch of expired messages and takes them
+// off their conversations' counters. It returns the deleted messages, oldest
+// expiry first.
+//
+// The other copies of their content are purged with them:
+//   - their attachments' message_media rows, and the media_blobs of files no
+//     other message or avatar uses
+//   - the content of their events and archived events: msg_in and
+//     msg_out_sent events by WhatsApp message ID, and msg_out_pending events
+//     through the delivery receipts linking that ID to the outbox entry
+//   - the content of those outbox entries
+//
+// Webhook deliveries only reference events, so pending ones send the
+// redacted event. What's kept: event metadata (type, conversation, sender and
+// message ID), the IDs in replies to the messages, a msg_out_pending event
+// that never got a receipt until retention removes it, and whatever was
+// already delivered to clients and webhooks.
+func (r *messageRepository) DeleteExpiredMessages(ctx context.Context, params DeleteExpiredMessagesParams) (ExpiredMessages, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return ExpiredMessages{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Every statement in the query sees the rows as they were before it, so
+	// the attachments are read before the delete cascades to them
+	query := `
+		WITH deleted AS (
+			DELETE FROM messages
+			WHERE id IN (
+				SELECT id FROM messages
+				WHERE expires_at <= $1
+				ORDER BY expires_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, conversation_id, external_message_id, is_from_me, timestamp, expires_at
+		), counts AS (
+			UPDATE conversations c
+			SET total_message_count = GREATEST(c.total_message_count - d.total, 0),
+				unread_count = GREATEST(c.unread_count - d.unread, 0),
+				updated_at = NOW()
+			FROM (
+				SELECT d.conversation_id,
+					COUNT(*) AS total,
+					COUNT(*) FILTER (
+						WHERE NOT d.is_from_me
+						AND d.timestamp > COALESCE(cc.last_read_at, '-infinity')
+					) AS unread
+				FROM deleted d
+				JOIN conversations cc ON cc.id = d.conversation_id
+				GROUP BY d.conversation_id
+			) d
+			WHERE c.id = d.conversation_id
+		)
+		SELECT ui.user_id, c.user_integration_id, c.external_conversation_id, d.external_message_id, d.expires_at,
+			ARRAY(
+				SELECT mm.content_hash FROM message_media mm
+				WHERE mm.message_id = d.id AND mm.content_hash IS NOT NULL
+			)
+		FROM deleted d
+		JOIN conversations c ON c.id = d.conversation_id
+		JOIN user_integrations ui ON ui.id = c.user_integration_id
+		ORDER BY d.expires_at, d.external_message_id`
+
+	rows, err := tx.Query(ctx, query, params.Now, params.BatchSize)
+	if err != nil {
+		return ExpiredMessages{}, fmt.Errorf("failed to delete expired messages: %w", err)
+	}
+
+	var expired ExpiredMessages
+	var accountIDs, messageIDs, mediaHashes []string
+	for rows.Next() {
+		var message ExpiredMessage
+		var hashes []string
+		if err := rows.Scan(
+			&message.UserID,
+			&message.UserIntegrationID,
+			&message.ExternalConversationID,
+			&message.ExternalMessageID,
+			&message.ExpiresAt,
+			&hashes,
+		); err != nil {
+			rows.Close()
+			return ExpiredMessages{}, fmt.Errorf("failed to scan expired message: %w", err)
+		}
+		expired.Messages = append(expired.Messages, message)
+		accountIDs = append(accountIDs, message.UserID.String())
+		messageIDs = append(messageIDs, message.ExternalMessageID)
+		mediaHashes = append(mediaHashes, hashes...)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return ExpiredMessages{}, fmt.Errorf("rows error: %w", err)
+	}
+	if len(expired.Messages) == 0 {
+		return expired, nil
+	}
+
+	if err := redactExpiredMessageEvents(ctx, tx, accountIDs, messageIDs); err != nil {
+		return ExpiredMessages{}, err
+	}
+
+	if len(mediaHashes) > 0 {
+		rows, err := tx.Query(ctx, `
+			DELETE FROM media_blobs b
+			WHERE b.content_hash = ANY($1::text[])
+			AND NOT EXISTS (SELECT 1 FROM message_media mm WHERE mm.content_hash = b.content_hash)
+			AND NOT EXISTS (SELECT 1 FROM contacts c WHERE c.avatar_url = $2 || b.content_hash)
+			AND NOT EXISTS (SELECT 1 FROM conversations cv WHERE cv.avatar_url = $2 || b.content_hash)
+			RETURNING b.content_hash`,
+			mediaHashes, events.AvatarURLPrefix)
+		if err != nil {
+			return ExpiredMessages{}, fmt.Errorf("failed to delete orphaned media: %w", err)
+		}
+		expired.OrphanedMedia, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return ExpiredMessages{}, fmt.Errorf("failed to delete orphaned media: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return ExpiredMessages{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return expired, nil
+}
+
+// redactExpiredMessageEvents empties the content of the events, archived
+// events and outbox entries of expired messages, given as pairs of account
+// and WhatsApp message IDs
+func redactExpiredMessageEvents(ctx context.Context, tx pgx.Tx, accountIDs, messageIDs []string) error {
+	_, err := tx.Exec(ctx, `
+		WITH expired AS (
+			SELECT * FROM unnest($1::text[], $2::text[]) AS x(account_id, wa_message_id)
+		), receipts AS (
+			SELECT r.payload->>'client_msg_uuid' AS client_msg_uuid
+			FROM expired x
+			JOIN events r ON r.account_id = x.account_id AND r.wa_message_id = x.wa_message_id
+			WHERE r.type = 'msg_delivery'
+			UNION
+			SELECT r.payload->>'client_msg_uuid'
+			FROM expired x
+			JOIN events_archive r ON r.account_id = x.account_id AND r.wa_message_id = x.wa_message_id
+			WHERE r.type = 'msg_delivery'
+		), entries AS (
+			UPDATE outbox o
+			SET payload = jsonb_set(o.payload, '{content}', '{}'::jsonb),
+				updated_at = NOW()
+			FROM receipts r
+			WHERE r.client_msg_uuid ~ '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'
+			AND o.client_msg_uuid = r.client_msg_uuid::uuid
+			RETURNING o.server_msg_id
+		), redacted AS (
+			UPDATE events e
+			SET payload = jsonb_set(e.payload, '{content}', '{}'::jsonb), attachment_ref = NULL
+			WHERE e.type IN ('msg_in', 'msg_out_pending', 'msg_out_sent')
+			AND (
+				e.seq IN (SELECT server_msg_id FROM entries)
+				OR (e.account_id, e.wa_message_id) IN (SELECT account_id, wa_message_id FROM expired)
+			)
+			RETURNING e.seq
+		)
+		UPDATE events_archive e
+		SET payload = jsonb_set(e.payload, '{content}', '{}'::jsonb), attachment_ref = NULL
+		WHERE e.type IN ('msg_in', 'msg_out_pending', 'msg_out_sent')
+		AND (
+			e.seq IN (SELECT server_msg_id FROM entries)
+			OR (e.account_id, e.wa_message_id) IN (SELECT account_id, wa_message_id FROM expired)
+		)`,
+		accountIDs, messageIDs)
+	if err != nil {
+		return fmt.Errorf("failed to redact expired message events: %w", err)
+	}
+	return nil
+}
+
+// ClearExpiredMutesParams selects mutes that expired by Now
+type ClearExpiredMutesParams struct {
+	Now       time.Time
+	BatchSize int32
+}
+
+// ExpiredMute is a conversation whose mute expired and was lifted
+type ExpiredMute struct {
+	UserID                 uuid.UUID
+	UserIntegrationID      int32
+	ExternalConversationID string
+	MuteUntil              time.Time
+}
+
+// ClearExpiredMutes unmutes one batch of conversations whose mute expired. A
+// lifted mute counts as a state change at its expiry, so an older mute
+// echoed back by the phone doesn't restore it. It returns the unmuted
+// conversations, earliest expiry first.
+func (r *messageRepository) ClearExpiredMutes(ctx context.Context, params ClearExpiredMutesParams) ([]ExpiredMute, error) {
+	query := `
+		WITH unmuted AS (
+			UPDATE conversations c
+			SET is_muted = FALSE,
+				mute_until = NULL,
+				state_changed_at = GREATEST(c.state_changed_at, e.mute_until),
+				updated_at = NOW()
+			FROM (
+				SELECT id, mute_until FROM conversations
+				WHERE is_muted AND mute_until <= $1
+				ORDER BY mute_until
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			) e
+			WHERE c.id = e.id
+			RETURNING c.user_integration_id, c.external_conversation_id, e.mute_until
+		)
+		SELECT ui.user_id, u.user_integration_id, u.external_conversation_id, u.mute_until
+		FROM unmuted u
+		JOIN user_integrations ui ON ui.id = u.user_integration_id
+		ORDER BY u.mute_until, u.external_conversation_id`
+
+	rows, err := r.db.Query(ctx, query, params.Now, params.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear expired mutes: %w", err)
+	}
+	defer rows.Close()
+
+	var expired []ExpiredMute
+	for rows.Next() {
+		var mute ExpiredMute
+		if err := rows.Scan(
+			&mute.UserID,
+			&mute.UserIntegrationID,
+			&mute.ExternalConversationID,
+			&mute.MuteUntil,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan expired mute: %w", err)
+		}
+		expired = append(expired, mute)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return expired, nil
+}
+
+// IsConversationMuted reports whether a conversation is muted at now. A mute
+// whose expiry has passed no longer counts, even before the expiry worker
+// lifts it.
+func (r *messageRepository) IsConversationMuted(ctx context.Context, userIntegrationID int32, externalConversationID string, now time.Time) (bool, error) {
+	query := `
+		SELECT is_muted AND (mute_until IS NULL OR mute_until > $3)
+		FROM conversations
+		WHERE user_integration_id = $1 AND external_conversation_id = $2`
+
+	var muted bool
+	err := r.db.QueryRow(ctx, query, userIntegrationID, externalConversationID, now).Scan(&muted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check conversation mute: %w", err)
+	}
+
+	return muted, nil
+}
@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -20,8 +21,8 @@ func NewOutboxRepository(db *pgxpool.Pool) OutboxRepository {
 
 func (r *outboxRepository) CreateOutboxEntry(ctx context.Context, params CreateOutboxEntryParams) (CreateOutboxEntryResult, error) {
 	query := `
-		INSERT INTO outbox (client_msg_uuid, account_id, convo_id, server_msg_id, status)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO outbox (client_msg_uuid, account_id, convo_id, server_msg_id, status, payload)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		ON CONFLICT (client_msg_uuid) DO NOTHING
 		RETURNING client_msg_uuid, created_at`
 
@@ -32,6 +33,7 @@ func (r *outboxRepository) CreateOutboxEntry(ctx context.Context, params CreateO
 		params.ConvoID,
 		params.ServerMsgID,
 		params.Status,
+		params.Payload,
 	).Scan(&result.ClientMsgUuid, &result.CreatedAt)
 
 	if err != nil {
@@ -47,7 +49,7 @@ func (r *outboxRepository) CreateOutboxEntry(ctx context.Context, params CreateO
 
 func (r *outboxRepository) GetPendingOutboxEntries(ctx context.Context, limit int32) ([]Outbox, error) {
 	query := `
-		SELECT client_msg_uuid, account_id, convo_id, server_msg_id, status, last_error, created_at, updated_at
+		SELECT client_msg_uuid, account_id, convo_id, server_msg_id, status, last_error, created_at, updated_at, payload
 		FROM outbox 
 		WHERE status IN ('queued', 'retry')
 		ORDER BY created_at ASC
@@ -71,6 +73,7 @@ func (r *outboxRepository) GetPendingOutboxEntries(ctx context.Context, limit in
 			&entry.LastError,
 			&entry.CreatedAt,
 			&entry.UpdatedAt,
+			&entry.Payload,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan outbox entry: %w", err)
@@ -89,7 +92,7 @@ func (r *outboxRepository) UpdateOutboxStatus(ctx context.Context, params Update
 	query := `
 		UPDATE outbox 
 		SET status = $2, last_error = $3, updated_at = NOW()
-		WHERE client_msg_uuid = $1`
+		WHERE client_msg_uuid = $1 AND status NOT IN ('delivered', 'read')`
 
 	result, err := r.db.Exec(ctx, query,
 		params.ClientMsgUuid,
@@ -101,6 +104,11 @@ func (r *outboxRepository) UpdateOutboxStatus(ctx context.Context, params Update
 	}
 
 	if result.RowsAffected() == 0 {
+		// A delivered or read entry was sent, whatever a late send result
+		// says, so it's left as it is
+		if _, err := r.GetOutboxEntry(ctx, params.ClientMsgUuid); err == nil {
+			return nil
+		}
 		return fmt.Errorf("outbox entry not found: %s", params.ClientMsgUuid)
 	}
 
@@ -109,7 +117,7 @@ func (r *outboxRepository) UpdateOutboxStatus(ctx context.Context, params Update
 
 func (r *outboxRepository) GetOutboxEntry(ctx context.Context, clientMsgUuid uuid.UUID) (Outbox, error) {
 	query := `
-		SELECT client_msg_uuid, account_id, convo_id, server_msg_id, status, last_error, created_at, updated_at
+		SELECT client_msg_uuid, account_id, convo_id, server_msg_id, status, last_error, created_at, updated_at, payload
 		FROM outbox 
 		WHERE client_msg_uuid = $1`
 
@@ -123,6 +131,7 @@ func (r *outboxRepository) GetOutboxEntry(ctx context.Context, clientMsgUuid uui
 		&entry.LastError,
 		&entry.CreatedAt,
 		&entry.UpdatedAt,
+		&entry.Payload,
 	)
 	if err != nil {
 		return Outbox{}, fmt.Errorf("failed to get outbox entry: %w", err)
@@ -133,7 +142,7 @@ func (r *outboxRepository) GetOutboxEntry(ctx context.Context, clientMsgUuid uui
 
 func (r *outboxRepository) GetFailedOutboxEntries(ctx context.Context) ([]Outbox, error) {
 	query := `
-		SELECT client_msg_uuid, account_id, convo_id, server_msg_id, status, last_error, created_at, updated_at
+		SELECT client_msg_uuid, account_id, convo_id, server_msg_id, status, last_error, created_at, updated_at, payload
 		FROM outbox 
 		WHERE status = 'failed' AND created_at > NOW() - INTERVAL '24 hours'
 		ORDER BY created_at DESC`
@@ -156,6 +165,7 @@ func (r *outboxRepository) GetFailedOutboxEntries(ctx context.Context) ([]Outbox
 			&entry.LastError,
 			&entry.CreatedAt,
 			&entry.UpdatedAt,
+			&entry.Payload,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan outbox entry: %w", err)
@@ -187,3 +197,47 @@ func (r *outboxRepository) RetryOutboxEntry(ctx context.Context, clientMsgUuid u
 
 	return nil
 }
+
+func (r *outboxRepository) CountOutboxByStatus(ctx context.Context) (map[string]int64, error) {
+	query := `
+		SELECT status, COUNT(*)
+		FROM outbox
+		GROUP BY status`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count outbox entries: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var status string
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox count: %w", err)
+		}
+		counts[status] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (r *outboxRepository) GetStuckOutboxSummary(ctx context.Context, cutoff time.Time) (OutboxStuckSummary, error) {
+	query := `
+		SELECT COUNT(*), MIN(updated_at)
+		FROM outbox
+		WHERE status IN ('queued', 'retry', 'sending') AND updated_at < $1`
+
+	var summary OutboxStuckSummary
+	err := r.db.QueryRow(ctx, query, cutoff).Scan(&summary.Count, &summary.Oldest)
+	if err != nil {
+		return OutboxStuckSummary{}, fmt.Errorf("failed to summarize stuck outbox entries: %w", err)
+	}
+
+	return summary, nil
+}
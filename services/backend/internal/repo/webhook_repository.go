@@ -0,0 +1,505 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type webhookRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *pgxpool.Pool) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+// Webhook is a user's subscription to receive events over HTTP
+type Webhook struct {
+	ID                  uuid.UUID    `json:"id"`
+	UserID              uuid.UUID    `json:"user_id"`
+	URL                 string       `json:"url"`
+	Secret              string       `json:"-"`
+	EventTypes          []string     `json:"event_types"`
+	Enabled             bool         `json:"enabled"`
+	LastEventSeq        int64        `json:"last_event_seq"`
+	ConsecutiveFailures int32        `json:"consecutive_failures"`
+	DisabledAt          sql.NullTime `json:"disabled_at"`
+	CreatedAt           time.Time    `json:"created_at"`
+	UpdatedAt           time.Time    `json:"updated_at"`
+}
+
+// WebhookDelivery is one event queued for delivery to a webhook
+type WebhookDelivery struct {
+	ID             int64          `json:"id"`
+	WebhookID      uuid.UUID      `json:"webhook_id"`
+	EventSeq       int64          `json:"event_seq"`
+	EventType      string         `json:"event_type"`
+	Status         string         `json:"status"`
+	Attempts       int32          `json:"attempts"`
+	NextAttemptAt  time.Time      `json:"next_attempt_at"`
+	LastStatusCode sql.NullInt32  `json:"last_status_code"`
+	LastError      sql.NullString `json:"last_error"`
+	DeliveredAt    sql.NullTime   `json:"delivered_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// DueWebhookDelivery is a claimed delivery together with where to send it
+// and the event it delivers. Deliveries only reference their event, so
+// EventFound is false once retention or message expiry removed it.
+type DueWebhookDelivery struct {
+	WebhookDelivery
+	URL        string
+	Secret     string
+	Event      Event
+	EventFound bool
+}
+
+// CreateWebhookParams holds parameters for creating a webhook
+type CreateWebhookParams struct {
+	UserID       uuid.UUID
+	URL          string
+	Secret       string
+	EventTypes   []string
+	LastEventSeq int64 // Events up to this seq are not delivered
+}
+
+// UpdateWebhookParams replaces a webhook's settings. Re-enabling a webhook
+// clears its failure count.
+type UpdateWebhookParams struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	URL        string
+	Secret     string
+	EventTypes []string
+	Enabled    bool
+}
+
+// NewWebhookDelivery is an event to queue for a webhook
+type NewWebhookDelivery struct {
+	EventSeq  int64
+	EventType string
+}
+
+// QueueWebhookDeliveriesParams queues deliveries and advances the webhook's
+// event cursor to LastEventSeq
+type QueueWebhookDeliveriesParams struct {
+	WebhookID    uuid.UUID
+	Deliveries   []NewWebhookDelivery
+	LastEventSeq int64
+}
+
+// ClaimWebhookDeliveriesParams selects pending deliveries due at Now and
+// holds them until LeaseUntil, so they aren't sent twice while in flight
+type ClaimWebhookDeliveriesParams struct {
+	Now        time.Time
+	LeaseUntil time.Time
+	Limit      int32
+}
+
+// RecordWebhookAttemptParams records the outcome of a delivery attempt.
+// RecordWebhookAttempt reports whether the attempt disabled the webhook.
+type RecordWebhookAttemptParams struct {
+	DeliveryID    int64
+	WebhookID     uuid.UUID
+	Status        string // Delivery status after the attempt
+	Succeeded     bool
+	StatusCode    sql.NullInt32
+	Error         sql.NullString
+	NextAttemptAt time.Time
+	// Disable the webhook once this many attempts in a row have failed
+	DisableAfter int32
+	// Nothing was sent, as the event was gone; the webhook's failure count
+	// is left alone
+	Skipped bool
+}
+
+const webhookColumns = `id, user_id, url, secret, event_types, enabled, last_event_seq, consecutive_failures, disabled_at, created_at, updated_at`
+
+func scanWebhook(row pgx.Row) (Webhook, error) {
+	var webhook Webhook
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		&webhook.Secret,
+		&webhook.EventTypes,
+		&webhook.Enabled,
+		&webhook.LastEventSeq,
+		&webhook.ConsecutiveFailures,
+		&webhook.DisabledAt,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+	return webhook, err
+}
+
+func collectWebhooks(rows pgx.Rows) ([]Webhook, error) {
+	defer rows.Close()
+
+	var webhooks []Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (r *webhookRepository) CreateWebhook(ctx context.Context, params CreateWebhookParams) (Webhook, error) {
+	query := `
+		INSERT INTO webhooks (user_id, url, secret, event_types, last_event_seq)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + webhookColumns
+
+	webhook, err := scanWebhook(r.db.QueryRow(ctx, query,
+		params.UserID,
+		params.URL,
+		params.Secret,
+		params.EventTypes,
+		params.LastEventSeq,
+	))
+	if err != nil {
+		return Webhook{}, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (r *webhookRepository) GetWebhook(ctx context.Context, userID, id uuid.UUID) (Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1 AND user_id = $2`
+
+	webhook, err := scanWebhook(r.db.QueryRow(ctx, query, id, userID))
+	if err != nil {
+		return Webhook{}, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (r *webhookRepository) ListWebhooks(ctx context.Context, userID uuid.UUID) ([]Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+
+	return collectWebhooks(rows)
+}
+
+func (r *webhookRepository) UpdateWebhook(ctx context.Context, params UpdateWebhookParams) (Webhook, error) {
+	query := `
+		UPDATE webhooks
+		SET url = $3,
+			secret = $4,
+			event_types = $5,
+			consecutive_failures = CASE WHEN $6 AND NOT enabled THEN 0 ELSE consecutive_failures END,
+			disabled_at = CASE WHEN $6 THEN NULL ELSE COALESCE(disabled_at, NOW()) END,
+			enabled = $6,
+			updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + webhookColumns
+
+	webhook, err := scanWebhook(r.db.QueryRow(ctx, query,
+		params.ID,
+		params.UserID,
+		params.URL,
+		params.Secret,
+		params.EventTypes,
+		params.Enabled,
+	))
+	if err != nil {
+		return Webhook{}, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (r *webhookRepository) DeleteWebhook(ctx context.Context, userID, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("webhook %s not found: %w", id, pgx.ErrNoRows)
+	}
+
+	return nil
+}
+
+func (r *webhookRepository) ListEnabledWebhooks(ctx context.Context) ([]Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE enabled ORDER BY id`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query enabled webhooks: %w", err)
+	}
+
+	return collectWebhooks(rows)
+}
+
+func (r *webhookRepository) QueueWebhookDeliveries(ctx context.Context, params QueueWebhookDeliveriesParams) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, delivery := range params.Deliveries {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO webhook_deliveries (webhook_id, event_seq, event_type)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (webhook_id, event_seq) DO NOTHING`,
+			params.WebhookID,
+			delivery.EventSeq,
+			delivery.EventType,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE webhooks
+		SET last_event_seq = GREATEST(last_event_seq, $2)
+		WHERE id = $1`,
+		params.WebhookID,
+		params.LastEventSeq,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to advance webhook cursor: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit webhook deliveries: %w", err)
+	}
+
+	return nil
+}
+
+func (r *webhookRepository) ClaimDueWebhookDeliveries(ctx context.Context, params ClaimWebhookDeliveriesParams) ([]DueWebhookDelivery, error) {
+	query := `
+		WITH due AS (
+			SELECT d.id
+			FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= $1 AND w.enabled
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT $3
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		), claimed AS (
+			UPDATE webhook_deliveries d
+			SET next_attempt_at = $2
+			FROM due
+			WHERE d.id = due.id
+			RETURNING d.*
+		)
+		SELECT c.id, c.webhook_id, c.event_seq, c.event_type, c.status, c.attempts,
+			c.next_attempt_at, c.last_status_code, c.last_error, c.delivered_at, c.created_at, c.updated_at,
+			w.url, w.secret, e.id, e.ts, e.account_id, e.convo_id, e.payload
+		FROM claimed c
+		JOIN webhooks w ON w.id = c.webhook_id
+		LEFT JOIN events e ON e.seq = c.event_seq`
+
+	rows, err := r.db.Query(ctx, query, params.Now, params.LeaseUntil, params.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []DueWebhookDelivery
+	for rows.Next() {
+		var delivery DueWebhookDelivery
+		var eventID uuid.NullUUID
+		var eventTs sql.NullTime
+		var accountID, convoID sql.NullString
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.EventSeq,
+			&delivery.EventType,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastStatusCode,
+			&delivery.LastError,
+			&delivery.DeliveredAt,
+			&delivery.CreatedAt,
+			&delivery.UpdatedAt,
+			&delivery.URL,
+			&delivery.Secret,
+			&eventID,
+			&eventTs,
+			&accountID,
+			&convoID,
+			&delivery.Event.Payload,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		if delivery.EventFound = eventID.Valid; delivery.EventFound {
+			delivery.Event.Seq = delivery.EventSeq
+			delivery.Event.ID = eventID.UUID
+			delivery.Event.Ts = eventTs.Time
+			delivery.Event.Type = delivery.EventType
+			delivery.Event.AccountID = accountID.String
+			delivery.Event.ConvoID = convoID.String
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *webhookRepository) RecordWebhookAttempt(ctx context.Context, params RecordWebhookAttemptParams) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2,
+			attempts = attempts + 1,
+			next_attempt_at = $3,
+			last_status_code = $4,
+			last_error = $5,
+			delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() ELSE delivered_at END,
+			updated_at = NOW()
+		WHERE id = $1`,
+		params.DeliveryID,
+		params.Status,
+		params.NextAttemptAt,
+		params.StatusCode,
+		params.Error,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, fmt.Errorf("webhook delivery %d not found", params.DeliveryID)
+	}
+
+	if params.Skipped {
+		if err := tx.Commit(ctx); err != nil {
+			return false, fmt.Errorf("failed to commit webhook attempt: %w", err)
+		}
+		return false, nil
+	}
+
+	// Any success resets the failure count; a failure counts towards
+	// disabling the webhook
+	var disabled bool
+	err = tx.QueryRow(ctx, `
+		WITH old AS (
+			SELECT enabled FROM webhooks WHERE id = $1 FOR UPDATE
+		)
+		UPDATE webhooks w
+		SET consecutive_failures = CASE WHEN $2 THEN 0 ELSE w.consecutive_failures + 1 END,
+			enabled = w.enabled AND ($2 OR w.consecutive_failures + 1 < $3),
+			disabled_at = CASE
+				WHEN w.enabled AND NOT $2 AND w.consecutive_failures + 1 >= $3 THEN NOW()
+				ELSE w.disabled_at
+			END,
+			updated_at = NOW()
+		FROM old
+		WHERE w.id = $1
+		RETURNING old.enabled AND NOT w.enabled`,
+		params.WebhookID,
+		params.Succeeded,
+		params.DisableAfter,
+	).Scan(&disabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, fmt.Errorf("webhook %s not found", params.WebhookID)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update webhook failures: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit webhook attempt: %w", err)
+	}
+
+	return disabled, nil
+}
+
+func (r *webhookRepository) ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, limit int32) ([]WebhookDelivery, error) {
+	query := `
+		SELECT id, webhook_id, event_seq, event_type, status, attempts,
+			next_attempt_at, last_status_code, last_error, delivered_at, created_at, updated_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY id DESC
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var delivery WebhookDelivery
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.EventSeq,
+			&delivery.EventType,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastStatusCode,
+			&delivery.LastError,
+			&delivery.DeliveredAt,
+			&delivery.CreatedAt,
+			&delivery.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *webhookRepository) DeleteFinishedWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Exec(ctx, `
+		DELETE FROM webhook_deliveries
+		WHERE status <> 'pending' AND updated_at < $1`,
+		before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete finished webhook deliveries: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
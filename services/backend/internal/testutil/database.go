@@ -0,0 +1,59 @@
+// Package testutil provides helpers shared by backend tests
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/tennex/pkg/db"
+)
+
+// SetupTestDB applies the schema migrations to a fresh Postgres schema.
+// Set TENNEX_TEST_DATABASE_URL to run tests that need a database.
+func SetupTestDB(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+
+	databaseURL := os.Getenv("TENNEX_TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TENNEX_TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+
+	admin, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+		admin.Close(context.Background())
+	})
+
+	poolConfig, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		t.Fatalf("invalid database URL: %v", err)
+	}
+	poolConfig.ConnConfig.RuntimeParams["search_path"] = schema
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	if _, err := db.MigrateUp(ctx, pool); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	return pool
+}
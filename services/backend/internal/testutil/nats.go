@@ -0,0 +1,28 @@
+package testutil
+
+import (
+	"os"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+// SetupTestNATS connects to the NATS server at TENNEX_TEST_NATS_URL, which
+// needs JetStream enabled for the outbox queue tests. Tests share the server,
+// so they should publish under a subject prefix of their own.
+func SetupTestNATS(t testing.TB) *nats.Conn {
+	t.Helper()
+
+	natsURL := os.Getenv("TENNEX_TEST_NATS_URL")
+	if natsURL == "" {
+		t.Skip("TENNEX_TEST_NATS_URL not set")
+	}
+
+	nc, err := nats.Connect(natsURL)
+	if err != nil {
+		t.Fatalf("failed to connect to NATS: %v", err)
+	}
+	t.Cleanup(nc.Close)
+
+	return nc
+}
@@ -17,6 +17,7 @@ import (
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-chi/chi/v5"
+	"github.com/oapi-codegen/runtime"
 	openapi_types "github.com/oapi-codegen/runtime/types"
 )
 
@@ -31,6 +32,15 @@ const (
 	Whatsapp ConnectionPlatform = "whatsapp"
 )
 
+// Defines values for PairingEventType.
+const (
+	PairingCode     PairingEventType = "code"
+	PairingError    PairingEventType = "error"
+	PairingRejected PairingEventType = "rejected"
+	PairingSuccess  PairingEventType = "success"
+	PairingTimeout  PairingEventType = "timeout"
+)
+
 // Connection defines model for Connection.
 type Connection struct {
 	// AvatarUrl Profile picture URL
@@ -95,6 +105,33 @@ type HealthResponse struct {
 	Version   *string   `json:"version,omitempty"`
 }
 
+// LoadOlderHistoryRequest defines model for LoadOlderHistoryRequest.
+type LoadOlderHistoryRequest struct {
+	// Count Maximum number of older messages to request
+	Count *int `json:"count,omitempty"`
+}
+
+// PairingEvent defines model for PairingEvent.
+type PairingEvent struct {
+	// Code QR code data, for code events
+	Code *string `json:"code,omitempty"`
+
+	// Error Why pairing failed, for rejected and error events
+	Error *string `json:"error,omitempty"`
+
+	// ExpiresAt When the QR code expires, for code events
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Jid Linked WhatsApp account, for success events
+	Jid *string `json:"jid,omitempty"`
+
+	// Type code carries a QR code that replaces the previous one; every other type ends the stream
+	Type PairingEventType `json:"type"`
+}
+
+// PairingEventType code carries a QR code that replaces the previous one; every other type ends the stream
+type PairingEventType string
+
 // SuccessResponse defines model for SuccessResponse.
 type SuccessResponse struct {
 	Message   string     `json:"message"`
@@ -102,6 +139,12 @@ type SuccessResponse struct {
 	Timestamp *time.Time `json:"timestamp,omitempty"`
 }
 
+// WhatsAppConnectRequest defines model for WhatsAppConnectRequest.
+type WhatsAppConnectRequest struct {
+	// FullSync Ask the phone for the complete message history instead of only recent messages
+	FullSync *bool `json:"full_sync,omitempty"`
+}
+
 // WhatsAppConnectResponse defines model for WhatsAppConnectResponse.
 type WhatsAppConnectResponse struct {
 	// ExpiresAt When the QR code expires
@@ -113,7 +156,7 @@ type WhatsAppConnectResponse struct {
 	// QrCode QR code data for WhatsApp connection
 	QrCode string `json:"qr_code"`
 
-	// SessionId Unique session identifier for this connection attempt
+	// SessionId Pairing session ID, for streaming later QR codes and the outcome from /whatsapp/pairing/{session_id}/ws
 	SessionId openapi_types.UUID `json:"session_id"`
 }
 
@@ -141,6 +184,18 @@ type WhatsAppStatusResponse struct {
 	WhatsappJid *string `json:"whatsapp_jid,omitempty"`
 }
 
+// StreamPairingParams defines parameters for StreamPairing.
+type StreamPairingParams struct {
+	// Token JWT, when the Authorization header can't be set
+	Token *string `form:"token,omitempty" json:"token,omitempty"`
+}
+
+// ConnectWhatsAppJSONRequestBody defines body for ConnectWhatsApp for application/json ContentType.
+type ConnectWhatsAppJSONRequestBody = WhatsAppConnectRequest
+
+// LoadOlderHistoryJSONRequestBody defines body for LoadOlderHistory for application/json ContentType.
+type LoadOlderHistoryJSONRequestBody = LoadOlderHistoryRequest
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
 	// List all user's messaging platform connections
@@ -152,9 +207,18 @@ type ServerInterface interface {
 	// Connect WhatsApp account
 	// (POST /whatsapp/connect)
 	ConnectWhatsApp(w http.ResponseWriter, r *http.Request)
+	// Load older conversation history
+	// (POST /whatsapp/conversations/{external_id}/load-older)
+	LoadOlderHistory(w http.ResponseWriter, r *http.Request, externalId string)
 	// Disconnect WhatsApp account
 	// (POST /whatsapp/disconnect)
 	DisconnectWhatsApp(w http.ResponseWriter, r *http.Request)
+	// Stream QR pairing progress
+	// (GET /whatsapp/pairing/{session_id}/ws)
+	StreamPairing(w http.ResponseWriter, r *http.Request, sessionId openapi_types.UUID, params StreamPairingParams)
+	// Resync the complete message history
+	// (POST /whatsapp/resync)
+	ResyncHistory(w http.ResponseWriter, r *http.Request)
 	// Get WhatsApp connection status
 	// (GET /whatsapp/status)
 	GetWhatsAppStatus(w http.ResponseWriter, r *http.Request)
@@ -182,12 +246,30 @@ func (_ Unimplemented) ConnectWhatsApp(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Load older conversation history
+// (POST /whatsapp/conversations/{external_id}/load-older)
+func (_ Unimplemented) LoadOlderHistory(w http.ResponseWriter, r *http.Request, externalId string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Disconnect WhatsApp account
 // (POST /whatsapp/disconnect)
 func (_ Unimplemented) DisconnectWhatsApp(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Stream QR pairing progress
+// (GET /whatsapp/pairing/{session_id}/ws)
+func (_ Unimplemented) StreamPairing(w http.ResponseWriter, r *http.Request, sessionId openapi_types.UUID, params StreamPairingParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Resync the complete message history
+// (POST /whatsapp/resync)
+func (_ Unimplemented) ResyncHistory(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Get WhatsApp connection status
 // (GET /whatsapp/status)
 func (_ Unimplemented) GetWhatsAppStatus(w http.ResponseWriter, r *http.Request) {
@@ -257,6 +339,37 @@ func (siw *ServerInterfaceWrapper) ConnectWhatsApp(w http.ResponseWriter, r *htt
 	handler.ServeHTTP(w, r)
 }
 
+// LoadOlderHistory operation middleware
+func (siw *ServerInterfaceWrapper) LoadOlderHistory(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "external_id" -------------
+	var externalId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "external_id", chi.URLParam(r, "external_id"), &externalId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "external_id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.LoadOlderHistory(w, r, externalId)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 // DisconnectWhatsApp operation middleware
 func (siw *ServerInterfaceWrapper) DisconnectWhatsApp(w http.ResponseWriter, r *http.Request) {
 
@@ -277,6 +390,68 @@ func (siw *ServerInterfaceWrapper) DisconnectWhatsApp(w http.ResponseWriter, r *
 	handler.ServeHTTP(w, r)
 }
 
+// StreamPairing operation middleware
+func (siw *ServerInterfaceWrapper) StreamPairing(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "session_id" -------------
+	var sessionId openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithOptions("simple", "session_id", chi.URLParam(r, "session_id"), &sessionId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "session_id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params StreamPairingParams
+
+	// ------------- Optional query parameter "token" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "token", r.URL.Query(), &params.Token)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "token", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.StreamPairing(w, r, sessionId, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ResyncHistory operation middleware
+func (siw *ServerInterfaceWrapper) ResyncHistory(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ResyncHistory(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 // GetWhatsAppStatus operation middleware
 func (siw *ServerInterfaceWrapper) GetWhatsAppStatus(w http.ResponseWriter, r *http.Request) {
 
@@ -419,9 +594,18 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/whatsapp/connect", wrapper.ConnectWhatsApp)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/whatsapp/conversations/{external_id}/load-older", wrapper.LoadOlderHistory)
+	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/whatsapp/disconnect", wrapper.DisconnectWhatsApp)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/whatsapp/pairing/{session_id}/ws", wrapper.StreamPairing)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/whatsapp/resync", wrapper.ResyncHistory)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/whatsapp/status", wrapper.GetWhatsAppStatus)
 	})
@@ -431,30 +615,51 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
-
-	"H4sIAAAAAAAC/8xYW3MTNxT+Kxq1M33Z2Oba1k8EUiAMnQIJk3YyHo+sPfYKtJKQzgZMxv+9I+19LTsu",
-	"EMpbNpI+nfOd2ydfU65zoxUodHR6TR3PIGfhzydaKeAotPJfxmoDFgWENXbFkNl5YaX/SsFxK0y5lb6y",
-	"eikkECM4FhbI2zcvaUJxbYBOqUMr1IpuEspLeEi3EdqbiUOGhWvPL7SWwFQPYM5wG+MiA0V4C/SROQIO",
-	"2UIKl0FKE7rUNvcnacoQjlDkEDMzFc5Itp4rlsP2LSflKvGrRCuCGRAjGXrsGJpkDucOQG1DvWQOCeMo",
-	"rgSuiTfHIcvNwYbmgCxlyEJ40lR4WCZfdcKGtoBkGKzK2CNngIul4KTBae7Qi3fA0d/RuLZl/Z/gHFsJ",
-	"tWrcD5zQhIIqcjq9pB8zho4Z7xCChJVlnqFUOK5tSmcRj2qkeeHAzkUkUd46sESkoFAsBdg6AvmWMTHC",
-	"9qOenhDMhOum0AKkVitHUHeDUhQi3YbfJNTCh0JYn9+XtGNHm/etCbMI120RuDfgjFYOtuuwtS58CoQ8",
-	"/PGzhSWd0p/GbXWPq9Ied+p609zLrGXr8K2RRYr63P+bqCJfeJqXpHtxAyIUwgrsgeSCgy5MRe+XsNs3",
-	"Zh+pf1ir7T4600iRh0MkrMUaBCAT0m0fO26KkEBAqHdG7Aobdt1cpnP08rZNTK8P6hMD5sp7uzgx1p4D",
-	"k5jtpq1q0tNrCp9YbmQ4/f6b2JvQK7CumkEt+p3RZDS50bt2eOx176zgHNyeKqsD0DPhLwOWhb7ga0wC",
-	"QkpcibQspFzHfKnWe0BlV94eb18b2vqupDE/5vuF78rHxlRNYTcH8MkIC273rPWN9/WbUCak2nzw7BLK",
-	"oS3aPtaHf17kTB1ZYClbSCC9zUknImecqbJp13Z8FJiR2kU/Hda6sMRkWkXN+GDn8Q5Q4/m5SJbatpht",
-	"6+mZcvfR49f82dPP//w9Go2imQDOZ3W8RSrxoQBSbenON3/1cCoxRMgN/uemWTvbs2VfipyFatqdIfs0",
-	"YcOX+WpxeJEBZtAJQUsHpF8qFH3y3rpYbCxOO6rxO6vEWxU9SSPy5u9EuoeAF6cnRCx7Yev09rv37j94",
-	"+Otvv08euVENOFKAh+qAG6RVqD5eWIHrMy+JyuxdALNgjwvM2q+ntbsvLs59nYTdPrfCamtOhmjoZhMa",
-	"2VJXugwZD9lW5gI9B6XgEzkHlpeqoaeeC4niqFHOrX5dWJGufCuwV4KHgAqUHbjH5frxq1PamZTVeNwk",
-	"VBtQzAg6pfdGd8LENAyz4PB4oB1XEMzV9Vg7TX26CYdPeurKVvUfztydTGpvQYXjzBgpeAAYv3Pl2C6F",
-	"5+GytO0xgdNBAQiHQwG6Sej9yZ1vZklfJEZseKtYgZm24jOkZT4Vec7sujaPSUl8/v3iIk+RoXRmK+dz",
-	"t8vyzGOOs6C6dobmGWCpy24zKAPlF+HirExN34VLg9e9CqPTy1mXnxKQ8Az4+473Z2uHkFeO1zVfZ2gY",
-	"NdpFOKhIq/vKbTKxSyhFKKnVwi6h8P+ma0IffENabrz8VCFY/wbyPQxs+RQaFE3FaUsW41wXCjsJ0oR4",
-	"kCLh94MbsuSk2fM9EmX4mohw0hUCzczqPx5+oJbW0vclAWrfhru6WF9ffo8aHijZfRHiWz9F/kCReQZI",
-	"9lgaD06/OfeFz+Vs47t1qFQXVgfDV3MmSQpXILXJQWFV1V5vedkflNB0PJZ+X6YdTh9OJvfoZrb5NwAA",
-	"//+5DxtdaBYAAA==",
+	"H4sIAAAAAAACA+VabXMbNw7+K5y9m8kXWbKdpL2kX+q8tEkmbVLbmdxN5PFQu5SX8Yrcklzbakb//QCQ",
+	"+6albDW1nc70m6TlAiAIPHgA6kuS6kWplVDOJk+/JDbNxYLTx+daKZE6qRV+K40uhXFS0DN+wR03p5Up",
+	"8FsmbGpk6Zcm742ey0KwUqauMoJ9OHybjBK3LAU8tM5IdZasRqCXxItsKKHVzKzjrrLt+zOtC8FVT8Ap",
+	"d0MZH3OhWNoKuuSWCZA2K6TNQekomWuzwDeTjDux4+RCxMzMpC0LvjxVHJ4PtLzwTxk+ZaDF5bDvgjuU",
+	"HZNWcOtOrRBqKOotPGIcjL2QbsnQHLB2UW5t6EI4Dgs4HU+WSRTLi/edY3OmEqP1wwrG7thSpHIuU9bI",
+	"aXTo2WdwIupotjaw/hdhLT8DU5rtk09AiFAVrP+UXObcWV7ihpwoxJnh6CFwb6pNlpxEdlRLOq2sMKcy",
+	"Eigf4AGTGQQvmA4fwwksBsbEHHa91NcvQJS03RCaiUKrM8uc7h5KVclsKB7kG/F7JQ3G96ekY0cb960J",
+	"JxFft0lgD4WFFLVimIetdfRVOrGgD/82Yg7S/jVps3sSUnvSyetVo5cbw5f0XTseSepj/JnBUc7QzXPW",
+	"VdwIkcqJM2G2dK6woismuPdrvNs35jqnvjRGm+vcmUWSnF5i9CwGEJAvsrDD1w6aJGSCJNQrI3bRgk2a",
+	"fThHlbcwAe9ugxNrnvN6u3JiXnsleOHyzW4LIA2fxBXIKOjt81uxd5RcCGNDDWql7413x7s37q4tHtdu",
+	"763m2bsiE+aVtE6b5SGIgOWx8KhUKDVzXhXw8fHuOqD+wq/kolp0UkWj6PoQET2YCQoAtP1qFASSFlL5",
+	"b3vDlFpFDH/PJe775YVQbttg/u2QIpkhxI/YPAQ2ExfEAEYdH+//+Oy39Oef/vjff8fjcexkNgTtx3zJ",
+	"Sm8Zm0PAi8zrMeIz4R7jKgsZ0Wgdyr4q4RTt5tKOOF/vJSyObWe7GPscQ6q3Up2DtR+xbh2UJdRmOn+v",
+	"xVZpCica89uT7/cf7+7tP3z0+Lvvf7Tjuu6NlXDRlKAf1pXTLlJAZThMxpudOhAGjoRykmIoIdsw4kLq",
+	"ykLtEz+gOWbJNDwwDAUzoTK/DhQKqrh1PQ54FjYSkkRXaGN9Ukl9xoPqPEqudlDQzgU3WOctSgzh+NwL",
+	"Dt+OGvnhh+NGTfjhsNVWB7RXup7OZEIsgYOOzQBVI2gPQ97BAk6FHYtkITA0gzfmVVEsY4dVe6sryNOq",
+	"IT/9q9jcnkxtfmzvdXiGqr4Ru3BLp3ap0h5+zXlhB5zwwJ770Mohpija8VvtpBrJWO7RkkllneAZQZ0q",
+	"lhCeKeREA3gR7r7aZh+bzvJrkGFrIMC9mKolVH3xr6oFVzuQRxm0EYL1FncR4CjlyrPH2o5L6fIWSSDk",
+	"lroy3sExM343pzejN51MI7PlQH8OxC0cE7wU5WohHVlYA7QtgB9hCT4BWgtIE6yyhOzofshvCBeIHaMX",
+	"bFIj4CRUhcmXVulqcmn/NN+r3dOz/rrkOCIisDmmrmtnGw+Xf7mvhfAkZG5Etv2FyL62x/W5ecd9bmNx",
+	"1ml477nBvdN+bdT0p6dRMtA44A0okvPesXVoqa/6/3mye2Phj7cwN3SFlK9pZcCBR9jN+eidCW6EOahc",
+	"3n77qd7um4/HmCe0GmOLnrbm5M6VIJegb65DS+nglPCjj4XkWIBtV+wY+cNqwHehjsidpulvW++ZkRlU",
+	"CdjLhUzpQKUrOuKe+ecH718nHZIfmD2ogfxUvJTw08PxHpH9krucNjxZa3vPBJmr64L+OiP6Zt3zXmNo",
+	"Qv7TO/u7u/VuA3uGoypkSgImn63vOHzPvH1H3WIM+XSdUEICrPXOsOjR7t6tWdLvbyM2fFAc4kQb+QeE",
+	"GsVTtVhws6zN40XBMP4e2MgUZb3r52fE+7peJtI2yalh3Hg0PwvnW8q7PJS1pjXiiyMfmojC3uBlL8Ng",
+	"aydd/3iBDMSn553dHy2B/izCxptSFzxFpUbbCHZDSTLONo0SH/QZY3ZAJ0FLiN6zTJC90AJwws+nU3WZ",
+	"Y0nijRzYi1RYqs7AsdARceWbgWAPkDOoXSq0BJDeU1UXd6zdEiyCzRvkbzXV6NA7izZ7a8FJGl4Aq8ZT",
+	"ZBz9Aw4RUW8p8VgHeP9MZ8tbO+EN1HfVx1aiuKs7DLRNzDUScbVPNzG3b4sGqPzJ/Sk/6AYtL5BWL7vB",
+	"y2aVYznQGaXZBS9k1nbBGsI2h0I2Zi/8TI3luvDh2zJCimg/AsIYBf2Pb/HUb9zea1BicPiHFRAy0HfS",
+	"fcgNITPI/Q68NDk0BBgsmmS6nXwRV14bUepC82yHxk6b8QeaPLvW5TUjKj+xcjn37BK/QmnA5lFQyuMp",
+	"8anqmjBmx83gHRsBY+QF4BK+kxutdGWhM+TYIYCAnQxcqLKpqltIXEbHBWfK8CfQU8hzEKDqaUbQXmuI",
+	"oc76HI8ogwGQA9egL6/ldABvgUk3e0qQFME65B3wObChjqeTLs74MUAbPF85EFqd3A1YbhpyboeW+7dm",
+	"x/qsJpI5ZGYzXgjOqAHqXjPYw043JrDPoCEjZum3h+tH96f8V92md0jGdjTUcdBSuHsvJU0mA9tpWzNg",
+	"P3X0dEsM9BUZpNqIeesNEUCoMbobduvcWCMDogW9zeYN0tyE13TROeCEfQB70azpMac74i1bZGK37W/c",
+	"2h+S/o0amNZ9X1NQNw2n2iZmzZDyzPDMX+gAexezI52eC+dH9H5CFgrs2hSNWDS4cbacqvfvjo7ZoGsY",
+	"s5c8zZtBK8Yue3P07lfWvfChijtVXpMXav2ckVKyz+JHoB1vAjhT4jKMRlEFdhFT1XjLaKBLsCXpRlSO",
+	"00JbrOZzJ0x3sPe0joERC7cGo6lq7nd0IDvYwdSbxomodRK6y5lgl9zBcXv84GwhVeWEVzJV0rG5VDi7",
+	"who/Vc+MvgQGZVHCA3AseBiatUxQS9RzfOUPBLaqydY3H4/Zgi9B4VSV3FpQKD2hcfpcKAbAYPCmKjCE",
+	"bqODiw5CmFEUA1UhpTHacUQnEI7mJs4xHKmGlowCgsXjIU5EOrPP63jIjaPVdRPBbyN2WY8We24Irg9n",
+	"McPpTmMcubO1jnycDKhF17Io8+mA3d4t4krvqjQ2C4DMoZiEbG5C6ocuoRW97GN+JGf/cRwAEj8fQJon",
+	"AoBTOLBYQ2WfHghE9Vt1g7cFKMOycGW1VRcDh4c4R78E5H9gN95cTRU/41KNCDUBw1ilCqnO0UQU4Kct",
+	"Y/bBAgQ7nxGcdVuWqfL32wgi7pIvfRNUr/A9kI00QY1+fGK7URYoSuBX1A2dy7IUGWBpmOR4lxCgIrI1",
+	"9JjtPQ5Qav21TAyrDunltj/6ljT/VUPwaUNrPP+fMgXZRF2pNAbXDMmrn2bs35+ZGNg1l4a8KrKmAAAW",
+	"Ot3m4Fr2+4C79gJ5Cxho/2C0aZ7cv+lL7mHct3aneN3RpoP/s/6NWDM4j11jafxw+mPy/hXUpxOs5H7q",
+	"FaNAb3XKC0RXUehygfHj1+LNF17A0p3U08mkwHU5wP7T73Z3Hyark9X/ARXHFnetLAAA",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
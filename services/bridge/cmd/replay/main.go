@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -22,16 +23,25 @@ func main() {
 	sessionID := flag.String("session", "", "Session ID to replay")
 	recordingRange := flag.String("range", "", "Recording range (e.g. '1', '1-5', '10-20')")
 	backendAddr := flag.String("backend", "localhost:6001", "Backend gRPC address")
+	timing := flag.String("timing", timingFixed, "Delay between recordings: 'fixed' (100ms) or 'recorded' (original gaps)")
+	speed := flag.Float64("speed", 1, "Replay speed multiplier (e.g. 2 for twice as fast)")
 	flag.Parse()
 
 	if *sessionID == "" {
 		fmt.Println("❌ Error: --session is required")
 		fmt.Println("\nUsage:")
-		fmt.Println("  replay --session <session-id> [--range <start>-<end>] [--backend <addr>]")
+		fmt.Println("  replay --session <session-id> [--range <start>-<end>] [--backend <addr>] [--timing fixed|recorded] [--speed <x>]")
 		fmt.Println("\nExamples:")
 		fmt.Println("  replay --session whatsapp-abc123-1234567890")
 		fmt.Println("  replay --session whatsapp-abc123-1234567890 --range 1-10")
 		fmt.Println("  replay --session whatsapp-abc123-1234567890 --range 5")
+		fmt.Println("  replay --session whatsapp-abc123-1234567890 --timing recorded --speed 10")
+		os.Exit(1)
+	}
+
+	pace, err := newPacer(*timing, *speed)
+	if err != nil {
+		fmt.Printf("❌ Invalid pacing: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -55,17 +65,17 @@ func main() {
 		os.Exit(1)
 	}
 
-	fmt.Printf("🎬 Replaying recordings #%d to #%d...\n\n", startID, endID)
+	fmt.Printf("🎬 Replaying recordings #%d to #%d (%s timing, %gx speed)...\n\n", startID, endID, *timing, *speed)
 
 	// Connect to backend
 	fmt.Printf("🔌 Connecting to backend at %s...\n", *backendAddr)
-	client, err := backendGRPC.NewIntegrationClient(*backendAddr)
+	client, err := backendGRPC.NewIntegrationClient(*backendAddr, slog.Default())
 	if err != nil {
 		fmt.Printf("❌ Failed to connect to backend: %v\n", err)
 		os.Exit(1)
 	}
 	defer client.Close()
-	fmt.Println("✅ Connected to backend\n")
+	fmt.Print("✅ Connected to backend\n\n")
 
 	// Replay recordings
 	ctx := context.Background()
@@ -73,15 +83,19 @@ func main() {
 	failureCount := 0
 
 	for id := startID; id <= endID; id++ {
-		fmt.Printf("📼 Replaying recording #%03d...\n", id)
-
 		rec, payload, err := recorder.LoadRecording(sessionDir, id)
 		if err != nil {
-			fmt.Printf("❌ Failed to load recording: %v\n", err)
+			fmt.Printf("❌ Failed to load recording #%03d: %v\n", id, err)
 			failureCount++
 			break
 		}
 
+		if delay := pace.delay(rec, time.Now()); delay > 0 {
+			fmt.Printf("⏳ Waiting %s\n", delay.Round(time.Millisecond))
+			time.Sleep(delay)
+		}
+		fmt.Printf("📼 Replaying recording #%03d...\n", id)
+
 		// Replay the recording
 		if err := replayRecording(ctx, client, rec, payload); err != nil {
 			fmt.Printf("❌ Failed to replay: %v\n", err)
@@ -91,9 +105,6 @@ func main() {
 
 		fmt.Printf("✅ Successfully replayed #%03d\n\n", id)
 		successCount++
-
-		// Small delay between recordings
-		time.Sleep(100 * time.Millisecond)
 	}
 
 	// Summary
@@ -167,6 +178,16 @@ func replayRecording(ctx context.Context, client *backendGRPC.IntegrationClient,
 		return replaySyncContacts(ctx, client, payload)
 	case "ProcessMessage":
 		return replayProcessMessage(ctx, client, payload)
+	case "ProcessPollVote":
+		return replayProcessPollVote(ctx, client, payload)
+	case "SyncIdentityMappings":
+		return replaySyncIdentityMappings(ctx, client, payload)
+	case "UpdateParticipantRoles":
+		return replayUpdateParticipantRoles(ctx, client, payload)
+	case "UpdateOutboxDelivery":
+		return replayUpdateOutboxDelivery(ctx, client, payload)
+	case "UpdateMessageDelivery":
+		return replayUpdateMessageDelivery(ctx, client, payload)
 	case "UpdateConnectionStatus":
 		return replayUpdateConnectionStatus(ctx, client, payload)
 	case "CreateUserIntegration":
@@ -217,6 +238,63 @@ func replayProcessMessage(ctx context.Context, client *backendGRPC.IntegrationCl
 	return client.ProcessMessage(ctx, req.Context, req.Message)
 }
 
+func replayProcessPollVote(ctx context.Context, client *backendGRPC.IntegrationClient, payload []byte) error {
+	var req pb.ProcessPollVoteRequest
+	if err := proto.Unmarshal(payload, &req); err != nil {
+		return fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	return client.ProcessPollVote(ctx, req.Context, req.Vote)
+}
+
+func replaySyncIdentityMappings(ctx context.Context, client *backendGRPC.IntegrationClient, payload []byte) error {
+	var req pb.SyncIdentityMappingsRequest
+	if err := proto.Unmarshal(payload, &req); err != nil {
+		return fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	return client.SyncIdentityMappings(ctx, req.Context, req.Mappings)
+}
+
+func replayUpdateParticipantRoles(ctx context.Context, client *backendGRPC.IntegrationClient, payload []byte) error {
+	var req pb.UpdateParticipantRolesRequest
+	if err := proto.Unmarshal(payload, &req); err != nil {
+		return fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	var changedAt time.Time
+	if req.ChangedAt != nil {
+		changedAt = req.ChangedAt.AsTime()
+	}
+	return client.UpdateParticipantRoles(ctx, req.Context, req.ConversationExternalId, req.Participants, req.ChangedByExternalId, changedAt)
+}
+
+func replayUpdateOutboxDelivery(ctx context.Context, client *backendGRPC.IntegrationClient, payload []byte) error {
+	var req pb.UpdateOutboxDeliveryRequest
+	if err := proto.Unmarshal(payload, &req); err != nil {
+		return fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	var at time.Time
+	if req.Timestamp != nil {
+		at = req.Timestamp.AsTime()
+	}
+	return client.UpdateOutboxDelivery(ctx, req.Context, req.ClientMsgUuid, req.WaMessageId, req.ConversationExternalId, req.Status, at)
+}
+
+func replayUpdateMessageDelivery(ctx context.Context, client *backendGRPC.IntegrationClient, payload []byte) error {
+	var req pb.UpdateMessageDeliveryRequest
+	if err := proto.Unmarshal(payload, &req); err != nil {
+		return fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	var at time.Time
+	if req.Timestamp != nil {
+		at = req.Timestamp.AsTime()
+	}
+	return client.UpdateMessageDelivery(ctx, req.Context, req.WaMessageId, req.ConversationExternalId, req.Status, at)
+}
+
 func replayUpdateConnectionStatus(ctx context.Context, client *backendGRPC.IntegrationClient, payload []byte) error {
 	var req pb.UpdateConnectionStatusRequest
 	if err := proto.Unmarshal(payload, &req); err != nil {
@@ -232,6 +310,6 @@ func replayCreateUserIntegration(ctx context.Context, client *backendGRPC.Integr
 		return fmt.Errorf("failed to unmarshal: %w", err)
 	}
 
-	_, err := client.CreateUserIntegration(ctx, req.UserId, req.PlatformUserId, req.DisplayName, req.AvatarUrl, req.Metadata)
+	_, err := client.CreateUserIntegration(ctx, req.UserId, req.PlatformUserId, req.IdempotencyKey, req.DisplayName, req.AvatarUrl, req.Metadata)
 	return err
 }
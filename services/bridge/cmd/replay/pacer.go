@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tennex/bridge/internal/recorder"
+)
+
+const (
+	// timingFixed waits fixedDelay between recordings
+	timingFixed = "fixed"
+	// timingRecorded keeps the gaps between the recordings' timestamps
+	timingRecorded = "recorded"
+
+	fixedDelay = 100 * time.Millisecond
+)
+
+// pacer decides how long to wait before replaying each recording. Delays are
+// divided by speed, so 2 replays twice as fast and 0.5 at half speed.
+type pacer struct {
+	timing string
+	speed  float64
+
+	started       bool
+	startedAt     time.Time // Wall clock when the first recording was replayed
+	firstRecorded time.Time // Timestamp of the first recording
+}
+
+func newPacer(timing string, speed float64) (*pacer, error) {
+	if timing != timingFixed && timing != timingRecorded {
+		return nil, fmt.Errorf("timing must be %q or %q", timingFixed, timingRecorded)
+	}
+	if speed <= 0 {
+		return nil, fmt.Errorf("speed must be positive")
+	}
+	return &pacer{timing: timing, speed: speed}, nil
+}
+
+// delay returns how long to wait at now before replaying rec. With recorded
+// timing each recording is scheduled relative to the first one, so time
+// spent replaying earlier recordings counts towards the gap.
+func (p *pacer) delay(rec *recorder.Recording, now time.Time) time.Duration {
+	if !p.started {
+		p.started = true
+		p.startedAt = now
+		p.firstRecorded = rec.Timestamp
+		return 0
+	}
+
+	if p.timing == timingFixed {
+		return p.scale(fixedDelay)
+	}
+
+	due := p.startedAt.Add(p.scale(rec.Timestamp.Sub(p.firstRecorded)))
+	return max(due.Sub(now), 0)
+}
+
+func (p *pacer) scale(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / p.speed)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tennex/bridge/internal/recorder"
+)
+
+func testRecording(seconds int) *recorder.Recording {
+	return &recorder.Recording{Timestamp: time.Unix(1700000000, 0).Add(time.Duration(seconds) * time.Second)}
+}
+
+func TestPacerRecordedTiming(t *testing.T) {
+	pace, err := newPacer(timingRecorded, 2)
+	if err != nil {
+		t.Fatalf("newPacer() error = %v", err)
+	}
+	start := time.Unix(1800000000, 0)
+
+	if got := pace.delay(testRecording(0), start); got != 0 {
+		t.Errorf("first delay = %s, want 0", got)
+	}
+	// 4s recorded gap at 2x speed
+	if got := pace.delay(testRecording(4), start); got != 2*time.Second {
+		t.Errorf("delay = %s, want 2s", got)
+	}
+	// Time spent replaying counts towards the gap
+	if got := pace.delay(testRecording(10), start.Add(3*time.Second)); got != 2*time.Second {
+		t.Errorf("delay after slow replay = %s, want 2s", got)
+	}
+	// Never negative when replay falls behind
+	if got := pace.delay(testRecording(11), start.Add(time.Minute)); got != 0 {
+		t.Errorf("delay when behind = %s, want 0", got)
+	}
+}
+
+func TestPacerFixedTiming(t *testing.T) {
+	pace, err := newPacer(timingFixed, 0.5)
+	if err != nil {
+		t.Fatalf("newPacer() error = %v", err)
+	}
+	now := time.Unix(1800000000, 0)
+
+	if got := pace.delay(testRecording(0), now); got != 0 {
+		t.Errorf("first delay = %s, want 0", got)
+	}
+	if got := pace.delay(testRecording(60), now); got != 2*fixedDelay {
+		t.Errorf("delay = %s, want %s", got, 2*fixedDelay)
+	}
+}
+
+func TestNewPacerValidation(t *testing.T) {
+	if _, err := newPacer("realtime", 1); err == nil {
+		t.Error("newPacer() with unknown timing succeeded")
+	}
+	if _, err := newPacer(timingRecorded, 0); err == nil {
+		t.Error("newPacer() with zero speed succeeded")
+	}
+}
@@ -0,0 +1,34 @@
+package control
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/tennex/pkg/requestid"
+)
+
+// LoggingUnaryInterceptor logs every call with its request ID, so a call can
+// be matched with the backend's log of it. It must be chained after the
+// requestid interceptors.
+func LoggingUnaryInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	logger = logger.With("component", "control_server")
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		attrs := []any{
+			"method", info.FullMethod,
+			"request_id", requestid.FromContext(ctx),
+			"duration", time.Since(start),
+		}
+		if err != nil {
+			logger.Warn("gRPC call failed", append(attrs, "code", status.Code(err).String(), "error", err)...)
+		} else {
+			logger.Debug("gRPC call", attrs...)
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,353 @@
+// Package control serves backend-initiated operations on connected WhatsApp sessions
+package control
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/tennex/bridge/whatsapp"
+	"github.com/tennex/pkg/events"
+	"github.com/tennex/pkg/requestid"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+// DefaultRPCTimeout bounds calls that arrive without a deadline
+const DefaultRPCTimeout = 30 * time.Second
+
+// MaxRequestBytes is the largest request the control server accepts, leaving
+// room for a media message carrying a file of up to 64 MiB
+const MaxRequestBytes = 65 << 20
+
+// SessionLookup finds the connected session for an account
+type SessionLookup interface {
+	Get(accountID string) (whatsapp.Session, bool)
+	Unregister(accountID string, session whatsapp.Session)
+}
+
+// Server implements the bridge control gRPC service
+type Server struct {
+	proto.UnimplementedBridgeControlServiceServer
+	sessions SessionLookup
+	logger   *slog.Logger
+}
+
+// NewServer creates a new bridge control gRPC server
+func NewServer(sessions SessionLookup, logger *slog.Logger) *Server {
+	return &Server{
+		sessions: sessions,
+		logger:   logger.With("component", "control_server"),
+	}
+}
+
+// SendMessage sends a message through the account's WhatsApp session
+func (s *Server) SendMessage(ctx context.Context, req *proto.SendMessageRequest) (*proto.SendMessageResponse, error) {
+	session, err := s.session(req.AccountId)
+	if err != nil {
+		return nil, err
+	}
+
+	content := req.GetContent()
+	var media *whatsapp.OutgoingMedia
+	switch {
+	case content.GetText() != nil:
+	case content.GetImage() != nil:
+		image := content.GetImage()
+		media = &whatsapp.OutgoingMedia{
+			Kind:     whatsapp.MediaKindImage,
+			Data:     image.Data,
+			MimeType: image.MimeType,
+			Caption:  image.Caption,
+		}
+	case content.GetDocument() != nil:
+		document := content.GetDocument()
+		media = &whatsapp.OutgoingMedia{
+			Kind:     whatsapp.MediaKindDocument,
+			Data:     document.Data,
+			MimeType: document.MimeType,
+			FileName: document.Filename,
+		}
+	default:
+		return nil, status.Error(codes.InvalidArgument, "only text, image and document content is supported")
+	}
+	if media != nil && (len(media.Data) == 0 || media.MimeType == "") {
+		return nil, status.Error(codes.InvalidArgument, "media content needs data and a mime_type")
+	}
+
+	toJID := req.ToJid
+	if toJID == "" {
+		toJID = req.ConvoId
+	}
+	if toJID == "" {
+		return nil, status.Error(codes.InvalidArgument, "to_jid or convo_id is required")
+	}
+
+	ctx, cancel := withDefaultDeadline(ctx)
+	defer cancel()
+
+	logger := s.requestLogger(ctx, req.AccountId)
+	var waMessageID string
+	if media != nil {
+		logger.Info("Sending media message", "to", toJID, "client_msg_uuid", req.ClientMsgUuid, "kind", media.Kind, "bytes", len(media.Data))
+		waMessageID, err = session.SendMedia(ctx, toJID, *media, req.ReplyToWaMessageId, req.ClientMsgUuid)
+	} else {
+		logger.Info("Sending text message", "to", toJID, "client_msg_uuid", req.ClientMsgUuid)
+		waMessageID, err = session.SendText(ctx, toJID, content.GetText().Text, req.ReplyToWaMessageId, req.ClientMsgUuid)
+	}
+	if err != nil {
+		logger.Error("Failed to send message", "to", toJID, "client_msg_uuid", req.ClientMsgUuid, "error", err)
+		return &proto.SendMessageResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &proto.SendMessageResponse{Success: true, WaMessageId: waMessageID}, nil
+}
+
+// MarkRead sends read receipts for messages in a conversation
+func (s *Server) MarkRead(ctx context.Context, req *proto.MarkReadRequest) (*proto.MarkReadResponse, error) {
+	session, err := s.session(req.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	if req.ConvoId == "" || len(req.WaMessageIds) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "convo_id and wa_message_ids are required")
+	}
+
+	ctx, cancel := withDefaultDeadline(ctx)
+	defer cancel()
+
+	logger := s.requestLogger(ctx, req.AccountId)
+	logger.Info("Marking messages read", "convo_id", req.ConvoId, "messages", len(req.WaMessageIds))
+
+	if err := session.MarkRead(ctx, req.ConvoId, req.SenderJid, req.WaMessageIds); err != nil {
+		logger.Error("Failed to mark messages read", "convo_id", req.ConvoId, "error", err)
+		return &proto.MarkReadResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &proto.MarkReadResponse{Success: true}, nil
+}
+
+// Logout unlinks the account's device and drops its session
+func (s *Server) Logout(ctx context.Context, req *proto.LogoutRequest) (*proto.LogoutResponse, error) {
+	session, err := s.session(req.AccountId)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withDefaultDeadline(ctx)
+	defer cancel()
+
+	logger := s.requestLogger(ctx, req.AccountId)
+	logger.Info("Logging out")
+
+	if err := session.Logout(ctx); err != nil {
+		logger.Error("Failed to log out", "error", err)
+		return &proto.LogoutResponse{Success: false, Error: err.Error()}, nil
+	}
+	s.sessions.Unregister(req.AccountId, session)
+
+	return &proto.LogoutResponse{Success: true}, nil
+}
+
+// TriggerResync re-fetches app state for the account
+func (s *Server) TriggerResync(ctx context.Context, req *proto.TriggerResyncRequest) (*proto.TriggerResyncResponse, error) {
+	session, err := s.session(req.AccountId)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withDefaultDeadline(ctx)
+	defer cancel()
+
+	logger := s.requestLogger(ctx, req.AccountId)
+	logger.Info("Resyncing app state", "full_sync", req.FullSync)
+
+	if err := session.Resync(ctx, req.FullSync); err != nil {
+		logger.Error("Failed to resync app state", "full_sync", req.FullSync, "error", err)
+		return &proto.TriggerResyncResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &proto.TriggerResyncResponse{Success: true}, nil
+}
+
+// ListGroups lists the account's WhatsApp groups and syncs them to the backend as conversations
+func (s *Server) ListGroups(ctx context.Context, req *proto.ListGroupsRequest) (*proto.ListGroupsResponse, error) {
+	session, err := s.session(req.AccountId)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withDefaultDeadline(ctx)
+	defer cancel()
+
+	logger := s.requestLogger(ctx, req.AccountId)
+	logger.Info("Listing groups")
+
+	groups, err := session.JoinedGroups(ctx)
+	if err != nil {
+		logger.Error("Failed to list groups", "error", err)
+		return &proto.ListGroupsResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	resp := &proto.ListGroupsResponse{Success: true, Groups: make([]*proto.Group, len(groups))}
+	for i, group := range groups {
+		resp.Groups[i] = &proto.Group{
+			Jid:              group.JID,
+			Name:             group.Name,
+			Topic:            group.Topic,
+			OwnerJid:         group.OwnerJID,
+			ParticipantCount: int32(group.ParticipantCount),
+			IsAnnounce:       group.IsAnnounce,
+			IsLocked:         group.IsLocked,
+		}
+		if !group.CreatedAt.IsZero() {
+			resp.Groups[i].CreatedAt = timestamppb.New(group.CreatedAt)
+		}
+	}
+	return resp, nil
+}
+
+// SetConversationState pins, archives or mutes a conversation through WhatsApp app state
+func (s *Server) SetConversationState(ctx context.Context, req *proto.SetConversationStateRequest) (*proto.SetConversationStateResponse, error) {
+	session, err := s.session(req.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	if req.ConvoId == "" || len(req.Fields) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "convo_id and fields are required")
+	}
+
+	change := whatsapp.ConversationStateChange{}
+	if req.ChangedAt != nil {
+		change.ChangedAt = req.ChangedAt.AsTime()
+	}
+	for _, field := range req.Fields {
+		switch field {
+		case events.ConversationFieldPinned:
+			change.Pinned = &req.IsPinned
+		case events.ConversationFieldArchived:
+			change.Archived = &req.IsArchived
+		case events.ConversationFieldMuted:
+			change.Muted = &req.IsMuted
+			if req.MuteUntil != nil {
+				change.MuteUntil = req.MuteUntil.AsTime()
+			}
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unknown conversation state field %q", field)
+		}
+	}
+	if change.Pinned != nil && *change.Pinned && change.Archived != nil && *change.Archived {
+		return nil, status.Error(codes.InvalidArgument, "an archived conversation can't be pinned")
+	}
+
+	ctx, cancel := withDefaultDeadline(ctx)
+	defer cancel()
+
+	logger := s.requestLogger(ctx, req.AccountId)
+	logger.Info("Setting conversation state", "convo_id", req.ConvoId, "fields", req.Fields)
+
+	if err := session.SetConversationState(ctx, req.ConvoId, change); err != nil {
+		logger.Error("Failed to set conversation state", "convo_id", req.ConvoId, "fields", req.Fields, "error", err)
+		return &proto.SetConversationStateResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &proto.SetConversationStateResponse{Success: true}, nil
+}
+
+// SubscribePresence starts forwarding the presence of contacts to the backend
+func (s *Server) SubscribePresence(ctx context.Context, req *proto.SubscribePresenceRequest) (*proto.SubscribePresenceResponse, error) {
+	session, err := s.session(req.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Jids) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "jids is required")
+	}
+
+	ctx, cancel := withDefaultDeadline(ctx)
+	defer cancel()
+
+	logger := s.requestLogger(ctx, req.AccountId)
+	logger.Info("Subscribing to presence", "jids", len(req.Jids))
+
+	subscribed, err := session.SubscribePresence(ctx, req.Jids)
+	if err != nil {
+		if code := presenceErrorCode(err); code != codes.Unknown {
+			return nil, status.Error(code, err.Error())
+		}
+		logger.Error("Failed to subscribe to presence", "jids", len(req.Jids), "error", err)
+		return &proto.SubscribePresenceResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &proto.SubscribePresenceResponse{Success: true, SubscribedJids: subscribed}, nil
+}
+
+// UnsubscribePresence stops forwarding the presence of contacts
+func (s *Server) UnsubscribePresence(ctx context.Context, req *proto.UnsubscribePresenceRequest) (*proto.UnsubscribePresenceResponse, error) {
+	session, err := s.session(req.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Jids) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "jids is required")
+	}
+
+	ctx, cancel := withDefaultDeadline(ctx)
+	defer cancel()
+
+	logger := s.requestLogger(ctx, req.AccountId)
+	logger.Info("Unsubscribing from presence", "jids", len(req.Jids))
+
+	subscribed, err := session.UnsubscribePresence(ctx, req.Jids)
+	if err != nil {
+		if code := presenceErrorCode(err); code != codes.Unknown {
+			return nil, status.Error(code, err.Error())
+		}
+		logger.Error("Failed to unsubscribe from presence", "jids", len(req.Jids), "error", err)
+		return &proto.UnsubscribePresenceResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &proto.UnsubscribePresenceResponse{Success: true, SubscribedJids: subscribed}, nil
+}
+
+// presenceErrorCode maps the caller's mistakes in a presence subscription to
+// a status code, and anything else to codes.Unknown
+func presenceErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, whatsapp.ErrInvalidPresenceJID):
+		return codes.InvalidArgument
+	case errors.Is(err, whatsapp.ErrTooManyPresenceSubscriptions):
+		return codes.ResourceExhausted
+	default:
+		return codes.Unknown
+	}
+}
+
+func (s *Server) session(accountID string) (whatsapp.Session, error) {
+	if accountID == "" {
+		return nil, status.Error(codes.InvalidArgument, "account_id is required")
+	}
+	session, ok := s.sessions.Get(accountID)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no connected WhatsApp session for account %s", accountID)
+	}
+	return session, nil
+}
+
+// requestLogger returns the logger for a call on an account, tagged with the
+// call's request ID so its entries can be matched with the backend's
+func (s *Server) requestLogger(ctx context.Context, accountID string) *slog.Logger {
+	return s.logger.With("request_id", requestid.FromContext(ctx), "account_id", accountID)
+}
+
+// withDefaultDeadline applies DefaultRPCTimeout when the caller set no deadline
+func withDefaultDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, DefaultRPCTimeout)
+}
@@ -0,0 +1,24 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordChangedAt returns when a user's password was last reset, or the
+// zero time if it never was. It reads the backend's users table, which shares
+// this database, and finds no rows for a deactivated user.
+func (s *Storage) PasswordChangedAt(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	var changedAt sql.NullTime
+	err := s.db.WithContext(ctx).
+		Raw("SELECT password_changed_at FROM users WHERE id = ? AND is_active = true", userID).
+		Row().
+		Scan(&changedAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return changedAt.Time, nil
+}
@@ -2,19 +2,18 @@ package grpc
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
-)
 
-// IntegrationClientInterface defines the interface for integration clients
-// This allows us to use either IntegrationClient or RecordingIntegrationClient interchangeably
-type IntegrationClientInterface interface {
-	Close() error
-	// Add other methods as needed - for now, we'll use the concrete types
-}
+	"google.golang.org/grpc"
+
+	"github.com/tennex/bridge/internal/recorder"
+)
 
-// NewIntegrationClientWithRecording creates an integration client with optional recording
-func NewIntegrationClientWithRecording(backendAddr string) (*RecordingIntegrationClient, error) {
+// NewIntegrationClientWithRecording creates an integration client that records
+// its requests when RECORDING_MODE is "on" or "record"
+func NewIntegrationClientWithRecording(backendAddr string, logger *slog.Logger, opts ...grpc.DialOption) (*IntegrationClient, error) {
 	// Determine recordings directory
 	recordingsDir := os.Getenv("RECORDINGS_DIR")
 	if recordingsDir == "" {
@@ -32,5 +31,14 @@ func NewIntegrationClientWithRecording(backendAddr string) (*RecordingIntegratio
 		return nil, fmt.Errorf("failed to create recordings directory: %w", err)
 	}
 
-	return NewRecordingIntegrationClient(backendAddr, recordingsDir)
+	client, err := NewIntegrationClient(backendAddr, logger, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode := os.Getenv("RECORDING_MODE"); mode == "on" || mode == "record" {
+		client.recorder = recorder.NewRecorder(recorder.ModeRecord, recordingsDir)
+		client.logger.Info("Recording mode enabled", "dir", recordingsDir)
+	}
+	return client, nil
 }
@@ -2,26 +2,48 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
+	protobuf "google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/tennex/bridge/internal/recorder"
+	"github.com/tennex/pkg/reconnect"
+	"github.com/tennex/pkg/requestid"
 	proto "github.com/tennex/shared/proto/gen/proto"
 )
 
-// IntegrationClient wraps the gRPC client for the platform-agnostic integration service
+// ErrIntegrationRejected is returned when the backend refuses to create an
+// integration, e.g. because the account is connected to another user
+var ErrIntegrationRejected = errors.New("integration rejected by backend")
+
+// IntegrationClient is the bridge's client for the backend. It calls the
+// platform-agnostic integration and media services, and records the requests
+// worth replaying when its recorder is on.
 type IntegrationClient struct {
-	client proto.IntegrationServiceClient
-	conn   *grpc.ClientConn
+	client   proto.IntegrationServiceClient
+	media    proto.MediaServiceClient
+	conn     *grpc.ClientConn
+	recorder *recorder.Recorder
+	logger   *slog.Logger
+
+	queue       *SyncQueue // Persists the syncs in progress; nil when off
+	syncBackoff reconnect.Backoff
 }
 
-// NewIntegrationClient creates a new integration gRPC client
-func NewIntegrationClient(backendAddr string) (*IntegrationClient, error) {
-	conn, err := grpc.Dial(backendAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// NewIntegrationClient creates a new integration gRPC client. opts are added
+// to the connection's dial options; it's plaintext unless they set other
+// transport credentials.
+func NewIntegrationClient(backendAddr string, logger *slog.Logger, opts ...grpc.DialOption) (*IntegrationClient, error) {
+	opts = append(append(requestid.DialOptions(), grpc.WithTransportCredentials(insecure.NewCredentials())), opts...)
+	conn, err := grpc.Dial(backendAddr, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to integration service at %s: %w", backendAddr, err)
 	}
@@ -29,11 +51,47 @@ func NewIntegrationClient(backendAddr string) (*IntegrationClient, error) {
 	client := proto.NewIntegrationServiceClient(conn)
 
 	return &IntegrationClient{
-		client: client,
-		conn:   conn,
+		client:      client,
+		media:       proto.NewMediaServiceClient(conn),
+		conn:        conn,
+		recorder:    recorder.NewRecorder(recorder.ModeOff, ""),
+		logger:      logger.With("component", "integration_client"),
+		syncBackoff: defaultSyncBackoff,
 	}, nil
 }
 
+// UseSyncQueue persists the syncs the client streams in queue, so the syncs
+// a restart cuts short can be resumed with ResumeSyncs
+func (c *IntegrationClient) UseSyncQueue(queue *SyncQueue) {
+	c.queue = queue
+}
+
+// StartRecordingSession starts a new recording session
+func (c *IntegrationClient) StartRecordingSession(userID, integrationType string) error {
+	return c.recorder.StartSession(userID, integrationType)
+}
+
+// EndRecordingSession ends the current recording session
+func (c *IntegrationClient) EndRecordingSession() error {
+	return c.recorder.EndSession()
+}
+
+// record saves a request to the current recording session, if any. Failing
+// to record doesn't fail the request.
+func (c *IntegrationClient) record(ctx context.Context, method string, req protobuf.Message, metadata map[string]interface{}) {
+	if err := c.recorder.Record(ctx, method, req, metadata); err != nil {
+		c.logger.Warn("Failed to record request", "method", method, "error", err)
+	}
+}
+
+// log returns the client's logger with the integration's user and ID
+func (c *IntegrationClient) log(integrationCtx *proto.IntegrationContext) *slog.Logger {
+	return c.logger.With(
+		"user_id", integrationCtx.GetUserId(),
+		"integration_id", integrationCtx.GetUserIntegrationId(),
+	)
+}
+
 // Close closes the gRPC connection
 func (c *IntegrationClient) Close() error {
 	if c.conn != nil {
@@ -42,8 +100,25 @@ func (c *IntegrationClient) Close() error {
 	return nil
 }
 
-// CreateUserIntegration creates a new WhatsApp integration for a user
-func (c *IntegrationClient) CreateUserIntegration(ctx context.Context, userID, waJID, displayName, avatarURL string, metadata map[string]string) (int32, error) {
+// CheckConnectivity waits until the connection to the backend is ready, or
+// fails once ctx is done
+func (c *IntegrationClient) CheckConnectivity(ctx context.Context) error {
+	c.conn.Connect()
+	for {
+		state := c.conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if !c.conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("backend connection is %s: %w", state, ctx.Err())
+		}
+	}
+}
+
+// CreateUserIntegration creates a new WhatsApp integration for a user.
+// idempotencyKey identifies the pairing, so retrying it returns the same
+// integration.
+func (c *IntegrationClient) CreateUserIntegration(ctx context.Context, userID, waJID, idempotencyKey, displayName, avatarURL string, metadata map[string]string) (int32, error) {
 	req := &proto.CreateUserIntegrationRequest{
 		UserId:          userID,
 		IntegrationType: "whatsapp",
@@ -51,7 +126,12 @@ func (c *IntegrationClient) CreateUserIntegration(ctx context.Context, userID, w
 		DisplayName:     displayName,
 		AvatarUrl:       avatarURL,
 		Metadata:        metadata,
+		IdempotencyKey:  idempotencyKey,
 	}
+	c.record(ctx, "CreateUserIntegration", req, map[string]interface{}{
+		"user_id":       userID,
+		"platform_type": "whatsapp",
+	})
 
 	resp, err := c.client.CreateUserIntegration(ctx, req)
 	if err != nil {
@@ -59,14 +139,15 @@ func (c *IntegrationClient) CreateUserIntegration(ctx context.Context, userID, w
 	}
 
 	if !resp.Success {
-		return 0, fmt.Errorf("backend reported failure: %s", resp.Error)
+		return 0, fmt.Errorf("%w: %s", ErrIntegrationRejected, resp.Error)
 	}
 
-	log.Printf("✅ User integration created: user_id=%s, integration_id=%d", userID, resp.UserIntegrationId)
+	c.logger.Info("User integration created", "user_id", userID, "integration_id", resp.UserIntegrationId)
 	return resp.UserIntegrationId, nil
 }
 
-// UpdateConnectionStatus updates the connection status
+// UpdateConnectionStatus updates the connection status. Status updates
+// aren't recorded, as they're not useful for replay.
 func (c *IntegrationClient) UpdateConnectionStatus(ctx context.Context, integrationCtx *proto.IntegrationContext, status proto.ConnectionStatus, qrCode string, metadata map[string]string) error {
 	req := &proto.UpdateConnectionStatusRequest{
 		Context:   integrationCtx,
@@ -88,183 +169,399 @@ func (c *IntegrationClient) UpdateConnectionStatus(ctx context.Context, integrat
 	return nil
 }
 
-// SyncConversations sends conversations to backend via streaming gRPC
+// SyncConversations sends conversations to backend via streaming gRPC,
+// resuming from the last acknowledged batch when the stream breaks
 func (c *IntegrationClient) SyncConversations(ctx context.Context, integrationCtx *proto.IntegrationContext, conversations []*proto.Conversation, syncType string) error {
-	stream, err := c.client.SyncConversations(ctx)
+	// Record the entire sync as a single batch, so it replays exactly
+	req := &proto.SyncConversationsRequest{
+		Context:       integrationCtx,
+		SyncType:      syncType,
+		Conversations: conversations,
+		IsFinalBatch:  true,
+		BatchNumber:   1,
+	}
+	c.record(ctx, "SyncConversations", req, map[string]interface{}{
+		"sync_type":          syncType,
+		"count":              len(conversations),
+		"conversation_count": len(conversations),
+	})
+
+	job := c.startSync("SyncConversations", len(conversations), conversationsBatchSize, req)
+	return c.syncConversations(ctx, job, req)
+}
+
+// syncConversations streams job, a sync of all's conversations
+func (c *IntegrationClient) syncConversations(ctx context.Context, job *syncJob, all *proto.SyncConversationsRequest) error {
+	processed, err := runSync(ctx, c, all.Context, job, c.client.SyncConversations, func(number int32) *proto.SyncConversationsRequest {
+		start, end := job.bounds(number, len(all.Conversations))
+		return &proto.SyncConversationsRequest{
+			Context:       all.Context,
+			SyncType:      all.SyncType,
+			Conversations: all.Conversations[start:end],
+			IsFinalBatch:  number == job.Batches,
+			BatchNumber:   number,
+			SyncId:        job.SyncID,
+		}
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create conversations sync stream: %w", err)
+		return fmt.Errorf("conversations sync failed: %w", err)
 	}
 
-	const batchSize = 50
-	totalBatches := (len(conversations) + batchSize - 1) / batchSize
+	c.log(all.Context).Info("Conversations synced",
+		"sync_type", all.SyncType,
+		"processed", processed,
+		"batches", job.Batches)
+	return nil
+}
 
-	for i := 0; i < len(conversations); i += batchSize {
-		end := i + batchSize
-		if end > len(conversations) {
-			end = len(conversations)
-		}
+// SyncContacts sends contacts to backend via streaming gRPC, resuming from the
+// last acknowledged batch when the stream breaks
+func (c *IntegrationClient) SyncContacts(ctx context.Context, integrationCtx *proto.IntegrationContext, contacts []*proto.Contact) error {
+	req := &proto.SyncContactsRequest{
+		Context:      integrationCtx,
+		Contacts:     contacts,
+		IsFinalBatch: true,
+		BatchNumber:  1,
+	}
+	c.record(ctx, "SyncContacts", req, map[string]interface{}{
+		"count":         len(contacts),
+		"contact_count": len(contacts),
+	})
 
-		batch := conversations[i:end]
-		isFinal := i+batchSize >= len(conversations)
+	job := c.startSync("SyncContacts", len(contacts), contactsBatchSize, req)
+	return c.syncContacts(ctx, job, req)
+}
 
-		req := &proto.SyncConversationsRequest{
-			Context:       integrationCtx,
-			SyncType:      syncType,
-			Conversations: batch,
-			IsFinalBatch:  isFinal,
-			BatchNumber:   int32(i/batchSize + 1),
+// syncContacts streams job, a sync of all's contacts
+func (c *IntegrationClient) syncContacts(ctx context.Context, job *syncJob, all *proto.SyncContactsRequest) error {
+	processed, err := runSync(ctx, c, all.Context, job, c.client.SyncContacts, func(number int32) *proto.SyncContactsRequest {
+		start, end := job.bounds(number, len(all.Contacts))
+		return &proto.SyncContactsRequest{
+			Context:      all.Context,
+			Contacts:     all.Contacts[start:end],
+			IsFinalBatch: number == job.Batches,
+			BatchNumber:  number,
+			SyncId:       job.SyncID,
 		}
+	})
+	if err != nil {
+		return fmt.Errorf("contacts sync failed: %w", err)
+	}
+
+	c.log(all.Context).Info("Contacts synced", "processed", processed)
+	return nil
+}
+
+// SyncMessages sends messages for a conversation to backend via streaming
+// gRPC, resuming from the last acknowledged batch when the stream breaks
+func (c *IntegrationClient) SyncMessages(ctx context.Context, integrationCtx *proto.IntegrationContext, conversationID string, messages []*proto.Message) error {
+	req := &proto.SyncMessagesRequest{
+		Context:                integrationCtx,
+		ConversationExternalId: conversationID,
+		Messages:               messages,
+		IsFinalBatch:           true,
+		BatchNumber:            1,
+	}
+	c.record(ctx, "SyncMessages", req, map[string]interface{}{
+		"conversation_id": conversationID,
+		"message_count":   len(messages),
+	})
+
+	job := c.startSync("SyncMessages", len(messages), messagesBatchSize, req)
+	return c.syncMessages(ctx, job, req)
+}
 
-		if err := stream.Send(req); err != nil {
-			return fmt.Errorf("failed to send conversation batch: %w", err)
+// syncMessages streams job, a sync of all's messages
+func (c *IntegrationClient) syncMessages(ctx context.Context, job *syncJob, all *proto.SyncMessagesRequest) error {
+	processed, err := runSync(ctx, c, all.Context, job, c.client.SyncMessages, func(number int32) *proto.SyncMessagesRequest {
+		start, end := job.bounds(number, len(all.Messages))
+		return &proto.SyncMessagesRequest{
+			Context:                all.Context,
+			ConversationExternalId: all.ConversationExternalId,
+			Messages:               all.Messages[start:end],
+			IsFinalBatch:           number == job.Batches,
+			BatchNumber:            number,
+			SyncId:                 job.SyncID,
 		}
+	})
+	if err != nil {
+		return fmt.Errorf("messages sync failed: %w", err)
+	}
 
-		log.Printf("📤 Sent conversation batch %d/%d (%d conversations)", i/batchSize+1, totalBatches, len(batch))
+	c.log(all.Context).Debug("Messages synced",
+		"conversation_id", all.ConversationExternalId,
+		"processed", processed)
+	return nil
+}
+
+// ProcessMessage sends a single real-time message to the backend
+func (c *IntegrationClient) ProcessMessage(ctx context.Context, integrationCtx *proto.IntegrationContext, message *proto.Message) error {
+	req := &proto.ProcessMessageRequest{
+		Context: integrationCtx,
+		Message: message,
 	}
+	c.record(ctx, "ProcessMessage", req, map[string]interface{}{
+		"message_id":   message.PlatformId,
+		"message_type": message.MessageType,
+	})
 
-	resp, err := stream.CloseAndRecv()
+	resp, err := c.client.ProcessMessage(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to close conversations sync stream: %w", err)
+		return fmt.Errorf("failed to process message: %w", err)
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("conversations sync failed: %s", resp.Error)
+		return fmt.Errorf("message processing failed: %s", resp.Error)
 	}
 
-	log.Printf("✅ Conversations sync completed: %d processed across %d batches", resp.ProcessedCount, resp.TotalBatches)
+	c.log(integrationCtx).Debug("Real-time message processed",
+		"platform_id", message.PlatformId,
+		"internal_id", resp.InternalMessageId)
 	return nil
 }
 
-// SyncContacts sends contacts to backend via streaming gRPC
-func (c *IntegrationClient) SyncContacts(ctx context.Context, integrationCtx *proto.IntegrationContext, contacts []*proto.Contact) error {
-	stream, err := c.client.SyncContacts(ctx)
+// ProcessPollVote sends a decrypted poll vote to the backend
+func (c *IntegrationClient) ProcessPollVote(ctx context.Context, integrationCtx *proto.IntegrationContext, vote *proto.PollVote) error {
+	req := &proto.ProcessPollVoteRequest{
+		Context: integrationCtx,
+		Vote:    vote,
+	}
+	c.record(ctx, "ProcessPollVote", req, map[string]interface{}{
+		"poll_message_id": vote.PollMessageId,
+		"voter_id":        vote.VoterId,
+	})
+
+	resp, err := c.client.ProcessPollVote(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to create contacts sync stream: %w", err)
+		return fmt.Errorf("failed to process poll vote: %w", err)
 	}
 
-	const batchSize = 100
-	totalBatches := (len(contacts) + batchSize - 1) / batchSize
+	if !resp.Success {
+		return fmt.Errorf("poll vote processing failed: %s", resp.Error)
+	}
 
-	for i := 0; i < len(contacts); i += batchSize {
-		end := i + batchSize
-		if end > len(contacts) {
-			end = len(contacts)
-		}
+	c.log(integrationCtx).Debug("Poll vote processed",
+		"poll_id", vote.PollMessageId,
+		"voter_id", vote.VoterId)
+	return nil
+}
 
-		batch := contacts[i:end]
-		isFinal := i+batchSize >= len(contacts)
+// SyncIdentityMappings reports which phone-number JID each LID belongs to
+func (c *IntegrationClient) SyncIdentityMappings(ctx context.Context, integrationCtx *proto.IntegrationContext, mappings []*proto.IdentityMapping) error {
+	req := &proto.SyncIdentityMappingsRequest{
+		Context:  integrationCtx,
+		Mappings: mappings,
+	}
+	c.record(ctx, "SyncIdentityMappings", req, map[string]interface{}{
+		"count":         len(mappings),
+		"mapping_count": len(mappings),
+	})
 
-		req := &proto.SyncContactsRequest{
-			Context:      integrationCtx,
-			Contacts:     batch,
-			IsFinalBatch: isFinal,
-			BatchNumber:  int32(i/batchSize + 1),
-		}
+	resp, err := c.client.SyncIdentityMappings(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to sync identity mappings: %w", err)
+	}
 
-		if err := stream.Send(req); err != nil {
-			return fmt.Errorf("failed to send contact batch: %w", err)
-		}
+	if !resp.Success {
+		return fmt.Errorf("identity mapping sync failed: %s", resp.Error)
+	}
 
-		log.Printf("📤 Sent contact batch %d/%d (%d contacts)", i/batchSize+1, totalBatches, len(batch))
+	c.log(integrationCtx).Info("Identity mappings synced",
+		"mappings", len(mappings),
+		"merged_conversations", resp.MergedCount)
+	return nil
+}
+
+// UpdateConversationState updates conversation state (pin, mute, archive).
+// Only the named fields of state are set, or all of them if fields is empty.
+// State updates are incremental and aren't recorded.
+func (c *IntegrationClient) UpdateConversationState(ctx context.Context, integrationCtx *proto.IntegrationContext, conversationID string, state *proto.ConversationState, fields []string, changedAt time.Time) error {
+	req := &proto.UpdateConversationStateRequest{
+		Context:                integrationCtx,
+		ConversationExternalId: conversationID,
+		State:                  state,
+		Fields:                 fields,
+	}
+	if !changedAt.IsZero() {
+		req.ChangedAt = timestamppb.New(changedAt)
 	}
 
-	resp, err := stream.CloseAndRecv()
+	resp, err := c.client.UpdateConversationState(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to close contacts sync stream: %w", err)
+		return fmt.Errorf("failed to update conversation state: %w", err)
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("contacts sync failed: %s", resp.Error)
+		return fmt.Errorf("conversation state update failed: %s", resp.Error)
 	}
 
-	log.Printf("✅ Contacts sync completed: %d processed", resp.ProcessedCount)
+	c.log(integrationCtx).Debug("Conversation state updated", "conversation_id", conversationID, "fields", fields)
 	return nil
 }
 
-// SyncMessages sends messages for a conversation to backend via streaming gRPC
-func (c *IntegrationClient) SyncMessages(ctx context.Context, integrationCtx *proto.IntegrationContext, conversationID string, messages []*proto.Message) error {
-	stream, err := c.client.SyncMessages(ctx)
+// UpdatePresence forwards the presence of a contact the user subscribed to.
+// Presence is ephemeral and meaningless on replay, so it isn't recorded.
+func (c *IntegrationClient) UpdatePresence(ctx context.Context, integrationCtx *proto.IntegrationContext, presence *proto.Presence) error {
+	req := &proto.UpdatePresenceRequest{
+		Context:  integrationCtx,
+		Presence: presence,
+	}
+
+	resp, err := c.client.UpdatePresence(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to create messages sync stream: %w", err)
+		return fmt.Errorf("failed to update presence: %w", err)
 	}
 
-	const batchSize = 200
-	totalBatches := (len(messages) + batchSize - 1) / batchSize
+	if !resp.Success {
+		return fmt.Errorf("presence update failed: %s", resp.Error)
+	}
 
-	for i := 0; i < len(messages); i += batchSize {
-		end := i + batchSize
-		if end > len(messages) {
-			end = len(messages)
-		}
+	c.log(integrationCtx).Debug("Presence updated", "external_user_id", presence.ExternalUserId)
+	return nil
+}
 
-		batch := messages[i:end]
-		isFinal := i+batchSize >= len(messages)
+// UpdateParticipantRoles forwards group participants' new roles after they
+// were promoted or demoted. changedBy and changedAt may be empty when unknown.
+func (c *IntegrationClient) UpdateParticipantRoles(ctx context.Context, integrationCtx *proto.IntegrationContext, conversationID string, participants []*proto.ConversationParticipant, changedBy string, changedAt time.Time) error {
+	req := &proto.UpdateParticipantRolesRequest{
+		Context:                integrationCtx,
+		ConversationExternalId: conversationID,
+		Participants:           participants,
+		ChangedByExternalId:    changedBy,
+	}
+	if !changedAt.IsZero() {
+		req.ChangedAt = timestamppb.New(changedAt)
+	}
 
-		req := &proto.SyncMessagesRequest{
-			Context:                integrationCtx,
-			ConversationExternalId: conversationID,
-			Messages:               batch,
-			IsFinalBatch:           isFinal,
-			BatchNumber:            int32(i/batchSize + 1),
-		}
+	c.record(ctx, "UpdateParticipantRoles", req, map[string]interface{}{
+		"conversation_id":   conversationID,
+		"participant_count": len(participants),
+	})
 
-		if err := stream.Send(req); err != nil {
-			return fmt.Errorf("failed to send message batch: %w", err)
-		}
+	resp, err := c.client.UpdateParticipantRoles(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to update participant roles: %w", err)
+	}
 
-		log.Printf("📤 Sent message batch %d/%d (%d messages) for conversation %s", i/batchSize+1, totalBatches, len(batch), conversationID)
+	if !resp.Success {
+		return fmt.Errorf("participant role update failed: %s", resp.Error)
 	}
 
-	resp, err := stream.CloseAndRecv()
+	c.log(integrationCtx).Debug("Participant roles updated", "conversation_id", conversationID, "changed", resp.ChangedCount)
+	return nil
+}
+
+// UpdateOutboxDelivery reports a delivery or read receipt for a message sent
+// from the backend's outbox
+func (c *IntegrationClient) UpdateOutboxDelivery(ctx context.Context, integrationCtx *proto.IntegrationContext, clientMsgUUID, waMessageID, conversationID string, status proto.MessageStatus, at time.Time) error {
+	req := &proto.UpdateOutboxDeliveryRequest{
+		Context:                integrationCtx,
+		ClientMsgUuid:          clientMsgUUID,
+		WaMessageId:            waMessageID,
+		ConversationExternalId: conversationID,
+		Status:                 status,
+	}
+	if !at.IsZero() {
+		req.Timestamp = timestamppb.New(at)
+	}
+
+	c.record(ctx, "UpdateOutboxDelivery", req, map[string]interface{}{
+		"client_msg_uuid": clientMsgUUID,
+		"wa_message_id":   waMessageID,
+		"status":          status.String(),
+	})
+
+	resp, err := c.client.UpdateOutboxDelivery(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to close messages sync stream: %w", err)
+		return fmt.Errorf("failed to update outbox delivery: %w", err)
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("messages sync failed: %s", resp.Error)
+		return fmt.Errorf("outbox delivery update failed: %s", resp.Error)
 	}
 
-	log.Printf("✅ Messages sync completed for conversation %s: %d processed", conversationID, resp.ProcessedCount)
+	c.log(integrationCtx).Debug("Outbox delivery updated", "client_msg_uuid", clientMsgUUID, "status", status.String(), "applied", resp.Applied)
 	return nil
 }
 
-// ProcessMessage sends a single real-time message to the backend
-func (c *IntegrationClient) ProcessMessage(ctx context.Context, integrationCtx *proto.IntegrationContext, message *proto.Message) error {
-	req := &proto.ProcessMessageRequest{
-		Context: integrationCtx,
-		Message: message,
+// UpdateMessageDelivery reports a delivery or read receipt for a message the
+// account sent from its phone or another linked device
+func (c *IntegrationClient) UpdateMessageDelivery(ctx context.Context, integrationCtx *proto.IntegrationContext, waMessageID, conversationID string, status proto.MessageStatus, at time.Time) error {
+	req := &proto.UpdateMessageDeliveryRequest{
+		Context:                integrationCtx,
+		WaMessageId:            waMessageID,
+		ConversationExternalId: conversationID,
+		Status:                 status,
+	}
+	if !at.IsZero() {
+		req.Timestamp = timestamppb.New(at)
 	}
 
-	resp, err := c.client.ProcessMessage(ctx, req)
+	c.record(ctx, "UpdateMessageDelivery", req, map[string]interface{}{
+		"wa_message_id": waMessageID,
+		"status":        status.String(),
+	})
+
+	resp, err := c.client.UpdateMessageDelivery(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to process message: %w", err)
+		return fmt.Errorf("failed to update message delivery: %w", err)
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("message processing failed: %s", resp.Error)
+		return fmt.Errorf("message delivery update failed: %s", resp.Error)
 	}
 
-	log.Printf("✅ Real-time message processed: platform_id=%s, internal_id=%s", message.PlatformId, resp.InternalMessageId)
+	c.log(integrationCtx).Debug("Message delivery updated", "wa_message_id", waMessageID, "status", status.String(), "applied", resp.Applied)
 	return nil
 }
 
-// UpdateConversationState updates conversation state (pin, mute, archive)
-func (c *IntegrationClient) UpdateConversationState(ctx context.Context, integrationCtx *proto.IntegrationContext, conversationID string, state *proto.ConversationState) error {
-	req := &proto.UpdateConversationStateRequest{
-		Context:                integrationCtx,
-		ConversationExternalId: conversationID,
-		State:                  state,
+// mediaChunkSize is how much of a file each UploadMedia request carries
+const mediaChunkSize = 256 * 1024
+
+// GetMissingMedia returns which of the content hashes the backend has no file for
+func (c *IntegrationClient) GetMissingMedia(ctx context.Context, integrationCtx *proto.IntegrationContext, contentHashes []string) ([]string, error) {
+	resp, err := c.media.GetMissingMedia(ctx, &proto.GetMissingMediaRequest{
+		Context:       integrationCtx,
+		ContentHashes: contentHashes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get missing media: %w", err)
 	}
+	return resp.MissingHashes, nil
+}
 
-	resp, err := c.client.UpdateConversationState(ctx, req)
+// UploadMedia stores a downloaded file in the backend under its content hash
+func (c *IntegrationClient) UploadMedia(ctx context.Context, integrationCtx *proto.IntegrationContext, contentHash, mimeType string, data []byte) error {
+	stream, err := c.media.UploadMedia(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to update conversation state: %w", err)
+		return fmt.Errorf("failed to create media upload stream: %w", err)
+	}
+
+	err = stream.Send(&proto.UploadMediaRequest{Header: &proto.UploadMediaHeader{
+		Context:     integrationCtx,
+		ContentHash: contentHash,
+		MimeType:    mimeType,
+	}})
+	for i := 0; err == nil && i < len(data); i += mediaChunkSize {
+		err = stream.Send(&proto.UploadMediaRequest{Data: data[i:min(i+mediaChunkSize, len(data))]})
+	}
+	// io.EOF means the backend ended the upload early; its response says why
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to send media: %w", err)
 	}
 
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return fmt.Errorf("failed to upload media: %w", err)
+	}
 	if !resp.Success {
-		return fmt.Errorf("conversation state update failed: %s", resp.Error)
+		return fmt.Errorf("backend rejected media: %s", resp.Error)
 	}
 
-	log.Printf("✅ Conversation state updated: conversation=%s", conversationID)
+	c.log(integrationCtx).Debug("Media uploaded",
+		"content_hash", contentHash,
+		"size", len(data),
+		"already_stored", resp.AlreadyStored,
+		"linked_media", resp.LinkedMediaCount)
 	return nil
 }
@@ -0,0 +1,144 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/tennex/bridge/internal/recorder"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+// fakeBackend counts the integration service calls it receives
+type fakeBackend struct {
+	proto.UnimplementedIntegrationServiceServer
+
+	mu    sync.Mutex
+	calls []string
+
+	// SyncMessages batches stored, by batch number, and the last batch stored
+	// per sync, so resent batches are acknowledged without being stored again
+	stored  map[int32]int
+	cursors map[string]int32
+	breakAt int32 // The batch whose stream breaks, 0 for none
+	breaks  int   // How many times it breaks, negative for every time
+	loseAck bool  // Whether it breaks after storing the batch, not before
+}
+
+func (b *fakeBackend) called(method string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, method)
+}
+
+func (b *fakeBackend) CreateUserIntegration(ctx context.Context, req *proto.CreateUserIntegrationRequest) (*proto.CreateUserIntegrationResponse, error) {
+	b.called("CreateUserIntegration")
+	return &proto.CreateUserIntegrationResponse{Success: true, UserIntegrationId: 7}, nil
+}
+
+func (b *fakeBackend) UpdateConnectionStatus(ctx context.Context, req *proto.UpdateConnectionStatusRequest) (*proto.UpdateConnectionStatusResponse, error) {
+	b.called("UpdateConnectionStatus")
+	return &proto.UpdateConnectionStatusResponse{Success: true}, nil
+}
+
+func (b *fakeBackend) ProcessMessage(ctx context.Context, req *proto.ProcessMessageRequest) (*proto.ProcessMessageResponse, error) {
+	b.called("ProcessMessage")
+	return &proto.ProcessMessageResponse{Success: true}, nil
+}
+
+// startClient connects an IntegrationClient to a fake backend over an
+// in-memory listener
+func startClient(t *testing.T, backend *fakeBackend) *IntegrationClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	proto.RegisterIntegrationServiceServer(server, backend)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	client, err := NewIntegrationClient("passthrough:///bufnet", slog.New(slog.DiscardHandler),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}))
+	if err != nil {
+		t.Fatalf("NewIntegrationClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestPairingRecordsOneSessionThroughOneClient(t *testing.T) {
+	ctx := context.Background()
+	backend := &fakeBackend{}
+	client := startClient(t, backend)
+
+	dir := t.TempDir()
+	client.recorder = recorder.NewRecorder(recorder.ModeRecord, dir)
+
+	if err := client.CheckConnectivity(ctx); err != nil {
+		t.Fatalf("CheckConnectivity: %v", err)
+	}
+
+	// The calls a successful QR pairing makes, followed by the first message
+	const userID = "8d4f1c2e-0000-4000-8000-000000000001"
+	if err := client.StartRecordingSession(userID, "whatsapp"); err != nil {
+		t.Fatalf("StartRecordingSession: %v", err)
+	}
+	integrationID, err := client.CreateUserIntegration(ctx, userID, "972500000000@s.whatsapp.net", "972500000000:3@s.whatsapp.net", "", "", nil)
+	if err != nil {
+		t.Fatalf("CreateUserIntegration: %v", err)
+	}
+	integrationCtx := &proto.IntegrationContext{UserId: userID, UserIntegrationId: integrationID, IntegrationType: "whatsapp"}
+	if err := client.UpdateConnectionStatus(ctx, integrationCtx, proto.ConnectionStatus_CONNECTION_STATUS_CONNECTED, "", nil); err != nil {
+		t.Fatalf("UpdateConnectionStatus: %v", err)
+	}
+	if err := client.ProcessMessage(ctx, integrationCtx, &proto.Message{PlatformId: "M1"}); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	if err := client.EndRecordingSession(); err != nil {
+		t.Fatalf("EndRecordingSession: %v", err)
+	}
+
+	wantCalls := []string{"CreateUserIntegration", "UpdateConnectionStatus", "ProcessMessage"}
+	if len(backend.calls) != len(wantCalls) {
+		t.Fatalf("expected backend calls %v, got %v", wantCalls, backend.calls)
+	}
+	for i, call := range wantCalls {
+		if backend.calls[i] != call {
+			t.Fatalf("expected backend calls %v, got %v", wantCalls, backend.calls)
+		}
+	}
+
+	sessions, err := recorder.ListSessions(dir)
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("expected one recording session, got %v: %v", sessions, err)
+	}
+	session, err := recorder.LoadSession(filepath.Join(dir, sessions[0]))
+	if err != nil {
+		t.Fatalf("LoadSession: %v", err)
+	}
+	if session.CompletedAt == nil {
+		t.Error("expected the session to be completed")
+	}
+	// Status updates aren't recorded
+	wantRecorded := []string{"CreateUserIntegration", "ProcessMessage"}
+	if len(session.Recordings) != len(wantRecorded) {
+		t.Fatalf("expected recordings %v, got %+v", wantRecorded, session.Recordings)
+	}
+	for i, recording := range session.Recordings {
+		if recording.RequestType != wantRecorded[i] {
+			t.Errorf("recording %d: expected %s, got %s", i, wantRecorded[i], recording.RequestType)
+		}
+		if _, err := os.Stat(filepath.Join(dir, sessions[0], recording.PayloadFile)); err != nil {
+			t.Errorf("recording %d has no payload: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/tennex/pkg/reconnect"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+// Sync batch sizes, in conversations, contacts and messages
+const (
+	conversationsBatchSize = 50
+	contactsBatchSize      = 100
+	messagesBatchSize      = 200
+)
+
+// syncAttempts is how many streams in a row a sync may lose without the
+// backend acknowledging a batch before the client gives up on it
+const syncAttempts = 5
+
+// defaultSyncBackoff paces the streams opened to resume a sync
+var defaultSyncBackoff = reconnect.Backoff{Initial: time.Second, Max: 30 * time.Second}
+
+// errSyncRejected is returned when the backend refuses a sync batch
+var errSyncRejected = errors.New("sync rejected by backend")
+
+// syncAck is the acknowledgement the backend sends for each batch of a sync
+type syncAck interface {
+	GetSuccess() bool
+	GetError() string
+	GetProcessedCount() int32
+	GetBatchNumber() int32
+}
+
+// syncOpener opens a sync stream, like IntegrationServiceClient.SyncMessages
+type syncOpener[Req, Resp any] func(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Req, Resp], error)
+
+// startSync creates the job for a sync of count items sent in batches of
+// batchSize, queueing it with req, the whole sync, when the client has a
+// queue. A sync that can't be queued is still sent.
+func (c *IntegrationClient) startSync(method string, count, batchSize int, req protobuf.Message) *syncJob {
+	job := &syncJob{
+		SyncID:    uuid.NewString(),
+		Method:    method,
+		BatchSize: batchSize,
+		Batches:   int32((count + batchSize - 1) / batchSize),
+		QueuedAt:  time.Now(),
+	}
+	if c.queue == nil || job.Batches == 0 {
+		return job
+	}
+
+	if err := c.queue.add(job, req); err != nil {
+		c.logger.Warn("Failed to queue sync, it won't resume after a restart", "method", method, "error", err)
+		return job
+	}
+	job.queued = true
+	return job
+}
+
+// dequeueSync drops a job from the client's queue
+func (c *IntegrationClient) dequeueSync(job *syncJob) {
+	if !job.queued {
+		return
+	}
+	if err := c.queue.remove(job.SyncID); err != nil {
+		c.logger.Warn("Failed to dequeue sync", "sync_id", job.SyncID, "error", err)
+	}
+	job.queued = false
+}
+
+// ResumeSyncs streams the syncs a previous process left in the queue, from
+// the first batch the backend hadn't acknowledged. A sync that fails again
+// stays queued for the next start, unless the backend rejected it.
+func (c *IntegrationClient) ResumeSyncs(ctx context.Context) error {
+	if c.queue == nil {
+		return nil
+	}
+	jobs, err := c.queue.pending()
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		c.logger.Info("Resuming sync",
+			"method", job.Method,
+			"sync_id", job.SyncID,
+			"acked", job.Acked,
+			"batches", job.Batches)
+		if err := c.resumeSync(ctx, job); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.logger.Warn("Failed to resume sync", "method", job.Method, "sync_id", job.SyncID, "error", err)
+		}
+	}
+	return nil
+}
+
+// resumeSync streams a queued job
+func (c *IntegrationClient) resumeSync(ctx context.Context, job *syncJob) error {
+	switch job.Method {
+	case "SyncConversations":
+		req := &proto.SyncConversationsRequest{}
+		if err := c.loadSync(job, req); err != nil {
+			return err
+		}
+		return c.syncConversations(ctx, job, req)
+	case "SyncContacts":
+		req := &proto.SyncContactsRequest{}
+		if err := c.loadSync(job, req); err != nil {
+			return err
+		}
+		return c.syncContacts(ctx, job, req)
+	case "SyncMessages":
+		req := &proto.SyncMessagesRequest{}
+		if err := c.loadSync(job, req); err != nil {
+			return err
+		}
+		return c.syncMessages(ctx, job, req)
+	default:
+		c.dequeueSync(job)
+		return fmt.Errorf("unknown sync method %q", job.Method)
+	}
+}
+
+// loadSync loads a queued job's sync into req, dropping the job when it can't
+// be read, as it won't read any better next time
+func (c *IntegrationClient) loadSync(job *syncJob, req protobuf.Message) error {
+	if err := c.queue.payload(job.SyncID, req); err != nil {
+		c.dequeueSync(job)
+		return err
+	}
+	return nil
+}
+
+// runSync streams the batches of job the backend hasn't acknowledged, built
+// by batch. When the stream breaks it opens a new one and resends from the
+// first unacknowledged batch, so the backend stores each batch once. It
+// returns the number of items the backend stored. The job stays queued when
+// the client gives up on it or ctx is done, so a restart resumes it.
+func runSync[Req, Resp any, Ack interface {
+	*Resp
+	syncAck
+}](ctx context.Context, c *IntegrationClient, integrationCtx *proto.IntegrationContext, job *syncJob, open syncOpener[Req, Resp], batch func(number int32) *Req) (int32, error) {
+	var processed int32
+	failures := 0
+	for job.Acked < job.Batches {
+		acked := job.Acked
+		stored, err := sendSyncBatches[Req, Resp, Ack](ctx, c, integrationCtx, job, open, batch)
+		processed += stored
+		// Once every batch is acknowledged, the sync is stored even if the
+		// stream then failed to close cleanly
+		if err == nil || job.Acked == job.Batches {
+			break
+		}
+		if ctx.Err() != nil {
+			return processed, ctx.Err()
+		}
+		if !retryableSyncError(err) {
+			c.dequeueSync(job)
+			return processed, err
+		}
+
+		if job.Acked > acked {
+			failures = 0
+		}
+		failures++
+		if failures >= syncAttempts {
+			return processed, fmt.Errorf("gave up after %d broken streams: %w", failures, err)
+		}
+
+		delay := c.syncBackoff.Delay(failures)
+		c.log(integrationCtx).Warn("Sync stream broke, resuming",
+			"method", job.Method,
+			"sync_id", job.SyncID,
+			"acked", job.Acked,
+			"batches", job.Batches,
+			"retry_in", delay,
+			"error", err)
+		select {
+		case <-ctx.Done():
+			return processed, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	c.dequeueSync(job)
+	return processed, nil
+}
+
+// sendSyncBatches sends the batches of job the backend hasn't acknowledged on
+// a new stream, waiting for each to be acknowledged before sending the next.
+// It returns the number of items the backend stored.
+func sendSyncBatches[Req, Resp any, Ack interface {
+	*Resp
+	syncAck
+}](ctx context.Context, c *IntegrationClient, integrationCtx *proto.IntegrationContext, job *syncJob, open syncOpener[Req, Resp], batch func(number int32) *Req) (int32, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := open(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open sync stream: %w", err)
+	}
+
+	var processed int32
+	for number := job.Acked + 1; number <= job.Batches; number++ {
+		if err := stream.Send(batch(number)); err != nil {
+			if err == io.EOF {
+				// The stream is gone, and Recv says why
+				_, err = stream.Recv()
+			}
+			return processed, err
+		}
+
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return processed, fmt.Errorf("backend ended the stream before acknowledging batch %d", number)
+		}
+		if err != nil {
+			return processed, err
+		}
+		ack := Ack(resp)
+		if !ack.GetSuccess() {
+			return processed, fmt.Errorf("%w: %s", errSyncRejected, ack.GetError())
+		}
+		if ack.GetBatchNumber() != number {
+			return processed, fmt.Errorf("backend acknowledged batch %d, expected %d", ack.GetBatchNumber(), number)
+		}
+
+		processed += ack.GetProcessedCount()
+		job.Acked = number
+		if job.queued {
+			if err := c.queue.save(job); err != nil {
+				c.logger.Warn("Failed to save sync progress", "sync_id", job.SyncID, "error", err)
+			}
+		}
+
+		c.log(integrationCtx).Debug("Sync batch acknowledged",
+			"method", job.Method,
+			"batch", number,
+			"total_batches", job.Batches,
+			"processed", ack.GetProcessedCount())
+	}
+
+	// Every batch is stored; let the backend finish with the stream
+	if err := stream.CloseSend(); err != nil {
+		return processed, err
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		if err == nil {
+			err = errors.New("backend sent an acknowledgement after the last batch")
+		}
+		return processed, err
+	}
+	return processed, nil
+}
+
+// retryableSyncError reports whether a sync stream failed in a way a new
+// stream may not, like the connection dropping
+func retryableSyncError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Aborted, codes.Internal:
+		return true
+	}
+	return false
+}
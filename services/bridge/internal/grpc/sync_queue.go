@@ -0,0 +1,137 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// SyncQueue persists the syncs the client is streaming to the backend, with
+// how many of their batches the backend has acknowledged, so a sync cut short
+// by a restart is resumed by the next process. Each sync is stored as the
+// whole sync request, written once, and a manifest rewritten per batch.
+type SyncQueue struct {
+	dir string
+}
+
+// syncJob is a sync being streamed to the backend, and its queue manifest
+type syncJob struct {
+	SyncID    string    `json:"sync_id"`
+	Method    string    `json:"method"`
+	BatchSize int       `json:"batch_size"`
+	Batches   int32     `json:"batches"`
+	Acked     int32     `json:"acked"` // Batches the backend has acknowledged
+	QueuedAt  time.Time `json:"queued_at"`
+
+	queued bool // Whether the job is in the client's queue
+}
+
+// bounds returns the range of items in the given batch, counting from 1
+func (j *syncJob) bounds(batch int32, count int) (int, int) {
+	start := int(batch-1) * j.BatchSize
+	return start, min(start+j.BatchSize, count)
+}
+
+// NewSyncQueue creates a sync queue in dir, creating the directory if needed.
+// Queued syncs hold message content, so only the bridge's user can read them.
+func NewSyncQueue(dir string) (*SyncQueue, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create sync queue directory: %w", err)
+	}
+	// A directory created by an older bridge was readable by everyone
+	if err := os.Chmod(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to restrict sync queue directory: %w", err)
+	}
+	return &SyncQueue{dir: dir}, nil
+}
+
+// add queues a job with req, the whole sync
+func (q *SyncQueue) add(job *syncJob, req protobuf.Message) error {
+	payload, err := protobuf.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync: %w", err)
+	}
+	if err := q.write(job.SyncID+".pb", payload); err != nil {
+		return err
+	}
+	return q.save(job)
+}
+
+// save rewrites a queued job's manifest, e.g. after a batch is acknowledged
+func (q *SyncQueue) save(job *syncJob) error {
+	manifest, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return q.write(job.SyncID+".json", manifest)
+}
+
+// remove drops a job from the queue
+func (q *SyncQueue) remove(syncID string) error {
+	// The manifest goes first, so a job is never listed without its payload
+	for _, name := range []string{syncID + ".json", syncID + ".pb"} {
+		if err := os.Remove(filepath.Join(q.dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove queued sync: %w", err)
+		}
+	}
+	return nil
+}
+
+// pending lists the queued jobs, oldest first
+func (q *SyncQueue) pending() ([]*syncJob, error) {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync queue: %w", err)
+	}
+
+	var jobs []*syncJob
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(q.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read queued sync: %w", err)
+		}
+		job := &syncJob{queued: true}
+		if err := json.Unmarshal(data, job); err != nil {
+			return nil, fmt.Errorf("failed to parse queued sync %s: %w", entry.Name(), err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].QueuedAt.Before(jobs[j].QueuedAt) })
+	return jobs, nil
+}
+
+// payload loads a queued job's sync into req
+func (q *SyncQueue) payload(syncID string, req protobuf.Message) error {
+	data, err := os.ReadFile(filepath.Join(q.dir, syncID+".pb"))
+	if err != nil {
+		return fmt.Errorf("failed to read queued sync: %w", err)
+	}
+	if err := protobuf.Unmarshal(data, req); err != nil {
+		return fmt.Errorf("failed to unmarshal queued sync: %w", err)
+	}
+	return nil
+}
+
+// write replaces a file in the queue, so a crash mid-write leaves the old
+// contents rather than a torn file
+func (q *SyncQueue) write(name string, data []byte) error {
+	path := filepath.Join(q.dir, name)
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return fmt.Errorf("failed to write queued sync: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write queued sync: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tennex/pkg/reconnect"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+func (b *fakeBackend) SyncMessages(stream grpc.BidiStreamingServer[proto.SyncMessagesRequest, proto.SyncMessagesResponse]) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		b.mu.Lock()
+		broken := req.BatchNumber == b.breakAt && b.breaks != 0
+		if broken {
+			b.breaks--
+		}
+		if broken && !b.loseAck {
+			b.mu.Unlock()
+			return status.Error(codes.Unavailable, "connection reset")
+		}
+		var processed int32
+		if req.BatchNumber > b.cursors[req.SyncId] {
+			b.stored[req.BatchNumber]++
+			b.cursors[req.SyncId] = req.BatchNumber
+			processed = int32(len(req.Messages))
+		}
+		b.mu.Unlock()
+		if broken {
+			return status.Error(codes.Unavailable, "connection reset")
+		}
+
+		if err := stream.Send(&proto.SyncMessagesResponse{Success: true, ProcessedCount: processed, BatchNumber: req.BatchNumber}); err != nil {
+			return err
+		}
+	}
+}
+
+// newSyncBackend returns a backend whose SyncMessages streams break at batch
+// breakAt, breaks times
+func newSyncBackend(breakAt int32, breaks int) *fakeBackend {
+	return &fakeBackend{
+		stored:  make(map[int32]int),
+		cursors: make(map[string]int32),
+		breakAt: breakAt,
+		breaks:  breaks,
+	}
+}
+
+// startSyncClient starts a client whose broken sync streams are resumed
+// without waiting
+func startSyncClient(t *testing.T, backend *fakeBackend) *IntegrationClient {
+	client := startClient(t, backend)
+	client.syncBackoff = reconnect.Backoff{Initial: time.Millisecond, Max: time.Millisecond}
+	return client
+}
+
+// testMessages returns enough messages for 5 sync batches
+func testMessages() []*proto.Message {
+	messages := make([]*proto.Message, 5*messagesBatchSize)
+	for i := range messages {
+		messages[i] = &proto.Message{PlatformId: fmt.Sprintf("M%d", i)}
+	}
+	return messages
+}
+
+// checkStoredOnce fails unless each of 5 batches was stored exactly once
+func checkStoredOnce(t *testing.T, backend *fakeBackend) {
+	t.Helper()
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	for batch := int32(1); batch <= 5; batch++ {
+		if backend.stored[batch] != 1 {
+			t.Errorf("expected batch %d to be stored once, stored %d times", batch, backend.stored[batch])
+		}
+	}
+}
+
+func TestSyncResumesBrokenStream(t *testing.T) {
+	integrationCtx := &proto.IntegrationContext{UserId: "user", UserIntegrationId: 7}
+
+	for _, loseAck := range []bool{false, true} {
+		t.Run(fmt.Sprintf("lose_ack=%v", loseAck), func(t *testing.T) {
+			// The stream breaks at batch 3 of 5, before or after storing it
+			backend := newSyncBackend(3, 1)
+			backend.loseAck = loseAck
+			client := startSyncClient(t, backend)
+
+			if err := client.SyncMessages(context.Background(), integrationCtx, "chat@s.whatsapp.net", testMessages()); err != nil {
+				t.Fatalf("SyncMessages: %v", err)
+			}
+			checkStoredOnce(t, backend)
+		})
+	}
+}
+
+func TestResumeSyncsAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	integrationCtx := &proto.IntegrationContext{UserId: "user", UserIntegrationId: 7}
+	dir := t.TempDir()
+
+	// The backend goes away at batch 3 of 5 for longer than the client retries
+	backend := newSyncBackend(3, -1)
+	queue, err := NewSyncQueue(dir)
+	if err != nil {
+		t.Fatalf("NewSyncQueue: %v", err)
+	}
+	client := startSyncClient(t, backend)
+	client.UseSyncQueue(queue)
+	if err := client.SyncMessages(ctx, integrationCtx, "chat@s.whatsapp.net", testMessages()); err == nil {
+		t.Fatal("expected the sync to give up")
+	}
+
+	jobs, err := queue.pending()
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("expected the sync to stay queued, got %+v: %v", jobs, err)
+	}
+	if jobs[0].Acked != 2 {
+		t.Errorf("expected 2 batches acknowledged, got %d", jobs[0].Acked)
+	}
+
+	// A restarted bridge resumes from batch 3
+	backend.mu.Lock()
+	backend.breaks = 0
+	backend.mu.Unlock()
+	queue, err = NewSyncQueue(dir)
+	if err != nil {
+		t.Fatalf("NewSyncQueue: %v", err)
+	}
+	client = startSyncClient(t, backend)
+	client.UseSyncQueue(queue)
+	if err := client.ResumeSyncs(ctx); err != nil {
+		t.Fatalf("ResumeSyncs: %v", err)
+	}
+	checkStoredOnce(t, backend)
+
+	if jobs, err := queue.pending(); err != nil || len(jobs) != 0 {
+		t.Errorf("expected the queue to be empty, got %+v: %v", jobs, err)
+	}
+}
+
+func TestSyncQueueIsPrivate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "syncs")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	queue, err := NewSyncQueue(dir)
+	if err != nil {
+		t.Fatalf("NewSyncQueue: %v", err)
+	}
+	if err := queue.write("sync.pb", []byte("messages")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	for path, want := range map[string]os.FileMode{
+		dir:                           0700,
+		filepath.Join(dir, "sync.pb"): 0600,
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", path, err)
+		}
+		if mode := info.Mode().Perm(); mode != want {
+			t.Errorf("expected %s to have mode %o, got %o", path, want, mode)
+		}
+	}
+}
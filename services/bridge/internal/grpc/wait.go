@@ -0,0 +1,72 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// WaitConfig bounds how long startup retries the backend, so the bridge can
+// start before it (e.g. under docker-compose)
+type WaitConfig struct {
+	Timeout        time.Duration // Total time to keep retrying; <= 0 tries once
+	AttemptTimeout time.Duration // How long a single connectivity check may take
+	InitialBackoff time.Duration // Delay after the first failed attempt
+	MaxBackoff     time.Duration // Upper bound on the delay between attempts
+}
+
+// DefaultWaitConfig returns the startup wait used unless overridden
+func DefaultWaitConfig() WaitConfig {
+	return WaitConfig{
+		Timeout:        60 * time.Second,
+		AttemptTimeout: 5 * time.Second,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+	}
+}
+
+// WaitForBackend checks connectivity until the backend is reachable or
+// config.Timeout has passed
+func (c *IntegrationClient) WaitForBackend(ctx context.Context, config WaitConfig) error {
+	return waitFor(ctx, "backend", config, func(ctx context.Context) error {
+		attemptCtx, cancel := context.WithTimeout(ctx, config.AttemptTimeout)
+		defer cancel()
+		return c.CheckConnectivity(attemptCtx)
+	}, c.logger)
+}
+
+// waitFor calls connect until it succeeds, doubling the pause between
+// attempts up to config.MaxBackoff. Once config.Timeout has passed it returns
+// the errors of every attempt.
+func waitFor(ctx context.Context, name string, config WaitConfig, connect func(context.Context) error, logger *slog.Logger) error {
+	deadline := time.Now().Add(config.Timeout)
+	backoff := config.InitialBackoff
+	var errs []error
+
+	for attempt := 1; ; attempt++ {
+		err := connect(ctx)
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("Dependency ready", "dependency", name, "attempts", attempt)
+			}
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("attempt %d: %w", attempt, err))
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%s not ready after %d attempts in %s: %w", name, attempt, config.Timeout, errors.Join(errs...))
+		}
+		delay := min(backoff, remaining)
+		logger.Warn("Dependency not ready, retrying", "dependency", name, "attempt", attempt, "retry_in", delay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s not ready after %d attempts: %w", name, attempt, errors.Join(append(errs, ctx.Err())...))
+		case <-time.After(delay):
+		}
+		backoff = min(backoff*2, config.MaxBackoff)
+	}
+}
@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForRetries(t *testing.T) {
+	config := WaitConfig{Timeout: time.Second, InitialBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}
+
+	attempts := 0
+	dial := func(context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	if err := waitFor(context.Background(), "backend", config, dial, slog.New(slog.DiscardHandler)); err != nil {
+		t.Fatalf("waitFor: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestWaitForGivesUp(t *testing.T) {
+	config := WaitConfig{Timeout: 20 * time.Millisecond, InitialBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}
+
+	attempts := 0
+	dial := func(context.Context) error {
+		attempts++
+		return errors.New("connection refused")
+	}
+	err := waitFor(context.Background(), "backend", config, dial, slog.New(slog.DiscardHandler))
+	if err == nil {
+		t.Fatal("expected an error once the wait expires")
+	}
+	if attempts < 2 {
+		t.Errorf("expected several attempts, got %d", attempts)
+	}
+	if !strings.HasPrefix(err.Error(), "backend not ready after") || !strings.Contains(err.Error(), "attempt 1: connection refused") {
+		t.Errorf("expected an error listing every attempt, got %q", err)
+	}
+}
@@ -0,0 +1,5 @@
+package handlers
+
+// DefaultMaxBodyBytes limits bridge request bodies, which only carry small
+// option objects
+const DefaultMaxBodyBytes = 64 << 10 // 64 KiB
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	api "github.com/tennex/bridge/api/gen"
+	"github.com/tennex/pkg/httpx"
+)
+
+func TestOptionalBodiesAreLimited(t *testing.T) {
+	handler := httpx.MaxBodySize(64)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.WhatsAppConnectRequest
+		if err := httpx.Decode(r, &req, 0); err != nil && !errors.Is(err, io.EOF) {
+			httpx.Error(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		body string
+		want int
+		code string
+	}{
+		{``, http.StatusOK, ""},
+		{`{"full_sync":true}`, http.StatusOK, ""},
+		{`{"full_sync":true,"padding":"` + strings.Repeat("x", 128) + `"}`, http.StatusRequestEntityTooLarge, "request_too_large"},
+		{`{"full_sync":`, http.StatusBadRequest, "invalid_request"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/connect", strings.NewReader(tt.body)))
+		if rec.Code != tt.want {
+			t.Errorf("body %.20q: got status %d, want %d", tt.body, rec.Code, tt.want)
+			continue
+		}
+		if tt.code == "" {
+			continue
+		}
+
+		var response api.ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("body %.20q: failed to decode error response: %v", tt.body, err)
+		}
+		if response.Code == nil || *response.Code != tt.code {
+			t.Errorf("body %.20q: got error code %v, want %s", tt.body, response.Code, tt.code)
+		}
+	}
+}
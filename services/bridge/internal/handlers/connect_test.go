@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	api "github.com/tennex/bridge/api/gen"
+	"github.com/tennex/bridge/whatsapp"
+)
+
+// fakeConnector runs connection flows that publish one QR code once released
+type fakeConnector struct {
+	pairing *whatsapp.PairingSessions
+	release chan struct{}
+	runs    atomic.Int32
+}
+
+func (c *fakeConnector) Pairing() *whatsapp.PairingSessions  { return c.pairing }
+func (c *fakeConnector) Sessions() *whatsapp.SessionRegistry { return nil }
+
+func (c *fakeConnector) RunWhatsAppConnectionFlow(ctx context.Context, accountID string, options whatsapp.ConnectOptions, pairingSession *whatsapp.PairingSession) error {
+	n := c.runs.Add(1)
+	go func() {
+		select {
+		case <-c.release:
+		case <-ctx.Done():
+			return
+		}
+		expiresAt := time.Now().Add(time.Minute)
+		c.pairing.Publish(pairingSession, whatsapp.PairingEvent{Type: whatsapp.PairingEventCode, Code: fmt.Sprintf("code-%d", n), ExpiresAt: &expiresAt})
+	}()
+	return nil
+}
+
+func connect(h *WhatsAppHandler, userID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/connect", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "user_id", userID))
+	rec := httptest.NewRecorder()
+	h.ConnectWhatsApp(rec, req)
+	return rec
+}
+
+func TestConcurrentConnectsShareOnePairing(t *testing.T) {
+	connector := &fakeConnector{
+		pairing: whatsapp.NewPairingSessions(whatsapp.PairingConfig{FirstCodeWait: 5 * time.Second}),
+		release: make(chan struct{}),
+	}
+	h := NewWhatsAppHandler(nil, connector, nil, slog.Default())
+	userID := uuid.NewString()
+
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 2)
+	for i := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = connect(h, userID)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(connector.release)
+	wg.Wait()
+
+	if runs := connector.runs.Load(); runs != 1 {
+		t.Fatalf("expected one connection flow, got %d", runs)
+	}
+	var sessionIDs []uuid.UUID
+	for i, rec := range responses {
+		if rec.Code != http.StatusOK {
+			t.Fatalf("connect %d: got status %d: %s", i, rec.Code, rec.Body)
+		}
+		var response api.WhatsAppConnectResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("connect %d: failed to decode response: %v", i, err)
+		}
+		if response.QrCode != "code-1" {
+			t.Errorf("connect %d: got QR code %q, want the shared one", i, response.QrCode)
+		}
+		sessionIDs = append(sessionIDs, response.SessionId)
+	}
+	if sessionIDs[0] != sessionIDs[1] {
+		t.Errorf("expected one pairing session, got %v", sessionIDs)
+	}
+
+	// Once the pairing ends, the next connect starts a new flow
+	session, _ := connector.pairing.Get(sessionIDs[0].String())
+	connector.pairing.Publish(session, whatsapp.PairingEvent{Type: whatsapp.PairingEventSuccess})
+	if rec := connect(h, userID); rec.Code != http.StatusOK || connector.runs.Load() != 2 {
+		t.Errorf("expected a new flow after the pairing ended, got status %d and %d flows", rec.Code, connector.runs.Load())
+	}
+}
+
+func TestConnectWhilePairingWithoutAValidCodeConflicts(t *testing.T) {
+	connector := &fakeConnector{
+		pairing: whatsapp.NewPairingSessions(whatsapp.PairingConfig{FirstCodeWait: 20 * time.Millisecond}),
+		release: make(chan struct{}), // Never released, so no code is issued
+	}
+	h := NewWhatsAppHandler(nil, connector, nil, slog.Default())
+	userID := uuid.NewString()
+
+	if rec := connect(h, userID); rec.Code != http.StatusRequestTimeout {
+		t.Fatalf("first connect: got status %d, want %d", rec.Code, http.StatusRequestTimeout)
+	}
+
+	rec := connect(h, userID)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("second connect: got status %d, want %d", rec.Code, http.StatusConflict)
+	}
+	var response api.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Code == nil || *response.Code != "pairing_in_progress" || response.Details == nil || (*response.Details)["status"] != "waiting_for_code" {
+		t.Errorf("unexpected conflict response: %+v", response)
+	}
+	if runs := connector.runs.Load(); runs != 1 {
+		t.Errorf("expected one connection flow, got %d", runs)
+	}
+}
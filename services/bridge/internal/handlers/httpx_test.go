@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// Responses go through pkg/httpx so every handler answers with the same
+// shapes; a private copy of the helpers would drift again
+func TestHandlersDoNotDefineTheirOwnResponseHelpers(t *testing.T) {
+	fset := token.NewFileSet()
+	packages, err := parser.ParseDir(fset, ".", func(info fs.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatalf("failed to parse handlers: %v", err)
+	}
+
+	for _, pkg := range packages {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				switch fn.Name.Name {
+				case "writeJSON", "writeError":
+					t.Errorf("%s defines %s; use httpx.JSON and httpx.Error instead", fset.Position(fn.Pos()), fn.Name.Name)
+				}
+			}
+		}
+	}
+}
@@ -1,13 +1,15 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	api "github.com/tennex/bridge/api/gen"
 	"github.com/tennex/bridge/db"
+	"github.com/tennex/pkg/apierror"
+	"github.com/tennex/pkg/httpx"
 	"github.com/tennex/shared/auth"
 )
 
@@ -32,6 +34,10 @@ func (h *MainHandler) Routes() chi.Router {
 	// Public routes (no auth required)
 	r.Get("/health", h.GetHealth)
 
+	// Browsers can't send headers with a WebSocket upgrade, so the pairing
+	// stream also takes its token as a query parameter
+	r.With(h.queryTokenAuth).Get("/whatsapp/pairing/{session_id}/ws", h.whatsappHandler.StreamPairing)
+
 	// Protected routes (JWT required)
 	r.Route("/", func(r chi.Router) {
 		// Apply JWT authentication middleware
@@ -55,7 +61,7 @@ func (h *MainHandler) GetHealth(w http.ResponseWriter, r *http.Request) {
 		Version:   stringPtr("1.0.0"),
 	}
 
-	h.writeJSON(w, http.StatusOK, response)
+	httpx.JSON(w, http.StatusOK, response)
 }
 
 // ListConnections implements GET /connections
@@ -63,7 +69,7 @@ func (h *MainHandler) ListConnections(w http.ResponseWriter, r *http.Request) {
 	// Get authenticated user ID
 	userID, err := auth.GetUserIDFromContext(r.Context())
 	if err != nil {
-		h.writeError(w, http.StatusUnauthorized, "authentication_required", "User must be authenticated", nil)
+		httpx.Error(w, apierror.New(http.StatusUnauthorized, "User must be authenticated").WithCode("authentication_required"))
 		return
 	}
 
@@ -85,28 +91,31 @@ func (h *MainHandler) ListConnections(w http.ResponseWriter, r *http.Request) {
 		Total:       intPtr(len(apiConnections)),
 	}
 
-	h.writeJSON(w, http.StatusOK, response)
-}
-
-// Helper functions
-func (h *MainHandler) writeJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
+	httpx.JSON(w, http.StatusOK, response)
 }
 
-func (h *MainHandler) writeError(w http.ResponseWriter, status int, code, message string, details map[string]interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-
-	response := api.ErrorResponse{
-		Error:     message,
-		Code:      &code,
-		Details:   &details,
-		Timestamp: time.Now(),
-	}
-
-	json.NewEncoder(w).Encode(response)
+// queryTokenAuth authenticates a request by the JWT in its token query
+// parameter, or else its Authorization header
+func (h *MainHandler) queryTokenAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			var err error
+			if token, err = auth.ExtractTokenFromHeader(r.Header.Get("Authorization")); err != nil {
+				httpx.Error(w, apierror.New(http.StatusUnauthorized, "User must be authenticated").WithCode("authentication_required"))
+				return
+			}
+		}
+
+		claims, err := h.jwtConfig.ValidateTokenContext(r.Context(), token)
+		if err != nil {
+			httpx.Error(w, apierror.New(http.StatusUnauthorized, "Invalid or expired token").WithCode("invalid_token"))
+			return
+		}
+		ctx := context.WithValue(r.Context(), auth.UserIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, auth.ClaimsKey, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
 }
 
 func getPlatformFromIntegrationID(integrationID string) api.ConnectionPlatform {
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/tennex/shared/auth"
+)
+
+func TestRevokedTokensAreRefused(t *testing.T) {
+	userID := uuid.New()
+	var changedAt time.Time
+	jwtConfig := auth.DefaultJWTConfig("test-secret")
+	jwtConfig.RevocationCheck = auth.RevokeSessionsBeforePasswordReset(func(ctx context.Context, id uuid.UUID) (time.Time, error) {
+		return changedAt, nil
+	})
+	token, _, err := jwtConfig.GenerateToken(userID, auth.RoleUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := NewMainHandler(nil, nil, jwtConfig).Routes()
+
+	get := func(target string, header bool) int {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if header {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := get("/connections", true); code != http.StatusOK {
+		t.Fatalf("expected the token to be accepted before a reset, got %d", code)
+	}
+
+	// The password is reset after the token was issued, which signs it out
+	// of routes taking it in a header and of the pairing stream taking it in
+	// the query
+	changedAt = time.Now().Add(time.Second)
+	if code := get("/connections", true); code != http.StatusUnauthorized {
+		t.Errorf("/connections: got status %d for a revoked token, want 401", code)
+	}
+	if code := get("/whatsapp/pairing/"+uuid.NewString()+"/ws?token="+token, false); code != http.StatusUnauthorized {
+		t.Errorf("pairing stream: got status %d for a revoked token, want 401", code)
+	}
+	if code := get("/whatsapp/pairing/"+uuid.NewString()+"/ws", true); code != http.StatusUnauthorized {
+		t.Errorf("pairing stream: got status %d for a revoked header token, want 401", code)
+	}
+}
@@ -3,9 +3,13 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -14,22 +18,39 @@ import (
 	"github.com/tennex/bridge/db"
 	backendGRPC "github.com/tennex/bridge/internal/grpc"
 	"github.com/tennex/bridge/whatsapp"
+	"github.com/tennex/pkg/apierror"
+	"github.com/tennex/pkg/httpx"
 	"github.com/tennex/shared/auth"
+	proto "github.com/tennex/shared/proto/gen/proto"
+	"nhooyr.io/websocket"
 )
 
+const (
+	defaultLoadOlderCount = 50
+	maxLoadOlderCount     = 500
+)
+
+// Connector runs WhatsApp connection flows and holds their sessions;
+// *whatsapp.WhatsAppConnector implements it
+type Connector interface {
+	Pairing() *whatsapp.PairingSessions
+	Sessions() *whatsapp.SessionRegistry
+	RunWhatsAppConnectionFlow(ctx context.Context, accountID string, options whatsapp.ConnectOptions, pairingSession *whatsapp.PairingSession) error
+}
+
 type WhatsAppHandler struct {
 	storage           *db.Storage
-	whatsappConnector *whatsapp.WhatsAppConnector
-	backendClient     *backendGRPC.BackendClient
-	integrationClient *backendGRPC.RecordingIntegrationClient
+	whatsappConnector Connector
+	integrationClient *backendGRPC.IntegrationClient
+	logger            *slog.Logger
 }
 
-func NewWhatsAppHandler(storage *db.Storage, whatsappConnector *whatsapp.WhatsAppConnector, backendClient *backendGRPC.BackendClient, integrationClient *backendGRPC.RecordingIntegrationClient) *WhatsAppHandler {
+func NewWhatsAppHandler(storage *db.Storage, whatsappConnector Connector, integrationClient *backendGRPC.IntegrationClient, logger *slog.Logger) *WhatsAppHandler {
 	return &WhatsAppHandler{
 		storage:           storage,
 		whatsappConnector: whatsappConnector,
-		backendClient:     backendClient,
 		integrationClient: integrationClient,
+		logger:            logger.With("component", "whatsapp_handler"),
 	}
 }
 
@@ -47,7 +68,7 @@ func (h *WhatsAppHandler) Routes() chi.Router {
 			if err != nil {
 				log.Printf("🔵 [WA HANDLER DEBUG] ❌ Failed to get user ID from context: %v\n", err)
 				log.Printf("🔵 [WA HANDLER DEBUG] ❌ Context keys available: %+v\n", r.Context())
-				h.writeError(w, http.StatusUnauthorized, "authentication_required", "User must be authenticated", nil)
+				httpx.Error(w, apierror.New(http.StatusUnauthorized, "User must be authenticated").WithCode("authentication_required"))
 				return
 			}
 
@@ -62,6 +83,8 @@ func (h *WhatsAppHandler) Routes() chi.Router {
 	r.Post("/connect", h.ConnectWhatsApp)
 	r.Get("/status", h.GetWhatsAppStatus)
 	r.Post("/disconnect", h.DisconnectWhatsApp)
+	r.Post("/conversations/{external_id}/load-older", h.LoadOlderHistory)
+	r.Post("/resync", h.ResyncHistory)
 
 	return r
 }
@@ -71,71 +94,184 @@ func (h *WhatsAppHandler) ConnectWhatsApp(w http.ResponseWriter, r *http.Request
 	// Get authenticated user ID
 	userIDStr, ok := r.Context().Value("user_id").(string)
 	if !ok {
-		h.writeError(w, http.StatusInternalServerError, "context_error", "Failed to get user ID from context", nil)
+		httpx.Error(w, apierror.New(http.StatusInternalServerError, "Failed to get user ID from context").WithCode("context_error"))
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_user_id", "Invalid user ID format", nil)
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Invalid user ID format").WithCode("invalid_user_id"))
 		return
 	}
 
-	fmt.Printf("🔐 User %s requesting WhatsApp connection\n", userID)
-
-	// Create QR channel for this connection attempt
-	qrChan := make(chan whatsapp.QRCodeData, 1)
-	sessionID := uuid.New()
-
-	fmt.Printf("📱 Starting WhatsApp connection flow for user %s (session: %s)\n", userID, sessionID)
-
-	// Start WhatsApp connection flow in background
-	// Use background context so connection survives HTTP request completion
-	connCtx := context.Background()
-	go func() {
-		fmt.Printf("🚀 [WA DEBUG] Starting WhatsApp connection with background context\n")
-		if err := h.whatsappConnector.RunWhatsAppConnectionFlow(connCtx, userIDStr, qrChan); err != nil {
-			fmt.Printf("❌ WhatsApp connection failed for user %s: %v\n", userID, err)
-		}
-		fmt.Printf("🔚 [WA DEBUG] WhatsApp connection flow completed for user %s\n", userID)
-	}()
+	// The request body is optional
+	var req api.WhatsAppConnectRequest
+	if err := httpx.Decode(r, &req, 0); err != nil && !errors.Is(err, io.EOF) {
+		httpx.Error(w, err)
+		return
+	}
+	options := whatsapp.ConnectOptions{FullSync: req.FullSync != nil && *req.FullSync}
+
+	fmt.Printf("🔐 User %s requesting WhatsApp connection (full sync: %v)\n", userID, options.FullSync)
+
+	// Pairing session that relays this connection attempt's QR codes. While
+	// one is in progress, a repeated connect shares its code instead of
+	// linking a second device.
+	pairingSession, started := h.whatsappConnector.Pairing().Start(userIDStr)
+	if started {
+		fmt.Printf("📱 Starting WhatsApp connection flow for user %s (session: %s)\n", userID, pairingSession.ID)
+
+		// Start WhatsApp connection flow in background. It runs in the
+		// pairing session's context so it survives HTTP request completion
+		// and stops if the pairing is abandoned.
+		go func() {
+			fmt.Printf("🚀 [WA DEBUG] Starting WhatsApp connection in the pairing context\n")
+			if err := h.whatsappConnector.RunWhatsAppConnectionFlow(pairingSession.Context(), userIDStr, options, pairingSession); err != nil {
+				fmt.Printf("❌ WhatsApp connection failed for user %s: %v\n", userID, err)
+				// Release the user's pairing slot; ignored if the flow already
+				// published its outcome
+				h.whatsappConnector.Pairing().Publish(pairingSession, whatsapp.PairingEvent{Type: whatsapp.PairingEventError, Error: "failed to start pairing"})
+			}
+			fmt.Printf("🔚 [WA DEBUG] WhatsApp connection flow completed for user %s\n", userID)
+		}()
+	} else {
+		fmt.Printf("📱 WhatsApp pairing already in progress for user %s (session: %s)\n", userID, pairingSession.ID)
+	}
 
-	// Wait for QR code (with timeout)
-	select {
-	case qrCode := <-qrChan:
+	// Wait for a QR code (with timeout); later ones are streamed from
+	// /whatsapp/pairing/{session_id}/ws
+	waitCtx, cancel := context.WithTimeout(r.Context(), h.whatsappConnector.Pairing().Config().FirstCodeWait)
+	defer cancel()
+	evt, err := validPairingCode(waitCtx, pairingSession)
+	switch {
+	case err == nil && evt.Type == whatsapp.PairingEventCode:
 		fmt.Printf("📲 QR code generated for user %s\n", userID)
 
 		response := api.WhatsAppConnectResponse{
-			QrCode:       string(qrCode),
-			SessionId:    sessionID,
-			ExpiresAt:    timePtr(time.Now().Add(2 * time.Minute)), // QR codes typically expire quickly
+			QrCode:       evt.Code,
+			SessionId:    uuid.MustParse(pairingSession.ID),
+			ExpiresAt:    evt.ExpiresAt,
 			Instructions: stringPtr("Open WhatsApp on your phone, tap Menu > Linked Devices > Link a Device, and scan this QR code"),
 		}
 
-		h.writeJSON(w, http.StatusOK, response)
+		httpx.JSON(w, http.StatusOK, response)
 
-	case <-time.After(30 * time.Second):
-		fmt.Printf("⏰ QR code generation timeout for user %s\n", userID)
-		h.writeError(w, http.StatusRequestTimeout, "qr_timeout", "QR code generation timed out", nil)
+	case !started:
+		status := "waiting_for_code"
+		if err == nil {
+			status = evt.Type
+		}
+		httpx.Error(w, &apierror.E{
+			Status:  http.StatusConflict,
+			Code:    "pairing_in_progress",
+			Message: "A WhatsApp pairing is already in progress",
+			Details: map[string]interface{}{
+				"session_id": pairingSession.ID,
+				"status":     status,
+			},
+		})
 
-	case <-r.Context().Done():
+	case err == nil:
+		fmt.Printf("❌ WhatsApp pairing ended before a QR code for user %s: %s\n", userID, evt.Type)
+		httpx.Error(w, apierror.New(http.StatusBadGateway, "WhatsApp pairing failed").WithCode("pairing_failed"))
+
+	case r.Context().Err() != nil:
 		fmt.Printf("🚫 Request cancelled for user %s\n", userID)
-		h.writeError(w, http.StatusRequestTimeout, "request_cancelled", "Request was cancelled", nil)
+		httpx.Error(w, apierror.New(http.StatusRequestTimeout, "Request was cancelled").WithCode("request_cancelled"))
+
+	default:
+		fmt.Printf("⏰ QR code generation timeout for user %s\n", userID)
+		httpx.Error(w, apierror.New(http.StatusRequestTimeout, "QR code generation timed out").WithCode("qr_timeout"))
 	}
 }
 
+// validPairingCode returns the session's QR code if it is still valid, or
+// else waits for its next event
+func validPairingCode(ctx context.Context, pairingSession *whatsapp.PairingSession) (whatsapp.PairingEvent, error) {
+	i := pairingSession.Latest()
+	evt, err := pairingSession.Next(ctx, i)
+	if err == nil && evt.Type == whatsapp.PairingEventCode && evt.ExpiresAt != nil && !time.Now().Before(*evt.ExpiresAt) {
+		return pairingSession.Next(ctx, i+1)
+	}
+	return evt, err
+}
+
+// StreamPairing implements GET /whatsapp/pairing/{session_id}/ws. It sends
+// the session's current QR code, each code that replaces it, and the outcome
+// as JSON messages, then closes the WebSocket.
+func (h *WhatsAppHandler) StreamPairing(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		httpx.Error(w, apierror.New(http.StatusUnauthorized, "User must be authenticated").WithCode("authentication_required"))
+		return
+	}
+
+	pairingSession, ok := h.whatsappConnector.Pairing().Get(chi.URLParam(r, "session_id"))
+	if !ok || pairingSession.UserID != userID.String() {
+		httpx.Error(w, apierror.New(http.StatusNotFound, "Pairing session not found").WithCode("pairing_session_not_found"))
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		OriginPatterns: []string{"*"}, // Allow all origins in development
+	})
+	if err != nil {
+		fmt.Printf("❌ Failed to accept pairing WebSocket for user %s: %v\n", userID, err)
+		return
+	}
+	defer conn.CloseNow()
+
+	// The client only listens; reading handles its close. Pairing outlasts
+	// the router's request timeout, so the stream isn't bound by it.
+	ctx := conn.CloseRead(context.WithoutCancel(r.Context()))
+	for i := pairingSession.Latest(); ; i++ {
+		evt, err := pairingSession.Next(ctx, i)
+		if err != nil {
+			return
+		}
+		data, err := json.Marshal(toAPIPairingEvent(evt))
+		if err != nil {
+			return
+		}
+		if err := conn.Write(ctx, websocket.MessageText, data); err != nil {
+			return
+		}
+		if evt.Final() {
+			conn.Close(websocket.StatusNormalClosure, evt.Type)
+			return
+		}
+	}
+}
+
+func toAPIPairingEvent(evt whatsapp.PairingEvent) api.PairingEvent {
+	event := api.PairingEvent{
+		Type:      api.PairingEventType(evt.Type),
+		ExpiresAt: evt.ExpiresAt,
+	}
+	if evt.Code != "" {
+		event.Code = &evt.Code
+	}
+	if evt.JID != "" {
+		event.Jid = &evt.JID
+	}
+	if evt.Error != "" {
+		event.Error = &evt.Error
+	}
+	return event
+}
+
 // GetWhatsAppStatus implements GET /whatsapp/status
 func (h *WhatsAppHandler) GetWhatsAppStatus(w http.ResponseWriter, r *http.Request) {
 	// Get authenticated user ID
 	userIDStr, ok := r.Context().Value("user_id").(string)
 	if !ok {
-		h.writeError(w, http.StatusInternalServerError, "context_error", "Failed to get user ID from context", nil)
+		httpx.Error(w, apierror.New(http.StatusInternalServerError, "Failed to get user ID from context").WithCode("context_error"))
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_user_id", "Invalid user ID format", nil)
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Invalid user ID format").WithCode("invalid_user_id"))
 		return
 	}
 
@@ -147,7 +283,7 @@ func (h *WhatsAppHandler) GetWhatsAppStatus(w http.ResponseWriter, r *http.Reque
 	}
 
 	fmt.Printf("📊 WhatsApp status for user %s: connected=%v\n", userID, response.Connected)
-	h.writeJSON(w, http.StatusOK, response)
+	httpx.JSON(w, http.StatusOK, response)
 }
 
 // DisconnectWhatsApp implements POST /whatsapp/disconnect
@@ -155,18 +291,21 @@ func (h *WhatsAppHandler) DisconnectWhatsApp(w http.ResponseWriter, r *http.Requ
 	// Get authenticated user ID
 	userIDStr, ok := r.Context().Value("user_id").(string)
 	if !ok {
-		h.writeError(w, http.StatusInternalServerError, "context_error", "Failed to get user ID from context", nil)
+		httpx.Error(w, apierror.New(http.StatusInternalServerError, "Failed to get user ID from context").WithCode("context_error"))
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_user_id", "Invalid user ID format", nil)
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Invalid user ID format").WithCode("invalid_user_id"))
 		return
 	}
 
-	// Notify backend about WhatsApp disconnection
-	if err := h.backendClient.UpdateAccountDisconnected(r.Context(), userIDStr); err != nil {
+	// Notify backend about WhatsApp disconnection. Without a platform user ID
+	// the backend resolves the user's WhatsApp integration.
+	integrationCtx := &proto.IntegrationContext{UserId: userIDStr, IntegrationType: "whatsapp"}
+	err = h.integrationClient.UpdateConnectionStatus(r.Context(), integrationCtx, proto.ConnectionStatus_CONNECTION_STATUS_DISCONNECTED, "", map[string]string{"reason": "api_disconnect"})
+	if err != nil {
 		fmt.Printf("⚠️  Failed to notify backend of WhatsApp disconnection: %v\n", err)
 		// Continue anyway - don't fail the API call for this
 	}
@@ -179,28 +318,108 @@ func (h *WhatsAppHandler) DisconnectWhatsApp(w http.ResponseWriter, r *http.Requ
 		Timestamp: timePtr(time.Now()),
 	}
 
-	h.writeJSON(w, http.StatusOK, response)
+	httpx.JSON(w, http.StatusOK, response)
 }
 
-// Helper functions
-func (h *WhatsAppHandler) writeJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
+// LoadOlderHistory implements POST /whatsapp/conversations/{external_id}/load-older
+func (h *WhatsAppHandler) LoadOlderHistory(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userIDStr, ok := r.Context().Value("user_id").(string)
+	if !ok {
+		httpx.Error(w, apierror.New(http.StatusInternalServerError, "Failed to get user ID from context").WithCode("context_error"))
+		return
+	}
+
+	conversationJID, err := url.PathUnescape(chi.URLParam(r, "external_id"))
+	if err == nil {
+		_, err = whatsapp.ParseConversationJID(conversationJID)
+	}
+	if err != nil {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, "Invalid conversation ID").WithCode("invalid_conversation_id"))
+		return
+	}
+
+	// The request body is optional
+	var req api.LoadOlderHistoryRequest
+	if err := httpx.Decode(r, &req, 0); err != nil && !errors.Is(err, io.EOF) {
+		httpx.Error(w, err)
+		return
+	}
+	count := defaultLoadOlderCount
+	if req.Count != nil {
+		count = *req.Count
+	}
+	if count < 1 || count > maxLoadOlderCount {
+		httpx.Error(w, apierror.New(http.StatusBadRequest, fmt.Sprintf("Count must be between 1 and %d", maxLoadOlderCount)).WithCode("invalid_count"))
+		return
+	}
+
+	session, ok := h.whatsappConnector.Sessions().Get(userIDStr)
+	if !ok {
+		httpx.Error(w, apierror.New(http.StatusConflict, "WhatsApp is not connected").WithCode("not_connected"))
+		return
+	}
+
+	if err := session.LoadOlderHistory(r.Context(), conversationJID, count); err != nil {
+		switch {
+		case errors.Is(err, whatsapp.ErrNoHistoryAnchor):
+			httpx.Error(w, apierror.New(http.StatusNotFound, err.Error()).WithCode("conversation_not_synced"))
+		case errors.Is(err, whatsapp.ErrHistoryRequestPending):
+			httpx.Error(w, apierror.New(http.StatusConflict, err.Error()).WithCode("request_pending"))
+		case errors.Is(err, whatsapp.ErrHistoryExhausted):
+			httpx.Error(w, apierror.New(http.StatusConflict, err.Error()).WithCode("history_exhausted"))
+		default:
+			h.logger.Error("Failed to load older history", "conversation", conversationJID, "error", err)
+			httpx.Error(w, apierror.New(http.StatusBadGateway, "Failed to request older history").WithCode("request_failed"))
+		}
+		return
+	}
+
+	h.logger.Info("📜 Requested older history", "count", count, "conversation", conversationJID, "user_id", userIDStr)
+
+	response := api.SuccessResponse{
+		Success:   true,
+		Message:   "Older history requested",
+		Timestamp: timePtr(time.Now()),
+	}
+
+	httpx.JSON(w, http.StatusAccepted, response)
 }
 
-func (h *WhatsAppHandler) writeError(w http.ResponseWriter, status int, code, message string, details map[string]interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
+// ResyncHistory implements POST /whatsapp/resync
+func (h *WhatsAppHandler) ResyncHistory(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userIDStr, ok := r.Context().Value("user_id").(string)
+	if !ok {
+		httpx.Error(w, apierror.New(http.StatusInternalServerError, "Failed to get user ID from context").WithCode("context_error"))
+		return
+	}
 
-	response := api.ErrorResponse{
-		Error:     message,
-		Code:      &code,
-		Details:   &details,
-		Timestamp: time.Now(),
+	session, ok := h.whatsappConnector.Sessions().Get(userIDStr)
+	if !ok {
+		httpx.Error(w, apierror.New(http.StatusConflict, "WhatsApp is not connected").WithCode("not_connected"))
+		return
+	}
+
+	if err := session.ResyncHistory(r.Context()); err != nil {
+		if errors.Is(err, whatsapp.ErrHistoryResyncPending) {
+			httpx.Error(w, apierror.New(http.StatusConflict, err.Error()).WithCode("request_pending"))
+			return
+		}
+		h.logger.Error("Failed to request history resync", "user_id", userIDStr, "error", err)
+		httpx.Error(w, apierror.New(http.StatusBadGateway, "Failed to request history resync").WithCode("request_failed"))
+		return
+	}
+
+	h.logger.Info("🔄 Requested full history resync", "user_id", userIDStr)
+
+	response := api.SuccessResponse{
+		Success:   true,
+		Message:   "History resync requested",
+		Timestamp: timePtr(time.Now()),
 	}
 
-	json.NewEncoder(w).Encode(response)
+	httpx.JSON(w, http.StatusAccepted, response)
 }
 
 func timePtr(t time.Time) *time.Time {
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestLoadOlderHistoryRejectsInvalidConversations(t *testing.T) {
+	// The fake connector has no sessions, so reaching them would panic
+	h := NewWhatsAppHandler(nil, &fakeConnector{}, nil, slog.Default())
+
+	for _, conversation := range []string{"972501234567", "status@broadcast", "972501234567:3@s.whatsapp.net"} {
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("external_id", url.PathEscape(conversation))
+		req := httptest.NewRequest(http.MethodPost, "/conversations/x/load-older", nil)
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx)
+		req = req.WithContext(context.WithValue(ctx, "user_id", "user"))
+
+		rec := httptest.NewRecorder()
+		h.LoadOlderHistory(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want %d: %s", conversation, rec.Code, http.StatusBadRequest, rec.Body)
+		}
+	}
+}
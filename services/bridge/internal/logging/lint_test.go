@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// structuredPackages log only through *slog.Logger, so their output can be
+// leveled, filtered and redacted
+var structuredPackages = []string{
+	"../../whatsapp",
+	"../grpc",
+}
+
+// TestNoPrintfLogging fails on log.Print* and fmt.Print* calls in the event
+// pipeline packages
+func TestNoPrintfLogging(t *testing.T) {
+	for _, dir := range structuredPackages {
+		files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			t.Fatalf("failed to list %s: %v", dir, err)
+		}
+		if len(files) == 0 {
+			t.Fatalf("no Go files in %s", dir)
+		}
+
+		for _, path := range files {
+			if strings.HasSuffix(path, "_test.go") {
+				continue
+			}
+
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, path, nil, 0)
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", path, err)
+			}
+
+			ast.Inspect(file, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				sel, ok := call.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				pkg, ok := sel.X.(*ast.Ident)
+				if ok && (pkg.Name == "log" || pkg.Name == "fmt") && strings.HasPrefix(sel.Sel.Name, "Print") {
+					t.Errorf("%s: %s.%s; use the component's *slog.Logger", fset.Position(call.Pos()), pkg.Name, sel.Sel.Name)
+				}
+				return true
+			})
+		}
+	}
+}
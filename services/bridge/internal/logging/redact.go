@@ -0,0 +1,81 @@
+// Package logging configures bridge log output, including optional redaction
+// of phone numbers and JIDs
+package logging
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// visibleDigits is how many trailing digits of a phone number stay readable
+const visibleDigits = 4
+
+// phoneNumber matches a standalone run of 7-15 digits: an E.164 phone number,
+// or the user part of a WhatsApp JID such as 972501234567@s.whatsapp.net or
+// 123456789012345@lid. Longer runs (group IDs) are left alone.
+var phoneNumber = regexp.MustCompile(`\b\d{7,15}\b`)
+
+var redactPII atomic.Bool
+
+// SetRedactPII turns redaction of phone numbers and JIDs on or off
+func SetRedactPII(enabled bool) {
+	redactPII.Store(enabled)
+}
+
+// RedactPII reports whether phone numbers and JIDs are redacted
+func RedactPII() bool {
+	return redactPII.Load()
+}
+
+// Redact masks all but the last 4 digits of every phone number in s when
+// redaction is enabled, and returns s unchanged otherwise. Use it for output
+// that doesn't go through a Writer, such as fmt.Printf.
+func Redact(s string) string {
+	if !RedactPII() {
+		return s
+	}
+	return mask(s)
+}
+
+func mask(s string) string {
+	return phoneNumber.ReplaceAllStringFunc(s, func(digits string) string {
+		return strings.Repeat("*", len(digits)-visibleDigits) + digits[len(digits)-visibleDigits:]
+	})
+}
+
+// redactingWriter masks phone numbers in everything written through it
+type redactingWriter struct {
+	w io.Writer
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	// A masked line has the same length, so callers see a full write
+	if _, err := r.w.Write(phoneNumber.ReplaceAllFunc(p, func(digits []byte) []byte {
+		masked := bytes.Repeat([]byte("*"), len(digits)-visibleDigits)
+		return append(masked, digits[len(digits)-visibleDigits:]...)
+	})); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Writer wraps w so that phone numbers and JIDs are masked when redaction is
+// enabled. Log handlers write whole lines, so numbers are never split across
+// writes.
+func Writer(w io.Writer) io.Writer {
+	if !RedactPII() {
+		return w
+	}
+	return &redactingWriter{w: w}
+}
+
+// Setup applies the redaction setting and routes the standard logger through
+// it. It must run before any loggers are created with Writer.
+func Setup(redact bool) {
+	SetRedactPII(redact)
+	log.SetOutput(Writer(log.Writer()))
+}
@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	SetRedactPII(true)
+	defer SetRedactPII(false)
+
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{in: "972501234567@s.whatsapp.net", want: "********4567@s.whatsapp.net"},
+		{in: "972501234567:12@s.whatsapp.net", want: "********4567:12@s.whatsapp.net"},
+		{in: "sender=123456789012345@lid", want: "sender=***********2345@lid"},
+		{in: "phone +15551234567 connected", want: "phone +*******4567 connected"},
+		// Group IDs are longer than any phone number and stay readable
+		{in: "120363025246125888@g.us", want: "120363025246125888@g.us"},
+		{in: "synced 250 messages", want: "synced 250 messages"},
+	} {
+		if got := Redact(tc.in); got != tc.want {
+			t.Errorf("Redact(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestRedactDisabled(t *testing.T) {
+	SetRedactPII(false)
+
+	const in = "972501234567@s.whatsapp.net"
+	if got := Redact(in); got != in {
+		t.Errorf("Redact(%q) = %q with redaction disabled", in, got)
+	}
+
+	var buf bytes.Buffer
+	if w := Writer(&buf); w != &buf {
+		t.Errorf("Writer should return the underlying writer with redaction disabled")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	SetRedactPII(true)
+	defer SetRedactPII(false)
+
+	var buf bytes.Buffer
+	line := []byte("level=INFO msg=connected jid=972501234567@s.whatsapp.net\n")
+	n, err := Writer(&buf).Write(line)
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if n != len(line) {
+		t.Errorf("Write returned %d, want %d", n, len(line))
+	}
+	if want := "level=INFO msg=connected jid=********4567@s.whatsapp.net\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+var whatsmeowLevels = map[string]int{"DEBUG": 0, "INFO": 1, "WARN": 2, "ERROR": 3}
+
+// Whatsmeow returns a logger for whatsmeow's client and store. With redaction
+// enabled it writes through Writer, since whatsmeow logs JIDs freely.
+func Whatsmeow(module, minLevel string) waLog.Logger {
+	if !RedactPII() {
+		return waLog.Stdout(module, minLevel, true)
+	}
+	return &whatsmeowLogger{
+		out:      log.New(Writer(os.Stdout), "", log.LstdFlags),
+		module:   module,
+		minLevel: whatsmeowLevels[strings.ToUpper(minLevel)],
+	}
+}
+
+// whatsmeowLogger is a plain-text waLog.Logger writing to a standard logger
+type whatsmeowLogger struct {
+	out      *log.Logger
+	module   string
+	minLevel int
+}
+
+func (l *whatsmeowLogger) Debugf(msg string, args ...interface{}) { l.logf("DEBUG", msg, args...) }
+func (l *whatsmeowLogger) Infof(msg string, args ...interface{})  { l.logf("INFO", msg, args...) }
+func (l *whatsmeowLogger) Warnf(msg string, args ...interface{})  { l.logf("WARN", msg, args...) }
+func (l *whatsmeowLogger) Errorf(msg string, args ...interface{}) { l.logf("ERROR", msg, args...) }
+
+func (l *whatsmeowLogger) Sub(module string) waLog.Logger {
+	return &whatsmeowLogger{out: l.out, module: l.module + "/" + module, minLevel: l.minLevel}
+}
+
+func (l *whatsmeowLogger) logf(level, msg string, args ...interface{}) {
+	if whatsmeowLevels[level] < l.minLevel {
+		return
+	}
+	l.out.Printf("[%s %s] %s", l.module, level, fmt.Sprintf(msg, args...))
+}
@@ -0,0 +1,245 @@
+package stats
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/tennex/pkg/httpx"
+)
+
+// Stats collects the bridge's runtime statistics, served at /stats. The
+// recorder methods are cheap and safe to call from any goroutine.
+type Stats struct {
+	mu            sync.Mutex
+	startTime     time.Time
+	activeClients func() int
+
+	qrCreated   int64
+	qrSucceeded int64
+	qrExpired   int64
+
+	eventsByType map[string]int64
+
+	callsByMethod map[string]*CallCounts
+	lastCheck     *BackendCheck
+}
+
+// Snapshot is the JSON body of GET /stats
+type Snapshot struct {
+	StartTime        time.Time         `json:"start_time"`
+	UptimeSeconds    int64             `json:"uptime_seconds"`
+	ActiveClients    int               `json:"active_clients"`     // Connected WhatsApp sessions
+	QRSessions       QRCounts          `json:"qr_sessions"`        // Since start
+	EventsProcessed  EventCounts       `json:"events_processed"`   // whatsmeow events, since start
+	BackendCalls     BackendCallCounts `json:"backend_calls"`      // gRPC calls to the backend, since start
+	LastBackendCheck *BackendCheck     `json:"last_backend_check"` // Null until the first check
+}
+
+// QRCounts counts pairing flows
+type QRCounts struct {
+	Created   int64 `json:"created"`   // Flows that started issuing QR codes
+	Succeeded int64 `json:"succeeded"` // Flows where the code was scanned
+	Expired   int64 `json:"expired"`   // Flows that ran out of codes before a scan
+}
+
+// EventCounts counts whatsmeow events by their type name, e.g. "Message"
+type EventCounts struct {
+	Total  int64            `json:"total"`
+	ByType map[string]int64 `json:"by_type"`
+}
+
+// CallCounts counts finished gRPC calls
+type CallCounts struct {
+	Succeeded int64 `json:"succeeded"`
+	Failed    int64 `json:"failed"`
+}
+
+// BackendCallCounts counts gRPC calls to the backend, overall and by full method name
+type BackendCallCounts struct {
+	CallCounts
+	ByMethod map[string]CallCounts `json:"by_method"`
+}
+
+// BackendCheck is the result of a backend connectivity check
+type BackendCheck struct {
+	CheckedAt time.Time `json:"checked_at"`
+	OK        bool      `json:"ok"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// New creates stats for a bridge starting now
+func New() *Stats {
+	return &Stats{
+		startTime:     time.Now(),
+		eventsByType:  make(map[string]int64),
+		callsByMethod: make(map[string]*CallCounts),
+	}
+}
+
+// TrackActiveClients sets where the active client count is read from. The
+// session registry already knows it, so it isn't counted separately.
+func (s *Stats) TrackActiveClients(count func() int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.activeClients = count
+}
+
+// RecordQRSessionCreated records a pairing flow that started issuing QR codes
+func (s *Stats) RecordQRSessionCreated() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.qrCreated++
+}
+
+// RecordQRSessionSucceeded records a scanned QR code
+func (s *Stats) RecordQRSessionSucceeded() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.qrSucceeded++
+}
+
+// RecordQRSessionExpired records a pairing flow whose codes all expired
+func (s *Stats) RecordQRSessionExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.qrExpired++
+}
+
+// RecordEvent records a processed whatsmeow event
+func (s *Stats) RecordEvent(eventType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.eventsByType[eventType]++
+}
+
+// RecordBackendCall records a finished gRPC call to the backend
+func (s *Stats) RecordBackendCall(method string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts, ok := s.callsByMethod[method]
+	if !ok {
+		counts = &CallCounts{}
+		s.callsByMethod[method] = counts
+	}
+	if err != nil {
+		counts.Failed++
+	} else {
+		counts.Succeeded++
+	}
+}
+
+// RecordBackendCheck records the result of a backend connectivity check
+func (s *Stats) RecordBackendCheck(err error) {
+	check := &BackendCheck{CheckedAt: time.Now(), OK: err == nil}
+	if err != nil {
+		check.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastCheck = check
+}
+
+// Snapshot returns a copy of the current statistics
+func (s *Stats) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := Snapshot{
+		StartTime:     s.startTime,
+		UptimeSeconds: int64(time.Since(s.startTime).Seconds()),
+		QRSessions: QRCounts{
+			Created:   s.qrCreated,
+			Succeeded: s.qrSucceeded,
+			Expired:   s.qrExpired,
+		},
+		EventsProcessed: EventCounts{ByType: make(map[string]int64, len(s.eventsByType))},
+		BackendCalls:    BackendCallCounts{ByMethod: make(map[string]CallCounts, len(s.callsByMethod))},
+	}
+	if s.activeClients != nil {
+		snapshot.ActiveClients = s.activeClients()
+	}
+	for eventType, count := range s.eventsByType {
+		snapshot.EventsProcessed.ByType[eventType] = count
+		snapshot.EventsProcessed.Total += count
+	}
+	for method, counts := range s.callsByMethod {
+		snapshot.BackendCalls.ByMethod[method] = *counts
+		snapshot.BackendCalls.Succeeded += counts.Succeeded
+		snapshot.BackendCalls.Failed += counts.Failed
+	}
+	if s.lastCheck != nil {
+		check := *s.lastCheck
+		snapshot.LastBackendCheck = &check
+	}
+	return snapshot
+}
+
+// ServeHTTP implements GET /stats
+func (s *Stats) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	httpx.JSON(w, http.StatusOK, s.Snapshot())
+}
+
+// DialOptions returns the options that make a backend connection record its calls
+func (s *Stats) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.WithChainStreamInterceptor(s.streamInterceptor),
+	}
+}
+
+func (s *Stats) unaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	s.RecordBackendCall(method, err)
+	return err
+}
+
+func (s *Stats) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		s.RecordBackendCall(method, err)
+		return nil, err
+	}
+	return &recordedStream{ClientStream: stream, stats: s, method: method, serverStreams: desc.ServerStreams}, nil
+}
+
+// recordedStream records a streaming call once its outcome is known: the
+// first failed send or receive, or the end of the server's responses
+type recordedStream struct {
+	grpc.ClientStream
+	stats         *Stats
+	method        string
+	serverStreams bool
+	once          sync.Once
+}
+
+func (s *recordedStream) record(err error) {
+	s.once.Do(func() { s.stats.RecordBackendCall(s.method, err) })
+}
+
+func (s *recordedStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil && err != io.EOF {
+		s.record(err)
+	}
+	return err
+}
+
+func (s *recordedStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.record(nil)
+	case err != nil:
+		s.record(err)
+	case !s.serverStreams:
+		// Client streams get a single response, which ends the call
+		s.record(nil)
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeClientStream is a client-streaming call whose response fails with recvErr
+type fakeClientStream struct {
+	grpc.ClientStream
+	recvErr error
+}
+
+func (s *fakeClientStream) SendMsg(m interface{}) error { return nil }
+func (s *fakeClientStream) RecvMsg(m interface{}) error { return s.recvErr }
+
+func TestStatsEndpointReflectsOperations(t *testing.T) {
+	bridgeStats := New()
+	bridgeStats.TrackActiveClients(func() int { return 2 })
+
+	// Two pairing flows: one scanned, one left to expire
+	bridgeStats.RecordQRSessionCreated()
+	bridgeStats.RecordQRSessionCreated()
+	bridgeStats.RecordQRSessionSucceeded()
+	bridgeStats.RecordQRSessionExpired()
+
+	bridgeStats.RecordEvent("Message")
+	bridgeStats.RecordEvent("Message")
+	bridgeStats.RecordEvent("Receipt")
+
+	// Calls through a connection dialed with the stats' options are counted
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	defer server.Stop()
+
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+	}, bridgeStats.DialOptions()...)
+	conn, err := grpc.NewClient("passthrough:///backend", dialOpts...)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	client := healthpb.NewHealthClient(conn)
+	ctx := context.Background()
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"}); err == nil {
+		t.Fatal("expected Check of an unknown service to fail")
+	}
+
+	// A client stream counts once, when its response arrives
+	desc := &grpc.StreamDesc{ClientStreams: true}
+	for _, recvErr := range []error{nil, errors.New("backend went away")} {
+		stream, err := bridgeStats.streamInterceptor(ctx, desc, nil, "/tennex.IntegrationService/SyncMessages",
+			func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+				return &fakeClientStream{recvErr: recvErr}, nil
+			})
+		if err != nil {
+			t.Fatalf("streamInterceptor: %v", err)
+		}
+		stream.SendMsg(nil)
+		stream.SendMsg(nil)
+		stream.RecvMsg(nil)
+	}
+
+	bridgeStats.RecordBackendCheck(nil)
+
+	rec := httptest.NewRecorder()
+	bridgeStats.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &snapshot); err != nil {
+		t.Fatalf("failed to decode /stats: %v", err)
+	}
+
+	if snapshot.ActiveClients != 2 {
+		t.Errorf("expected 2 active clients, got %d", snapshot.ActiveClients)
+	}
+	if snapshot.QRSessions != (QRCounts{Created: 2, Succeeded: 1, Expired: 1}) {
+		t.Errorf("unexpected QR sessions: %+v", snapshot.QRSessions)
+	}
+	if snapshot.EventsProcessed.Total != 3 || snapshot.EventsProcessed.ByType["Message"] != 2 || snapshot.EventsProcessed.ByType["Receipt"] != 1 {
+		t.Errorf("unexpected events: %+v", snapshot.EventsProcessed)
+	}
+	if snapshot.BackendCalls.Succeeded != 2 || snapshot.BackendCalls.Failed != 2 {
+		t.Errorf("expected 2 succeeded and 2 failed backend calls, got %+v", snapshot.BackendCalls)
+	}
+	if got := snapshot.BackendCalls.ByMethod["/grpc.health.v1.Health/Check"]; got != (CallCounts{Succeeded: 1, Failed: 1}) {
+		t.Errorf("unexpected Check counts: %+v", got)
+	}
+	if got := snapshot.BackendCalls.ByMethod["/tennex.IntegrationService/SyncMessages"]; got != (CallCounts{Succeeded: 1, Failed: 1}) {
+		t.Errorf("unexpected SyncMessages counts: %+v", got)
+	}
+	if snapshot.LastBackendCheck == nil || !snapshot.LastBackendCheck.OK {
+		t.Errorf("expected a successful backend check, got %+v", snapshot.LastBackendCheck)
+	}
+}
+
+func TestServerStreamCountsAtEnd(t *testing.T) {
+	bridgeStats := New()
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	stream, _ := bridgeStats.streamInterceptor(context.Background(), desc, nil, "/svc/Watch",
+		func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+			return &fakeClientStream{}, nil
+		})
+
+	// Responses keep coming, so the call hasn't finished
+	stream.RecvMsg(nil)
+	if calls := bridgeStats.Snapshot().BackendCalls; calls.Succeeded+calls.Failed != 0 {
+		t.Fatalf("expected no finished calls yet, got %+v", calls)
+	}
+
+	stream.(*recordedStream).ClientStream = &fakeClientStream{recvErr: io.EOF}
+	stream.RecvMsg(nil)
+	stream.RecvMsg(nil)
+	if calls := bridgeStats.Snapshot().BackendCalls; calls.Succeeded != 1 || calls.Failed != 0 {
+		t.Errorf("expected one succeeded call, got %+v", calls)
+	}
+}
@@ -2,27 +2,48 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/nats-io/nats.go"
+	"github.com/tennex/bridge/control"
 	"github.com/tennex/bridge/db"
 	backendGRPC "github.com/tennex/bridge/internal/grpc"
 	"github.com/tennex/bridge/internal/handlers"
+	"github.com/tennex/bridge/internal/logging"
+	"github.com/tennex/bridge/internal/stats"
+	"github.com/tennex/bridge/outbox"
 	"github.com/tennex/bridge/whatsapp"
+	"github.com/tennex/pkg/apierror"
+	"github.com/tennex/pkg/grpctls"
+	"github.com/tennex/pkg/httpx"
+	"github.com/tennex/pkg/reconnect"
+	"github.com/tennex/pkg/requestid"
 	"github.com/tennex/shared/auth"
+	proto "github.com/tennex/shared/proto/gen/proto"
+	"google.golang.org/grpc"
 )
 
 const (
 	DefaultJWTSecret       = "dev-jwt-secret-change-in-production"
 	DefaultPort            = "6003"
 	DefaultBackendGRPCAddr = "backend:6001" // Default for Docker, can be overridden
+	DefaultGRPCPort        = "6004"
+	DefaultGRPCToken       = "dev-bridge-token-change-in-production"
+	DefaultSyncQueueDir    = "sync-queue"
+
+	backendCheckInterval = 30 * time.Second
+	backendCheckTimeout  = 5 * time.Second
 )
 
 func min(a, b int) int {
@@ -32,11 +53,36 @@ func min(a, b int) int {
 	return b
 }
 
+// grpcTLSConfig reads a gRPC link's TLS settings from the environment:
+// <prefix>_TLS=true enables TLS, and <prefix>_TLS_CA_FILE,
+// <prefix>_TLS_CERT_FILE, <prefix>_TLS_KEY_FILE and <prefix>_TLS_SERVER_NAME
+// set the grpctls.Config fields of the same names
+func grpcTLSConfig(prefix string) grpctls.Config {
+	return grpctls.Config{
+		Enabled:    os.Getenv(prefix+"_TLS") == "true",
+		CAFile:     os.Getenv(prefix + "_TLS_CA_FILE"),
+		CertFile:   os.Getenv(prefix + "_TLS_CERT_FILE"),
+		KeyFile:    os.Getenv(prefix + "_TLS_KEY_FILE"),
+		ServerName: os.Getenv(prefix + "_TLS_SERVER_NAME"),
+	}
+}
+
 func main() {
-	// Setup structured logging
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	}))
+	// Setup structured logging; TENNEX_LOG_REDACT_PII (log.redact_pii) masks
+	// phone numbers and JIDs in all log output, and TENNEX_LOG_LEVEL=debug
+	// enables per-event logging
+	logging.Setup(os.Getenv("TENNEX_LOG_REDACT_PII") == "true")
+	logLevel := slog.LevelInfo
+	if level := os.Getenv("TENNEX_LOG_LEVEL"); level != "" {
+		if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+			slog.Error("Invalid TENNEX_LOG_LEVEL", "error", err, "value", level)
+			os.Exit(1)
+		}
+	}
+	// Entries logged with a request's context carry its request ID
+	logger := slog.New(requestid.NewSlogHandler(slog.NewTextHandler(logging.Writer(os.Stdout), &slog.HandlerOptions{
+		Level: logLevel,
+	})))
 	slog.SetDefault(logger)
 
 	// Create cancellation context
@@ -62,6 +108,13 @@ func main() {
 	}
 	slog.Info("✅ Database connection established")
 
+	// WhatsApp device store, shared by every connection
+	deviceStore, err := whatsapp.OpenDeviceStore(ctx)
+	if err != nil {
+		slog.Error("Failed to open WhatsApp device store", "error", err)
+		os.Exit(1)
+	}
+
 	// Initialize WhatsApp connector (temporarily without backend client)
 	var whatsappConnector *whatsapp.WhatsAppConnector
 
@@ -80,6 +133,8 @@ func main() {
 	)
 
 	jwtConfig := auth.DefaultJWTConfig(jwtSecret)
+	// Sessions signed out by a password reset on the backend are refused here too
+	jwtConfig.RevocationCheck = auth.RevokeSessionsBeforePasswordReset(storage.PasswordChangedAt)
 	slog.Info("✅ JWT authentication configured")
 
 	// Initialize backend gRPC client
@@ -89,36 +144,250 @@ func main() {
 		slog.Info("Using default backend gRPC address", "addr", backendAddr)
 	}
 
-	backendClient, err := backendGRPC.NewBackendClient(backendAddr)
+	// Runtime statistics served at /stats
+	bridgeStats := stats.New()
+
+	// BACKEND_GRPC_TLS=true dials the backend over TLS (see grpcTLSConfig)
+	backendTLS, err := grpctls.DialOption(grpcTLSConfig("BACKEND_GRPC"))
 	if err != nil {
-		slog.Error("Failed to initialize backend gRPC client", "error", err, "addr", backendAddr)
+		slog.Error("Invalid backend gRPC TLS config", "error", err)
 		os.Exit(1)
 	}
-	defer backendClient.Close()
-	slog.Info("✅ Backend gRPC client connected", "addr", backendAddr)
 
 	// Initialize integration gRPC client (with recording support)
-	integrationClient, err := backendGRPC.NewIntegrationClientWithRecording(backendAddr)
+	integrationClient, err := backendGRPC.NewIntegrationClientWithRecording(backendAddr, logger, append(bridgeStats.DialOptions(), backendTLS)...)
 	if err != nil {
 		slog.Error("Failed to initialize integration gRPC client", "error", err, "addr", backendAddr)
 		os.Exit(1)
 	}
 	defer integrationClient.Close()
-	slog.Info("✅ Integration gRPC client connected", "recording_mode", os.Getenv("RECORDING_MODE"))
 
-	// Initialize WhatsApp connector with both clients
-	whatsappConnector = whatsapp.NewWhatsAppConnector(storage, backendClient, integrationClient)
+	// Wait for the backend, which may still be starting; BRIDGE_STARTUP_WAIT
+	// bounds how long
+	waitConfig := backendGRPC.DefaultWaitConfig()
+	if wait := os.Getenv("BRIDGE_STARTUP_WAIT"); wait != "" {
+		timeout, err := time.ParseDuration(wait)
+		if err != nil {
+			slog.Error("Invalid BRIDGE_STARTUP_WAIT", "error", err, "value", wait)
+			os.Exit(1)
+		}
+		waitConfig.Timeout = timeout
+	}
+	if err := integrationClient.WaitForBackend(ctx, waitConfig); err != nil {
+		slog.Error("Backend unreachable", "error", err, "addr", backendAddr)
+		os.Exit(1)
+	}
+	slog.Info("✅ Integration gRPC client connected", "addr", backendAddr, "recording_mode", os.Getenv("RECORDING_MODE"))
+
+	// Syncs in progress are queued on disk, so those a restart cuts short
+	// resume from the last batch the backend acknowledged
+	syncQueueDir := os.Getenv("BRIDGE_SYNC_QUEUE_DIR")
+	if syncQueueDir == "" {
+		syncQueueDir = DefaultSyncQueueDir
+	}
+	syncQueue, err := backendGRPC.NewSyncQueue(syncQueueDir)
+	if err != nil {
+		slog.Error("Failed to open sync queue", "error", err, "dir", syncQueueDir)
+		os.Exit(1)
+	}
+	integrationClient.UseSyncQueue(syncQueue)
+	go func() {
+		if err := integrationClient.ResumeSyncs(ctx); err != nil {
+			slog.Warn("Failed to resume queued syncs", "error", err, "dir", syncQueueDir)
+		}
+	}()
+
+	// Periodically check that the backend is reachable, for /stats
+	go func() {
+		ticker := time.NewTicker(backendCheckInterval)
+		defer ticker.Stop()
+		for {
+			checkCtx, checkCancel := context.WithTimeout(ctx, backendCheckTimeout)
+			err := integrationClient.CheckConnectivity(checkCtx)
+			checkCancel()
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				slog.Warn("Backend connectivity check failed", "error", err, "addr", backendAddr)
+			}
+			bridgeStats.RecordBackendCheck(err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	// Reconnect settings for dropped WhatsApp connections
+	watchdogConfig := whatsapp.DefaultWatchdogConfig()
+	if window := os.Getenv("BRIDGE_RECONNECT_WINDOW"); window != "" {
+		giveUpAfter, err := time.ParseDuration(window)
+		if err != nil {
+			slog.Error("Invalid BRIDGE_RECONNECT_WINDOW", "error", err, "value", window)
+			os.Exit(1)
+		}
+		watchdogConfig.GiveUpAfter = giveUpAfter
+	}
+
+	// Deadlines for backend calls made while processing WhatsApp events
+	eventsConfig := whatsapp.DefaultEventsConfig()
+	if timeout := os.Getenv("BRIDGE_BACKEND_CALL_TIMEOUT"); timeout != "" {
+		callTimeout, err := time.ParseDuration(timeout)
+		if err != nil {
+			slog.Error("Invalid BRIDGE_BACKEND_CALL_TIMEOUT", "error", err, "value", timeout)
+			os.Exit(1)
+		}
+		eventsConfig.CallTimeout = callTimeout
+	}
+	if timeout := os.Getenv("BRIDGE_BACKEND_SYNC_TIMEOUT"); timeout != "" {
+		syncTimeout, err := time.ParseDuration(timeout)
+		if err != nil {
+			slog.Error("Invalid BRIDGE_BACKEND_SYNC_TIMEOUT", "error", err, "value", timeout)
+			os.Exit(1)
+		}
+		eventsConfig.SyncTimeout = syncTimeout
+	}
+
+	// Profile pictures fetched a second; 0 doesn't fetch them
+	if rate := os.Getenv("BRIDGE_AVATAR_FETCH_RATE"); rate != "" {
+		perSecond, err := strconv.ParseFloat(rate, 64)
+		if err != nil || perSecond < 0 {
+			slog.Error("Invalid BRIDGE_AVATAR_FETCH_RATE", "error", err, "value", rate)
+			os.Exit(1)
+		}
+		eventsConfig.AvatarFetchRate = perSecond
+	}
+
+	// Optional event categories to process, e.g. "receipts,contacts,history"
+	// to drop presence; all of them unless set
+	if list, ok := os.LookupEnv("BRIDGE_EVENT_CATEGORIES"); ok {
+		categories, err := whatsapp.ParseEventCategories(list)
+		if err != nil {
+			slog.Error("Invalid BRIDGE_EVENT_CATEGORIES", "error", err, "value", list)
+			os.Exit(1)
+		}
+		eventsConfig.Categories = categories
+	}
+
+	// How long users have to scan a QR code; fresh codes are issued until then
+	pairingConfig := whatsapp.DefaultPairingConfig()
+	if timeout := os.Getenv("BRIDGE_PAIRING_TIMEOUT"); timeout != "" {
+		pairingTimeout, err := time.ParseDuration(timeout)
+		if err != nil {
+			slog.Error("Invalid BRIDGE_PAIRING_TIMEOUT", "error", err, "value", timeout)
+			os.Exit(1)
+		}
+		pairingConfig.Timeout = pairingTimeout
+	}
+
+	// Initialize WhatsApp connector
+	whatsappConnector = whatsapp.NewWhatsAppConnector(storage, deviceStore, integrationClient, watchdogConfig, eventsConfig, pairingConfig, bridgeStats, logger)
 	slog.Info("✅ WhatsApp connector initialized")
 
+	// Initialize control gRPC server for backend-initiated operations
+	grpcPort := os.Getenv("BRIDGE_GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = DefaultGRPCPort
+	}
+	grpcToken := os.Getenv("BRIDGE_GRPC_TOKEN")
+	if grpcToken == "" {
+		grpcToken = DefaultGRPCToken
+		slog.Warn("Using default bridge gRPC token - change for production!")
+	}
+
+	// BRIDGE_GRPC_TLS=true serves the backend over TLS (see grpcTLSConfig)
+	controlTLS, err := grpctls.ServerOption(grpcTLSConfig("BRIDGE_GRPC"))
+	if err != nil {
+		slog.Error("Invalid bridge gRPC TLS config", "error", err)
+		os.Exit(1)
+	}
+
+	controlServer := control.NewServer(whatsappConnector.Sessions(), logger)
+	grpcServer := grpc.NewServer(
+		controlTLS,
+		grpc.ChainUnaryInterceptor(
+			requestid.UnaryServerInterceptor,
+			control.LoggingUnaryInterceptor(logger),
+			auth.TokenUnaryServerInterceptor(grpcToken),
+		),
+		grpc.MaxRecvMsgSize(control.MaxRequestBytes),
+	)
+	proto.RegisterBridgeControlServiceServer(grpcServer, controlServer)
+
+	grpcListener, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		slog.Error("Failed to listen for gRPC", "error", err, "port", grpcPort)
+		os.Exit(1)
+	}
+	go func() {
+		slog.Info("🛰️ gRPC control server starting", "addr", grpcListener.Addr().String())
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			slog.Error("gRPC server error", "error", err)
+			cancel()
+		}
+	}()
+
+	// Send messages the backend queues on NATS when its outbox transport is
+	// "nats"; with the default gRPC transport nothing is ever queued
+	if natsURL := os.Getenv("BRIDGE_NATS_URL"); natsURL != "" {
+		// A dropped connection is retried forever, backing off between
+		// attempts so a recovering server isn't hammered
+		natsBackoff := reconnect.DefaultBackoff()
+		if maxWait := os.Getenv("BRIDGE_NATS_RECONNECT_MAX_WAIT"); maxWait != "" {
+			ceiling, err := time.ParseDuration(maxWait)
+			if err != nil {
+				slog.Error("Invalid BRIDGE_NATS_RECONNECT_MAX_WAIT", "error", err, "value", maxWait)
+				os.Exit(1)
+			}
+			natsBackoff = natsBackoff.WithMax(ceiling)
+		}
+		nc, err := nats.Connect(natsURL, nats.MaxReconnects(-1), nats.CustomReconnectDelay(natsBackoff.Delay))
+		if err != nil {
+			slog.Error("Failed to connect to NATS", "error", err, "url", natsURL)
+			os.Exit(1)
+		}
+		defer nc.Close()
+
+		js, err := nc.JetStream()
+		if err != nil {
+			slog.Error("Failed to get JetStream context", "error", err)
+			os.Exit(1)
+		}
+
+		outboxConsumer := outbox.NewConsumer(js, controlServer, outbox.Config{
+			Prefix:          os.Getenv("BRIDGE_NATS_PREFIX"),
+			IntegrationType: "whatsapp",
+		})
+		go func() {
+			if err := outboxConsumer.Run(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Outbox consumer stopped", "error", err)
+			}
+		}()
+		slog.Info("✅ Outbox queue consumer started", "nats_url", natsURL)
+	}
+
 	// Initialize handlers
-	whatsappHandler := handlers.NewWhatsAppHandler(storage, whatsappConnector, backendClient, integrationClient)
+	whatsappHandler := handlers.NewWhatsAppHandler(storage, whatsappConnector, integrationClient, logger)
 	mainHandler := handlers.NewMainHandler(storage, whatsappHandler, jwtConfig)
 
+	// Log every error response the handlers write
+	httpx.SetHooks(httpx.Hooks{
+		OnError: func(e *apierror.E) {
+			slog.Warn("API error", "status", e.Status, "code", e.Code, "message", e.Message, "error", e.Err)
+		},
+		OnEncodeError: func(err error) {
+			slog.Error("Failed to encode JSON response", "error", err)
+		},
+	})
+
 	// Setup HTTP router
 	r := chi.NewRouter()
 
 	// Middleware
-	r.Use(middleware.RequestID)
+	r.Use(requestid.Middleware)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
@@ -134,6 +403,12 @@ func main() {
 		MaxAge:           300,
 	}))
 
+	r.Use(httpx.MaxBodySize(handlers.DefaultMaxBodyBytes))
+
+	// Metrics
+	r.Handle("/debug/vars", expvar.Handler())
+	r.Get("/stats", bridgeStats.ServeHTTP)
+
 	// Mount all routes
 	r.Mount("/", mainHandler.Routes())
 
@@ -158,6 +433,7 @@ func main() {
 	slog.Info("✅ Tennex Bridge Service is running!")
 	slog.Info("📊 Service endpoints:")
 	slog.Info("  Health check: http://localhost:" + DefaultPort + "/health")
+	slog.Info("  Runtime stats: http://localhost:" + DefaultPort + "/stats")
 	slog.Info("  WhatsApp connect: POST http://localhost:" + DefaultPort + "/whatsapp/connect (requires JWT)")
 	slog.Info("  WhatsApp status: GET http://localhost:" + DefaultPort + "/whatsapp/status (requires JWT)")
 	slog.Info("  Connections: GET http://localhost:" + DefaultPort + "/connections (requires JWT)")
@@ -172,6 +448,8 @@ func main() {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 
+	grpcServer.GracefulStop()
+
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		slog.Error("Server forced to shutdown", "error", err)
 		os.Exit(1)
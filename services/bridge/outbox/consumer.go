@@ -0,0 +1,307 @@
+// Package outbox sends the outbound messages the backend queues on the NATS
+// outbox work queue, as an alternative to the backend calling this bridge's
+// control service directly
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/tennex/pkg/events"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+const (
+	// Messages fetched per pull, and how long a pull waits for them
+	fetchBatch = 10
+	fetchWait  = 5 * time.Second
+
+	// How often to retry until the backend has created the outbox stream
+	subscribeRetry = 5 * time.Second
+
+	// Attempts to report a send result before giving up on it, and how long
+	// each waits for the stream to store it
+	resultPublishAttempts = 3
+	resultPublishTimeout  = 10 * time.Second
+
+	// How long a sent message is remembered, as long as the backend keeps
+	// queued messages
+	sentTTL = 24 * time.Hour
+
+	// Time left before the ack deadline for acknowledging a sent message
+	ackMargin = 10 * time.Second
+)
+
+// Sender sends one outbound message (implemented by *control.Server)
+type Sender interface {
+	SendMessage(ctx context.Context, req *proto.SendMessageRequest) (*proto.SendMessageResponse, error)
+}
+
+// Config controls which messages a consumer takes and how long they may wait
+// for the bridge that holds their account
+type Config struct {
+	// NATS subject prefix shared with the backend, e.g. "tennex.prod"
+	Prefix string
+
+	// Integration type whose messages this bridge sends, e.g. "whatsapp"
+	IntegrationType string
+
+	// A message for an account with no session on this bridge is offered to
+	// the other bridges again after RedeliverDelay, and reported failed after
+	// MaxDeliver deliveries
+	RedeliverDelay time.Duration
+	MaxDeliver     int
+
+	// How long a bridge may take to send a message before it is redelivered.
+	// Sends are cut off before it runs out.
+	AckWait time.Duration
+}
+
+// Consumer takes messages from the outbox work queue, sends them, and
+// reports each result to the backend. Bridge replicas share one durable
+// consumer, so each message is handled by one of them at a time, and record
+// what they sent in a shared bucket, so a message is sent once even if it's
+// redelivered to another replica.
+type Consumer struct {
+	js     nats.JetStreamContext
+	sent   nats.KeyValue
+	sender Sender
+	config Config
+}
+
+// NewConsumer creates a new outbox consumer
+func NewConsumer(js nats.JetStreamContext, sender Sender, config Config) *Consumer {
+	if config.RedeliverDelay <= 0 {
+		config.RedeliverDelay = 2 * time.Second
+	}
+	if config.MaxDeliver <= 0 {
+		config.MaxDeliver = 10
+	}
+	if config.AckWait <= 0 {
+		config.AckWait = time.Minute
+	}
+
+	return &Consumer{
+		js:     js,
+		sender: sender,
+		config: config,
+	}
+}
+
+// Run sends queued messages until ctx is done
+func (c *Consumer) Run(ctx context.Context) error {
+	sub, err := c.subscribe(ctx)
+	if err != nil {
+		return err
+	}
+	if c.sent, err = c.sentBucket(); err != nil {
+		return err
+	}
+
+	log.Printf("📬 [OUTBOX] Consuming %s", events.OutboxSendSubject(c.config.Prefix, c.config.IntegrationType))
+
+	for ctx.Err() == nil {
+		fetchCtx, cancel := context.WithTimeout(ctx, fetchWait)
+		msgs, err := sub.Fetch(fetchBatch, nats.Context(fetchCtx))
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, nats.ErrTimeout) {
+				log.Printf("⚠️ [OUTBOX] Fetch failed: %v", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		for _, msg := range msgs {
+			c.handle(ctx, msg)
+		}
+	}
+	return nil
+}
+
+// subscribe creates the bridges' durable consumer if needed and binds to it,
+// waiting for the backend to create the stream
+func (c *Consumer) subscribe(ctx context.Context) (*nats.Subscription, error) {
+	stream := events.OutboxStreamName(c.config.Prefix)
+	durable := "bridge-" + c.config.IntegrationType
+	consumerConfig := &nats.ConsumerConfig{
+		Durable:       durable,
+		FilterSubject: events.OutboxSendSubject(c.config.Prefix, c.config.IntegrationType),
+		AckPolicy:     nats.AckExplicitPolicy,
+		AckWait:       c.config.AckWait,
+		MaxDeliver:    c.config.MaxDeliver,
+	}
+
+	waiting := false
+	for {
+		_, err := c.js.AddConsumer(stream, consumerConfig)
+		if err == nil {
+			return c.js.PullSubscribe(consumerConfig.FilterSubject, durable, nats.Bind(stream, durable))
+		}
+		if !errors.Is(err, nats.ErrStreamNotFound) {
+			return nil, err
+		}
+
+		if !waiting {
+			log.Printf("⏳ [OUTBOX] Waiting for the backend to create stream %s", stream)
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(subscribeRetry):
+		}
+	}
+}
+
+// sentBucket opens the bucket sent messages are recorded in, creating it if
+// this is the first bridge to run
+func (c *Consumer) sentBucket() (nats.KeyValue, error) {
+	bucket := events.OutboxSentBucket(c.config.Prefix)
+	kv, err := c.js.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = c.js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:  bucket,
+			TTL:     sentTTL,
+			Storage: nats.FileStorage,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox sent bucket %s: %w", bucket, err)
+	}
+	return kv, nil
+}
+
+// handle sends one queued message. The message's client_msg_uuid is claimed
+// in the sent bucket before sending, so a message is sent at most once even
+// if it's redelivered or its result can't be reported; a redelivered message
+// that was sent before only has its result reported again.
+func (c *Consumer) handle(ctx context.Context, msg *nats.Msg) {
+	var req proto.SendMessageRequest
+	if err := protobuf.Unmarshal(msg.Data, &req); err != nil {
+		log.Printf("❌ [OUTBOX] Dropping malformed message: %v", err)
+		msg.Term()
+		return
+	}
+
+	// Restart the ack deadline; the send below ends before it runs out
+	if err := msg.InProgress(); err != nil {
+		log.Printf("⚠️ [OUTBOX] Failed to mark client_msg_uuid=%s in progress: %v", req.ClientMsgUuid, err)
+	}
+
+	revision, err := c.sent.Create(req.ClientMsgUuid, nil)
+	if errors.Is(err, nats.ErrKeyExists) {
+		c.resendResult(ctx, msg, req.ClientMsgUuid)
+		return
+	}
+	if err != nil {
+		// Without a claim the message could be sent twice; try again later
+		log.Printf("⚠️ [OUTBOX] Failed to claim client_msg_uuid=%s: %v", req.ClientMsgUuid, err)
+		msg.NakWithDelay(c.config.RedeliverDelay)
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, c.sendTimeout())
+	resp, err := c.sender.SendMessage(sendCtx, &req)
+	cancel()
+	if status.Code(err) == codes.NotFound && !c.lastDelivery(msg) {
+		// Nothing was sent, and another bridge may hold the account's session
+		if err := c.sent.Delete(req.ClientMsgUuid, nats.LastRevision(revision)); err != nil {
+			log.Printf("⚠️ [OUTBOX] Failed to release client_msg_uuid=%s: %v", req.ClientMsgUuid, err)
+		}
+		msg.NakWithDelay(c.config.RedeliverDelay)
+		return
+	}
+
+	result := &proto.SendMessageResult{
+		ClientMsgUuid: req.ClientMsgUuid,
+		AccountId:     req.AccountId,
+	}
+	if err != nil {
+		result.Error = status.Convert(err).Message()
+	} else {
+		result.Success = resp.Success
+		result.WaMessageId = resp.WaMessageId
+		result.Error = resp.Error
+	}
+
+	data, err := protobuf.Marshal(result)
+	if err != nil {
+		log.Printf("❌ [OUTBOX] Failed to marshal result for client_msg_uuid=%s: %v", result.ClientMsgUuid, err)
+		msg.AckSync()
+		return
+	}
+	if _, err := c.sent.Update(req.ClientMsgUuid, data, revision); err != nil {
+		log.Printf("⚠️ [OUTBOX] Failed to record result for client_msg_uuid=%s: %v", req.ClientMsgUuid, err)
+	}
+
+	if err := msg.AckSync(); err != nil {
+		log.Printf("⚠️ [OUTBOX] Failed to ack client_msg_uuid=%s: %v", req.ClientMsgUuid, err)
+	}
+	c.publishResult(ctx, result.ClientMsgUuid, data)
+}
+
+// resendResult acknowledges a redelivered message that was already claimed,
+// reporting its recorded result again. A message claimed by a send that never
+// finished has no result; the backend reports its entry as stuck.
+func (c *Consumer) resendResult(ctx context.Context, msg *nats.Msg, clientMsgUUID string) {
+	log.Printf("♻️ [OUTBOX] Skipping client_msg_uuid=%s, which was already sent", clientMsgUUID)
+	if err := msg.AckSync(); err != nil {
+		log.Printf("⚠️ [OUTBOX] Failed to ack client_msg_uuid=%s: %v", clientMsgUUID, err)
+	}
+
+	entry, err := c.sent.Get(clientMsgUUID)
+	if err != nil {
+		log.Printf("⚠️ [OUTBOX] Failed to read the result for client_msg_uuid=%s: %v", clientMsgUUID, err)
+		return
+	}
+	if len(entry.Value()) > 0 {
+		c.publishResult(ctx, clientMsgUUID, entry.Value())
+	}
+}
+
+// sendTimeout bounds a send so the message is acknowledged before its ack
+// deadline and isn't redelivered while it's being sent
+func (c *Consumer) sendTimeout() time.Duration {
+	if c.config.AckWait > 2*ackMargin {
+		return c.config.AckWait - ackMargin
+	}
+	return c.config.AckWait / 2
+}
+
+// lastDelivery reports whether msg won't be delivered again if it isn't acked
+func (c *Consumer) lastDelivery(msg *nats.Msg) bool {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return true
+	}
+	return meta.NumDelivered >= uint64(c.config.MaxDeliver)
+}
+
+// publishResult reports a marshaled SendMessageResult to the backend
+func (c *Consumer) publishResult(ctx context.Context, clientMsgUUID string, data []byte) {
+	var err error
+	for attempt := 1; ; attempt++ {
+		msg := nats.NewMsg(events.OutboxResultSubject(c.config.Prefix))
+		msg.Data = data
+		publishCtx, cancel := context.WithTimeout(ctx, resultPublishTimeout)
+		_, err = c.js.PublishMsg(msg, nats.MsgId("result-"+clientMsgUUID), nats.Context(publishCtx))
+		cancel()
+		if err == nil || attempt == resultPublishAttempts || ctx.Err() != nil {
+			break
+		}
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+	if err != nil {
+		// The backend reports the entry as stuck
+		log.Printf("❌ [OUTBOX] Failed to report result for client_msg_uuid=%s: %v", clientMsgUUID, err)
+	}
+}
@@ -0,0 +1,349 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	protobuf "google.golang.org/protobuf/proto"
+
+	tennexevents "github.com/tennex/pkg/events"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+const (
+	avatarFetchQueue   = 1024        // Avatars waiting to be fetched; more are dropped
+	avatarFetchTimeout = time.Minute // Per avatar, covering the lookup, the download and the upload
+	avatarMaxBytes     = 5 << 20     // Larger pictures are skipped
+)
+
+// avatarFetcher looks up profile pictures; *whatsmeow.Client implements it
+type avatarFetcher interface {
+	GetProfilePictureInfo(jid types.JID, params *whatsmeow.GetProfilePictureParams) (*types.ProfilePictureInfo, error)
+}
+
+// avatarSyncer sends contacts and conversations to the backend
+type avatarSyncer interface {
+	SyncConversations(ctx context.Context, integrationCtx *proto.IntegrationContext, conversations []*proto.Conversation, syncType string) error
+	SyncContacts(ctx context.Context, integrationCtx *proto.IntegrationContext, contacts []*proto.Contact) error
+}
+
+// avatarEntry is what's known about the avatar of one JID
+type avatarEntry struct {
+	fetched   bool   // Whether pictureID and url are known
+	pictureID string // WhatsApp's ID of the picture; empty when there's none
+	url       string // Backend URL of the stored picture; empty when there's none
+
+	// The contact and conversation of the JID as last synced, re-synced with
+	// the avatar once it's fetched
+	integrationCtx *proto.IntegrationContext
+	contact        *proto.Contact
+	conversation   *proto.Conversation
+}
+
+// Avatars fetches the profile pictures of contacts and groups into the
+// backend's media store. A picture is fetched the first time its JID is
+// synced and again when WhatsApp reports it changed; fetches are paced, as
+// WhatsApp rate limits them. Fetched avatars are filled into every later sync,
+// with the picture's ID in the platform metadata so an unchanged picture isn't
+// downloaded again.
+type Avatars struct {
+	fetcher  avatarFetcher
+	download func(ctx context.Context, url string) ([]byte, error)
+	uploader mediaUploader
+	syncer   avatarSyncer
+	interval time.Duration // Between fetches
+	jobs     chan types.JID
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	entries map[types.JID]*avatarEntry
+	queued  map[types.JID]bool
+}
+
+// NewAvatars creates an avatar fetcher making at most perSecond fetches a
+// second; Run starts it
+func NewAvatars(fetcher avatarFetcher, uploader mediaUploader, syncer avatarSyncer, perSecond float64, logger *slog.Logger) *Avatars {
+	return &Avatars{
+		fetcher:  fetcher,
+		download: downloadAvatar,
+		uploader: uploader,
+		syncer:   syncer,
+		interval: time.Duration(float64(time.Second) / perSecond),
+		jobs:     make(chan types.JID, avatarFetchQueue),
+		logger:   logger.With("component", "avatars"),
+		entries:  make(map[types.JID]*avatarEntry),
+		queued:   make(map[types.JID]bool),
+	}
+}
+
+// Run fetches queued avatars, one per interval, until ctx is done
+func (a *Avatars) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case jid := <-a.jobs:
+			a.fetch(ctx, jid)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(a.interval):
+		}
+	}
+}
+
+// ApplyContacts fills the known avatars into contacts about to be synced and
+// queues fetches for the others
+func (a *Avatars) ApplyContacts(integrationCtx *proto.IntegrationContext, contacts []*proto.Contact) {
+	for _, contact := range contacts {
+		jid, err := types.ParseJID(contact.PlatformId)
+		if err != nil {
+			continue
+		}
+		a.apply(jid, func(entry *avatarEntry) {
+			if entry.fetched {
+				setContactAvatar(contact, entry.pictureID, entry.url)
+			}
+			entry.integrationCtx = integrationCtx
+			entry.contact = protobuf.Clone(contact).(*proto.Contact)
+		})
+	}
+}
+
+// ApplyConversations fills the known avatars into conversations about to be
+// synced and queues fetches for the others. Only groups and individual chats
+// have pictures.
+func (a *Avatars) ApplyConversations(integrationCtx *proto.IntegrationContext, conversations []*proto.Conversation) {
+	for _, conv := range conversations {
+		if conv.Type != proto.ConversationType_CONVERSATION_TYPE_GROUP && conv.Type != proto.ConversationType_CONVERSATION_TYPE_INDIVIDUAL {
+			continue
+		}
+		jid, err := types.ParseJID(conv.PlatformId)
+		if err != nil {
+			continue
+		}
+		a.apply(jid, func(entry *avatarEntry) {
+			if entry.fetched {
+				setConversationAvatar(conv, entry.pictureID, entry.url)
+			}
+			entry.integrationCtx = integrationCtx
+			entry.conversation = protobuf.Clone(conv).(*proto.Conversation)
+		})
+	}
+}
+
+// apply updates the entry of jid and queues a fetch when its avatar isn't known
+func (a *Avatars) apply(jid types.JID, update func(entry *avatarEntry)) {
+	a.mu.Lock()
+	entry, ok := a.entries[jid]
+	if !ok {
+		entry = &avatarEntry{}
+		a.entries[jid] = entry
+	}
+	update(entry)
+	fetched := entry.fetched
+	a.mu.Unlock()
+
+	if !fetched {
+		a.enqueue(jid)
+	}
+}
+
+// PictureChanged handles WhatsApp reporting a new or removed picture. JIDs
+// that were never synced are skipped; their picture is fetched when they are.
+func (a *Avatars) PictureChanged(ctx context.Context, evt *events.Picture) {
+	a.mu.Lock()
+	entry, ok := a.entries[evt.JID]
+	unchanged := ok && entry.fetched && !evt.Remove && entry.pictureID == evt.PictureID
+	a.mu.Unlock()
+
+	switch {
+	case !ok || unchanged:
+		return
+	case evt.Remove:
+		a.update(ctx, evt.JID, "", "")
+	default:
+		a.enqueue(evt.JID)
+	}
+}
+
+// enqueue queues a fetch of jid's avatar without blocking, unless one is
+// already queued. If the queue is full the fetch is dropped and retried the
+// next time the JID is synced.
+func (a *Avatars) enqueue(jid types.JID) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.queued[jid] {
+		return
+	}
+
+	select {
+	case a.jobs <- jid:
+		a.queued[jid] = true
+	default:
+		a.logger.Warn("Avatar fetch queue full, skipping avatar", "jid", jid.String())
+	}
+}
+
+// fetch looks up jid's picture and, when it's new, stores it in the backend
+// and re-syncs the JID's contact and conversation with it
+func (a *Avatars) fetch(ctx context.Context, jid types.JID) {
+	ctx, cancel := context.WithTimeout(ctx, avatarFetchTimeout)
+	defer cancel()
+	logger := a.logger.With("jid", jid.String())
+
+	a.mu.Lock()
+	delete(a.queued, jid)
+	entry := a.entries[jid]
+	if entry == nil {
+		a.mu.Unlock()
+		return
+	}
+	integrationCtx := entry.integrationCtx
+	var existingID string
+	if entry.fetched {
+		existingID = entry.pictureID
+	}
+	a.mu.Unlock()
+
+	info, err := a.fetcher.GetProfilePictureInfo(jid, &whatsmeow.GetProfilePictureParams{ExistingID: existingID})
+	switch {
+	case errors.Is(err, whatsmeow.ErrProfilePictureNotSet), errors.Is(err, whatsmeow.ErrProfilePictureUnauthorized):
+		a.update(ctx, jid, "", "")
+		return
+	case err != nil:
+		logger.Warn("Failed to look up profile picture", "error", err)
+		return
+	case info == nil || (existingID != "" && info.ID == existingID):
+		// WhatsApp answers with no picture when the existing one is current
+		logger.Debug("Profile picture unchanged")
+		return
+	}
+
+	data, err := a.download(ctx, info.URL)
+	if err != nil {
+		logger.Warn("Failed to download profile picture", "error", err)
+		return
+	}
+	sum := sha256.Sum256(data)
+	contentHash := hex.EncodeToString(sum[:])
+
+	missing, err := a.uploader.GetMissingMedia(ctx, integrationCtx, []string{contentHash})
+	if err != nil {
+		logger.Warn("Failed to check for stored profile picture", "error", err)
+		return
+	}
+	if len(missing) > 0 {
+		if err := a.uploader.UploadMedia(ctx, integrationCtx, contentHash, http.DetectContentType(data), data); err != nil {
+			logger.Warn("Failed to upload profile picture", "error", err)
+			return
+		}
+	}
+	a.update(ctx, jid, info.ID, tennexevents.AvatarURLPrefix+contentHash)
+}
+
+// update records jid's avatar and re-syncs its contact and conversation when
+// the avatar they were last synced with is different. The contact is synced
+// whole, as last synced; the conversation with SyncTypeAvatar, as a full
+// conversation sync is skipped when the stored one has newer activity.
+func (a *Avatars) update(ctx context.Context, jid types.JID, pictureID, url string) {
+	a.mu.Lock()
+	entry, ok := a.entries[jid]
+	if !ok {
+		a.mu.Unlock()
+		return
+	}
+	entry.fetched = true
+	entry.pictureID = pictureID
+	entry.url = url
+
+	integrationCtx := entry.integrationCtx
+	var contact *proto.Contact
+	if entry.contact != nil && entry.contact.AvatarUrl != url {
+		setContactAvatar(entry.contact, pictureID, url)
+		contact = protobuf.Clone(entry.contact).(*proto.Contact)
+	}
+	var conv *proto.Conversation
+	if entry.conversation != nil && entry.conversation.AvatarUrl != url {
+		setConversationAvatar(entry.conversation, pictureID, url)
+		conv = protobuf.Clone(entry.conversation).(*proto.Conversation)
+	}
+	a.mu.Unlock()
+
+	logger := a.logger.With("jid", jid.String(), "picture_id", pictureID)
+	if contact != nil {
+		if err := a.syncer.SyncContacts(ctx, integrationCtx, []*proto.Contact{contact}); err != nil {
+			logger.Warn("Failed to sync contact avatar", "error", err)
+		}
+	}
+	if conv != nil {
+		if err := a.syncer.SyncConversations(ctx, integrationCtx, []*proto.Conversation{conv}, tennexevents.SyncTypeAvatar); err != nil {
+			logger.Warn("Failed to sync conversation avatar", "error", err)
+		}
+	}
+	if contact != nil || conv != nil {
+		logger.Info("Avatar updated", "removed", url == "")
+	}
+}
+
+func setContactAvatar(contact *proto.Contact, pictureID, url string) {
+	contact.AvatarUrl = url
+	if contact.PlatformMetadata == nil {
+		contact.PlatformMetadata = make(map[string]string)
+	}
+	setAvatarPictureID(contact.PlatformMetadata, pictureID)
+}
+
+func setConversationAvatar(conv *proto.Conversation, pictureID, url string) {
+	conv.AvatarUrl = url
+	if conv.PlatformMetadata == nil {
+		conv.PlatformMetadata = make(map[string]string)
+	}
+	setAvatarPictureID(conv.PlatformMetadata, pictureID)
+}
+
+func setAvatarPictureID(metadata map[string]string, pictureID string) {
+	if pictureID == "" {
+		delete(metadata, tennexevents.AvatarPictureIDKey)
+		return
+	}
+	metadata[tennexevents.AvatarPictureIDKey] = pictureID
+}
+
+// downloadAvatar downloads a profile picture from the URL WhatsApp gave for it
+func downloadAvatar(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, avatarMaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > avatarMaxBytes {
+		return nil, fmt.Errorf("picture is larger than %d bytes", avatarMaxBytes)
+	}
+	return data, nil
+}
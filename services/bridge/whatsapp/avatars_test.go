@@ -0,0 +1,212 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"testing"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	tennexevents "github.com/tennex/pkg/events"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+// fakeAvatarFetcher answers with the picture set for each JID, and with
+// nothing when it's the picture the request says is known
+type fakeAvatarFetcher struct {
+	pictures    map[types.JID]string // JID to picture ID; missing means none is set
+	existingIDs []string             // The ExistingID of each lookup
+}
+
+func (f *fakeAvatarFetcher) GetProfilePictureInfo(jid types.JID, params *whatsmeow.GetProfilePictureParams) (*types.ProfilePictureInfo, error) {
+	f.existingIDs = append(f.existingIDs, params.ExistingID)
+	id, ok := f.pictures[jid]
+	switch {
+	case !ok:
+		return nil, whatsmeow.ErrProfilePictureNotSet
+	case id == params.ExistingID:
+		return nil, nil
+	}
+	return &types.ProfilePictureInfo{ID: id, URL: "https://pps.whatsapp.net/" + id}, nil
+}
+
+type fakeAvatarSyncer struct {
+	contacts      []*proto.Contact
+	conversations []*proto.Conversation
+	syncTypes     []string
+}
+
+func (s *fakeAvatarSyncer) SyncConversations(ctx context.Context, integrationCtx *proto.IntegrationContext, conversations []*proto.Conversation, syncType string) error {
+	s.conversations = append(s.conversations, conversations...)
+	for range conversations {
+		s.syncTypes = append(s.syncTypes, syncType)
+	}
+	return nil
+}
+
+func (s *fakeAvatarSyncer) SyncContacts(ctx context.Context, integrationCtx *proto.IntegrationContext, contacts []*proto.Contact) error {
+	s.contacts = append(s.contacts, contacts...)
+	return nil
+}
+
+var avatarTestJID = types.NewJID("972501111111", types.DefaultUserServer)
+
+func newTestAvatars(fetcher *fakeAvatarFetcher, syncer *fakeAvatarSyncer) (*Avatars, *int) {
+	a := NewAvatars(fetcher, &fakeMediaUploader{stored: map[string]bool{}}, syncer, 1, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	downloads := new(int)
+	a.download = func(ctx context.Context, url string) ([]byte, error) {
+		*downloads++
+		return []byte(url), nil
+	}
+	return a, downloads
+}
+
+// runQueued fetches the avatars queued so far, as Run would
+func runQueued(ctx context.Context, a *Avatars) int {
+	fetched := 0
+	for {
+		select {
+		case jid := <-a.jobs:
+			a.fetch(ctx, jid)
+			fetched++
+		default:
+			return fetched
+		}
+	}
+}
+
+func avatarURL(pictureID string) string {
+	sum := sha256.Sum256([]byte("https://pps.whatsapp.net/" + pictureID))
+	return tennexevents.AvatarURLPrefix + hex.EncodeToString(sum[:])
+}
+
+func TestAvatarsFetchOnFirstSync(t *testing.T) {
+	fetcher := &fakeAvatarFetcher{pictures: map[types.JID]string{avatarTestJID: "pic1"}}
+	syncer := &fakeAvatarSyncer{}
+	a, downloads := newTestAvatars(fetcher, syncer)
+	ctx := context.Background()
+
+	contact := &proto.Contact{PlatformId: avatarTestJID.String(), DisplayName: "Dana"}
+	a.ApplyContacts(nil, []*proto.Contact{contact})
+	a.ApplyConversations(nil, []*proto.Conversation{{PlatformId: avatarTestJID.String(), Type: proto.ConversationType_CONVERSATION_TYPE_INDIVIDUAL}})
+	if contact.AvatarUrl != "" {
+		t.Fatalf("expected no avatar before it's fetched, got %q", contact.AvatarUrl)
+	}
+	if n := runQueued(ctx, a); n != 1 {
+		t.Fatalf("expected 1 fetch for the contact and conversation of one JID, got %d", n)
+	}
+	if *downloads != 1 {
+		t.Errorf("expected 1 download, got %d", *downloads)
+	}
+
+	if len(syncer.contacts) != 1 || len(syncer.conversations) != 1 {
+		t.Fatalf("expected the contact and conversation to be re-synced, got %d and %d", len(syncer.contacts), len(syncer.conversations))
+	}
+	synced := syncer.contacts[0]
+	if synced.AvatarUrl != avatarURL("pic1") || synced.DisplayName != "Dana" {
+		t.Errorf("got contact %q with avatar %q, want Dana with %q", synced.DisplayName, synced.AvatarUrl, avatarURL("pic1"))
+	}
+	if got := synced.PlatformMetadata[tennexevents.AvatarPictureIDKey]; got != "pic1" {
+		t.Errorf("got picture ID %q in the contact's metadata, want pic1", got)
+	}
+	if syncer.conversations[0].AvatarUrl != avatarURL("pic1") || syncer.syncTypes[0] != tennexevents.SyncTypeAvatar {
+		t.Errorf("got conversation avatar %q synced as %q, want %q as %q",
+			syncer.conversations[0].AvatarUrl, syncer.syncTypes[0], avatarURL("pic1"), tennexevents.SyncTypeAvatar)
+	}
+
+	// A later sync gets the avatar filled in without another fetch
+	again := &proto.Contact{PlatformId: avatarTestJID.String(), DisplayName: "Dana Levi"}
+	a.ApplyContacts(nil, []*proto.Contact{again})
+	if again.AvatarUrl != avatarURL("pic1") || again.PlatformMetadata[tennexevents.AvatarPictureIDKey] != "pic1" {
+		t.Errorf("expected the known avatar to be filled in, got %q", again.AvatarUrl)
+	}
+	if n := runQueued(ctx, a); n != 0 {
+		t.Errorf("expected no fetch once the avatar is known, got %d", n)
+	}
+}
+
+func TestAvatarsPictureChanged(t *testing.T) {
+	fetcher := &fakeAvatarFetcher{pictures: map[types.JID]string{avatarTestJID: "pic1"}}
+	syncer := &fakeAvatarSyncer{}
+	a, downloads := newTestAvatars(fetcher, syncer)
+	ctx := context.Background()
+
+	a.ApplyContacts(nil, []*proto.Contact{{PlatformId: avatarTestJID.String()}})
+	runQueued(ctx, a)
+
+	// The picture the avatar was fetched with isn't fetched again
+	a.PictureChanged(ctx, &events.Picture{JID: avatarTestJID, PictureID: "pic1"})
+	if n := runQueued(ctx, a); n != 0 {
+		t.Errorf("expected no fetch for the same picture ID, got %d", n)
+	}
+
+	// Neither is one WhatsApp says is current
+	a.PictureChanged(ctx, &events.Picture{JID: avatarTestJID, PictureID: "pic1-reported"})
+	runQueued(ctx, a)
+	if got := fetcher.existingIDs[len(fetcher.existingIDs)-1]; got != "pic1" {
+		t.Errorf("expected the lookup to pass the known picture ID, got %q", got)
+	}
+	if *downloads != 1 || len(syncer.contacts) != 1 {
+		t.Errorf("expected an unchanged picture not to be downloaded or synced, got %d downloads and %d syncs", *downloads, len(syncer.contacts))
+	}
+
+	// A new picture is
+	fetcher.pictures[avatarTestJID] = "pic2"
+	a.PictureChanged(ctx, &events.Picture{JID: avatarTestJID, PictureID: "pic2"})
+	runQueued(ctx, a)
+	if *downloads != 2 || len(syncer.contacts) != 2 {
+		t.Fatalf("expected the new picture to be downloaded and synced, got %d downloads and %d syncs", *downloads, len(syncer.contacts))
+	}
+	if got := syncer.contacts[1].AvatarUrl; got != avatarURL("pic2") {
+		t.Errorf("got avatar %q, want %q", got, avatarURL("pic2"))
+	}
+
+	// A removed picture clears the avatar without a lookup
+	lookups := len(fetcher.existingIDs)
+	a.PictureChanged(ctx, &events.Picture{JID: avatarTestJID, Remove: true})
+	if len(fetcher.existingIDs) != lookups {
+		t.Error("expected no lookup for a removed picture")
+	}
+	if len(syncer.contacts) != 3 {
+		t.Fatalf("expected the removal to be synced, got %d syncs", len(syncer.contacts))
+	}
+	removed := syncer.contacts[2]
+	if _, ok := removed.PlatformMetadata[tennexevents.AvatarPictureIDKey]; removed.AvatarUrl != "" || ok {
+		t.Errorf("expected the avatar and picture ID to be cleared, got %q and %v", removed.AvatarUrl, removed.PlatformMetadata)
+	}
+}
+
+func TestAvatarsSkipUnknownAndUnsetPictures(t *testing.T) {
+	fetcher := &fakeAvatarFetcher{pictures: map[types.JID]string{}}
+	syncer := &fakeAvatarSyncer{}
+	a, downloads := newTestAvatars(fetcher, syncer)
+	ctx := context.Background()
+
+	// JIDs that were never synced are left for their first sync
+	a.PictureChanged(ctx, &events.Picture{JID: avatarTestJID, PictureID: "pic1"})
+	if n := runQueued(ctx, a); n != 0 {
+		t.Errorf("expected no fetch for a JID that was never synced, got %d", n)
+	}
+
+	// Broadcast lists and the like have no picture
+	a.ApplyConversations(nil, []*proto.Conversation{{PlatformId: "status@broadcast", Type: proto.ConversationType_CONVERSATION_TYPE_BROADCAST}})
+	if n := runQueued(ctx, a); n != 0 {
+		t.Errorf("expected no fetch for a broadcast list, got %d", n)
+	}
+
+	// A contact without a picture is known not to have one and isn't re-synced
+	a.ApplyContacts(nil, []*proto.Contact{{PlatformId: avatarTestJID.String()}})
+	runQueued(ctx, a)
+	a.ApplyContacts(nil, []*proto.Contact{{PlatformId: avatarTestJID.String()}})
+	if n := runQueued(ctx, a); n != 0 {
+		t.Errorf("expected a contact without a picture to be fetched once, got %d more fetches", n)
+	}
+	if *downloads != 0 || len(syncer.contacts) != 0 {
+		t.Errorf("expected nothing downloaded or synced, got %d downloads and %d syncs", *downloads, len(syncer.contacts))
+	}
+}
@@ -2,109 +2,243 @@ package whatsapp
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
 
-	"github.com/mdp/qrterminal/v3"
 	"github.com/tennex/bridge/db"
 	backendGRPC "github.com/tennex/bridge/internal/grpc"
+	"github.com/tennex/bridge/internal/logging"
+	"github.com/tennex/bridge/internal/stats"
 	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/proto/waCompanionReg"
 	"go.mau.fi/whatsmeow/store"
 	"go.mau.fi/whatsmeow/store/sqlstore"
-	waLog "go.mau.fi/whatsmeow/util/log"
 	"google.golang.org/protobuf/proto"
-
-	_ "github.com/lib/pq" // PostgreSQL driver
 )
 
 type WhatsAppConnector struct {
-	storage           *db.Storage // Still needed for whatsmeow store
-	backendClient     *backendGRPC.BackendClient
-	integrationClient *backendGRPC.RecordingIntegrationClient
-	eventsProcessor   *EventsProcessor
+	storage           *db.Storage
+	deviceStore       *sqlstore.Container // Shared by every connection flow
+	integrationClient *backendGRPC.IntegrationClient
+	sessions          *SessionRegistry
+	pairing           *PairingSessions
+	watchdogConfig    WatchdogConfig
+	eventsConfig      EventsConfig
+	stats             *stats.Stats
+	logger            *slog.Logger
 }
 
-func NewWhatsAppConnector(storage *db.Storage, backendClient *backendGRPC.BackendClient, integrationClient *backendGRPC.RecordingIntegrationClient) *WhatsAppConnector {
+func NewWhatsAppConnector(storage *db.Storage, deviceStore *sqlstore.Container, integrationClient *backendGRPC.IntegrationClient, watchdogConfig WatchdogConfig, eventsConfig EventsConfig, pairingConfig PairingConfig, bridgeStats *stats.Stats, logger *slog.Logger) *WhatsAppConnector {
+	sessions := NewSessionRegistry()
+	bridgeStats.TrackActiveClients(sessions.Len)
 	return &WhatsAppConnector{
 		storage:           storage,
-		backendClient:     backendClient,
+		deviceStore:       deviceStore,
 		integrationClient: integrationClient,
+		sessions:          sessions,
+		pairing:           NewPairingSessions(pairingConfig),
+		watchdogConfig:    watchdogConfig,
+		eventsConfig:      eventsConfig,
+		stats:             bridgeStats,
+		logger:            logger,
 	}
 }
 
-type QRCodeData string
+// Sessions returns the registry of connected WhatsApp sessions
+func (c *WhatsAppConnector) Sessions() *SessionRegistry {
+	return c.sessions
+}
 
-func (c *WhatsAppConnector) RunWhatsAppConnectionFlow(ctx context.Context, accountID string, callbackChan chan<- QRCodeData) error {
-	fmt.Println("Starting WhatsApp connection flow...")
+// Pairing returns the registry of QR pairing flows in progress
+func (c *WhatsAppConnector) Pairing() *PairingSessions {
+	return c.pairing
+}
 
-	// Create events processor for this connection
-	c.eventsProcessor = NewEventsProcessor(c.integrationClient, c.backendClient, accountID)
+// ConnectOptions configures a single connection flow
+type ConnectOptions struct {
+	// FullSync asks the phone for the account's complete message history when
+	// the device is linked instead of only recent messages
+	FullSync bool
+}
 
-	dsn := db.GetConnectionString()
-	dbLogger := waLog.Stdout("whatsapp", "DEBUG", true)
+// devicePropsMu guards the global device props, which whatsmeow reads while
+// a new device registers during Connect
+var devicePropsMu sync.Mutex
 
-	container, err := sqlstore.New(ctx, "postgres", dsn, dbLogger)
-	if err != nil {
-		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
-	}
+// connectWithDeviceProps connects the client with the device props for this connection
+func connectWithDeviceProps(client *whatsmeow.Client, options ConnectOptions) error {
+	devicePropsMu.Lock()
+	defer devicePropsMu.Unlock()
 
 	store.DeviceProps.Os = proto.String("Tennex")
 	store.DeviceProps.PlatformType = waCompanionReg.DeviceProps_DESKTOP.Enum()
-	store.DeviceProps.RequireFullSync = proto.Bool(false)
+	store.DeviceProps.RequireFullSync = proto.Bool(options.FullSync)
+	return client.Connect()
+}
 
-	device := container.NewDevice()
-	client := whatsmeow.NewClient(device, dbLogger)
+// qrDisconnectWait bounds how long a new pairing round waits for whatsmeow to
+// drop the connection whose codes ran out
+const qrDisconnectWait = 5 * time.Second
+
+// refreshQRChannel starts a new pairing round on a client whose QR codes ran
+// out. whatsmeow disconnects the client after closing the old channel, so the
+// new connection waits for that; connecting first would have it dropped.
+func refreshQRChannel(ctx context.Context, client *whatsmeow.Client, options ConnectOptions) (<-chan whatsmeow.QRChannelItem, error) {
+	deadline := time.Now().Add(qrDisconnectWait)
+	for client.IsConnected() {
+		if time.Now().After(deadline) {
+			client.Disconnect()
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	qrChan, err := client.GetQRChannel(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get QR channel: %w", err)
+	}
+	if err := connectWithDeviceProps(client, options); err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	return qrChan, nil
+}
+
+// RunWhatsAppConnectionFlow links a new device for accountID, publishing its
+// QR codes and the outcome to pairingSession. ctx should be the session's
+// context, so the flow stops when the pairing is abandoned.
+func (c *WhatsAppConnector) RunWhatsAppConnectionFlow(ctx context.Context, accountID string, options ConnectOptions, pairingSession *PairingSession) error {
+	logger := c.logger.With("component", "whatsapp_connector", "user_id", accountID, "pairing_session", pairingSession.ID)
+	logger.Info("Starting WhatsApp connection flow", "full_sync", options.FullSync)
+
+	// Each flow has its own events processor. Everything below captures this
+	// one, so flows of other users, or of another number of this user,
+	// running at the same time never process this session's events.
+	processor := NewEventsProcessor(c.integrationClient, accountID, c.eventsConfig, c.logger)
+	go processor.Run(ctx)
+
+	device := c.deviceStore.NewDevice()
+	client := whatsmeow.NewClient(device, logging.Whatsmeow("whatsapp", "DEBUG"))
+	client.EnableAutoReconnect = false // The watchdog owns reconnection
+	session := &clientSession{client: client, processor: processor}
+	processor.SetClient(client)
+	mediaDownloader := NewMediaDownloader(client, c.integrationClient, logger)
+	go mediaDownloader.Run(ctx)
+	processor.SetMediaDownloader(mediaDownloader)
+	if c.eventsConfig.AvatarFetchRate > 0 {
+		avatars := NewAvatars(client, c.integrationClient, c.integrationClient, c.eventsConfig.AvatarFetchRate, logger)
+		go avatars.Run(ctx)
+		processor.SetAvatars(avatars)
+	}
+	watchdog := NewWatchdog(client, processor, c.watchdogConfig, logger)
 
-	// Use the events processor instead of the generic event handler
+	flow := &flowEvents{processor: processor, watchdog: watchdog, stats: c.stats}
 	client.AddEventHandler(func(evt interface{}) {
-		c.eventsProcessor.ProcessEvent(ctx, evt)
+		flow.handle(ctx, evt)
 	})
 
 	qrChan, err := client.GetQRChannel(ctx)
 	if err != nil {
+		c.pairing.Publish(pairingSession, PairingEvent{Type: PairingEventError, Error: "failed to start pairing"})
 		return fmt.Errorf("failed to get QR channel: %w", err)
 	}
 
-	if err := client.Connect(); err != nil {
+	if err := connectWithDeviceProps(client, options); err != nil {
+		c.pairing.Publish(pairingSession, PairingEvent{Type: PairingEventError, Error: "failed to connect to WhatsApp"})
 		return fmt.Errorf("failed to connect: %w", err)
 	}
+	c.stats.RecordQRSessionCreated()
+
+	pairingTimeout := c.pairing.Config().Timeout
+	pairingDeadline := time.NewTimer(pairingTimeout)
 
 	go func() {
-		fmt.Printf("🔄 [WA CLIENT DEBUG] Starting QR handler goroutine\n")
-		defer fmt.Printf("🔄 [WA CLIENT DEBUG] QR handler goroutine exiting\n")
+		logger.Debug("QR handler started")
+		defer logger.Debug("QR handler exiting")
+		defer pairingDeadline.Stop()
 
 		qrHandled := false
-
-		// Handle QR events
-		for evt := range qrChan {
-			fmt.Printf("🔄 [WA CLIENT DEBUG] Received QR event: %s\n", evt.Event)
+		qrCodesIssued := 0
+		startedAt := time.Now()
+
+		// Handle QR events until the channel closes, starting a new pairing
+		// round whenever the codes run out before the pairing timeout
+		for qrChan != nil {
+			var evt whatsmeow.QRChannelItem
+			select {
+			case item, ok := <-qrChan:
+				if !ok {
+					qrChan = nil
+					continue
+				}
+				evt = item
+			case <-pairingDeadline.C:
+				logger.Info("Pairing timed out without a scan", "qr_codes_issued", qrCodesIssued, "timeout", pairingTimeout)
+				c.stats.RecordQRSessionExpired()
+				c.pairing.Publish(pairingSession, PairingEvent{Type: PairingEventTimeout})
+				client.Disconnect()
+				qrChan = nil
+				continue
+			case <-ctx.Done():
+				// The pairing ended, e.g. another one of this user succeeded
+				logger.Info("Pairing abandoned", "qr_codes_issued", qrCodesIssued)
+				client.Disconnect()
+				qrChan = nil
+				continue
+			}
+			logger.Debug("QR event received", "event", evt.Event)
 
 			switch evt.Event {
 			case "code":
-				fmt.Println("\nScan this QR with WhatsApp:")
-				qrterminal.GenerateHalfBlock(evt.Code, qrterminal.L, os.Stdout)
-				fmt.Println()
-				fmt.Println("(If it expires, just run again.)")
-
-				callbackChan <- QRCodeData(evt.Code)
+				qrCodesIssued++
+				logger.Debug("QR code issued", "count", qrCodesIssued, "timeout", evt.Timeout)
+				expiresAt := time.Now().Add(evt.Timeout)
+				c.pairing.Publish(pairingSession, PairingEvent{Type: PairingEventCode, Code: evt.Code, ExpiresAt: &expiresAt})
+
+			case "timeout":
+				if time.Since(startedAt) < pairingTimeout {
+					next, err := refreshQRChannel(ctx, client, options)
+					if err == nil {
+						logger.Info("QR codes ran out, requested fresh ones", "qr_codes_issued", qrCodesIssued)
+						qrChan = next
+						continue
+					}
+					logger.Warn("Failed to refresh QR codes", "error", err)
+				}
+				logger.Info("QR codes expired without a scan", "qr_codes_issued", qrCodesIssued)
+				c.stats.RecordQRSessionExpired()
+				c.pairing.Publish(pairingSession, PairingEvent{Type: PairingEventTimeout})
 
 			case "success":
+				pairingDeadline.Stop()
 				jid := ""
+				deviceJID := ""
 				displayName := ""
 				avatarURL := ""
 
+				// Identify the integration by the account JID rather than this
+				// device's, so re-pairing a number reuses its integration. The
+				// device JID is unique to this pairing, so it makes retries of
+				// it idempotent.
 				if client.Store != nil && client.Store.ID != nil {
-					jid = client.Store.ID.String()
+					jid = client.Store.ID.ToNonAD().String()
+					deviceJID = client.Store.ID.String()
 				}
 
-				fmt.Printf("\n🎉 QR scan successful! Session established.\n")
-				fmt.Printf("👤 User ID: %s\n", accountID)
-				fmt.Printf("📱 WhatsApp JID: %s\n", jid)
+				logger.Info("QR scan successful, session established", "jid", jid)
+				c.stats.RecordQRSessionSucceeded()
 
 				// Start recording session if recording mode is enabled
 				if err := c.integrationClient.StartRecordingSession(accountID, "whatsapp"); err != nil {
-					fmt.Printf("⚠️  Failed to start recording session: %v\n", err)
+					logger.Warn("Failed to start recording session", "error", err)
 				}
 
 				// Create user integration in backend
@@ -112,51 +246,97 @@ func (c *WhatsAppConnector) RunWhatsAppConnectionFlow(ctx context.Context, accou
 					ctx,
 					accountID,
 					jid,
+					deviceJID,
 					displayName,
 					avatarURL,
 					map[string]string{
-						"device_id":     device.ID.String(),
-						"platform_type": "desktop",
+						"device_id":       device.ID.String(),
+						"platform_type":   "desktop",
+						"qr_codes_issued": strconv.Itoa(qrCodesIssued),
 					},
 				)
+				if errors.Is(err, backendGRPC.ErrIntegrationRejected) {
+					// The account can't be attached to this user, e.g. it is
+					// connected to another one; don't keep the new device linked
+					logger.Error("Backend rejected the pairing, logging the device out", "error", err)
+					if err := client.Logout(ctx); err != nil {
+						logger.Warn("Failed to log out rejected device", "error", err)
+					}
+					if err := c.integrationClient.EndRecordingSession(); err != nil {
+						logger.Warn("Failed to end recording session", "error", err)
+					}
+					c.pairing.Publish(pairingSession, PairingEvent{Type: PairingEventRejected, Error: err.Error()})
+					continue
+				}
 				if err != nil {
-					fmt.Printf("❌ Failed to create user integration: %v\n", err)
+					logger.Error("Failed to create user integration", "error", err)
 					// Continue anyway - don't fail the entire flow for this
 				} else {
-					fmt.Printf("✅ User integration created: ID=%d\n", userIntegrationID)
+					logger = logger.With("integration_id", userIntegrationID)
 
-					// Set integration context in events processor
-					c.eventsProcessor.SetIntegrationContext(userIntegrationID, jid)
+					// Set integration context in this flow's events processor
+					processor.SetIntegrationContext(userIntegrationID, jid)
 				}
 
-				// Also notify backend about connection via old bridge service (for compatibility)
-				if err := c.backendClient.UpdateAccountStatus(ctx, accountID, jid, displayName, avatarURL); err != nil {
-					fmt.Printf("❌ Failed to notify backend of WhatsApp connection: %v\n", err)
-					// Continue anyway - don't fail the entire flow for this
-				} else {
-					fmt.Printf("✅ Backend notified of WhatsApp connection!\n")
-				}
+				// Make the session reachable for backend-initiated operations
+				c.sessions.Register(accountID, session)
+				watchdog.Arm()
+				c.pairing.Publish(pairingSession, PairingEvent{Type: PairingEventSuccess, JID: jid})
+
 				qrHandled = true
+
+			default:
+				// err-client-outdated, err-scanned-without-multidevice and
+				// other errors end the QR channel
+				logger.Warn("QR pairing failed", "event", evt.Event, "error", evt.Error)
+				c.pairing.Publish(pairingSession, PairingEvent{Type: PairingEventError, Error: evt.Event})
 			}
 		}
 
-		fmt.Printf("🔄 [WA CLIENT DEBUG] QR channel closed, qrHandled=%v\n", qrHandled)
+		logger.Debug("QR channel closed", "qr_handled", qrHandled)
 
 		// Keep connection alive if QR was successfully handled
 		if qrHandled {
-			fmt.Printf("🔄 [WA CLIENT DEBUG] Keeping WhatsApp connection alive...\n")
 			// Keep the client connected and handle events
 			<-ctx.Done()
-			fmt.Printf("🔄 [WA CLIENT DEBUG] Context cancelled, disconnecting WhatsApp client\n")
+			logger.Info("Context cancelled, disconnecting WhatsApp client")
 
 			// End recording session before disconnecting
 			if err := c.integrationClient.EndRecordingSession(); err != nil {
-				fmt.Printf("⚠️  Failed to end recording session: %v\n", err)
+				logger.Warn("Failed to end recording session", "error", err)
 			}
 
+			c.sessions.Unregister(accountID, session)
 			client.Disconnect()
 		}
 	}()
 
 	return nil
 }
+
+// flowEvents routes the events of one connection flow's client to the
+// processor and watchdog of that flow
+type flowEvents struct {
+	processor *EventsProcessor
+	watchdog  *Watchdog
+	stats     *stats.Stats
+}
+
+// handle passes an event to the watchdog first, so a failed status update
+// can't stop a reconnect, then to the processor. Events are processed on the
+// processor's workers, so handle returns without waiting for the backend.
+func (f *flowEvents) handle(ctx context.Context, evt interface{}) {
+	f.watchdog.HandleEvent(ctx, evt)
+	f.processor.Dispatch(ctx, evt)
+	f.stats.RecordEvent(eventTypeName(evt))
+}
+
+// eventTypeName returns the name stats count a whatsmeow event under, e.g.
+// "Message" for *events.Message
+func eventTypeName(evt interface{}) string {
+	t := reflect.TypeOf(evt)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
@@ -0,0 +1,99 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tennex/bridge/internal/stats"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+// routedMessage is a message as it reached the backend
+type routedMessage struct {
+	userID        string
+	integrationID int32
+	messageID     string
+}
+
+// routingIntegrationClient reports the integration each message was sent
+// under. One is shared by every flow, like the bridge's backend client.
+type routingIntegrationClient struct {
+	fakeIntegrationClient
+	messages chan routedMessage
+}
+
+func (c *routingIntegrationClient) ProcessMessage(ctx context.Context, integrationCtx *proto.IntegrationContext, message *proto.Message) error {
+	c.messages <- routedMessage{
+		userID:        integrationCtx.UserId,
+		integrationID: integrationCtx.UserIntegrationId,
+		messageID:     message.PlatformId,
+	}
+	return nil
+}
+
+func TestConcurrentFlowsRouteEventsToTheirOwnIntegration(t *testing.T) {
+	const perFlow = 20
+	flows := []struct {
+		userID        string
+		integrationID int32
+		jid           string
+	}{
+		{"user-1", 11, "972501111111@s.whatsapp.net"},
+		{"user-1", 12, "972502222222@s.whatsapp.net"}, // A second number of the same user
+		{"user-2", 21, "972503333333@s.whatsapp.net"},
+	}
+
+	client := &routingIntegrationClient{messages: make(chan routedMessage, len(flows)*perFlow)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bridgeStats := stats.New()
+	logger := slog.New(slog.DiscardHandler)
+
+	// Start every flow before any of them pairs, as overlapping pairings do
+	handlers := make([]*flowEvents, len(flows))
+	for i, f := range flows {
+		processor := NewEventsProcessor(client, f.userID, DefaultEventsConfig(), logger)
+		go processor.Run(ctx)
+		handlers[i] = &flowEvents{
+			processor: processor,
+			watchdog:  NewWatchdog(nil, processor, DefaultWatchdogConfig(), logger),
+			stats:     bridgeStats,
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, f := range flows {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handlers[i].processor.SetIntegrationContext(f.integrationID, f.jid)
+			for n := range perFlow {
+				handlers[i].handle(ctx, messageEvent(testChat, fmt.Sprintf("%d-%d", f.integrationID, n)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	for range len(flows) * perFlow {
+		var msg routedMessage
+		select {
+		case msg = <-client.messages:
+		case <-time.After(5 * time.Second):
+			t.Fatal("not every message reached the backend")
+		}
+		want := fmt.Sprintf("%d-", msg.integrationID)
+		if !strings.HasPrefix(msg.messageID, want) {
+			t.Errorf("message %s was sent under integration %d of %s", msg.messageID, msg.integrationID, msg.userID)
+		}
+		for _, f := range flows {
+			if f.integrationID == msg.integrationID && f.userID != msg.userID {
+				t.Errorf("integration %d was sent as %s, want %s", msg.integrationID, msg.userID, f.userID)
+			}
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tennex/bridge/db"
+	"github.com/tennex/bridge/internal/logging"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+
+	_ "github.com/lib/pq" // PostgreSQL driver
+)
+
+// OpenDeviceStore opens the whatsmeow device store in the bridge's Postgres
+// database, upgrading its tables if needed. The store is shared by every
+// connection flow, so the bridge holds one connection pool for it however
+// many devices it links.
+func OpenDeviceStore(ctx context.Context) (*sqlstore.Container, error) {
+	container, err := sqlstore.New(ctx, "postgres", db.GetConnectionString(), logging.Whatsmeow("whatsapp", "DEBUG"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WhatsApp device store: %w", err)
+	}
+	return container, nil
+}
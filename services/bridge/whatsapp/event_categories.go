@@ -0,0 +1,66 @@
+package whatsapp
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// EventCategory is a group of WhatsApp events a deployment can choose not to
+// process. Connection events, messages and chat settings are always processed.
+type EventCategory string
+
+const (
+	EventCategoryPresence EventCategory = "presence" // Contacts coming online and typing
+	EventCategoryReceipts EventCategory = "receipts" // Delivery and read receipts, including the outbox's
+	EventCategoryContacts EventCategory = "contacts" // Address book, push name and profile picture changes
+	EventCategoryHistory  EventCategory = "history"  // History syncs sent by the phone
+)
+
+// EventCategories lists every event category
+var EventCategories = []EventCategory{
+	EventCategoryPresence,
+	EventCategoryReceipts,
+	EventCategoryContacts,
+	EventCategoryHistory,
+}
+
+// eventCategory returns the category of evt, or "" for events that are always
+// processed
+func eventCategory(evt interface{}) EventCategory {
+	switch evt.(type) {
+	case *events.Presence, *events.ChatPresence:
+		return EventCategoryPresence
+	case *events.Receipt:
+		return EventCategoryReceipts
+	case *events.Contact, *events.PushName, *events.Picture:
+		return EventCategoryContacts
+	case *events.HistorySync:
+		return EventCategoryHistory
+	default:
+		return ""
+	}
+}
+
+// ParseEventCategories parses a comma-separated list of event categories, like
+// "receipts,history". An empty list enables none.
+func ParseEventCategories(list string) (map[EventCategory]bool, error) {
+	enabled := make(map[EventCategory]bool, len(EventCategories))
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		category := EventCategory(name)
+		known := false
+		for _, c := range EventCategories {
+			known = known || c == category
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown event category %q", name)
+		}
+		enabled[category] = true
+	}
+	return enabled, nil
+}
@@ -0,0 +1,55 @@
+package whatsapp
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestParseEventCategories(t *testing.T) {
+	enabled, err := ParseEventCategories(" receipts, history ,")
+	if err != nil {
+		t.Fatalf("ParseEventCategories() error = %v", err)
+	}
+	if len(enabled) != 2 || !enabled[EventCategoryReceipts] || !enabled[EventCategoryHistory] {
+		t.Errorf("expected receipts and history, got %v", enabled)
+	}
+
+	if enabled, err := ParseEventCategories(""); err != nil || len(enabled) != 0 {
+		t.Errorf("expected no categories for an empty list, got %v, %v", enabled, err)
+	}
+	if _, err := ParseEventCategories("receipts,typing"); err == nil {
+		t.Error("expected an error for an unknown category")
+	}
+}
+
+func TestProcessEventDropsDisabledCategories(t *testing.T) {
+	fake := &fakeIntegrationClient{}
+	config := DefaultEventsConfig()
+	config.Categories = map[EventCategory]bool{EventCategoryReceipts: true}
+	p := NewEventsProcessor(fake, "user-1", config, slog.New(slog.DiscardHandler))
+	p.SetIntegrationContext(7, "972500000000@s.whatsapp.net")
+	ctx := context.Background()
+
+	if _, err := p.presence.Add([]string{testSender.String()}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	p.ProcessEvent(ctx, &events.Presence{From: testSender})
+	p.ProcessEvent(ctx, &events.Receipt{
+		MessageSource: types.MessageSource{Chat: testChat, Sender: testChat},
+		MessageIDs:    []types.MessageID{"PHONE1"},
+		Timestamp:     time.Unix(1700000000, 0),
+		Type:          types.ReceiptTypeDelivered,
+	})
+
+	if len(fake.presence) != 0 {
+		t.Errorf("expected presence to be dropped, got %+v", fake.presence)
+	}
+	if len(fake.phoneReceipts) != 1 {
+		t.Errorf("expected the receipt to be processed, got %+v", fake.phoneReceipts)
+	}
+}
@@ -0,0 +1,133 @@
+package whatsapp
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// EventsConfig controls how WhatsApp events are processed and sent to the backend
+type EventsConfig struct {
+	CallTimeout time.Duration // Deadline for a single backend call
+	SyncTimeout time.Duration // Deadline for streaming one sync batch to the backend
+	Workers     int           // Goroutines processing events other than history syncs
+	QueueSize   int           // Events a worker can have waiting before dispatch blocks
+
+	// Profile pictures fetched a second; WhatsApp rate limits the lookups.
+	// 0 doesn't fetch them.
+	AvatarFetchRate float64
+
+	// Optional event categories processed; events of the others are dropped
+	// before they're queued
+	Categories map[EventCategory]bool
+}
+
+// DefaultEventsConfig returns the event processing settings used unless overridden
+func DefaultEventsConfig() EventsConfig {
+	return EventsConfig{
+		CallTimeout:     15 * time.Second,
+		SyncTimeout:     2 * time.Minute,
+		Workers:         4,
+		QueueSize:       256,
+		AvatarFetchRate: 1,
+		Categories: map[EventCategory]bool{
+			EventCategoryPresence: true,
+			EventCategoryReceipts: true,
+			EventCategoryContacts: true,
+			EventCategoryHistory:  true,
+		},
+	}
+}
+
+// eventPool processes events on a fixed set of workers, so that whatsmeow's
+// event loop only hands events over. Events with the same key always go to
+// the same worker and are processed in the order they were submitted.
+type eventPool struct {
+	queues []chan func()
+	logger *slog.Logger
+}
+
+func newEventPool(workers, queueSize int, logger *slog.Logger) *eventPool {
+	queues := make([]chan func(), max(workers, 1))
+	for i := range queues {
+		queues[i] = make(chan func(), queueSize)
+	}
+	return &eventPool{queues: queues, logger: logger}
+}
+
+// Run processes submitted events until ctx is done
+func (p *eventPool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, queue := range p.queues {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-queue:
+					p.run(job)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// run runs a job, logging a panic instead of stopping the worker, as
+// whatsmeow does for a panicking event handler
+func (p *eventPool) run(job func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.logger.Error("Event handler panicked", "panic", r)
+		}
+	}()
+	job()
+}
+
+// Submit queues job on the worker for key. It blocks while that worker's
+// queue is full, which the backend call deadlines keep short, and drops the
+// job once ctx is done.
+func (p *eventPool) Submit(ctx context.Context, key string, job func()) {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	select {
+	case p.queues[hash.Sum32()%uint32(len(p.queues))] <- job:
+	case <-ctx.Done():
+	}
+}
+
+// eventKey returns the key that orders an event: the conversation for events
+// about one, so a chat's messages reach the backend in order. Connection
+// events share one key.
+func eventKey(evt interface{}) string {
+	switch v := evt.(type) {
+	case *events.Message:
+		return v.Info.Chat.String()
+	case *events.Receipt:
+		return v.Chat.String()
+	case *events.ChatPresence:
+		return v.Chat.String()
+	case *events.Presence:
+		return v.From.ToNonAD().String()
+	case *events.Contact:
+		return v.JID.String()
+	case *events.Pin:
+		return v.JID.String()
+	case *events.Archive:
+		return v.JID.String()
+	case *events.Mute:
+		return v.JID.String()
+	case *events.GroupInfo:
+		return v.JID.String()
+	case *events.Picture:
+		return v.JID.String()
+	default:
+		return "connection"
+	}
+}
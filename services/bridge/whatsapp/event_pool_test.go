@@ -0,0 +1,143 @@
+package whatsapp
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	protobuf "google.golang.org/protobuf/proto"
+
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+// slowIntegrationClient is a backend that never answers syncs and, when
+// hangMessages is set, real-time messages either; each call only returns once
+// its deadline passes. It reports the messages that reach it on messages.
+type slowIntegrationClient struct {
+	fakeIntegrationClient
+	t            *testing.T
+	hangMessages bool
+	syncStarted  chan struct{}
+	messages     chan *proto.Message
+}
+
+func (c *slowIntegrationClient) SyncConversations(ctx context.Context, integrationCtx *proto.IntegrationContext, conversations []*proto.Conversation, syncType string) error {
+	close(c.syncStarted)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (c *slowIntegrationClient) ProcessMessage(ctx context.Context, integrationCtx *proto.IntegrationContext, message *proto.Message) error {
+	if _, ok := ctx.Deadline(); !ok {
+		c.t.Error("backend call without a deadline")
+	}
+	c.messages <- message
+	if c.hangMessages {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+// startProcessor returns a processor with its workers running until the test ends
+func startProcessor(t *testing.T, client IntegrationClient, config EventsConfig) (*EventsProcessor, context.Context) {
+	t.Helper()
+	p := NewEventsProcessor(client, "user-1", config, slog.New(slog.DiscardHandler))
+	p.SetIntegrationContext(7, "972500000000@s.whatsapp.net")
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go p.Run(ctx)
+	return p, ctx
+}
+
+func messageEvent(chat types.JID, id string) *events.Message {
+	evt := testMessageEvent(&waE2E.Message{Conversation: protobuf.String(id)})
+	evt.Info.Chat = chat
+	evt.Info.ID = id
+	return evt
+}
+
+// receiveMessages waits for n messages to reach the backend and returns their IDs
+func receiveMessages(t *testing.T, messages <-chan *proto.Message, n int) []string {
+	t.Helper()
+	var ids []string
+	for range n {
+		select {
+		case msg := <-messages:
+			ids = append(ids, msg.PlatformId)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d messages reached the backend: %v", len(ids), n, ids)
+		}
+	}
+	return ids
+}
+
+func TestDispatchKeepsMessagesFlowingDuringSlowHistorySync(t *testing.T) {
+	client := &slowIntegrationClient{t: t, syncStarted: make(chan struct{}), messages: make(chan *proto.Message, 10)}
+	config := DefaultEventsConfig()
+	config.SyncTimeout = time.Hour // The history sync stays stuck for the whole test
+	p, ctx := startProcessor(t, client, config)
+
+	p.Dispatch(ctx, &events.HistorySync{Data: &waHistorySync.HistorySync{
+		SyncType:      waHistorySync.HistorySync_RECENT.Enum(),
+		Conversations: []*waHistorySync.Conversation{{ID: protobuf.String(testChat.String())}},
+	}})
+	select {
+	case <-client.syncStarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("history sync never reached the backend")
+	}
+
+	// Dispatching returns right away, as whatsmeow's event loop needs
+	otherChat := types.NewJID("972503333333", types.DefaultUserServer)
+	dispatched := make(chan struct{})
+	go func() {
+		for _, evt := range []*events.Message{
+			messageEvent(testChat, "MSG1"),
+			messageEvent(otherChat, "OTHER1"),
+			messageEvent(testChat, "MSG2"),
+			messageEvent(testChat, "MSG3"),
+		} {
+			p.Dispatch(ctx, evt)
+		}
+		close(dispatched)
+	}()
+	select {
+	case <-dispatched:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Dispatch blocked behind the history sync")
+	}
+
+	ids := receiveMessages(t, client.messages, 4)
+	var chatOrder []string
+	for _, id := range ids {
+		if id != "OTHER1" {
+			chatOrder = append(chatOrder, id)
+		}
+	}
+	if !slices.Equal(chatOrder, []string{"MSG1", "MSG2", "MSG3"}) {
+		t.Errorf("messages of one chat reached the backend as %v, want them in order", chatOrder)
+	}
+}
+
+func TestBackendCallsTimeOut(t *testing.T) {
+	client := &slowIntegrationClient{t: t, hangMessages: true, messages: make(chan *proto.Message, 10)}
+	config := DefaultEventsConfig()
+	config.CallTimeout = 20 * time.Millisecond
+	p, ctx := startProcessor(t, client, config)
+
+	// Each call gives up at its deadline, so the next message in the same
+	// chat still gets through
+	p.Dispatch(ctx, messageEvent(testChat, "MSG1"))
+	p.Dispatch(ctx, messageEvent(testChat, "MSG2"))
+
+	if ids := receiveMessages(t, client.messages, 2); !slices.Equal(ids, []string{"MSG1", "MSG2"}) {
+		t.Errorf("messages = %v, want [MSG1 MSG2]", ids)
+	}
+}
@@ -2,43 +2,134 @@ package whatsapp
 
 import (
 	"context"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/proto/waHistorySync"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
-	backendGRPC "github.com/tennex/bridge/internal/grpc"
+	tennexevents "github.com/tennex/pkg/events"
+	"github.com/tennex/pkg/requestid"
 	proto "github.com/tennex/shared/proto/gen/proto"
 )
 
+// IntegrationClient is the part of the backend integration API the events
+// processor sends to. It is implemented by the bridge's backend client in
+// internal/grpc.
+type IntegrationClient interface {
+	UpdateConnectionStatus(ctx context.Context, integrationCtx *proto.IntegrationContext, status proto.ConnectionStatus, qrCode string, metadata map[string]string) error
+	SyncConversations(ctx context.Context, integrationCtx *proto.IntegrationContext, conversations []*proto.Conversation, syncType string) error
+	SyncContacts(ctx context.Context, integrationCtx *proto.IntegrationContext, contacts []*proto.Contact) error
+	SyncMessages(ctx context.Context, integrationCtx *proto.IntegrationContext, conversationID string, messages []*proto.Message) error
+	ProcessMessage(ctx context.Context, integrationCtx *proto.IntegrationContext, message *proto.Message) error
+	ProcessPollVote(ctx context.Context, integrationCtx *proto.IntegrationContext, vote *proto.PollVote) error
+	SyncIdentityMappings(ctx context.Context, integrationCtx *proto.IntegrationContext, mappings []*proto.IdentityMapping) error
+	UpdateConversationState(ctx context.Context, integrationCtx *proto.IntegrationContext, conversationID string, state *proto.ConversationState, fields []string, changedAt time.Time) error
+	UpdatePresence(ctx context.Context, integrationCtx *proto.IntegrationContext, presence *proto.Presence) error
+	UpdateParticipantRoles(ctx context.Context, integrationCtx *proto.IntegrationContext, conversationID string, participants []*proto.ConversationParticipant, changedBy string, changedAt time.Time) error
+	UpdateOutboxDelivery(ctx context.Context, integrationCtx *proto.IntegrationContext, clientMsgUUID, waMessageID, conversationID string, status proto.MessageStatus, at time.Time) error
+	UpdateMessageDelivery(ctx context.Context, integrationCtx *proto.IntegrationContext, waMessageID, conversationID string, status proto.MessageStatus, at time.Time) error
+}
+
 // EventsProcessor handles WhatsApp events and sends them to the backend
 type EventsProcessor struct {
-	integrationClient *backendGRPC.RecordingIntegrationClient
-	backendClient     *backendGRPC.BackendClient // Keep old client for compatibility
+	integrationClient IntegrationClient
 	userID            string
 	userIntegrationID int32
 	integrationCtx    *proto.IntegrationContext
+	client            *whatsmeow.Client // Used to decrypt poll votes
+	config            EventsConfig
+	events            *eventPool // Real-time events, ordered per conversation
+	historySyncs      *eventPool // History syncs, one at a time
+	logger            *slog.Logger
+
+	mu           sync.Mutex
+	reportedLIDs map[string]string // LID -> phone-number JID already sent to the backend
+
+	history  *HistoryTracker        // Synced and requested history spans per conversation
+	resync   *HistoryResync         // Outstanding full history resync
+	recent   *RecentMessages        // Messages already sent to the backend
+	sent     *SentMessages          // Outbox messages awaiting receipts
+	presence *PresenceSubscriptions // Contacts whose presence is forwarded
+	media    *MediaDownloader       // Stores attachments in the backend; nil skips them
+	avatars  *Avatars               // Fetches profile pictures; nil skips them
 }
 
 // NewEventsProcessor creates a new events processor
-func NewEventsProcessor(integrationClient *backendGRPC.RecordingIntegrationClient, backendClient *backendGRPC.BackendClient, userID string) *EventsProcessor {
+func NewEventsProcessor(integrationClient IntegrationClient, userID string, config EventsConfig, logger *slog.Logger) *EventsProcessor {
+	logger = logger.With("component", "events_processor", "user_id", userID)
 	return &EventsProcessor{
 		integrationClient: integrationClient,
-		backendClient:     backendClient,
 		userID:            userID,
+		config:            config,
+		events:            newEventPool(config.Workers, config.QueueSize, logger),
+		historySyncs:      newEventPool(1, config.QueueSize, logger),
+		logger:            logger,
+		history:           NewHistoryTracker(),
+		resync:            NewHistoryResync(),
+		recent:            NewRecentMessages(recentMessagesSize),
+		sent:              NewSentMessages(sentMessagesSize, sentMessageTTL),
+		presence:          NewPresenceSubscriptions(),
+	}
+}
+
+// Run processes dispatched events until ctx is done
+func (p *EventsProcessor) Run(ctx context.Context) {
+	go p.historySyncs.Run(ctx)
+	p.events.Run(ctx)
+}
+
+// Dispatch hands an event to the worker for its conversation and returns
+// without waiting for the backend, so a slow backend doesn't hold up
+// whatsmeow's event loop. History syncs, which can take minutes to stream,
+// have a worker of their own so real-time messages never wait behind one.
+func (p *EventsProcessor) Dispatch(ctx context.Context, evt interface{}) {
+	if !p.processes(evt) {
+		return
+	}
+	job := func() {
+		p.ProcessEvent(ctx, evt)
 	}
+	if _, ok := evt.(*events.HistorySync); ok {
+		p.historySyncs.Submit(ctx, "", job)
+		return
+	}
+	p.events.Submit(ctx, eventKey(evt), job)
+}
+
+// processes reports whether evt is in an event category the processor is
+// configured to process
+func (p *EventsProcessor) processes(evt interface{}) bool {
+	category := eventCategory(evt)
+	return category == "" || p.config.Categories[category]
+}
+
+// callContext bounds a single backend call
+func (p *EventsProcessor) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, p.config.CallTimeout)
+}
+
+// syncContext bounds streaming one sync batch to the backend
+func (p *EventsProcessor) syncContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, p.config.SyncTimeout)
 }
 
 // SetIntegrationContext sets the integration context after user integration is created
 func (p *EventsProcessor) SetIntegrationContext(userIntegrationID int32, waJID string) {
 	p.userIntegrationID = userIntegrationID
+	p.logger = p.logger.With("integration_id", userIntegrationID)
 	p.integrationCtx = &proto.IntegrationContext{
 		UserId:            p.userID,
 		UserIntegrationId: userIntegrationID,
@@ -47,12 +138,51 @@ func (p *EventsProcessor) SetIntegrationContext(userIntegrationID int32, waJID s
 	}
 }
 
-// ProcessEvent processes a WhatsApp event and sends it to the backend
-// On any error, it will panic to force disconnection for easier debugging
+// SetClient sets the WhatsApp client the events are coming from
+func (p *EventsProcessor) SetClient(client *whatsmeow.Client) {
+	p.client = client
+}
+
+// SetMediaDownloader sets the downloader that stores real-time messages'
+// attachments in the backend
+func (p *EventsProcessor) SetMediaDownloader(media *MediaDownloader) {
+	p.media = media
+}
+
+// SetAvatars sets the fetcher that fills profile pictures into synced
+// contacts and conversations
+func (p *EventsProcessor) SetAvatars(avatars *Avatars) {
+	p.avatars = avatars
+}
+
+// ReportConnectionStatus sends a connection status update to the backend once
+// the account's integration exists
+func (p *EventsProcessor) ReportConnectionStatus(ctx context.Context, status proto.ConnectionStatus, metadata map[string]string) error {
+	if p.integrationCtx == nil {
+		return nil
+	}
+	ctx, cancel := p.callContext(ctx)
+	defer cancel()
+	return p.integrationClient.UpdateConnectionStatus(ctx, p.integrationCtx, status, "", metadata)
+}
+
+// ProcessEvent processes a WhatsApp event and sends it to the backend.
+// On any error it panics so the failure is loud; under Dispatch the worker
+// logs the panic and moves on to the next event.
 func (p *EventsProcessor) ProcessEvent(ctx context.Context, evt interface{}) {
+	// Each event gets a request ID, sent with the backend calls it makes, so
+	// the bridge's and the backend's logs of it can be matched up
+	if requestid.FromContext(ctx) == "" {
+		ctx = requestid.NewContext(ctx, requestid.New())
+	}
+
 	// Get event type name
 	eventType := reflect.TypeOf(evt).String()
-	log.Printf("\n🔔 Processing WhatsApp event: %s", eventType)
+	if !p.processes(evt) {
+		p.logger.DebugContext(ctx, "Dropping WhatsApp event of a disabled category", "event_type", eventType)
+		return
+	}
+	p.logger.DebugContext(ctx, "Processing WhatsApp event", "event_type", eventType)
 
 	var err error
 
@@ -97,27 +227,42 @@ func (p *EventsProcessor) ProcessEvent(ctx context.Context, evt interface{}) {
 	case *events.ChatPresence:
 		err = p.handleChatPresence(ctx, v)
 
+	case *events.Pin:
+		err = p.handlePin(ctx, v)
+
+	case *events.Archive:
+		err = p.handleArchive(ctx, v)
+
+	case *events.Mute:
+		err = p.handleMute(ctx, v)
+
+	case *events.Picture:
+		err = p.handlePicture(ctx, v)
+
 	// Note: OfflineSyncPreview and OfflineSyncCompleted events don't exist in this whatsmeow version
 
 	default:
-		log.Printf("❓ Unhandled WhatsApp event type: %s", eventType)
+		p.logger.DebugContext(ctx, "Unhandled WhatsApp event", "event_type", eventType)
 		return
 	}
 
 	// FAIL FAST: Panic on first error to force disconnection for debugging
 	if err != nil {
-		log.Printf("🚨 FATAL ERROR processing %s: %v", eventType, err)
-		log.Printf("🚨 Panicking to force disconnection for easier debugging")
+		p.logger.ErrorContext(ctx, "Event processing failed, panicking to force disconnection",
+			"event_type", eventType,
+			"error", err)
 		panic(fmt.Sprintf("Event processing failed: %v", err))
 	}
 }
 
 func (p *EventsProcessor) handleConnected(ctx context.Context, evt *events.Connected) error {
-	log.Printf("🔗 WhatsApp Connected!")
+	p.logger.InfoContext(ctx, "WhatsApp connected")
 
 	if p.integrationCtx != nil {
+		callCtx, cancel := p.callContext(ctx)
+		defer cancel()
 		err := p.integrationClient.UpdateConnectionStatus(
-			ctx,
+			callCtx,
 			p.integrationCtx,
 			proto.ConnectionStatus_CONNECTION_STATUS_CONNECTED,
 			"",
@@ -129,15 +274,20 @@ func (p *EventsProcessor) handleConnected(ctx context.Context, evt *events.Conne
 			return fmt.Errorf("failed to update connection status to connected: %w", err)
 		}
 	}
+
+	// WhatsApp drops presence subscriptions with the connection
+	p.resubscribePresence(ctx)
 	return nil
 }
 
 func (p *EventsProcessor) handleDisconnected(ctx context.Context, evt *events.Disconnected) error {
-	log.Printf("❌ WhatsApp Disconnected")
+	p.logger.InfoContext(ctx, "WhatsApp disconnected")
 
 	if p.integrationCtx != nil {
+		callCtx, cancel := p.callContext(ctx)
+		defer cancel()
 		err := p.integrationClient.UpdateConnectionStatus(
-			ctx,
+			callCtx,
 			p.integrationCtx,
 			proto.ConnectionStatus_CONNECTION_STATUS_DISCONNECTED,
 			"",
@@ -153,11 +303,13 @@ func (p *EventsProcessor) handleDisconnected(ctx context.Context, evt *events.Di
 }
 
 func (p *EventsProcessor) handleLoggedOut(ctx context.Context, evt *events.LoggedOut) error {
-	log.Printf("👋 WhatsApp Logged Out: reason=%s", evt.Reason)
+	p.logger.InfoContext(ctx, "WhatsApp logged out", "reason", evt.Reason.String())
 
 	if p.integrationCtx != nil {
+		callCtx, cancel := p.callContext(ctx)
+		defer cancel()
 		err := p.integrationClient.UpdateConnectionStatus(
-			ctx,
+			callCtx,
 			p.integrationCtx,
 			proto.ConnectionStatus_CONNECTION_STATUS_DISCONNECTED,
 			"",
@@ -174,14 +326,35 @@ func (p *EventsProcessor) handleLoggedOut(ctx context.Context, evt *events.Logge
 }
 
 func (p *EventsProcessor) handleHistorySync(ctx context.Context, evt *events.HistorySync) error {
-	log.Printf("🔄 History Sync: type=%s, conversations=%d",
-		evt.Data.SyncType, len(evt.Data.Conversations))
+	p.logger.InfoContext(ctx, "History sync received",
+		"sync_type", evt.Data.GetSyncType().String(),
+		"conversations", len(evt.Data.Conversations))
 
 	if p.integrationCtx == nil {
-		log.Printf("⚠️  Integration context not set, skipping history sync")
+		p.logger.WarnContext(ctx, "Integration context not set, skipping history sync")
 		return nil
 	}
 
+	// Report LID mappings first so the conversations and messages below are
+	// stored under phone-number JIDs
+	mappings := make([]*proto.IdentityMapping, 0, len(evt.Data.GetPhoneNumberToLidMappings()))
+	for _, waMapping := range evt.Data.GetPhoneNumberToLidMappings() {
+		lid, lidErr := types.ParseJID(waMapping.GetLidJID())
+		pn, pnErr := types.ParseJID(waMapping.GetPnJID())
+		if lidErr != nil || pnErr != nil {
+			p.logger.WarnContext(ctx, "Skipping malformed LID mapping",
+				"lid_jid", waMapping.GetLidJID(),
+				"pn_jid", waMapping.GetPnJID())
+			continue
+		}
+		if mapping := identityMapping(lid, pn); mapping != nil {
+			mappings = append(mappings, mapping)
+		}
+	}
+	if err := p.reportIdentityMappings(ctx, mappings); err != nil {
+		return err
+	}
+
 	// Process conversations
 	if len(evt.Data.Conversations) > 0 {
 		conversations := make([]*proto.Conversation, 0, len(evt.Data.Conversations))
@@ -194,81 +367,332 @@ func (p *EventsProcessor) handleHistorySync(ctx context.Context, evt *events.His
 		}
 
 		if len(conversations) > 0 {
-			err := p.integrationClient.SyncConversations(ctx, p.integrationCtx, conversations, evt.Data.SyncType.String())
+			sortConversations(conversations)
+			if p.avatars != nil {
+				p.avatars.ApplyConversations(p.integrationCtx, conversations)
+			}
+			syncCtx, cancel := p.syncContext(ctx)
+			err := p.integrationClient.SyncConversations(syncCtx, p.integrationCtx, conversations, evt.Data.SyncType.String())
+			cancel()
 			if err != nil {
 				return fmt.Errorf("failed to sync %d conversations: %w", len(conversations), err)
 			}
-			log.Printf("✅ Synced %d conversations from history", len(conversations))
+			p.logger.InfoContext(ctx, "Synced conversations from history", "count", len(conversations))
 		}
 	}
 
-	// Process messages from conversations
-	var totalMessages int
+	// Process messages from conversations. A resync resends the account's
+	// whole history, so messages the backend already has are skipped rather
+	// than sent again.
+	var totalMessages, skippedMessages int
 	messagesByConversation := make(map[string][]*proto.Message)
+	resyncing := p.resync.Pending()
 
 	for _, waConv := range evt.Data.Conversations {
 		for _, waMsg := range waConv.Messages {
 			protoMsg := p.convertHistorySyncMessage(waMsg)
 			if protoMsg != nil {
 				conversationID := protoMsg.ConversationId
+				if resyncing && p.recent.Contains(conversationID, protoMsg.PlatformId) {
+					skippedMessages++
+					continue
+				}
 				messagesByConversation[conversationID] = append(messagesByConversation[conversationID], protoMsg)
 				totalMessages++
 			}
 		}
 	}
+	if skippedMessages > 0 {
+		p.logger.InfoContext(ctx, "Skipped history messages already synced", "count", skippedMessages)
+	}
 
-	// Sync messages for each conversation
+	// Sync messages for each conversation, in a fixed order so that replaying
+	// the same history sync assigns the same seqs
+	onDemand := evt.Data.GetSyncType() == waHistorySync.HistorySync_ON_DEMAND
 	if totalMessages > 0 {
-		log.Printf("🔄 Processing %d messages from %d conversations", totalMessages, len(messagesByConversation))
-		for conversationID, messages := range messagesByConversation {
-			err := p.integrationClient.SyncMessages(ctx, p.integrationCtx, conversationID, messages)
+		p.logger.InfoContext(ctx, "Syncing messages from history",
+			"messages", totalMessages,
+			"conversations", len(messagesByConversation))
+		for _, conversationID := range sortMessagesByConversation(messagesByConversation) {
+			messages := messagesByConversation[conversationID]
+			syncCtx, cancel := p.syncContext(ctx)
+			err := p.integrationClient.SyncMessages(syncCtx, p.integrationCtx, conversationID, messages)
+			cancel()
 			if err != nil {
 				return fmt.Errorf("failed to sync %d messages for conversation %s: %w", len(messages), conversationID, err)
 			}
-			log.Printf("✅ Synced %d messages for conversation %s from history", len(messages), conversationID)
+			for _, msg := range messages {
+				p.recent.Add(conversationID, msg.PlatformId)
+			}
+			p.history.Observe(conversationID, historyMessages(messages), onDemand)
+			p.logger.DebugContext(ctx, "Synced messages from history",
+				"conversation_id", conversationID,
+				"count", len(messages))
 		}
 	}
+
+	// An on-demand answer without messages for a conversation means there is
+	// no older history to load
+	if onDemand {
+		for _, waConv := range evt.Data.Conversations {
+			if _, ok := messagesByConversation[waConv.GetID()]; !ok {
+				p.history.Observe(waConv.GetID(), nil, true)
+			}
+		}
+	}
+	return nil
+}
+
+// sortConversations orders conversations by last activity, oldest first, then by ID
+func sortConversations(conversations []*proto.Conversation) {
+	slices.SortStableFunc(conversations, func(a, b *proto.Conversation) int {
+		if c := a.GetLastActivityAt().AsTime().Compare(b.GetLastActivityAt().AsTime()); c != 0 {
+			return c
+		}
+		return strings.Compare(a.PlatformId, b.PlatformId)
+	})
+}
+
+// sortMessagesByConversation sorts each conversation's messages by timestamp,
+// oldest first, and returns the conversation IDs ordered by their oldest message
+func sortMessagesByConversation(messagesByConversation map[string][]*proto.Message) []string {
+	conversationIDs := make([]string, 0, len(messagesByConversation))
+	for conversationID, messages := range messagesByConversation {
+		slices.SortStableFunc(messages, func(a, b *proto.Message) int {
+			if c := a.GetTimestamp().AsTime().Compare(b.GetTimestamp().AsTime()); c != 0 {
+				return c
+			}
+			return strings.Compare(a.PlatformId, b.PlatformId)
+		})
+		conversationIDs = append(conversationIDs, conversationID)
+	}
+
+	slices.SortFunc(conversationIDs, func(a, b string) int {
+		oldestA := messagesByConversation[a][0].GetTimestamp().AsTime()
+		oldestB := messagesByConversation[b][0].GetTimestamp().AsTime()
+		if c := oldestA.Compare(oldestB); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	return conversationIDs
+}
+
+// SyncGroups upserts the given groups as conversations. Conversations that
+// already have synced activity keep their stored state.
+func (p *EventsProcessor) SyncGroups(ctx context.Context, groups []*types.GroupInfo) error {
+	if p.integrationCtx == nil {
+		return fmt.Errorf("integration context not set, cannot sync groups")
+	}
+
+	conversations := make([]*proto.Conversation, 0, len(groups))
+	for _, group := range groups {
+		if conv := p.convertGroupInfo(group); conv != nil {
+			conversations = append(conversations, conv)
+		}
+	}
+	if len(conversations) == 0 {
+		return nil
+	}
+	if p.avatars != nil {
+		p.avatars.ApplyConversations(p.integrationCtx, conversations)
+	}
+
+	ctx, cancel := p.syncContext(ctx)
+	defer cancel()
+	if err := p.integrationClient.SyncConversations(ctx, p.integrationCtx, conversations, "GROUP_LIST"); err != nil {
+		return fmt.Errorf("failed to sync %d groups: %w", len(conversations), err)
+	}
+	p.logger.InfoContext(ctx, "Synced joined groups", "count", len(conversations))
 	return nil
 }
 
 func (p *EventsProcessor) handleMessage(ctx context.Context, evt *events.Message) error {
-	log.Printf("📨 New Message: ID=%s, from=%s, chat=%s",
-		evt.Info.ID, evt.Info.Sender.String(), evt.Info.Chat.String())
+	p.logger.DebugContext(ctx, "Message received",
+		"message_id", evt.Info.ID,
+		"sender", evt.Info.Sender.String(),
+		"chat", evt.Info.Chat.String())
 
 	if p.integrationCtx == nil {
-		log.Printf("⚠️  Integration context not set, skipping message")
+		p.logger.WarnContext(ctx, "Integration context not set, skipping message")
 		return nil
 	}
 
+	if evt.Message.GetPollUpdateMessage() != nil {
+		return p.handlePollVote(ctx, evt)
+	}
+
+	// Messages from a LID sender carry the phone-number JID as the alternate
+	if mapping := identityMapping(evt.Info.Sender, evt.Info.SenderAlt); mapping != nil {
+		if err := p.reportIdentityMappings(ctx, []*proto.IdentityMapping{mapping}); err != nil {
+			return err
+		}
+	}
+
 	protoMsg := p.convertMessage(evt)
 	if protoMsg == nil {
-		log.Printf("⚠️  Failed to convert message")
+		p.logger.WarnContext(ctx, "Failed to convert message", "message_id", evt.Info.ID)
+		return nil
+	}
+	if p.recent.Contains(protoMsg.ConversationId, protoMsg.PlatformId) {
+		p.logger.DebugContext(ctx, "Message already synced, skipping", "message_id", protoMsg.PlatformId)
 		return nil
 	}
 
-	err := p.integrationClient.ProcessMessage(ctx, p.integrationCtx, protoMsg)
+	callCtx, cancel := p.callContext(ctx)
+	defer cancel()
+	err := p.integrationClient.ProcessMessage(callCtx, p.integrationCtx, protoMsg)
 	if err != nil {
 		return fmt.Errorf("failed to process real-time message: %w", err)
 	}
+	p.recent.Add(protoMsg.ConversationId, protoMsg.PlatformId)
+	p.history.Observe(protoMsg.ConversationId, historyMessages([]*proto.Message{protoMsg}), false)
+	p.downloadMedia(protoMsg.PlatformId, evt.Message)
+	return nil
+}
+
+// downloadMedia queues a message's attachment to be stored in the backend.
+// Attachments of history sync messages stay pending; their download links
+// have often expired by the time history is synced.
+func (p *EventsProcessor) downloadMedia(messageID string, waMsg *waE2E.Message) {
+	if p.media == nil {
+		return
+	}
+	media, downloadable := messageMedia(waMsg)
+	if media == nil || media.ContentHash == "" {
+		return
+	}
+	p.media.Enqueue(mediaDownload{
+		integrationCtx: p.integrationCtx,
+		messageID:      messageID,
+		contentHash:    media.ContentHash,
+		mimeType:       media.MimeType,
+		media:          downloadable,
+	})
+}
+
+// handlePollVote decrypts a poll vote and forwards the voter's selection to the backend
+func (p *EventsProcessor) handlePollVote(ctx context.Context, evt *events.Message) error {
+	if p.client == nil {
+		p.logger.WarnContext(ctx, "WhatsApp client not set, skipping poll vote")
+		return nil
+	}
+
+	vote, err := p.client.DecryptPollVote(ctx, evt)
+	if err != nil {
+		// Votes on polls created before this device was linked can't be decrypted
+		p.logger.WarnContext(ctx, "Failed to decrypt poll vote", "message_id", evt.Info.ID, "error", err)
+		return nil
+	}
+
+	selected := vote.GetSelectedOptions()
+	hashes := make([]string, len(selected))
+	for i, hash := range selected {
+		hashes[i] = hex.EncodeToString(hash)
+	}
+
+	pollKey := evt.Message.GetPollUpdateMessage().GetPollCreationMessageKey()
+	p.logger.DebugContext(ctx, "Poll vote received",
+		"poll_id", pollKey.GetID(),
+		"voter", evt.Info.Sender.String(),
+		"options", len(hashes))
+
+	callCtx, cancel := p.callContext(ctx)
+	defer cancel()
+	err = p.integrationClient.ProcessPollVote(callCtx, p.integrationCtx, &proto.PollVote{
+		PollMessageId:        pollKey.GetID(),
+		ConversationId:       evt.Info.Chat.String(),
+		VoterId:              evt.Info.Sender.ToNonAD().String(),
+		SelectedOptionHashes: hashes,
+		Timestamp:            timestamppb.New(evt.Info.Timestamp),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to process poll vote: %w", err)
+	}
 	return nil
 }
 
 func (p *EventsProcessor) handleReceipt(ctx context.Context, evt *events.Receipt) error {
-	log.Printf("✅ Message Receipt: type=%s, messages=%v, sender=%s",
-		evt.Type, evt.MessageIDs, evt.SourceString())
+	p.logger.DebugContext(ctx, "Message receipt",
+		"type", string(evt.Type),
+		"message_ids", evt.MessageIDs,
+		"source", evt.SourceString())
+
+	// Receipts from the account's own devices are for messages it received
+	status := receiptStatus(evt.Type)
+	if status == proto.MessageStatus_MESSAGE_STATUS_UNSPECIFIED || evt.IsFromMe {
+		return nil
+	}
+	if p.integrationCtx == nil {
+		p.logger.WarnContext(ctx, "Integration context not set, skipping receipt")
+		return nil
+	}
+
+	for _, messageID := range evt.MessageIDs {
+		if clientMsgUUID, ok := p.sent.Advance(messageID, status); ok {
+			if err := p.updateOutboxDelivery(ctx, clientMsgUUID, messageID, evt.Chat.String(), status, evt.Timestamp); err != nil {
+				return err
+			}
+			continue
+		}
+		if p.sent.Tracks(messageID) {
+			// An outbox message that already has this status or a later one
+			continue
+		}
+
+		// Sent from the phone or another linked device; the backend ignores
+		// receipts that don't move the message to a later status
+		if err := p.updateMessageDelivery(ctx, messageID, evt.Chat.String(), status, evt.Timestamp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateOutboxDelivery reports a receipt for a message sent from the outbox
+func (p *EventsProcessor) updateOutboxDelivery(ctx context.Context, clientMsgUUID, messageID, conversationID string, status proto.MessageStatus, at time.Time) error {
+	ctx, cancel := p.callContext(ctx)
+	defer cancel()
+	if err := p.integrationClient.UpdateOutboxDelivery(ctx, p.integrationCtx, clientMsgUUID, messageID, conversationID, status, at); err != nil {
+		return fmt.Errorf("failed to update outbox delivery: %w", err)
+	}
+	return nil
+}
+
+// updateMessageDelivery reports a receipt for a message the account sent from
+// another device
+func (p *EventsProcessor) updateMessageDelivery(ctx context.Context, messageID, conversationID string, status proto.MessageStatus, at time.Time) error {
+	ctx, cancel := p.callContext(ctx)
+	defer cancel()
+	if err := p.integrationClient.UpdateMessageDelivery(ctx, p.integrationCtx, messageID, conversationID, status, at); err != nil {
+		return fmt.Errorf("failed to update message delivery: %w", err)
+	}
 	return nil
 }
 
+// receiptStatus maps a receipt type to the delivery status it reports, or
+// MESSAGE_STATUS_UNSPECIFIED for receipts that don't report one
+func receiptStatus(receiptType types.ReceiptType) proto.MessageStatus {
+	switch receiptType {
+	case types.ReceiptTypeDelivered:
+		return proto.MessageStatus_MESSAGE_STATUS_DELIVERED
+	case types.ReceiptTypeRead, types.ReceiptTypePlayed:
+		return proto.MessageStatus_MESSAGE_STATUS_READ
+	default:
+		return proto.MessageStatus_MESSAGE_STATUS_UNSPECIFIED
+	}
+}
+
 func (p *EventsProcessor) handleAppStateSyncComplete(ctx context.Context, evt *events.AppStateSyncComplete) error {
-	log.Printf("🔄 App State Sync Complete: %s", evt.Name)
+	p.logger.DebugContext(ctx, "App state sync complete", "name", string(evt.Name))
 	return nil
 }
 
 func (p *EventsProcessor) handleContact(ctx context.Context, evt *events.Contact) error {
-	log.Printf("👤 Contact Update: JID=%s", evt.JID.String())
+	p.logger.DebugContext(ctx, "Contact updated", "jid", evt.JID.String())
 
 	if p.integrationCtx == nil {
-		log.Printf("⚠️  Integration context not set, skipping contact")
+		p.logger.WarnContext(ctx, "Integration context not set, skipping contact")
 		return nil
 	}
 
@@ -277,37 +701,198 @@ func (p *EventsProcessor) handleContact(ctx context.Context, evt *events.Contact
 		return nil
 	}
 
+	if lid, err := types.ParseJID(evt.Action.GetLidJID()); err == nil {
+		if mapping := identityMapping(lid, evt.JID); mapping != nil {
+			if err := p.reportIdentityMappings(ctx, []*proto.IdentityMapping{mapping}); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Send single contact as a batch
 	contacts := []*proto.Contact{protoContact}
-	err := p.integrationClient.SyncContacts(ctx, p.integrationCtx, contacts)
+	if p.avatars != nil {
+		p.avatars.ApplyContacts(p.integrationCtx, contacts)
+	}
+	syncCtx, cancel := p.syncContext(ctx)
+	defer cancel()
+	err := p.integrationClient.SyncContacts(syncCtx, p.integrationCtx, contacts)
 	if err != nil {
 		return fmt.Errorf("failed to sync contact update: %w", err)
 	}
 	return nil
 }
 
+// handlePicture fetches a contact's or group's new profile picture, or
+// removes the one it had
+func (p *EventsProcessor) handlePicture(ctx context.Context, evt *events.Picture) error {
+	p.logger.DebugContext(ctx, "Profile picture changed", "jid", evt.JID.String(), "removed", evt.Remove)
+
+	if p.avatars == nil || p.integrationCtx == nil {
+		return nil
+	}
+	p.avatars.PictureChanged(ctx, evt)
+	return nil
+}
+
 func (p *EventsProcessor) handlePushName(ctx context.Context, evt *events.PushName) error {
-	log.Printf("📛 Push Name Update: JID=%s, name=%s", evt.JID.String(), evt.Message.PushName)
+	p.logger.DebugContext(ctx, "Push name updated", "jid", evt.JID.String(), "push_name", evt.Message.PushName)
 	return nil
 }
 
 func (p *EventsProcessor) handleGroupInfo(ctx context.Context, evt *events.GroupInfo) error {
-	log.Printf("👥 Group Info Update: JID=%s, name=%s", evt.JID.String(), evt.Name.Name)
+	p.logger.DebugContext(ctx, "Group info updated", "jid", evt.JID.String())
+
+	// WhatsApp promotes to admin; the owner's role never changes this way
+	var participants []*proto.ConversationParticipant
+	for _, jid := range evt.Promote {
+		participants = append(participants, &proto.ConversationParticipant{ExternalUserId: jid.ToNonAD().String(), Role: "admin"})
+	}
+	for _, jid := range evt.Demote {
+		participants = append(participants, &proto.ConversationParticipant{ExternalUserId: jid.ToNonAD().String(), Role: "member"})
+	}
+	if len(participants) == 0 {
+		return nil
+	}
+
+	if p.integrationCtx == nil {
+		p.logger.WarnContext(ctx, "Integration context not set, skipping participant roles")
+		return nil
+	}
+
+	var changedBy string
+	if evt.Sender != nil {
+		changedBy = evt.Sender.ToNonAD().String()
+	}
+
+	ctx, cancel := p.callContext(ctx)
+	defer cancel()
+	if err := p.integrationClient.UpdateParticipantRoles(ctx, p.integrationCtx, evt.JID.String(), participants, changedBy, evt.Timestamp); err != nil {
+		return fmt.Errorf("failed to update participant roles: %w", err)
+	}
 	return nil
 }
 
 func (p *EventsProcessor) handleJoinedGroup(ctx context.Context, evt *events.JoinedGroup) error {
-	log.Printf("🎉 Joined Group: JID=%s, reason=%s", evt.JID.String(), evt.Reason)
+	p.logger.DebugContext(ctx, "Joined group", "jid", evt.JID.String(), "reason", evt.Reason)
 	return nil
 }
 
 func (p *EventsProcessor) handlePresence(ctx context.Context, evt *events.Presence) error {
-	log.Printf("👁️  Presence Update: from=%s, last_seen=%v", evt.From.String(), evt.LastSeen)
-	return nil
+	if !p.presenceSubscribed(evt.From) {
+		return nil
+	}
+	p.logger.DebugContext(ctx, "Presence updated", "from", evt.From.String(), "last_seen", evt.LastSeen)
+
+	presence := &proto.Presence{
+		ExternalUserId: evt.From.ToNonAD().String(),
+		IsOnline:       !evt.Unavailable,
+	}
+	if !evt.LastSeen.IsZero() {
+		presence.LastSeen = timestamppb.New(evt.LastSeen)
+	}
+	return p.updatePresence(ctx, presence)
 }
 
 func (p *EventsProcessor) handleChatPresence(ctx context.Context, evt *events.ChatPresence) error {
-	log.Printf("👥 Chat Presence Update: chat=%s, participants=%v", evt.Chat.String(), evt.State)
+	if !p.presenceSubscribed(evt.Sender) {
+		return nil
+	}
+	p.logger.DebugContext(ctx, "Chat presence updated", "chat", evt.Chat.String(), "state", string(evt.State))
+
+	composing := evt.State == types.ChatPresenceComposing
+	recording := composing && evt.Media == types.ChatPresenceMediaAudio
+	return p.updatePresence(ctx, &proto.Presence{
+		ExternalUserId:         evt.Sender.ToNonAD().String(),
+		IsOnline:               true, // Typing implies online
+		ConversationExternalId: evt.Chat.String(),
+		IsTyping:               composing && !recording,
+		IsRecording:            recording,
+	})
+}
+
+// presenceSubscribed reports whether presence from jid is forwarded. Presence
+// may come from a contact's LID when the subscription used their phone number.
+func (p *EventsProcessor) presenceSubscribed(jid types.JID) bool {
+	jid = jid.ToNonAD()
+	if p.presence.Has(jid.String()) {
+		return true
+	}
+
+	p.mu.Lock()
+	pnJID, ok := p.reportedLIDs[jid.String()]
+	p.mu.Unlock()
+	return ok && p.presence.Has(pnJID)
+}
+
+func (p *EventsProcessor) updatePresence(ctx context.Context, presence *proto.Presence) error {
+	if p.integrationCtx == nil {
+		p.logger.WarnContext(ctx, "Integration context not set, skipping presence")
+		return nil
+	}
+
+	ctx, cancel := p.callContext(ctx)
+	defer cancel()
+	if err := p.integrationClient.UpdatePresence(ctx, p.integrationCtx, presence); err != nil {
+		return fmt.Errorf("failed to update presence: %w", err)
+	}
+	return nil
+}
+
+// resubscribePresence renews the account's presence subscriptions after a reconnect
+func (p *EventsProcessor) resubscribePresence(ctx context.Context) {
+	if p.client == nil || !p.config.Categories[EventCategoryPresence] {
+		return
+	}
+	for _, jid := range p.presence.List() {
+		parsed, err := types.ParseJID(jid)
+		if err == nil {
+			err = p.client.SubscribePresence(parsed)
+		}
+		if err != nil {
+			p.logger.WarnContext(ctx, "Failed to renew presence subscription", "jid", jid, "error", err)
+		}
+	}
+}
+
+// Pin, archive and mute changes arrive one setting at a time, both for
+// changes made on the phone and as the echo of changes the backend made
+// through the control service. Each carries the mutation's timestamp, which
+// lets the backend ignore an echo that a newer change has overtaken.
+
+func (p *EventsProcessor) handlePin(ctx context.Context, evt *events.Pin) error {
+	state := &proto.ConversationState{IsPinned: evt.Action.GetPinned()}
+	return p.updateConversationState(ctx, evt.JID, evt.Timestamp, state, tennexevents.ConversationFieldPinned)
+}
+
+func (p *EventsProcessor) handleArchive(ctx context.Context, evt *events.Archive) error {
+	state := &proto.ConversationState{IsArchived: evt.Action.GetArchived()}
+	return p.updateConversationState(ctx, evt.JID, evt.Timestamp, state, tennexevents.ConversationFieldArchived)
+}
+
+func (p *EventsProcessor) handleMute(ctx context.Context, evt *events.Mute) error {
+	state := &proto.ConversationState{IsMuted: evt.Action.GetMuted()}
+	// A mute with no expiry has no positive end timestamp
+	if end := evt.Action.GetMuteEndTimestamp(); state.IsMuted && end > 0 {
+		state.MuteUntil = timestamppb.New(time.UnixMilli(end))
+	}
+	return p.updateConversationState(ctx, evt.JID, evt.Timestamp, state, tennexevents.ConversationFieldMuted)
+}
+
+func (p *EventsProcessor) updateConversationState(ctx context.Context, chat types.JID, changedAt time.Time, state *proto.ConversationState, field string) error {
+	p.logger.DebugContext(ctx, "Conversation state changed", "jid", chat.String(), "field", field)
+
+	if p.integrationCtx == nil {
+		p.logger.WarnContext(ctx, "Integration context not set, skipping conversation state")
+		return nil
+	}
+
+	ctx, cancel := p.callContext(ctx)
+	defer cancel()
+	err := p.integrationClient.UpdateConversationState(ctx, p.integrationCtx, chat.String(), state, []string{field}, changedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update conversation state: %w", err)
+	}
 	return nil
 }
 
@@ -333,20 +918,20 @@ func (p *EventsProcessor) convertHistorySyncConversation(waConv *waHistorySync.C
 		conv.MuteUntil = timestamppb.New(time.Unix(int64(*waConv.MuteEndTime), 0))
 	}
 
-	// Set conversation type based on participant count
-	if len(waConv.Participant) > 0 {
-		conv.Type = proto.ConversationType_CONVERSATION_TYPE_GROUP
+	conv.Type = conversationType(conv.PlatformId, len(waConv.Participant) > 0)
+	for _, participant := range waConv.Participant {
+		rank := participant.GetRank()
+		conv.Participants = append(conv.Participants, &proto.ConversationParticipant{
+			ExternalUserId: getStringPtr(participant.UserJID),
+			DisplayName:    "", // DisplayName not available in GroupParticipant
+			Role:           participantRole(rank == waHistorySync.GroupParticipant_ADMIN, rank == waHistorySync.GroupParticipant_SUPERADMIN),
+			IsActive:       true, // IsDeleted not available, assume active
+		})
+	}
 
-		// Add participants
-		for _, participant := range waConv.Participant {
-			conv.Participants = append(conv.Participants, &proto.ConversationParticipant{
-				ExternalUserId: getStringPtr(participant.UserJID),
-				DisplayName:    "",   // DisplayName not available in GroupParticipant
-				IsActive:       true, // IsDeleted not available, assume active
-			})
-		}
-	} else {
-		conv.Type = proto.ConversationType_CONVERSATION_TYPE_INDIVIDUAL
+	// Status updates aren't messages the user is expected to read through
+	if conv.PlatformId == types.StatusBroadcastJID.String() {
+		conv.UnreadCount = 0
 	}
 
 	// Convert timestamps
@@ -363,6 +948,76 @@ func (p *EventsProcessor) convertHistorySyncConversation(waConv *waHistorySync.C
 	if waConv.DisappearingMode != nil {
 		conv.PlatformMetadata["disappearing_mode"] = fmt.Sprintf("%v", waConv.DisappearingMode)
 	}
+	if expiration := waConv.GetEphemeralExpiration(); expiration > 0 {
+		conv.PlatformMetadata["disappearing_seconds"] = strconv.FormatUint(uint64(expiration), 10)
+	}
+
+	return conv
+}
+
+// conversationType tells a conversation's type from its JID's server. JIDs
+// that don't say are groups when they have participants.
+func conversationType(jid string, hasParticipants bool) proto.ConversationType {
+	if parsed, err := types.ParseJID(jid); err == nil {
+		switch parsed.Server {
+		case types.GroupServer:
+			return proto.ConversationType_CONVERSATION_TYPE_GROUP
+		case types.BroadcastServer:
+			// Broadcast lists and status@broadcast
+			return proto.ConversationType_CONVERSATION_TYPE_BROADCAST
+		case types.NewsletterServer:
+			return proto.ConversationType_CONVERSATION_TYPE_CHANNEL
+		}
+	}
+	if hasParticipants {
+		return proto.ConversationType_CONVERSATION_TYPE_GROUP
+	}
+	return proto.ConversationType_CONVERSATION_TYPE_INDIVIDUAL
+}
+
+// participantRole names a group participant's role. WhatsApp's super admin
+// is the group's owner.
+func participantRole(isAdmin, isSuperAdmin bool) string {
+	switch {
+	case isSuperAdmin:
+		return "owner"
+	case isAdmin:
+		return "admin"
+	default:
+		return "member"
+	}
+}
+
+func (p *EventsProcessor) convertGroupInfo(info *types.GroupInfo) *proto.Conversation {
+	if info == nil {
+		return nil
+	}
+
+	conv := &proto.Conversation{
+		PlatformId:       info.JID.String(),
+		Name:             info.Name,
+		Type:             proto.ConversationType_CONVERSATION_TYPE_GROUP,
+		Description:      info.Topic,
+		IsReadOnly:       info.IsAnnounce,
+		IsLocked:         info.IsLocked,
+		PlatformMetadata: make(map[string]string),
+	}
+
+	for _, participant := range info.Participants {
+		conv.Participants = append(conv.Participants, &proto.ConversationParticipant{
+			ExternalUserId: participant.JID.String(),
+			DisplayName:    participant.DisplayName,
+			Role:           participantRole(participant.IsAdmin, participant.IsSuperAdmin),
+			IsActive:       true,
+		})
+	}
+
+	if !info.OwnerJID.IsEmpty() {
+		conv.PlatformMetadata["owner_jid"] = info.OwnerJID.String()
+	}
+	if !info.GroupCreated.IsZero() {
+		conv.PlatformMetadata["created_at"] = strconv.FormatInt(info.GroupCreated.Unix(), 10)
+	}
 
 	return conv
 }
@@ -373,12 +1028,13 @@ func (p *EventsProcessor) convertHistorySyncMessage(waMsg *waHistorySync.History
 	}
 
 	webMsg := waMsg.Message
+	key := webMsg.GetKey() // nil-safe: a message without a key converts with empty IDs
 	msg := &proto.Message{
-		PlatformId:       getStringPtr(webMsg.Key.ID),
-		ConversationId:   getStringPtr(webMsg.Key.RemoteJID),
-		SenderId:         getStringPtr(webMsg.Key.Participant),
+		PlatformId:       key.GetID(),
+		ConversationId:   key.GetRemoteJID(),
+		SenderId:         key.GetParticipant(),
 		Timestamp:        timestamppb.New(time.Unix(int64(getUint64Ptr(webMsg.MessageTimestamp)), 0)),
-		IsFromMe:         getBoolPtr(webMsg.Key.FromMe),
+		IsFromMe:         key.GetFromMe(),
 		PlatformMetadata: make(map[string]string),
 	}
 
@@ -392,6 +1048,9 @@ func (p *EventsProcessor) convertHistorySyncMessage(waMsg *waHistorySync.History
 		if webMsg.Message.Conversation != nil {
 			msg.MessageType = proto.MessageType_MESSAGE_TYPE_TEXT
 			msg.Content = getStringPtr(webMsg.Message.Conversation)
+		} else if webMsg.Message.ExtendedTextMessage != nil {
+			msg.MessageType = proto.MessageType_MESSAGE_TYPE_TEXT
+			msg.Content = getStringPtr(webMsg.Message.ExtendedTextMessage.Text)
 		} else if webMsg.Message.ImageMessage != nil {
 			msg.MessageType = proto.MessageType_MESSAGE_TYPE_IMAGE
 			msg.Content = getStringPtr(webMsg.Message.ImageMessage.Caption)
@@ -403,10 +1062,15 @@ func (p *EventsProcessor) convertHistorySyncMessage(waMsg *waHistorySync.History
 		} else if webMsg.Message.DocumentMessage != nil {
 			msg.MessageType = proto.MessageType_MESSAGE_TYPE_DOCUMENT
 			msg.Content = getStringPtr(webMsg.Message.DocumentMessage.Title)
-		} else {
+		} else if !convertStructuredMessage(webMsg.Message, msg) {
 			msg.MessageType = proto.MessageType_MESSAGE_TYPE_TEXT
 			msg.Content = "[Unsupported message type]"
 		}
+		if media, _ := messageMedia(webMsg.Message); media != nil {
+			msg.Media = []*proto.MessageMedia{media}
+		}
+
+		applyContextInfo(msg, messageContextInfo(webMsg.Message))
 	} else {
 		msg.MessageType = proto.MessageType_MESSAGE_TYPE_TEXT
 		msg.Content = "[Empty message]"
@@ -438,6 +1102,9 @@ func (p *EventsProcessor) convertMessage(evt *events.Message) *proto.Message {
 	if evt.Message.GetConversation() != "" {
 		msg.MessageType = proto.MessageType_MESSAGE_TYPE_TEXT
 		msg.Content = evt.Message.GetConversation()
+	} else if evt.Message.GetExtendedTextMessage() != nil {
+		msg.MessageType = proto.MessageType_MESSAGE_TYPE_TEXT
+		msg.Content = evt.Message.GetExtendedTextMessage().GetText()
 	} else if evt.Message.GetImageMessage() != nil {
 		msg.MessageType = proto.MessageType_MESSAGE_TYPE_IMAGE
 		if evt.Message.GetImageMessage().GetCaption() != "" {
@@ -455,10 +1122,15 @@ func (p *EventsProcessor) convertMessage(evt *events.Message) *proto.Message {
 		if evt.Message.GetDocumentMessage().GetTitle() != "" {
 			msg.Content = evt.Message.GetDocumentMessage().GetTitle()
 		}
-	} else {
+	} else if !convertStructuredMessage(evt.Message, msg) {
 		msg.MessageType = proto.MessageType_MESSAGE_TYPE_TEXT
 		msg.Content = "[Unsupported message type]"
 	}
+	if media, _ := messageMedia(evt.Message); media != nil {
+		msg.Media = []*proto.MessageMedia{media}
+	}
+
+	applyContextInfo(msg, messageContextInfo(evt.Message))
 
 	// Add platform metadata
 	msg.PlatformMetadata["server_id"] = strconv.Itoa(int(evt.Info.ServerID))
@@ -467,6 +1139,198 @@ func (p *EventsProcessor) convertMessage(evt *events.Message) *proto.Message {
 	return msg
 }
 
+// convertStructuredMessage fills in location, contact card, poll and sticker
+// messages. The structured data is stored in PlatformMetadata; Content gets a
+// readable summary. Returns false if the message is none of these types.
+func convertStructuredMessage(waMsg *waE2E.Message, msg *proto.Message) bool {
+	if loc := waMsg.GetLocationMessage(); loc != nil {
+		msg.MessageType = proto.MessageType_MESSAGE_TYPE_LOCATION
+		msg.Content = loc.GetName()
+		if msg.Content == "" {
+			msg.Content = loc.GetAddress()
+		}
+		msg.PlatformMetadata["latitude"] = strconv.FormatFloat(loc.GetDegreesLatitude(), 'f', -1, 64)
+		msg.PlatformMetadata["longitude"] = strconv.FormatFloat(loc.GetDegreesLongitude(), 'f', -1, 64)
+		msg.PlatformMetadata["location_name"] = loc.GetName()
+		msg.PlatformMetadata["location_address"] = loc.GetAddress()
+		msg.PlatformMetadata["location_url"] = loc.GetURL()
+		return true
+	}
+
+	if live := waMsg.GetLiveLocationMessage(); live != nil {
+		msg.MessageType = proto.MessageType_MESSAGE_TYPE_LOCATION
+		msg.Content = live.GetCaption()
+		msg.PlatformMetadata["latitude"] = strconv.FormatFloat(live.GetDegreesLatitude(), 'f', -1, 64)
+		msg.PlatformMetadata["longitude"] = strconv.FormatFloat(live.GetDegreesLongitude(), 'f', -1, 64)
+		msg.PlatformMetadata["location_live"] = "true"
+		if accuracy := live.GetAccuracyInMeters(); accuracy > 0 {
+			msg.PlatformMetadata["location_accuracy_meters"] = strconv.FormatUint(uint64(accuracy), 10)
+		}
+		return true
+	}
+
+	if contact := waMsg.GetContactMessage(); contact != nil {
+		msg.MessageType = proto.MessageType_MESSAGE_TYPE_CONTACT
+		msg.Content = contact.GetDisplayName()
+		setJSONMetadata(msg, "vcards", []string{contact.GetVcard()})
+		setJSONMetadata(msg, "contact_names", []string{contact.GetDisplayName()})
+		return true
+	}
+
+	if contacts := waMsg.GetContactsArrayMessage(); contacts != nil {
+		msg.MessageType = proto.MessageType_MESSAGE_TYPE_CONTACT
+		msg.Content = contacts.GetDisplayName()
+		vcards := make([]string, 0, len(contacts.GetContacts()))
+		names := make([]string, 0, len(contacts.GetContacts()))
+		for _, contact := range contacts.GetContacts() {
+			vcards = append(vcards, contact.GetVcard())
+			names = append(names, contact.GetDisplayName())
+		}
+		if msg.Content == "" {
+			msg.Content = strings.Join(names, ", ")
+		}
+		setJSONMetadata(msg, "vcards", vcards)
+		setJSONMetadata(msg, "contact_names", names)
+		return true
+	}
+
+	poll := waMsg.GetPollCreationMessage()
+	if poll == nil {
+		poll = waMsg.GetPollCreationMessageV2()
+	}
+	if poll == nil {
+		poll = waMsg.GetPollCreationMessageV3()
+	}
+	if poll != nil {
+		msg.MessageType = proto.MessageType_MESSAGE_TYPE_POLL
+		msg.Content = poll.GetName()
+		options := make([]string, 0, len(poll.GetOptions()))
+		for _, option := range poll.GetOptions() {
+			options = append(options, option.GetOptionName())
+		}
+		msg.PlatformMetadata["poll_question"] = poll.GetName()
+		msg.PlatformMetadata["poll_selectable_count"] = strconv.FormatUint(uint64(poll.GetSelectableOptionsCount()), 10)
+		setJSONMetadata(msg, "poll_options", options)
+		return true
+	}
+
+	// The sticker image itself is downloaded as the message's media
+	if sticker := waMsg.GetStickerMessage(); sticker != nil {
+		msg.MessageType = proto.MessageType_MESSAGE_TYPE_STICKER
+		msg.PlatformMetadata["sticker_animated"] = strconv.FormatBool(sticker.GetIsAnimated())
+		if label := sticker.GetAccessibilityLabel(); label != "" {
+			msg.Content = label
+		}
+		return true
+	}
+
+	return false
+}
+
+// messageContextInfo returns the context of whichever kind of message waMsg
+// holds: what it quotes and who it mentions. It returns nil for messages
+// without one.
+func messageContextInfo(waMsg *waE2E.Message) *waE2E.ContextInfo {
+	switch {
+	case waMsg.GetExtendedTextMessage() != nil:
+		return waMsg.GetExtendedTextMessage().GetContextInfo()
+	case waMsg.GetImageMessage() != nil:
+		return waMsg.GetImageMessage().GetContextInfo()
+	case waMsg.GetVideoMessage() != nil:
+		return waMsg.GetVideoMessage().GetContextInfo()
+	case waMsg.GetAudioMessage() != nil:
+		return waMsg.GetAudioMessage().GetContextInfo()
+	case waMsg.GetDocumentMessage() != nil:
+		return waMsg.GetDocumentMessage().GetContextInfo()
+	case waMsg.GetStickerMessage() != nil:
+		return waMsg.GetStickerMessage().GetContextInfo()
+	case waMsg.GetLocationMessage() != nil:
+		return waMsg.GetLocationMessage().GetContextInfo()
+	case waMsg.GetLiveLocationMessage() != nil:
+		return waMsg.GetLiveLocationMessage().GetContextInfo()
+	case waMsg.GetContactMessage() != nil:
+		return waMsg.GetContactMessage().GetContextInfo()
+	case waMsg.GetContactsArrayMessage() != nil:
+		return waMsg.GetContactsArrayMessage().GetContextInfo()
+	case waMsg.GetPollCreationMessage() != nil:
+		return waMsg.GetPollCreationMessage().GetContextInfo()
+	case waMsg.GetPollCreationMessageV2() != nil:
+		return waMsg.GetPollCreationMessageV2().GetContextInfo()
+	case waMsg.GetPollCreationMessageV3() != nil:
+		return waMsg.GetPollCreationMessageV3().GetContextInfo()
+	}
+	return nil
+}
+
+// applyContextInfo records the message a reply quotes, whose message it was,
+// and the JIDs mentioned in the message
+func applyContextInfo(msg *proto.Message, contextInfo *waE2E.ContextInfo) {
+	msg.ReplyToExternalId = contextInfo.GetStanzaID()
+	if msg.ReplyToExternalId != "" && contextInfo.GetParticipant() != "" {
+		msg.PlatformMetadata["quoted_participant"] = contextInfo.GetParticipant()
+	}
+	if mentions := contextInfo.GetMentionedJID(); len(mentions) > 0 {
+		setJSONMetadata(msg, "mentioned_jids", mentions)
+	}
+	// Messages sent in a chat with disappearing messages carry its timer
+	if expiration := contextInfo.GetExpiration(); expiration > 0 {
+		msg.PlatformMetadata["expiration_seconds"] = strconv.FormatUint(uint64(expiration), 10)
+	}
+}
+
+// setJSONMetadata stores a JSON-encoded value in the message's platform metadata
+func setJSONMetadata(msg *proto.Message, key string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		slog.Warn("Failed to encode message metadata", "key", key, "error", err)
+		return
+	}
+	msg.PlatformMetadata[key] = string(data)
+}
+
+// identityMapping pairs a LID with the phone-number JID it belongs to, or
+// returns nil unless lid is a LID and pn a phone-number JID
+func identityMapping(lid, pn types.JID) *proto.IdentityMapping {
+	if lid.Server != types.HiddenUserServer || pn.Server != types.DefaultUserServer {
+		return nil
+	}
+	return &proto.IdentityMapping{
+		LidJid: lid.ToNonAD().String(),
+		PnJid:  pn.ToNonAD().String(),
+	}
+}
+
+// reportIdentityMappings sends the mappings the backend hasn't been sent yet
+func (p *EventsProcessor) reportIdentityMappings(ctx context.Context, mappings []*proto.IdentityMapping) error {
+	p.mu.Lock()
+	if p.reportedLIDs == nil {
+		p.reportedLIDs = make(map[string]string)
+	}
+	pending := make([]*proto.IdentityMapping, 0, len(mappings))
+	for _, mapping := range mappings {
+		if p.reportedLIDs[mapping.LidJid] != mapping.PnJid {
+			pending = append(pending, mapping)
+		}
+	}
+	p.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	callCtx, cancel := p.callContext(ctx)
+	defer cancel()
+	if err := p.integrationClient.SyncIdentityMappings(callCtx, p.integrationCtx, pending); err != nil {
+		return fmt.Errorf("failed to sync %d identity mappings: %w", len(pending), err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, mapping := range pending {
+		p.reportedLIDs[mapping.LidJid] = mapping.PnJid
+	}
+	return nil
+}
+
 func (p *EventsProcessor) convertContact(evt *events.Contact) *proto.Contact {
 	if evt == nil || evt.Action == nil {
 		return nil
@@ -0,0 +1,1243 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/proto/waSyncAction"
+	"go.mau.fi/whatsmeow/proto/waWeb"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	protobuf "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/tennex/pkg/requestid"
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+func TestConvertStructuredMessage(t *testing.T) {
+	pollOptions := []*waE2E.PollCreationMessage_Option{
+		{OptionName: protobuf.String("Pizza")},
+		{OptionName: protobuf.String("Sushi")},
+	}
+	poll := &waE2E.PollCreationMessage{
+		Name:                   protobuf.String("Dinner?"),
+		Options:                pollOptions,
+		SelectableOptionsCount: protobuf.Uint32(1),
+	}
+
+	tests := []struct {
+		name         string
+		message      *waE2E.Message
+		wantType     proto.MessageType
+		wantContent  string
+		wantMetadata map[string]string
+		wantJSON     map[string][]string
+	}{
+		{
+			name: "location",
+			message: &waE2E.Message{LocationMessage: &waE2E.LocationMessage{
+				DegreesLatitude:  protobuf.Float64(32.0853),
+				DegreesLongitude: protobuf.Float64(34.7818),
+				Name:             protobuf.String("Office"),
+				Address:          protobuf.String("1 Main St"),
+			}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_LOCATION,
+			wantContent: "Office",
+			wantMetadata: map[string]string{
+				"latitude":         "32.0853",
+				"longitude":        "34.7818",
+				"location_name":    "Office",
+				"location_address": "1 Main St",
+			},
+		},
+		{
+			name: "location without name uses address",
+			message: &waE2E.Message{LocationMessage: &waE2E.LocationMessage{
+				DegreesLatitude:  protobuf.Float64(1.5),
+				DegreesLongitude: protobuf.Float64(-2.25),
+				Address:          protobuf.String("1 Main St"),
+			}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_LOCATION,
+			wantContent: "1 Main St",
+			wantMetadata: map[string]string{
+				"latitude":  "1.5",
+				"longitude": "-2.25",
+			},
+		},
+		{
+			name: "live location",
+			message: &waE2E.Message{LiveLocationMessage: &waE2E.LiveLocationMessage{
+				DegreesLatitude:  protobuf.Float64(32.0853),
+				DegreesLongitude: protobuf.Float64(34.7818),
+				AccuracyInMeters: protobuf.Uint32(20),
+				Caption:          protobuf.String("On my way"),
+			}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_LOCATION,
+			wantContent: "On my way",
+			wantMetadata: map[string]string{
+				"latitude":                 "32.0853",
+				"longitude":                "34.7818",
+				"location_live":            "true",
+				"location_accuracy_meters": "20",
+			},
+		},
+		{
+			name: "contact",
+			message: &waE2E.Message{ContactMessage: &waE2E.ContactMessage{
+				DisplayName: protobuf.String("Alice"),
+				Vcard:       protobuf.String("BEGIN:VCARD\nFN:Alice\nEND:VCARD"),
+			}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_CONTACT,
+			wantContent: "Alice",
+			wantJSON: map[string][]string{
+				"vcards":        {"BEGIN:VCARD\nFN:Alice\nEND:VCARD"},
+				"contact_names": {"Alice"},
+			},
+		},
+		{
+			name: "contacts array",
+			message: &waE2E.Message{ContactsArrayMessage: &waE2E.ContactsArrayMessage{
+				Contacts: []*waE2E.ContactMessage{
+					{DisplayName: protobuf.String("Alice"), Vcard: protobuf.String("VCARD-A")},
+					{DisplayName: protobuf.String("Bob"), Vcard: protobuf.String("VCARD-B")},
+				},
+			}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_CONTACT,
+			wantContent: "Alice, Bob",
+			wantJSON: map[string][]string{
+				"vcards":        {"VCARD-A", "VCARD-B"},
+				"contact_names": {"Alice", "Bob"},
+			},
+		},
+		{
+			name:        "poll",
+			message:     &waE2E.Message{PollCreationMessage: poll},
+			wantType:    proto.MessageType_MESSAGE_TYPE_POLL,
+			wantContent: "Dinner?",
+			wantMetadata: map[string]string{
+				"poll_question":         "Dinner?",
+				"poll_selectable_count": "1",
+			},
+			wantJSON: map[string][]string{
+				"poll_options": {"Pizza", "Sushi"},
+			},
+		},
+		{
+			name:        "poll v2",
+			message:     &waE2E.Message{PollCreationMessageV2: poll},
+			wantType:    proto.MessageType_MESSAGE_TYPE_POLL,
+			wantContent: "Dinner?",
+			wantJSON: map[string][]string{
+				"poll_options": {"Pizza", "Sushi"},
+			},
+		},
+		{
+			name:        "poll v3",
+			message:     &waE2E.Message{PollCreationMessageV3: poll},
+			wantType:    proto.MessageType_MESSAGE_TYPE_POLL,
+			wantContent: "Dinner?",
+			wantJSON: map[string][]string{
+				"poll_options": {"Pizza", "Sushi"},
+			},
+		},
+		{
+			name: "sticker",
+			message: &waE2E.Message{StickerMessage: &waE2E.StickerMessage{
+				IsAnimated:         protobuf.Bool(true),
+				AccessibilityLabel: protobuf.String("Waving cat"),
+			}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_STICKER,
+			wantContent: "Waving cat",
+			wantMetadata: map[string]string{
+				"sticker_animated": "true",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &proto.Message{PlatformMetadata: make(map[string]string)}
+
+			if !convertStructuredMessage(tt.message, msg) {
+				t.Fatal("expected message to be converted")
+			}
+			if msg.MessageType != tt.wantType {
+				t.Errorf("MessageType = %s, want %s", msg.MessageType, tt.wantType)
+			}
+			if msg.Content != tt.wantContent {
+				t.Errorf("Content = %q, want %q", msg.Content, tt.wantContent)
+			}
+			for key, want := range tt.wantMetadata {
+				if got := msg.PlatformMetadata[key]; got != want {
+					t.Errorf("PlatformMetadata[%q] = %q, want %q", key, got, want)
+				}
+			}
+			for key, want := range tt.wantJSON {
+				var got []string
+				if err := json.Unmarshal([]byte(msg.PlatformMetadata[key]), &got); err != nil {
+					t.Fatalf("PlatformMetadata[%q] is not a JSON string array: %v", key, err)
+				}
+				if len(got) != len(want) {
+					t.Fatalf("PlatformMetadata[%q] = %v, want %v", key, got, want)
+				}
+				for i := range want {
+					if got[i] != want[i] {
+						t.Errorf("PlatformMetadata[%q][%d] = %q, want %q", key, i, got[i], want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestConvertStructuredMessageUnsupported(t *testing.T) {
+	msg := &proto.Message{PlatformMetadata: make(map[string]string)}
+
+	if convertStructuredMessage(&waE2E.Message{Conversation: protobuf.String("hi")}, msg) {
+		t.Fatal("expected plain text not to be handled")
+	}
+	if len(msg.PlatformMetadata) != 0 {
+		t.Errorf("expected no metadata, got %v", msg.PlatformMetadata)
+	}
+}
+
+func TestApplyContextInfo(t *testing.T) {
+	quote := &waE2E.ContextInfo{
+		StanzaID:     protobuf.String("PARENT"),
+		Participant:  protobuf.String("972503333333@s.whatsapp.net"),
+		MentionedJID: []string{"972504444444@s.whatsapp.net", "972505555555@s.whatsapp.net"},
+	}
+	poll := &waE2E.PollCreationMessage{Name: protobuf.String("Dinner?"), ContextInfo: quote}
+
+	tests := []struct {
+		name    string
+		message *waE2E.Message
+	}{
+		{"extended text", &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{ContextInfo: quote}}},
+		{"image", &waE2E.Message{ImageMessage: &waE2E.ImageMessage{ContextInfo: quote}}},
+		{"video", &waE2E.Message{VideoMessage: &waE2E.VideoMessage{ContextInfo: quote}}},
+		{"audio", &waE2E.Message{AudioMessage: &waE2E.AudioMessage{ContextInfo: quote}}},
+		{"document", &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{ContextInfo: quote}}},
+		{"sticker", &waE2E.Message{StickerMessage: &waE2E.StickerMessage{ContextInfo: quote}}},
+		{"location", &waE2E.Message{LocationMessage: &waE2E.LocationMessage{ContextInfo: quote}}},
+		{"live location", &waE2E.Message{LiveLocationMessage: &waE2E.LiveLocationMessage{ContextInfo: quote}}},
+		{"contact", &waE2E.Message{ContactMessage: &waE2E.ContactMessage{ContextInfo: quote}}},
+		{"contacts array", &waE2E.Message{ContactsArrayMessage: &waE2E.ContactsArrayMessage{ContextInfo: quote}}},
+		{"poll", &waE2E.Message{PollCreationMessage: poll}},
+		{"poll v3", &waE2E.Message{PollCreationMessageV3: poll}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := (&EventsProcessor{}).convertMessage(testMessageEvent(tt.message))
+
+			if msg.ReplyToExternalId != "PARENT" {
+				t.Errorf("ReplyToExternalId = %q, want PARENT", msg.ReplyToExternalId)
+			}
+			if got := msg.PlatformMetadata["quoted_participant"]; got != "972503333333@s.whatsapp.net" {
+				t.Errorf("quoted_participant = %q", got)
+			}
+			var mentions []string
+			if err := json.Unmarshal([]byte(msg.PlatformMetadata["mentioned_jids"]), &mentions); err != nil || len(mentions) != 2 {
+				t.Errorf("mentioned_jids = %q, want both mentions", msg.PlatformMetadata["mentioned_jids"])
+			}
+		})
+	}
+
+	// A mention without a quote isn't a reply
+	msg := (&EventsProcessor{}).convertMessage(testMessageEvent(&waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+		Text:        protobuf.String("@Dana hi"),
+		ContextInfo: &waE2E.ContextInfo{MentionedJID: []string{testSender.String()}},
+	}}))
+	if msg.ReplyToExternalId != "" || msg.PlatformMetadata["quoted_participant"] != "" {
+		t.Errorf("expected no reply, got %q from %q", msg.ReplyToExternalId, msg.PlatformMetadata["quoted_participant"])
+	}
+	if msg.PlatformMetadata["mentioned_jids"] != `["`+testSender.String()+`"]` {
+		t.Errorf("mentioned_jids = %q", msg.PlatformMetadata["mentioned_jids"])
+	}
+
+	// Messages in a disappearing chat carry the chat's timer
+	msg = (&EventsProcessor{}).convertMessage(testMessageEvent(&waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+		Text:        protobuf.String("gone in a week"),
+		ContextInfo: &waE2E.ContextInfo{Expiration: protobuf.Uint32(604800)},
+	}}))
+	if got := msg.PlatformMetadata["expiration_seconds"]; got != "604800" {
+		t.Errorf("expiration_seconds = %q, want 604800", got)
+	}
+}
+
+func TestConvertGroupInfo(t *testing.T) {
+	info := &types.GroupInfo{
+		JID:           types.NewJID("120363000000000000", types.GroupServer),
+		OwnerJID:      types.NewJID("111", types.DefaultUserServer),
+		GroupName:     types.GroupName{Name: "Family"},
+		GroupTopic:    types.GroupTopic{Topic: "Weekend plans"},
+		GroupAnnounce: types.GroupAnnounce{IsAnnounce: true},
+		GroupCreated:  time.Unix(1700000000, 0),
+		Participants: []types.GroupParticipant{
+			{JID: types.NewJID("111", types.DefaultUserServer), IsAdmin: true, IsSuperAdmin: true},
+			{JID: types.NewJID("222", types.DefaultUserServer), IsAdmin: true},
+			{JID: types.NewJID("333", types.DefaultUserServer), DisplayName: "Sam"},
+		},
+	}
+
+	conv := (&EventsProcessor{}).convertGroupInfo(info)
+
+	if conv.PlatformId != "120363000000000000@g.us" || conv.Name != "Family" || conv.Description != "Weekend plans" {
+		t.Errorf("unexpected conversation identity: %+v", conv)
+	}
+	if conv.Type != proto.ConversationType_CONVERSATION_TYPE_GROUP || !conv.IsReadOnly || conv.IsLocked {
+		t.Errorf("unexpected conversation flags: %+v", conv)
+	}
+	if conv.PlatformMetadata["owner_jid"] != "111@s.whatsapp.net" || conv.PlatformMetadata["created_at"] != "1700000000" {
+		t.Errorf("unexpected metadata: %v", conv.PlatformMetadata)
+	}
+
+	wantRoles := map[string]string{
+		"111@s.whatsapp.net": "owner",
+		"222@s.whatsapp.net": "admin",
+		"333@s.whatsapp.net": "member",
+	}
+	if len(conv.Participants) != len(wantRoles) {
+		t.Fatalf("expected %d participants, got %d", len(wantRoles), len(conv.Participants))
+	}
+	for _, participant := range conv.Participants {
+		if want := wantRoles[participant.ExternalUserId]; participant.Role != want {
+			t.Errorf("participant %s: expected role %q, got %q", participant.ExternalUserId, want, participant.Role)
+		}
+	}
+	if conv.Participants[2].DisplayName != "Sam" {
+		t.Errorf("expected display name Sam, got %q", conv.Participants[2].DisplayName)
+	}
+}
+
+// fakeIntegrationClient records what the events processor sends to the backend
+type fakeIntegrationClient struct {
+	err error // returned from every call
+
+	statuses      []proto.ConnectionStatus
+	conversations []*proto.Conversation
+	contacts      []*proto.Contact
+	synced        map[string][]*proto.Message
+	messages      []*proto.Message
+	pollVotes     []*proto.PollVote
+	mappings      []*proto.IdentityMapping
+	stateUpdates  []stateUpdate
+	presence      []*proto.Presence
+	roleUpdates   []roleUpdate
+	deliveries    []delivery
+	phoneReceipts []delivery
+	calls         []string // order of calls that carry identities
+	requestIDs    []string // request ID of each ProcessMessage call
+}
+
+type roleUpdate struct {
+	conversationID string
+	participants   []*proto.ConversationParticipant
+	changedBy      string
+	changedAt      time.Time
+}
+
+type delivery struct {
+	clientMsgUUID string
+	waMessageID   string
+	status        proto.MessageStatus
+}
+
+type stateUpdate struct {
+	conversationID string
+	state          *proto.ConversationState
+	fields         []string
+	changedAt      time.Time
+}
+
+func (f *fakeIntegrationClient) UpdateConnectionStatus(ctx context.Context, integrationCtx *proto.IntegrationContext, status proto.ConnectionStatus, qrCode string, metadata map[string]string) error {
+	f.statuses = append(f.statuses, status)
+	return f.err
+}
+
+func (f *fakeIntegrationClient) SyncConversations(ctx context.Context, integrationCtx *proto.IntegrationContext, conversations []*proto.Conversation, syncType string) error {
+	f.conversations = append(f.conversations, conversations...)
+	f.calls = append(f.calls, "SyncConversations")
+	return f.err
+}
+
+func (f *fakeIntegrationClient) SyncContacts(ctx context.Context, integrationCtx *proto.IntegrationContext, contacts []*proto.Contact) error {
+	f.contacts = append(f.contacts, contacts...)
+	return f.err
+}
+
+func (f *fakeIntegrationClient) SyncMessages(ctx context.Context, integrationCtx *proto.IntegrationContext, conversationID string, messages []*proto.Message) error {
+	if f.synced == nil {
+		f.synced = make(map[string][]*proto.Message)
+	}
+	f.synced[conversationID] = append(f.synced[conversationID], messages...)
+	return f.err
+}
+
+func (f *fakeIntegrationClient) ProcessMessage(ctx context.Context, integrationCtx *proto.IntegrationContext, message *proto.Message) error {
+	f.messages = append(f.messages, message)
+	f.calls = append(f.calls, "ProcessMessage")
+	f.requestIDs = append(f.requestIDs, requestid.FromContext(ctx))
+	return f.err
+}
+
+func (f *fakeIntegrationClient) ProcessPollVote(ctx context.Context, integrationCtx *proto.IntegrationContext, vote *proto.PollVote) error {
+	f.pollVotes = append(f.pollVotes, vote)
+	return f.err
+}
+
+func (f *fakeIntegrationClient) SyncIdentityMappings(ctx context.Context, integrationCtx *proto.IntegrationContext, mappings []*proto.IdentityMapping) error {
+	f.mappings = append(f.mappings, mappings...)
+	f.calls = append(f.calls, "SyncIdentityMappings")
+	return f.err
+}
+
+func (f *fakeIntegrationClient) UpdateConversationState(ctx context.Context, integrationCtx *proto.IntegrationContext, conversationID string, state *proto.ConversationState, fields []string, changedAt time.Time) error {
+	f.stateUpdates = append(f.stateUpdates, stateUpdate{conversationID: conversationID, state: state, fields: fields, changedAt: changedAt})
+	return f.err
+}
+
+func (f *fakeIntegrationClient) UpdatePresence(ctx context.Context, integrationCtx *proto.IntegrationContext, presence *proto.Presence) error {
+	f.presence = append(f.presence, presence)
+	return f.err
+}
+
+func (f *fakeIntegrationClient) UpdateParticipantRoles(ctx context.Context, integrationCtx *proto.IntegrationContext, conversationID string, participants []*proto.ConversationParticipant, changedBy string, changedAt time.Time) error {
+	f.roleUpdates = append(f.roleUpdates, roleUpdate{conversationID: conversationID, participants: participants, changedBy: changedBy, changedAt: changedAt})
+	return f.err
+}
+
+func (f *fakeIntegrationClient) UpdateOutboxDelivery(ctx context.Context, integrationCtx *proto.IntegrationContext, clientMsgUUID, waMessageID, conversationID string, status proto.MessageStatus, at time.Time) error {
+	f.deliveries = append(f.deliveries, delivery{clientMsgUUID: clientMsgUUID, waMessageID: waMessageID, status: status})
+	return f.err
+}
+
+func (f *fakeIntegrationClient) UpdateMessageDelivery(ctx context.Context, integrationCtx *proto.IntegrationContext, waMessageID, conversationID string, status proto.MessageStatus, at time.Time) error {
+	f.phoneReceipts = append(f.phoneReceipts, delivery{waMessageID: waMessageID, status: status})
+	return f.err
+}
+
+// newTestProcessor returns a processor with its integration context set, sending to fake
+func newTestProcessor(fake *fakeIntegrationClient) *EventsProcessor {
+	p := NewEventsProcessor(fake, "user-1", DefaultEventsConfig(), slog.New(slog.DiscardHandler))
+	p.SetIntegrationContext(7, "972500000000@s.whatsapp.net")
+	return p
+}
+
+var (
+	testChat   = types.NewJID("972501111111", types.DefaultUserServer)
+	testSender = types.NewJID("972502222222", types.DefaultUserServer)
+)
+
+func testMessageEvent(message *waE2E.Message) *events.Message {
+	return &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{Chat: testChat, Sender: testSender},
+			ID:            "MSG1",
+			ServerID:      42,
+			PushName:      "Dana",
+			Timestamp:     time.Unix(1700000000, 0),
+		},
+		Message: message,
+	}
+}
+
+func TestConvertMessage(t *testing.T) {
+	reply := &waE2E.ContextInfo{StanzaID: protobuf.String("PARENT")}
+
+	tests := []struct {
+		name        string
+		message     *waE2E.Message
+		wantType    proto.MessageType
+		wantContent string
+		wantReplyTo string
+	}{
+		{
+			name:        "plain text",
+			message:     &waE2E.Message{Conversation: protobuf.String("hello")},
+			wantType:    proto.MessageType_MESSAGE_TYPE_TEXT,
+			wantContent: "hello",
+		},
+		{
+			name: "extended text reply",
+			message: &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+				Text:        protobuf.String("agreed"),
+				ContextInfo: reply,
+			}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_TEXT,
+			wantContent: "agreed",
+			wantReplyTo: "PARENT",
+		},
+		{
+			name:        "image with caption",
+			message:     &waE2E.Message{ImageMessage: &waE2E.ImageMessage{Caption: protobuf.String("sunset"), ContextInfo: reply}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_IMAGE,
+			wantContent: "sunset",
+			wantReplyTo: "PARENT",
+		},
+		{
+			name:     "image without caption",
+			message:  &waE2E.Message{ImageMessage: &waE2E.ImageMessage{}},
+			wantType: proto.MessageType_MESSAGE_TYPE_IMAGE,
+		},
+		{
+			name:        "video",
+			message:     &waE2E.Message{VideoMessage: &waE2E.VideoMessage{Caption: protobuf.String("clip")}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_VIDEO,
+			wantContent: "clip",
+		},
+		{
+			name:     "audio",
+			message:  &waE2E.Message{AudioMessage: &waE2E.AudioMessage{}},
+			wantType: proto.MessageType_MESSAGE_TYPE_AUDIO,
+		},
+		{
+			name:        "document",
+			message:     &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{Title: protobuf.String("report.pdf")}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_DOCUMENT,
+			wantContent: "report.pdf",
+		},
+		{
+			name:        "location",
+			message:     &waE2E.Message{LocationMessage: &waE2E.LocationMessage{Name: protobuf.String("Office")}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_LOCATION,
+			wantContent: "Office",
+		},
+		{
+			name:     "sticker",
+			message:  &waE2E.Message{StickerMessage: &waE2E.StickerMessage{}},
+			wantType: proto.MessageType_MESSAGE_TYPE_STICKER,
+		},
+		{
+			name:        "unsupported",
+			message:     &waE2E.Message{ReactionMessage: &waE2E.ReactionMessage{Text: protobuf.String("👍")}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_TEXT,
+			wantContent: "[Unsupported message type]",
+		},
+		{
+			name:        "nil message",
+			message:     nil,
+			wantType:    proto.MessageType_MESSAGE_TYPE_TEXT,
+			wantContent: "[Unsupported message type]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := (&EventsProcessor{}).convertMessage(testMessageEvent(tt.message))
+
+			if msg.MessageType != tt.wantType {
+				t.Errorf("MessageType = %s, want %s", msg.MessageType, tt.wantType)
+			}
+			if msg.Content != tt.wantContent {
+				t.Errorf("Content = %q, want %q", msg.Content, tt.wantContent)
+			}
+			if msg.ReplyToExternalId != tt.wantReplyTo {
+				t.Errorf("ReplyToExternalId = %q, want %q", msg.ReplyToExternalId, tt.wantReplyTo)
+			}
+			if msg.PlatformId != "MSG1" || msg.ConversationId != testChat.String() || msg.SenderId != testSender.String() {
+				t.Errorf("unexpected identity: id=%q chat=%q sender=%q", msg.PlatformId, msg.ConversationId, msg.SenderId)
+			}
+			if msg.PlatformMetadata["server_id"] != "42" || msg.PlatformMetadata["push_name"] != "Dana" {
+				t.Errorf("unexpected metadata: %v", msg.PlatformMetadata)
+			}
+		})
+	}
+
+	if (&EventsProcessor{}).convertMessage(nil) != nil {
+		t.Error("expected nil event to convert to nil")
+	}
+}
+
+func TestConvertHistorySyncMessage(t *testing.T) {
+	key := &waCommon.MessageKey{
+		ID:        protobuf.String("HIST1"),
+		RemoteJID: protobuf.String("120363000000000000@g.us"),
+		FromMe:    protobuf.Bool(true),
+	}
+
+	tests := []struct {
+		name        string
+		msg         *waHistorySync.HistorySyncMsg
+		wantNil     bool
+		wantType    proto.MessageType
+		wantContent string
+		wantSender  string
+		wantID      string
+	}{
+		{name: "nil", msg: nil, wantNil: true},
+		{name: "nil web message", msg: &waHistorySync.HistorySyncMsg{}, wantNil: true},
+		{
+			name: "text without participant uses chat as sender",
+			msg: &waHistorySync.HistorySyncMsg{Message: &waWeb.WebMessageInfo{
+				Key:              key,
+				Message:          &waE2E.Message{Conversation: protobuf.String("old news")},
+				MessageTimestamp: protobuf.Uint64(1600000000),
+			}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_TEXT,
+			wantContent: "old news",
+			wantSender:  "120363000000000000@g.us",
+			wantID:      "HIST1",
+		},
+		{
+			name: "participant is the sender",
+			msg: &waHistorySync.HistorySyncMsg{Message: &waWeb.WebMessageInfo{
+				Key: &waCommon.MessageKey{
+					ID:          protobuf.String("HIST2"),
+					RemoteJID:   protobuf.String("120363000000000000@g.us"),
+					Participant: protobuf.String("972502222222@s.whatsapp.net"),
+				},
+				Message: &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{Title: protobuf.String("notes")}},
+			}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_DOCUMENT,
+			wantContent: "notes",
+			wantSender:  "972502222222@s.whatsapp.net",
+			wantID:      "HIST2",
+		},
+		{
+			name: "empty content",
+			msg: &waHistorySync.HistorySyncMsg{Message: &waWeb.WebMessageInfo{
+				Key: key,
+			}},
+			wantType:    proto.MessageType_MESSAGE_TYPE_TEXT,
+			wantContent: "[Empty message]",
+			wantSender:  "120363000000000000@g.us",
+			wantID:      "HIST1",
+		},
+		{
+			name: "missing key",
+			msg: &waHistorySync.HistorySyncMsg{Message: &waWeb.WebMessageInfo{
+				Message: &waE2E.Message{AudioMessage: &waE2E.AudioMessage{}},
+			}},
+			wantType: proto.MessageType_MESSAGE_TYPE_AUDIO,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := (&EventsProcessor{}).convertHistorySyncMessage(tt.msg)
+			if tt.wantNil {
+				if msg != nil {
+					t.Fatalf("expected nil, got %+v", msg)
+				}
+				return
+			}
+
+			if msg.MessageType != tt.wantType {
+				t.Errorf("MessageType = %s, want %s", msg.MessageType, tt.wantType)
+			}
+			if msg.Content != tt.wantContent {
+				t.Errorf("Content = %q, want %q", msg.Content, tt.wantContent)
+			}
+			if msg.SenderId != tt.wantSender {
+				t.Errorf("SenderId = %q, want %q", msg.SenderId, tt.wantSender)
+			}
+			if msg.PlatformId != tt.wantID {
+				t.Errorf("PlatformId = %q, want %q", msg.PlatformId, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestConvertContact(t *testing.T) {
+	tests := []struct {
+		name      string
+		evt       *events.Contact
+		wantNil   bool
+		wantName  string
+		wantPhone string
+	}{
+		{name: "nil event", evt: nil, wantNil: true},
+		{name: "no action", evt: &events.Contact{JID: testSender}, wantNil: true},
+		{
+			name: "phone number from user JID",
+			evt: &events.Contact{JID: testSender, Action: &waSyncAction.ContactAction{
+				FullName:  protobuf.String("Dana Levi"),
+				FirstName: protobuf.String("Dana"),
+				LidJID:    protobuf.String("123456789012345@lid"),
+			}},
+			wantName:  "Dana Levi",
+			wantPhone: "972502222222",
+		},
+		{
+			name:     "no phone number for LID",
+			evt:      &events.Contact{JID: types.NewJID("123456789012345", types.HiddenUserServer), Action: &waSyncAction.ContactAction{}},
+			wantName: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contact := (&EventsProcessor{}).convertContact(tt.evt)
+			if tt.wantNil {
+				if contact != nil {
+					t.Fatalf("expected nil, got %+v", contact)
+				}
+				return
+			}
+
+			if contact.DisplayName != tt.wantName {
+				t.Errorf("DisplayName = %q, want %q", contact.DisplayName, tt.wantName)
+			}
+			if contact.PhoneNumber != tt.wantPhone {
+				t.Errorf("PhoneNumber = %q, want %q", contact.PhoneNumber, tt.wantPhone)
+			}
+			if contact.PlatformId != tt.evt.JID.String() {
+				t.Errorf("PlatformId = %q, want %q", contact.PlatformId, tt.evt.JID.String())
+			}
+		})
+	}
+}
+
+func TestProcessEventForwardsToBackend(t *testing.T) {
+	fake := &fakeIntegrationClient{}
+	p := newTestProcessor(fake)
+	ctx := context.Background()
+
+	p.ProcessEvent(ctx, &events.Connected{})
+	p.ProcessEvent(ctx, testMessageEvent(&waE2E.Message{Conversation: protobuf.String("hi")}))
+	p.ProcessEvent(ctx, &events.Contact{JID: testSender, Action: &waSyncAction.ContactAction{FullName: protobuf.String("Dana")}})
+	p.ProcessEvent(ctx, &events.LoggedOut{})
+
+	wantStatuses := []proto.ConnectionStatus{
+		proto.ConnectionStatus_CONNECTION_STATUS_CONNECTED,
+		proto.ConnectionStatus_CONNECTION_STATUS_DISCONNECTED,
+	}
+	if len(fake.statuses) != len(wantStatuses) || fake.statuses[0] != wantStatuses[0] || fake.statuses[1] != wantStatuses[1] {
+		t.Errorf("statuses = %v, want %v", fake.statuses, wantStatuses)
+	}
+	if len(fake.messages) != 1 || fake.messages[0].Content != "hi" {
+		t.Errorf("expected one forwarded message, got %v", fake.messages)
+	}
+	if len(fake.contacts) != 1 || fake.contacts[0].DisplayName != "Dana" {
+		t.Errorf("expected one synced contact, got %v", fake.contacts)
+	}
+}
+
+func TestProcessEventGivesEachEventARequestID(t *testing.T) {
+	fake := &fakeIntegrationClient{}
+	p := newTestProcessor(fake)
+
+	for i, text := range []string{"one", "two", "three"} {
+		ctx := context.Background()
+		if text == "three" {
+			ctx = requestid.NewContext(ctx, "abc-123")
+		}
+		evt := testMessageEvent(&waE2E.Message{Conversation: protobuf.String(text)})
+		evt.Info.ID = types.MessageID(fmt.Sprintf("MSG%d", i+1))
+		p.ProcessEvent(ctx, evt)
+	}
+
+	if len(fake.requestIDs) != 3 {
+		t.Fatalf("expected 3 forwarded messages, got %d", len(fake.requestIDs))
+	}
+	if fake.requestIDs[0] == "" || fake.requestIDs[0] == fake.requestIDs[1] {
+		t.Errorf("expected each event to get its own request ID, got %q", fake.requestIDs[:2])
+	}
+	if fake.requestIDs[2] != "abc-123" {
+		t.Errorf("expected an event's existing request ID to be kept, got %q", fake.requestIDs[2])
+	}
+}
+
+func TestProcessEventForwardsConversationState(t *testing.T) {
+	fake := &fakeIntegrationClient{}
+	p := newTestProcessor(fake)
+	ctx := context.Background()
+
+	changedAt := time.UnixMilli(1700000000123)
+	muteEnd := time.UnixMilli(1800000000000)
+	p.ProcessEvent(ctx, &events.Pin{JID: testChat, Timestamp: changedAt, Action: &waSyncAction.PinAction{Pinned: protobuf.Bool(true)}})
+	p.ProcessEvent(ctx, &events.Archive{JID: testChat, Timestamp: changedAt, Action: &waSyncAction.ArchiveChatAction{Archived: protobuf.Bool(true)}})
+	p.ProcessEvent(ctx, &events.Mute{JID: testChat, Timestamp: changedAt, Action: &waSyncAction.MuteAction{Muted: protobuf.Bool(true), MuteEndTimestamp: protobuf.Int64(muteEnd.UnixMilli())}})
+	p.ProcessEvent(ctx, &events.Mute{JID: testChat, Timestamp: changedAt, Action: &waSyncAction.MuteAction{Muted: protobuf.Bool(true), MuteEndTimestamp: protobuf.Int64(-1)}})
+
+	if len(fake.stateUpdates) != 4 {
+		t.Fatalf("expected 4 state updates, got %d", len(fake.stateUpdates))
+	}
+	for i, want := range []string{"is_pinned", "is_archived", "is_muted", "is_muted"} {
+		update := fake.stateUpdates[i]
+		if update.conversationID != testChat.String() || !slices.Equal(update.fields, []string{want}) || !update.changedAt.Equal(changedAt) {
+			t.Errorf("update %d = %+v, want field %s at %s", i, update, want, changedAt)
+		}
+	}
+	if !fake.stateUpdates[0].state.IsPinned || !fake.stateUpdates[1].state.IsArchived {
+		t.Errorf("expected pinned and archived states, got %v and %v", fake.stateUpdates[0].state, fake.stateUpdates[1].state)
+	}
+	if muted := fake.stateUpdates[2].state; !muted.IsMuted || !muted.MuteUntil.AsTime().Equal(muteEnd) {
+		t.Errorf("expected mute until %s, got %v", muteEnd, muted)
+	}
+	// A mute with no expiry has no end time
+	if muted := fake.stateUpdates[3].state; !muted.IsMuted || muted.MuteUntil != nil {
+		t.Errorf("expected mute with no expiry, got %v", muted)
+	}
+}
+
+func TestProcessEventForwardsParticipantRoles(t *testing.T) {
+	fake := &fakeIntegrationClient{}
+	p := newTestProcessor(fake)
+	ctx := context.Background()
+
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	admin := types.NewJID("972503333333", types.DefaultUserServer)
+	changedAt := time.Unix(1700000000, 0)
+	p.ProcessEvent(ctx, &events.GroupInfo{
+		JID:       group,
+		Sender:    &admin,
+		Timestamp: changedAt,
+		Promote:   []types.JID{testSender},
+		Demote:    []types.JID{testChat},
+	})
+	// Other group changes don't touch roles
+	p.ProcessEvent(ctx, &events.GroupInfo{JID: group, Timestamp: changedAt, Leave: []types.JID{testSender}})
+
+	if len(fake.roleUpdates) != 1 {
+		t.Fatalf("expected 1 role update, got %d", len(fake.roleUpdates))
+	}
+	update := fake.roleUpdates[0]
+	if update.conversationID != group.String() || update.changedBy != admin.String() || !update.changedAt.Equal(changedAt) {
+		t.Errorf("unexpected role update: %+v", update)
+	}
+	want := map[string]string{testSender.String(): "admin", testChat.String(): "member"}
+	if len(update.participants) != len(want) {
+		t.Fatalf("expected %d participants, got %d", len(want), len(update.participants))
+	}
+	for _, participant := range update.participants {
+		if role := want[participant.ExternalUserId]; participant.Role != role {
+			t.Errorf("participant %s: expected role %q, got %q", participant.ExternalUserId, role, participant.Role)
+		}
+	}
+}
+
+func TestProcessEventForwardsOutboxReceipts(t *testing.T) {
+	fake := &fakeIntegrationClient{}
+	p := newTestProcessor(fake)
+	ctx := context.Background()
+
+	p.sent.Add("OUT1", "uuid-1")
+	receipt := func(receiptType types.ReceiptType, fromMe bool, ids ...types.MessageID) *events.Receipt {
+		return &events.Receipt{
+			MessageSource: types.MessageSource{Chat: testChat, Sender: testChat, IsFromMe: fromMe},
+			MessageIDs:    ids,
+			Timestamp:     time.Unix(1700000000, 0),
+			Type:          receiptType,
+		}
+	}
+	p.ProcessEvent(ctx, receipt(types.ReceiptTypeDelivered, false, "OUT1", "INCOMING1"))
+	p.ProcessEvent(ctx, receipt(types.ReceiptTypeReadSelf, true, "OUT1"))
+	p.ProcessEvent(ctx, receipt(types.ReceiptTypeDelivered, false, "OUT1"))
+	p.ProcessEvent(ctx, receipt(types.ReceiptTypeRead, false, "OUT1"))
+
+	want := []delivery{
+		{clientMsgUUID: "uuid-1", waMessageID: "OUT1", status: proto.MessageStatus_MESSAGE_STATUS_DELIVERED},
+		{clientMsgUUID: "uuid-1", waMessageID: "OUT1", status: proto.MessageStatus_MESSAGE_STATUS_READ},
+	}
+	if len(fake.deliveries) != len(want) {
+		t.Fatalf("expected %d deliveries, got %+v", len(want), fake.deliveries)
+	}
+	for i, got := range fake.deliveries {
+		if got != want[i] {
+			t.Errorf("delivery %d: expected %+v, got %+v", i, want[i], got)
+		}
+	}
+}
+
+func TestProcessEventForwardsPhoneReceipts(t *testing.T) {
+	fake := &fakeIntegrationClient{}
+	p := newTestProcessor(fake)
+	ctx := context.Background()
+
+	p.sent.Add("OUT1", "uuid-1")
+	receipt := func(receiptType types.ReceiptType, ids ...types.MessageID) *events.Receipt {
+		return &events.Receipt{
+			MessageSource: types.MessageSource{Chat: testChat, Sender: testChat},
+			MessageIDs:    ids,
+			Timestamp:     time.Unix(1700000000, 0),
+			Type:          receiptType,
+		}
+	}
+	p.ProcessEvent(ctx, receipt(types.ReceiptTypeDelivered, "OUT1", "PHONE1"))
+	p.ProcessEvent(ctx, receipt(types.ReceiptTypeDelivered, "OUT1"))
+	p.ProcessEvent(ctx, receipt(types.ReceiptTypeRead, "PHONE1"))
+
+	// Only messages the outbox didn't send are reported as phone receipts,
+	// even when a repeated receipt doesn't advance an outbox message
+	want := []delivery{
+		{waMessageID: "PHONE1", status: proto.MessageStatus_MESSAGE_STATUS_DELIVERED},
+		{waMessageID: "PHONE1", status: proto.MessageStatus_MESSAGE_STATUS_READ},
+	}
+	if len(fake.phoneReceipts) != len(want) {
+		t.Fatalf("expected %d phone receipts, got %+v", len(want), fake.phoneReceipts)
+	}
+	for i, got := range fake.phoneReceipts {
+		if got != want[i] {
+			t.Errorf("phone receipt %d: expected %+v, got %+v", i, want[i], got)
+		}
+	}
+	if len(fake.deliveries) != 1 {
+		t.Errorf("expected 1 outbox delivery, got %+v", fake.deliveries)
+	}
+}
+
+func TestProcessEventForwardsSubscribedPresence(t *testing.T) {
+	fake := &fakeIntegrationClient{}
+	p := newTestProcessor(fake)
+	ctx := context.Background()
+
+	lidSender := types.NewJID("222222222222222", types.HiddenUserServer)
+	p.reportedLIDs = map[string]string{lidSender.String(): testSender.String()}
+	if _, err := p.presence.Add([]string{testSender.String()}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	lastSeen := time.Unix(1700000000, 0)
+	p.ProcessEvent(ctx, &events.Presence{From: testChat})
+	p.ProcessEvent(ctx, &events.Presence{From: testSender, Unavailable: true, LastSeen: lastSeen})
+	p.ProcessEvent(ctx, &events.Presence{From: lidSender})
+	p.ProcessEvent(ctx, &events.ChatPresence{
+		MessageSource: types.MessageSource{Chat: testChat, Sender: testChat},
+		State:         types.ChatPresenceComposing,
+	})
+	p.ProcessEvent(ctx, &events.ChatPresence{
+		MessageSource: types.MessageSource{Chat: testSender, Sender: testSender},
+		State:         types.ChatPresenceComposing,
+		Media:         types.ChatPresenceMediaAudio,
+	})
+
+	// Presence from contacts that weren't subscribed to is dropped
+	if len(fake.presence) != 3 {
+		t.Fatalf("expected 3 presence updates, got %v", fake.presence)
+	}
+	if offline := fake.presence[0]; offline.ExternalUserId != testSender.String() || offline.IsOnline || !offline.LastSeen.AsTime().Equal(lastSeen) {
+		t.Errorf("expected offline since %s, got %v", lastSeen, offline)
+	}
+	// Presence from the contact's LID counts for their phone number subscription
+	if online := fake.presence[1]; online.ExternalUserId != lidSender.String() || !online.IsOnline {
+		t.Errorf("expected online presence from the LID, got %v", online)
+	}
+	if recording := fake.presence[2]; !recording.IsRecording || recording.IsTyping || recording.ConversationExternalId != testSender.String() {
+		t.Errorf("expected recording presence, got %v", recording)
+	}
+
+	// Nothing is forwarded once unsubscribed
+	p.presence.Remove([]string{testSender.String()})
+	p.ProcessEvent(ctx, &events.Presence{From: testSender})
+	if len(fake.presence) != 3 {
+		t.Errorf("expected no presence after unsubscribing, got %v", fake.presence[3:])
+	}
+}
+
+func TestProcessEventHistorySyncGroupsMessagesByConversation(t *testing.T) {
+	fake := &fakeIntegrationClient{}
+	p := newTestProcessor(fake)
+
+	message := func(chat, id string) *waHistorySync.HistorySyncMsg {
+		return &waHistorySync.HistorySyncMsg{Message: &waWeb.WebMessageInfo{
+			Key:     &waCommon.MessageKey{ID: protobuf.String(id), RemoteJID: protobuf.String(chat)},
+			Message: &waE2E.Message{Conversation: protobuf.String(id)},
+		}}
+	}
+
+	p.ProcessEvent(context.Background(), &events.HistorySync{Data: &waHistorySync.HistorySync{
+		SyncType: waHistorySync.HistorySync_RECENT.Enum(),
+		Conversations: []*waHistorySync.Conversation{
+			{ID: protobuf.String("a@s.whatsapp.net"), Messages: []*waHistorySync.HistorySyncMsg{message("a@s.whatsapp.net", "A1"), message("a@s.whatsapp.net", "A2")}},
+			{ID: protobuf.String("b@s.whatsapp.net"), Messages: []*waHistorySync.HistorySyncMsg{message("b@s.whatsapp.net", "B1"), nil}},
+		},
+	}})
+
+	if len(fake.conversations) != 2 {
+		t.Errorf("expected 2 synced conversations, got %d", len(fake.conversations))
+	}
+	if len(fake.synced["a@s.whatsapp.net"]) != 2 || len(fake.synced["b@s.whatsapp.net"]) != 1 {
+		t.Errorf("unexpected messages per conversation: %v", fake.synced)
+	}
+}
+
+func TestHistoryResyncSkipsMessagesAlreadySynced(t *testing.T) {
+	fake := &fakeIntegrationClient{}
+	p := newTestProcessor(fake)
+	ctx := context.Background()
+
+	historySync := func(ids ...string) *events.HistorySync {
+		messages := make([]*waHistorySync.HistorySyncMsg, len(ids))
+		for i, id := range ids {
+			messages[i] = &waHistorySync.HistorySyncMsg{Message: &waWeb.WebMessageInfo{
+				Key:     &waCommon.MessageKey{ID: protobuf.String(id), RemoteJID: protobuf.String(testChat.String())},
+				Message: &waE2E.Message{Conversation: protobuf.String("hi")},
+			}}
+		}
+		return &events.HistorySync{Data: &waHistorySync.HistorySync{
+			SyncType:      waHistorySync.HistorySync_FULL.Enum(),
+			Conversations: []*waHistorySync.Conversation{{ID: protobuf.String(testChat.String()), Messages: messages}},
+		}}
+	}
+
+	// A sync that stopped partway, then a resync resending all of it
+	p.ProcessEvent(ctx, historySync("MSG1"))
+	if err := p.resync.Request(); err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	p.ProcessEvent(ctx, historySync("MSG1", "MSG2"))
+
+	var ids []string
+	for _, message := range fake.synced[testChat.String()] {
+		ids = append(ids, message.PlatformId)
+	}
+	if !slices.Equal(ids, []string{"MSG1", "MSG2"}) {
+		t.Errorf("expected each message synced once, got %v", ids)
+	}
+}
+
+func TestProcessEventSkipsMessageAlreadySynced(t *testing.T) {
+	fake := &fakeIntegrationClient{}
+	p := newTestProcessor(fake)
+	ctx := context.Background()
+
+	// The history sync after pairing carries a message that then also
+	// arrives as a real-time event
+	p.ProcessEvent(ctx, &events.HistorySync{Data: &waHistorySync.HistorySync{
+		SyncType: waHistorySync.HistorySync_INITIAL_BOOTSTRAP.Enum(),
+		Conversations: []*waHistorySync.Conversation{{
+			ID: protobuf.String(testChat.String()),
+			Messages: []*waHistorySync.HistorySyncMsg{{Message: &waWeb.WebMessageInfo{
+				Key:     &waCommon.MessageKey{ID: protobuf.String("MSG1"), RemoteJID: protobuf.String(testChat.String())},
+				Message: &waE2E.Message{Conversation: protobuf.String("hi")},
+			}}},
+		}},
+	}})
+	p.ProcessEvent(ctx, testMessageEvent(&waE2E.Message{Conversation: protobuf.String("hi")}))
+
+	if len(fake.synced[testChat.String()]) != 1 {
+		t.Fatalf("expected the message in the history sync, got %v", fake.synced)
+	}
+	if len(fake.messages) != 0 {
+		t.Errorf("expected the real-time copy to be skipped, got %v", fake.messages)
+	}
+
+	// A new message goes through, once
+	newer := testMessageEvent(&waE2E.Message{Conversation: protobuf.String("again")})
+	newer.Info.ID = "MSG2"
+	p.ProcessEvent(ctx, newer)
+	p.ProcessEvent(ctx, newer)
+	if len(fake.messages) != 1 || fake.messages[0].PlatformId != "MSG2" {
+		t.Errorf("expected MSG2 forwarded once, got %v", fake.messages)
+	}
+}
+
+func TestProcessEventReportsIdentityMappings(t *testing.T) {
+	fake := &fakeIntegrationClient{}
+	p := newTestProcessor(fake)
+	ctx := context.Background()
+
+	p.ProcessEvent(ctx, &events.HistorySync{Data: &waHistorySync.HistorySync{
+		SyncType: waHistorySync.HistorySync_RECENT.Enum(),
+		PhoneNumberToLidMappings: []*waHistorySync.PhoneNumberToLIDMapping{
+			{LidJID: protobuf.String("111111111111111@lid"), PnJID: protobuf.String("972501111111@s.whatsapp.net")},
+			{LidJID: protobuf.String("not-a-lid@s.whatsapp.net"), PnJID: protobuf.String("972503333333@s.whatsapp.net")},
+		},
+		Conversations: []*waHistorySync.Conversation{{ID: protobuf.String("111111111111111@lid")}},
+	}})
+
+	// A live message from a known LID doesn't report it again; a new one does
+	for _, sender := range []struct{ lid, pn types.JID }{
+		{types.NewJID("111111111111111", types.HiddenUserServer), types.NewJID("972501111111", types.DefaultUserServer)},
+		{types.NewJID("222222222222222", types.HiddenUserServer), testSender},
+	} {
+		evt := testMessageEvent(&waE2E.Message{Conversation: protobuf.String("hi")})
+		evt.Info.ID = types.MessageID("MSG-" + sender.lid.User)
+		evt.Info.Sender = sender.lid
+		evt.Info.SenderAlt = sender.pn
+		p.ProcessEvent(ctx, evt)
+	}
+
+	want := []*proto.IdentityMapping{
+		{LidJid: "111111111111111@lid", PnJid: "972501111111@s.whatsapp.net"},
+		{LidJid: "222222222222222@lid", PnJid: testSender.String()},
+	}
+	if len(fake.mappings) != len(want) {
+		t.Fatalf("expected %d mappings, got %v", len(want), fake.mappings)
+	}
+	for i := range want {
+		if fake.mappings[i].LidJid != want[i].LidJid || fake.mappings[i].PnJid != want[i].PnJid {
+			t.Errorf("mapping %d = %v, want %v", i, fake.mappings[i], want[i])
+		}
+	}
+
+	// Mappings go out before the conversations and messages they apply to
+	wantCalls := []string{"SyncIdentityMappings", "SyncConversations", "ProcessMessage", "SyncIdentityMappings", "ProcessMessage"}
+	if !slices.Equal(fake.calls, wantCalls) {
+		t.Errorf("calls = %v, want %v", fake.calls, wantCalls)
+	}
+}
+
+func TestProcessEventWithoutIntegrationContextSendsNothing(t *testing.T) {
+	fake := &fakeIntegrationClient{err: errors.New("should not be called")}
+	p := NewEventsProcessor(fake, "user-1", DefaultEventsConfig(), slog.New(slog.DiscardHandler))
+	ctx := context.Background()
+
+	p.ProcessEvent(ctx, &events.Connected{})
+	p.ProcessEvent(ctx, testMessageEvent(&waE2E.Message{Conversation: protobuf.String("hi")}))
+	p.ProcessEvent(ctx, &events.Contact{JID: testSender, Action: &waSyncAction.ContactAction{}})
+
+	if len(fake.statuses) != 0 || len(fake.messages) != 0 || len(fake.contacts) != 0 {
+		t.Errorf("expected no backend calls, got statuses=%v messages=%v contacts=%v", fake.statuses, fake.messages, fake.contacts)
+	}
+}
+
+func TestProcessEventPanicsOnBackendError(t *testing.T) {
+	fake := &fakeIntegrationClient{err: errors.New("backend unavailable")}
+	p := newTestProcessor(fake)
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("expected ProcessEvent to panic when the backend call fails")
+		}
+		if msg, ok := r.(string); !ok || !strings.Contains(msg, "backend unavailable") {
+			t.Errorf("expected panic to carry the backend error, got %v", r)
+		}
+	}()
+
+	p.ProcessEvent(context.Background(), testMessageEvent(&waE2E.Message{Conversation: protobuf.String("hi")}))
+}
+
+func TestSortMessagesByConversation(t *testing.T) {
+	message := func(id string, unix int64) *proto.Message {
+		return &proto.Message{PlatformId: id, Timestamp: timestamppb.New(time.Unix(unix, 0))}
+	}
+
+	messagesByConversation := map[string][]*proto.Message{
+		"b@s.whatsapp.net": {message("B2", 300), message("B1", 100)},
+		"a@s.whatsapp.net": {message("A2", 200), message("A3", 200), message("A1", 150)},
+		"c@s.whatsapp.net": {message("C1", 100)},
+	}
+
+	order := sortMessagesByConversation(messagesByConversation)
+
+	// Ties on the oldest message are broken by conversation ID
+	wantOrder := []string{"b@s.whatsapp.net", "c@s.whatsapp.net", "a@s.whatsapp.net"}
+	if strings.Join(order, ",") != strings.Join(wantOrder, ",") {
+		t.Errorf("conversation order = %v, want %v", order, wantOrder)
+	}
+
+	wantMessages := map[string]string{
+		"a@s.whatsapp.net": "A1,A2,A3",
+		"b@s.whatsapp.net": "B1,B2",
+		"c@s.whatsapp.net": "C1",
+	}
+	for conversationID, want := range wantMessages {
+		var ids []string
+		for _, msg := range messagesByConversation[conversationID] {
+			ids = append(ids, msg.PlatformId)
+		}
+		if got := strings.Join(ids, ","); got != want {
+			t.Errorf("%s messages = %s, want %s", conversationID, got, want)
+		}
+	}
+}
+
+func TestSortConversations(t *testing.T) {
+	conversations := []*proto.Conversation{
+		{PlatformId: "c", LastActivityAt: timestamppb.New(time.Unix(300, 0))},
+		{PlatformId: "b"},
+		{PlatformId: "a", LastActivityAt: timestamppb.New(time.Unix(100, 0))},
+		{PlatformId: "d", LastActivityAt: timestamppb.New(time.Unix(100, 0))},
+	}
+
+	sortConversations(conversations)
+
+	var ids []string
+	for _, conv := range conversations {
+		ids = append(ids, conv.PlatformId)
+	}
+	if got := strings.Join(ids, ","); got != "b,a,d,c" {
+		t.Errorf("conversation order = %s, want b,a,d,c", got)
+	}
+}
+
+func TestConvertHistorySyncConversationType(t *testing.T) {
+	processor := &EventsProcessor{}
+	member := []*waHistorySync.GroupParticipant{{UserJID: protobuf.String("972500000000@s.whatsapp.net")}}
+
+	tests := []struct {
+		jid          string
+		participants []*waHistorySync.GroupParticipant
+		want         proto.ConversationType
+		wantUnread   int32
+	}{
+		{"972500000000@s.whatsapp.net", nil, proto.ConversationType_CONVERSATION_TYPE_INDIVIDUAL, 3},
+		{"120363000000000000@g.us", member, proto.ConversationType_CONVERSATION_TYPE_GROUP, 3},
+		// A group whose participants weren't synced is still a group
+		{"120363000000000000@g.us", nil, proto.ConversationType_CONVERSATION_TYPE_GROUP, 3},
+		{"1700000000@broadcast", member, proto.ConversationType_CONVERSATION_TYPE_BROADCAST, 3},
+		{"status@broadcast", nil, proto.ConversationType_CONVERSATION_TYPE_BROADCAST, 0},
+		{"120363000000000000@newsletter", nil, proto.ConversationType_CONVERSATION_TYPE_CHANNEL, 3},
+	}
+	for _, tt := range tests {
+		conv := processor.convertHistorySyncConversation(&waHistorySync.Conversation{
+			ID:          protobuf.String(tt.jid),
+			Participant: tt.participants,
+			UnreadCount: protobuf.Uint32(3),
+		})
+		if conv.Type != tt.want {
+			t.Errorf("%s: type = %v, want %v", tt.jid, conv.Type, tt.want)
+		}
+		if conv.UnreadCount != tt.wantUnread {
+			t.Errorf("%s: unread count = %d, want %d", tt.jid, conv.UnreadCount, tt.wantUnread)
+		}
+	}
+}
+
+func TestConvertHistorySyncConversationParticipantRoles(t *testing.T) {
+	participant := func(jid string, rank waHistorySync.GroupParticipant_Rank) *waHistorySync.GroupParticipant {
+		return &waHistorySync.GroupParticipant{UserJID: protobuf.String(jid), Rank: rank.Enum()}
+	}
+	conv := (&EventsProcessor{}).convertHistorySyncConversation(&waHistorySync.Conversation{
+		ID: protobuf.String("120363000000000000@g.us"),
+		Participant: []*waHistorySync.GroupParticipant{
+			participant("111@s.whatsapp.net", waHistorySync.GroupParticipant_SUPERADMIN),
+			participant("222@s.whatsapp.net", waHistorySync.GroupParticipant_ADMIN),
+			participant("333@s.whatsapp.net", waHistorySync.GroupParticipant_REGULAR),
+			{UserJID: protobuf.String("444@s.whatsapp.net")},
+		},
+	})
+
+	want := []string{"owner", "admin", "member", "member"}
+	if len(conv.Participants) != len(want) {
+		t.Fatalf("expected %d participants, got %d", len(want), len(conv.Participants))
+	}
+	for i, participant := range conv.Participants {
+		if participant.Role != want[i] {
+			t.Errorf("participant %s: expected role %q, got %q", participant.ExternalUserId, want[i], participant.Role)
+		}
+	}
+}
+
+func TestConvertHistorySyncConversationDisappearingTimer(t *testing.T) {
+	conv := (&EventsProcessor{}).convertHistorySyncConversation(&waHistorySync.Conversation{
+		ID:                  protobuf.String("972500000000@s.whatsapp.net"),
+		EphemeralExpiration: protobuf.Uint32(86400),
+	})
+	if got := conv.PlatformMetadata["disappearing_seconds"]; got != "86400" {
+		t.Errorf("disappearing_seconds = %q, want 86400", got)
+	}
+
+	conv = (&EventsProcessor{}).convertHistorySyncConversation(&waHistorySync.Conversation{
+		ID: protobuf.String("972500000000@s.whatsapp.net"),
+	})
+	if got, ok := conv.PlatformMetadata["disappearing_seconds"]; ok {
+		t.Errorf("expected no disappearing_seconds without a timer, got %q", got)
+	}
+}
@@ -0,0 +1,171 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+// historyRequestTimeout is how long an on-demand history request blocks
+// another request for the same span. The phone may never answer, so an
+// unanswered request can be retried after this.
+const historyRequestTimeout = 2 * time.Minute
+
+var (
+	ErrHistoryRequestPending = errors.New("older history for this conversation was already requested")
+	ErrNoHistoryAnchor       = errors.New("no messages synced for this conversation to load older history from")
+	ErrHistoryExhausted      = errors.New("no older history available for this conversation")
+	ErrInvalidConversation   = errors.New("not a WhatsApp chat or group JID")
+)
+
+// ParseConversationJID parses the JID of a chat or group that history can be
+// loaded for
+func ParseConversationJID(conversationJID string) (types.JID, error) {
+	chat, err := types.ParseJID(conversationJID)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("%w: %v", ErrInvalidConversation, err)
+	}
+	switch chat.Server {
+	case types.DefaultUserServer, types.HiddenUserServer, types.GroupServer:
+	default:
+		return types.JID{}, ErrInvalidConversation
+	}
+	if chat.User == "" || chat.Device != 0 {
+		return types.JID{}, ErrInvalidConversation
+	}
+	return chat, nil
+}
+
+// historyMessage identifies a message an older-history request can be anchored at
+type historyMessage struct {
+	ID        string
+	FromMe    bool
+	Timestamp time.Time
+}
+
+// historyMessages converts synced messages to history anchors
+func historyMessages(messages []*proto.Message) []historyMessage {
+	result := make([]historyMessage, len(messages))
+	for i, message := range messages {
+		result[i] = historyMessage{
+			ID:        message.PlatformId,
+			FromMe:    message.IsFromMe,
+			Timestamp: message.GetTimestamp().AsTime(),
+		}
+	}
+	return result
+}
+
+// historyRange is the span of message timestamps synced for a conversation
+type historyRange struct {
+	From, To time.Time
+}
+
+// conversationHistory is the bookkeeping for one conversation
+type conversationHistory struct {
+	synced      historyRange
+	oldest      historyMessage  // Anchor for the next older-history request
+	pending     *historyMessage // Anchor of the outstanding request, if any
+	requestedAt time.Time
+	exhausted   bool // The phone answered a request with nothing older
+}
+
+// HistoryTracker records which span of each conversation's history has been
+// synced and which older spans have been requested from the phone, so that
+// repeated load-older calls don't ask for the same span twice.
+type HistoryTracker struct {
+	mu            sync.Mutex
+	conversations map[string]*conversationHistory
+	now           func() time.Time // Replaceable in tests
+}
+
+// NewHistoryTracker creates an empty history tracker
+func NewHistoryTracker() *HistoryTracker {
+	return &HistoryTracker{
+		conversations: make(map[string]*conversationHistory),
+		now:           time.Now,
+	}
+}
+
+// Observe records messages synced for a conversation. An on-demand history
+// sync settles the outstanding request; if it brought nothing older than the
+// anchor, the conversation's history is exhausted.
+func (t *HistoryTracker) Observe(conversationID string, messages []historyMessage, onDemand bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.conversations[conversationID]
+	if h == nil {
+		h = &conversationHistory{}
+		t.conversations[conversationID] = h
+	}
+
+	for _, message := range messages {
+		if message.ID == "" {
+			continue
+		}
+		if h.synced.From.IsZero() || message.Timestamp.Before(h.synced.From) {
+			h.synced.From = message.Timestamp
+			h.oldest = message
+		}
+		if message.Timestamp.After(h.synced.To) {
+			h.synced.To = message.Timestamp
+		}
+	}
+
+	if onDemand && h.pending != nil {
+		h.exhausted = !h.oldest.Timestamp.Before(h.pending.Timestamp)
+		h.pending = nil
+	}
+}
+
+// Request returns the message to anchor an older-history request at and
+// marks everything before it as requested
+func (t *HistoryTracker) Request(conversationID string) (historyMessage, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.conversations[conversationID]
+	if h == nil || h.oldest.ID == "" {
+		return historyMessage{}, ErrNoHistoryAnchor
+	}
+	if h.exhausted {
+		return historyMessage{}, ErrHistoryExhausted
+	}
+	if h.pending != nil && t.now().Sub(h.requestedAt) < historyRequestTimeout {
+		return historyMessage{}, ErrHistoryRequestPending
+	}
+
+	anchor := h.oldest
+	h.pending = &anchor
+	h.requestedAt = t.now()
+	return anchor, nil
+}
+
+// Cancel forgets the outstanding request for a conversation, e.g. when it
+// couldn't be sent
+func (t *HistoryTracker) Cancel(conversationID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if h := t.conversations[conversationID]; h != nil {
+		h.pending = nil
+	}
+}
+
+// Synced returns the span of message timestamps synced for a conversation
+func (t *HistoryTracker) Synced(conversationID string) (historyRange, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.conversations[conversationID]
+	if h == nil || h.synced.From.IsZero() {
+		return historyRange{}, false
+	}
+	return h.synced, true
+}
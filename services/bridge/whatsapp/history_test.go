@@ -0,0 +1,188 @@
+package whatsapp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestHistoryTracker returns a history tracker on a fake clock
+func newTestHistoryTracker() (*HistoryTracker, *time.Time) {
+	t := NewHistoryTracker()
+	clock := time.Unix(1700000000, 0)
+	t.now = func() time.Time { return clock }
+	return t, &clock
+}
+
+func testHistoryMessage(id string, minutes int) historyMessage {
+	return historyMessage{ID: id, Timestamp: time.Unix(1690000000, 0).Add(time.Duration(minutes) * time.Minute)}
+}
+
+func TestHistoryTrackerRequestsFromOldestMessage(t *testing.T) {
+	tracker, _ := newTestHistoryTracker()
+	tracker.Observe("chat", []historyMessage{
+		testHistoryMessage("m2", 2),
+		testHistoryMessage("m1", 1),
+		testHistoryMessage("m3", 3),
+	}, false)
+	tracker.Observe("chat", []historyMessage{testHistoryMessage("m4", 4)}, false)
+
+	anchor, err := tracker.Request("chat")
+	if err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	if anchor.ID != "m1" {
+		t.Errorf("anchor = %s, want m1", anchor.ID)
+	}
+
+	synced, ok := tracker.Synced("chat")
+	if !ok {
+		t.Fatal("Synced() = false, want true")
+	}
+	if want := (historyRange{From: testHistoryMessage("", 1).Timestamp, To: testHistoryMessage("", 4).Timestamp}); synced != want {
+		t.Errorf("Synced() = %v, want %v", synced, want)
+	}
+}
+
+func TestHistoryTrackerRequestWithoutMessages(t *testing.T) {
+	tracker, _ := newTestHistoryTracker()
+	if _, err := tracker.Request("chat"); !errors.Is(err, ErrNoHistoryAnchor) {
+		t.Errorf("Request() error = %v, want ErrNoHistoryAnchor", err)
+	}
+
+	// Messages without IDs can't anchor a request
+	tracker.Observe("chat", []historyMessage{{Timestamp: time.Unix(1690000000, 0)}}, false)
+	if _, err := tracker.Request("chat"); !errors.Is(err, ErrNoHistoryAnchor) {
+		t.Errorf("Request() error = %v, want ErrNoHistoryAnchor", err)
+	}
+}
+
+func TestHistoryTrackerDoesNotRepeatPendingRequest(t *testing.T) {
+	tracker, clock := newTestHistoryTracker()
+	tracker.Observe("chat", []historyMessage{testHistoryMessage("m1", 1)}, false)
+
+	if _, err := tracker.Request("chat"); err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	if _, err := tracker.Request("chat"); !errors.Is(err, ErrHistoryRequestPending) {
+		t.Errorf("second Request() error = %v, want ErrHistoryRequestPending", err)
+	}
+
+	// Live messages don't answer the request
+	tracker.Observe("chat", []historyMessage{testHistoryMessage("m9", 9)}, false)
+	if _, err := tracker.Request("chat"); !errors.Is(err, ErrHistoryRequestPending) {
+		t.Errorf("Request() after live message error = %v, want ErrHistoryRequestPending", err)
+	}
+
+	// Other conversations are tracked separately
+	tracker.Observe("other", []historyMessage{testHistoryMessage("o1", 1)}, false)
+	if _, err := tracker.Request("other"); err != nil {
+		t.Errorf("Request(other) error = %v", err)
+	}
+
+	// An unanswered request can be retried once it times out
+	*clock = clock.Add(historyRequestTimeout)
+	anchor, err := tracker.Request("chat")
+	if err != nil {
+		t.Fatalf("Request() after timeout error = %v", err)
+	}
+	if anchor.ID != "m1" {
+		t.Errorf("anchor after timeout = %s, want m1", anchor.ID)
+	}
+}
+
+func TestHistoryTrackerCancel(t *testing.T) {
+	tracker, _ := newTestHistoryTracker()
+	tracker.Observe("chat", []historyMessage{testHistoryMessage("m1", 1)}, false)
+
+	if _, err := tracker.Request("chat"); err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	tracker.Cancel("chat")
+	if _, err := tracker.Request("chat"); err != nil {
+		t.Errorf("Request() after Cancel() error = %v", err)
+	}
+}
+
+func TestHistoryTrackerAdvancesAfterOnDemandSync(t *testing.T) {
+	tracker, _ := newTestHistoryTracker()
+	tracker.Observe("chat", []historyMessage{testHistoryMessage("m10", 10)}, false)
+
+	if _, err := tracker.Request("chat"); err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	tracker.Observe("chat", []historyMessage{
+		testHistoryMessage("m5", 5),
+		testHistoryMessage("m6", 6),
+	}, true)
+
+	anchor, err := tracker.Request("chat")
+	if err != nil {
+		t.Fatalf("Request() after on-demand sync error = %v", err)
+	}
+	if anchor.ID != "m5" {
+		t.Errorf("anchor = %s, want m5", anchor.ID)
+	}
+}
+
+func TestHistoryTrackerExhausted(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []historyMessage
+	}{
+		{name: "no messages"},
+		{name: "only known messages", messages: []historyMessage{testHistoryMessage("m10", 10), testHistoryMessage("m11", 11)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, _ := newTestHistoryTracker()
+			tracker.Observe("chat", []historyMessage{testHistoryMessage("m10", 10)}, false)
+
+			if _, err := tracker.Request("chat"); err != nil {
+				t.Fatalf("Request() error = %v", err)
+			}
+			tracker.Observe("chat", tt.messages, true)
+
+			if _, err := tracker.Request("chat"); !errors.Is(err, ErrHistoryExhausted) {
+				t.Errorf("Request() error = %v, want ErrHistoryExhausted", err)
+			}
+		})
+	}
+}
+
+func TestHistoryTrackerIgnoresUnrequestedOnDemandSync(t *testing.T) {
+	tracker, _ := newTestHistoryTracker()
+	tracker.Observe("chat", []historyMessage{testHistoryMessage("m10", 10)}, false)
+
+	// Nothing was requested, so an empty answer says nothing about older history
+	tracker.Observe("chat", nil, true)
+	if _, err := tracker.Request("chat"); err != nil {
+		t.Errorf("Request() error = %v", err)
+	}
+}
+
+func TestParseConversationJID(t *testing.T) {
+	for _, jid := range []string{
+		"972501234567@s.whatsapp.net",
+		"123456789012345@lid",
+		"120363012345678901@g.us",
+	} {
+		if _, err := ParseConversationJID(jid); err != nil {
+			t.Errorf("%s: unexpected error %v", jid, err)
+		}
+	}
+
+	for _, jid := range []string{
+		"",
+		"972501234567",
+		"972501234567:3@s.whatsapp.net",
+		"status@broadcast",
+		"@g.us",
+		"972501234567@example.com",
+	} {
+		if _, err := ParseConversationJID(jid); !errors.Is(err, ErrInvalidConversation) {
+			t.Errorf("%q: expected ErrInvalidConversation, got %v", jid, err)
+		}
+	}
+}
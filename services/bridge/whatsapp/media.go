@@ -0,0 +1,169 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+const (
+	mediaDownloadWorkers = 2
+	mediaDownloadQueue   = 256             // Downloads waiting for a worker; more are dropped
+	mediaDownloadTimeout = 5 * time.Minute // Per file, covering the download and the upload
+)
+
+// mediaFetcher downloads and decrypts WhatsApp media; *whatsmeow.Client implements it
+type mediaFetcher interface {
+	Download(ctx context.Context, msg whatsmeow.DownloadableMessage) ([]byte, error)
+}
+
+// mediaUploader stores media in the backend
+type mediaUploader interface {
+	GetMissingMedia(ctx context.Context, integrationCtx *proto.IntegrationContext, contentHashes []string) ([]string, error)
+	UploadMedia(ctx context.Context, integrationCtx *proto.IntegrationContext, contentHash, mimeType string, data []byte) error
+}
+
+// mediaDownload is one attachment to store in the backend
+type mediaDownload struct {
+	integrationCtx *proto.IntegrationContext
+	messageID      string
+	contentHash    string
+	mimeType       string
+	media          whatsmeow.DownloadableMessage
+}
+
+// MediaDownloader stores the attachments of incoming messages in the backend.
+// The backend keeps one file per content hash, so an attachment it already
+// has, e.g. a forwarded image, isn't downloaded again.
+type MediaDownloader struct {
+	fetcher  mediaFetcher
+	uploader mediaUploader
+	jobs     chan mediaDownload
+	logger   *slog.Logger
+}
+
+// NewMediaDownloader creates a media downloader; Run starts it
+func NewMediaDownloader(fetcher mediaFetcher, uploader mediaUploader, logger *slog.Logger) *MediaDownloader {
+	return &MediaDownloader{
+		fetcher:  fetcher,
+		uploader: uploader,
+		jobs:     make(chan mediaDownload, mediaDownloadQueue),
+		logger:   logger.With("component", "media_downloader"),
+	}
+}
+
+// Run downloads queued attachments until ctx is done
+func (d *MediaDownloader) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range mediaDownloadWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-d.jobs:
+					d.download(ctx, job)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Enqueue queues an attachment without blocking. If the queue is full it is
+// dropped and stays pending in the backend.
+func (d *MediaDownloader) Enqueue(job mediaDownload) {
+	select {
+	case d.jobs <- job:
+	default:
+		d.logger.Warn("Media download queue full, skipping attachment", "message_id", job.messageID)
+	}
+}
+
+func (d *MediaDownloader) download(ctx context.Context, job mediaDownload) {
+	ctx, cancel := context.WithTimeout(ctx, mediaDownloadTimeout)
+	defer cancel()
+	logger := d.logger.With("message_id", job.messageID, "content_hash", job.contentHash)
+
+	missing, err := d.uploader.GetMissingMedia(ctx, job.integrationCtx, []string{job.contentHash})
+	if err != nil {
+		logger.Warn("Failed to check for stored media", "error", err)
+		return
+	}
+	if len(missing) == 0 {
+		logger.Debug("Media already stored, skipping download")
+		return
+	}
+
+	data, err := d.fetcher.Download(ctx, job.media)
+	if err != nil {
+		logger.Warn("Failed to download media", "error", err)
+		return
+	}
+	if err := d.uploader.UploadMedia(ctx, job.integrationCtx, job.contentHash, job.mimeType, data); err != nil {
+		logger.Warn("Failed to upload media", "error", err)
+	}
+}
+
+// messageMedia returns the attachment of an image, video, audio or document
+// message and the part of the message it is downloaded with, or nil for other
+// messages
+func messageMedia(waMsg *waE2E.Message) (*proto.MessageMedia, whatsmeow.DownloadableMessage) {
+	media := &proto.MessageMedia{DownloadStatus: proto.DownloadStatus_DOWNLOAD_STATUS_PENDING}
+	var downloadable whatsmeow.DownloadableMessage
+	var fileSHA256 []byte
+
+	if image := waMsg.GetImageMessage(); image != nil {
+		media.MediaType = proto.MediaType_MEDIA_TYPE_IMAGE
+		media.MimeType = image.GetMimetype()
+		media.FileSize = int64(image.GetFileLength())
+		media.Width = int32(image.GetWidth())
+		media.Height = int32(image.GetHeight())
+		fileSHA256, downloadable = image.GetFileSHA256(), image
+	} else if video := waMsg.GetVideoMessage(); video != nil {
+		media.MediaType = proto.MediaType_MEDIA_TYPE_VIDEO
+		media.MimeType = video.GetMimetype()
+		media.FileSize = int64(video.GetFileLength())
+		media.DurationSeconds = int32(video.GetSeconds())
+		media.Width = int32(video.GetWidth())
+		media.Height = int32(video.GetHeight())
+		fileSHA256, downloadable = video.GetFileSHA256(), video
+	} else if audio := waMsg.GetAudioMessage(); audio != nil {
+		media.MediaType = proto.MediaType_MEDIA_TYPE_AUDIO
+		media.MimeType = audio.GetMimetype()
+		media.FileSize = int64(audio.GetFileLength())
+		media.DurationSeconds = int32(audio.GetSeconds())
+		fileSHA256, downloadable = audio.GetFileSHA256(), audio
+	} else if document := waMsg.GetDocumentMessage(); document != nil {
+		media.MediaType = proto.MediaType_MEDIA_TYPE_DOCUMENT
+		media.MimeType = document.GetMimetype()
+		media.FileSize = int64(document.GetFileLength())
+		media.FileName = document.GetFileName()
+		fileSHA256, downloadable = document.GetFileSHA256(), document
+	} else if sticker := waMsg.GetStickerMessage(); sticker != nil {
+		media.MediaType = proto.MediaType_MEDIA_TYPE_STICKER
+		media.MimeType = sticker.GetMimetype()
+		media.FileSize = int64(sticker.GetFileLength())
+		media.Width = int32(sticker.GetWidth())
+		media.Height = int32(sticker.GetHeight())
+		fileSHA256, downloadable = sticker.GetFileSHA256(), sticker
+	} else {
+		return nil, nil
+	}
+
+	// WhatsApp sends the hash of the decrypted file, which is what the
+	// backend stores files under
+	if len(fileSHA256) > 0 {
+		media.ContentHash = hex.EncodeToString(fileSHA256)
+	}
+	return media, downloadable
+}
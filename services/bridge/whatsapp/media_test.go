@@ -0,0 +1,98 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	protobuf "google.golang.org/protobuf/proto"
+
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+type fakeMediaFetcher struct {
+	downloads int
+}
+
+func (f *fakeMediaFetcher) Download(ctx context.Context, msg whatsmeow.DownloadableMessage) ([]byte, error) {
+	f.downloads++
+	return []byte("image data"), nil
+}
+
+// fakeMediaUploader is a backend that has the files in stored
+type fakeMediaUploader struct {
+	stored   map[string]bool
+	uploaded []string
+}
+
+func (u *fakeMediaUploader) GetMissingMedia(ctx context.Context, integrationCtx *proto.IntegrationContext, contentHashes []string) ([]string, error) {
+	var missing []string
+	for _, hash := range contentHashes {
+		if !u.stored[hash] {
+			missing = append(missing, hash)
+		}
+	}
+	return missing, nil
+}
+
+func (u *fakeMediaUploader) UploadMedia(ctx context.Context, integrationCtx *proto.IntegrationContext, contentHash, mimeType string, data []byte) error {
+	u.stored[contentHash] = true
+	u.uploaded = append(u.uploaded, contentHash)
+	return nil
+}
+
+func TestMediaDownloaderSkipsStoredMedia(t *testing.T) {
+	fetcher := &fakeMediaFetcher{}
+	uploader := &fakeMediaUploader{stored: map[string]bool{"stored": true}}
+	d := NewMediaDownloader(fetcher, uploader, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	// The same forwarded file twice, then one the backend already has
+	for _, hash := range []string{"forwarded", "forwarded", "stored"} {
+		d.download(ctx, mediaDownload{messageID: "MSG", contentHash: hash, media: &waE2E.ImageMessage{}})
+	}
+
+	if fetcher.downloads != 1 {
+		t.Errorf("downloads = %d, want 1", fetcher.downloads)
+	}
+	if !slices.Equal(uploader.uploaded, []string{"forwarded"}) {
+		t.Errorf("uploaded = %v, want [forwarded]", uploader.uploaded)
+	}
+}
+
+func TestMessageMedia(t *testing.T) {
+	fileSHA256 := []byte{0xab, 0xcd}
+	media, downloadable := messageMedia(&waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{
+		Mimetype:   protobuf.String("application/pdf"),
+		FileLength: protobuf.Uint64(1024),
+		FileName:   protobuf.String("invoice.pdf"),
+		FileSHA256: fileSHA256,
+	}})
+	if media == nil || downloadable == nil {
+		t.Fatal("expected a document to have media")
+	}
+	if media.MediaType != proto.MediaType_MEDIA_TYPE_DOCUMENT || media.FileName != "invoice.pdf" || media.FileSize != 1024 {
+		t.Errorf("unexpected media: %+v", media)
+	}
+	if media.ContentHash != hex.EncodeToString(fileSHA256) {
+		t.Errorf("ContentHash = %q, want %q", media.ContentHash, hex.EncodeToString(fileSHA256))
+	}
+
+	sticker, downloadable := messageMedia(&waE2E.Message{StickerMessage: &waE2E.StickerMessage{
+		Mimetype: protobuf.String("image/webp"),
+		Width:    protobuf.Uint32(512),
+		Height:   protobuf.Uint32(512),
+	}})
+	if sticker == nil || downloadable == nil || sticker.MediaType != proto.MediaType_MEDIA_TYPE_STICKER || sticker.Width != 512 {
+		t.Errorf("unexpected sticker media: %+v", sticker)
+	}
+
+	if media, _ := messageMedia(&waE2E.Message{}); media != nil {
+		t.Errorf("expected no media for a text message, got %+v", media)
+	}
+}
@@ -0,0 +1,240 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Pairing event types
+const (
+	PairingEventCode     = "code"     // A QR code to scan; replaces the previous one
+	PairingEventSuccess  = "success"  // The code was scanned and the account is linked
+	PairingEventTimeout  = "timeout"  // Every code expired without a scan
+	PairingEventRejected = "rejected" // The account can't be linked to this user
+	PairingEventError    = "error"    // WhatsApp ended the pairing with an error
+)
+
+// PairingConfig controls how long a QR pairing flow lasts
+type PairingConfig struct {
+	// How long a user has to scan a code. WhatsApp issues a few rotating codes
+	// per connection; when they run out before Timeout, the bridge reconnects
+	// for fresh ones.
+	Timeout time.Duration
+
+	// How long a connect request waits for the first code
+	FirstCodeWait time.Duration
+}
+
+// DefaultPairingConfig returns the default pairing settings
+func DefaultPairingConfig() PairingConfig {
+	return PairingConfig{
+		Timeout:       5 * time.Minute,
+		FirstCodeWait: 30 * time.Second,
+	}
+}
+
+// pairingSessionRetention is how long a finished pairing session can still be
+// watched, so a client that connects late learns the outcome
+const pairingSessionRetention = time.Minute
+
+var (
+	ErrPairingFinished   = errors.New("pairing session finished")
+	ErrPairingSuperseded = errors.New("another pairing of this user succeeded")
+)
+
+// PairingEvent is one step of a QR pairing flow. Every type but
+// PairingEventCode ends the flow.
+type PairingEvent struct {
+	Type      string
+	Code      string     // The QR code, for PairingEventCode
+	ExpiresAt *time.Time // When the QR code expires, for PairingEventCode
+	JID       string     // The linked account, for PairingEventSuccess
+	Error     string     // Why pairing failed, for PairingEventRejected and PairingEventError
+}
+
+// Final reports whether the event ends the pairing flow
+func (e PairingEvent) Final() bool {
+	return e.Type != PairingEventCode
+}
+
+// PairingSession relays the QR codes of one pairing flow, as they rotate, and
+// its outcome to any number of watchers
+type PairingSession struct {
+	ID     string
+	UserID string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	events  []PairingEvent
+	changed chan struct{} // Closed and replaced when an event is published
+}
+
+func newPairingSession(userID string) *PairingSession {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &PairingSession{
+		ID:      uuid.NewString(),
+		UserID:  userID,
+		ctx:     ctx,
+		cancel:  cancel,
+		changed: make(chan struct{}),
+	}
+}
+
+// Context is the context the session's connection flow runs in. It is
+// cancelled when the pairing ends without linking the device, including when
+// another pairing of the same user succeeds first; a linked device's
+// connection keeps it.
+func (s *PairingSession) Context() context.Context {
+	return s.ctx
+}
+
+// publish adds an event, reporting false if the session had already finished
+func (s *PairingSession) publish(evt PairingEvent) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finishedLocked() {
+		return false
+	}
+	s.events = append(s.events, evt)
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return true
+}
+
+func (s *PairingSession) finishedLocked() bool {
+	return len(s.events) > 0 && s.events[len(s.events)-1].Final()
+}
+
+// Finished reports whether the pairing flow has ended
+func (s *PairingSession) Finished() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.finishedLocked()
+}
+
+// Latest returns the index of the newest event, where a watcher starts: codes
+// before it have already expired
+func (s *PairingSession) Latest() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return max(len(s.events)-1, 0)
+}
+
+// Next returns the event at index i, waiting until it is published. It
+// returns ErrPairingFinished when the flow ended before index i.
+func (s *PairingSession) Next(ctx context.Context, i int) (PairingEvent, error) {
+	for {
+		s.mu.Lock()
+		if i < len(s.events) {
+			evt := s.events[i]
+			s.mu.Unlock()
+			return evt, nil
+		}
+		if s.finishedLocked() {
+			s.mu.Unlock()
+			return PairingEvent{}, ErrPairingFinished
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return PairingEvent{}, ctx.Err()
+		}
+	}
+}
+
+// PairingSessions holds the pairing flows in progress, by session ID
+type PairingSessions struct {
+	mu        sync.Mutex
+	sessions  map[string]*PairingSession
+	config    PairingConfig
+	retention time.Duration
+}
+
+// NewPairingSessions creates an empty pairing session registry
+func NewPairingSessions(config PairingConfig) *PairingSessions {
+	defaults := DefaultPairingConfig()
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.FirstCodeWait <= 0 {
+		config.FirstCodeWait = defaults.FirstCodeWait
+	}
+
+	return &PairingSessions{
+		sessions:  make(map[string]*PairingSession),
+		config:    config,
+		retention: pairingSessionRetention,
+	}
+}
+
+// Config returns the pairing settings
+func (r *PairingSessions) Config() PairingConfig {
+	return r.config
+}
+
+// Start registers a new pairing session for a user. A user pairs one device
+// at a time: while one of their sessions is in progress, Start returns it and
+// false instead, so a repeated connect can't link a second device that
+// strands the first.
+func (r *PairingSessions) Start(userID string) (*PairingSession, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, session := range r.sessions {
+		if session.UserID == userID && !session.Finished() {
+			return session, false
+		}
+	}
+
+	session := newPairingSession(userID)
+	r.sessions[session.ID] = session
+	return session, true
+}
+
+// Get returns a pairing session by ID
+func (r *PairingSessions) Get(id string) (*PairingSession, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session, ok := r.sessions[id]
+	return session, ok
+}
+
+// Publish adds an event to a session. A final event schedules the session's
+// removal once late watchers have had time to see it, and cancels the
+// session's context unless the device was linked. A successful pairing also
+// ends the user's other pairing sessions, so their devices aren't linked too.
+func (r *PairingSessions) Publish(session *PairingSession, evt PairingEvent) {
+	if !session.publish(evt) || !evt.Final() {
+		return
+	}
+
+	if evt.Type == PairingEventSuccess {
+		r.mu.Lock()
+		var siblings []*PairingSession
+		for _, sibling := range r.sessions {
+			if sibling != session && sibling.UserID == session.UserID {
+				siblings = append(siblings, sibling)
+			}
+		}
+		r.mu.Unlock()
+		for _, sibling := range siblings {
+			r.Publish(sibling, PairingEvent{Type: PairingEventError, Error: ErrPairingSuperseded.Error()})
+		}
+	} else {
+		session.cancel()
+	}
+
+	time.AfterFunc(r.retention, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.sessions, session.ID)
+	})
+}
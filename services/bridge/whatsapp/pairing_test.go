@@ -0,0 +1,121 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPairingSessionStreamsCodesAndOutcome(t *testing.T) {
+	sessions := NewPairingSessions(DefaultPairingConfig())
+	sessions.retention = 10 * time.Millisecond
+	session, _ := sessions.Start("user-1")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sessions.Publish(session, PairingEvent{Type: PairingEventCode, Code: "first"})
+	sessions.Publish(session, PairingEvent{Type: PairingEventCode, Code: "second"})
+
+	// A watcher starts at the current code; the first one has expired
+	i := session.Latest()
+	if evt, err := session.Next(ctx, i); err != nil || evt.Code != "second" {
+		t.Fatalf("Next() = %+v, %v, want the second code", evt, err)
+	}
+
+	// Later events reach a waiting watcher
+	next := make(chan PairingEvent)
+	go func() {
+		evt, _ := session.Next(ctx, i+1)
+		next <- evt
+	}()
+	sessions.Publish(session, PairingEvent{Type: PairingEventSuccess, JID: "972500000000@s.whatsapp.net"})
+	if evt := <-next; !evt.Final() || evt.JID != "972500000000@s.whatsapp.net" {
+		t.Fatalf("expected the success event, got %+v", evt)
+	}
+
+	// Nothing follows the outcome
+	sessions.Publish(session, PairingEvent{Type: PairingEventCode, Code: "late"})
+	if _, err := session.Next(ctx, i+2); !errors.Is(err, ErrPairingFinished) {
+		t.Errorf("Next() after the outcome error = %v, want ErrPairingFinished", err)
+	}
+
+	// A late watcher still sees the outcome until the session is removed
+	if evt, err := session.Next(ctx, session.Latest()); err != nil || evt.Type != PairingEventSuccess {
+		t.Errorf("late watcher got %+v, %v, want the success event", evt, err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := sessions.Get(session.ID); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("finished session was never removed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPairingSessionNextHonorsContext(t *testing.T) {
+	session, _ := NewPairingSessions(DefaultPairingConfig()).Start("user-1")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := session.Next(ctx, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Next() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestPairingSessionsPairOneDeviceAtATimePerUser(t *testing.T) {
+	sessions := NewPairingSessions(DefaultPairingConfig())
+	sessions.retention = time.Hour
+
+	first, started := sessions.Start("user-1")
+	if !started {
+		t.Fatal("expected the first session to start")
+	}
+	if again, started := sessions.Start("user-1"); started || again != first {
+		t.Errorf("Start() while pairing = %s, %v, want the session in progress", again.ID, started)
+	}
+	if other, started := sessions.Start("user-2"); !started || other == first {
+		t.Error("expected another user to get their own session")
+	}
+
+	// A failed pairing frees the user to start again and abandons its flow
+	sessions.Publish(first, PairingEvent{Type: PairingEventTimeout})
+	if first.Context().Err() == nil {
+		t.Error("expected a failed pairing's context to be cancelled")
+	}
+	if next, started := sessions.Start("user-1"); !started || next == first {
+		t.Error("expected a new session once the previous one finished")
+	}
+}
+
+func TestSuccessfulPairingSupersedesSiblings(t *testing.T) {
+	sessions := NewPairingSessions(DefaultPairingConfig())
+	sessions.retention = time.Hour
+
+	winner, _ := sessions.Start("user-1")
+	// Start hands out one session per user, so add the sibling directly
+	sibling := newPairingSession("user-1")
+	other, _ := sessions.Start("user-2")
+	sessions.mu.Lock()
+	sessions.sessions[sibling.ID] = sibling
+	sessions.mu.Unlock()
+
+	sessions.Publish(winner, PairingEvent{Type: PairingEventSuccess, JID: "972500000000@s.whatsapp.net"})
+
+	if winner.Context().Err() != nil {
+		t.Error("the linked device's context must stay alive")
+	}
+	if sibling.Context().Err() == nil {
+		t.Error("expected the sibling's context to be cancelled")
+	}
+	evt, err := sibling.Next(context.Background(), sibling.Latest())
+	if err != nil || evt.Type != PairingEventError || evt.Error != ErrPairingSuperseded.Error() {
+		t.Errorf("sibling ended with %+v, %v, want a superseded error", evt, err)
+	}
+	if other.Finished() || other.Context().Err() != nil {
+		t.Error("another user's pairing must be left alone")
+	}
+}
@@ -0,0 +1,80 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// MaxPresenceSubscriptions caps the contacts one account can follow the presence of
+const MaxPresenceSubscriptions = 256
+
+var (
+	ErrInvalidPresenceJID           = errors.New("presence can only be subscribed to for user JIDs")
+	ErrTooManyPresenceSubscriptions = fmt.Errorf("at most %d presence subscriptions per account", MaxPresenceSubscriptions)
+)
+
+// PresenceSubscriptions is the set of contacts whose presence is forwarded to
+// the backend. Presence from anyone else is dropped.
+type PresenceSubscriptions struct {
+	mu   sync.Mutex
+	jids map[string]struct{}
+}
+
+// NewPresenceSubscriptions creates an empty subscription set
+func NewPresenceSubscriptions() *PresenceSubscriptions {
+	return &PresenceSubscriptions{
+		jids: make(map[string]struct{}),
+	}
+}
+
+// Add subscribes to jids and returns those that weren't subscribed yet. If
+// that would go over MaxPresenceSubscriptions none are added.
+func (s *PresenceSubscriptions) Add(jids []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var added []string
+	for _, jid := range jids {
+		if _, ok := s.jids[jid]; !ok && !slices.Contains(added, jid) {
+			added = append(added, jid)
+		}
+	}
+	if len(s.jids)+len(added) > MaxPresenceSubscriptions {
+		return nil, ErrTooManyPresenceSubscriptions
+	}
+	for _, jid := range added {
+		s.jids[jid] = struct{}{}
+	}
+	return added, nil
+}
+
+// Remove unsubscribes from jids
+func (s *PresenceSubscriptions) Remove(jids []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, jid := range jids {
+		delete(s.jids, jid)
+	}
+}
+
+// Has reports whether jid is subscribed to
+func (s *PresenceSubscriptions) Has(jid string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.jids[jid]
+	return ok
+}
+
+// List returns the subscribed JIDs in order
+func (s *PresenceSubscriptions) List() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jids := make([]string, 0, len(s.jids))
+	for jid := range s.jids {
+		jids = append(jids, jid)
+	}
+	slices.Sort(jids)
+	return jids
+}
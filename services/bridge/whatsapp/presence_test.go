@@ -0,0 +1,54 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestPresenceSubscriptions(t *testing.T) {
+	s := NewPresenceSubscriptions()
+
+	added, err := s.Add([]string{"b@s.whatsapp.net", "a@s.whatsapp.net", "b@s.whatsapp.net"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if !slices.Equal(added, []string{"b@s.whatsapp.net", "a@s.whatsapp.net"}) {
+		t.Errorf("added = %v, want each new JID once", added)
+	}
+	// Subscribing again adds nothing
+	if added, _ := s.Add([]string{"a@s.whatsapp.net"}); len(added) != 0 {
+		t.Errorf("added = %v, want none", added)
+	}
+
+	s.Remove([]string{"b@s.whatsapp.net", "c@s.whatsapp.net"})
+	if !slices.Equal(s.List(), []string{"a@s.whatsapp.net"}) {
+		t.Errorf("List() = %v, want [a@s.whatsapp.net]", s.List())
+	}
+	if s.Has("b@s.whatsapp.net") || !s.Has("a@s.whatsapp.net") {
+		t.Error("Has() doesn't match the subscriptions")
+	}
+}
+
+func TestPresenceSubscriptionsLimit(t *testing.T) {
+	s := NewPresenceSubscriptions()
+	jids := make([]string, MaxPresenceSubscriptions)
+	for i := range jids {
+		jids[i] = fmt.Sprintf("%d@s.whatsapp.net", i)
+	}
+	if _, err := s.Add(jids); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// Going over the limit adds none of the JIDs, but known ones don't count
+	if _, err := s.Add([]string{jids[0], "new@s.whatsapp.net"}); !errors.Is(err, ErrTooManyPresenceSubscriptions) {
+		t.Errorf("Add() error = %v, want ErrTooManyPresenceSubscriptions", err)
+	}
+	if s.Has("new@s.whatsapp.net") {
+		t.Error("a JID was added over the limit")
+	}
+	if _, err := s.Add([]string{jids[0]}); err != nil {
+		t.Errorf("Add() of a subscribed JID error = %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package whatsapp
+
+import (
+	"container/list"
+	"sync"
+)
+
+// recentMessagesSize is how many messages RecentMessages remembers
+const recentMessagesSize = 4096
+
+// RecentMessages remembers the messages most recently sent to the backend.
+// After pairing, WhatsApp delivers recent messages both in the history sync
+// and as real-time events, and this lets a real-time copy of a message that
+// was already synced be skipped. The backend counts each message once either
+// way.
+type RecentMessages struct {
+	mu       sync.Mutex
+	size     int
+	order    *list.List               // Most recent first
+	elements map[string]*list.Element // Message key -> its element in order
+}
+
+// NewRecentMessages creates a set of recent messages holding up to size messages
+func NewRecentMessages(size int) *RecentMessages {
+	return &RecentMessages{
+		size:     size,
+		order:    list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+func recentMessageKey(conversationID, messageID string) string {
+	return conversationID + "/" + messageID
+}
+
+// Contains reports whether the message was added recently
+func (r *RecentMessages) Contains(conversationID, messageID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.elements[recentMessageKey(conversationID, messageID)]
+	return ok
+}
+
+// Add remembers a message, forgetting the least recently added one when full
+func (r *RecentMessages) Add(conversationID, messageID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := recentMessageKey(conversationID, messageID)
+	if element, ok := r.elements[key]; ok {
+		r.order.MoveToFront(element)
+		return
+	}
+	r.elements[key] = r.order.PushFront(key)
+	if r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.elements, oldest.Value.(string))
+	}
+}
@@ -0,0 +1,27 @@
+package whatsapp
+
+import "testing"
+
+func TestRecentMessages(t *testing.T) {
+	r := NewRecentMessages(2)
+	r.Add("a@s.whatsapp.net", "MSG1")
+	r.Add("b@s.whatsapp.net", "MSG1")
+
+	if !r.Contains("a@s.whatsapp.net", "MSG1") || !r.Contains("b@s.whatsapp.net", "MSG1") {
+		t.Fatal("expected both messages to be recent")
+	}
+	if r.Contains("a@s.whatsapp.net", "MSG2") {
+		t.Error("a message that wasn't added is recent")
+	}
+
+	// Adding a known message makes it the most recent, so the other one is
+	// forgotten first
+	r.Add("a@s.whatsapp.net", "MSG1")
+	r.Add("a@s.whatsapp.net", "MSG2")
+	if r.Contains("b@s.whatsapp.net", "MSG1") {
+		t.Error("expected the least recent message to be forgotten")
+	}
+	if !r.Contains("a@s.whatsapp.net", "MSG1") || !r.Contains("a@s.whatsapp.net", "MSG2") {
+		t.Error("expected the two most recent messages to be kept")
+	}
+}
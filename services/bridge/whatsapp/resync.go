@@ -0,0 +1,82 @@
+package whatsapp
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+// historyResyncWindow is how long after a full history resync is requested
+// another one is refused. The phone answers with the account's whole history,
+// which can take many minutes to stream, and a second request in the meantime
+// would have it send everything again.
+const historyResyncWindow = 15 * time.Minute
+
+var ErrHistoryResyncPending = errors.New("a full history resync was already requested")
+
+// HistoryResync tracks the outstanding full history resync of an account.
+// While one is outstanding, history syncs skip messages the backend already
+// has, so the resync only fills in what an earlier sync missed.
+type HistoryResync struct {
+	mu          sync.Mutex
+	requestedAt time.Time        // Zero when no resync is outstanding
+	now         func() time.Time // Replaceable in tests
+}
+
+// NewHistoryResync creates a tracker with no resync outstanding
+func NewHistoryResync() *HistoryResync {
+	return &HistoryResync{now: time.Now}
+}
+
+// Request marks a resync as requested, unless one already is
+func (r *HistoryResync) Request() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pending() {
+		return ErrHistoryResyncPending
+	}
+	r.requestedAt = r.now()
+	return nil
+}
+
+// Cancel forgets the outstanding resync, e.g. when its request couldn't be sent
+func (r *HistoryResync) Cancel() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requestedAt = time.Time{}
+}
+
+// Pending reports whether a resync is outstanding
+func (r *HistoryResync) Pending() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pending()
+}
+
+func (r *HistoryResync) pending() bool {
+	return !r.requestedAt.IsZero() && r.now().Sub(r.requestedAt) < historyResyncWindow
+}
+
+// buildFullHistorySyncRequest builds the peer message asking the phone to send
+// the account's complete history again. It must be sent to the account's own
+// JID with whatsmeow.SendRequestExtra{Peer: true}; the history arrives as
+// history sync events like the one after pairing.
+func buildFullHistorySyncRequest(requestID string) *waE2E.Message {
+	return &waE2E.Message{
+		ProtocolMessage: &waE2E.ProtocolMessage{
+			Type: waE2E.ProtocolMessage_PEER_DATA_OPERATION_REQUEST_MESSAGE.Enum(),
+			PeerDataOperationRequestMessage: &waE2E.PeerDataOperationRequestMessage{
+				PeerDataOperationRequestType: waE2E.PeerDataOperationRequestType_FULL_HISTORY_SYNC_ON_DEMAND.Enum(),
+				FullHistorySyncOnDemandRequest: &waE2E.PeerDataOperationRequestMessage_FullHistorySyncOnDemandRequest{
+					RequestMetadata: &waE2E.FullHistorySyncOnDemandRequestMetadata{
+						RequestID: proto.String(requestID),
+					},
+				},
+			},
+		},
+	}
+}
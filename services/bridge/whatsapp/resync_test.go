@@ -0,0 +1,56 @@
+package whatsapp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHistoryResyncRefusesRepeatedRequests(t *testing.T) {
+	resync := NewHistoryResync()
+	clock := time.Unix(1700000000, 0)
+	resync.now = func() time.Time { return clock }
+
+	if resync.Pending() {
+		t.Fatal("Pending() = true before any request")
+	}
+	if err := resync.Request(); err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	if err := resync.Request(); !errors.Is(err, ErrHistoryResyncPending) {
+		t.Errorf("second Request() error = %v, want ErrHistoryResyncPending", err)
+	}
+
+	// Once the window passes, the phone may never have answered
+	clock = clock.Add(historyResyncWindow)
+	if resync.Pending() {
+		t.Error("Pending() = true after the window")
+	}
+	if err := resync.Request(); err != nil {
+		t.Errorf("Request() after the window error = %v", err)
+	}
+}
+
+func TestHistoryResyncCancel(t *testing.T) {
+	resync := NewHistoryResync()
+	if err := resync.Request(); err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	resync.Cancel()
+	if resync.Pending() {
+		t.Error("Pending() = true after Cancel()")
+	}
+	if err := resync.Request(); err != nil {
+		t.Errorf("Request() after Cancel() error = %v", err)
+	}
+}
+
+func TestBuildFullHistorySyncRequest(t *testing.T) {
+	request := buildFullHistorySyncRequest("REQ1").GetProtocolMessage().GetPeerDataOperationRequestMessage()
+	if request.GetPeerDataOperationRequestType().String() != "FULL_HISTORY_SYNC_ON_DEMAND" {
+		t.Errorf("request type = %v", request.GetPeerDataOperationRequestType())
+	}
+	if id := request.GetFullHistorySyncOnDemandRequest().GetRequestMetadata().GetRequestID(); id != "REQ1" {
+		t.Errorf("request ID = %q, want REQ1", id)
+	}
+}
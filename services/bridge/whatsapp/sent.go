@@ -0,0 +1,136 @@
+package whatsapp
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+// sentMessagesSize is how many sent messages SentMessages tracks
+const sentMessagesSize = 4096
+
+// sentMessageTTL is how long SentMessages waits for a sent message to be read
+const sentMessageTTL = 7 * 24 * time.Hour
+
+// SentMessages maps the IDs of messages sent from the backend's outbox to
+// their client_msg_uuid, so receipts for them can be reported against the
+// outbox entry. A message is added before it's sent, since its delivery
+// receipt can arrive before WhatsApp acknowledges the send. It's forgotten
+// once read, once it expires, or when the oldest message has to make room.
+type SentMessages struct {
+	mu       sync.Mutex
+	size     int
+	ttl      time.Duration
+	now      func() time.Time
+	order    *list.List               // Oldest first
+	elements map[string]*list.Element // WhatsApp message ID -> its element in order
+}
+
+type sentMessage struct {
+	messageID     string
+	clientMsgUUID string
+	sentAt        time.Time
+	status        proto.MessageStatus // Latest receipt reported
+}
+
+// NewSentMessages creates a set of sent messages holding up to size messages
+// for up to ttl each
+func NewSentMessages(size int, ttl time.Duration) *SentMessages {
+	return &SentMessages{
+		size:     size,
+		ttl:      ttl,
+		now:      time.Now,
+		order:    list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+// Add tracks a message about to be sent, forgetting the oldest one when full
+func (s *SentMessages) Add(messageID, clientMsgUUID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.expire(now)
+	if element, ok := s.elements[messageID]; ok {
+		s.order.Remove(element)
+	}
+	s.elements[messageID] = s.order.PushBack(&sentMessage{
+		messageID:     messageID,
+		clientMsgUUID: clientMsgUUID,
+		sentAt:        now,
+		status:        proto.MessageStatus_MESSAGE_STATUS_SENT,
+	})
+	if s.order.Len() > s.size {
+		s.remove(s.order.Front())
+	}
+}
+
+// Remove forgets a message, like one that failed to send
+func (s *SentMessages) Remove(messageID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.elements[messageID]; ok {
+		s.remove(element)
+	}
+}
+
+// Advance records a delivered or read receipt for a message and returns its
+// client_msg_uuid when the receipt moves it to a later status. Receipts for
+// messages that aren't tracked, or that repeat or precede the latest one, like
+// a group member's delivery after another member read it, return false. A
+// read message is forgotten.
+func (s *SentMessages) Advance(messageID string, status proto.MessageStatus) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(s.now())
+	element, ok := s.elements[messageID]
+	if !ok {
+		return "", false
+	}
+	message := element.Value.(*sentMessage)
+	if status <= message.status {
+		return "", false
+	}
+
+	message.status = status
+	if status >= proto.MessageStatus_MESSAGE_STATUS_READ {
+		s.remove(element)
+	}
+	return message.clientMsgUUID, true
+}
+
+// Tracks reports whether a message sent from the outbox is tracked, i.e. sent
+// and not yet read
+func (s *SentMessages) Tracks(messageID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(s.now())
+	_, ok := s.elements[messageID]
+	return ok
+}
+
+// Len returns the number of messages tracked
+func (s *SentMessages) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// expire forgets the messages sent more than ttl before now
+func (s *SentMessages) expire(now time.Time) {
+	for element := s.order.Front(); element != nil; element = s.order.Front() {
+		if now.Sub(element.Value.(*sentMessage).sentAt) <= s.ttl {
+			return
+		}
+		s.remove(element)
+	}
+}
+
+func (s *SentMessages) remove(element *list.Element) {
+	s.order.Remove(element)
+	delete(s.elements, element.Value.(*sentMessage).messageID)
+}
@@ -0,0 +1,90 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+const (
+	statusDelivered = proto.MessageStatus_MESSAGE_STATUS_DELIVERED
+	statusRead      = proto.MessageStatus_MESSAGE_STATUS_READ
+)
+
+func TestSentMessagesLifecycle(t *testing.T) {
+	s := NewSentMessages(10, time.Hour)
+	s.Add("MSG1", "uuid-1")
+
+	// A receipt can arrive before the send returns, so it's tracked from Add
+	if uuid, ok := s.Advance("MSG1", statusDelivered); !ok || uuid != "uuid-1" {
+		t.Fatalf("expected the delivery of uuid-1 to be reported, got %q, %v", uuid, ok)
+	}
+	if _, ok := s.Advance("MSG1", statusDelivered); ok {
+		t.Error("a repeated delivery receipt was reported")
+	}
+	if uuid, ok := s.Advance("MSG1", statusRead); !ok || uuid != "uuid-1" {
+		t.Fatalf("expected the read of uuid-1 to be reported, got %q, %v", uuid, ok)
+	}
+
+	// Nothing follows a read, so the message is forgotten
+	if s.Len() != 0 {
+		t.Errorf("expected a read message to be forgotten, %d tracked", s.Len())
+	}
+	if _, ok := s.Advance("MSG1", statusRead); ok {
+		t.Error("a receipt for a forgotten message was reported")
+	}
+	if _, ok := s.Advance("OTHER", statusDelivered); ok {
+		t.Error("a receipt for a message that wasn't sent from the outbox was reported")
+	}
+}
+
+func TestSentMessagesReadBeforeDelivered(t *testing.T) {
+	s := NewSentMessages(10, time.Hour)
+	s.Add("MSG1", "uuid-1")
+
+	if _, ok := s.Advance("MSG1", statusRead); !ok {
+		t.Fatal("expected the read receipt to be reported")
+	}
+	if _, ok := s.Advance("MSG1", statusDelivered); ok {
+		t.Error("a delivery receipt after the read was reported")
+	}
+}
+
+func TestSentMessagesRemove(t *testing.T) {
+	s := NewSentMessages(10, time.Hour)
+	s.Add("MSG1", "uuid-1")
+	s.Remove("MSG1")
+
+	if _, ok := s.Advance("MSG1", statusDelivered); ok {
+		t.Error("a receipt for a removed message was reported")
+	}
+}
+
+func TestSentMessagesEviction(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewSentMessages(2, time.Hour)
+	s.now = func() time.Time { return now }
+
+	s.Add("MSG1", "uuid-1")
+	now = now.Add(30 * time.Minute)
+	s.Add("MSG2", "uuid-2")
+	s.Add("MSG3", "uuid-3")
+
+	// Full, so the oldest message made room
+	if _, ok := s.Advance("MSG1", statusDelivered); ok {
+		t.Error("expected the oldest message to be evicted")
+	}
+	if s.Len() != 2 {
+		t.Fatalf("expected 2 tracked messages, got %d", s.Len())
+	}
+
+	// Past the TTL nobody is waiting on the receipts any more
+	now = now.Add(time.Hour + time.Second)
+	if _, ok := s.Advance("MSG2", statusDelivered); ok {
+		t.Error("expected an expired message to be forgotten")
+	}
+	if s.Len() != 0 {
+		t.Errorf("expected expired messages to be evicted, %d tracked", s.Len())
+	}
+}
@@ -0,0 +1,449 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// Session is a connected WhatsApp account that the backend can drive
+type Session interface {
+	// SendText sends a text message and returns the WhatsApp-assigned message
+	// ID. Receipts for it are reported against clientMsgUUID's outbox entry
+	// when it is set.
+	SendText(ctx context.Context, toJID, text, replyToMessageID, clientMsgUUID string) (string, error)
+	// SendMedia uploads an image or document to WhatsApp, sends it and returns
+	// the WhatsApp-assigned message ID. Receipts are reported like SendText's.
+	SendMedia(ctx context.Context, toJID string, media OutgoingMedia, replyToMessageID, clientMsgUUID string) (string, error)
+	// MarkRead sends read receipts for messages in a chat
+	MarkRead(ctx context.Context, chatJID, senderJID string, messageIDs []string) error
+	// Logout unlinks the device from the WhatsApp account
+	Logout(ctx context.Context) error
+	// Resync re-fetches app state (contacts, chat settings) from WhatsApp
+	Resync(ctx context.Context, fullSync bool) error
+	// ResyncHistory asks the phone to send the account's complete message
+	// history again, e.g. after a history sync failed partway. It arrives as
+	// history syncs.
+	ResyncHistory(ctx context.Context) error
+	// JoinedGroups lists the groups the account belongs to and syncs them to the backend
+	JoinedGroups(ctx context.Context) ([]Group, error)
+	// LoadOlderHistory asks the phone for up to count messages older than the
+	// oldest synced one in a conversation. They arrive as a history sync.
+	LoadOlderHistory(ctx context.Context, conversationJID string, count int) error
+	// SetConversationState pins, archives or mutes a chat through app state
+	SetConversationState(ctx context.Context, chatJID string, change ConversationStateChange) error
+	// SubscribePresence starts forwarding the presence of contacts and returns
+	// every JID the account is subscribed to
+	SubscribePresence(ctx context.Context, jids []string) ([]string, error)
+	// UnsubscribePresence stops forwarding the presence of contacts and returns
+	// every JID the account is still subscribed to
+	UnsubscribePresence(ctx context.Context, jids []string) ([]string, error)
+}
+
+// Kinds of OutgoingMedia
+const (
+	MediaKindImage    = "image"
+	MediaKindDocument = "document"
+)
+
+// OutgoingMedia is an attachment to send
+type OutgoingMedia struct {
+	Kind     string // MediaKindImage or MediaKindDocument
+	Data     []byte
+	MimeType string
+	Caption  string
+	FileName string // Shown for documents
+}
+
+// ConversationStateChange changes a chat's pin, archive or mute setting. Nil
+// fields are left as they are.
+type ConversationStateChange struct {
+	Pinned    *bool
+	Archived  *bool
+	Muted     *bool
+	MuteUntil time.Time // With Muted; zero mutes with no expiry
+
+	// Timestamp of the app state mutations, echoed back in the events
+	// WhatsApp sends for them
+	ChangedAt time.Time
+}
+
+// Group is a WhatsApp group the account belongs to
+type Group struct {
+	JID              string
+	Name             string
+	Topic            string
+	OwnerJID         string
+	ParticipantCount int
+	IsAnnounce       bool
+	IsLocked         bool
+	CreatedAt        time.Time
+}
+
+// SessionRegistry tracks connected sessions by account ID
+type SessionRegistry struct {
+	mu       sync.RWMutex
+	sessions map[string]Session
+}
+
+// NewSessionRegistry creates an empty session registry
+func NewSessionRegistry() *SessionRegistry {
+	return &SessionRegistry{
+		sessions: make(map[string]Session),
+	}
+}
+
+// Register stores the session for an account, replacing any previous one
+func (r *SessionRegistry) Register(accountID string, session Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[accountID] = session
+}
+
+// Unregister removes the session for an account if it is still the registered one
+func (r *SessionRegistry) Unregister(accountID string, session Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sessions[accountID] == session {
+		delete(r.sessions, accountID)
+	}
+}
+
+// Len returns the number of connected sessions
+func (r *SessionRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.sessions)
+}
+
+// Get returns the session for an account
+func (r *SessionRegistry) Get(accountID string) (Session, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	session, ok := r.sessions[accountID]
+	return session, ok
+}
+
+// clientSession adapts a whatsmeow client to the Session interface
+type clientSession struct {
+	client    *whatsmeow.Client
+	processor *EventsProcessor // Syncs fetched data to the backend
+}
+
+func (s *clientSession) SendText(ctx context.Context, toJID, text, replyToMessageID, clientMsgUUID string) (string, error) {
+	to, err := types.ParseJID(toJID)
+	if err != nil {
+		return "", fmt.Errorf("invalid recipient JID %q: %w", toJID, err)
+	}
+
+	message := &waE2E.Message{}
+	if replyToMessageID != "" {
+		message.ExtendedTextMessage = &waE2E.ExtendedTextMessage{
+			Text: proto.String(text),
+			ContextInfo: &waE2E.ContextInfo{
+				StanzaID: proto.String(replyToMessageID),
+			},
+		}
+	} else {
+		message.Conversation = proto.String(text)
+	}
+
+	return s.send(ctx, to, message, clientMsgUUID)
+}
+
+func (s *clientSession) SendMedia(ctx context.Context, toJID string, media OutgoingMedia, replyToMessageID, clientMsgUUID string) (string, error) {
+	to, err := types.ParseJID(toJID)
+	if err != nil {
+		return "", fmt.Errorf("invalid recipient JID %q: %w", toJID, err)
+	}
+
+	var contextInfo *waE2E.ContextInfo
+	if replyToMessageID != "" {
+		contextInfo = &waE2E.ContextInfo{StanzaID: proto.String(replyToMessageID)}
+	}
+
+	message := &waE2E.Message{}
+	switch media.Kind {
+	case MediaKindImage:
+		uploaded, err := s.client.Upload(ctx, media.Data, whatsmeow.MediaImage)
+		if err != nil {
+			return "", fmt.Errorf("failed to upload image: %w", err)
+		}
+		message.ImageMessage = &waE2E.ImageMessage{
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+			Mimetype:      proto.String(media.MimeType),
+			Caption:       optionalString(media.Caption),
+			ContextInfo:   contextInfo,
+		}
+	case MediaKindDocument:
+		uploaded, err := s.client.Upload(ctx, media.Data, whatsmeow.MediaDocument)
+		if err != nil {
+			return "", fmt.Errorf("failed to upload document: %w", err)
+		}
+		message.DocumentMessage = &waE2E.DocumentMessage{
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+			Mimetype:      proto.String(media.MimeType),
+			Caption:       optionalString(media.Caption),
+			FileName:      optionalString(media.FileName),
+			Title:         optionalString(media.FileName),
+			ContextInfo:   contextInfo,
+		}
+	default:
+		return "", fmt.Errorf("unsupported media kind %q", media.Kind)
+	}
+
+	return s.send(ctx, to, message, clientMsgUUID)
+}
+
+// send sends a message and returns its ID. A message from the outbox is
+// tracked under an ID picked before sending, since its delivery receipt can
+// arrive before SendMessage returns.
+func (s *clientSession) send(ctx context.Context, to types.JID, message *waE2E.Message, clientMsgUUID string) (string, error) {
+	var extra whatsmeow.SendRequestExtra
+	if clientMsgUUID != "" {
+		extra.ID = s.client.GenerateMessageID()
+		s.processor.sent.Add(extra.ID, clientMsgUUID)
+	}
+
+	resp, err := s.client.SendMessage(ctx, to, message, extra)
+	if err != nil {
+		if clientMsgUUID != "" {
+			s.processor.sent.Remove(extra.ID)
+		}
+		return "", fmt.Errorf("failed to send message: %w", err)
+	}
+	return resp.ID, nil
+}
+
+// optionalString returns nil for an empty string, leaving the proto field unset
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return proto.String(value)
+}
+
+func (s *clientSession) MarkRead(ctx context.Context, chatJID, senderJID string, messageIDs []string) error {
+	chat, err := types.ParseJID(chatJID)
+	if err != nil {
+		return fmt.Errorf("invalid chat JID %q: %w", chatJID, err)
+	}
+
+	var sender types.JID
+	if senderJID != "" {
+		sender, err = types.ParseJID(senderJID)
+		if err != nil {
+			return fmt.Errorf("invalid sender JID %q: %w", senderJID, err)
+		}
+	}
+
+	ids := make([]types.MessageID, len(messageIDs))
+	for i, id := range messageIDs {
+		ids[i] = types.MessageID(id)
+	}
+
+	if err := s.client.MarkRead(ids, time.Now(), chat, sender); err != nil {
+		return fmt.Errorf("failed to mark messages as read: %w", err)
+	}
+	return nil
+}
+
+func (s *clientSession) Logout(ctx context.Context) error {
+	if err := s.client.Logout(ctx); err != nil {
+		return fmt.Errorf("failed to log out: %w", err)
+	}
+	return nil
+}
+
+func (s *clientSession) Resync(ctx context.Context, fullSync bool) error {
+	for _, name := range appstate.AllPatchNames {
+		if err := s.client.FetchAppState(ctx, name, fullSync, false); err != nil {
+			return fmt.Errorf("failed to fetch app state %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (s *clientSession) ResyncHistory(ctx context.Context) error {
+	if s.client.Store.ID == nil {
+		return fmt.Errorf("client is not logged in")
+	}
+	if err := s.processor.resync.Request(); err != nil {
+		return err
+	}
+
+	// History requests are peer messages to the account's own phone
+	request := buildFullHistorySyncRequest(string(s.client.GenerateMessageID()))
+	_, err := s.client.SendMessage(ctx, s.client.Store.ID.ToNonAD(), request, whatsmeow.SendRequestExtra{Peer: true})
+	if err != nil {
+		s.processor.resync.Cancel()
+		return fmt.Errorf("failed to request history resync: %w", err)
+	}
+	return nil
+}
+
+func (s *clientSession) JoinedGroups(ctx context.Context) ([]Group, error) {
+	infos, err := s.client.GetJoinedGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get joined groups: %w", err)
+	}
+	if err := s.processor.SyncGroups(ctx, infos); err != nil {
+		return nil, err
+	}
+
+	groups := make([]Group, len(infos))
+	for i, info := range infos {
+		groups[i] = Group{
+			JID:              info.JID.String(),
+			Name:             info.Name,
+			Topic:            info.Topic,
+			OwnerJID:         info.OwnerJID.String(),
+			ParticipantCount: len(info.Participants),
+			IsAnnounce:       info.IsAnnounce,
+			IsLocked:         info.IsLocked,
+			CreatedAt:        info.GroupCreated,
+		}
+	}
+	return groups, nil
+}
+
+func (s *clientSession) LoadOlderHistory(ctx context.Context, conversationJID string, count int) error {
+	chat, err := ParseConversationJID(conversationJID)
+	if err != nil {
+		return err
+	}
+	if s.client.Store.ID == nil {
+		return fmt.Errorf("client is not logged in")
+	}
+
+	anchor, err := s.processor.history.Request(conversationJID)
+	if err != nil {
+		return err
+	}
+
+	request := s.client.BuildHistorySyncRequest(&types.MessageInfo{
+		MessageSource: types.MessageSource{
+			Chat:     chat,
+			IsFromMe: anchor.FromMe,
+		},
+		ID:        anchor.ID,
+		Timestamp: anchor.Timestamp,
+	}, count)
+
+	// History requests are peer messages to the account's own phone
+	_, err = s.client.SendMessage(ctx, s.client.Store.ID.ToNonAD(), request, whatsmeow.SendRequestExtra{Peer: true})
+	if err != nil {
+		s.processor.history.Cancel(conversationJID)
+		return fmt.Errorf("failed to request older history: %w", err)
+	}
+	return nil
+}
+
+func (s *clientSession) SetConversationState(ctx context.Context, chatJID string, change ConversationStateChange) error {
+	chat, err := types.ParseJID(chatJID)
+	if err != nil {
+		return fmt.Errorf("invalid chat JID %q: %w", chatJID, err)
+	}
+
+	var patches []appstate.PatchInfo
+	archiving := change.Archived != nil && *change.Archived
+	if change.Archived != nil {
+		patches = append(patches, appstate.BuildArchive(chat, *change.Archived, time.Time{}, nil))
+	}
+	// The archive patch also unpins the chat
+	if change.Pinned != nil && !archiving {
+		patches = append(patches, appstate.BuildPin(chat, *change.Pinned))
+	}
+	if change.Muted != nil {
+		var duration time.Duration
+		if *change.Muted && !change.MuteUntil.IsZero() {
+			if duration = time.Until(change.MuteUntil); duration <= 0 {
+				return fmt.Errorf("mute end %s is in the past", change.MuteUntil)
+			}
+		}
+		patch := appstate.BuildMute(chat, *change.Muted, duration)
+		if duration > 0 {
+			// Keep the exact end time rather than one recomputed from the duration
+			patch.Mutations[0].Value.MuteAction.MuteEndTimestamp = proto.Int64(change.MuteUntil.UnixMilli())
+		}
+		patches = append(patches, patch)
+	}
+
+	for _, patch := range patches {
+		patch.Timestamp = change.ChangedAt
+		if err := s.client.SendAppState(ctx, patch); err != nil {
+			return fmt.Errorf("failed to send %s app state: %w", patch.Type, err)
+		}
+	}
+	return nil
+}
+
+func (s *clientSession) SubscribePresence(ctx context.Context, jids []string) ([]string, error) {
+	parsed, err := presenceJIDs(jids)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(parsed))
+	for i, jid := range parsed {
+		keys[i] = jid.String()
+	}
+
+	added, err := s.processor.presence.Add(keys)
+	if err != nil {
+		return nil, err
+	}
+	// Subscribing again is harmless and renews a subscription WhatsApp dropped
+	for _, jid := range parsed {
+		if err := s.client.SubscribePresence(jid); err != nil {
+			s.processor.presence.Remove(added)
+			return nil, fmt.Errorf("failed to subscribe to presence of %s: %w", jid, err)
+		}
+	}
+	return s.processor.presence.List(), nil
+}
+
+func (s *clientSession) UnsubscribePresence(ctx context.Context, jids []string) ([]string, error) {
+	parsed, err := presenceJIDs(jids)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(parsed))
+	for i, jid := range parsed {
+		keys[i] = jid.String()
+	}
+
+	// WhatsApp has no unsubscribe; the presence is dropped here instead and
+	// the subscription lapses on the next reconnect
+	s.processor.presence.Remove(keys)
+	return s.processor.presence.List(), nil
+}
+
+// presenceJIDs parses the user JIDs of a presence subscription, dropping any device part
+func presenceJIDs(jids []string) ([]types.JID, error) {
+	result := make([]types.JID, len(jids))
+	for i, jid := range jids {
+		parsed, err := types.ParseJID(jid)
+		if err != nil || (parsed.Server != types.DefaultUserServer && parsed.Server != types.HiddenUserServer) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPresenceJID, jid)
+		}
+		result[i] = parsed.ToNonAD()
+	}
+	return result, nil
+}
@@ -0,0 +1,221 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"log/slog"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types/events"
+
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+var (
+	reconnectAttempts = expvar.NewInt("whatsapp_reconnect_attempts")
+	reconnectGiveUps  = expvar.NewInt("whatsapp_reconnect_give_ups")
+
+	errLoggedOut       = errors.New("account logged out")
+	errReconnectWindow = errors.New("reconnect window exceeded")
+)
+
+// WatchdogConfig controls how a dropped WhatsApp connection is retried
+type WatchdogConfig struct {
+	InitialBackoff time.Duration // Delay before the first reconnect attempt
+	MaxBackoff     time.Duration // Upper bound on the delay between attempts
+	GiveUpAfter    time.Duration // How long to keep retrying before marking the integration as errored
+}
+
+// DefaultWatchdogConfig returns the reconnect settings used unless overridden
+func DefaultWatchdogConfig() WatchdogConfig {
+	return WatchdogConfig{
+		InitialBackoff: 2 * time.Second,
+		MaxBackoff:     2 * time.Minute,
+		GiveUpAfter:    15 * time.Minute,
+	}
+}
+
+// connectionClient is the part of *whatsmeow.Client the watchdog drives
+type connectionClient interface {
+	Connect() error
+	IsConnected() bool
+}
+
+// statusReporter propagates connection status changes to the backend
+type statusReporter interface {
+	ReportConnectionStatus(ctx context.Context, status proto.ConnectionStatus, metadata map[string]string) error
+}
+
+// Watchdog reconnects a WhatsApp client after its connection drops and keeps
+// the backend's view of the connection status up to date. It does nothing
+// until Arm is called, so a client that hasn't paired yet is never retried.
+type Watchdog struct {
+	client   connectionClient
+	reporter statusReporter
+	config   WatchdogConfig
+	logger   *slog.Logger
+
+	// Replaceable in tests
+	jitter func() float64
+	sleep  func(ctx context.Context, d time.Duration) error
+	now    func() time.Time
+
+	mu        sync.Mutex
+	armed     bool
+	loggedOut bool
+	cancel    context.CancelFunc // Stops the running reconnect loop; nil when idle
+	loopID    int
+}
+
+// NewWatchdog creates a watchdog for client that reports status through reporter
+func NewWatchdog(client connectionClient, reporter statusReporter, config WatchdogConfig, logger *slog.Logger) *Watchdog {
+	return &Watchdog{
+		client:   client,
+		reporter: reporter,
+		config:   config,
+		logger:   logger.With("component", "watchdog"),
+		jitter:   rand.Float64,
+		sleep:    sleepContext,
+		now:      time.Now,
+	}
+}
+
+// Arm enables reconnection once the client has a paired session
+func (w *Watchdog) Arm() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.armed = true
+}
+
+// HandleEvent reacts to the client's connection events. Disconnected starts a
+// reconnect loop, Connected ends it, and LoggedOut ends it for good: a
+// logged-out session can't be resumed by reconnecting.
+func (w *Watchdog) HandleEvent(ctx context.Context, evt interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch evt.(type) {
+	case *events.Disconnected:
+		if !w.armed || w.loggedOut || w.cancel != nil {
+			return
+		}
+		loopCtx, cancel := context.WithCancel(ctx)
+		w.cancel = cancel
+		w.loopID++
+		go w.run(loopCtx, w.loopID)
+
+	case *events.Connected:
+		w.stopLocked()
+
+	case *events.LoggedOut:
+		w.loggedOut = true
+		w.stopLocked()
+	}
+}
+
+// run reconnects and then clears the loop state, unless a newer loop replaced it
+func (w *Watchdog) run(ctx context.Context, id int) {
+	if err := w.reconnect(ctx); err != nil {
+		w.logger.Warn("WhatsApp reconnect stopped", "error", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.loopID == id {
+		w.stopLocked()
+	}
+}
+
+func (w *Watchdog) stopLocked() {
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+}
+
+func (w *Watchdog) isLoggedOut() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.loggedOut
+}
+
+// reconnect retries Connect with jittered exponential backoff until it
+// succeeds, the account is logged out, ctx is cancelled or the give-up
+// window closes. Giving up marks the integration as errored.
+func (w *Watchdog) reconnect(ctx context.Context) error {
+	started := w.now()
+	w.report(ctx, proto.ConnectionStatus_CONNECTION_STATUS_CONNECTING, map[string]string{
+		"reason": "reconnecting",
+	})
+
+	for attempt := 0; ; attempt++ {
+		if w.isLoggedOut() {
+			return errLoggedOut
+		}
+
+		delay := w.backoff(attempt)
+		if w.now().Add(delay).Sub(started) > w.config.GiveUpAfter {
+			reconnectGiveUps.Add(1)
+			w.logger.Error("WhatsApp reconnect gave up",
+				"attempts", attempt,
+				"elapsed", w.now().Sub(started).Round(time.Second))
+			w.report(ctx, proto.ConnectionStatus_CONNECTION_STATUS_ERROR, map[string]string{
+				"reason":   "reconnect_failed",
+				"attempts": strconv.Itoa(attempt),
+			})
+			return errReconnectWindow
+		}
+
+		if err := w.sleep(ctx, delay); err != nil {
+			return err
+		}
+		if w.isLoggedOut() {
+			return errLoggedOut
+		}
+		if w.client.IsConnected() {
+			return nil
+		}
+
+		reconnectAttempts.Add(1)
+		err := w.client.Connect()
+		if err == nil || errors.Is(err, whatsmeow.ErrAlreadyConnected) {
+			w.logger.Info("WhatsApp reconnected", "attempts", attempt+1)
+			return nil
+		}
+		w.logger.Warn("WhatsApp reconnect attempt failed", "attempt", attempt+1, "error", err)
+	}
+}
+
+// backoff returns the delay before the given attempt (counting from 0): the
+// initial backoff doubled per attempt, capped at the maximum, with the upper
+// half jittered so that many clients dropped at once don't retry in lockstep
+func (w *Watchdog) backoff(attempt int) time.Duration {
+	delay := w.config.InitialBackoff
+	for i := 0; i < attempt && delay < w.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, w.config.MaxBackoff)
+	return delay/2 + time.Duration(w.jitter()*float64(delay/2))
+}
+
+func (w *Watchdog) report(ctx context.Context, status proto.ConnectionStatus, metadata map[string]string) {
+	if err := w.reporter.ReportConnectionStatus(ctx, status, metadata); err != nil {
+		w.logger.Warn("Failed to report connection status", "status", status.String(), "error", err)
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
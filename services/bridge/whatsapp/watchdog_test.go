@@ -0,0 +1,181 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+
+	proto "github.com/tennex/shared/proto/gen/proto"
+)
+
+// fakeConnectionClient fails to connect until failures runs out
+type fakeConnectionClient struct {
+	mu       sync.Mutex
+	failures int
+	connects int
+}
+
+func (f *fakeConnectionClient) Connect() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.connects++
+	if f.failures != 0 {
+		f.failures--
+		return errors.New("socket closed")
+	}
+	return nil
+}
+
+func (f *fakeConnectionClient) IsConnected() bool { return false }
+
+type fakeStatusReporter struct {
+	mu       sync.Mutex
+	statuses []proto.ConnectionStatus
+}
+
+func (f *fakeStatusReporter) ReportConnectionStatus(ctx context.Context, status proto.ConnectionStatus, metadata map[string]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses = append(f.statuses, status)
+	return nil
+}
+
+// newTestWatchdog returns a watchdog on a fake clock that records its sleeps
+func newTestWatchdog(client *fakeConnectionClient, reporter *fakeStatusReporter, config WatchdogConfig) (*Watchdog, *[]time.Duration) {
+	w := NewWatchdog(client, reporter, config, slog.New(slog.DiscardHandler))
+	clock := time.Unix(1700000000, 0)
+	var sleeps []time.Duration
+	w.now = func() time.Time { return clock }
+	w.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		clock = clock.Add(d)
+		return nil
+	}
+	return w, &sleeps
+}
+
+func TestWatchdogBackoff(t *testing.T) {
+	w := NewWatchdog(nil, nil, WatchdogConfig{InitialBackoff: time.Second, MaxBackoff: 8 * time.Second}, slog.New(slog.DiscardHandler))
+
+	w.jitter = func() float64 { return 1 }
+	for attempt, want := range []time.Duration{1, 2, 4, 8, 8, 8} {
+		if got := w.backoff(attempt); got != want*time.Second {
+			t.Errorf("backoff(%d) with full jitter = %s, want %s", attempt, got, want*time.Second)
+		}
+	}
+
+	// Jitter only ever shortens the delay, down to half
+	w.jitter = func() float64 { return 0 }
+	for attempt, want := range []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		if got := w.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) without jitter = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestWatchdogReconnectsWithBackoff(t *testing.T) {
+	client := &fakeConnectionClient{failures: 3}
+	reporter := &fakeStatusReporter{}
+	w, sleeps := newTestWatchdog(client, reporter, WatchdogConfig{
+		InitialBackoff: time.Second,
+		MaxBackoff:     4 * time.Second,
+		GiveUpAfter:    time.Minute,
+	})
+	w.jitter = func() float64 { return 1 }
+
+	if err := w.reconnect(context.Background()); err != nil {
+		t.Fatalf("reconnect: %v", err)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+	if len(*sleeps) != len(want) {
+		t.Fatalf("sleeps = %v, want %v", *sleeps, want)
+	}
+	for i := range want {
+		if (*sleeps)[i] != want[i] {
+			t.Errorf("sleep %d = %s, want %s", i, (*sleeps)[i], want[i])
+		}
+	}
+	if client.connects != 4 {
+		t.Errorf("expected 4 connect attempts, got %d", client.connects)
+	}
+	if len(reporter.statuses) != 1 || reporter.statuses[0] != proto.ConnectionStatus_CONNECTION_STATUS_CONNECTING {
+		t.Errorf("expected a single connecting status, got %v", reporter.statuses)
+	}
+}
+
+func TestWatchdogGivesUpAfterWindow(t *testing.T) {
+	client := &fakeConnectionClient{failures: -1} // never connects
+	reporter := &fakeStatusReporter{}
+	w, sleeps := newTestWatchdog(client, reporter, WatchdogConfig{
+		InitialBackoff: time.Second,
+		MaxBackoff:     4 * time.Second,
+		GiveUpAfter:    10 * time.Second,
+	})
+	w.jitter = func() float64 { return 1 }
+
+	if err := w.reconnect(context.Background()); !errors.Is(err, errReconnectWindow) {
+		t.Fatalf("expected errReconnectWindow, got %v", err)
+	}
+
+	// 1s + 2s + 4s fit in the window; another 4s would exceed it
+	if len(*sleeps) != 3 || client.connects != 3 {
+		t.Errorf("expected 3 attempts before giving up, got sleeps=%v connects=%d", *sleeps, client.connects)
+	}
+	want := []proto.ConnectionStatus{
+		proto.ConnectionStatus_CONNECTION_STATUS_CONNECTING,
+		proto.ConnectionStatus_CONNECTION_STATUS_ERROR,
+	}
+	if len(reporter.statuses) != 2 || reporter.statuses[0] != want[0] || reporter.statuses[1] != want[1] {
+		t.Errorf("statuses = %v, want %v", reporter.statuses, want)
+	}
+}
+
+func TestWatchdogDoesNotRetryLoggedOut(t *testing.T) {
+	client := &fakeConnectionClient{}
+	reporter := &fakeStatusReporter{}
+	w, sleeps := newTestWatchdog(client, reporter, WatchdogConfig{InitialBackoff: time.Second, MaxBackoff: time.Second, GiveUpAfter: time.Minute})
+	w.Arm()
+	ctx := context.Background()
+
+	w.HandleEvent(ctx, &events.LoggedOut{})
+	w.HandleEvent(ctx, &events.Disconnected{})
+
+	w.mu.Lock()
+	running := w.cancel != nil
+	w.mu.Unlock()
+	if running {
+		t.Fatal("expected no reconnect loop after logout")
+	}
+
+	// A logout while a reconnect is pending stops it before the next attempt
+	w.sleep = func(ctx context.Context, d time.Duration) error {
+		*sleeps = append(*sleeps, d)
+		w.HandleEvent(ctx, &events.LoggedOut{})
+		return nil
+	}
+	w.loggedOut = false
+	if err := w.reconnect(ctx); !errors.Is(err, errLoggedOut) {
+		t.Fatalf("expected errLoggedOut, got %v", err)
+	}
+	if client.connects != 0 {
+		t.Errorf("expected no connect attempts after logout, got %d", client.connects)
+	}
+}
+
+func TestWatchdogIgnoresDisconnectBeforeArm(t *testing.T) {
+	w := NewWatchdog(&fakeConnectionClient{}, &fakeStatusReporter{}, DefaultWatchdogConfig(), slog.New(slog.DiscardHandler))
+
+	w.HandleEvent(context.Background(), &events.Disconnected{})
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		t.Fatal("expected an unpaired client not to be reconnected")
+	}
+}
@@ -2,11 +2,11 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -14,16 +14,23 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
-	"github.com/knadh/koanf/parsers/yaml"
-	"github.com/knadh/koanf/providers/env"
-	"github.com/knadh/koanf/providers/file"
-	"github.com/knadh/koanf/v2"
 	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
+	"github.com/tennex/eventstream/internal/registry"
 	"github.com/tennex/eventstream/internal/stream"
+	"github.com/tennex/pkg/bootstrap"
+	"github.com/tennex/pkg/httpx"
+	"github.com/tennex/pkg/natsx"
+	"github.com/tennex/pkg/reconnect"
+	"github.com/tennex/pkg/requestid"
 )
 
+// natsDrainTimeout bounds how long shutdown waits for the subscriptions to
+// finish handling the notifications they already received
+const natsDrainTimeout = 5 * time.Second
+
 type Config struct {
 	HTTP struct {
 		Port int    `koanf:"port"`
@@ -31,17 +38,36 @@ type Config struct {
 	} `koanf:"http"`
 
 	NATS struct {
-		URL string `koanf:"url"`
+		URL              string `koanf:"url"`
+		Prefix           string `koanf:"prefix"`             // Subject prefix, e.g. "tennex.prod"; must match the backend's
+		ReconnectMaxWait string `koanf:"reconnect_max_wait"` // Ceiling on the backoff between reconnection attempts
 	} `koanf:"nats"`
 
 	Backend struct {
 		URL string `koanf:"url"`
 	} `koanf:"backend"`
 
+	Stream struct {
+		QueueSize            int    `koanf:"queue_size"`
+		NotificationInterval string `koanf:"notification_interval"` // Minimum time between notifications to a client; "0" sends each at once
+	} `koanf:"stream"`
+
+	// Connection registry; without a Redis URL connections are tracked in memory only
+	Registry struct {
+		InstanceID               string `koanf:"instance_id"`
+		RedisURL                 string `koanf:"redis_url"`
+		TTL                      string `koanf:"ttl"`
+		MaxConnectionsPerAccount int    `koanf:"max_connections_per_account"`
+	} `koanf:"registry"`
+
 	Log struct {
 		Level string `koanf:"level"`
 		JSON  bool   `koanf:"json"`
 	} `koanf:"log"`
+
+	Startup struct {
+		Wait string `koanf:"wait"` // How long to retry NATS before giving up; "0" tries once
+	} `koanf:"startup"`
 }
 
 func main() {
@@ -57,7 +83,7 @@ func main() {
 	}
 
 	// Setup logger
-	logger, err := setupLogger(config.Log.Level, config.Log.JSON)
+	logger, err := bootstrap.NewLogger(config.Log.Level, config.Log.JSON)
 	if err != nil {
 		fmt.Printf("Failed to setup logger: %v\n", err)
 		os.Exit(1)
@@ -69,14 +95,52 @@ func main() {
 		zap.Int("http_port", config.HTTP.Port))
 
 	// Setup NATS connection
-	natsConn, err := setupNATS(config.NATS.URL, logger)
+	startupWait, err := time.ParseDuration(config.Startup.Wait)
+	if err != nil {
+		logger.Fatal("Invalid startup wait", zap.Error(err))
+	}
+	reconnectMaxWait, err := time.ParseDuration(config.NATS.ReconnectMaxWait)
+	if err != nil {
+		logger.Fatal("Invalid nats reconnect_max_wait", zap.Error(err))
+	}
+	natsConn, err := bootstrap.ConnectNATS(ctx, config.NATS.URL, reconnect.DefaultBackoff().WithMax(reconnectMaxWait), bootstrap.NewDependencyWait(startupWait), logger)
 	if err != nil {
 		logger.Fatal("Failed to setup NATS", zap.Error(err))
 	}
-	defer natsConn.Close()
 
 	// Create stream manager
-	streamManager := stream.NewManager(natsConn, config.Backend.URL, logger)
+	connRegistry, registryTTL, err := setupRegistry(ctx, config.Registry.RedisURL, config.Registry.TTL, logger)
+	if err != nil {
+		logger.Fatal("Failed to setup connection registry", zap.Error(err))
+	}
+
+	notificationInterval, err := time.ParseDuration(config.Stream.NotificationInterval)
+	if err != nil {
+		logger.Fatal("Invalid stream notification_interval", zap.Error(err))
+	}
+
+	streamManager := stream.NewManager(natsConn, config.Backend.URL, stream.Config{
+		QueueSize:                config.Stream.QueueSize,
+		NotificationInterval:     notificationInterval,
+		MaxConnectionsPerAccount: config.Registry.MaxConnectionsPerAccount,
+		InstanceID:               config.Registry.InstanceID,
+		SubjectPrefix:            config.NATS.Prefix,
+		Registry:                 connRegistry,
+	}, logger)
+	if config.Registry.RedisURL != "" {
+		go streamManager.RunHeartbeats(ctx, registryTTL/3)
+	}
+
+	// Expose stream metrics on /debug/vars
+	expvar.Publish("eventstream_clients", expvar.Func(func() any {
+		return streamManager.GetClientCount()
+	}))
+	expvar.Publish("eventstream_overflowed_notifications", expvar.Func(func() any {
+		return streamManager.OverflowedNotificationCount()
+	}))
+	expvar.Publish("eventstream_coalesced_notifications", expvar.Func(func() any {
+		return streamManager.CoalescedNotificationCount()
+	}))
 
 	// Setup servers
 	var wg sync.WaitGroup
@@ -92,7 +156,7 @@ func main() {
 			Port: config.HTTP.Port,
 			Host: config.HTTP.Host,
 		}
-		if err := runHTTPServer(ctx, httpConfig, streamManager, logger); err != nil {
+		if err := runHTTPServer(ctx, httpConfig, streamManager, natsConn, logger); err != nil {
 			logger.Error("HTTP server error", zap.Error(err))
 		}
 	}()
@@ -105,113 +169,85 @@ func main() {
 	logger.Info("Shutdown signal received, stopping servers...")
 	cancel()
 
-	// Wait for all goroutines to finish
+	// Wait for all goroutines to finish, then let the subscriptions finish
+	// handling what they received
 	wg.Wait()
+	natsx.Drain(natsConn, natsDrainTimeout, logger)
 	logger.Info("All servers stopped gracefully")
 }
 
 func loadConfig() (*Config, error) {
-	k := koanf.New(".")
-
 	// Load defaults
 	config := &Config{}
 	config.HTTP.Port = 6002
 	config.HTTP.Host = "0.0.0.0"
 	config.NATS.URL = "nats://localhost:4222"
+	config.NATS.ReconnectMaxWait = reconnect.DefaultBackoff().Max.String()
 	config.Backend.URL = "http://localhost:8000"
+	config.Stream.QueueSize = stream.DefaultQueueSize
+	config.Stream.NotificationInterval = stream.DefaultNotificationInterval.String()
+	config.Registry.InstanceID = defaultInstanceID()
+	config.Registry.TTL = "30s"
 	config.Log.Level = "info"
 	config.Log.JSON = false
+	config.Startup.Wait = "60s"
 
-	// Load from file if exists
-	if err := k.Load(file.Provider("config.yaml"), yaml.Parser()); err != nil {
-		// File doesn't exist, that's okay
-	}
-
-	// Load from environment (TENNEX_EVENTSTREAM_ prefix)
-	// Map env like TENNEX_EVENTSTREAM_NATS_URL -> nats.url
-	if err := k.Load(env.Provider("TENNEX_EVENTSTREAM_", ".", func(s string) string {
-		key := s[18:] // Remove TENNEX_EVENTSTREAM_ prefix
-		key = strings.ToLower(key)
-		key = strings.ReplaceAll(key, "_", ".")
-		return key
-	}), nil); err != nil {
-		return nil, fmt.Errorf("error loading env config: %w", err)
-	}
-
-	// Also support generic TENNEX_ prefix to align with docker-compose
-	if err := k.Load(env.Provider("TENNEX_", ".", func(s string) string {
-		key := s[7:]
-		key = strings.ToLower(key)
-		key = strings.ReplaceAll(key, "_", ".")
-		return key
-	}), nil); err != nil {
-		return nil, fmt.Errorf("error loading env config: %w", err)
-	}
-
-	// Unmarshal into config struct
-	if err := k.Unmarshal("", config); err != nil {
-		return nil, fmt.Errorf("error unmarshaling config: %w", err)
+	// Overlay config.yaml and the environment, e.g. TENNEX_EVENTSTREAM_NATS_URL
+	// -> nats.url, with the generic TENNEX_ prefix to align with docker-compose
+	if err := bootstrap.LoadConfig(config, "TENNEX_EVENTSTREAM_", "TENNEX_"); err != nil {
+		return nil, err
 	}
 
 	return config, nil
 }
 
-func setupLogger(level string, jsonFormat bool) (*zap.Logger, error) {
-	var config zap.Config
-	if jsonFormat {
-		config = zap.NewProductionConfig()
-	} else {
-		config = zap.NewDevelopmentConfig()
+// defaultInstanceID identifies this process in the connection registry
+func defaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "eventstream"
 	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
 
-	switch level {
-	case "debug":
-		config.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
-	case "info":
-		config.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
-	case "warn":
-		config.Level = zap.NewAtomicLevelAt(zap.WarnLevel)
-	case "error":
-		config.Level = zap.NewAtomicLevelAt(zap.ErrorLevel)
-	default:
-		config.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
+// setupRegistry connects to Redis when a URL is configured. A nil registry
+// keeps connection tracking in process memory.
+func setupRegistry(ctx context.Context, redisURL, ttl string, logger *zap.Logger) (registry.Registry, time.Duration, error) {
+	registryTTL, err := time.ParseDuration(ttl)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid registry ttl: %w", err)
+	}
+	if redisURL == "" {
+		logger.Info("Connection registry disabled, tracking connections in memory")
+		return nil, registryTTL, nil
 	}
 
-	return config.Build()
-}
-
-func setupNATS(url string, logger *zap.Logger) (*nats.Conn, error) {
-	nc, err := nats.Connect(url,
-		nats.MaxReconnects(-1),
-		nats.ReconnectWait(2*time.Second),
-		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
-			logger.Warn("NATS disconnected", zap.Error(err))
-		}),
-		nats.ReconnectHandler(func(nc *nats.Conn) {
-			logger.Info("NATS reconnected", zap.String("url", nc.ConnectedUrl()))
-		}),
-	)
+	opts, err := redis.ParseURL(redisURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+		return nil, 0, fmt.Errorf("invalid registry redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, 0, fmt.Errorf("failed to connect to redis: %w", err)
 	}
 
-	logger.Info("NATS connection established", zap.String("url", url))
-	return nc, nil
+	logger.Info("Connection registry enabled", zap.String("redis_addr", opts.Addr), zap.Duration("ttl", registryTTL))
+	return registry.NewRedisRegistry(client, registryTTL), registryTTL, nil
 }
 
 func runHTTPServer(ctx context.Context, httpConfig struct {
 	Port int
 	Host string
-}, streamManager *stream.Manager, logger *zap.Logger) error {
+}, streamManager *stream.Manager, natsConn *nats.Conn, logger *zap.Logger) error {
 
 	router := chi.NewRouter()
 
 	// Middleware
-	router.Use(middleware.RequestID)
+	router.Use(requestid.Middleware)
 	router.Use(middleware.RealIP)
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
-	router.Use(middleware.Timeout(60 * time.Second))
 
 	// CORS
 	router.Use(cors.Handler(cors.Options{
@@ -223,14 +259,28 @@ func runHTTPServer(ctx context.Context, httpConfig struct {
 		MaxAge:           300,
 	}))
 
-	// WebSocket endpoint
+	// Streaming endpoints are long-lived, so they are not subject to the request timeout
 	router.Get("/ws", streamManager.HandleWebSocket)
-
-	// Health check
-	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok","service":"eventstream"}`))
+	router.Get("/events", streamManager.HandleSSE)
+
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(60 * time.Second))
+
+		// Metrics
+		r.Handle("/debug/vars", expvar.Handler())
+		r.Get("/debug/connections", streamManager.HandleDebugConnections)
+
+		// Health check
+		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+			httpx.JSON(w, http.StatusOK, map[string]interface{}{
+				"status":  "ok",
+				"service": "eventstream",
+				"nats":    map[string]string{"state": natsx.State(natsConn)},
+			})
+		})
+
+		// Readiness: 503 while NATS is down, as notifications can't be delivered
+		r.Get("/ready", streamManager.HandleReady)
 	})
 
 	addr := fmt.Sprintf("%s:%d", httpConfig.Host, httpConfig.Port)
@@ -0,0 +1,162 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "eventstream:"
+
+// registerScript atomically prunes expired connections, checks the account
+// limit and records the connection.
+//
+// KEYS: account set, global set, connection key
+// ARGV: now (ms), expiry (ms), client ID, limit, connection JSON, TTL (ms)
+var registerScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+local limit = tonumber(ARGV[4])
+if limit > 0 and redis.call('ZCARD', KEYS[1]) >= limit then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[3])
+redis.call('PEXPIRE', KEYS[1], ARGV[6])
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[3])
+redis.call('SET', KEYS[3], ARGV[5], 'PX', ARGV[6])
+return 1
+`)
+
+// RedisRegistry shares connections between instances through Redis. Each
+// connection expires after ttl unless refreshed, so a crashed instance's
+// connections disappear on their own.
+type RedisRegistry struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewRedisRegistry creates a Redis-backed registry
+func NewRedisRegistry(client redis.UniversalClient, ttl time.Duration) *RedisRegistry {
+	return &RedisRegistry{
+		client: client,
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+// Register records a connection, enforcing the per-account limit
+func (r *RedisRegistry) Register(ctx context.Context, conn Connection, limit int) error {
+	data, err := json.Marshal(conn)
+	if err != nil {
+		return fmt.Errorf("failed to marshal connection: %w", err)
+	}
+
+	now := r.now()
+	added, err := registerScript.Run(ctx, r.client,
+		[]string{accountKey(conn.AccountID), connectionsKey(), connectionKey(conn.ClientID)},
+		now.UnixMilli(), now.Add(r.ttl).UnixMilli(), conn.ClientID, limit, data, r.ttl.Milliseconds(),
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to register connection: %w", err)
+	}
+	if added == 0 {
+		return ErrLimitExceeded
+	}
+	return nil
+}
+
+// Unregister removes a connection
+func (r *RedisRegistry) Unregister(ctx context.Context, conn Connection) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, accountKey(conn.AccountID), conn.ClientID)
+		pipe.ZRem(ctx, connectionsKey(), conn.ClientID)
+		pipe.Del(ctx, connectionKey(conn.ClientID))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unregister connection: %w", err)
+	}
+	return nil
+}
+
+// Refresh extends the TTL of the given connections
+func (r *RedisRegistry) Refresh(ctx context.Context, conns []Connection) error {
+	if len(conns) == 0 {
+		return nil
+	}
+
+	expiry := float64(r.now().Add(r.ttl).UnixMilli())
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, conn := range conns {
+			data, err := json.Marshal(conn)
+			if err != nil {
+				return fmt.Errorf("failed to marshal connection: %w", err)
+			}
+			pipe.Set(ctx, connectionKey(conn.ClientID), data, r.ttl)
+			pipe.ZAdd(ctx, accountKey(conn.AccountID), redis.Z{Score: expiry, Member: conn.ClientID})
+			pipe.PExpire(ctx, accountKey(conn.AccountID), r.ttl)
+			pipe.ZAdd(ctx, connectionsKey(), redis.Z{Score: expiry, Member: conn.ClientID})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to refresh connections: %w", err)
+	}
+	return nil
+}
+
+// List returns all live connections across instances
+func (r *RedisRegistry) List(ctx context.Context) ([]Connection, error) {
+	now := strconv.FormatInt(r.now().UnixMilli(), 10)
+	if err := r.client.ZRemRangeByScore(ctx, connectionsKey(), "-inf", now).Err(); err != nil {
+		return nil, fmt.Errorf("failed to prune expired connections: %w", err)
+	}
+
+	clientIDs, err := r.client.ZRange(ctx, connectionsKey(), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list connections: %w", err)
+	}
+	if len(clientIDs) == 0 {
+		return []Connection{}, nil
+	}
+
+	keys := make([]string, len(clientIDs))
+	for i, clientID := range clientIDs {
+		keys[i] = connectionKey(clientID)
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connections: %w", err)
+	}
+
+	conns := make([]Connection, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			// Expired between ZRANGE and MGET
+			continue
+		}
+		var conn Connection
+		if err := json.Unmarshal([]byte(data), &conn); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal connection: %w", err)
+		}
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
+
+func connectionKey(clientID string) string {
+	return keyPrefix + "conn:" + clientID
+}
+
+func accountKey(accountID string) string {
+	return keyPrefix + "account:" + accountID
+}
+
+func connectionsKey() string {
+	return keyPrefix + "conns"
+}
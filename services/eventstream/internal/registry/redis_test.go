@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRegistry(t *testing.T, mr *miniredis.Miniredis, clock *time.Time) *RedisRegistry {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	registry := NewRedisRegistry(client, 30*time.Second)
+	registry.now = func() time.Time { return *clock }
+	return registry
+}
+
+func clientIDs(conns []Connection) []string {
+	ids := make([]string, len(conns))
+	for i, conn := range conns {
+		ids[i] = conn.ClientID
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestRedisRegistryEnforcesAccountLimitAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	clock := time.Unix(1700000000, 0)
+	instanceA := newTestRegistry(t, mr, &clock)
+	instanceB := newTestRegistry(t, mr, &clock)
+
+	first := Connection{InstanceID: "a", ClientID: "c1", AccountID: "acct", ConnectedAt: clock}
+	second := Connection{InstanceID: "b", ClientID: "c2", AccountID: "acct", ConnectedAt: clock}
+	third := Connection{InstanceID: "b", ClientID: "c3", AccountID: "acct", ConnectedAt: clock}
+	other := Connection{InstanceID: "a", ClientID: "c4", AccountID: "other", ConnectedAt: clock}
+
+	if err := instanceA.Register(ctx, first, 2); err != nil {
+		t.Fatalf("Register first: %v", err)
+	}
+	if err := instanceB.Register(ctx, second, 2); err != nil {
+		t.Fatalf("Register second: %v", err)
+	}
+	if err := instanceB.Register(ctx, third, 2); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("Register third: expected ErrLimitExceeded, got %v", err)
+	}
+	if err := instanceA.Register(ctx, other, 2); err != nil {
+		t.Fatalf("Register other account: %v", err)
+	}
+
+	conns, err := instanceB.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if got, want := clientIDs(conns), []string{"c1", "c2", "c4"}; !equal(got, want) {
+		t.Fatalf("List = %v, want %v", got, want)
+	}
+
+	// Freeing a slot lets the next connection in
+	if err := instanceA.Unregister(ctx, first); err != nil {
+		t.Fatalf("Unregister: %v", err)
+	}
+	if err := instanceB.Register(ctx, third, 2); err != nil {
+		t.Fatalf("Register third after unregister: %v", err)
+	}
+}
+
+func TestRedisRegistryExpiresConnectionsWithoutHeartbeat(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	clock := time.Unix(1700000000, 0)
+	registry := newTestRegistry(t, mr, &clock)
+
+	live := Connection{InstanceID: "a", ClientID: "live", AccountID: "acct", ConnectedAt: clock}
+	stale := Connection{InstanceID: "crashed", ClientID: "stale", AccountID: "acct", ConnectedAt: clock}
+	for _, conn := range []Connection{live, stale} {
+		if err := registry.Register(ctx, conn, 2); err != nil {
+			t.Fatalf("Register %s: %v", conn.ClientID, err)
+		}
+	}
+
+	// Only the live connection keeps heartbeating
+	for i := 0; i < 3; i++ {
+		clock = clock.Add(20 * time.Second)
+		mr.FastForward(20 * time.Second)
+		if err := registry.Refresh(ctx, []Connection{live}); err != nil {
+			t.Fatalf("Refresh: %v", err)
+		}
+	}
+
+	conns, err := registry.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if got, want := clientIDs(conns), []string{"live"}; !equal(got, want) {
+		t.Fatalf("List = %v, want %v", got, want)
+	}
+
+	// The expired connection no longer counts towards the limit
+	newer := Connection{InstanceID: "a", ClientID: "newer", AccountID: "acct", ConnectedAt: clock}
+	if err := registry.Register(ctx, newer, 2); err != nil {
+		t.Fatalf("Register after expiry: %v", err)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
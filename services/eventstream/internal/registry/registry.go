@@ -0,0 +1,97 @@
+// Package registry tracks WebSocket connections across eventstream instances
+package registry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLimitExceeded is returned by Register when the account is at its connection limit
+var ErrLimitExceeded = errors.New("account connection limit reached")
+
+// Connection describes one WebSocket client connected to an eventstream instance
+type Connection struct {
+	InstanceID  string    `json:"instance_id"`
+	ClientID    string    `json:"client_id"`
+	AccountID   string    `json:"account_id"`
+	ConnectedAt time.Time `json:"connected_at"`
+}
+
+// Registry records live connections
+type Registry interface {
+	// Register records a connection. If limit > 0 and the account already has
+	// limit connections, nothing is recorded and ErrLimitExceeded is returned.
+	Register(ctx context.Context, conn Connection, limit int) error
+
+	// Unregister removes a connection
+	Unregister(ctx context.Context, conn Connection) error
+
+	// Refresh marks connections as still alive
+	Refresh(ctx context.Context, conns []Connection) error
+
+	// List returns all live connections
+	List(ctx context.Context) ([]Connection, error)
+}
+
+// MemoryRegistry keeps connections in process memory. It only sees the
+// connections of the local instance.
+type MemoryRegistry struct {
+	mu    sync.Mutex
+	conns map[string]Connection
+}
+
+// NewMemoryRegistry creates an in-memory registry
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{
+		conns: make(map[string]Connection),
+	}
+}
+
+// Register records a connection
+func (r *MemoryRegistry) Register(ctx context.Context, conn Connection, limit int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if limit > 0 {
+		count := 0
+		for _, c := range r.conns {
+			if c.AccountID == conn.AccountID {
+				count++
+			}
+		}
+		if count >= limit {
+			return ErrLimitExceeded
+		}
+	}
+
+	r.conns[conn.ClientID] = conn
+	return nil
+}
+
+// Unregister removes a connection
+func (r *MemoryRegistry) Unregister(ctx context.Context, conn Connection) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.conns, conn.ClientID)
+	return nil
+}
+
+// Refresh is a no-op; in-memory connections don't expire
+func (r *MemoryRegistry) Refresh(ctx context.Context, conns []Connection) error {
+	return nil
+}
+
+// List returns all connections
+func (r *MemoryRegistry) List(ctx context.Context) ([]Connection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conns := make([]Connection, 0, len(r.conns))
+	for _, c := range r.conns {
+		conns = append(conns, c)
+	}
+	return conns, nil
+}
@@ -0,0 +1,440 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+	"nhooyr.io/websocket"
+
+	"github.com/tennex/eventstream/internal/registry"
+)
+
+// transport delivers frames to a connected client (WebSocket or SSE)
+type transport interface {
+	// Write sends one frame to the client
+	Write(ctx context.Context, frame outboundFrame) error
+
+	// Ping checks the connection is alive
+	Ping(ctx context.Context) error
+
+	// Close ends the connection. WebSocket clients receive the close code.
+	Close(code websocket.StatusCode, reason string)
+}
+
+// Client represents a connected client. It owns the per-client queue and
+// backpressure handling; the transport only writes frames.
+type Client struct {
+	id        string
+	accountID string
+	transport transport
+	send      chan outboundFrame
+	manager   *Manager
+	logger    *zap.Logger
+	requestID string // ID of the request that opened the connection
+
+	// NATS subscription for this client's account
+	subscription *nats.Subscription
+
+	// Conversations the client subscribed to; empty means all conversations
+	conversations map[string]struct{}
+	filterMu      sync.RWMutex
+
+	// Highest notification seq written to the connection (owned by writePump)
+	deliveredSeq int64
+
+	// Notification coalescing: the latest notification waiting for the
+	// interval since lastNotified to end, if any
+	pendingMu    sync.Mutex
+	pending      *Notification
+	lastNotified time.Time
+
+	// Overflow: the latest notification that didn't fit in the send queue,
+	// merged with any that arrive until writePump takes it. backlogReady
+	// wakes writePump when it's set.
+	backlogMu    sync.Mutex
+	backlog      *Notification
+	backlogReady chan struct{}
+
+	// When the client connected, as recorded in the registry
+	connectedAt time.Time
+
+	// Context and cancel for graceful shutdown
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+// outboundFrame is a queued message; seq is set for notifications
+type outboundFrame struct {
+	data []byte
+	seq  int64
+}
+
+// Notification represents a NATS notification message
+type Notification struct {
+	AccountID      string `json:"account_id"`
+	IntegrationID  int32  `json:"integration_id,omitempty"` // 0 for events not tied to an integration
+	ConversationID string `json:"conversation_id,omitempty"`
+	NextSeq        int64  `json:"next_seq"`
+	Muted          bool   `json:"muted,omitempty"` // The conversation is muted; clients sync without alerting
+}
+
+// clientMessage is a control message sent by a WebSocket client
+type clientMessage struct {
+	Type          string   `json:"type"`
+	Conversations []string `json:"conversations"`
+}
+
+// handleNotification handles NATS notifications
+func (c *Client) handleNotification(msg *nats.Msg) {
+	var notification Notification
+	if err := json.Unmarshal(msg.Data, &notification); err != nil {
+		c.logger.Error("Failed to unmarshal notification", zap.Error(err))
+		return
+	}
+
+	if !c.wantsConversation(notification.ConversationID) {
+		return
+	}
+
+	if c.manager.notificationInterval <= 0 {
+		c.sendNotification(notification)
+		return
+	}
+	c.coalesceNotification(notification)
+}
+
+// coalesceNotification sends a notification at once if none was sent within
+// the notification interval. Otherwise it waits for the interval to end,
+// merged with any that arrive meanwhile, so a bulk sync wakes the client
+// promptly without a frame per event.
+func (c *Client) coalesceNotification(notification Notification) {
+	c.pendingMu.Lock()
+	if c.pending != nil {
+		c.pending.merge(notification)
+		c.pendingMu.Unlock()
+		c.manager.coalescedNotifications.Add(1)
+		return
+	}
+
+	wait := c.manager.notificationInterval - time.Since(c.lastNotified)
+	if wait <= 0 {
+		c.lastNotified = time.Now()
+		c.pendingMu.Unlock()
+		c.sendNotification(notification)
+		return
+	}
+	c.pending = &notification
+	c.pendingMu.Unlock()
+	time.AfterFunc(wait, c.flushNotification)
+}
+
+// flushNotification sends the pending notification
+func (c *Client) flushNotification() {
+	c.pendingMu.Lock()
+	notification := c.pending
+	c.pending = nil
+	c.lastNotified = time.Now()
+	c.pendingMu.Unlock()
+
+	if notification != nil && c.ctx.Err() == nil {
+		c.sendNotification(*notification)
+	}
+}
+
+// merge folds a later notification into n. The merged notification carries
+// the latest seq, and a conversation or integration only if every merged
+// notification had the same one. It's muted only if every merged one was.
+func (n *Notification) merge(later Notification) {
+	n.NextSeq = max(n.NextSeq, later.NextSeq)
+	n.Muted = n.Muted && later.Muted
+	if n.ConversationID != later.ConversationID {
+		n.ConversationID = ""
+	}
+	if n.IntegrationID != later.IntegrationID {
+		n.IntegrationID = 0
+	}
+}
+
+// sendNotification queues a notification frame. When the queue is full the
+// notification is held back instead, merged with any later ones, and written
+// once the client catches up. A client only needs the latest seq, so a slow
+// client misses no events and isn't disconnected.
+func (c *Client) sendNotification(notification Notification) {
+	c.backlogMu.Lock()
+	defer c.backlogMu.Unlock()
+
+	// Later notifications join the held back one, so they aren't written
+	// ahead of it
+	if c.backlog != nil {
+		c.backlog.merge(notification)
+		c.manager.overflowedNotifications.Add(1)
+		return
+	}
+
+	frame, err := notificationFrame(notification)
+	if err != nil {
+		c.logger.Error("Failed to marshal notification message", zap.Error(err))
+		return
+	}
+
+	// Send to client (non-blocking)
+	select {
+	case <-c.ctx.Done():
+	case c.send <- frame:
+		c.logger.Debug("Notification sent to client",
+			zap.Int64("next_seq", notification.NextSeq))
+	default:
+		// Queue is full, client is too slow
+		c.logger.Debug("Client message queue full, holding back notification",
+			zap.Int64("next_seq", notification.NextSeq))
+		c.backlog = &notification
+		c.manager.overflowedNotifications.Add(1)
+		select {
+		case c.backlogReady <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// takeBacklog returns the held back notification's frame and clears it
+func (c *Client) takeBacklog() (outboundFrame, bool) {
+	c.backlogMu.Lock()
+	notification := c.backlog
+	c.backlog = nil
+	c.backlogMu.Unlock()
+
+	if notification == nil {
+		return outboundFrame{}, false
+	}
+	frame, err := notificationFrame(*notification)
+	if err != nil {
+		c.logger.Error("Failed to marshal notification message", zap.Error(err))
+		return outboundFrame{}, false
+	}
+	return frame, true
+}
+
+// notificationFrame builds the frame a client receives for a notification
+func notificationFrame(notification Notification) (outboundFrame, error) {
+	clientMsg := map[string]interface{}{
+		"type":     "notification",
+		"next_seq": notification.NextSeq,
+	}
+	if notification.IntegrationID != 0 {
+		clientMsg["integration_id"] = notification.IntegrationID
+	}
+	if notification.ConversationID != "" {
+		clientMsg["conversation_id"] = notification.ConversationID
+	}
+	if notification.Muted {
+		clientMsg["muted"] = true
+	}
+
+	data, err := json.Marshal(clientMsg)
+	if err != nil {
+		return outboundFrame{}, err
+	}
+	return outboundFrame{data: data, seq: notification.NextSeq}, nil
+}
+
+// wantsConversation reports whether a notification for the conversation should be
+// forwarded. Clients without a filter, and notifications without a conversation,
+// always match.
+func (c *Client) wantsConversation(conversationID string) bool {
+	c.filterMu.RLock()
+	defer c.filterMu.RUnlock()
+
+	if len(c.conversations) == 0 || conversationID == "" {
+		return true
+	}
+	_, ok := c.conversations[conversationID]
+	return ok
+}
+
+// subscribe adds conversations to the client's filter and returns the resulting filter
+func (c *Client) subscribe(conversationIDs []string) []string {
+	c.filterMu.Lock()
+	defer c.filterMu.Unlock()
+
+	for _, id := range conversationIDs {
+		c.conversations[id] = struct{}{}
+	}
+	return c.filterLocked()
+}
+
+// unsubscribe removes conversations from the client's filter and returns the resulting filter
+func (c *Client) unsubscribe(conversationIDs []string) []string {
+	c.filterMu.Lock()
+	defer c.filterMu.Unlock()
+
+	for _, id := range conversationIDs {
+		delete(c.conversations, id)
+	}
+	return c.filterLocked()
+}
+
+func (c *Client) filterLocked() []string {
+	conversations := make([]string, 0, len(c.conversations))
+	for id := range c.conversations {
+		conversations = append(conversations, id)
+	}
+	sort.Strings(conversations)
+	return conversations
+}
+
+// handleClientMessage applies subscribe/unsubscribe requests to the client's filter
+func (c *Client) handleClientMessage(message []byte) {
+	var msg clientMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.logger.Warn("Ignoring malformed client message", zap.Error(err))
+		return
+	}
+
+	var conversations []string
+	switch msg.Type {
+	case "subscribe":
+		conversations = c.subscribe(msg.Conversations)
+	case "unsubscribe":
+		conversations = c.unsubscribe(msg.Conversations)
+	default:
+		c.logger.Debug("Ignoring unknown client message", zap.String("type", msg.Type))
+		return
+	}
+
+	c.logger.Debug("Updated conversation filter", zap.Strings("conversations", conversations))
+
+	// Acknowledge with the resulting filter
+	data, err := json.Marshal(map[string]interface{}{
+		"type":          "subscriptions",
+		"conversations": conversations,
+	})
+	if err != nil {
+		c.logger.Error("Failed to marshal subscriptions message", zap.Error(err))
+		return
+	}
+	select {
+	case <-c.ctx.Done():
+	case c.send <- outboundFrame{data: data}:
+	default:
+		c.logger.Warn("Client message queue full, dropping subscriptions ack")
+	}
+}
+
+// writePump sends queued messages to the transport. Only a failed write
+// disconnects the client.
+func (c *Client) writePump() {
+	defer c.close()
+
+	for {
+		var frame outboundFrame
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.backlogReady:
+			var ok bool
+			if frame, ok = c.takeBacklog(); !ok {
+				continue
+			}
+		case frame = <-c.send:
+			// A notification queued before a held back one that was already
+			// written carries nothing new
+			if frame.seq != 0 && frame.seq <= c.deliveredSeq {
+				continue
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(c.ctx, writeTimeout)
+		err := c.transport.Write(ctx, frame)
+		cancel()
+
+		if err != nil {
+			c.logger.Error("Failed to write message", zap.Error(err))
+			return
+		}
+		if frame.seq > c.deliveredSeq {
+			c.deliveredSeq = frame.seq
+		}
+	}
+}
+
+// pingTicker sends periodic ping messages
+func (c *Client) pingTicker() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(c.ctx, writeTimeout)
+			err := c.transport.Ping(ctx)
+			cancel()
+
+			if err != nil {
+				c.logger.Error("Failed to ping client", zap.Error(err))
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+// close gracefully closes the client connection. It is safe to call more than once.
+func (c *Client) close() {
+	c.closeWithStatus(websocket.StatusNormalClosure, "")
+}
+
+// closeWithStatus closes the client connection with the given close code.
+// The reason for an abnormal close carries the connection's request ID, so a
+// client can quote it when reporting the error.
+func (c *Client) closeWithStatus(code websocket.StatusCode, reason string) {
+	if code != websocket.StatusNormalClosure {
+		reason = closeReason(reason, c.requestID)
+	}
+	c.closeOnce.Do(func() {
+		// Unsubscribe from NATS
+		if c.subscription != nil {
+			c.subscription.Unsubscribe()
+		}
+
+		// Close the transport before cancelling, so a pending WebSocket read
+		// doesn't close it first with a different status
+		c.transport.Close(code, reason)
+
+		// Cancel context to stop all goroutines
+		c.cancel()
+
+		// Remove from manager
+		c.manager.mu.Lock()
+		delete(c.manager.clients, c.id)
+		c.manager.mu.Unlock()
+
+		c.manager.unregister(c.registration())
+	})
+}
+
+// closeReason appends a request ID to a WebSocket close reason
+func closeReason(reason, requestID string) string {
+	if requestID == "" {
+		return reason
+	}
+	return fmt.Sprintf("%s (request %s)", reason, requestID)
+}
+
+// registration returns the client's registry entry
+func (c *Client) registration() registry.Connection {
+	return registry.Connection{
+		InstanceID:  c.manager.instanceID,
+		ClientID:    c.id,
+		AccountID:   c.accountID,
+		ConnectedAt: c.connectedAt,
+	}
+}
@@ -3,19 +3,39 @@ package stream
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nats-io/nats.go"
 	"go.uber.org/zap"
 	"nhooyr.io/websocket"
+
+	"github.com/tennex/eventstream/internal/registry"
 )
 
 const (
-	// Maximum message queue size per client
-	maxQueueSize = 1000
+	// DefaultQueueSize is the per-client message queue size used when none is configured
+	DefaultQueueSize = 1000
+
+	// DefaultNotificationInterval is the minimum time between notifications
+	// to a client the service is configured with by default
+	DefaultNotificationInterval = 200 * time.Millisecond
+
+	// CloseCodeTooManyConnections is the WebSocket close code sent to clients
+	// over their account's connection limit, the equivalent of HTTP 429
+	CloseCodeTooManyConnections websocket.StatusCode = 4029
+
+	// Timeout for a single write to a client
+	writeTimeout = 10 * time.Second
+
+	// Timeout for connection registry calls made outside a request
+	registryTimeout = 5 * time.Second
 
 	// Ping interval to keep connections alive
 	pingInterval = 30 * time.Second
@@ -24,100 +44,173 @@ const (
 	maxMessageSize = 32 * 1024 // 32KB
 )
 
-// Manager handles WebSocket connections and NATS subscriptions
-type Manager struct {
-	nats       *nats.Conn
-	backendURL string
-	logger     *zap.Logger
-
-	// Connection management
-	clients map[string]*Client
-	mu      sync.RWMutex
+// Subscriber subscribes to NATS subjects (implemented by *nats.Conn)
+type Subscriber interface {
+	Subscribe(subject string, cb nats.MsgHandler) (*nats.Subscription, error)
+	IsConnected() bool
 }
 
-// Client represents a connected WebSocket client
-type Client struct {
-	id        string
-	accountID string
-	conn      *websocket.Conn
-	send      chan []byte
-	manager   *Manager
-	logger    *zap.Logger
-
-	// NATS subscription for this client's account
-	subscription *nats.Subscription
-
-	// Context and cancel for graceful shutdown
-	ctx    context.Context
-	cancel context.CancelFunc
+// Config holds optional Manager settings
+type Config struct {
+	// Per-client message queue size; <= 0 uses DefaultQueueSize
+	QueueSize int
+
+	// Maximum concurrent connections per account; <= 0 means unlimited
+	MaxConnectionsPerAccount int
+
+	// Identifies this instance in the connection registry
+	InstanceID string
+
+	// NATS subject prefix (e.g. "tennex.prod"); must match the backend's
+	SubjectPrefix string
+
+	// Minimum time between notifications to a client. Notifications arriving
+	// sooner are coalesced into one carrying the latest seq; <= 0 sends each
+	// as it arrives.
+	NotificationInterval time.Duration
+
+	// Shared connection registry; nil keeps connections in process memory
+	Registry registry.Registry
 }
 
-// Notification represents a NATS notification message
-type Notification struct {
-	AccountID string `json:"account_id"`
-	NextSeq   int64  `json:"next_seq"`
+// Manager handles client connections (WebSocket or SSE) and NATS subscriptions
+type Manager struct {
+	nats                     Subscriber
+	backendURL               string
+	queueSize                int
+	maxConnectionsPerAccount int
+	instanceID               string
+	subjectPrefix            string
+	notificationInterval     time.Duration
+	registry                 registry.Registry
+	logger                   *zap.Logger
+
+	// Number of notifications held back because a client's queue was full
+	overflowedNotifications atomic.Int64
+
+	// Number of notifications merged into a later one instead of being sent
+	coalescedNotifications atomic.Int64
+
+	// Connection management
+	clients        map[string]*Client
+	accountClients map[string]int // Admitted connections per account on this instance
+	mu             sync.RWMutex
 }
 
+// errMissingAccountID is returned by admit for requests without an account_id
+var errMissingAccountID = errors.New("missing account_id parameter")
+
 // NewManager creates a new stream manager
-func NewManager(natsConn *nats.Conn, backendURL string, logger *zap.Logger) *Manager {
+func NewManager(natsConn Subscriber, backendURL string, config Config, logger *zap.Logger) *Manager {
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.Registry == nil {
+		config.Registry = registry.NewMemoryRegistry()
+	}
 	return &Manager{
-		nats:       natsConn,
-		backendURL: backendURL,
-		logger:     logger.Named("stream_manager"),
-		clients:    make(map[string]*Client),
+		nats:                     natsConn,
+		backendURL:               backendURL,
+		queueSize:                config.QueueSize,
+		maxConnectionsPerAccount: config.MaxConnectionsPerAccount,
+		instanceID:               config.InstanceID,
+		subjectPrefix:            config.SubjectPrefix,
+		notificationInterval:     config.NotificationInterval,
+		registry:                 config.Registry,
+		logger:                   logger.Named("stream_manager"),
+		clients:                  make(map[string]*Client),
+		accountClients:           make(map[string]int),
 	}
 }
 
-// HandleWebSocket handles incoming WebSocket connections
-func (m *Manager) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+// admit validates a connection request and records it before the transport
+// is set up, so the account limit can be enforced. It returns
+// registry.ErrLimitExceeded if the account is at its limit.
+func (m *Manager) admit(r *http.Request) (registry.Connection, error) {
 	// Get account ID from query parameters
 	accountID := r.URL.Query().Get("account_id")
 	if accountID == "" {
-		http.Error(w, "Missing account_id parameter", http.StatusBadRequest)
-		return
+		return registry.Connection{}, errMissingAccountID
 	}
 
-	// Accept WebSocket connection
-	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
-		OriginPatterns: []string{"*"}, // Allow all origins in development
-	})
-	if err != nil {
-		m.logger.Error("Failed to accept WebSocket connection", zap.Error(err))
-		return
+	registration := registry.Connection{
+		InstanceID:  m.instanceID,
+		ClientID:    fmt.Sprintf("%s-%d", accountID, time.Now().UnixNano()),
+		AccountID:   accountID,
+		ConnectedAt: time.Now().UTC(),
 	}
 
-	// Create client
-	client := m.createClient(accountID, conn)
+	// Count this instance's connections too, so the limit holds when the
+	// shared registry is unavailable
+	m.mu.Lock()
+	if m.maxConnectionsPerAccount > 0 && m.accountClients[accountID] >= m.maxConnectionsPerAccount {
+		m.mu.Unlock()
+		m.logger.Warn("Rejecting connection over account limit",
+			zap.String("account_id", accountID),
+			zap.Int("limit", m.maxConnectionsPerAccount))
+		return registry.Connection{}, registry.ErrLimitExceeded
+	}
+	m.accountClients[accountID]++
+	m.mu.Unlock()
 
-	m.logger.Info("WebSocket client connected",
-		zap.String("client_id", client.id),
-		zap.String("account_id", accountID))
+	if err := m.registry.Register(r.Context(), registration, m.maxConnectionsPerAccount); err != nil {
+		if errors.Is(err, registry.ErrLimitExceeded) {
+			m.release(accountID)
+			m.logger.Warn("Rejecting connection over account limit",
+				zap.String("account_id", accountID),
+				zap.Int("limit", m.maxConnectionsPerAccount))
+			return registry.Connection{}, err
+		}
+		// The registry is for visibility and limits; don't refuse clients when it's down
+		m.logger.Error("Failed to register connection", zap.Error(err))
+	}
 
-	// Start client goroutines
-	go client.writePump()
-	go client.readPump()
+	return registration, nil
+}
 
-	// Wait for client to disconnect
-	<-client.ctx.Done()
+// rejectHTTP writes the HTTP response for a connection request admit refused
+func rejectHTTP(w http.ResponseWriter, err error) {
+	if errors.Is(err, registry.ErrLimitExceeded) {
+		http.Error(w, "Too many connections for account", http.StatusTooManyRequests)
+		return
+	}
+	http.Error(w, "Missing account_id parameter", http.StatusBadRequest)
+}
+
+// release frees an account's connection slot on this instance
+func (m *Manager) release(accountID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	m.logger.Info("WebSocket client disconnected",
-		zap.String("client_id", client.id),
-		zap.String("account_id", accountID))
+	if m.accountClients[accountID] <= 1 {
+		delete(m.accountClients, accountID)
+		return
+	}
+	m.accountClients[accountID]--
 }
 
-// createClient creates a new WebSocket client
-func (m *Manager) createClient(accountID string, conn *websocket.Conn) *Client {
+// createClient creates a new client on the given transport, initially filtered
+// to the given conversations (nil for all)
+func (m *Manager) createClient(registration registry.Connection, requestID string, transport transport, conversations []string) *Client {
 	ctx, cancel := context.WithCancel(context.Background())
+	accountID := registration.AccountID
 
 	client := &Client{
-		id:        fmt.Sprintf("%s-%d", accountID, time.Now().UnixNano()),
-		accountID: accountID,
-		conn:      conn,
-		send:      make(chan []byte, maxQueueSize),
-		manager:   m,
-		logger:    m.logger.With(zap.String("client_id", fmt.Sprintf("%s-*", accountID))),
-		ctx:       ctx,
-		cancel:    cancel,
+		id:            registration.ClientID,
+		accountID:     accountID,
+		transport:     transport,
+		send:          make(chan outboundFrame, m.queueSize),
+		manager:       m,
+		logger:        m.logger.With(zap.String("client_id", fmt.Sprintf("%s-*", accountID)), zap.String("request_id", requestID)),
+		requestID:     requestID,
+		conversations: make(map[string]struct{}),
+		backlogReady:  make(chan struct{}, 1),
+		connectedAt:   registration.ConnectedAt,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	for _, id := range conversations {
+		client.conversations[id] = struct{}{}
 	}
 
 	// Register client
@@ -125,8 +218,8 @@ func (m *Manager) createClient(accountID string, conn *websocket.Conn) *Client {
 	m.clients[client.id] = client
 	m.mu.Unlock()
 
-	// Subscribe to NATS notifications for this account
-	subject := fmt.Sprintf("notify.account.%s", accountID)
+	// Subscribe to NATS notifications for all of this account's integrations
+	subject := notificationSubject(m.subjectPrefix, accountID)
 	sub, err := m.nats.Subscribe(subject, client.handleNotification)
 	if err != nil {
 		m.logger.Error("Failed to subscribe to NATS",
@@ -143,132 +236,126 @@ func (m *Manager) createClient(accountID string, conn *websocket.Conn) *Client {
 	return client
 }
 
-// handleNotification handles NATS notifications
-func (c *Client) handleNotification(msg *nats.Msg) {
-	var notification Notification
-	if err := json.Unmarshal(msg.Data, &notification); err != nil {
-		c.logger.Error("Failed to unmarshal notification", zap.Error(err))
-		return
+// notificationSubject returns the NATS subject matching the notifications of
+// every integration of an account, e.g.
+// "tennex.prod.notify.user.<id>.integration.*" for the prefix "tennex.prod"
+func notificationSubject(prefix, accountID string) string {
+	subject := "notify.user." + accountID + ".integration.*"
+	if prefix = strings.Trim(prefix, "."); prefix != "" {
+		subject = prefix + "." + subject
 	}
+	return subject
+}
 
-	// Create WebSocket message
-	wsMsg := map[string]interface{}{
-		"type":     "notification",
-		"next_seq": notification.NextSeq,
-	}
+// unregister removes a connection from the registry and frees its slot
+func (m *Manager) unregister(conn registry.Connection) {
+	m.release(conn.AccountID)
 
-	data, err := json.Marshal(wsMsg)
-	if err != nil {
-		c.logger.Error("Failed to marshal WebSocket message", zap.Error(err))
-		return
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+	defer cancel()
 
-	// Send to client (non-blocking)
-	select {
-	case c.send <- data:
-		c.logger.Debug("Notification sent to client",
-			zap.Int64("next_seq", notification.NextSeq))
-	default:
-		c.logger.Warn("Client message queue full, dropping notification")
-		// Queue is full, client is too slow
-		c.close()
+	if err := m.registry.Unregister(ctx, conn); err != nil {
+		m.logger.Error("Failed to unregister connection",
+			zap.String("client_id", conn.ClientID),
+			zap.Error(err))
 	}
 }
 
-// writePump sends messages to the WebSocket connection
-func (c *Client) writePump() {
-	defer c.close()
+// RunHeartbeats refreshes this instance's connections in the registry until ctx is done
+func (m *Manager) RunHeartbeats(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
 	for {
 		select {
-		case <-c.ctx.Done():
+		case <-ctx.Done():
 			return
-		case message, ok := <-c.send:
-			if !ok {
-				return
+		case <-ticker.C:
+			m.mu.RLock()
+			conns := make([]registry.Connection, 0, len(m.clients))
+			for _, client := range m.clients {
+				conns = append(conns, client.registration())
 			}
+			m.mu.RUnlock()
 
-			ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
-			err := c.conn.Write(ctx, websocket.MessageText, message)
-			cancel()
-
-			if err != nil {
-				c.logger.Error("Failed to write message", zap.Error(err))
-				return
+			refreshCtx, cancel := context.WithTimeout(ctx, registryTimeout)
+			if err := m.registry.Refresh(refreshCtx, conns); err != nil {
+				m.logger.Error("Failed to refresh connections", zap.Error(err))
 			}
+			cancel()
 		}
 	}
 }
 
-// readPump reads messages from the WebSocket connection
-func (c *Client) readPump() {
-	defer c.close()
-
-	// Set read limit
-	c.conn.SetReadLimit(maxMessageSize)
-
-	for {
-		_, message, err := c.conn.Read(c.ctx)
-		if err != nil {
-			if websocket.CloseStatus(err) == websocket.StatusNormalClosure {
-				c.logger.Debug("Client closed connection normally")
-			} else {
-				c.logger.Error("Failed to read message", zap.Error(err))
-			}
-			return
-		}
-
-		// Handle client message (for now, just log)
-		c.logger.Debug("Received message from client",
-			zap.String("message", string(message)))
-
-		// TODO: Handle ping/pong, authentication, etc.
+// HandleDebugConnections reports connection counts across all instances sharing the registry
+func (m *Manager) HandleDebugConnections(w http.ResponseWriter, r *http.Request) {
+	conns, err := m.registry.List(r.Context())
+	if err != nil {
+		m.logger.Error("Failed to list connections", zap.Error(err))
+		http.Error(w, "Failed to list connections", http.StatusInternalServerError)
+		return
 	}
-}
 
-// pingTicker sends periodic ping messages
-func (c *Client) pingTicker() {
-	ticker := time.NewTicker(pingInterval)
-	defer ticker.Stop()
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].ConnectedAt.Before(conns[j].ConnectedAt)
+	})
+	byInstance := make(map[string]int)
+	byAccount := make(map[string]int)
+	for _, conn := range conns {
+		byInstance[conn.InstanceID]++
+		byAccount[conn.AccountID]++
+	}
 
-	for {
-		select {
-		case <-c.ctx.Done():
-			return
-		case <-ticker.C:
-			ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
-			err := c.conn.Ping(ctx)
-			cancel()
+	response := map[string]interface{}{
+		"instance_id": m.instanceID,
+		"total":       len(conns),
+		"instances":   byInstance,
+		"accounts":    byAccount,
+		"connections": conns,
+	}
 
-			if err != nil {
-				c.logger.Error("Failed to ping client", zap.Error(err))
-				c.close()
-				return
-			}
-		}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		m.logger.Error("Failed to encode connections response", zap.Error(err))
 	}
 }
 
-// close gracefully closes the client connection
-func (c *Client) close() {
-	// Cancel context to stop all goroutines
-	c.cancel()
+// HandleReady reports whether this instance can deliver notifications. It
+// responds 503 while NATS is disconnected, so orchestrators stop routing
+// clients to it.
+func (m *Manager) HandleReady(w http.ResponseWriter, r *http.Request) {
+	connected := m.nats.IsConnected()
+	status, code := "ready", http.StatusOK
+	if !connected {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
 
-	// Unsubscribe from NATS
-	if c.subscription != nil {
-		c.subscription.Unsubscribe()
+	response := map[string]interface{}{
+		"status":  status,
+		"service": "eventstream",
+		"checks": map[string]interface{}{
+			"nats": map[string]interface{}{"connected": connected},
+		},
+		"clients": m.GetClientCount(),
 	}
 
-	// Close send channel
-	close(c.send)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		m.logger.Error("Failed to encode ready response", zap.Error(err))
+	}
+}
 
-	// Close WebSocket connection
-	c.conn.Close(websocket.StatusNormalClosure, "")
+// OverflowedNotificationCount returns how many notifications were held back
+// because a client's queue was full
+func (m *Manager) OverflowedNotificationCount() int64 {
+	return m.overflowedNotifications.Load()
+}
 
-	// Remove from manager
-	c.manager.mu.Lock()
-	delete(c.manager.clients, c.id)
-	c.manager.mu.Unlock()
+// CoalescedNotificationCount returns how many notifications were merged into
+// a later one instead of being sent
+func (m *Manager) CoalescedNotificationCount() int64 {
+	return m.coalescedNotifications.Load()
 }
 
 // GetClientCount returns the number of connected clients
@@ -0,0 +1,580 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"nhooyr.io/websocket"
+
+	"github.com/tennex/eventstream/internal/registry"
+)
+
+// fakeSubscriber records NATS handlers so tests can publish without a server.
+// Published subjects reach the handlers of every subject pattern they match,
+// as they would on a server.
+type fakeSubscriber struct {
+	mu           sync.Mutex
+	handlers     map[string][]nats.MsgHandler
+	disconnected bool
+}
+
+func newFakeSubscriber() *fakeSubscriber {
+	return &fakeSubscriber{handlers: make(map[string][]nats.MsgHandler)}
+}
+
+func (f *fakeSubscriber) Subscribe(subject string, cb nats.MsgHandler) (*nats.Subscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[subject] = append(f.handlers[subject], cb)
+	return nil, nil
+}
+
+func (f *fakeSubscriber) IsConnected() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return !f.disconnected
+}
+
+func (f *fakeSubscriber) count(subject string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.handlers[subject])
+}
+
+func (f *fakeSubscriber) publish(t *testing.T, subject string, notification Notification) {
+	t.Helper()
+	data, err := json.Marshal(notification)
+	if err != nil {
+		t.Fatalf("failed to marshal notification: %v", err)
+	}
+	f.mu.Lock()
+	var handlers []nats.MsgHandler
+	for pattern, subscribed := range f.handlers {
+		if subjectMatches(pattern, subject) {
+			handlers = append(handlers, subscribed...)
+		}
+	}
+	f.mu.Unlock()
+	for _, handler := range handlers {
+		handler(&nats.Msg{Subject: subject, Data: data})
+	}
+}
+
+// subjectMatches reports whether subject matches a NATS subscription pattern,
+// where "*" matches one token and a trailing ">" matches the rest
+func subjectMatches(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+	for i, token := range patternTokens {
+		if token == ">" {
+			return len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) || (token != "*" && token != subjectTokens[i]) {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
+
+type frame struct {
+	Type           string   `json:"type"`
+	IntegrationID  int32    `json:"integration_id"`
+	ConversationID string   `json:"conversation_id"`
+	NextSeq        int64    `json:"next_seq"`
+	Conversations  []string `json:"conversations"`
+	Muted          bool     `json:"muted"`
+}
+
+func waitForSubscriptions(ctx context.Context, t *testing.T, subscriber *fakeSubscriber, subject string, want int) {
+	t.Helper()
+	for subscriber.count(subject) < want {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %d subscriptions on %s", want, subject)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func dial(ctx context.Context, t *testing.T, serverURL, accountID string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(serverURL, "http") + "/ws?account_id=" + accountID
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close(websocket.StatusNormalClosure, "") })
+	return conn
+}
+
+func readFrame(ctx context.Context, t *testing.T, conn *websocket.Conn) frame {
+	t.Helper()
+	_, data, err := conn.Read(ctx)
+	if err != nil {
+		t.Fatalf("failed to read frame: %v", err)
+	}
+	var f frame
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatalf("failed to unmarshal frame %s: %v", data, err)
+	}
+	return f
+}
+
+func writeFrame(ctx context.Context, t *testing.T, conn *websocket.Conn, msg clientMessage) {
+	t.Helper()
+	data, _ := json.Marshal(msg)
+	if err := conn.Write(ctx, websocket.MessageText, data); err != nil {
+		t.Fatalf("failed to write frame: %v", err)
+	}
+}
+
+func TestConversationFilter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	subscriber := newFakeSubscriber()
+	manager := NewManager(subscriber, "", Config{}, zap.NewNop())
+	server := httptest.NewServer(http.HandlerFunc(manager.HandleWebSocket))
+	defer server.Close()
+
+	const accountID = "account-1"
+	const subject = "notify.user." + accountID + ".integration.1"
+
+	filtered := dial(ctx, t, server.URL, accountID)
+	unfiltered := dial(ctx, t, server.URL, accountID)
+
+	writeFrame(ctx, t, filtered, clientMessage{Type: "subscribe", Conversations: []string{"convo-a", "convo-c"}})
+	if ack := readFrame(ctx, t, filtered); ack.Type != "subscriptions" || len(ack.Conversations) != 2 {
+		t.Fatalf("expected subscriptions ack for 2 conversations, got %+v", ack)
+	}
+	writeFrame(ctx, t, filtered, clientMessage{Type: "unsubscribe", Conversations: []string{"convo-c"}})
+	if ack := readFrame(ctx, t, filtered); len(ack.Conversations) != 1 || ack.Conversations[0] != "convo-a" {
+		t.Fatalf("expected filter [convo-a] after unsubscribe, got %+v", ack)
+	}
+
+	waitForSubscriptions(ctx, t, subscriber, "notify.user."+accountID+".integration.*", 2)
+
+	subscriber.publish(t, subject, Notification{AccountID: accountID, ConversationID: "convo-a", NextSeq: 1})
+	subscriber.publish(t, subject, Notification{AccountID: accountID, ConversationID: "convo-b", NextSeq: 2})
+	subscriber.publish(t, subject, Notification{AccountID: accountID, ConversationID: "convo-c", NextSeq: 3})
+
+	for _, want := range []int64{1, 2, 3} {
+		if got := readFrame(ctx, t, unfiltered); got.Type != "notification" || got.NextSeq != want {
+			t.Fatalf("unfiltered client: expected notification %d, got %+v", want, got)
+		}
+	}
+
+	got := readFrame(ctx, t, filtered)
+	if got.Type != "notification" || got.NextSeq != 1 || got.ConversationID != "convo-a" {
+		t.Fatalf("filtered client: expected notification 1 for convo-a, got %+v", got)
+	}
+
+	// Nothing else should reach the filtered client
+	readCtx, readCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer readCancel()
+	if _, data, err := filtered.Read(readCtx); err == nil {
+		t.Fatalf("filtered client received unexpected frame %s", data)
+	}
+}
+
+func TestSlowConsumerIsNotDropped(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	subscriber := newFakeSubscriber()
+	manager := NewManager(subscriber, "", Config{QueueSize: 4}, zap.NewNop())
+	server := httptest.NewServer(http.HandlerFunc(manager.HandleWebSocket))
+	defer server.Close()
+
+	const accountID = "account-1"
+	const subject = "notify.user." + accountID + ".integration.1"
+
+	conn := dial(ctx, t, server.URL, accountID)
+	waitForSubscriptions(ctx, t, subscriber, "notify.user."+accountID+".integration.*", 1)
+
+	// The client doesn't read, so socket buffers fill up and the queue overflows
+	seq := int64(0)
+	for manager.OverflowedNotificationCount() < 100 {
+		seq++
+		if seq > 5_000_000 {
+			t.Fatal("queue never overflowed")
+		}
+		subscriber.publish(t, subject, Notification{AccountID: accountID, NextSeq: seq})
+	}
+
+	// Once it reads again, notifications arrive in order and end with the
+	// latest, with the overflowed ones merged into it
+	lastSeq := int64(0)
+	frames := 0
+	for lastSeq < seq {
+		f := readFrame(ctx, t, conn)
+		if f.Type != "notification" || f.NextSeq <= lastSeq {
+			t.Fatalf("expected a notification after %d, got %+v", lastSeq, f)
+		}
+		lastSeq = f.NextSeq
+		frames++
+	}
+	if lastSeq != seq {
+		t.Fatalf("expected to end with notification %d, got %d", seq, lastSeq)
+	}
+	if int64(frames) >= seq {
+		t.Fatalf("expected overflowed notifications to be merged, got all %d", seq)
+	}
+
+	// The client stays connected and keeps receiving notifications
+	if manager.GetClientCount() != 1 {
+		t.Fatalf("expected the client to stay connected, got %d clients", manager.GetClientCount())
+	}
+	subscriber.publish(t, subject, Notification{AccountID: accountID, NextSeq: seq + 1})
+	if f := readFrame(ctx, t, conn); f.NextSeq != seq+1 {
+		t.Fatalf("expected notification %d, got %+v", seq+1, f)
+	}
+}
+
+func TestConnectionLimitAndRegistryAcrossInstances(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	mr := miniredis.RunT(t)
+	newInstance := func(instanceID string) (*Manager, *httptest.Server) {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+
+		manager := NewManager(newFakeSubscriber(), "", Config{
+			MaxConnectionsPerAccount: 2,
+			InstanceID:               instanceID,
+			Registry:                 registry.NewRedisRegistry(client, time.Minute),
+		}, zap.NewNop())
+		mux := http.NewServeMux()
+		mux.HandleFunc("/ws", manager.HandleWebSocket)
+		mux.HandleFunc("/debug/connections", manager.HandleDebugConnections)
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+		return manager, server
+	}
+	_, serverA := newInstance("instance-a")
+	_, serverB := newInstance("instance-b")
+
+	dial(ctx, t, serverA.URL, "account-1")
+	second := dial(ctx, t, serverB.URL, "account-1")
+	dial(ctx, t, serverB.URL, "account-2")
+
+	// A third connection for account-1 is rejected on either instance
+	expectTooManyConnections(ctx, t, serverA.URL, "account-1")
+
+	var debug struct {
+		Total     int            `json:"total"`
+		Instances map[string]int `json:"instances"`
+		Accounts  map[string]int `json:"accounts"`
+	}
+	getDebug := func() {
+		t.Helper()
+		resp, err := http.Get(serverA.URL + "/debug/connections")
+		if err != nil {
+			t.Fatalf("GET /debug/connections: %v", err)
+		}
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(&debug); err != nil {
+			t.Fatalf("failed to decode /debug/connections: %v", err)
+		}
+	}
+
+	getDebug()
+	if debug.Total != 3 || debug.Instances["instance-a"] != 1 || debug.Instances["instance-b"] != 2 ||
+		debug.Accounts["account-1"] != 2 || debug.Accounts["account-2"] != 1 {
+		t.Fatalf("unexpected /debug/connections response: %+v", debug)
+	}
+
+	// Disconnecting frees the slot for a new connection
+	second.Close(websocket.StatusNormalClosure, "")
+	for {
+		getDebug()
+		if debug.Accounts["account-1"] == 1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for disconnect to be unregistered: %+v", debug)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	dial(ctx, t, serverA.URL, "account-1")
+}
+
+// expectTooManyConnections dials and expects the connection to be closed for
+// being over the account's limit
+func expectTooManyConnections(ctx context.Context, t *testing.T, serverURL, accountID string) {
+	t.Helper()
+	conn := dial(ctx, t, serverURL, accountID)
+	_, _, err := conn.Read(ctx)
+	if status := websocket.CloseStatus(err); status != CloseCodeTooManyConnections {
+		t.Fatalf("expected close code %d, got %d (%v)", CloseCodeTooManyConnections, status, err)
+	}
+}
+
+// failingRegistry fails every call, like a shared registry that is down
+type failingRegistry struct{}
+
+func (failingRegistry) Register(ctx context.Context, conn registry.Connection, limit int) error {
+	return errors.New("registry down")
+}
+
+func (failingRegistry) Unregister(ctx context.Context, conn registry.Connection) error {
+	return errors.New("registry down")
+}
+
+func (failingRegistry) Refresh(ctx context.Context, conns []registry.Connection) error {
+	return errors.New("registry down")
+}
+
+func (failingRegistry) List(ctx context.Context) ([]registry.Connection, error) {
+	return nil, errors.New("registry down")
+}
+
+func TestConnectionLimitWithoutRegistry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	manager := NewManager(newFakeSubscriber(), "", Config{
+		MaxConnectionsPerAccount: 1,
+		Registry:                 failingRegistry{},
+	}, zap.NewNop())
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", manager.HandleWebSocket)
+	mux.HandleFunc("/sse", manager.HandleSSE)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// The instance's own count enforces the limit while the registry is down
+	first := dial(ctx, t, server.URL, "account-1")
+	expectTooManyConnections(ctx, t, server.URL, "account-1")
+	dial(ctx, t, server.URL, "account-2")
+
+	// SSE can't carry a close code and gets a plain 429
+	resp, err := http.Get(server.URL + "/sse?account_id=account-1")
+	if err != nil {
+		t.Fatalf("GET /sse: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for SSE, got %d", resp.StatusCode)
+	}
+
+	// Disconnecting frees the slot
+	first.Close(websocket.StatusNormalClosure, "")
+	for {
+		manager.mu.RLock()
+		admitted := manager.accountClients["account-1"]
+		manager.mu.RUnlock()
+		if admitted == 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for disconnect")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	dial(ctx, t, server.URL, "account-1")
+}
+
+func TestSubjectPrefix(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	subscriber := newFakeSubscriber()
+	manager := NewManager(subscriber, "", Config{SubjectPrefix: "tennex.staging"}, zap.NewNop())
+	server := httptest.NewServer(http.HandlerFunc(manager.HandleWebSocket))
+	defer server.Close()
+
+	conn := dial(ctx, t, server.URL, "account-1")
+
+	// Only the prefixed subject is subscribed, so other environments don't cross-talk
+	waitForSubscriptions(ctx, t, subscriber, "tennex.staging.notify.user.account-1.integration.*", 1)
+	if n := subscriber.count("notify.user.account-1.integration.*"); n != 0 {
+		t.Fatalf("expected no subscriptions on the unprefixed subject, got %d", n)
+	}
+
+	subscriber.publish(t, "tennex.staging.notify.user.account-1.integration.1", Notification{AccountID: "account-1", NextSeq: 7})
+	if got := readFrame(ctx, t, conn); got.Type != "notification" || got.NextSeq != 7 {
+		t.Fatalf("expected notification 7, got %+v", got)
+	}
+}
+
+func TestNotificationsFromEveryIntegration(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	subscriber := newFakeSubscriber()
+	manager := NewManager(subscriber, "", Config{}, zap.NewNop())
+	server := httptest.NewServer(http.HandlerFunc(manager.HandleWebSocket))
+	defer server.Close()
+
+	conn := dial(ctx, t, server.URL, "account-1")
+	waitForSubscriptions(ctx, t, subscriber, "notify.user.account-1.integration.*", 1)
+
+	// Another account's notifications and the legacy subject don't match
+	subscriber.publish(t, "notify.user.account-2.integration.3", Notification{AccountID: "account-2", IntegrationID: 3, NextSeq: 1})
+	subscriber.publish(t, "notify.account.account-1", Notification{AccountID: "account-1", IntegrationID: 3, NextSeq: 2})
+
+	subscriber.publish(t, "notify.user.account-1.integration.3", Notification{AccountID: "account-1", IntegrationID: 3, NextSeq: 3})
+	subscriber.publish(t, "notify.user.account-1.integration.8", Notification{AccountID: "account-1", IntegrationID: 8, NextSeq: 4})
+	subscriber.publish(t, "notify.user.account-1.integration.0", Notification{AccountID: "account-1", NextSeq: 5})
+
+	for _, want := range []frame{
+		{Type: "notification", IntegrationID: 3, NextSeq: 3},
+		{Type: "notification", IntegrationID: 8, NextSeq: 4},
+		{Type: "notification", NextSeq: 5},
+	} {
+		if got := readFrame(ctx, t, conn); got.Type != want.Type || got.IntegrationID != want.IntegrationID || got.NextSeq != want.NextSeq {
+			t.Fatalf("expected %+v, got %+v", want, got)
+		}
+	}
+}
+
+func TestNotificationCoalescing(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	subscriber := newFakeSubscriber()
+	manager := NewManager(subscriber, "", Config{NotificationInterval: 100 * time.Millisecond}, zap.NewNop())
+	server := httptest.NewServer(http.HandlerFunc(manager.HandleWebSocket))
+	defer server.Close()
+
+	const subject = "notify.user.account-1.integration.1"
+	conn := dial(ctx, t, server.URL, "account-1")
+	waitForSubscriptions(ctx, t, subscriber, "notify.user.account-1.integration.*", 1)
+
+	// The first notification goes out at once; the burst after it becomes one
+	// frame with the latest seq
+	started := time.Now()
+	for seq := int64(1); seq <= 50; seq++ {
+		conversationID := "convo-a"
+		if seq%2 == 0 {
+			conversationID = "convo-b"
+		}
+		subscriber.publish(t, subject, Notification{AccountID: "account-1", IntegrationID: 1, ConversationID: conversationID, NextSeq: seq})
+	}
+
+	if got := readFrame(ctx, t, conn); got.NextSeq != 1 || got.ConversationID != "convo-a" {
+		t.Fatalf("expected the first notification at once, got %+v", got)
+	}
+	got := readFrame(ctx, t, conn)
+	if got.Type != "notification" || got.NextSeq != 50 || got.IntegrationID != 1 {
+		t.Fatalf("expected one coalesced notification for seq 50, got %+v", got)
+	}
+	if got.ConversationID != "" {
+		t.Errorf("expected no conversation on a notification for several, got %q", got.ConversationID)
+	}
+	if elapsed := time.Since(started); elapsed < 100*time.Millisecond {
+		t.Errorf("expected the coalesced notification after the interval, got it after %v", elapsed)
+	}
+	if n := manager.CoalescedNotificationCount(); n != 48 {
+		t.Errorf("CoalescedNotificationCount = %d, want 48", n)
+	}
+
+	// Once the interval has passed, the next notification goes out at once
+	time.Sleep(150 * time.Millisecond)
+	subscriber.publish(t, subject, Notification{AccountID: "account-1", IntegrationID: 1, ConversationID: "convo-a", NextSeq: 51})
+	readCtx, readCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer readCancel()
+	if got := readFrame(readCtx, t, conn); got.NextSeq != 51 || got.ConversationID != "convo-a" {
+		t.Fatalf("expected notification 51 at once, got %+v", got)
+	}
+}
+
+func TestMutedNotifications(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	subscriber := newFakeSubscriber()
+	manager := NewManager(subscriber, "", Config{}, zap.NewNop())
+	server := httptest.NewServer(http.HandlerFunc(manager.HandleWebSocket))
+	defer server.Close()
+
+	const subject = "notify.user.account-1.integration.1"
+	conn := dial(ctx, t, server.URL, "account-1")
+	waitForSubscriptions(ctx, t, subscriber, "notify.user.account-1.integration.*", 1)
+
+	// Muted conversations are still notified, flagged so clients don't alert
+	subscriber.publish(t, subject, Notification{AccountID: "account-1", IntegrationID: 1, ConversationID: "convo-a", NextSeq: 1, Muted: true})
+	subscriber.publish(t, subject, Notification{AccountID: "account-1", IntegrationID: 1, ConversationID: "convo-b", NextSeq: 2})
+	for _, want := range []frame{
+		{ConversationID: "convo-a", NextSeq: 1, Muted: true},
+		{ConversationID: "convo-b", NextSeq: 2},
+	} {
+		if got := readFrame(ctx, t, conn); got.ConversationID != want.ConversationID || got.NextSeq != want.NextSeq || got.Muted != want.Muted {
+			t.Fatalf("expected %+v, got %+v", want, got)
+		}
+	}
+}
+
+func TestNotificationMergeMuted(t *testing.T) {
+	muted := Notification{ConversationID: "convo-a", NextSeq: 1, Muted: true}
+	muted.merge(Notification{ConversationID: "convo-b", NextSeq: 2, Muted: true})
+	if !muted.Muted {
+		t.Error("expected notifications that were all muted to stay muted")
+	}
+
+	// One unmuted notification in the batch is enough to alert
+	muted.merge(Notification{ConversationID: "convo-c", NextSeq: 3})
+	if muted.Muted {
+		t.Error("expected a merge with an unmuted notification to be unmuted")
+	}
+}
+
+func TestHandleReady(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	subscriber := newFakeSubscriber()
+	manager := NewManager(subscriber, "", Config{}, zap.NewNop())
+	server := httptest.NewServer(http.HandlerFunc(manager.HandleWebSocket))
+	defer server.Close()
+
+	dial(ctx, t, server.URL, "account-1")
+	waitForSubscriptions(ctx, t, subscriber, "notify.user.account-1.integration.*", 1)
+
+	ready := func() (int, map[string]interface{}) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		manager.HandleReady(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to decode ready response: %v", err)
+		}
+		return rec.Code, body
+	}
+
+	code, body := ready()
+	if code != http.StatusOK || body["status"] != "ready" {
+		t.Fatalf("expected 200 ready, got %d %v", code, body)
+	}
+	if body["clients"] != float64(1) {
+		t.Errorf("expected 1 client, got %v", body["clients"])
+	}
+
+	subscriber.mu.Lock()
+	subscriber.disconnected = true
+	subscriber.mu.Unlock()
+
+	code, body = ready()
+	if code != http.StatusServiceUnavailable || body["status"] != "unavailable" {
+		t.Fatalf("expected 503 while NATS is down, got %d %v", code, body)
+	}
+	natsCheck := body["checks"].(map[string]interface{})["nats"].(map[string]interface{})
+	if natsCheck["connected"] != false {
+		t.Errorf("expected nats.connected false, got %v", natsCheck["connected"])
+	}
+}
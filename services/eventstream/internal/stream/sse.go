@@ -0,0 +1,123 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"nhooyr.io/websocket"
+
+	"github.com/tennex/pkg/requestid"
+)
+
+var errTransportClosed = errors.New("transport closed")
+
+// sseTransport writes frames as Server-Sent Events. Notifications carry their
+// seq as the event ID, so EventSource reconnects send it back as Last-Event-ID.
+type sseTransport struct {
+	mu         sync.Mutex
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	closed     bool
+}
+
+// HandleSSE streams notifications as Server-Sent Events, for clients whose
+// network blocks WebSocket upgrades. SSE is one-way, so the conversation filter
+// is given as repeated conversation query parameters.
+func (m *Manager) HandleSSE(w http.ResponseWriter, r *http.Request) {
+	registration, err := m.admit(r)
+	if err != nil {
+		rejectHTTP(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+
+	transport := &sseTransport{w: w, controller: http.NewResponseController(w)}
+	if err := transport.controller.Flush(); err != nil {
+		m.logger.Error("SSE streaming not supported", zap.Error(err))
+		m.unregister(registration)
+		return
+	}
+
+	client := m.createClient(registration, requestid.FromContext(r.Context()), transport, r.URL.Query()["conversation"])
+
+	// Resume hints start from the last notification the client saw before reconnecting
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		if seq, err := strconv.ParseInt(lastEventID, 10, 64); err == nil {
+			client.deliveredSeq = seq
+		}
+	}
+
+	m.logger.Info("SSE client connected",
+		zap.String("client_id", client.id),
+		zap.String("account_id", registration.AccountID),
+		zap.Int64("last_event_id", client.deliveredSeq))
+
+	go client.writePump()
+
+	// Wait for either side to disconnect
+	select {
+	case <-r.Context().Done():
+		client.close()
+	case <-client.ctx.Done():
+	}
+
+	m.logger.Info("SSE client disconnected",
+		zap.String("client_id", client.id),
+		zap.String("account_id", registration.AccountID))
+}
+
+// Write sends a frame as an SSE data event
+func (t *sseTransport) Write(ctx context.Context, frame outboundFrame) error {
+	var event []byte
+	if frame.seq > 0 {
+		event = fmt.Appendf(event, "id: %d\n", frame.seq)
+	}
+	event = fmt.Appendf(event, "data: %s\n\n", frame.data)
+	return t.write(ctx, event)
+}
+
+// Ping sends an SSE comment, which clients ignore
+func (t *sseTransport) Ping(ctx context.Context) error {
+	return t.write(ctx, []byte(": ping\n\n"))
+}
+
+// Close stops further writes; the handler then ends the response.
+// SSE has no close codes, so code and reason are ignored.
+func (t *sseTransport) Close(code websocket.StatusCode, reason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+}
+
+func (t *sseTransport) write(ctx context.Context, data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return errTransportClosed
+	}
+
+	// Bound writes to slow clients; not every ResponseWriter supports deadlines
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := t.controller.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		defer t.controller.SetWriteDeadline(time.Time{})
+	}
+
+	if _, err := t.w.Write(data); err != nil {
+		return err
+	}
+	return t.controller.Flush()
+}
@@ -0,0 +1,138 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// flushRecorder is a ResponseWriter that records flushed output and is safe to
+// read while the handler is still writing
+type flushRecorder struct {
+	mu      sync.Mutex
+	header  http.Header
+	status  int
+	body    bytes.Buffer
+	flushed int
+}
+
+func newFlushRecorder() *flushRecorder {
+	return &flushRecorder{header: make(http.Header)}
+}
+
+func (r *flushRecorder) Header() http.Header { return r.header }
+
+func (r *flushRecorder) WriteHeader(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func (r *flushRecorder) Write(data []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.body.Write(data)
+}
+
+func (r *flushRecorder) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flushed++
+}
+
+func (r *flushRecorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.body.String()
+}
+
+func waitForBody(ctx context.Context, t *testing.T, rec *flushRecorder, want string) {
+	t.Helper()
+	for !strings.Contains(rec.String(), want) {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %q, got %q", want, rec.String())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestSSEStreamsNotificationsWithEventIDs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	subscriber := newFakeSubscriber()
+	manager := NewManager(subscriber, "", Config{}, zap.NewNop())
+
+	reqCtx, disconnect := context.WithCancel(ctx)
+	defer disconnect()
+	req := httptest.NewRequest(http.MethodGet, "/events?account_id=acct-1&conversation=convo-a", nil).WithContext(reqCtx)
+	req.Header.Set("Last-Event-ID", "3")
+	rec := newFlushRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		manager.HandleSSE(rec, req)
+	}()
+
+	subject := "notify.user.acct-1.integration.1"
+	waitForSubscriptions(ctx, t, subscriber, "notify.user.acct-1.integration.*", 1)
+
+	subscriber.publish(t, subject, Notification{AccountID: "acct-1", ConversationID: "convo-b", NextSeq: 4})
+	subscriber.publish(t, subject, Notification{AccountID: "acct-1", ConversationID: "convo-a", NextSeq: 5})
+	waitForBody(ctx, t, rec, "id: 5\n")
+
+	body := rec.String()
+	want := "id: 5\ndata: {\"conversation_id\":\"convo-a\",\"next_seq\":5,\"type\":\"notification\"}\n\n"
+	if body != want {
+		t.Fatalf("unexpected body:\n got %q\nwant %q", body, want)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("expected text/event-stream content type, got %q", got)
+	}
+
+	rec.mu.Lock()
+	status, flushed := rec.status, rec.flushed
+	rec.mu.Unlock()
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	if flushed < 2 {
+		t.Fatalf("expected headers and event to be flushed, got %d flushes", flushed)
+	}
+
+	clients := manager.GetClientsByAccount("acct-1")
+	if len(clients) != 1 {
+		t.Fatalf("expected 1 client, got %d", len(clients))
+	}
+
+	// Disconnecting ends the handler and removes the client
+	disconnect()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatal("handler did not return after the request was cancelled")
+	}
+	if count := manager.GetClientCount(); count != 0 {
+		t.Fatalf("expected no clients after disconnect, got %d", count)
+	}
+}
+
+func TestSSERequiresAccountID(t *testing.T) {
+	manager := NewManager(newFakeSubscriber(), "", Config{}, zap.NewNop())
+
+	rec := httptest.NewRecorder()
+	manager.HandleSSE(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,107 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+	"nhooyr.io/websocket"
+
+	"github.com/tennex/eventstream/internal/registry"
+	"github.com/tennex/pkg/requestid"
+)
+
+// wsTransport writes frames to a WebSocket connection
+type wsTransport struct {
+	conn *websocket.Conn
+}
+
+// HandleWebSocket handles incoming WebSocket connections
+func (m *Manager) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// Record the connection before upgrading so the account limit can be enforced
+	registration, err := m.admit(r)
+	overLimit := errors.Is(err, registry.ErrLimitExceeded)
+	if err != nil && !overLimit {
+		rejectHTTP(w, err)
+		return
+	}
+
+	// Accept WebSocket connection
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		OriginPatterns: []string{"*"}, // Allow all origins in development
+	})
+	if err != nil {
+		m.logger.Error("Failed to accept WebSocket connection", zap.Error(err))
+		if !overLimit {
+			m.unregister(registration)
+		}
+		return
+	}
+
+	// Browsers can't see the status of a failed upgrade, so clients over the
+	// limit are told with a close code instead
+	if overLimit {
+		conn.Close(CloseCodeTooManyConnections, closeReason("too many connections for account", requestid.FromContext(r.Context())))
+		return
+	}
+
+	// Create client
+	transport := &wsTransport{conn: conn}
+	client := m.createClient(registration, requestid.FromContext(r.Context()), transport, nil)
+
+	m.logger.Info("WebSocket client connected",
+		zap.String("client_id", client.id),
+		zap.String("account_id", registration.AccountID))
+
+	// Start client goroutines
+	go client.writePump()
+	go transport.readPump(client)
+
+	// Wait for client to disconnect
+	<-client.ctx.Done()
+
+	m.logger.Info("WebSocket client disconnected",
+		zap.String("client_id", client.id),
+		zap.String("account_id", registration.AccountID))
+}
+
+// Write sends a frame as a text message
+func (t *wsTransport) Write(ctx context.Context, frame outboundFrame) error {
+	return t.conn.Write(ctx, websocket.MessageText, frame.data)
+}
+
+// Ping sends a WebSocket ping and waits for the pong
+func (t *wsTransport) Ping(ctx context.Context) error {
+	return t.conn.Ping(ctx)
+}
+
+// Close closes the connection with the given close code
+func (t *wsTransport) Close(code websocket.StatusCode, reason string) {
+	t.conn.Close(code, reason)
+}
+
+// readPump reads control messages from the WebSocket connection
+func (t *wsTransport) readPump(c *Client) {
+	defer c.close()
+
+	// Set read limit
+	t.conn.SetReadLimit(maxMessageSize)
+
+	for {
+		_, message, err := t.conn.Read(c.ctx)
+		if err != nil {
+			if websocket.CloseStatus(err) == websocket.StatusNormalClosure {
+				c.logger.Debug("Client closed connection normally")
+			} else {
+				c.logger.Error("Failed to read message", zap.Error(err))
+			}
+			return
+		}
+
+		c.logger.Debug("Received message from client",
+			zap.String("message", string(message)))
+
+		c.handleClientMessage(message)
+	}
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authorizationMetadataKey is the gRPC metadata key carrying the service token
+const authorizationMetadataKey = "authorization"
+
+// TokenUnaryServerInterceptor rejects unary calls that don't carry the shared service token
+// as a Bearer authorization header
+func TokenUnaryServerInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := checkServiceToken(ctx, token); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func checkServiceToken(ctx context.Context, token string) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, ErrMissingToken.Message)
+	}
+
+	values := md.Get(authorizationMetadataKey)
+	if len(values) == 0 {
+		return status.Error(codes.Unauthenticated, ErrMissingToken.Message)
+	}
+
+	provided, err := ExtractTokenFromHeader(values[0])
+	if err != nil {
+		return status.Error(codes.Unauthenticated, ErrMissingToken.Message)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+		return status.Error(codes.Unauthenticated, ErrInvalidToken.Message)
+	}
+	return nil
+}
+
+// ServiceTokenCredentials attaches the shared service token to every outgoing gRPC call
+type ServiceTokenCredentials struct {
+	Token string
+	// AllowInsecure permits sending the token over plaintext connections (internal networks only)
+	AllowInsecure bool
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials
+func (c ServiceTokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		authorizationMetadataKey: "Bearer " + c.Token,
+	}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials
+func (c ServiceTokenCredentials) RequireTransportSecurity() bool {
+	return !c.AllowInsecure
+}
+
+var _ credentials.PerRPCCredentials = ServiceTokenCredentials{}
@@ -19,16 +19,30 @@ func min(a, b int) int {
 	return b
 }
 
+// User roles carried in the JWT role claim
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 // Claims represents the JWT token claims
 type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
+	Role   string    `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// RevocationCheck returns an error if the session of a validly signed token
+// has since been ended, such as by a password reset
+type RevocationCheck func(ctx context.Context, claims *Claims) error
+
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
 	Secret []byte
 	TTL    time.Duration
+
+	// Optional; when set, tokens it rejects fail validation
+	RevocationCheck RevocationCheck
 }
 
 // NewJWTConfig creates a new JWT configuration
@@ -44,9 +58,9 @@ func DefaultJWTConfig(secret string) *JWTConfig {
 	return NewJWTConfig(secret, 24*time.Hour)
 }
 
-// GenerateToken generates a new JWT token for the given user ID
-func (c *JWTConfig) GenerateToken(userID uuid.UUID) (string, time.Time, error) {
-	fmt.Printf("🔥 [TOKEN GEN DEBUG] Generating token for user: %s\n", userID.String())
+// GenerateToken generates a new JWT token for the given user ID and role
+func (c *JWTConfig) GenerateToken(userID uuid.UUID, role string) (string, time.Time, error) {
+	fmt.Printf("🔥 [TOKEN GEN DEBUG] Generating token for user: %s (role: %s)\n", userID.String(), role)
 
 	now := time.Now()
 	expirationTime := now.Add(c.TTL)
@@ -57,6 +71,7 @@ func (c *JWTConfig) GenerateToken(userID uuid.UUID) (string, time.Time, error) {
 
 	claims := &Claims{
 		UserID: userID,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -83,6 +98,12 @@ func (c *JWTConfig) GenerateToken(userID uuid.UUID) (string, time.Time, error) {
 
 // ValidateToken validates and parses a JWT token, returning the claims
 func (c *JWTConfig) ValidateToken(tokenString string) (*Claims, error) {
+	return c.ValidateTokenContext(context.Background(), tokenString)
+}
+
+// ValidateTokenContext validates and parses a JWT token like ValidateToken,
+// passing ctx to the revocation check
+func (c *JWTConfig) ValidateTokenContext(ctx context.Context, tokenString string) (*Claims, error) {
 	fmt.Printf("🔍 [TOKEN DEBUG] Starting token validation\n")
 	fmt.Printf("🔍 [TOKEN DEBUG] Token string length: %d\n", len(tokenString))
 
@@ -124,6 +145,12 @@ func (c *JWTConfig) ValidateToken(tokenString string) (*Claims, error) {
 		fmt.Printf("🔍 [TOKEN DEBUG] Issued at: %v\n", claims.IssuedAt.Time)
 	}
 
+	if c.RevocationCheck != nil {
+		if err := c.RevocationCheck(ctx, claims); err != nil {
+			return nil, fmt.Errorf("token revoked: %w", err)
+		}
+	}
+
 	return claims, nil
 }
 
@@ -197,4 +224,9 @@ var (
 		Message: "Token has expired",
 		Status:  http.StatusUnauthorized,
 	}
+	ErrForbidden = &AuthError{
+		Code:    "forbidden",
+		Message: "Insufficient permissions",
+		Status:  http.StatusForbidden,
+	}
 )
@@ -30,7 +30,7 @@ func (c *JWTConfig) ChiMiddleware() func(http.Handler) http.Handler {
 			fmt.Printf("🔐 [JWT DEBUG] Token (first 50 chars): '%s...'\n", tokenString[:min(50, len(tokenString))])
 
 			// Validate token
-			claims, err := c.ValidateToken(tokenString)
+			claims, err := c.ValidateTokenContext(r.Context(), tokenString)
 			if err != nil {
 				fmt.Printf("🔐 [JWT DEBUG] ❌ Token validation failed: %v\n", err)
 				writeAuthError(w, ErrInvalidToken)
@@ -67,7 +67,7 @@ func (c *JWTConfig) OptionalChiMiddleware() func(http.Handler) http.Handler {
 			}
 
 			// Validate token if provided
-			claims, err := c.ValidateToken(tokenString)
+			claims, err := c.ValidateTokenContext(r.Context(), tokenString)
 			if err != nil {
 				// Invalid token, continue without authentication
 				next.ServeHTTP(w, r)
@@ -101,6 +101,26 @@ func RequireAuth() func(http.Handler) http.Handler {
 	}
 }
 
+// RequireRole is a Chi middleware that only lets through requests whose JWT
+// carries the given role. It must run after ChiMiddleware.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := GetClaimsFromContext(r.Context())
+			if err != nil {
+				writeAuthError(w, ErrMissingToken)
+				return
+			}
+			if claims.Role != role {
+				writeAuthError(w, ErrForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // writeAuthError writes an authentication error response
 func writeAuthError(w http.ResponseWriter, authErr *AuthError) {
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrSessionRevoked is returned for a token issued before its user's password
+// was reset
+var ErrSessionRevoked = errors.New("session revoked by a password reset")
+
+// PasswordChangedAtFunc returns when a user's password was last reset, or the
+// zero time if it never was
+type PasswordChangedAtFunc func(ctx context.Context, userID uuid.UUID) (time.Time, error)
+
+// RevokeSessionsBeforePasswordReset returns a token revocation check that
+// rejects the tokens issued before their user's last password reset, so a
+// reset signs out every existing session. A token whose user can't be looked
+// up is rejected too.
+func RevokeSessionsBeforePasswordReset(passwordChangedAt PasswordChangedAtFunc) RevocationCheck {
+	return func(ctx context.Context, claims *Claims) error {
+		changedAt, err := passwordChangedAt(ctx, claims.UserID)
+		if err != nil {
+			return err
+		}
+		if changedAt.IsZero() {
+			return nil
+		}
+		// Tokens carry their issue time in whole seconds, rounded down, so
+		// one issued in the second of the reset but after it is refused
+		// too. Refusing it is the safe side of that second.
+		if claims.IssuedAt == nil || claims.IssuedAt.Before(changedAt) {
+			return ErrSessionRevoked
+		}
+		return nil
+	}
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRevokeSessionsBeforePasswordReset(t *testing.T) {
+	userID := uuid.New()
+	var changedAt time.Time
+	var lookupErr error
+	jwtConfig := DefaultJWTConfig("test-secret")
+	jwtConfig.RevocationCheck = RevokeSessionsBeforePasswordReset(func(ctx context.Context, id uuid.UUID) (time.Time, error) {
+		if id != userID {
+			t.Errorf("looked up user %s, want %s", id, userID)
+		}
+		return changedAt, lookupErr
+	})
+
+	preReset, _, err := jwtConfig.GenerateToken(userID, RoleUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwtConfig.ValidateToken(preReset); err != nil {
+		t.Fatalf("expected a token to be valid before any reset, got %v", err)
+	}
+
+	// The password is reset after the token was issued
+	changedAt = time.Now().Add(time.Second)
+	if _, err := jwtConfig.ValidateToken(preReset); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("got %v for a pre-reset token, want ErrSessionRevoked", err)
+	}
+
+	// Tokens issued after the reset are still valid
+	changedAt = time.Now().Add(-2 * time.Second)
+	postReset, _, err := jwtConfig.GenerateToken(userID, RoleUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwtConfig.ValidateToken(postReset); err != nil {
+		t.Errorf("expected a post-reset token to be valid, got %v", err)
+	}
+
+	// Users that can't be looked up, such as deactivated ones, are refused
+	lookupErr = errors.New("no rows in result set")
+	if _, err := jwtConfig.ValidateToken(postReset); !errors.Is(err, lookupErr) {
+		t.Errorf("got %v for a user that can't be looked up, want the lookup error", err)
+	}
+}
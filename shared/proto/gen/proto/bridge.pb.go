@@ -436,6 +436,84 @@ func (x *SendMessageResponse) GetError() string {
 	return ""
 }
 
+// Reported by a bridge after handling a SendMessageRequest taken from the
+// NATS outbox work queue
+type SendMessageResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientMsgUuid string                 `protobuf:"bytes,1,opt,name=client_msg_uuid,json=clientMsgUuid,proto3" json:"client_msg_uuid,omitempty"`
+	AccountId     string                 `protobuf:"bytes,2,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	Success       bool                   `protobuf:"varint,3,opt,name=success,proto3" json:"success,omitempty"`
+	WaMessageId   string                 `protobuf:"bytes,4,opt,name=wa_message_id,json=waMessageId,proto3" json:"wa_message_id,omitempty"` // WhatsApp-assigned message ID
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`                                  // Error message if success = false
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendMessageResult) Reset() {
+	*x = SendMessageResult{}
+	mi := &file_proto_bridge_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendMessageResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendMessageResult) ProtoMessage() {}
+
+func (x *SendMessageResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendMessageResult.ProtoReflect.Descriptor instead.
+func (*SendMessageResult) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SendMessageResult) GetClientMsgUuid() string {
+	if x != nil {
+		return x.ClientMsgUuid
+	}
+	return ""
+}
+
+func (x *SendMessageResult) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *SendMessageResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SendMessageResult) GetWaMessageId() string {
+	if x != nil {
+		return x.WaMessageId
+	}
+	return ""
+}
+
+func (x *SendMessageResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 type MessageContent struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Types that are valid to be assigned to Content:
@@ -452,7 +530,7 @@ type MessageContent struct {
 
 func (x *MessageContent) Reset() {
 	*x = MessageContent{}
-	mi := &file_proto_bridge_proto_msgTypes[5]
+	mi := &file_proto_bridge_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -464,7 +542,7 @@ func (x *MessageContent) String() string {
 func (*MessageContent) ProtoMessage() {}
 
 func (x *MessageContent) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_bridge_proto_msgTypes[5]
+	mi := &file_proto_bridge_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -477,7 +555,7 @@ func (x *MessageContent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MessageContent.ProtoReflect.Descriptor instead.
 func (*MessageContent) Descriptor() ([]byte, []int) {
-	return file_proto_bridge_proto_rawDescGZIP(), []int{5}
+	return file_proto_bridge_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *MessageContent) GetContent() isMessageContent_Content {
@@ -575,7 +653,7 @@ type TextContent struct {
 
 func (x *TextContent) Reset() {
 	*x = TextContent{}
-	mi := &file_proto_bridge_proto_msgTypes[6]
+	mi := &file_proto_bridge_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -587,7 +665,7 @@ func (x *TextContent) String() string {
 func (*TextContent) ProtoMessage() {}
 
 func (x *TextContent) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_bridge_proto_msgTypes[6]
+	mi := &file_proto_bridge_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -600,7 +678,7 @@ func (x *TextContent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TextContent.ProtoReflect.Descriptor instead.
 func (*TextContent) Descriptor() ([]byte, []int) {
-	return file_proto_bridge_proto_rawDescGZIP(), []int{6}
+	return file_proto_bridge_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *TextContent) GetText() string {
@@ -621,7 +699,7 @@ type ImageContent struct {
 
 func (x *ImageContent) Reset() {
 	*x = ImageContent{}
-	mi := &file_proto_bridge_proto_msgTypes[7]
+	mi := &file_proto_bridge_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -633,7 +711,7 @@ func (x *ImageContent) String() string {
 func (*ImageContent) ProtoMessage() {}
 
 func (x *ImageContent) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_bridge_proto_msgTypes[7]
+	mi := &file_proto_bridge_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -646,7 +724,7 @@ func (x *ImageContent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ImageContent.ProtoReflect.Descriptor instead.
 func (*ImageContent) Descriptor() ([]byte, []int) {
-	return file_proto_bridge_proto_rawDescGZIP(), []int{7}
+	return file_proto_bridge_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *ImageContent) GetData() []byte {
@@ -681,7 +759,7 @@ type AudioContent struct {
 
 func (x *AudioContent) Reset() {
 	*x = AudioContent{}
-	mi := &file_proto_bridge_proto_msgTypes[8]
+	mi := &file_proto_bridge_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -693,7 +771,7 @@ func (x *AudioContent) String() string {
 func (*AudioContent) ProtoMessage() {}
 
 func (x *AudioContent) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_bridge_proto_msgTypes[8]
+	mi := &file_proto_bridge_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -706,7 +784,7 @@ func (x *AudioContent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AudioContent.ProtoReflect.Descriptor instead.
 func (*AudioContent) Descriptor() ([]byte, []int) {
-	return file_proto_bridge_proto_rawDescGZIP(), []int{8}
+	return file_proto_bridge_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *AudioContent) GetData() []byte {
@@ -742,7 +820,7 @@ type VideoContent struct {
 
 func (x *VideoContent) Reset() {
 	*x = VideoContent{}
-	mi := &file_proto_bridge_proto_msgTypes[9]
+	mi := &file_proto_bridge_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -754,7 +832,7 @@ func (x *VideoContent) String() string {
 func (*VideoContent) ProtoMessage() {}
 
 func (x *VideoContent) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_bridge_proto_msgTypes[9]
+	mi := &file_proto_bridge_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -767,7 +845,7 @@ func (x *VideoContent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use VideoContent.ProtoReflect.Descriptor instead.
 func (*VideoContent) Descriptor() ([]byte, []int) {
-	return file_proto_bridge_proto_rawDescGZIP(), []int{9}
+	return file_proto_bridge_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *VideoContent) GetData() []byte {
@@ -809,7 +887,7 @@ type DocumentContent struct {
 
 func (x *DocumentContent) Reset() {
 	*x = DocumentContent{}
-	mi := &file_proto_bridge_proto_msgTypes[10]
+	mi := &file_proto_bridge_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -821,7 +899,7 @@ func (x *DocumentContent) String() string {
 func (*DocumentContent) ProtoMessage() {}
 
 func (x *DocumentContent) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_bridge_proto_msgTypes[10]
+	mi := &file_proto_bridge_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -834,7 +912,7 @@ func (x *DocumentContent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DocumentContent.ProtoReflect.Descriptor instead.
 func (*DocumentContent) Descriptor() ([]byte, []int) {
-	return file_proto_bridge_proto_rawDescGZIP(), []int{10}
+	return file_proto_bridge_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *DocumentContent) GetData() []byte {
@@ -858,29 +936,32 @@ func (x *DocumentContent) GetFilename() string {
 	return ""
 }
 
-// QR code generation
-type GetQRCodeRequest struct {
+// Mark messages as read
+type MarkReadRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	AccountId     string                 `protobuf:"bytes,1,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	ConvoId       string                 `protobuf:"bytes,2,opt,name=convo_id,json=convoId,proto3" json:"convo_id,omitempty"`
+	SenderJid     string                 `protobuf:"bytes,3,opt,name=sender_jid,json=senderJid,proto3" json:"sender_jid,omitempty"` // Required for group chats
+	WaMessageIds  []string               `protobuf:"bytes,4,rep,name=wa_message_ids,json=waMessageIds,proto3" json:"wa_message_ids,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetQRCodeRequest) Reset() {
-	*x = GetQRCodeRequest{}
-	mi := &file_proto_bridge_proto_msgTypes[11]
+func (x *MarkReadRequest) Reset() {
+	*x = MarkReadRequest{}
+	mi := &file_proto_bridge_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetQRCodeRequest) String() string {
+func (x *MarkReadRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetQRCodeRequest) ProtoMessage() {}
+func (*MarkReadRequest) ProtoMessage() {}
 
-func (x *GetQRCodeRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_bridge_proto_msgTypes[11]
+func (x *MarkReadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -891,42 +972,62 @@ func (x *GetQRCodeRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetQRCodeRequest.ProtoReflect.Descriptor instead.
-func (*GetQRCodeRequest) Descriptor() ([]byte, []int) {
-	return file_proto_bridge_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use MarkReadRequest.ProtoReflect.Descriptor instead.
+func (*MarkReadRequest) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *GetQRCodeRequest) GetAccountId() string {
+func (x *MarkReadRequest) GetAccountId() string {
 	if x != nil {
 		return x.AccountId
 	}
 	return ""
 }
 
-type GetQRCodeResponse struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	QrCodePng        []byte                 `protobuf:"bytes,1,opt,name=qr_code_png,json=qrCodePng,proto3" json:"qr_code_png,omitempty"` // PNG image data
-	PairingSessionId string                 `protobuf:"bytes,2,opt,name=pairing_session_id,json=pairingSessionId,proto3" json:"pairing_session_id,omitempty"`
-	ExpiresAt        *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+func (x *MarkReadRequest) GetConvoId() string {
+	if x != nil {
+		return x.ConvoId
+	}
+	return ""
 }
 
-func (x *GetQRCodeResponse) Reset() {
-	*x = GetQRCodeResponse{}
-	mi := &file_proto_bridge_proto_msgTypes[12]
+func (x *MarkReadRequest) GetSenderJid() string {
+	if x != nil {
+		return x.SenderJid
+	}
+	return ""
+}
+
+func (x *MarkReadRequest) GetWaMessageIds() []string {
+	if x != nil {
+		return x.WaMessageIds
+	}
+	return nil
+}
+
+type MarkReadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"` // Error message if success = false
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarkReadResponse) Reset() {
+	*x = MarkReadResponse{}
+	mi := &file_proto_bridge_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetQRCodeResponse) String() string {
+func (x *MarkReadResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetQRCodeResponse) ProtoMessage() {}
+func (*MarkReadResponse) ProtoMessage() {}
 
-func (x *GetQRCodeResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_bridge_proto_msgTypes[12]
+func (x *MarkReadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -937,58 +1038,48 @@ func (x *GetQRCodeResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetQRCodeResponse.ProtoReflect.Descriptor instead.
-func (*GetQRCodeResponse) Descriptor() ([]byte, []int) {
-	return file_proto_bridge_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use MarkReadResponse.ProtoReflect.Descriptor instead.
+func (*MarkReadResponse) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *GetQRCodeResponse) GetQrCodePng() []byte {
+func (x *MarkReadResponse) GetSuccess() bool {
 	if x != nil {
-		return x.QrCodePng
+		return x.Success
 	}
-	return nil
+	return false
 }
 
-func (x *GetQRCodeResponse) GetPairingSessionId() string {
+func (x *MarkReadResponse) GetError() string {
 	if x != nil {
-		return x.PairingSessionId
+		return x.Error
 	}
 	return ""
 }
 
-func (x *GetQRCodeResponse) GetExpiresAt() *timestamppb.Timestamp {
-	if x != nil {
-		return x.ExpiresAt
-	}
-	return nil
-}
-
-// Account status updates
-type UpdateAccountStatusRequest struct {
+// Log out of WhatsApp
+type LogoutRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	AccountId     string                 `protobuf:"bytes,1,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
-	Status        AccountStatus          `protobuf:"varint,2,opt,name=status,proto3,enum=tennex.bridge.v1.AccountStatus" json:"status,omitempty"`
-	LastSeen      *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
-	Info          *AccountInfo           `protobuf:"bytes,4,opt,name=info,proto3" json:"info,omitempty"` // Optional account details
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UpdateAccountStatusRequest) Reset() {
-	*x = UpdateAccountStatusRequest{}
-	mi := &file_proto_bridge_proto_msgTypes[13]
+func (x *LogoutRequest) Reset() {
+	*x = LogoutRequest{}
+	mi := &file_proto_bridge_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UpdateAccountStatusRequest) String() string {
+func (x *LogoutRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UpdateAccountStatusRequest) ProtoMessage() {}
+func (*LogoutRequest) ProtoMessage() {}
 
-func (x *UpdateAccountStatusRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_bridge_proto_msgTypes[13]
+func (x *LogoutRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -999,61 +1090,41 @@ func (x *UpdateAccountStatusRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UpdateAccountStatusRequest.ProtoReflect.Descriptor instead.
-func (*UpdateAccountStatusRequest) Descriptor() ([]byte, []int) {
-	return file_proto_bridge_proto_rawDescGZIP(), []int{13}
+// Deprecated: Use LogoutRequest.ProtoReflect.Descriptor instead.
+func (*LogoutRequest) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *UpdateAccountStatusRequest) GetAccountId() string {
+func (x *LogoutRequest) GetAccountId() string {
 	if x != nil {
 		return x.AccountId
 	}
 	return ""
 }
 
-func (x *UpdateAccountStatusRequest) GetStatus() AccountStatus {
-	if x != nil {
-		return x.Status
-	}
-	return AccountStatus_ACCOUNT_STATUS_UNSPECIFIED
-}
-
-func (x *UpdateAccountStatusRequest) GetLastSeen() *timestamppb.Timestamp {
-	if x != nil {
-		return x.LastSeen
-	}
-	return nil
-}
-
-func (x *UpdateAccountStatusRequest) GetInfo() *AccountInfo {
-	if x != nil {
-		return x.Info
-	}
-	return nil
-}
-
-type UpdateAccountStatusResponse struct {
+type LogoutResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"` // Error message if success = false
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UpdateAccountStatusResponse) Reset() {
-	*x = UpdateAccountStatusResponse{}
-	mi := &file_proto_bridge_proto_msgTypes[14]
+func (x *LogoutResponse) Reset() {
+	*x = LogoutResponse{}
+	mi := &file_proto_bridge_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UpdateAccountStatusResponse) String() string {
+func (x *LogoutResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UpdateAccountStatusResponse) ProtoMessage() {}
+func (*LogoutResponse) ProtoMessage() {}
 
-func (x *UpdateAccountStatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_bridge_proto_msgTypes[14]
+func (x *LogoutResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1064,42 +1135,49 @@ func (x *UpdateAccountStatusResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UpdateAccountStatusResponse.ProtoReflect.Descriptor instead.
-func (*UpdateAccountStatusResponse) Descriptor() ([]byte, []int) {
-	return file_proto_bridge_proto_rawDescGZIP(), []int{14}
+// Deprecated: Use LogoutResponse.ProtoReflect.Descriptor instead.
+func (*LogoutResponse) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{15}
 }
 
-func (x *UpdateAccountStatusResponse) GetSuccess() bool {
+func (x *LogoutResponse) GetSuccess() bool {
 	if x != nil {
 		return x.Success
 	}
 	return false
 }
 
-type AccountInfo struct {
+func (x *LogoutResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Resync app state from WhatsApp
+type TriggerResyncRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	WaJid         string                 `protobuf:"bytes,1,opt,name=wa_jid,json=waJid,proto3" json:"wa_jid,omitempty"`
-	DisplayName   string                 `protobuf:"bytes,2,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
-	AvatarUrl     string                 `protobuf:"bytes,3,opt,name=avatar_url,json=avatarUrl,proto3" json:"avatar_url,omitempty"`
+	AccountId     string                 `protobuf:"bytes,1,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	FullSync      bool                   `protobuf:"varint,2,opt,name=full_sync,json=fullSync,proto3" json:"full_sync,omitempty"` // Re-download the full app state instead of patches since the last version
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AccountInfo) Reset() {
-	*x = AccountInfo{}
-	mi := &file_proto_bridge_proto_msgTypes[15]
+func (x *TriggerResyncRequest) Reset() {
+	*x = TriggerResyncRequest{}
+	mi := &file_proto_bridge_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AccountInfo) String() string {
+func (x *TriggerResyncRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AccountInfo) ProtoMessage() {}
+func (*TriggerResyncRequest) ProtoMessage() {}
 
-func (x *AccountInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_bridge_proto_msgTypes[15]
+func (x *TriggerResyncRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1110,30 +1188,938 @@ func (x *AccountInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AccountInfo.ProtoReflect.Descriptor instead.
-func (*AccountInfo) Descriptor() ([]byte, []int) {
-	return file_proto_bridge_proto_rawDescGZIP(), []int{15}
-}
-
-func (x *AccountInfo) GetWaJid() string {
-	if x != nil {
-		return x.WaJid
-	}
-	return ""
+// Deprecated: Use TriggerResyncRequest.ProtoReflect.Descriptor instead.
+func (*TriggerResyncRequest) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{16}
 }
 
-func (x *AccountInfo) GetDisplayName() string {
+func (x *TriggerResyncRequest) GetAccountId() string {
 	if x != nil {
-		return x.DisplayName
+		return x.AccountId
 	}
 	return ""
 }
 
-func (x *AccountInfo) GetAvatarUrl() string {
+func (x *TriggerResyncRequest) GetFullSync() bool {
 	if x != nil {
-		return x.AvatarUrl
+		return x.FullSync
 	}
-	return ""
+	return false
+}
+
+type TriggerResyncResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"` // Error message if success = false
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TriggerResyncResponse) Reset() {
+	*x = TriggerResyncResponse{}
+	mi := &file_proto_bridge_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TriggerResyncResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TriggerResyncResponse) ProtoMessage() {}
+
+func (x *TriggerResyncResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TriggerResyncResponse.ProtoReflect.Descriptor instead.
+func (*TriggerResyncResponse) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *TriggerResyncResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *TriggerResyncResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// List joined WhatsApp groups
+type ListGroupsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AccountId     string                 `protobuf:"bytes,1,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupsRequest) Reset() {
+	*x = ListGroupsRequest{}
+	mi := &file_proto_bridge_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupsRequest) ProtoMessage() {}
+
+func (x *ListGroupsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupsRequest.ProtoReflect.Descriptor instead.
+func (*ListGroupsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListGroupsRequest) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+type ListGroupsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"` // Error message if success = false
+	Groups        []*Group               `protobuf:"bytes,3,rep,name=groups,proto3" json:"groups,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupsResponse) Reset() {
+	*x = ListGroupsResponse{}
+	mi := &file_proto_bridge_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupsResponse) ProtoMessage() {}
+
+func (x *ListGroupsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupsResponse.ProtoReflect.Descriptor instead.
+func (*ListGroupsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListGroupsResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ListGroupsResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ListGroupsResponse) GetGroups() []*Group {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+type Group struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Jid              string                 `protobuf:"bytes,1,opt,name=jid,proto3" json:"jid,omitempty"`
+	Name             string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Topic            string                 `protobuf:"bytes,3,opt,name=topic,proto3" json:"topic,omitempty"`
+	OwnerJid         string                 `protobuf:"bytes,4,opt,name=owner_jid,json=ownerJid,proto3" json:"owner_jid,omitempty"`
+	ParticipantCount int32                  `protobuf:"varint,5,opt,name=participant_count,json=participantCount,proto3" json:"participant_count,omitempty"`
+	IsAnnounce       bool                   `protobuf:"varint,6,opt,name=is_announce,json=isAnnounce,proto3" json:"is_announce,omitempty"` // Only admins can send messages
+	IsLocked         bool                   `protobuf:"varint,7,opt,name=is_locked,json=isLocked,proto3" json:"is_locked,omitempty"`       // Only admins can edit group info
+	CreatedAt        *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Group) Reset() {
+	*x = Group{}
+	mi := &file_proto_bridge_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Group) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Group) ProtoMessage() {}
+
+func (x *Group) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Group.ProtoReflect.Descriptor instead.
+func (*Group) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Group) GetJid() string {
+	if x != nil {
+		return x.Jid
+	}
+	return ""
+}
+
+func (x *Group) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Group) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *Group) GetOwnerJid() string {
+	if x != nil {
+		return x.OwnerJid
+	}
+	return ""
+}
+
+func (x *Group) GetParticipantCount() int32 {
+	if x != nil {
+		return x.ParticipantCount
+	}
+	return 0
+}
+
+func (x *Group) GetIsAnnounce() bool {
+	if x != nil {
+		return x.IsAnnounce
+	}
+	return false
+}
+
+func (x *Group) GetIsLocked() bool {
+	if x != nil {
+		return x.IsLocked
+	}
+	return false
+}
+
+func (x *Group) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+// Change a conversation's pin, archive or mute state
+type SetConversationStateRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	AccountId  string                 `protobuf:"bytes,1,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	ConvoId    string                 `protobuf:"bytes,2,opt,name=convo_id,json=convoId,proto3" json:"convo_id,omitempty"`
+	IsPinned   bool                   `protobuf:"varint,3,opt,name=is_pinned,json=isPinned,proto3" json:"is_pinned,omitempty"`
+	IsArchived bool                   `protobuf:"varint,4,opt,name=is_archived,json=isArchived,proto3" json:"is_archived,omitempty"`
+	IsMuted    bool                   `protobuf:"varint,5,opt,name=is_muted,json=isMuted,proto3" json:"is_muted,omitempty"`
+	MuteUntil  *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=mute_until,json=muteUntil,proto3" json:"mute_until,omitempty"` // Unset mutes with no expiry
+	// Fields to change ("is_pinned", "is_archived", "is_muted"); the others are left as they are
+	Fields []string `protobuf:"bytes,7,rep,name=fields,proto3" json:"fields,omitempty"`
+	// Timestamp for the WhatsApp mutations. Their echo carries it back to the backend.
+	ChangedAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=changed_at,json=changedAt,proto3" json:"changed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetConversationStateRequest) Reset() {
+	*x = SetConversationStateRequest{}
+	mi := &file_proto_bridge_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetConversationStateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetConversationStateRequest) ProtoMessage() {}
+
+func (x *SetConversationStateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetConversationStateRequest.ProtoReflect.Descriptor instead.
+func (*SetConversationStateRequest) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *SetConversationStateRequest) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *SetConversationStateRequest) GetConvoId() string {
+	if x != nil {
+		return x.ConvoId
+	}
+	return ""
+}
+
+func (x *SetConversationStateRequest) GetIsPinned() bool {
+	if x != nil {
+		return x.IsPinned
+	}
+	return false
+}
+
+func (x *SetConversationStateRequest) GetIsArchived() bool {
+	if x != nil {
+		return x.IsArchived
+	}
+	return false
+}
+
+func (x *SetConversationStateRequest) GetIsMuted() bool {
+	if x != nil {
+		return x.IsMuted
+	}
+	return false
+}
+
+func (x *SetConversationStateRequest) GetMuteUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.MuteUntil
+	}
+	return nil
+}
+
+func (x *SetConversationStateRequest) GetFields() []string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *SetConversationStateRequest) GetChangedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ChangedAt
+	}
+	return nil
+}
+
+type SetConversationStateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"` // Error message if success = false
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetConversationStateResponse) Reset() {
+	*x = SetConversationStateResponse{}
+	mi := &file_proto_bridge_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetConversationStateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetConversationStateResponse) ProtoMessage() {}
+
+func (x *SetConversationStateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetConversationStateResponse.ProtoReflect.Descriptor instead.
+func (*SetConversationStateResponse) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *SetConversationStateResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SetConversationStateResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Presence subscriptions
+type SubscribePresenceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AccountId     string                 `protobuf:"bytes,1,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	Jids          []string               `protobuf:"bytes,2,rep,name=jids,proto3" json:"jids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribePresenceRequest) Reset() {
+	*x = SubscribePresenceRequest{}
+	mi := &file_proto_bridge_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribePresenceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribePresenceRequest) ProtoMessage() {}
+
+func (x *SubscribePresenceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribePresenceRequest.ProtoReflect.Descriptor instead.
+func (*SubscribePresenceRequest) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *SubscribePresenceRequest) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *SubscribePresenceRequest) GetJids() []string {
+	if x != nil {
+		return x.Jids
+	}
+	return nil
+}
+
+type SubscribePresenceResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Success        bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error          string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`                                         // Error message if success = false
+	SubscribedJids []string               `protobuf:"bytes,3,rep,name=subscribed_jids,json=subscribedJids,proto3" json:"subscribed_jids,omitempty"` // All JIDs the account is now subscribed to
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SubscribePresenceResponse) Reset() {
+	*x = SubscribePresenceResponse{}
+	mi := &file_proto_bridge_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribePresenceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribePresenceResponse) ProtoMessage() {}
+
+func (x *SubscribePresenceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribePresenceResponse.ProtoReflect.Descriptor instead.
+func (*SubscribePresenceResponse) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *SubscribePresenceResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SubscribePresenceResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *SubscribePresenceResponse) GetSubscribedJids() []string {
+	if x != nil {
+		return x.SubscribedJids
+	}
+	return nil
+}
+
+type UnsubscribePresenceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AccountId     string                 `protobuf:"bytes,1,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	Jids          []string               `protobuf:"bytes,2,rep,name=jids,proto3" json:"jids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnsubscribePresenceRequest) Reset() {
+	*x = UnsubscribePresenceRequest{}
+	mi := &file_proto_bridge_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnsubscribePresenceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnsubscribePresenceRequest) ProtoMessage() {}
+
+func (x *UnsubscribePresenceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnsubscribePresenceRequest.ProtoReflect.Descriptor instead.
+func (*UnsubscribePresenceRequest) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *UnsubscribePresenceRequest) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *UnsubscribePresenceRequest) GetJids() []string {
+	if x != nil {
+		return x.Jids
+	}
+	return nil
+}
+
+type UnsubscribePresenceResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Success        bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error          string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`                                         // Error message if success = false
+	SubscribedJids []string               `protobuf:"bytes,3,rep,name=subscribed_jids,json=subscribedJids,proto3" json:"subscribed_jids,omitempty"` // All JIDs the account is still subscribed to
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *UnsubscribePresenceResponse) Reset() {
+	*x = UnsubscribePresenceResponse{}
+	mi := &file_proto_bridge_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnsubscribePresenceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnsubscribePresenceResponse) ProtoMessage() {}
+
+func (x *UnsubscribePresenceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnsubscribePresenceResponse.ProtoReflect.Descriptor instead.
+func (*UnsubscribePresenceResponse) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *UnsubscribePresenceResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *UnsubscribePresenceResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *UnsubscribePresenceResponse) GetSubscribedJids() []string {
+	if x != nil {
+		return x.SubscribedJids
+	}
+	return nil
+}
+
+// QR code generation
+type GetQRCodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AccountId     string                 `protobuf:"bytes,1,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetQRCodeRequest) Reset() {
+	*x = GetQRCodeRequest{}
+	mi := &file_proto_bridge_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetQRCodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQRCodeRequest) ProtoMessage() {}
+
+func (x *GetQRCodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQRCodeRequest.ProtoReflect.Descriptor instead.
+func (*GetQRCodeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *GetQRCodeRequest) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+type GetQRCodeResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	QrCodePng        []byte                 `protobuf:"bytes,1,opt,name=qr_code_png,json=qrCodePng,proto3" json:"qr_code_png,omitempty"` // PNG image data
+	PairingSessionId string                 `protobuf:"bytes,2,opt,name=pairing_session_id,json=pairingSessionId,proto3" json:"pairing_session_id,omitempty"`
+	ExpiresAt        *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *GetQRCodeResponse) Reset() {
+	*x = GetQRCodeResponse{}
+	mi := &file_proto_bridge_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetQRCodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQRCodeResponse) ProtoMessage() {}
+
+func (x *GetQRCodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQRCodeResponse.ProtoReflect.Descriptor instead.
+func (*GetQRCodeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *GetQRCodeResponse) GetQrCodePng() []byte {
+	if x != nil {
+		return x.QrCodePng
+	}
+	return nil
+}
+
+func (x *GetQRCodeResponse) GetPairingSessionId() string {
+	if x != nil {
+		return x.PairingSessionId
+	}
+	return ""
+}
+
+func (x *GetQRCodeResponse) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+// Account status updates
+type UpdateAccountStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AccountId     string                 `protobuf:"bytes,1,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	Status        AccountStatus          `protobuf:"varint,2,opt,name=status,proto3,enum=tennex.bridge.v1.AccountStatus" json:"status,omitempty"`
+	LastSeen      *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	Info          *AccountInfo           `protobuf:"bytes,4,opt,name=info,proto3" json:"info,omitempty"` // Optional account details
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateAccountStatusRequest) Reset() {
+	*x = UpdateAccountStatusRequest{}
+	mi := &file_proto_bridge_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateAccountStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateAccountStatusRequest) ProtoMessage() {}
+
+func (x *UpdateAccountStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateAccountStatusRequest.ProtoReflect.Descriptor instead.
+func (*UpdateAccountStatusRequest) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *UpdateAccountStatusRequest) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *UpdateAccountStatusRequest) GetStatus() AccountStatus {
+	if x != nil {
+		return x.Status
+	}
+	return AccountStatus_ACCOUNT_STATUS_UNSPECIFIED
+}
+
+func (x *UpdateAccountStatusRequest) GetLastSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSeen
+	}
+	return nil
+}
+
+func (x *UpdateAccountStatusRequest) GetInfo() *AccountInfo {
+	if x != nil {
+		return x.Info
+	}
+	return nil
+}
+
+type UpdateAccountStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateAccountStatusResponse) Reset() {
+	*x = UpdateAccountStatusResponse{}
+	mi := &file_proto_bridge_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateAccountStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateAccountStatusResponse) ProtoMessage() {}
+
+func (x *UpdateAccountStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateAccountStatusResponse.ProtoReflect.Descriptor instead.
+func (*UpdateAccountStatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *UpdateAccountStatusResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type AccountInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WaJid         string                 `protobuf:"bytes,1,opt,name=wa_jid,json=waJid,proto3" json:"wa_jid,omitempty"`
+	DisplayName   string                 `protobuf:"bytes,2,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	AvatarUrl     string                 `protobuf:"bytes,3,opt,name=avatar_url,json=avatarUrl,proto3" json:"avatar_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AccountInfo) Reset() {
+	*x = AccountInfo{}
+	mi := &file_proto_bridge_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AccountInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccountInfo) ProtoMessage() {}
+
+func (x *AccountInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_bridge_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccountInfo.ProtoReflect.Descriptor instead.
+func (*AccountInfo) Descriptor() ([]byte, []int) {
+	return file_proto_bridge_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *AccountInfo) GetWaJid() string {
+	if x != nil {
+		return x.WaJid
+	}
+	return ""
+}
+
+func (x *AccountInfo) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *AccountInfo) GetAvatarUrl() string {
+	if x != nil {
+		return x.AvatarUrl
+	}
+	return ""
 }
 
 var File_proto_bridge_proto protoreflect.FileDescriptor
@@ -1171,7 +2157,14 @@ const file_proto_bridge_proto_rawDesc = "" +
 	"\x13SendMessageResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\"\n" +
 	"\rwa_message_id\x18\x02 \x01(\tR\vwaMessageId\x12\x14\n" +
-	"\x05error\x18\x03 \x01(\tR\x05error\"\xb9\x02\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\xae\x01\n" +
+	"\x11SendMessageResult\x12&\n" +
+	"\x0fclient_msg_uuid\x18\x01 \x01(\tR\rclientMsgUuid\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x02 \x01(\tR\taccountId\x12\x18\n" +
+	"\asuccess\x18\x03 \x01(\bR\asuccess\x12\"\n" +
+	"\rwa_message_id\x18\x04 \x01(\tR\vwaMessageId\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\"\xb9\x02\n" +
 	"\x0eMessageContent\x123\n" +
 	"\x04text\x18\x01 \x01(\v2\x1d.tennex.bridge.v1.TextContentH\x00R\x04text\x126\n" +
 	"\x05image\x18\x02 \x01(\v2\x1e.tennex.bridge.v1.ImageContentH\x00R\x05image\x126\n" +
@@ -1197,7 +2190,80 @@ const file_proto_bridge_proto_rawDesc = "" +
 	"\x0fDocumentContent\x12\x12\n" +
 	"\x04data\x18\x01 \x01(\fR\x04data\x12\x1b\n" +
 	"\tmime_type\x18\x02 \x01(\tR\bmimeType\x12\x1a\n" +
-	"\bfilename\x18\x03 \x01(\tR\bfilename\"1\n" +
+	"\bfilename\x18\x03 \x01(\tR\bfilename\"\x90\x01\n" +
+	"\x0fMarkReadRequest\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x01 \x01(\tR\taccountId\x12\x19\n" +
+	"\bconvo_id\x18\x02 \x01(\tR\aconvoId\x12\x1d\n" +
+	"\n" +
+	"sender_jid\x18\x03 \x01(\tR\tsenderJid\x12$\n" +
+	"\x0ewa_message_ids\x18\x04 \x03(\tR\fwaMessageIds\"B\n" +
+	"\x10MarkReadResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\".\n" +
+	"\rLogoutRequest\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x01 \x01(\tR\taccountId\"@\n" +
+	"\x0eLogoutResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"R\n" +
+	"\x14TriggerResyncRequest\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x01 \x01(\tR\taccountId\x12\x1b\n" +
+	"\tfull_sync\x18\x02 \x01(\bR\bfullSync\"G\n" +
+	"\x15TriggerResyncResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"2\n" +
+	"\x11ListGroupsRequest\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x01 \x01(\tR\taccountId\"u\n" +
+	"\x12ListGroupsResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12/\n" +
+	"\x06groups\x18\x03 \x03(\v2\x17.tennex.bridge.v1.GroupR\x06groups\"\x86\x02\n" +
+	"\x05Group\x12\x10\n" +
+	"\x03jid\x18\x01 \x01(\tR\x03jid\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05topic\x18\x03 \x01(\tR\x05topic\x12\x1b\n" +
+	"\towner_jid\x18\x04 \x01(\tR\bownerJid\x12+\n" +
+	"\x11participant_count\x18\x05 \x01(\x05R\x10participantCount\x12\x1f\n" +
+	"\vis_announce\x18\x06 \x01(\bR\n" +
+	"isAnnounce\x12\x1b\n" +
+	"\tis_locked\x18\a \x01(\bR\bisLocked\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\xbe\x02\n" +
+	"\x1bSetConversationStateRequest\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x01 \x01(\tR\taccountId\x12\x19\n" +
+	"\bconvo_id\x18\x02 \x01(\tR\aconvoId\x12\x1b\n" +
+	"\tis_pinned\x18\x03 \x01(\bR\bisPinned\x12\x1f\n" +
+	"\vis_archived\x18\x04 \x01(\bR\n" +
+	"isArchived\x12\x19\n" +
+	"\bis_muted\x18\x05 \x01(\bR\aisMuted\x129\n" +
+	"\n" +
+	"mute_until\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tmuteUntil\x12\x16\n" +
+	"\x06fields\x18\a \x03(\tR\x06fields\x129\n" +
+	"\n" +
+	"changed_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tchangedAt\"N\n" +
+	"\x1cSetConversationStateResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"M\n" +
+	"\x18SubscribePresenceRequest\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x01 \x01(\tR\taccountId\x12\x12\n" +
+	"\x04jids\x18\x02 \x03(\tR\x04jids\"t\n" +
+	"\x19SubscribePresenceResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12'\n" +
+	"\x0fsubscribed_jids\x18\x03 \x03(\tR\x0esubscribedJids\"O\n" +
+	"\x1aUnsubscribePresenceRequest\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x01 \x01(\tR\taccountId\x12\x12\n" +
+	"\x04jids\x18\x02 \x03(\tR\x04jids\"v\n" +
+	"\x1bUnsubscribePresenceResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12'\n" +
+	"\x0fsubscribed_jids\x18\x03 \x03(\tR\x0esubscribedJids\"1\n" +
 	"\x10GetQRCodeRequest\x12\x1d\n" +
 	"\n" +
 	"account_id\x18\x01 \x01(\tR\taccountId\"\x9c\x01\n" +
@@ -1229,7 +2295,17 @@ const file_proto_bridge_proto_rawDesc = "" +
 	"\x0ePublishInbound\x12'.tennex.bridge.v1.PublishInboundRequest\x1a(.tennex.bridge.v1.PublishInboundResponse\x12Z\n" +
 	"\vSendMessage\x12$.tennex.bridge.v1.SendMessageRequest\x1a%.tennex.bridge.v1.SendMessageResponse\x12T\n" +
 	"\tGetQRCode\x12\".tennex.bridge.v1.GetQRCodeRequest\x1a#.tennex.bridge.v1.GetQRCodeResponse\x12r\n" +
-	"\x13UpdateAccountStatus\x12,.tennex.bridge.v1.UpdateAccountStatusRequest\x1a-.tennex.bridge.v1.UpdateAccountStatusResponseB*Z(github.com/tennex/shared/proto/gen;protob\x06proto3"
+	"\x13UpdateAccountStatus\x12,.tennex.bridge.v1.UpdateAccountStatusRequest\x1a-.tennex.bridge.v1.UpdateAccountStatusResponse2\xa6\x06\n" +
+	"\x14BridgeControlService\x12Z\n" +
+	"\vSendMessage\x12$.tennex.bridge.v1.SendMessageRequest\x1a%.tennex.bridge.v1.SendMessageResponse\x12Q\n" +
+	"\bMarkRead\x12!.tennex.bridge.v1.MarkReadRequest\x1a\".tennex.bridge.v1.MarkReadResponse\x12K\n" +
+	"\x06Logout\x12\x1f.tennex.bridge.v1.LogoutRequest\x1a .tennex.bridge.v1.LogoutResponse\x12`\n" +
+	"\rTriggerResync\x12&.tennex.bridge.v1.TriggerResyncRequest\x1a'.tennex.bridge.v1.TriggerResyncResponse\x12W\n" +
+	"\n" +
+	"ListGroups\x12#.tennex.bridge.v1.ListGroupsRequest\x1a$.tennex.bridge.v1.ListGroupsResponse\x12u\n" +
+	"\x14SetConversationState\x12-.tennex.bridge.v1.SetConversationStateRequest\x1a..tennex.bridge.v1.SetConversationStateResponse\x12l\n" +
+	"\x11SubscribePresence\x12*.tennex.bridge.v1.SubscribePresenceRequest\x1a+.tennex.bridge.v1.SubscribePresenceResponse\x12r\n" +
+	"\x13UnsubscribePresence\x12,.tennex.bridge.v1.UnsubscribePresenceRequest\x1a-.tennex.bridge.v1.UnsubscribePresenceResponseB*Z(github.com/tennex/shared/proto/gen;protob\x06proto3"
 
 var (
 	file_proto_bridge_proto_rawDescOnce sync.Once
@@ -1244,53 +2320,89 @@ func file_proto_bridge_proto_rawDescGZIP() []byte {
 }
 
 var file_proto_bridge_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_proto_bridge_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_proto_bridge_proto_msgTypes = make([]protoimpl.MessageInfo, 32)
 var file_proto_bridge_proto_goTypes = []any{
-	(AccountStatus)(0),                  // 0: tennex.bridge.v1.AccountStatus
-	(*Event)(nil),                       // 1: tennex.bridge.v1.Event
-	(*PublishInboundRequest)(nil),       // 2: tennex.bridge.v1.PublishInboundRequest
-	(*PublishInboundResponse)(nil),      // 3: tennex.bridge.v1.PublishInboundResponse
-	(*SendMessageRequest)(nil),          // 4: tennex.bridge.v1.SendMessageRequest
-	(*SendMessageResponse)(nil),         // 5: tennex.bridge.v1.SendMessageResponse
-	(*MessageContent)(nil),              // 6: tennex.bridge.v1.MessageContent
-	(*TextContent)(nil),                 // 7: tennex.bridge.v1.TextContent
-	(*ImageContent)(nil),                // 8: tennex.bridge.v1.ImageContent
-	(*AudioContent)(nil),                // 9: tennex.bridge.v1.AudioContent
-	(*VideoContent)(nil),                // 10: tennex.bridge.v1.VideoContent
-	(*DocumentContent)(nil),             // 11: tennex.bridge.v1.DocumentContent
-	(*GetQRCodeRequest)(nil),            // 12: tennex.bridge.v1.GetQRCodeRequest
-	(*GetQRCodeResponse)(nil),           // 13: tennex.bridge.v1.GetQRCodeResponse
-	(*UpdateAccountStatusRequest)(nil),  // 14: tennex.bridge.v1.UpdateAccountStatusRequest
-	(*UpdateAccountStatusResponse)(nil), // 15: tennex.bridge.v1.UpdateAccountStatusResponse
-	(*AccountInfo)(nil),                 // 16: tennex.bridge.v1.AccountInfo
-	(*timestamppb.Timestamp)(nil),       // 17: google.protobuf.Timestamp
+	(AccountStatus)(0),                   // 0: tennex.bridge.v1.AccountStatus
+	(*Event)(nil),                        // 1: tennex.bridge.v1.Event
+	(*PublishInboundRequest)(nil),        // 2: tennex.bridge.v1.PublishInboundRequest
+	(*PublishInboundResponse)(nil),       // 3: tennex.bridge.v1.PublishInboundResponse
+	(*SendMessageRequest)(nil),           // 4: tennex.bridge.v1.SendMessageRequest
+	(*SendMessageResponse)(nil),          // 5: tennex.bridge.v1.SendMessageResponse
+	(*SendMessageResult)(nil),            // 6: tennex.bridge.v1.SendMessageResult
+	(*MessageContent)(nil),               // 7: tennex.bridge.v1.MessageContent
+	(*TextContent)(nil),                  // 8: tennex.bridge.v1.TextContent
+	(*ImageContent)(nil),                 // 9: tennex.bridge.v1.ImageContent
+	(*AudioContent)(nil),                 // 10: tennex.bridge.v1.AudioContent
+	(*VideoContent)(nil),                 // 11: tennex.bridge.v1.VideoContent
+	(*DocumentContent)(nil),              // 12: tennex.bridge.v1.DocumentContent
+	(*MarkReadRequest)(nil),              // 13: tennex.bridge.v1.MarkReadRequest
+	(*MarkReadResponse)(nil),             // 14: tennex.bridge.v1.MarkReadResponse
+	(*LogoutRequest)(nil),                // 15: tennex.bridge.v1.LogoutRequest
+	(*LogoutResponse)(nil),               // 16: tennex.bridge.v1.LogoutResponse
+	(*TriggerResyncRequest)(nil),         // 17: tennex.bridge.v1.TriggerResyncRequest
+	(*TriggerResyncResponse)(nil),        // 18: tennex.bridge.v1.TriggerResyncResponse
+	(*ListGroupsRequest)(nil),            // 19: tennex.bridge.v1.ListGroupsRequest
+	(*ListGroupsResponse)(nil),           // 20: tennex.bridge.v1.ListGroupsResponse
+	(*Group)(nil),                        // 21: tennex.bridge.v1.Group
+	(*SetConversationStateRequest)(nil),  // 22: tennex.bridge.v1.SetConversationStateRequest
+	(*SetConversationStateResponse)(nil), // 23: tennex.bridge.v1.SetConversationStateResponse
+	(*SubscribePresenceRequest)(nil),     // 24: tennex.bridge.v1.SubscribePresenceRequest
+	(*SubscribePresenceResponse)(nil),    // 25: tennex.bridge.v1.SubscribePresenceResponse
+	(*UnsubscribePresenceRequest)(nil),   // 26: tennex.bridge.v1.UnsubscribePresenceRequest
+	(*UnsubscribePresenceResponse)(nil),  // 27: tennex.bridge.v1.UnsubscribePresenceResponse
+	(*GetQRCodeRequest)(nil),             // 28: tennex.bridge.v1.GetQRCodeRequest
+	(*GetQRCodeResponse)(nil),            // 29: tennex.bridge.v1.GetQRCodeResponse
+	(*UpdateAccountStatusRequest)(nil),   // 30: tennex.bridge.v1.UpdateAccountStatusRequest
+	(*UpdateAccountStatusResponse)(nil),  // 31: tennex.bridge.v1.UpdateAccountStatusResponse
+	(*AccountInfo)(nil),                  // 32: tennex.bridge.v1.AccountInfo
+	(*timestamppb.Timestamp)(nil),        // 33: google.protobuf.Timestamp
 }
 var file_proto_bridge_proto_depIdxs = []int32{
-	17, // 0: tennex.bridge.v1.Event.timestamp:type_name -> google.protobuf.Timestamp
+	33, // 0: tennex.bridge.v1.Event.timestamp:type_name -> google.protobuf.Timestamp
 	1,  // 1: tennex.bridge.v1.PublishInboundRequest.event:type_name -> tennex.bridge.v1.Event
-	6,  // 2: tennex.bridge.v1.SendMessageRequest.content:type_name -> tennex.bridge.v1.MessageContent
-	7,  // 3: tennex.bridge.v1.MessageContent.text:type_name -> tennex.bridge.v1.TextContent
-	8,  // 4: tennex.bridge.v1.MessageContent.image:type_name -> tennex.bridge.v1.ImageContent
-	9,  // 5: tennex.bridge.v1.MessageContent.audio:type_name -> tennex.bridge.v1.AudioContent
-	10, // 6: tennex.bridge.v1.MessageContent.video:type_name -> tennex.bridge.v1.VideoContent
-	11, // 7: tennex.bridge.v1.MessageContent.document:type_name -> tennex.bridge.v1.DocumentContent
-	17, // 8: tennex.bridge.v1.GetQRCodeResponse.expires_at:type_name -> google.protobuf.Timestamp
-	0,  // 9: tennex.bridge.v1.UpdateAccountStatusRequest.status:type_name -> tennex.bridge.v1.AccountStatus
-	17, // 10: tennex.bridge.v1.UpdateAccountStatusRequest.last_seen:type_name -> google.protobuf.Timestamp
-	16, // 11: tennex.bridge.v1.UpdateAccountStatusRequest.info:type_name -> tennex.bridge.v1.AccountInfo
-	2,  // 12: tennex.bridge.v1.BridgeService.PublishInbound:input_type -> tennex.bridge.v1.PublishInboundRequest
-	4,  // 13: tennex.bridge.v1.BridgeService.SendMessage:input_type -> tennex.bridge.v1.SendMessageRequest
-	12, // 14: tennex.bridge.v1.BridgeService.GetQRCode:input_type -> tennex.bridge.v1.GetQRCodeRequest
-	14, // 15: tennex.bridge.v1.BridgeService.UpdateAccountStatus:input_type -> tennex.bridge.v1.UpdateAccountStatusRequest
-	3,  // 16: tennex.bridge.v1.BridgeService.PublishInbound:output_type -> tennex.bridge.v1.PublishInboundResponse
-	5,  // 17: tennex.bridge.v1.BridgeService.SendMessage:output_type -> tennex.bridge.v1.SendMessageResponse
-	13, // 18: tennex.bridge.v1.BridgeService.GetQRCode:output_type -> tennex.bridge.v1.GetQRCodeResponse
-	15, // 19: tennex.bridge.v1.BridgeService.UpdateAccountStatus:output_type -> tennex.bridge.v1.UpdateAccountStatusResponse
-	16, // [16:20] is the sub-list for method output_type
-	12, // [12:16] is the sub-list for method input_type
-	12, // [12:12] is the sub-list for extension type_name
-	12, // [12:12] is the sub-list for extension extendee
-	0,  // [0:12] is the sub-list for field type_name
+	7,  // 2: tennex.bridge.v1.SendMessageRequest.content:type_name -> tennex.bridge.v1.MessageContent
+	8,  // 3: tennex.bridge.v1.MessageContent.text:type_name -> tennex.bridge.v1.TextContent
+	9,  // 4: tennex.bridge.v1.MessageContent.image:type_name -> tennex.bridge.v1.ImageContent
+	10, // 5: tennex.bridge.v1.MessageContent.audio:type_name -> tennex.bridge.v1.AudioContent
+	11, // 6: tennex.bridge.v1.MessageContent.video:type_name -> tennex.bridge.v1.VideoContent
+	12, // 7: tennex.bridge.v1.MessageContent.document:type_name -> tennex.bridge.v1.DocumentContent
+	21, // 8: tennex.bridge.v1.ListGroupsResponse.groups:type_name -> tennex.bridge.v1.Group
+	33, // 9: tennex.bridge.v1.Group.created_at:type_name -> google.protobuf.Timestamp
+	33, // 10: tennex.bridge.v1.SetConversationStateRequest.mute_until:type_name -> google.protobuf.Timestamp
+	33, // 11: tennex.bridge.v1.SetConversationStateRequest.changed_at:type_name -> google.protobuf.Timestamp
+	33, // 12: tennex.bridge.v1.GetQRCodeResponse.expires_at:type_name -> google.protobuf.Timestamp
+	0,  // 13: tennex.bridge.v1.UpdateAccountStatusRequest.status:type_name -> tennex.bridge.v1.AccountStatus
+	33, // 14: tennex.bridge.v1.UpdateAccountStatusRequest.last_seen:type_name -> google.protobuf.Timestamp
+	32, // 15: tennex.bridge.v1.UpdateAccountStatusRequest.info:type_name -> tennex.bridge.v1.AccountInfo
+	2,  // 16: tennex.bridge.v1.BridgeService.PublishInbound:input_type -> tennex.bridge.v1.PublishInboundRequest
+	4,  // 17: tennex.bridge.v1.BridgeService.SendMessage:input_type -> tennex.bridge.v1.SendMessageRequest
+	28, // 18: tennex.bridge.v1.BridgeService.GetQRCode:input_type -> tennex.bridge.v1.GetQRCodeRequest
+	30, // 19: tennex.bridge.v1.BridgeService.UpdateAccountStatus:input_type -> tennex.bridge.v1.UpdateAccountStatusRequest
+	4,  // 20: tennex.bridge.v1.BridgeControlService.SendMessage:input_type -> tennex.bridge.v1.SendMessageRequest
+	13, // 21: tennex.bridge.v1.BridgeControlService.MarkRead:input_type -> tennex.bridge.v1.MarkReadRequest
+	15, // 22: tennex.bridge.v1.BridgeControlService.Logout:input_type -> tennex.bridge.v1.LogoutRequest
+	17, // 23: tennex.bridge.v1.BridgeControlService.TriggerResync:input_type -> tennex.bridge.v1.TriggerResyncRequest
+	19, // 24: tennex.bridge.v1.BridgeControlService.ListGroups:input_type -> tennex.bridge.v1.ListGroupsRequest
+	22, // 25: tennex.bridge.v1.BridgeControlService.SetConversationState:input_type -> tennex.bridge.v1.SetConversationStateRequest
+	24, // 26: tennex.bridge.v1.BridgeControlService.SubscribePresence:input_type -> tennex.bridge.v1.SubscribePresenceRequest
+	26, // 27: tennex.bridge.v1.BridgeControlService.UnsubscribePresence:input_type -> tennex.bridge.v1.UnsubscribePresenceRequest
+	3,  // 28: tennex.bridge.v1.BridgeService.PublishInbound:output_type -> tennex.bridge.v1.PublishInboundResponse
+	5,  // 29: tennex.bridge.v1.BridgeService.SendMessage:output_type -> tennex.bridge.v1.SendMessageResponse
+	29, // 30: tennex.bridge.v1.BridgeService.GetQRCode:output_type -> tennex.bridge.v1.GetQRCodeResponse
+	31, // 31: tennex.bridge.v1.BridgeService.UpdateAccountStatus:output_type -> tennex.bridge.v1.UpdateAccountStatusResponse
+	5,  // 32: tennex.bridge.v1.BridgeControlService.SendMessage:output_type -> tennex.bridge.v1.SendMessageResponse
+	14, // 33: tennex.bridge.v1.BridgeControlService.MarkRead:output_type -> tennex.bridge.v1.MarkReadResponse
+	16, // 34: tennex.bridge.v1.BridgeControlService.Logout:output_type -> tennex.bridge.v1.LogoutResponse
+	18, // 35: tennex.bridge.v1.BridgeControlService.TriggerResync:output_type -> tennex.bridge.v1.TriggerResyncResponse
+	20, // 36: tennex.bridge.v1.BridgeControlService.ListGroups:output_type -> tennex.bridge.v1.ListGroupsResponse
+	23, // 37: tennex.bridge.v1.BridgeControlService.SetConversationState:output_type -> tennex.bridge.v1.SetConversationStateResponse
+	25, // 38: tennex.bridge.v1.BridgeControlService.SubscribePresence:output_type -> tennex.bridge.v1.SubscribePresenceResponse
+	27, // 39: tennex.bridge.v1.BridgeControlService.UnsubscribePresence:output_type -> tennex.bridge.v1.UnsubscribePresenceResponse
+	28, // [28:40] is the sub-list for method output_type
+	16, // [16:28] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
 }
 
 func init() { file_proto_bridge_proto_init() }
@@ -1298,7 +2410,7 @@ func file_proto_bridge_proto_init() {
 	if File_proto_bridge_proto != nil {
 		return
 	}
-	file_proto_bridge_proto_msgTypes[5].OneofWrappers = []any{
+	file_proto_bridge_proto_msgTypes[6].OneofWrappers = []any{
 		(*MessageContent_Text)(nil),
 		(*MessageContent_Image)(nil),
 		(*MessageContent_Audio)(nil),
@@ -1311,9 +2423,9 @@ func file_proto_bridge_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_bridge_proto_rawDesc), len(file_proto_bridge_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   16,
+			NumMessages:   32,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_proto_bridge_proto_goTypes,
 		DependencyIndexes: file_proto_bridge_proto_depIdxs,
@@ -245,3 +245,391 @@ var BridgeService_ServiceDesc = grpc.ServiceDesc{
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/bridge.proto",
 }
+
+const (
+	BridgeControlService_SendMessage_FullMethodName          = "/tennex.bridge.v1.BridgeControlService/SendMessage"
+	BridgeControlService_MarkRead_FullMethodName             = "/tennex.bridge.v1.BridgeControlService/MarkRead"
+	BridgeControlService_Logout_FullMethodName               = "/tennex.bridge.v1.BridgeControlService/Logout"
+	BridgeControlService_TriggerResync_FullMethodName        = "/tennex.bridge.v1.BridgeControlService/TriggerResync"
+	BridgeControlService_ListGroups_FullMethodName           = "/tennex.bridge.v1.BridgeControlService/ListGroups"
+	BridgeControlService_SetConversationState_FullMethodName = "/tennex.bridge.v1.BridgeControlService/SetConversationState"
+	BridgeControlService_SubscribePresence_FullMethodName    = "/tennex.bridge.v1.BridgeControlService/SubscribePresence"
+	BridgeControlService_UnsubscribePresence_FullMethodName  = "/tennex.bridge.v1.BridgeControlService/UnsubscribePresence"
+)
+
+// BridgeControlServiceClient is the client API for BridgeControlService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Bridge control service is served by the bridge for backend-initiated operations
+// on a connected WhatsApp session
+type BridgeControlServiceClient interface {
+	// Send a message through the account's WhatsApp session
+	SendMessage(ctx context.Context, in *SendMessageRequest, opts ...grpc.CallOption) (*SendMessageResponse, error)
+	// Mark messages in a conversation as read
+	MarkRead(ctx context.Context, in *MarkReadRequest, opts ...grpc.CallOption) (*MarkReadResponse, error)
+	// Log the account out of WhatsApp and drop its session
+	Logout(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutResponse, error)
+	// Ask WhatsApp to resend app state for the account
+	TriggerResync(ctx context.Context, in *TriggerResyncRequest, opts ...grpc.CallOption) (*TriggerResyncResponse, error)
+	// List the WhatsApp groups the account belongs to, syncing each to the backend as a conversation
+	ListGroups(ctx context.Context, in *ListGroupsRequest, opts ...grpc.CallOption) (*ListGroupsResponse, error)
+	// Pin, archive or mute a conversation on WhatsApp
+	SetConversationState(ctx context.Context, in *SetConversationStateRequest, opts ...grpc.CallOption) (*SetConversationStateResponse, error)
+	// Start or stop forwarding the presence of contacts, e.g. those whose chats are open
+	SubscribePresence(ctx context.Context, in *SubscribePresenceRequest, opts ...grpc.CallOption) (*SubscribePresenceResponse, error)
+	UnsubscribePresence(ctx context.Context, in *UnsubscribePresenceRequest, opts ...grpc.CallOption) (*UnsubscribePresenceResponse, error)
+}
+
+type bridgeControlServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBridgeControlServiceClient(cc grpc.ClientConnInterface) BridgeControlServiceClient {
+	return &bridgeControlServiceClient{cc}
+}
+
+func (c *bridgeControlServiceClient) SendMessage(ctx context.Context, in *SendMessageRequest, opts ...grpc.CallOption) (*SendMessageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SendMessageResponse)
+	err := c.cc.Invoke(ctx, BridgeControlService_SendMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bridgeControlServiceClient) MarkRead(ctx context.Context, in *MarkReadRequest, opts ...grpc.CallOption) (*MarkReadResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MarkReadResponse)
+	err := c.cc.Invoke(ctx, BridgeControlService_MarkRead_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bridgeControlServiceClient) Logout(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LogoutResponse)
+	err := c.cc.Invoke(ctx, BridgeControlService_Logout_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bridgeControlServiceClient) TriggerResync(ctx context.Context, in *TriggerResyncRequest, opts ...grpc.CallOption) (*TriggerResyncResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TriggerResyncResponse)
+	err := c.cc.Invoke(ctx, BridgeControlService_TriggerResync_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bridgeControlServiceClient) ListGroups(ctx context.Context, in *ListGroupsRequest, opts ...grpc.CallOption) (*ListGroupsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListGroupsResponse)
+	err := c.cc.Invoke(ctx, BridgeControlService_ListGroups_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bridgeControlServiceClient) SetConversationState(ctx context.Context, in *SetConversationStateRequest, opts ...grpc.CallOption) (*SetConversationStateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetConversationStateResponse)
+	err := c.cc.Invoke(ctx, BridgeControlService_SetConversationState_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bridgeControlServiceClient) SubscribePresence(ctx context.Context, in *SubscribePresenceRequest, opts ...grpc.CallOption) (*SubscribePresenceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubscribePresenceResponse)
+	err := c.cc.Invoke(ctx, BridgeControlService_SubscribePresence_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bridgeControlServiceClient) UnsubscribePresence(ctx context.Context, in *UnsubscribePresenceRequest, opts ...grpc.CallOption) (*UnsubscribePresenceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UnsubscribePresenceResponse)
+	err := c.cc.Invoke(ctx, BridgeControlService_UnsubscribePresence_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BridgeControlServiceServer is the server API for BridgeControlService service.
+// All implementations must embed UnimplementedBridgeControlServiceServer
+// for forward compatibility.
+//
+// Bridge control service is served by the bridge for backend-initiated operations
+// on a connected WhatsApp session
+type BridgeControlServiceServer interface {
+	// Send a message through the account's WhatsApp session
+	SendMessage(context.Context, *SendMessageRequest) (*SendMessageResponse, error)
+	// Mark messages in a conversation as read
+	MarkRead(context.Context, *MarkReadRequest) (*MarkReadResponse, error)
+	// Log the account out of WhatsApp and drop its session
+	Logout(context.Context, *LogoutRequest) (*LogoutResponse, error)
+	// Ask WhatsApp to resend app state for the account
+	TriggerResync(context.Context, *TriggerResyncRequest) (*TriggerResyncResponse, error)
+	// List the WhatsApp groups the account belongs to, syncing each to the backend as a conversation
+	ListGroups(context.Context, *ListGroupsRequest) (*ListGroupsResponse, error)
+	// Pin, archive or mute a conversation on WhatsApp
+	SetConversationState(context.Context, *SetConversationStateRequest) (*SetConversationStateResponse, error)
+	// Start or stop forwarding the presence of contacts, e.g. those whose chats are open
+	SubscribePresence(context.Context, *SubscribePresenceRequest) (*SubscribePresenceResponse, error)
+	UnsubscribePresence(context.Context, *UnsubscribePresenceRequest) (*UnsubscribePresenceResponse, error)
+	mustEmbedUnimplementedBridgeControlServiceServer()
+}
+
+// UnimplementedBridgeControlServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBridgeControlServiceServer struct{}
+
+func (UnimplementedBridgeControlServiceServer) SendMessage(context.Context, *SendMessageRequest) (*SendMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendMessage not implemented")
+}
+func (UnimplementedBridgeControlServiceServer) MarkRead(context.Context, *MarkReadRequest) (*MarkReadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MarkRead not implemented")
+}
+func (UnimplementedBridgeControlServiceServer) Logout(context.Context, *LogoutRequest) (*LogoutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Logout not implemented")
+}
+func (UnimplementedBridgeControlServiceServer) TriggerResync(context.Context, *TriggerResyncRequest) (*TriggerResyncResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TriggerResync not implemented")
+}
+func (UnimplementedBridgeControlServiceServer) ListGroups(context.Context, *ListGroupsRequest) (*ListGroupsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGroups not implemented")
+}
+func (UnimplementedBridgeControlServiceServer) SetConversationState(context.Context, *SetConversationStateRequest) (*SetConversationStateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetConversationState not implemented")
+}
+func (UnimplementedBridgeControlServiceServer) SubscribePresence(context.Context, *SubscribePresenceRequest) (*SubscribePresenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubscribePresence not implemented")
+}
+func (UnimplementedBridgeControlServiceServer) UnsubscribePresence(context.Context, *UnsubscribePresenceRequest) (*UnsubscribePresenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnsubscribePresence not implemented")
+}
+func (UnimplementedBridgeControlServiceServer) mustEmbedUnimplementedBridgeControlServiceServer() {}
+func (UnimplementedBridgeControlServiceServer) testEmbeddedByValue()                              {}
+
+// UnsafeBridgeControlServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BridgeControlServiceServer will
+// result in compilation errors.
+type UnsafeBridgeControlServiceServer interface {
+	mustEmbedUnimplementedBridgeControlServiceServer()
+}
+
+func RegisterBridgeControlServiceServer(s grpc.ServiceRegistrar, srv BridgeControlServiceServer) {
+	// If the following call pancis, it indicates UnimplementedBridgeControlServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&BridgeControlService_ServiceDesc, srv)
+}
+
+func _BridgeControlService_SendMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BridgeControlServiceServer).SendMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BridgeControlService_SendMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BridgeControlServiceServer).SendMessage(ctx, req.(*SendMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BridgeControlService_MarkRead_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarkReadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BridgeControlServiceServer).MarkRead(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BridgeControlService_MarkRead_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BridgeControlServiceServer).MarkRead(ctx, req.(*MarkReadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BridgeControlService_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BridgeControlServiceServer).Logout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BridgeControlService_Logout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BridgeControlServiceServer).Logout(ctx, req.(*LogoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BridgeControlService_TriggerResync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TriggerResyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BridgeControlServiceServer).TriggerResync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BridgeControlService_TriggerResync_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BridgeControlServiceServer).TriggerResync(ctx, req.(*TriggerResyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BridgeControlService_ListGroups_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGroupsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BridgeControlServiceServer).ListGroups(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BridgeControlService_ListGroups_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BridgeControlServiceServer).ListGroups(ctx, req.(*ListGroupsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BridgeControlService_SetConversationState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetConversationStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BridgeControlServiceServer).SetConversationState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BridgeControlService_SetConversationState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BridgeControlServiceServer).SetConversationState(ctx, req.(*SetConversationStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BridgeControlService_SubscribePresence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubscribePresenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BridgeControlServiceServer).SubscribePresence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BridgeControlService_SubscribePresence_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BridgeControlServiceServer).SubscribePresence(ctx, req.(*SubscribePresenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BridgeControlService_UnsubscribePresence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnsubscribePresenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BridgeControlServiceServer).UnsubscribePresence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BridgeControlService_UnsubscribePresence_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BridgeControlServiceServer).UnsubscribePresence(ctx, req.(*UnsubscribePresenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BridgeControlService_ServiceDesc is the grpc.ServiceDesc for BridgeControlService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BridgeControlService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tennex.bridge.v1.BridgeControlService",
+	HandlerType: (*BridgeControlServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SendMessage",
+			Handler:    _BridgeControlService_SendMessage_Handler,
+		},
+		{
+			MethodName: "MarkRead",
+			Handler:    _BridgeControlService_MarkRead_Handler,
+		},
+		{
+			MethodName: "Logout",
+			Handler:    _BridgeControlService_Logout_Handler,
+		},
+		{
+			MethodName: "TriggerResync",
+			Handler:    _BridgeControlService_TriggerResync_Handler,
+		},
+		{
+			MethodName: "ListGroups",
+			Handler:    _BridgeControlService_ListGroups_Handler,
+		},
+		{
+			MethodName: "SetConversationState",
+			Handler:    _BridgeControlService_SetConversationState_Handler,
+		},
+		{
+			MethodName: "SubscribePresence",
+			Handler:    _BridgeControlService_SubscribePresence_Handler,
+		},
+		{
+			MethodName: "UnsubscribePresence",
+			Handler:    _BridgeControlService_UnsubscribePresence_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/bridge.proto",
+}
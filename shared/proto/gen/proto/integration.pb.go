@@ -588,7 +588,8 @@ type SyncConversationsRequest struct {
 	SyncType      string                 `protobuf:"bytes,2,opt,name=sync_type,json=syncType,proto3" json:"sync_type,omitempty"`                // "INITIAL_BOOTSTRAP", "RECENT", etc.
 	Conversations []*Conversation        `protobuf:"bytes,3,rep,name=conversations,proto3" json:"conversations,omitempty"`                      // Batch of conversations
 	IsFinalBatch  bool                   `protobuf:"varint,4,opt,name=is_final_batch,json=isFinalBatch,proto3" json:"is_final_batch,omitempty"` // Indicates end of sync
-	BatchNumber   int32                  `protobuf:"varint,5,opt,name=batch_number,json=batchNumber,proto3" json:"batch_number,omitempty"`      // For progress tracking
+	BatchNumber   int32                  `protobuf:"varint,5,opt,name=batch_number,json=batchNumber,proto3" json:"batch_number,omitempty"`      // For progress tracking, counted from 1
+	SyncId        string                 `protobuf:"bytes,6,opt,name=sync_id,json=syncId,proto3" json:"sync_id,omitempty"`                      // Identifies the sync across resumed streams
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -658,12 +659,21 @@ func (x *SyncConversationsRequest) GetBatchNumber() int32 {
 	return 0
 }
 
+func (x *SyncConversationsRequest) GetSyncId() string {
+	if x != nil {
+		return x.SyncId
+	}
+	return ""
+}
+
+// Acknowledges one batch of a SyncConversations stream
 type SyncConversationsResponse struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
 	Success        bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	Error          string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
-	ProcessedCount int32                  `protobuf:"varint,3,opt,name=processed_count,json=processedCount,proto3" json:"processed_count,omitempty"`
-	TotalBatches   int32                  `protobuf:"varint,4,opt,name=total_batches,json=totalBatches,proto3" json:"total_batches,omitempty"`
+	ProcessedCount int32                  `protobuf:"varint,3,opt,name=processed_count,json=processedCount,proto3" json:"processed_count,omitempty"` // Conversations of the batch stored
+	TotalBatches   int32                  `protobuf:"varint,4,opt,name=total_batches,json=totalBatches,proto3" json:"total_batches,omitempty"`       // Batches acknowledged on this stream
+	BatchNumber    int32                  `protobuf:"varint,5,opt,name=batch_number,json=batchNumber,proto3" json:"batch_number,omitempty"`          // The batch acknowledged
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
@@ -726,6 +736,13 @@ func (x *SyncConversationsResponse) GetTotalBatches() int32 {
 	return 0
 }
 
+func (x *SyncConversationsResponse) GetBatchNumber() int32 {
+	if x != nil {
+		return x.BatchNumber
+	}
+	return 0
+}
+
 // Contact synchronization
 type SyncContactsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -733,6 +750,7 @@ type SyncContactsRequest struct {
 	Contacts      []*Contact             `protobuf:"bytes,2,rep,name=contacts,proto3" json:"contacts,omitempty"`
 	IsFinalBatch  bool                   `protobuf:"varint,3,opt,name=is_final_batch,json=isFinalBatch,proto3" json:"is_final_batch,omitempty"`
 	BatchNumber   int32                  `protobuf:"varint,4,opt,name=batch_number,json=batchNumber,proto3" json:"batch_number,omitempty"`
+	SyncId        string                 `protobuf:"bytes,5,opt,name=sync_id,json=syncId,proto3" json:"sync_id,omitempty"` // Identifies the sync across resumed streams
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -795,11 +813,20 @@ func (x *SyncContactsRequest) GetBatchNumber() int32 {
 	return 0
 }
 
+func (x *SyncContactsRequest) GetSyncId() string {
+	if x != nil {
+		return x.SyncId
+	}
+	return ""
+}
+
+// Acknowledges one batch of a SyncContacts stream
 type SyncContactsResponse struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
 	Success        bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	Error          string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
-	ProcessedCount int32                  `protobuf:"varint,3,opt,name=processed_count,json=processedCount,proto3" json:"processed_count,omitempty"`
+	ProcessedCount int32                  `protobuf:"varint,3,opt,name=processed_count,json=processedCount,proto3" json:"processed_count,omitempty"` // Contacts of the batch stored
+	BatchNumber    int32                  `protobuf:"varint,4,opt,name=batch_number,json=batchNumber,proto3" json:"batch_number,omitempty"`          // The batch acknowledged
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
@@ -855,6 +882,13 @@ func (x *SyncContactsResponse) GetProcessedCount() int32 {
 	return 0
 }
 
+func (x *SyncContactsResponse) GetBatchNumber() int32 {
+	if x != nil {
+		return x.BatchNumber
+	}
+	return 0
+}
+
 // Message synchronization
 type SyncMessagesRequest struct {
 	state                  protoimpl.MessageState `protogen:"open.v1"`
@@ -863,6 +897,7 @@ type SyncMessagesRequest struct {
 	Messages               []*Message             `protobuf:"bytes,3,rep,name=messages,proto3" json:"messages,omitempty"`
 	IsFinalBatch           bool                   `protobuf:"varint,4,opt,name=is_final_batch,json=isFinalBatch,proto3" json:"is_final_batch,omitempty"`
 	BatchNumber            int32                  `protobuf:"varint,5,opt,name=batch_number,json=batchNumber,proto3" json:"batch_number,omitempty"`
+	SyncId                 string                 `protobuf:"bytes,6,opt,name=sync_id,json=syncId,proto3" json:"sync_id,omitempty"` // Identifies the sync across resumed streams
 	unknownFields          protoimpl.UnknownFields
 	sizeCache              protoimpl.SizeCache
 }
@@ -932,11 +967,20 @@ func (x *SyncMessagesRequest) GetBatchNumber() int32 {
 	return 0
 }
 
+func (x *SyncMessagesRequest) GetSyncId() string {
+	if x != nil {
+		return x.SyncId
+	}
+	return ""
+}
+
+// Acknowledges one batch of a SyncMessages stream
 type SyncMessagesResponse struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
 	Success        bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	Error          string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
-	ProcessedCount int32                  `protobuf:"varint,3,opt,name=processed_count,json=processedCount,proto3" json:"processed_count,omitempty"`
+	ProcessedCount int32                  `protobuf:"varint,3,opt,name=processed_count,json=processedCount,proto3" json:"processed_count,omitempty"` // Messages of the batch stored
+	BatchNumber    int32                  `protobuf:"varint,4,opt,name=batch_number,json=batchNumber,proto3" json:"batch_number,omitempty"`          // The batch acknowledged
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
@@ -992,6 +1036,13 @@ func (x *SyncMessagesResponse) GetProcessedCount() int32 {
 	return 0
 }
 
+func (x *SyncMessagesResponse) GetBatchNumber() int32 {
+	if x != nil {
+		return x.BatchNumber
+	}
+	return 0
+}
+
 // Real-time message processing
 type ProcessMessageRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1093,43 +1144,1068 @@ func (x *ProcessMessageResponse) GetSuccess() bool {
 
 func (x *ProcessMessageResponse) GetError() string {
 	if x != nil {
-		return x.Error
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ProcessMessageResponse) GetInternalMessageId() string {
+	if x != nil {
+		return x.InternalMessageId
+	}
+	return ""
+}
+
+// Conversation state updates
+type UpdateConversationStateRequest struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Context                *IntegrationContext    `protobuf:"bytes,1,opt,name=context,proto3" json:"context,omitempty"`
+	ConversationExternalId string                 `protobuf:"bytes,2,opt,name=conversation_external_id,json=conversationExternalId,proto3" json:"conversation_external_id,omitempty"`
+	State                  *ConversationState     `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
+	// Fields of state to set ("is_pinned", "is_archived", "is_muted"; mute_until
+	// goes with is_muted). Empty sets the whole state.
+	Fields []string `protobuf:"bytes,4,rep,name=fields,proto3" json:"fields,omitempty"`
+	// When the change was made. Updates older than the conversation's latest
+	// change are ignored, so a late echo can't undo a newer change.
+	ChangedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=changed_at,json=changedAt,proto3" json:"changed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateConversationStateRequest) Reset() {
+	*x = UpdateConversationStateRequest{}
+	mi := &file_proto_integration_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateConversationStateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateConversationStateRequest) ProtoMessage() {}
+
+func (x *UpdateConversationStateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateConversationStateRequest.ProtoReflect.Descriptor instead.
+func (*UpdateConversationStateRequest) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *UpdateConversationStateRequest) GetContext() *IntegrationContext {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *UpdateConversationStateRequest) GetConversationExternalId() string {
+	if x != nil {
+		return x.ConversationExternalId
+	}
+	return ""
+}
+
+func (x *UpdateConversationStateRequest) GetState() *ConversationState {
+	if x != nil {
+		return x.State
+	}
+	return nil
+}
+
+func (x *UpdateConversationStateRequest) GetFields() []string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *UpdateConversationStateRequest) GetChangedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ChangedAt
+	}
+	return nil
+}
+
+type UpdateConversationStateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateConversationStateResponse) Reset() {
+	*x = UpdateConversationStateResponse{}
+	mi := &file_proto_integration_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateConversationStateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateConversationStateResponse) ProtoMessage() {}
+
+func (x *UpdateConversationStateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateConversationStateResponse.ProtoReflect.Descriptor instead.
+func (*UpdateConversationStateResponse) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *UpdateConversationStateResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *UpdateConversationStateResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Presence of a contact the user subscribed to
+type UpdatePresenceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Context       *IntegrationContext    `protobuf:"bytes,1,opt,name=context,proto3" json:"context,omitempty"`
+	Presence      *Presence              `protobuf:"bytes,2,opt,name=presence,proto3" json:"presence,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdatePresenceRequest) Reset() {
+	*x = UpdatePresenceRequest{}
+	mi := &file_proto_integration_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdatePresenceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePresenceRequest) ProtoMessage() {}
+
+func (x *UpdatePresenceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePresenceRequest.ProtoReflect.Descriptor instead.
+func (*UpdatePresenceRequest) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *UpdatePresenceRequest) GetContext() *IntegrationContext {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *UpdatePresenceRequest) GetPresence() *Presence {
+	if x != nil {
+		return x.Presence
+	}
+	return nil
+}
+
+type UpdatePresenceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdatePresenceResponse) Reset() {
+	*x = UpdatePresenceResponse{}
+	mi := &file_proto_integration_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdatePresenceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePresenceResponse) ProtoMessage() {}
+
+func (x *UpdatePresenceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePresenceResponse.ProtoReflect.Descriptor instead.
+func (*UpdatePresenceResponse) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *UpdatePresenceResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *UpdatePresenceResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Group participants promoted or demoted after the group was synced
+type UpdateParticipantRolesRequest struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Context                *IntegrationContext    `protobuf:"bytes,1,opt,name=context,proto3" json:"context,omitempty"`
+	ConversationExternalId string                 `protobuf:"bytes,2,opt,name=conversation_external_id,json=conversationExternalId,proto3" json:"conversation_external_id,omitempty"`
+	// Each participant's external_user_id and new role; other fields are ignored
+	Participants        []*ConversationParticipant `protobuf:"bytes,3,rep,name=participants,proto3" json:"participants,omitempty"`
+	ChangedByExternalId string                     `protobuf:"bytes,4,opt,name=changed_by_external_id,json=changedByExternalId,proto3" json:"changed_by_external_id,omitempty"` // Who made the change, when known
+	ChangedAt           *timestamppb.Timestamp     `protobuf:"bytes,5,opt,name=changed_at,json=changedAt,proto3" json:"changed_at,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *UpdateParticipantRolesRequest) Reset() {
+	*x = UpdateParticipantRolesRequest{}
+	mi := &file_proto_integration_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateParticipantRolesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateParticipantRolesRequest) ProtoMessage() {}
+
+func (x *UpdateParticipantRolesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateParticipantRolesRequest.ProtoReflect.Descriptor instead.
+func (*UpdateParticipantRolesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *UpdateParticipantRolesRequest) GetContext() *IntegrationContext {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *UpdateParticipantRolesRequest) GetConversationExternalId() string {
+	if x != nil {
+		return x.ConversationExternalId
+	}
+	return ""
+}
+
+func (x *UpdateParticipantRolesRequest) GetParticipants() []*ConversationParticipant {
+	if x != nil {
+		return x.Participants
+	}
+	return nil
+}
+
+func (x *UpdateParticipantRolesRequest) GetChangedByExternalId() string {
+	if x != nil {
+		return x.ChangedByExternalId
+	}
+	return ""
+}
+
+func (x *UpdateParticipantRolesRequest) GetChangedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ChangedAt
+	}
+	return nil
+}
+
+type UpdateParticipantRolesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	ChangedCount  int32                  `protobuf:"varint,3,opt,name=changed_count,json=changedCount,proto3" json:"changed_count,omitempty"` // Participants whose stored role changed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateParticipantRolesResponse) Reset() {
+	*x = UpdateParticipantRolesResponse{}
+	mi := &file_proto_integration_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateParticipantRolesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateParticipantRolesResponse) ProtoMessage() {}
+
+func (x *UpdateParticipantRolesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateParticipantRolesResponse.ProtoReflect.Descriptor instead.
+func (*UpdateParticipantRolesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *UpdateParticipantRolesResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *UpdateParticipantRolesResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *UpdateParticipantRolesResponse) GetChangedCount() int32 {
+	if x != nil {
+		return x.ChangedCount
+	}
+	return 0
+}
+
+// A delivery or read receipt for a message sent from the outbox
+type UpdateOutboxDeliveryRequest struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Context                *IntegrationContext    `protobuf:"bytes,1,opt,name=context,proto3" json:"context,omitempty"`
+	ClientMsgUuid          string                 `protobuf:"bytes,2,opt,name=client_msg_uuid,json=clientMsgUuid,proto3" json:"client_msg_uuid,omitempty"`
+	WaMessageId            string                 `protobuf:"bytes,3,opt,name=wa_message_id,json=waMessageId,proto3" json:"wa_message_id,omitempty"`
+	ConversationExternalId string                 `protobuf:"bytes,4,opt,name=conversation_external_id,json=conversationExternalId,proto3" json:"conversation_external_id,omitempty"`
+	Status                 MessageStatus          `protobuf:"varint,5,opt,name=status,proto3,enum=tennex.integration.v1.MessageStatus" json:"status,omitempty"` // DELIVERED or READ
+	Timestamp              *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *UpdateOutboxDeliveryRequest) Reset() {
+	*x = UpdateOutboxDeliveryRequest{}
+	mi := &file_proto_integration_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateOutboxDeliveryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateOutboxDeliveryRequest) ProtoMessage() {}
+
+func (x *UpdateOutboxDeliveryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateOutboxDeliveryRequest.ProtoReflect.Descriptor instead.
+func (*UpdateOutboxDeliveryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *UpdateOutboxDeliveryRequest) GetContext() *IntegrationContext {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *UpdateOutboxDeliveryRequest) GetClientMsgUuid() string {
+	if x != nil {
+		return x.ClientMsgUuid
+	}
+	return ""
+}
+
+func (x *UpdateOutboxDeliveryRequest) GetWaMessageId() string {
+	if x != nil {
+		return x.WaMessageId
+	}
+	return ""
+}
+
+func (x *UpdateOutboxDeliveryRequest) GetConversationExternalId() string {
+	if x != nil {
+		return x.ConversationExternalId
+	}
+	return ""
+}
+
+func (x *UpdateOutboxDeliveryRequest) GetStatus() MessageStatus {
+	if x != nil {
+		return x.Status
+	}
+	return MessageStatus_MESSAGE_STATUS_UNSPECIFIED
+}
+
+func (x *UpdateOutboxDeliveryRequest) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+type UpdateOutboxDeliveryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Applied       bool                   `protobuf:"varint,3,opt,name=applied,proto3" json:"applied,omitempty"` // False when the entry already had this status or a later one
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateOutboxDeliveryResponse) Reset() {
+	*x = UpdateOutboxDeliveryResponse{}
+	mi := &file_proto_integration_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateOutboxDeliveryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateOutboxDeliveryResponse) ProtoMessage() {}
+
+func (x *UpdateOutboxDeliveryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateOutboxDeliveryResponse.ProtoReflect.Descriptor instead.
+func (*UpdateOutboxDeliveryResponse) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *UpdateOutboxDeliveryResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *UpdateOutboxDeliveryResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *UpdateOutboxDeliveryResponse) GetApplied() bool {
+	if x != nil {
+		return x.Applied
+	}
+	return false
+}
+
+// A delivery or read receipt for a message the account sent from its phone or
+// another linked device, rather than from the outbox
+type UpdateMessageDeliveryRequest struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Context                *IntegrationContext    `protobuf:"bytes,1,opt,name=context,proto3" json:"context,omitempty"`
+	WaMessageId            string                 `protobuf:"bytes,2,opt,name=wa_message_id,json=waMessageId,proto3" json:"wa_message_id,omitempty"`
+	ConversationExternalId string                 `protobuf:"bytes,3,opt,name=conversation_external_id,json=conversationExternalId,proto3" json:"conversation_external_id,omitempty"`
+	Status                 MessageStatus          `protobuf:"varint,4,opt,name=status,proto3,enum=tennex.integration.v1.MessageStatus" json:"status,omitempty"` // DELIVERED or READ
+	Timestamp              *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *UpdateMessageDeliveryRequest) Reset() {
+	*x = UpdateMessageDeliveryRequest{}
+	mi := &file_proto_integration_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMessageDeliveryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMessageDeliveryRequest) ProtoMessage() {}
+
+func (x *UpdateMessageDeliveryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMessageDeliveryRequest.ProtoReflect.Descriptor instead.
+func (*UpdateMessageDeliveryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *UpdateMessageDeliveryRequest) GetContext() *IntegrationContext {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *UpdateMessageDeliveryRequest) GetWaMessageId() string {
+	if x != nil {
+		return x.WaMessageId
+	}
+	return ""
+}
+
+func (x *UpdateMessageDeliveryRequest) GetConversationExternalId() string {
+	if x != nil {
+		return x.ConversationExternalId
+	}
+	return ""
+}
+
+func (x *UpdateMessageDeliveryRequest) GetStatus() MessageStatus {
+	if x != nil {
+		return x.Status
+	}
+	return MessageStatus_MESSAGE_STATUS_UNSPECIFIED
+}
+
+func (x *UpdateMessageDeliveryRequest) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+type UpdateMessageDeliveryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Applied       bool                   `protobuf:"varint,3,opt,name=applied,proto3" json:"applied,omitempty"` // False when the message isn't synced yet, or already had this status or a later one
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateMessageDeliveryResponse) Reset() {
+	*x = UpdateMessageDeliveryResponse{}
+	mi := &file_proto_integration_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMessageDeliveryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMessageDeliveryResponse) ProtoMessage() {}
+
+func (x *UpdateMessageDeliveryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMessageDeliveryResponse.ProtoReflect.Descriptor instead.
+func (*UpdateMessageDeliveryResponse) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *UpdateMessageDeliveryResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *UpdateMessageDeliveryResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *UpdateMessageDeliveryResponse) GetApplied() bool {
+	if x != nil {
+		return x.Applied
+	}
+	return false
+}
+
+// Poll votes
+type ProcessPollVoteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Context       *IntegrationContext    `protobuf:"bytes,1,opt,name=context,proto3" json:"context,omitempty"`
+	Vote          *PollVote              `protobuf:"bytes,2,opt,name=vote,proto3" json:"vote,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessPollVoteRequest) Reset() {
+	*x = ProcessPollVoteRequest{}
+	mi := &file_proto_integration_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessPollVoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessPollVoteRequest) ProtoMessage() {}
+
+func (x *ProcessPollVoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessPollVoteRequest.ProtoReflect.Descriptor instead.
+func (*ProcessPollVoteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ProcessPollVoteRequest) GetContext() *IntegrationContext {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *ProcessPollVoteRequest) GetVote() *PollVote {
+	if x != nil {
+		return x.Vote
+	}
+	return nil
+}
+
+type ProcessPollVoteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessPollVoteResponse) Reset() {
+	*x = ProcessPollVoteResponse{}
+	mi := &file_proto_integration_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessPollVoteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessPollVoteResponse) ProtoMessage() {}
+
+func (x *ProcessPollVoteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessPollVoteResponse.ProtoReflect.Descriptor instead.
+func (*ProcessPollVoteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ProcessPollVoteResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ProcessPollVoteResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Identity mappings between a contact's LID and phone-number JID
+type SyncIdentityMappingsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Context       *IntegrationContext    `protobuf:"bytes,1,opt,name=context,proto3" json:"context,omitempty"`
+	Mappings      []*IdentityMapping     `protobuf:"bytes,2,rep,name=mappings,proto3" json:"mappings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncIdentityMappingsRequest) Reset() {
+	*x = SyncIdentityMappingsRequest{}
+	mi := &file_proto_integration_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncIdentityMappingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncIdentityMappingsRequest) ProtoMessage() {}
+
+func (x *SyncIdentityMappingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncIdentityMappingsRequest.ProtoReflect.Descriptor instead.
+func (*SyncIdentityMappingsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *SyncIdentityMappingsRequest) GetContext() *IntegrationContext {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *SyncIdentityMappingsRequest) GetMappings() []*IdentityMapping {
+	if x != nil {
+		return x.Mappings
+	}
+	return nil
+}
+
+type SyncIdentityMappingsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	MergedCount   int32                  `protobuf:"varint,3,opt,name=merged_count,json=mergedCount,proto3" json:"merged_count,omitempty"` // Duplicate conversations merged into their phone-number conversation
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncIdentityMappingsResponse) Reset() {
+	*x = SyncIdentityMappingsResponse{}
+	mi := &file_proto_integration_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncIdentityMappingsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncIdentityMappingsResponse) ProtoMessage() {}
+
+func (x *SyncIdentityMappingsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncIdentityMappingsResponse.ProtoReflect.Descriptor instead.
+func (*SyncIdentityMappingsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *SyncIdentityMappingsResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SyncIdentityMappingsResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *SyncIdentityMappingsResponse) GetMergedCount() int32 {
+	if x != nil {
+		return x.MergedCount
+	}
+	return 0
+}
+
+// Media storage
+type GetMissingMediaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Context       *IntegrationContext    `protobuf:"bytes,1,opt,name=context,proto3" json:"context,omitempty"`
+	ContentHashes []string               `protobuf:"bytes,2,rep,name=content_hashes,json=contentHashes,proto3" json:"content_hashes,omitempty"` // Hex SHA-256 of each file
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMissingMediaRequest) Reset() {
+	*x = GetMissingMediaRequest{}
+	mi := &file_proto_integration_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMissingMediaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMissingMediaRequest) ProtoMessage() {}
+
+func (x *GetMissingMediaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMissingMediaRequest.ProtoReflect.Descriptor instead.
+func (*GetMissingMediaRequest) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *GetMissingMediaRequest) GetContext() *IntegrationContext {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *GetMissingMediaRequest) GetContentHashes() []string {
+	if x != nil {
+		return x.ContentHashes
+	}
+	return nil
+}
+
+type GetMissingMediaResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MissingHashes []string               `protobuf:"bytes,1,rep,name=missing_hashes,json=missingHashes,proto3" json:"missing_hashes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMissingMediaResponse) Reset() {
+	*x = GetMissingMediaResponse{}
+	mi := &file_proto_integration_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMissingMediaResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMissingMediaResponse) ProtoMessage() {}
+
+func (x *GetMissingMediaResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMissingMediaResponse.ProtoReflect.Descriptor instead.
+func (*GetMissingMediaResponse) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *GetMissingMediaResponse) GetMissingHashes() []string {
+	if x != nil {
+		return x.MissingHashes
+	}
+	return nil
+}
+
+type UploadMediaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Header        *UploadMediaHeader     `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"` // Only in the first request
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`     // The next chunk of the file
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadMediaRequest) Reset() {
+	*x = UploadMediaRequest{}
+	mi := &file_proto_integration_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadMediaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadMediaRequest) ProtoMessage() {}
+
+func (x *UploadMediaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadMediaRequest.ProtoReflect.Descriptor instead.
+func (*UploadMediaRequest) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *UploadMediaRequest) GetHeader() *UploadMediaHeader {
+	if x != nil {
+		return x.Header
 	}
-	return ""
+	return nil
 }
 
-func (x *ProcessMessageResponse) GetInternalMessageId() string {
+func (x *UploadMediaRequest) GetData() []byte {
 	if x != nil {
-		return x.InternalMessageId
+		return x.Data
 	}
-	return ""
+	return nil
 }
 
-// Conversation state updates
-type UpdateConversationStateRequest struct {
-	state                  protoimpl.MessageState `protogen:"open.v1"`
-	Context                *IntegrationContext    `protobuf:"bytes,1,opt,name=context,proto3" json:"context,omitempty"`
-	ConversationExternalId string                 `protobuf:"bytes,2,opt,name=conversation_external_id,json=conversationExternalId,proto3" json:"conversation_external_id,omitempty"`
-	State                  *ConversationState     `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
-	unknownFields          protoimpl.UnknownFields
-	sizeCache              protoimpl.SizeCache
+type UploadMediaHeader struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Context       *IntegrationContext    `protobuf:"bytes,1,opt,name=context,proto3" json:"context,omitempty"`
+	ContentHash   string                 `protobuf:"bytes,2,opt,name=content_hash,json=contentHash,proto3" json:"content_hash,omitempty"` // Hex SHA-256 of the file; the upload fails if the data doesn't match
+	MimeType      string                 `protobuf:"bytes,3,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UpdateConversationStateRequest) Reset() {
-	*x = UpdateConversationStateRequest{}
-	mi := &file_proto_integration_proto_msgTypes[11]
+func (x *UploadMediaHeader) Reset() {
+	*x = UploadMediaHeader{}
+	mi := &file_proto_integration_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UpdateConversationStateRequest) String() string {
+func (x *UploadMediaHeader) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UpdateConversationStateRequest) ProtoMessage() {}
+func (*UploadMediaHeader) ProtoMessage() {}
 
-func (x *UpdateConversationStateRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_integration_proto_msgTypes[11]
+func (x *UploadMediaHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1140,55 +2216,57 @@ func (x *UpdateConversationStateRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UpdateConversationStateRequest.ProtoReflect.Descriptor instead.
-func (*UpdateConversationStateRequest) Descriptor() ([]byte, []int) {
-	return file_proto_integration_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use UploadMediaHeader.ProtoReflect.Descriptor instead.
+func (*UploadMediaHeader) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{28}
 }
 
-func (x *UpdateConversationStateRequest) GetContext() *IntegrationContext {
+func (x *UploadMediaHeader) GetContext() *IntegrationContext {
 	if x != nil {
 		return x.Context
 	}
 	return nil
 }
 
-func (x *UpdateConversationStateRequest) GetConversationExternalId() string {
+func (x *UploadMediaHeader) GetContentHash() string {
 	if x != nil {
-		return x.ConversationExternalId
+		return x.ContentHash
 	}
 	return ""
 }
 
-func (x *UpdateConversationStateRequest) GetState() *ConversationState {
+func (x *UploadMediaHeader) GetMimeType() string {
 	if x != nil {
-		return x.State
+		return x.MimeType
 	}
-	return nil
+	return ""
 }
 
-type UpdateConversationStateResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type UploadMediaResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Success          bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error            string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	AlreadyStored    bool                   `protobuf:"varint,3,opt,name=already_stored,json=alreadyStored,proto3" json:"already_stored,omitempty"`            // Another upload stored the file first
+	LinkedMediaCount int64                  `protobuf:"varint,4,opt,name=linked_media_count,json=linkedMediaCount,proto3" json:"linked_media_count,omitempty"` // Attachments waiting for this file that now have it
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
-func (x *UpdateConversationStateResponse) Reset() {
-	*x = UpdateConversationStateResponse{}
-	mi := &file_proto_integration_proto_msgTypes[12]
+func (x *UploadMediaResponse) Reset() {
+	*x = UploadMediaResponse{}
+	mi := &file_proto_integration_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UpdateConversationStateResponse) String() string {
+func (x *UploadMediaResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UpdateConversationStateResponse) ProtoMessage() {}
+func (*UploadMediaResponse) ProtoMessage() {}
 
-func (x *UpdateConversationStateResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_integration_proto_msgTypes[12]
+func (x *UploadMediaResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[29]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1199,25 +2277,39 @@ func (x *UpdateConversationStateResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UpdateConversationStateResponse.ProtoReflect.Descriptor instead.
-func (*UpdateConversationStateResponse) Descriptor() ([]byte, []int) {
-	return file_proto_integration_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use UploadMediaResponse.ProtoReflect.Descriptor instead.
+func (*UploadMediaResponse) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{29}
 }
 
-func (x *UpdateConversationStateResponse) GetSuccess() bool {
+func (x *UploadMediaResponse) GetSuccess() bool {
 	if x != nil {
 		return x.Success
 	}
 	return false
 }
 
-func (x *UpdateConversationStateResponse) GetError() string {
+func (x *UploadMediaResponse) GetError() string {
 	if x != nil {
 		return x.Error
 	}
 	return ""
 }
 
+func (x *UploadMediaResponse) GetAlreadyStored() bool {
+	if x != nil {
+		return x.AlreadyStored
+	}
+	return false
+}
+
+func (x *UploadMediaResponse) GetLinkedMediaCount() int64 {
+	if x != nil {
+		return x.LinkedMediaCount
+	}
+	return 0
+}
+
 // Integration creation
 type CreateUserIntegrationRequest struct {
 	state           protoimpl.MessageState `protogen:"open.v1"`
@@ -1227,13 +2319,16 @@ type CreateUserIntegrationRequest struct {
 	DisplayName     string                 `protobuf:"bytes,4,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
 	AvatarUrl       string                 `protobuf:"bytes,5,opt,name=avatar_url,json=avatarUrl,proto3" json:"avatar_url,omitempty"`
 	Metadata        map[string]string      `protobuf:"bytes,6,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	// Identifies one pairing, e.g. the WhatsApp device JID. Retrying with the
+	// same key returns the integration the first call created.
+	IdempotencyKey string `protobuf:"bytes,7,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *CreateUserIntegrationRequest) Reset() {
 	*x = CreateUserIntegrationRequest{}
-	mi := &file_proto_integration_proto_msgTypes[13]
+	mi := &file_proto_integration_proto_msgTypes[30]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1245,7 +2340,7 @@ func (x *CreateUserIntegrationRequest) String() string {
 func (*CreateUserIntegrationRequest) ProtoMessage() {}
 
 func (x *CreateUserIntegrationRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_integration_proto_msgTypes[13]
+	mi := &file_proto_integration_proto_msgTypes[30]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1258,7 +2353,7 @@ func (x *CreateUserIntegrationRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateUserIntegrationRequest.ProtoReflect.Descriptor instead.
 func (*CreateUserIntegrationRequest) Descriptor() ([]byte, []int) {
-	return file_proto_integration_proto_rawDescGZIP(), []int{13}
+	return file_proto_integration_proto_rawDescGZIP(), []int{30}
 }
 
 func (x *CreateUserIntegrationRequest) GetUserId() string {
@@ -1303,6 +2398,13 @@ func (x *CreateUserIntegrationRequest) GetMetadata() map[string]string {
 	return nil
 }
 
+func (x *CreateUserIntegrationRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
 type CreateUserIntegrationResponse struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
 	Success           bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
@@ -1314,7 +2416,7 @@ type CreateUserIntegrationResponse struct {
 
 func (x *CreateUserIntegrationResponse) Reset() {
 	*x = CreateUserIntegrationResponse{}
-	mi := &file_proto_integration_proto_msgTypes[14]
+	mi := &file_proto_integration_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1326,7 +2428,7 @@ func (x *CreateUserIntegrationResponse) String() string {
 func (*CreateUserIntegrationResponse) ProtoMessage() {}
 
 func (x *CreateUserIntegrationResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_integration_proto_msgTypes[14]
+	mi := &file_proto_integration_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1339,7 +2441,7 @@ func (x *CreateUserIntegrationResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateUserIntegrationResponse.ProtoReflect.Descriptor instead.
 func (*CreateUserIntegrationResponse) Descriptor() ([]byte, []int) {
-	return file_proto_integration_proto_rawDescGZIP(), []int{14}
+	return file_proto_integration_proto_rawDescGZIP(), []int{31}
 }
 
 func (x *CreateUserIntegrationResponse) GetSuccess() bool {
@@ -1390,7 +2492,7 @@ type Conversation struct {
 
 func (x *Conversation) Reset() {
 	*x = Conversation{}
-	mi := &file_proto_integration_proto_msgTypes[15]
+	mi := &file_proto_integration_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1402,7 +2504,7 @@ func (x *Conversation) String() string {
 func (*Conversation) ProtoMessage() {}
 
 func (x *Conversation) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_integration_proto_msgTypes[15]
+	mi := &file_proto_integration_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1415,7 +2517,7 @@ func (x *Conversation) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Conversation.ProtoReflect.Descriptor instead.
 func (*Conversation) Descriptor() ([]byte, []int) {
-	return file_proto_integration_proto_rawDescGZIP(), []int{15}
+	return file_proto_integration_proto_rawDescGZIP(), []int{32}
 }
 
 func (x *Conversation) GetPlatformId() string {
@@ -1560,7 +2662,7 @@ type ConversationParticipant struct {
 
 func (x *ConversationParticipant) Reset() {
 	*x = ConversationParticipant{}
-	mi := &file_proto_integration_proto_msgTypes[16]
+	mi := &file_proto_integration_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1572,7 +2674,7 @@ func (x *ConversationParticipant) String() string {
 func (*ConversationParticipant) ProtoMessage() {}
 
 func (x *ConversationParticipant) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_integration_proto_msgTypes[16]
+	mi := &file_proto_integration_proto_msgTypes[33]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1585,7 +2687,7 @@ func (x *ConversationParticipant) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ConversationParticipant.ProtoReflect.Descriptor instead.
 func (*ConversationParticipant) Descriptor() ([]byte, []int) {
-	return file_proto_integration_proto_rawDescGZIP(), []int{16}
+	return file_proto_integration_proto_rawDescGZIP(), []int{33}
 }
 
 func (x *ConversationParticipant) GetExternalUserId() string {
@@ -1644,6 +2746,91 @@ func (x *ConversationParticipant) GetPlatformMetadata() map[string]string {
 	return nil
 }
 
+type Presence struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ExternalUserId string                 `protobuf:"bytes,1,opt,name=external_user_id,json=externalUserId,proto3" json:"external_user_id,omitempty"`
+	IsOnline       bool                   `protobuf:"varint,2,opt,name=is_online,json=isOnline,proto3" json:"is_online,omitempty"`
+	LastSeen       *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	// Typing or recording a voice note in conversation_external_id
+	ConversationExternalId string `protobuf:"bytes,4,opt,name=conversation_external_id,json=conversationExternalId,proto3" json:"conversation_external_id,omitempty"`
+	IsTyping               bool   `protobuf:"varint,5,opt,name=is_typing,json=isTyping,proto3" json:"is_typing,omitempty"`
+	IsRecording            bool   `protobuf:"varint,6,opt,name=is_recording,json=isRecording,proto3" json:"is_recording,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *Presence) Reset() {
+	*x = Presence{}
+	mi := &file_proto_integration_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Presence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Presence) ProtoMessage() {}
+
+func (x *Presence) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Presence.ProtoReflect.Descriptor instead.
+func (*Presence) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *Presence) GetExternalUserId() string {
+	if x != nil {
+		return x.ExternalUserId
+	}
+	return ""
+}
+
+func (x *Presence) GetIsOnline() bool {
+	if x != nil {
+		return x.IsOnline
+	}
+	return false
+}
+
+func (x *Presence) GetLastSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSeen
+	}
+	return nil
+}
+
+func (x *Presence) GetConversationExternalId() string {
+	if x != nil {
+		return x.ConversationExternalId
+	}
+	return ""
+}
+
+func (x *Presence) GetIsTyping() bool {
+	if x != nil {
+		return x.IsTyping
+	}
+	return false
+}
+
+func (x *Presence) GetIsRecording() bool {
+	if x != nil {
+		return x.IsRecording
+	}
+	return false
+}
+
 type ConversationState struct {
 	state              protoimpl.MessageState `protogen:"open.v1"`
 	IsPinned           bool                   `protobuf:"varint,1,opt,name=is_pinned,json=isPinned,proto3" json:"is_pinned,omitempty"`
@@ -1660,7 +2847,7 @@ type ConversationState struct {
 
 func (x *ConversationState) Reset() {
 	*x = ConversationState{}
-	mi := &file_proto_integration_proto_msgTypes[17]
+	mi := &file_proto_integration_proto_msgTypes[35]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1672,7 +2859,7 @@ func (x *ConversationState) String() string {
 func (*ConversationState) ProtoMessage() {}
 
 func (x *ConversationState) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_integration_proto_msgTypes[17]
+	mi := &file_proto_integration_proto_msgTypes[35]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1685,7 +2872,7 @@ func (x *ConversationState) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ConversationState.ProtoReflect.Descriptor instead.
 func (*ConversationState) Descriptor() ([]byte, []int) {
-	return file_proto_integration_proto_rawDescGZIP(), []int{17}
+	return file_proto_integration_proto_rawDescGZIP(), []int{35}
 }
 
 func (x *ConversationState) GetIsPinned() bool {
@@ -1768,7 +2955,7 @@ type Message struct {
 
 func (x *Message) Reset() {
 	*x = Message{}
-	mi := &file_proto_integration_proto_msgTypes[18]
+	mi := &file_proto_integration_proto_msgTypes[36]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1780,7 +2967,7 @@ func (x *Message) String() string {
 func (*Message) ProtoMessage() {}
 
 func (x *Message) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_integration_proto_msgTypes[18]
+	mi := &file_proto_integration_proto_msgTypes[36]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1793,7 +2980,7 @@ func (x *Message) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Message.ProtoReflect.Descriptor instead.
 func (*Message) Descriptor() ([]byte, []int) {
-	return file_proto_integration_proto_rawDescGZIP(), []int{18}
+	return file_proto_integration_proto_rawDescGZIP(), []int{36}
 }
 
 func (x *Message) GetPlatformId() string {
@@ -1908,6 +3095,82 @@ func (x *Message) GetMedia() []*MessageMedia {
 	return nil
 }
 
+type PollVote struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	PollMessageId        string                 `protobuf:"bytes,1,opt,name=poll_message_id,json=pollMessageId,proto3" json:"poll_message_id,omitempty"`                      // Platform ID of the poll message
+	ConversationId       string                 `protobuf:"bytes,2,opt,name=conversation_id,json=conversationId,proto3" json:"conversation_id,omitempty"`                     // Platform conversation ID
+	VoterId              string                 `protobuf:"bytes,3,opt,name=voter_id,json=voterId,proto3" json:"voter_id,omitempty"`                                          // Platform ID of the voter
+	SelectedOptionHashes []string               `protobuf:"bytes,4,rep,name=selected_option_hashes,json=selectedOptionHashes,proto3" json:"selected_option_hashes,omitempty"` // Hex SHA-256 of each selected option name; empty retracts the vote
+	Timestamp            *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *PollVote) Reset() {
+	*x = PollVote{}
+	mi := &file_proto_integration_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PollVote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PollVote) ProtoMessage() {}
+
+func (x *PollVote) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PollVote.ProtoReflect.Descriptor instead.
+func (*PollVote) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *PollVote) GetPollMessageId() string {
+	if x != nil {
+		return x.PollMessageId
+	}
+	return ""
+}
+
+func (x *PollVote) GetConversationId() string {
+	if x != nil {
+		return x.ConversationId
+	}
+	return ""
+}
+
+func (x *PollVote) GetVoterId() string {
+	if x != nil {
+		return x.VoterId
+	}
+	return ""
+}
+
+func (x *PollVote) GetSelectedOptionHashes() []string {
+	if x != nil {
+		return x.SelectedOptionHashes
+	}
+	return nil
+}
+
+func (x *PollVote) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
 type MessageMedia struct {
 	state            protoimpl.MessageState `protogen:"open.v1"`
 	MediaType        MediaType              `protobuf:"varint,1,opt,name=media_type,json=mediaType,proto3,enum=tennex.integration.v1.MediaType" json:"media_type,omitempty"`
@@ -1921,13 +3184,14 @@ type MessageMedia struct {
 	ThumbnailUrl     string                 `protobuf:"bytes,9,opt,name=thumbnail_url,json=thumbnailUrl,proto3" json:"thumbnail_url,omitempty"`
 	DownloadStatus   DownloadStatus         `protobuf:"varint,10,opt,name=download_status,json=downloadStatus,proto3,enum=tennex.integration.v1.DownloadStatus" json:"download_status,omitempty"`
 	PlatformMetadata map[string]string      `protobuf:"bytes,11,rep,name=platform_metadata,json=platformMetadata,proto3" json:"platform_metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	ContentHash      string                 `protobuf:"bytes,12,opt,name=content_hash,json=contentHash,proto3" json:"content_hash,omitempty"` // Hex SHA-256 of the file, if the platform provides it
 	unknownFields    protoimpl.UnknownFields
 	sizeCache        protoimpl.SizeCache
 }
 
 func (x *MessageMedia) Reset() {
 	*x = MessageMedia{}
-	mi := &file_proto_integration_proto_msgTypes[19]
+	mi := &file_proto_integration_proto_msgTypes[38]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1939,7 +3203,7 @@ func (x *MessageMedia) String() string {
 func (*MessageMedia) ProtoMessage() {}
 
 func (x *MessageMedia) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_integration_proto_msgTypes[19]
+	mi := &file_proto_integration_proto_msgTypes[38]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1952,7 +3216,7 @@ func (x *MessageMedia) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MessageMedia.ProtoReflect.Descriptor instead.
 func (*MessageMedia) Descriptor() ([]byte, []int) {
-	return file_proto_integration_proto_rawDescGZIP(), []int{19}
+	return file_proto_integration_proto_rawDescGZIP(), []int{38}
 }
 
 func (x *MessageMedia) GetMediaType() MediaType {
@@ -2032,6 +3296,13 @@ func (x *MessageMedia) GetPlatformMetadata() map[string]string {
 	return nil
 }
 
+func (x *MessageMedia) GetContentHash() string {
+	if x != nil {
+		return x.ContentHash
+	}
+	return ""
+}
+
 type Contact struct {
 	state            protoimpl.MessageState `protogen:"open.v1"`
 	PlatformId       string                 `protobuf:"bytes,1,opt,name=platform_id,json=platformId,proto3" json:"platform_id,omitempty"` // Contact's platform ID
@@ -2051,7 +3322,7 @@ type Contact struct {
 
 func (x *Contact) Reset() {
 	*x = Contact{}
-	mi := &file_proto_integration_proto_msgTypes[20]
+	mi := &file_proto_integration_proto_msgTypes[39]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2063,7 +3334,7 @@ func (x *Contact) String() string {
 func (*Contact) ProtoMessage() {}
 
 func (x *Contact) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_integration_proto_msgTypes[20]
+	mi := &file_proto_integration_proto_msgTypes[39]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2076,7 +3347,7 @@ func (x *Contact) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Contact.ProtoReflect.Descriptor instead.
 func (*Contact) Descriptor() ([]byte, []int) {
-	return file_proto_integration_proto_rawDescGZIP(), []int{20}
+	return file_proto_integration_proto_rawDescGZIP(), []int{39}
 }
 
 func (x *Contact) GetPlatformId() string {
@@ -2156,6 +3427,59 @@ func (x *Contact) GetPlatformMetadata() map[string]string {
 	return nil
 }
 
+// A WhatsApp LID and the phone-number JID it belongs to
+type IdentityMapping struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LidJid        string                 `protobuf:"bytes,1,opt,name=lid_jid,json=lidJid,proto3" json:"lid_jid,omitempty"` // e.g. 123456789012345@lid
+	PnJid         string                 `protobuf:"bytes,2,opt,name=pn_jid,json=pnJid,proto3" json:"pn_jid,omitempty"`    // e.g. 972501234567@s.whatsapp.net
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IdentityMapping) Reset() {
+	*x = IdentityMapping{}
+	mi := &file_proto_integration_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IdentityMapping) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IdentityMapping) ProtoMessage() {}
+
+func (x *IdentityMapping) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_integration_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IdentityMapping.ProtoReflect.Descriptor instead.
+func (*IdentityMapping) Descriptor() ([]byte, []int) {
+	return file_proto_integration_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *IdentityMapping) GetLidJid() string {
+	if x != nil {
+		return x.LidJid
+	}
+	return ""
+}
+
+func (x *IdentityMapping) GetPnJid() string {
+	if x != nil {
+		return x.PnJid
+	}
+	return ""
+}
+
 var File_proto_integration_proto protoreflect.FileDescriptor
 
 const file_proto_integration_proto_rawDesc = "" +
@@ -2177,51 +3501,128 @@ const file_proto_integration_proto_rawDesc = "" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"P\n" +
 	"\x1eUpdateConnectionStatusResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error\"\x90\x02\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\xa9\x02\n" +
 	"\x18SyncConversationsRequest\x12C\n" +
 	"\acontext\x18\x01 \x01(\v2).tennex.integration.v1.IntegrationContextR\acontext\x12\x1b\n" +
 	"\tsync_type\x18\x02 \x01(\tR\bsyncType\x12I\n" +
 	"\rconversations\x18\x03 \x03(\v2#.tennex.integration.v1.ConversationR\rconversations\x12$\n" +
 	"\x0eis_final_batch\x18\x04 \x01(\bR\fisFinalBatch\x12!\n" +
-	"\fbatch_number\x18\x05 \x01(\x05R\vbatchNumber\"\x99\x01\n" +
+	"\fbatch_number\x18\x05 \x01(\x05R\vbatchNumber\x12\x17\n" +
+	"\async_id\x18\x06 \x01(\tR\x06syncId\"\xbc\x01\n" +
 	"\x19SyncConversationsResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
 	"\x05error\x18\x02 \x01(\tR\x05error\x12'\n" +
 	"\x0fprocessed_count\x18\x03 \x01(\x05R\x0eprocessedCount\x12#\n" +
-	"\rtotal_batches\x18\x04 \x01(\x05R\ftotalBatches\"\xdf\x01\n" +
+	"\rtotal_batches\x18\x04 \x01(\x05R\ftotalBatches\x12!\n" +
+	"\fbatch_number\x18\x05 \x01(\x05R\vbatchNumber\"\xf8\x01\n" +
 	"\x13SyncContactsRequest\x12C\n" +
 	"\acontext\x18\x01 \x01(\v2).tennex.integration.v1.IntegrationContextR\acontext\x12:\n" +
 	"\bcontacts\x18\x02 \x03(\v2\x1e.tennex.integration.v1.ContactR\bcontacts\x12$\n" +
 	"\x0eis_final_batch\x18\x03 \x01(\bR\fisFinalBatch\x12!\n" +
-	"\fbatch_number\x18\x04 \x01(\x05R\vbatchNumber\"o\n" +
+	"\fbatch_number\x18\x04 \x01(\x05R\vbatchNumber\x12\x17\n" +
+	"\async_id\x18\x05 \x01(\tR\x06syncId\"\x92\x01\n" +
 	"\x14SyncContactsResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
 	"\x05error\x18\x02 \x01(\tR\x05error\x12'\n" +
-	"\x0fprocessed_count\x18\x03 \x01(\x05R\x0eprocessedCount\"\x99\x02\n" +
+	"\x0fprocessed_count\x18\x03 \x01(\x05R\x0eprocessedCount\x12!\n" +
+	"\fbatch_number\x18\x04 \x01(\x05R\vbatchNumber\"\xb2\x02\n" +
 	"\x13SyncMessagesRequest\x12C\n" +
 	"\acontext\x18\x01 \x01(\v2).tennex.integration.v1.IntegrationContextR\acontext\x128\n" +
 	"\x18conversation_external_id\x18\x02 \x01(\tR\x16conversationExternalId\x12:\n" +
 	"\bmessages\x18\x03 \x03(\v2\x1e.tennex.integration.v1.MessageR\bmessages\x12$\n" +
 	"\x0eis_final_batch\x18\x04 \x01(\bR\fisFinalBatch\x12!\n" +
-	"\fbatch_number\x18\x05 \x01(\x05R\vbatchNumber\"o\n" +
+	"\fbatch_number\x18\x05 \x01(\x05R\vbatchNumber\x12\x17\n" +
+	"\async_id\x18\x06 \x01(\tR\x06syncId\"\x92\x01\n" +
 	"\x14SyncMessagesResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
 	"\x05error\x18\x02 \x01(\tR\x05error\x12'\n" +
-	"\x0fprocessed_count\x18\x03 \x01(\x05R\x0eprocessedCount\"\x96\x01\n" +
+	"\x0fprocessed_count\x18\x03 \x01(\x05R\x0eprocessedCount\x12!\n" +
+	"\fbatch_number\x18\x04 \x01(\x05R\vbatchNumber\"\x96\x01\n" +
 	"\x15ProcessMessageRequest\x12C\n" +
 	"\acontext\x18\x01 \x01(\v2).tennex.integration.v1.IntegrationContextR\acontext\x128\n" +
 	"\amessage\x18\x02 \x01(\v2\x1e.tennex.integration.v1.MessageR\amessage\"x\n" +
 	"\x16ProcessMessageResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
 	"\x05error\x18\x02 \x01(\tR\x05error\x12.\n" +
-	"\x13internal_message_id\x18\x03 \x01(\tR\x11internalMessageId\"\xdf\x01\n" +
+	"\x13internal_message_id\x18\x03 \x01(\tR\x11internalMessageId\"\xb2\x02\n" +
 	"\x1eUpdateConversationStateRequest\x12C\n" +
 	"\acontext\x18\x01 \x01(\v2).tennex.integration.v1.IntegrationContextR\acontext\x128\n" +
 	"\x18conversation_external_id\x18\x02 \x01(\tR\x16conversationExternalId\x12>\n" +
-	"\x05state\x18\x03 \x01(\v2(.tennex.integration.v1.ConversationStateR\x05state\"Q\n" +
+	"\x05state\x18\x03 \x01(\v2(.tennex.integration.v1.ConversationStateR\x05state\x12\x16\n" +
+	"\x06fields\x18\x04 \x03(\tR\x06fields\x129\n" +
+	"\n" +
+	"changed_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tchangedAt\"Q\n" +
 	"\x1fUpdateConversationStateResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error\"\xea\x02\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\x99\x01\n" +
+	"\x15UpdatePresenceRequest\x12C\n" +
+	"\acontext\x18\x01 \x01(\v2).tennex.integration.v1.IntegrationContextR\acontext\x12;\n" +
+	"\bpresence\x18\x02 \x01(\v2\x1f.tennex.integration.v1.PresenceR\bpresence\"H\n" +
+	"\x16UpdatePresenceResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\xe2\x02\n" +
+	"\x1dUpdateParticipantRolesRequest\x12C\n" +
+	"\acontext\x18\x01 \x01(\v2).tennex.integration.v1.IntegrationContextR\acontext\x128\n" +
+	"\x18conversation_external_id\x18\x02 \x01(\tR\x16conversationExternalId\x12R\n" +
+	"\fparticipants\x18\x03 \x03(\v2..tennex.integration.v1.ConversationParticipantR\fparticipants\x123\n" +
+	"\x16changed_by_external_id\x18\x04 \x01(\tR\x13changedByExternalId\x129\n" +
+	"\n" +
+	"changed_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tchangedAt\"u\n" +
+	"\x1eUpdateParticipantRolesResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12#\n" +
+	"\rchanged_count\x18\x03 \x01(\x05R\fchangedCount\"\xe0\x02\n" +
+	"\x1bUpdateOutboxDeliveryRequest\x12C\n" +
+	"\acontext\x18\x01 \x01(\v2).tennex.integration.v1.IntegrationContextR\acontext\x12&\n" +
+	"\x0fclient_msg_uuid\x18\x02 \x01(\tR\rclientMsgUuid\x12\"\n" +
+	"\rwa_message_id\x18\x03 \x01(\tR\vwaMessageId\x128\n" +
+	"\x18conversation_external_id\x18\x04 \x01(\tR\x16conversationExternalId\x12<\n" +
+	"\x06status\x18\x05 \x01(\x0e2$.tennex.integration.v1.MessageStatusR\x06status\x128\n" +
+	"\ttimestamp\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"h\n" +
+	"\x1cUpdateOutboxDeliveryResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x18\n" +
+	"\aapplied\x18\x03 \x01(\bR\aapplied\"\xb9\x02\n" +
+	"\x1cUpdateMessageDeliveryRequest\x12C\n" +
+	"\acontext\x18\x01 \x01(\v2).tennex.integration.v1.IntegrationContextR\acontext\x12\"\n" +
+	"\rwa_message_id\x18\x02 \x01(\tR\vwaMessageId\x128\n" +
+	"\x18conversation_external_id\x18\x03 \x01(\tR\x16conversationExternalId\x12<\n" +
+	"\x06status\x18\x04 \x01(\x0e2$.tennex.integration.v1.MessageStatusR\x06status\x128\n" +
+	"\ttimestamp\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"i\n" +
+	"\x1dUpdateMessageDeliveryResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x18\n" +
+	"\aapplied\x18\x03 \x01(\bR\aapplied\"\x92\x01\n" +
+	"\x16ProcessPollVoteRequest\x12C\n" +
+	"\acontext\x18\x01 \x01(\v2).tennex.integration.v1.IntegrationContextR\acontext\x123\n" +
+	"\x04vote\x18\x02 \x01(\v2\x1f.tennex.integration.v1.PollVoteR\x04vote\"I\n" +
+	"\x17ProcessPollVoteResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\xa6\x01\n" +
+	"\x1bSyncIdentityMappingsRequest\x12C\n" +
+	"\acontext\x18\x01 \x01(\v2).tennex.integration.v1.IntegrationContextR\acontext\x12B\n" +
+	"\bmappings\x18\x02 \x03(\v2&.tennex.integration.v1.IdentityMappingR\bmappings\"q\n" +
+	"\x1cSyncIdentityMappingsResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12!\n" +
+	"\fmerged_count\x18\x03 \x01(\x05R\vmergedCount\"\x84\x01\n" +
+	"\x16GetMissingMediaRequest\x12C\n" +
+	"\acontext\x18\x01 \x01(\v2).tennex.integration.v1.IntegrationContextR\acontext\x12%\n" +
+	"\x0econtent_hashes\x18\x02 \x03(\tR\rcontentHashes\"@\n" +
+	"\x17GetMissingMediaResponse\x12%\n" +
+	"\x0emissing_hashes\x18\x01 \x03(\tR\rmissingHashes\"j\n" +
+	"\x12UploadMediaRequest\x12@\n" +
+	"\x06header\x18\x01 \x01(\v2(.tennex.integration.v1.UploadMediaHeaderR\x06header\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"\x98\x01\n" +
+	"\x11UploadMediaHeader\x12C\n" +
+	"\acontext\x18\x01 \x01(\v2).tennex.integration.v1.IntegrationContextR\acontext\x12!\n" +
+	"\fcontent_hash\x18\x02 \x01(\tR\vcontentHash\x12\x1b\n" +
+	"\tmime_type\x18\x03 \x01(\tR\bmimeType\"\x9a\x01\n" +
+	"\x13UploadMediaResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12%\n" +
+	"\x0ealready_stored\x18\x03 \x01(\bR\ralreadyStored\x12,\n" +
+	"\x12linked_media_count\x18\x04 \x01(\x03R\x10linkedMediaCount\"\x93\x03\n" +
 	"\x1cCreateUserIntegrationRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12)\n" +
 	"\x10integration_type\x18\x02 \x01(\tR\x0fintegrationType\x12(\n" +
@@ -2229,7 +3630,8 @@ const file_proto_integration_proto_rawDesc = "" +
 	"\fdisplay_name\x18\x04 \x01(\tR\vdisplayName\x12\x1d\n" +
 	"\n" +
 	"avatar_url\x18\x05 \x01(\tR\tavatarUrl\x12]\n" +
-	"\bmetadata\x18\x06 \x03(\v2A.tennex.integration.v1.CreateUserIntegrationRequest.MetadataEntryR\bmetadata\x1a;\n" +
+	"\bmetadata\x18\x06 \x03(\v2A.tennex.integration.v1.CreateUserIntegrationRequest.MetadataEntryR\bmetadata\x12'\n" +
+	"\x0fidempotency_key\x18\a \x01(\tR\x0eidempotencyKey\x1a;\n" +
 	"\rMetadataEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x7f\n" +
@@ -2276,7 +3678,14 @@ const file_proto_integration_proto_rawDesc = "" +
 	"\x11platform_metadata\x18\b \x03(\v2D.tennex.integration.v1.ConversationParticipant.PlatformMetadataEntryR\x10platformMetadata\x1aC\n" +
 	"\x15PlatformMetadataEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xbb\x02\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x84\x02\n" +
+	"\bPresence\x12(\n" +
+	"\x10external_user_id\x18\x01 \x01(\tR\x0eexternalUserId\x12\x1b\n" +
+	"\tis_online\x18\x02 \x01(\bR\bisOnline\x127\n" +
+	"\tlast_seen\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\blastSeen\x128\n" +
+	"\x18conversation_external_id\x18\x04 \x01(\tR\x16conversationExternalId\x12\x1b\n" +
+	"\tis_typing\x18\x05 \x01(\bR\bisTyping\x12!\n" +
+	"\fis_recording\x18\x06 \x01(\bR\visRecording\"\xbb\x02\n" +
 	"\x11ConversationState\x12\x1b\n" +
 	"\tis_pinned\x18\x01 \x01(\bR\bisPinned\x12\x1f\n" +
 	"\vis_archived\x18\x02 \x01(\bR\n" +
@@ -2313,7 +3722,13 @@ const file_proto_integration_proto_rawDesc = "" +
 	"\x05media\x18\x10 \x03(\v2#.tennex.integration.v1.MessageMediaR\x05media\x1aC\n" +
 	"\x15PlatformMetadataEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xc4\x04\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xe6\x01\n" +
+	"\bPollVote\x12&\n" +
+	"\x0fpoll_message_id\x18\x01 \x01(\tR\rpollMessageId\x12'\n" +
+	"\x0fconversation_id\x18\x02 \x01(\tR\x0econversationId\x12\x19\n" +
+	"\bvoter_id\x18\x03 \x01(\tR\avoterId\x124\n" +
+	"\x16selected_option_hashes\x18\x04 \x03(\tR\x14selectedOptionHashes\x128\n" +
+	"\ttimestamp\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"\xe7\x04\n" +
 	"\fMessageMedia\x12?\n" +
 	"\n" +
 	"media_type\x18\x01 \x01(\x0e2 .tennex.integration.v1.MediaTypeR\tmediaType\x12\x1b\n" +
@@ -2327,7 +3742,8 @@ const file_proto_integration_proto_rawDesc = "" +
 	"\rthumbnail_url\x18\t \x01(\tR\fthumbnailUrl\x12N\n" +
 	"\x0fdownload_status\x18\n" +
 	" \x01(\x0e2%.tennex.integration.v1.DownloadStatusR\x0edownloadStatus\x12f\n" +
-	"\x11platform_metadata\x18\v \x03(\v29.tennex.integration.v1.MessageMedia.PlatformMetadataEntryR\x10platformMetadata\x1aC\n" +
+	"\x11platform_metadata\x18\v \x03(\v29.tennex.integration.v1.MessageMedia.PlatformMetadataEntryR\x10platformMetadata\x12!\n" +
+	"\fcontent_hash\x18\f \x01(\tR\vcontentHash\x1aC\n" +
 	"\x15PlatformMetadataEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x88\x04\n" +
@@ -2351,7 +3767,10 @@ const file_proto_integration_proto_rawDesc = "" +
 	"\x11platform_metadata\x18\v \x03(\v24.tennex.integration.v1.Contact.PlatformMetadataEntryR\x10platformMetadata\x1aC\n" +
 	"\x15PlatformMetadataEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01*\xfb\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"A\n" +
+	"\x0fIdentityMapping\x12\x17\n" +
+	"\alid_jid\x18\x01 \x01(\tR\x06lidJid\x12\x15\n" +
+	"\x06pn_jid\x18\x02 \x01(\tR\x05pnJid*\xfb\x01\n" +
 	"\x10ConnectionStatus\x12!\n" +
 	"\x1dCONNECTION_STATUS_UNSPECIFIED\x10\x00\x12 \n" +
 	"\x1cCONNECTION_STATUS_CONNECTING\x10\x01\x12\"\n" +
@@ -2398,15 +3817,24 @@ const file_proto_integration_proto_rawDesc = "" +
 	"\x17DOWNLOAD_STATUS_PENDING\x10\x01\x12\x1f\n" +
 	"\x1bDOWNLOAD_STATUS_DOWNLOADING\x10\x02\x12\x1d\n" +
 	"\x19DOWNLOAD_STATUS_COMPLETED\x10\x03\x12\x1a\n" +
-	"\x16DOWNLOAD_STATUS_FAILED\x10\x042\xeb\x06\n" +
+	"\x16DOWNLOAD_STATUS_FAILED\x10\x042\xe1\f\n" +
 	"\x12IntegrationService\x12\x85\x01\n" +
-	"\x16UpdateConnectionStatus\x124.tennex.integration.v1.UpdateConnectionStatusRequest\x1a5.tennex.integration.v1.UpdateConnectionStatusResponse\x12x\n" +
-	"\x11SyncConversations\x12/.tennex.integration.v1.SyncConversationsRequest\x1a0.tennex.integration.v1.SyncConversationsResponse(\x01\x12i\n" +
-	"\fSyncContacts\x12*.tennex.integration.v1.SyncContactsRequest\x1a+.tennex.integration.v1.SyncContactsResponse(\x01\x12i\n" +
-	"\fSyncMessages\x12*.tennex.integration.v1.SyncMessagesRequest\x1a+.tennex.integration.v1.SyncMessagesResponse(\x01\x12m\n" +
+	"\x16UpdateConnectionStatus\x124.tennex.integration.v1.UpdateConnectionStatusRequest\x1a5.tennex.integration.v1.UpdateConnectionStatusResponse\x12z\n" +
+	"\x11SyncConversations\x12/.tennex.integration.v1.SyncConversationsRequest\x1a0.tennex.integration.v1.SyncConversationsResponse(\x010\x01\x12k\n" +
+	"\fSyncContacts\x12*.tennex.integration.v1.SyncContactsRequest\x1a+.tennex.integration.v1.SyncContactsResponse(\x010\x01\x12k\n" +
+	"\fSyncMessages\x12*.tennex.integration.v1.SyncMessagesRequest\x1a+.tennex.integration.v1.SyncMessagesResponse(\x010\x01\x12m\n" +
 	"\x0eProcessMessage\x12,.tennex.integration.v1.ProcessMessageRequest\x1a-.tennex.integration.v1.ProcessMessageResponse\x12\x88\x01\n" +
-	"\x17UpdateConversationState\x125.tennex.integration.v1.UpdateConversationStateRequest\x1a6.tennex.integration.v1.UpdateConversationStateResponse\x12\x82\x01\n" +
-	"\x15CreateUserIntegration\x123.tennex.integration.v1.CreateUserIntegrationRequest\x1a4.tennex.integration.v1.CreateUserIntegrationResponseB*Z(github.com/tennex/shared/proto/gen;protob\x06proto3"
+	"\x17UpdateConversationState\x125.tennex.integration.v1.UpdateConversationStateRequest\x1a6.tennex.integration.v1.UpdateConversationStateResponse\x12m\n" +
+	"\x0eUpdatePresence\x12,.tennex.integration.v1.UpdatePresenceRequest\x1a-.tennex.integration.v1.UpdatePresenceResponse\x12\x85\x01\n" +
+	"\x16UpdateParticipantRoles\x124.tennex.integration.v1.UpdateParticipantRolesRequest\x1a5.tennex.integration.v1.UpdateParticipantRolesResponse\x12\x7f\n" +
+	"\x14UpdateOutboxDelivery\x122.tennex.integration.v1.UpdateOutboxDeliveryRequest\x1a3.tennex.integration.v1.UpdateOutboxDeliveryResponse\x12\x82\x01\n" +
+	"\x15UpdateMessageDelivery\x123.tennex.integration.v1.UpdateMessageDeliveryRequest\x1a4.tennex.integration.v1.UpdateMessageDeliveryResponse\x12p\n" +
+	"\x0fProcessPollVote\x12-.tennex.integration.v1.ProcessPollVoteRequest\x1a..tennex.integration.v1.ProcessPollVoteResponse\x12\x7f\n" +
+	"\x14SyncIdentityMappings\x122.tennex.integration.v1.SyncIdentityMappingsRequest\x1a3.tennex.integration.v1.SyncIdentityMappingsResponse\x12\x82\x01\n" +
+	"\x15CreateUserIntegration\x123.tennex.integration.v1.CreateUserIntegrationRequest\x1a4.tennex.integration.v1.CreateUserIntegrationResponse2\xe8\x01\n" +
+	"\fMediaService\x12p\n" +
+	"\x0fGetMissingMedia\x12-.tennex.integration.v1.GetMissingMediaRequest\x1a..tennex.integration.v1.GetMissingMediaResponse\x12f\n" +
+	"\vUploadMedia\x12).tennex.integration.v1.UploadMediaRequest\x1a*.tennex.integration.v1.UploadMediaResponse(\x01B*Z(github.com/tennex/shared/proto/gen;protob\x06proto3"
 
 var (
 	file_proto_integration_proto_rawDescOnce sync.Once
@@ -2421,7 +3849,7 @@ func file_proto_integration_proto_rawDescGZIP() []byte {
 }
 
 var file_proto_integration_proto_enumTypes = make([]protoimpl.EnumInfo, 6)
-var file_proto_integration_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
+var file_proto_integration_proto_msgTypes = make([]protoimpl.MessageInfo, 48)
 var file_proto_integration_proto_goTypes = []any{
 	(ConnectionStatus)(0),                   // 0: tennex.integration.v1.ConnectionStatus
 	(ConversationType)(0),                   // 1: tennex.integration.v1.ConversationType
@@ -2442,80 +3870,137 @@ var file_proto_integration_proto_goTypes = []any{
 	(*ProcessMessageResponse)(nil),          // 16: tennex.integration.v1.ProcessMessageResponse
 	(*UpdateConversationStateRequest)(nil),  // 17: tennex.integration.v1.UpdateConversationStateRequest
 	(*UpdateConversationStateResponse)(nil), // 18: tennex.integration.v1.UpdateConversationStateResponse
-	(*CreateUserIntegrationRequest)(nil),    // 19: tennex.integration.v1.CreateUserIntegrationRequest
-	(*CreateUserIntegrationResponse)(nil),   // 20: tennex.integration.v1.CreateUserIntegrationResponse
-	(*Conversation)(nil),                    // 21: tennex.integration.v1.Conversation
-	(*ConversationParticipant)(nil),         // 22: tennex.integration.v1.ConversationParticipant
-	(*ConversationState)(nil),               // 23: tennex.integration.v1.ConversationState
-	(*Message)(nil),                         // 24: tennex.integration.v1.Message
-	(*MessageMedia)(nil),                    // 25: tennex.integration.v1.MessageMedia
-	(*Contact)(nil),                         // 26: tennex.integration.v1.Contact
-	nil,                                     // 27: tennex.integration.v1.UpdateConnectionStatusRequest.MetadataEntry
-	nil,                                     // 28: tennex.integration.v1.CreateUserIntegrationRequest.MetadataEntry
-	nil,                                     // 29: tennex.integration.v1.Conversation.PlatformMetadataEntry
-	nil,                                     // 30: tennex.integration.v1.ConversationParticipant.PlatformMetadataEntry
-	nil,                                     // 31: tennex.integration.v1.Message.PlatformMetadataEntry
-	nil,                                     // 32: tennex.integration.v1.MessageMedia.PlatformMetadataEntry
-	nil,                                     // 33: tennex.integration.v1.Contact.PlatformMetadataEntry
-	(*timestamppb.Timestamp)(nil),           // 34: google.protobuf.Timestamp
+	(*UpdatePresenceRequest)(nil),           // 19: tennex.integration.v1.UpdatePresenceRequest
+	(*UpdatePresenceResponse)(nil),          // 20: tennex.integration.v1.UpdatePresenceResponse
+	(*UpdateParticipantRolesRequest)(nil),   // 21: tennex.integration.v1.UpdateParticipantRolesRequest
+	(*UpdateParticipantRolesResponse)(nil),  // 22: tennex.integration.v1.UpdateParticipantRolesResponse
+	(*UpdateOutboxDeliveryRequest)(nil),     // 23: tennex.integration.v1.UpdateOutboxDeliveryRequest
+	(*UpdateOutboxDeliveryResponse)(nil),    // 24: tennex.integration.v1.UpdateOutboxDeliveryResponse
+	(*UpdateMessageDeliveryRequest)(nil),    // 25: tennex.integration.v1.UpdateMessageDeliveryRequest
+	(*UpdateMessageDeliveryResponse)(nil),   // 26: tennex.integration.v1.UpdateMessageDeliveryResponse
+	(*ProcessPollVoteRequest)(nil),          // 27: tennex.integration.v1.ProcessPollVoteRequest
+	(*ProcessPollVoteResponse)(nil),         // 28: tennex.integration.v1.ProcessPollVoteResponse
+	(*SyncIdentityMappingsRequest)(nil),     // 29: tennex.integration.v1.SyncIdentityMappingsRequest
+	(*SyncIdentityMappingsResponse)(nil),    // 30: tennex.integration.v1.SyncIdentityMappingsResponse
+	(*GetMissingMediaRequest)(nil),          // 31: tennex.integration.v1.GetMissingMediaRequest
+	(*GetMissingMediaResponse)(nil),         // 32: tennex.integration.v1.GetMissingMediaResponse
+	(*UploadMediaRequest)(nil),              // 33: tennex.integration.v1.UploadMediaRequest
+	(*UploadMediaHeader)(nil),               // 34: tennex.integration.v1.UploadMediaHeader
+	(*UploadMediaResponse)(nil),             // 35: tennex.integration.v1.UploadMediaResponse
+	(*CreateUserIntegrationRequest)(nil),    // 36: tennex.integration.v1.CreateUserIntegrationRequest
+	(*CreateUserIntegrationResponse)(nil),   // 37: tennex.integration.v1.CreateUserIntegrationResponse
+	(*Conversation)(nil),                    // 38: tennex.integration.v1.Conversation
+	(*ConversationParticipant)(nil),         // 39: tennex.integration.v1.ConversationParticipant
+	(*Presence)(nil),                        // 40: tennex.integration.v1.Presence
+	(*ConversationState)(nil),               // 41: tennex.integration.v1.ConversationState
+	(*Message)(nil),                         // 42: tennex.integration.v1.Message
+	(*PollVote)(nil),                        // 43: tennex.integration.v1.PollVote
+	(*MessageMedia)(nil),                    // 44: tennex.integration.v1.MessageMedia
+	(*Contact)(nil),                         // 45: tennex.integration.v1.Contact
+	(*IdentityMapping)(nil),                 // 46: tennex.integration.v1.IdentityMapping
+	nil,                                     // 47: tennex.integration.v1.UpdateConnectionStatusRequest.MetadataEntry
+	nil,                                     // 48: tennex.integration.v1.CreateUserIntegrationRequest.MetadataEntry
+	nil,                                     // 49: tennex.integration.v1.Conversation.PlatformMetadataEntry
+	nil,                                     // 50: tennex.integration.v1.ConversationParticipant.PlatformMetadataEntry
+	nil,                                     // 51: tennex.integration.v1.Message.PlatformMetadataEntry
+	nil,                                     // 52: tennex.integration.v1.MessageMedia.PlatformMetadataEntry
+	nil,                                     // 53: tennex.integration.v1.Contact.PlatformMetadataEntry
+	(*timestamppb.Timestamp)(nil),           // 54: google.protobuf.Timestamp
 }
 var file_proto_integration_proto_depIdxs = []int32{
 	6,  // 0: tennex.integration.v1.UpdateConnectionStatusRequest.context:type_name -> tennex.integration.v1.IntegrationContext
 	0,  // 1: tennex.integration.v1.UpdateConnectionStatusRequest.status:type_name -> tennex.integration.v1.ConnectionStatus
-	34, // 2: tennex.integration.v1.UpdateConnectionStatusRequest.timestamp:type_name -> google.protobuf.Timestamp
-	27, // 3: tennex.integration.v1.UpdateConnectionStatusRequest.metadata:type_name -> tennex.integration.v1.UpdateConnectionStatusRequest.MetadataEntry
+	54, // 2: tennex.integration.v1.UpdateConnectionStatusRequest.timestamp:type_name -> google.protobuf.Timestamp
+	47, // 3: tennex.integration.v1.UpdateConnectionStatusRequest.metadata:type_name -> tennex.integration.v1.UpdateConnectionStatusRequest.MetadataEntry
 	6,  // 4: tennex.integration.v1.SyncConversationsRequest.context:type_name -> tennex.integration.v1.IntegrationContext
-	21, // 5: tennex.integration.v1.SyncConversationsRequest.conversations:type_name -> tennex.integration.v1.Conversation
+	38, // 5: tennex.integration.v1.SyncConversationsRequest.conversations:type_name -> tennex.integration.v1.Conversation
 	6,  // 6: tennex.integration.v1.SyncContactsRequest.context:type_name -> tennex.integration.v1.IntegrationContext
-	26, // 7: tennex.integration.v1.SyncContactsRequest.contacts:type_name -> tennex.integration.v1.Contact
+	45, // 7: tennex.integration.v1.SyncContactsRequest.contacts:type_name -> tennex.integration.v1.Contact
 	6,  // 8: tennex.integration.v1.SyncMessagesRequest.context:type_name -> tennex.integration.v1.IntegrationContext
-	24, // 9: tennex.integration.v1.SyncMessagesRequest.messages:type_name -> tennex.integration.v1.Message
+	42, // 9: tennex.integration.v1.SyncMessagesRequest.messages:type_name -> tennex.integration.v1.Message
 	6,  // 10: tennex.integration.v1.ProcessMessageRequest.context:type_name -> tennex.integration.v1.IntegrationContext
-	24, // 11: tennex.integration.v1.ProcessMessageRequest.message:type_name -> tennex.integration.v1.Message
+	42, // 11: tennex.integration.v1.ProcessMessageRequest.message:type_name -> tennex.integration.v1.Message
 	6,  // 12: tennex.integration.v1.UpdateConversationStateRequest.context:type_name -> tennex.integration.v1.IntegrationContext
-	23, // 13: tennex.integration.v1.UpdateConversationStateRequest.state:type_name -> tennex.integration.v1.ConversationState
-	28, // 14: tennex.integration.v1.CreateUserIntegrationRequest.metadata:type_name -> tennex.integration.v1.CreateUserIntegrationRequest.MetadataEntry
-	1,  // 15: tennex.integration.v1.Conversation.type:type_name -> tennex.integration.v1.ConversationType
-	34, // 16: tennex.integration.v1.Conversation.mute_until:type_name -> google.protobuf.Timestamp
-	34, // 17: tennex.integration.v1.Conversation.last_message_at:type_name -> google.protobuf.Timestamp
-	34, // 18: tennex.integration.v1.Conversation.last_activity_at:type_name -> google.protobuf.Timestamp
-	29, // 19: tennex.integration.v1.Conversation.platform_metadata:type_name -> tennex.integration.v1.Conversation.PlatformMetadataEntry
-	22, // 20: tennex.integration.v1.Conversation.participants:type_name -> tennex.integration.v1.ConversationParticipant
-	34, // 21: tennex.integration.v1.ConversationParticipant.joined_at:type_name -> google.protobuf.Timestamp
-	34, // 22: tennex.integration.v1.ConversationParticipant.left_at:type_name -> google.protobuf.Timestamp
-	30, // 23: tennex.integration.v1.ConversationParticipant.platform_metadata:type_name -> tennex.integration.v1.ConversationParticipant.PlatformMetadataEntry
-	34, // 24: tennex.integration.v1.ConversationState.mute_until:type_name -> google.protobuf.Timestamp
-	34, // 25: tennex.integration.v1.Message.timestamp:type_name -> google.protobuf.Timestamp
-	34, // 26: tennex.integration.v1.Message.edit_timestamp:type_name -> google.protobuf.Timestamp
-	2,  // 27: tennex.integration.v1.Message.message_type:type_name -> tennex.integration.v1.MessageType
-	34, // 28: tennex.integration.v1.Message.deleted_at:type_name -> google.protobuf.Timestamp
-	3,  // 29: tennex.integration.v1.Message.status:type_name -> tennex.integration.v1.MessageStatus
-	31, // 30: tennex.integration.v1.Message.platform_metadata:type_name -> tennex.integration.v1.Message.PlatformMetadataEntry
-	25, // 31: tennex.integration.v1.Message.media:type_name -> tennex.integration.v1.MessageMedia
-	4,  // 32: tennex.integration.v1.MessageMedia.media_type:type_name -> tennex.integration.v1.MediaType
-	5,  // 33: tennex.integration.v1.MessageMedia.download_status:type_name -> tennex.integration.v1.DownloadStatus
-	32, // 34: tennex.integration.v1.MessageMedia.platform_metadata:type_name -> tennex.integration.v1.MessageMedia.PlatformMetadataEntry
-	34, // 35: tennex.integration.v1.Contact.last_seen:type_name -> google.protobuf.Timestamp
-	33, // 36: tennex.integration.v1.Contact.platform_metadata:type_name -> tennex.integration.v1.Contact.PlatformMetadataEntry
-	7,  // 37: tennex.integration.v1.IntegrationService.UpdateConnectionStatus:input_type -> tennex.integration.v1.UpdateConnectionStatusRequest
-	9,  // 38: tennex.integration.v1.IntegrationService.SyncConversations:input_type -> tennex.integration.v1.SyncConversationsRequest
-	11, // 39: tennex.integration.v1.IntegrationService.SyncContacts:input_type -> tennex.integration.v1.SyncContactsRequest
-	13, // 40: tennex.integration.v1.IntegrationService.SyncMessages:input_type -> tennex.integration.v1.SyncMessagesRequest
-	15, // 41: tennex.integration.v1.IntegrationService.ProcessMessage:input_type -> tennex.integration.v1.ProcessMessageRequest
-	17, // 42: tennex.integration.v1.IntegrationService.UpdateConversationState:input_type -> tennex.integration.v1.UpdateConversationStateRequest
-	19, // 43: tennex.integration.v1.IntegrationService.CreateUserIntegration:input_type -> tennex.integration.v1.CreateUserIntegrationRequest
-	8,  // 44: tennex.integration.v1.IntegrationService.UpdateConnectionStatus:output_type -> tennex.integration.v1.UpdateConnectionStatusResponse
-	10, // 45: tennex.integration.v1.IntegrationService.SyncConversations:output_type -> tennex.integration.v1.SyncConversationsResponse
-	12, // 46: tennex.integration.v1.IntegrationService.SyncContacts:output_type -> tennex.integration.v1.SyncContactsResponse
-	14, // 47: tennex.integration.v1.IntegrationService.SyncMessages:output_type -> tennex.integration.v1.SyncMessagesResponse
-	16, // 48: tennex.integration.v1.IntegrationService.ProcessMessage:output_type -> tennex.integration.v1.ProcessMessageResponse
-	18, // 49: tennex.integration.v1.IntegrationService.UpdateConversationState:output_type -> tennex.integration.v1.UpdateConversationStateResponse
-	20, // 50: tennex.integration.v1.IntegrationService.CreateUserIntegration:output_type -> tennex.integration.v1.CreateUserIntegrationResponse
-	44, // [44:51] is the sub-list for method output_type
-	37, // [37:44] is the sub-list for method input_type
-	37, // [37:37] is the sub-list for extension type_name
-	37, // [37:37] is the sub-list for extension extendee
-	0,  // [0:37] is the sub-list for field type_name
+	41, // 13: tennex.integration.v1.UpdateConversationStateRequest.state:type_name -> tennex.integration.v1.ConversationState
+	54, // 14: tennex.integration.v1.UpdateConversationStateRequest.changed_at:type_name -> google.protobuf.Timestamp
+	6,  // 15: tennex.integration.v1.UpdatePresenceRequest.context:type_name -> tennex.integration.v1.IntegrationContext
+	40, // 16: tennex.integration.v1.UpdatePresenceRequest.presence:type_name -> tennex.integration.v1.Presence
+	6,  // 17: tennex.integration.v1.UpdateParticipantRolesRequest.context:type_name -> tennex.integration.v1.IntegrationContext
+	39, // 18: tennex.integration.v1.UpdateParticipantRolesRequest.participants:type_name -> tennex.integration.v1.ConversationParticipant
+	54, // 19: tennex.integration.v1.UpdateParticipantRolesRequest.changed_at:type_name -> google.protobuf.Timestamp
+	6,  // 20: tennex.integration.v1.UpdateOutboxDeliveryRequest.context:type_name -> tennex.integration.v1.IntegrationContext
+	3,  // 21: tennex.integration.v1.UpdateOutboxDeliveryRequest.status:type_name -> tennex.integration.v1.MessageStatus
+	54, // 22: tennex.integration.v1.UpdateOutboxDeliveryRequest.timestamp:type_name -> google.protobuf.Timestamp
+	6,  // 23: tennex.integration.v1.UpdateMessageDeliveryRequest.context:type_name -> tennex.integration.v1.IntegrationContext
+	3,  // 24: tennex.integration.v1.UpdateMessageDeliveryRequest.status:type_name -> tennex.integration.v1.MessageStatus
+	54, // 25: tennex.integration.v1.UpdateMessageDeliveryRequest.timestamp:type_name -> google.protobuf.Timestamp
+	6,  // 26: tennex.integration.v1.ProcessPollVoteRequest.context:type_name -> tennex.integration.v1.IntegrationContext
+	43, // 27: tennex.integration.v1.ProcessPollVoteRequest.vote:type_name -> tennex.integration.v1.PollVote
+	6,  // 28: tennex.integration.v1.SyncIdentityMappingsRequest.context:type_name -> tennex.integration.v1.IntegrationContext
+	46, // 29: tennex.integration.v1.SyncIdentityMappingsRequest.mappings:type_name -> tennex.integration.v1.IdentityMapping
+	6,  // 30: tennex.integration.v1.GetMissingMediaRequest.context:type_name -> tennex.integration.v1.IntegrationContext
+	34, // 31: tennex.integration.v1.UploadMediaRequest.header:type_name -> tennex.integration.v1.UploadMediaHeader
+	6,  // 32: tennex.integration.v1.UploadMediaHeader.context:type_name -> tennex.integration.v1.IntegrationContext
+	48, // 33: tennex.integration.v1.CreateUserIntegrationRequest.metadata:type_name -> tennex.integration.v1.CreateUserIntegrationRequest.MetadataEntry
+	1,  // 34: tennex.integration.v1.Conversation.type:type_name -> tennex.integration.v1.ConversationType
+	54, // 35: tennex.integration.v1.Conversation.mute_until:type_name -> google.protobuf.Timestamp
+	54, // 36: tennex.integration.v1.Conversation.last_message_at:type_name -> google.protobuf.Timestamp
+	54, // 37: tennex.integration.v1.Conversation.last_activity_at:type_name -> google.protobuf.Timestamp
+	49, // 38: tennex.integration.v1.Conversation.platform_metadata:type_name -> tennex.integration.v1.Conversation.PlatformMetadataEntry
+	39, // 39: tennex.integration.v1.Conversation.participants:type_name -> tennex.integration.v1.ConversationParticipant
+	54, // 40: tennex.integration.v1.ConversationParticipant.joined_at:type_name -> google.protobuf.Timestamp
+	54, // 41: tennex.integration.v1.ConversationParticipant.left_at:type_name -> google.protobuf.Timestamp
+	50, // 42: tennex.integration.v1.ConversationParticipant.platform_metadata:type_name -> tennex.integration.v1.ConversationParticipant.PlatformMetadataEntry
+	54, // 43: tennex.integration.v1.Presence.last_seen:type_name -> google.protobuf.Timestamp
+	54, // 44: tennex.integration.v1.ConversationState.mute_until:type_name -> google.protobuf.Timestamp
+	54, // 45: tennex.integration.v1.Message.timestamp:type_name -> google.protobuf.Timestamp
+	54, // 46: tennex.integration.v1.Message.edit_timestamp:type_name -> google.protobuf.Timestamp
+	2,  // 47: tennex.integration.v1.Message.message_type:type_name -> tennex.integration.v1.MessageType
+	54, // 48: tennex.integration.v1.Message.deleted_at:type_name -> google.protobuf.Timestamp
+	3,  // 49: tennex.integration.v1.Message.status:type_name -> tennex.integration.v1.MessageStatus
+	51, // 50: tennex.integration.v1.Message.platform_metadata:type_name -> tennex.integration.v1.Message.PlatformMetadataEntry
+	44, // 51: tennex.integration.v1.Message.media:type_name -> tennex.integration.v1.MessageMedia
+	54, // 52: tennex.integration.v1.PollVote.timestamp:type_name -> google.protobuf.Timestamp
+	4,  // 53: tennex.integration.v1.MessageMedia.media_type:type_name -> tennex.integration.v1.MediaType
+	5,  // 54: tennex.integration.v1.MessageMedia.download_status:type_name -> tennex.integration.v1.DownloadStatus
+	52, // 55: tennex.integration.v1.MessageMedia.platform_metadata:type_name -> tennex.integration.v1.MessageMedia.PlatformMetadataEntry
+	54, // 56: tennex.integration.v1.Contact.last_seen:type_name -> google.protobuf.Timestamp
+	53, // 57: tennex.integration.v1.Contact.platform_metadata:type_name -> tennex.integration.v1.Contact.PlatformMetadataEntry
+	7,  // 58: tennex.integration.v1.IntegrationService.UpdateConnectionStatus:input_type -> tennex.integration.v1.UpdateConnectionStatusRequest
+	9,  // 59: tennex.integration.v1.IntegrationService.SyncConversations:input_type -> tennex.integration.v1.SyncConversationsRequest
+	11, // 60: tennex.integration.v1.IntegrationService.SyncContacts:input_type -> tennex.integration.v1.SyncContactsRequest
+	13, // 61: tennex.integration.v1.IntegrationService.SyncMessages:input_type -> tennex.integration.v1.SyncMessagesRequest
+	15, // 62: tennex.integration.v1.IntegrationService.ProcessMessage:input_type -> tennex.integration.v1.ProcessMessageRequest
+	17, // 63: tennex.integration.v1.IntegrationService.UpdateConversationState:input_type -> tennex.integration.v1.UpdateConversationStateRequest
+	19, // 64: tennex.integration.v1.IntegrationService.UpdatePresence:input_type -> tennex.integration.v1.UpdatePresenceRequest
+	21, // 65: tennex.integration.v1.IntegrationService.UpdateParticipantRoles:input_type -> tennex.integration.v1.UpdateParticipantRolesRequest
+	23, // 66: tennex.integration.v1.IntegrationService.UpdateOutboxDelivery:input_type -> tennex.integration.v1.UpdateOutboxDeliveryRequest
+	25, // 67: tennex.integration.v1.IntegrationService.UpdateMessageDelivery:input_type -> tennex.integration.v1.UpdateMessageDeliveryRequest
+	27, // 68: tennex.integration.v1.IntegrationService.ProcessPollVote:input_type -> tennex.integration.v1.ProcessPollVoteRequest
+	29, // 69: tennex.integration.v1.IntegrationService.SyncIdentityMappings:input_type -> tennex.integration.v1.SyncIdentityMappingsRequest
+	36, // 70: tennex.integration.v1.IntegrationService.CreateUserIntegration:input_type -> tennex.integration.v1.CreateUserIntegrationRequest
+	31, // 71: tennex.integration.v1.MediaService.GetMissingMedia:input_type -> tennex.integration.v1.GetMissingMediaRequest
+	33, // 72: tennex.integration.v1.MediaService.UploadMedia:input_type -> tennex.integration.v1.UploadMediaRequest
+	8,  // 73: tennex.integration.v1.IntegrationService.UpdateConnectionStatus:output_type -> tennex.integration.v1.UpdateConnectionStatusResponse
+	10, // 74: tennex.integration.v1.IntegrationService.SyncConversations:output_type -> tennex.integration.v1.SyncConversationsResponse
+	12, // 75: tennex.integration.v1.IntegrationService.SyncContacts:output_type -> tennex.integration.v1.SyncContactsResponse
+	14, // 76: tennex.integration.v1.IntegrationService.SyncMessages:output_type -> tennex.integration.v1.SyncMessagesResponse
+	16, // 77: tennex.integration.v1.IntegrationService.ProcessMessage:output_type -> tennex.integration.v1.ProcessMessageResponse
+	18, // 78: tennex.integration.v1.IntegrationService.UpdateConversationState:output_type -> tennex.integration.v1.UpdateConversationStateResponse
+	20, // 79: tennex.integration.v1.IntegrationService.UpdatePresence:output_type -> tennex.integration.v1.UpdatePresenceResponse
+	22, // 80: tennex.integration.v1.IntegrationService.UpdateParticipantRoles:output_type -> tennex.integration.v1.UpdateParticipantRolesResponse
+	24, // 81: tennex.integration.v1.IntegrationService.UpdateOutboxDelivery:output_type -> tennex.integration.v1.UpdateOutboxDeliveryResponse
+	26, // 82: tennex.integration.v1.IntegrationService.UpdateMessageDelivery:output_type -> tennex.integration.v1.UpdateMessageDeliveryResponse
+	28, // 83: tennex.integration.v1.IntegrationService.ProcessPollVote:output_type -> tennex.integration.v1.ProcessPollVoteResponse
+	30, // 84: tennex.integration.v1.IntegrationService.SyncIdentityMappings:output_type -> tennex.integration.v1.SyncIdentityMappingsResponse
+	37, // 85: tennex.integration.v1.IntegrationService.CreateUserIntegration:output_type -> tennex.integration.v1.CreateUserIntegrationResponse
+	32, // 86: tennex.integration.v1.MediaService.GetMissingMedia:output_type -> tennex.integration.v1.GetMissingMediaResponse
+	35, // 87: tennex.integration.v1.MediaService.UploadMedia:output_type -> tennex.integration.v1.UploadMediaResponse
+	73, // [73:88] is the sub-list for method output_type
+	58, // [58:73] is the sub-list for method input_type
+	58, // [58:58] is the sub-list for extension type_name
+	58, // [58:58] is the sub-list for extension extendee
+	0,  // [0:58] is the sub-list for field type_name
 }
 
 func init() { file_proto_integration_proto_init() }
@@ -2529,9 +4014,9 @@ func file_proto_integration_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_integration_proto_rawDesc), len(file_proto_integration_proto_rawDesc)),
 			NumEnums:      6,
-			NumMessages:   28,
+			NumMessages:   48,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_proto_integration_proto_goTypes,
 		DependencyIndexes: file_proto_integration_proto_depIdxs,
@@ -25,6 +25,12 @@ const (
 	IntegrationService_SyncMessages_FullMethodName            = "/tennex.integration.v1.IntegrationService/SyncMessages"
 	IntegrationService_ProcessMessage_FullMethodName          = "/tennex.integration.v1.IntegrationService/ProcessMessage"
 	IntegrationService_UpdateConversationState_FullMethodName = "/tennex.integration.v1.IntegrationService/UpdateConversationState"
+	IntegrationService_UpdatePresence_FullMethodName          = "/tennex.integration.v1.IntegrationService/UpdatePresence"
+	IntegrationService_UpdateParticipantRoles_FullMethodName  = "/tennex.integration.v1.IntegrationService/UpdateParticipantRoles"
+	IntegrationService_UpdateOutboxDelivery_FullMethodName    = "/tennex.integration.v1.IntegrationService/UpdateOutboxDelivery"
+	IntegrationService_UpdateMessageDelivery_FullMethodName   = "/tennex.integration.v1.IntegrationService/UpdateMessageDelivery"
+	IntegrationService_ProcessPollVote_FullMethodName         = "/tennex.integration.v1.IntegrationService/ProcessPollVote"
+	IntegrationService_SyncIdentityMappings_FullMethodName    = "/tennex.integration.v1.IntegrationService/SyncIdentityMappings"
 	IntegrationService_CreateUserIntegration_FullMethodName   = "/tennex.integration.v1.IntegrationService/CreateUserIntegration"
 )
 
@@ -36,13 +42,21 @@ const (
 type IntegrationServiceClient interface {
 	// Connection Management
 	UpdateConnectionStatus(ctx context.Context, in *UpdateConnectionStatusRequest, opts ...grpc.CallOption) (*UpdateConnectionStatusResponse, error)
-	// Bulk Synchronization (with streaming for large datasets)
-	SyncConversations(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SyncConversationsRequest, SyncConversationsResponse], error)
-	SyncContacts(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SyncContactsRequest, SyncContactsResponse], error)
-	SyncMessages(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SyncMessagesRequest, SyncMessagesResponse], error)
+	// Bulk Synchronization (with streaming for large datasets). The server
+	// acknowledges each batch with a response once it's stored, so a client
+	// whose stream breaks can resend the unacknowledged batches on a new one.
+	SyncConversations(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SyncConversationsRequest, SyncConversationsResponse], error)
+	SyncContacts(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SyncContactsRequest, SyncContactsResponse], error)
+	SyncMessages(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SyncMessagesRequest, SyncMessagesResponse], error)
 	// Real-time Events
 	ProcessMessage(ctx context.Context, in *ProcessMessageRequest, opts ...grpc.CallOption) (*ProcessMessageResponse, error)
 	UpdateConversationState(ctx context.Context, in *UpdateConversationStateRequest, opts ...grpc.CallOption) (*UpdateConversationStateResponse, error)
+	UpdatePresence(ctx context.Context, in *UpdatePresenceRequest, opts ...grpc.CallOption) (*UpdatePresenceResponse, error)
+	UpdateParticipantRoles(ctx context.Context, in *UpdateParticipantRolesRequest, opts ...grpc.CallOption) (*UpdateParticipantRolesResponse, error)
+	UpdateOutboxDelivery(ctx context.Context, in *UpdateOutboxDeliveryRequest, opts ...grpc.CallOption) (*UpdateOutboxDeliveryResponse, error)
+	UpdateMessageDelivery(ctx context.Context, in *UpdateMessageDeliveryRequest, opts ...grpc.CallOption) (*UpdateMessageDeliveryResponse, error)
+	ProcessPollVote(ctx context.Context, in *ProcessPollVoteRequest, opts ...grpc.CallOption) (*ProcessPollVoteResponse, error)
+	SyncIdentityMappings(ctx context.Context, in *SyncIdentityMappingsRequest, opts ...grpc.CallOption) (*SyncIdentityMappingsResponse, error)
 	// Integration Management
 	CreateUserIntegration(ctx context.Context, in *CreateUserIntegrationRequest, opts ...grpc.CallOption) (*CreateUserIntegrationResponse, error)
 }
@@ -65,7 +79,7 @@ func (c *integrationServiceClient) UpdateConnectionStatus(ctx context.Context, i
 	return out, nil
 }
 
-func (c *integrationServiceClient) SyncConversations(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SyncConversationsRequest, SyncConversationsResponse], error) {
+func (c *integrationServiceClient) SyncConversations(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SyncConversationsRequest, SyncConversationsResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	stream, err := c.cc.NewStream(ctx, &IntegrationService_ServiceDesc.Streams[0], IntegrationService_SyncConversations_FullMethodName, cOpts...)
 	if err != nil {
@@ -76,9 +90,9 @@ func (c *integrationServiceClient) SyncConversations(ctx context.Context, opts .
 }
 
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type IntegrationService_SyncConversationsClient = grpc.ClientStreamingClient[SyncConversationsRequest, SyncConversationsResponse]
+type IntegrationService_SyncConversationsClient = grpc.BidiStreamingClient[SyncConversationsRequest, SyncConversationsResponse]
 
-func (c *integrationServiceClient) SyncContacts(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SyncContactsRequest, SyncContactsResponse], error) {
+func (c *integrationServiceClient) SyncContacts(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SyncContactsRequest, SyncContactsResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	stream, err := c.cc.NewStream(ctx, &IntegrationService_ServiceDesc.Streams[1], IntegrationService_SyncContacts_FullMethodName, cOpts...)
 	if err != nil {
@@ -89,9 +103,9 @@ func (c *integrationServiceClient) SyncContacts(ctx context.Context, opts ...grp
 }
 
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type IntegrationService_SyncContactsClient = grpc.ClientStreamingClient[SyncContactsRequest, SyncContactsResponse]
+type IntegrationService_SyncContactsClient = grpc.BidiStreamingClient[SyncContactsRequest, SyncContactsResponse]
 
-func (c *integrationServiceClient) SyncMessages(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SyncMessagesRequest, SyncMessagesResponse], error) {
+func (c *integrationServiceClient) SyncMessages(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SyncMessagesRequest, SyncMessagesResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	stream, err := c.cc.NewStream(ctx, &IntegrationService_ServiceDesc.Streams[2], IntegrationService_SyncMessages_FullMethodName, cOpts...)
 	if err != nil {
@@ -102,7 +116,7 @@ func (c *integrationServiceClient) SyncMessages(ctx context.Context, opts ...grp
 }
 
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type IntegrationService_SyncMessagesClient = grpc.ClientStreamingClient[SyncMessagesRequest, SyncMessagesResponse]
+type IntegrationService_SyncMessagesClient = grpc.BidiStreamingClient[SyncMessagesRequest, SyncMessagesResponse]
 
 func (c *integrationServiceClient) ProcessMessage(ctx context.Context, in *ProcessMessageRequest, opts ...grpc.CallOption) (*ProcessMessageResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
@@ -124,6 +138,66 @@ func (c *integrationServiceClient) UpdateConversationState(ctx context.Context,
 	return out, nil
 }
 
+func (c *integrationServiceClient) UpdatePresence(ctx context.Context, in *UpdatePresenceRequest, opts ...grpc.CallOption) (*UpdatePresenceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdatePresenceResponse)
+	err := c.cc.Invoke(ctx, IntegrationService_UpdatePresence_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *integrationServiceClient) UpdateParticipantRoles(ctx context.Context, in *UpdateParticipantRolesRequest, opts ...grpc.CallOption) (*UpdateParticipantRolesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateParticipantRolesResponse)
+	err := c.cc.Invoke(ctx, IntegrationService_UpdateParticipantRoles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *integrationServiceClient) UpdateOutboxDelivery(ctx context.Context, in *UpdateOutboxDeliveryRequest, opts ...grpc.CallOption) (*UpdateOutboxDeliveryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateOutboxDeliveryResponse)
+	err := c.cc.Invoke(ctx, IntegrationService_UpdateOutboxDelivery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *integrationServiceClient) UpdateMessageDelivery(ctx context.Context, in *UpdateMessageDeliveryRequest, opts ...grpc.CallOption) (*UpdateMessageDeliveryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateMessageDeliveryResponse)
+	err := c.cc.Invoke(ctx, IntegrationService_UpdateMessageDelivery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *integrationServiceClient) ProcessPollVote(ctx context.Context, in *ProcessPollVoteRequest, opts ...grpc.CallOption) (*ProcessPollVoteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProcessPollVoteResponse)
+	err := c.cc.Invoke(ctx, IntegrationService_ProcessPollVote_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *integrationServiceClient) SyncIdentityMappings(ctx context.Context, in *SyncIdentityMappingsRequest, opts ...grpc.CallOption) (*SyncIdentityMappingsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SyncIdentityMappingsResponse)
+	err := c.cc.Invoke(ctx, IntegrationService_SyncIdentityMappings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *integrationServiceClient) CreateUserIntegration(ctx context.Context, in *CreateUserIntegrationRequest, opts ...grpc.CallOption) (*CreateUserIntegrationResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(CreateUserIntegrationResponse)
@@ -142,13 +216,21 @@ func (c *integrationServiceClient) CreateUserIntegration(ctx context.Context, in
 type IntegrationServiceServer interface {
 	// Connection Management
 	UpdateConnectionStatus(context.Context, *UpdateConnectionStatusRequest) (*UpdateConnectionStatusResponse, error)
-	// Bulk Synchronization (with streaming for large datasets)
-	SyncConversations(grpc.ClientStreamingServer[SyncConversationsRequest, SyncConversationsResponse]) error
-	SyncContacts(grpc.ClientStreamingServer[SyncContactsRequest, SyncContactsResponse]) error
-	SyncMessages(grpc.ClientStreamingServer[SyncMessagesRequest, SyncMessagesResponse]) error
+	// Bulk Synchronization (with streaming for large datasets). The server
+	// acknowledges each batch with a response once it's stored, so a client
+	// whose stream breaks can resend the unacknowledged batches on a new one.
+	SyncConversations(grpc.BidiStreamingServer[SyncConversationsRequest, SyncConversationsResponse]) error
+	SyncContacts(grpc.BidiStreamingServer[SyncContactsRequest, SyncContactsResponse]) error
+	SyncMessages(grpc.BidiStreamingServer[SyncMessagesRequest, SyncMessagesResponse]) error
 	// Real-time Events
 	ProcessMessage(context.Context, *ProcessMessageRequest) (*ProcessMessageResponse, error)
 	UpdateConversationState(context.Context, *UpdateConversationStateRequest) (*UpdateConversationStateResponse, error)
+	UpdatePresence(context.Context, *UpdatePresenceRequest) (*UpdatePresenceResponse, error)
+	UpdateParticipantRoles(context.Context, *UpdateParticipantRolesRequest) (*UpdateParticipantRolesResponse, error)
+	UpdateOutboxDelivery(context.Context, *UpdateOutboxDeliveryRequest) (*UpdateOutboxDeliveryResponse, error)
+	UpdateMessageDelivery(context.Context, *UpdateMessageDeliveryRequest) (*UpdateMessageDeliveryResponse, error)
+	ProcessPollVote(context.Context, *ProcessPollVoteRequest) (*ProcessPollVoteResponse, error)
+	SyncIdentityMappings(context.Context, *SyncIdentityMappingsRequest) (*SyncIdentityMappingsResponse, error)
 	// Integration Management
 	CreateUserIntegration(context.Context, *CreateUserIntegrationRequest) (*CreateUserIntegrationResponse, error)
 	mustEmbedUnimplementedIntegrationServiceServer()
@@ -164,13 +246,13 @@ type UnimplementedIntegrationServiceServer struct{}
 func (UnimplementedIntegrationServiceServer) UpdateConnectionStatus(context.Context, *UpdateConnectionStatusRequest) (*UpdateConnectionStatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateConnectionStatus not implemented")
 }
-func (UnimplementedIntegrationServiceServer) SyncConversations(grpc.ClientStreamingServer[SyncConversationsRequest, SyncConversationsResponse]) error {
+func (UnimplementedIntegrationServiceServer) SyncConversations(grpc.BidiStreamingServer[SyncConversationsRequest, SyncConversationsResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method SyncConversations not implemented")
 }
-func (UnimplementedIntegrationServiceServer) SyncContacts(grpc.ClientStreamingServer[SyncContactsRequest, SyncContactsResponse]) error {
+func (UnimplementedIntegrationServiceServer) SyncContacts(grpc.BidiStreamingServer[SyncContactsRequest, SyncContactsResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method SyncContacts not implemented")
 }
-func (UnimplementedIntegrationServiceServer) SyncMessages(grpc.ClientStreamingServer[SyncMessagesRequest, SyncMessagesResponse]) error {
+func (UnimplementedIntegrationServiceServer) SyncMessages(grpc.BidiStreamingServer[SyncMessagesRequest, SyncMessagesResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method SyncMessages not implemented")
 }
 func (UnimplementedIntegrationServiceServer) ProcessMessage(context.Context, *ProcessMessageRequest) (*ProcessMessageResponse, error) {
@@ -179,6 +261,24 @@ func (UnimplementedIntegrationServiceServer) ProcessMessage(context.Context, *Pr
 func (UnimplementedIntegrationServiceServer) UpdateConversationState(context.Context, *UpdateConversationStateRequest) (*UpdateConversationStateResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateConversationState not implemented")
 }
+func (UnimplementedIntegrationServiceServer) UpdatePresence(context.Context, *UpdatePresenceRequest) (*UpdatePresenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePresence not implemented")
+}
+func (UnimplementedIntegrationServiceServer) UpdateParticipantRoles(context.Context, *UpdateParticipantRolesRequest) (*UpdateParticipantRolesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateParticipantRoles not implemented")
+}
+func (UnimplementedIntegrationServiceServer) UpdateOutboxDelivery(context.Context, *UpdateOutboxDeliveryRequest) (*UpdateOutboxDeliveryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateOutboxDelivery not implemented")
+}
+func (UnimplementedIntegrationServiceServer) UpdateMessageDelivery(context.Context, *UpdateMessageDeliveryRequest) (*UpdateMessageDeliveryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateMessageDelivery not implemented")
+}
+func (UnimplementedIntegrationServiceServer) ProcessPollVote(context.Context, *ProcessPollVoteRequest) (*ProcessPollVoteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProcessPollVote not implemented")
+}
+func (UnimplementedIntegrationServiceServer) SyncIdentityMappings(context.Context, *SyncIdentityMappingsRequest) (*SyncIdentityMappingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SyncIdentityMappings not implemented")
+}
 func (UnimplementedIntegrationServiceServer) CreateUserIntegration(context.Context, *CreateUserIntegrationRequest) (*CreateUserIntegrationResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateUserIntegration not implemented")
 }
@@ -226,21 +326,21 @@ func _IntegrationService_SyncConversations_Handler(srv interface{}, stream grpc.
 }
 
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type IntegrationService_SyncConversationsServer = grpc.ClientStreamingServer[SyncConversationsRequest, SyncConversationsResponse]
+type IntegrationService_SyncConversationsServer = grpc.BidiStreamingServer[SyncConversationsRequest, SyncConversationsResponse]
 
 func _IntegrationService_SyncContacts_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(IntegrationServiceServer).SyncContacts(&grpc.GenericServerStream[SyncContactsRequest, SyncContactsResponse]{ServerStream: stream})
 }
 
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type IntegrationService_SyncContactsServer = grpc.ClientStreamingServer[SyncContactsRequest, SyncContactsResponse]
+type IntegrationService_SyncContactsServer = grpc.BidiStreamingServer[SyncContactsRequest, SyncContactsResponse]
 
 func _IntegrationService_SyncMessages_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(IntegrationServiceServer).SyncMessages(&grpc.GenericServerStream[SyncMessagesRequest, SyncMessagesResponse]{ServerStream: stream})
 }
 
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type IntegrationService_SyncMessagesServer = grpc.ClientStreamingServer[SyncMessagesRequest, SyncMessagesResponse]
+type IntegrationService_SyncMessagesServer = grpc.BidiStreamingServer[SyncMessagesRequest, SyncMessagesResponse]
 
 func _IntegrationService_ProcessMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ProcessMessageRequest)
@@ -278,6 +378,114 @@ func _IntegrationService_UpdateConversationState_Handler(srv interface{}, ctx co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _IntegrationService_UpdatePresence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePresenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntegrationServiceServer).UpdatePresence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IntegrationService_UpdatePresence_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntegrationServiceServer).UpdatePresence(ctx, req.(*UpdatePresenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntegrationService_UpdateParticipantRoles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateParticipantRolesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntegrationServiceServer).UpdateParticipantRoles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IntegrationService_UpdateParticipantRoles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntegrationServiceServer).UpdateParticipantRoles(ctx, req.(*UpdateParticipantRolesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntegrationService_UpdateOutboxDelivery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateOutboxDeliveryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntegrationServiceServer).UpdateOutboxDelivery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IntegrationService_UpdateOutboxDelivery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntegrationServiceServer).UpdateOutboxDelivery(ctx, req.(*UpdateOutboxDeliveryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntegrationService_UpdateMessageDelivery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateMessageDeliveryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntegrationServiceServer).UpdateMessageDelivery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IntegrationService_UpdateMessageDelivery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntegrationServiceServer).UpdateMessageDelivery(ctx, req.(*UpdateMessageDeliveryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntegrationService_ProcessPollVote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProcessPollVoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntegrationServiceServer).ProcessPollVote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IntegrationService_ProcessPollVote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntegrationServiceServer).ProcessPollVote(ctx, req.(*ProcessPollVoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntegrationService_SyncIdentityMappings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncIdentityMappingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntegrationServiceServer).SyncIdentityMappings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IntegrationService_SyncIdentityMappings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntegrationServiceServer).SyncIdentityMappings(ctx, req.(*SyncIdentityMappingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _IntegrationService_CreateUserIntegration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CreateUserIntegrationRequest)
 	if err := dec(in); err != nil {
@@ -315,6 +523,30 @@ var IntegrationService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateConversationState",
 			Handler:    _IntegrationService_UpdateConversationState_Handler,
 		},
+		{
+			MethodName: "UpdatePresence",
+			Handler:    _IntegrationService_UpdatePresence_Handler,
+		},
+		{
+			MethodName: "UpdateParticipantRoles",
+			Handler:    _IntegrationService_UpdateParticipantRoles_Handler,
+		},
+		{
+			MethodName: "UpdateOutboxDelivery",
+			Handler:    _IntegrationService_UpdateOutboxDelivery_Handler,
+		},
+		{
+			MethodName: "UpdateMessageDelivery",
+			Handler:    _IntegrationService_UpdateMessageDelivery_Handler,
+		},
+		{
+			MethodName: "ProcessPollVote",
+			Handler:    _IntegrationService_ProcessPollVote_Handler,
+		},
+		{
+			MethodName: "SyncIdentityMappings",
+			Handler:    _IntegrationService_SyncIdentityMappings_Handler,
+		},
 		{
 			MethodName: "CreateUserIntegration",
 			Handler:    _IntegrationService_CreateUserIntegration_Handler,
@@ -324,16 +556,163 @@ var IntegrationService_ServiceDesc = grpc.ServiceDesc{
 		{
 			StreamName:    "SyncConversations",
 			Handler:       _IntegrationService_SyncConversations_Handler,
+			ServerStreams: true,
 			ClientStreams: true,
 		},
 		{
 			StreamName:    "SyncContacts",
 			Handler:       _IntegrationService_SyncContacts_Handler,
+			ServerStreams: true,
 			ClientStreams: true,
 		},
 		{
 			StreamName:    "SyncMessages",
 			Handler:       _IntegrationService_SyncMessages_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "proto/integration.proto",
+}
+
+const (
+	MediaService_GetMissingMedia_FullMethodName = "/tennex.integration.v1.MediaService/GetMissingMedia"
+	MediaService_UploadMedia_FullMethodName     = "/tennex.integration.v1.MediaService/UploadMedia"
+)
+
+// MediaServiceClient is the client API for MediaService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Media service stores the media files bridges download. Files are kept once
+// per content hash, so media forwarded to many chats is only stored once.
+type MediaServiceClient interface {
+	// Returns which content hashes have no stored file yet
+	GetMissingMedia(ctx context.Context, in *GetMissingMediaRequest, opts ...grpc.CallOption) (*GetMissingMediaResponse, error)
+	// Stores a file; the first request carries the header, the rest its data
+	UploadMedia(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadMediaRequest, UploadMediaResponse], error)
+}
+
+type mediaServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMediaServiceClient(cc grpc.ClientConnInterface) MediaServiceClient {
+	return &mediaServiceClient{cc}
+}
+
+func (c *mediaServiceClient) GetMissingMedia(ctx context.Context, in *GetMissingMediaRequest, opts ...grpc.CallOption) (*GetMissingMediaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMissingMediaResponse)
+	err := c.cc.Invoke(ctx, MediaService_GetMissingMedia_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mediaServiceClient) UploadMedia(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadMediaRequest, UploadMediaResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MediaService_ServiceDesc.Streams[0], MediaService_UploadMedia_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadMediaRequest, UploadMediaResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MediaService_UploadMediaClient = grpc.ClientStreamingClient[UploadMediaRequest, UploadMediaResponse]
+
+// MediaServiceServer is the server API for MediaService service.
+// All implementations must embed UnimplementedMediaServiceServer
+// for forward compatibility.
+//
+// Media service stores the media files bridges download. Files are kept once
+// per content hash, so media forwarded to many chats is only stored once.
+type MediaServiceServer interface {
+	// Returns which content hashes have no stored file yet
+	GetMissingMedia(context.Context, *GetMissingMediaRequest) (*GetMissingMediaResponse, error)
+	// Stores a file; the first request carries the header, the rest its data
+	UploadMedia(grpc.ClientStreamingServer[UploadMediaRequest, UploadMediaResponse]) error
+	mustEmbedUnimplementedMediaServiceServer()
+}
+
+// UnimplementedMediaServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMediaServiceServer struct{}
+
+func (UnimplementedMediaServiceServer) GetMissingMedia(context.Context, *GetMissingMediaRequest) (*GetMissingMediaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMissingMedia not implemented")
+}
+func (UnimplementedMediaServiceServer) UploadMedia(grpc.ClientStreamingServer[UploadMediaRequest, UploadMediaResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method UploadMedia not implemented")
+}
+func (UnimplementedMediaServiceServer) mustEmbedUnimplementedMediaServiceServer() {}
+func (UnimplementedMediaServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeMediaServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MediaServiceServer will
+// result in compilation errors.
+type UnsafeMediaServiceServer interface {
+	mustEmbedUnimplementedMediaServiceServer()
+}
+
+func RegisterMediaServiceServer(s grpc.ServiceRegistrar, srv MediaServiceServer) {
+	// If the following call pancis, it indicates UnimplementedMediaServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MediaService_ServiceDesc, srv)
+}
+
+func _MediaService_GetMissingMedia_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMissingMediaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MediaServiceServer).GetMissingMedia(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MediaService_GetMissingMedia_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MediaServiceServer).GetMissingMedia(ctx, req.(*GetMissingMediaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MediaService_UploadMedia_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MediaServiceServer).UploadMedia(&grpc.GenericServerStream[UploadMediaRequest, UploadMediaResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MediaService_UploadMediaServer = grpc.ClientStreamingServer[UploadMediaRequest, UploadMediaResponse]
+
+// MediaService_ServiceDesc is the grpc.ServiceDesc for MediaService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MediaService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tennex.integration.v1.MediaService",
+	HandlerType: (*MediaServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetMissingMedia",
+			Handler:    _MediaService_GetMissingMedia_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadMedia",
+			Handler:       _MediaService_UploadMedia_Handler,
 			ClientStreams: true,
 		},
 	},